@@ -33,6 +33,7 @@ const EnvVaultWrapTTL = "VAULT_WRAP_TTL"
 const EnvVaultMaxRetries = "VAULT_MAX_RETRIES"
 const EnvVaultToken = "VAULT_TOKEN"
 const EnvVaultMFA = "VAULT_MFA"
+const EnvVaultNamespace = "VAULT_NAMESPACE"
 
 // WrappingLookupFunc is a function that, given an HTTP verb and a path,
 // returns an optional string duration to be used for response wrapping (e.g.
@@ -248,6 +249,7 @@ type Client struct {
 	wrappingLookupFunc WrappingLookupFunc
 	mfaCreds           []string
 	policyOverride     bool
+	namespace          string
 }
 
 // SetMFACreds sets the MFA credentials supplied either via the environment
@@ -323,6 +325,10 @@ func NewClient(c *Config) (*Client, error) {
 		client.SetToken(token)
 	}
 
+	if namespace := os.Getenv(EnvVaultNamespace); namespace != "" {
+		client.SetNamespace(namespace)
+	}
+
 	return client, nil
 }
 
@@ -376,6 +382,19 @@ func (c *Client) ClearToken() {
 	c.token = ""
 }
 
+// Namespace returns the namespace requests are made in. It will return the
+// empty string for the root namespace.
+func (c *Client) Namespace() string {
+	return c.namespace
+}
+
+// SetNamespace sets the namespace future requests are made in; request paths
+// are then relative to the namespace. Setting this on a client will override
+// the value of the VAULT_NAMESPACE environment variable.
+func (c *Client) SetNamespace(namespace string) {
+	c.namespace = namespace
+}
+
 // SetHeaders sets the headers to be used for future requests.
 func (c *Client) SetHeaders(headers http.Header) {
 	c.headers = headers
@@ -445,6 +464,7 @@ func (c *Client) NewRequest(method, requestPath string) *Request {
 	}
 
 	req.PolicyOverride = c.policyOverride
+	req.Namespace = c.namespace
 
 	return req
 }
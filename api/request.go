@@ -26,6 +26,10 @@ type Request struct {
 	// EGPs). If set, the override flag will take effect for all policies
 	// evaluated during the request.
 	PolicyOverride bool
+
+	// Namespace is the path of the namespace the request is made in. Request
+	// paths are relative to it.
+	Namespace string
 }
 
 // SetJSONBody is used to set a request body that is a JSON-encoded value.
@@ -93,5 +97,9 @@ func (r *Request) ToHTTP() (*http.Request, error) {
 		req.Header.Set("X-Vault-Policy-Override", "true")
 	}
 
+	if len(r.Namespace) != 0 {
+		req.Header.Set("X-Vault-Namespace", r.Namespace)
+	}
+
 	return req, nil
 }
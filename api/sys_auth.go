@@ -91,6 +91,7 @@ type EnableAuthOptions struct {
 
 type AuthConfigInput struct {
 	PluginName string `json:"plugin_name,omitempty" structs:"plugin_name,omitempty" mapstructure:"plugin_name"`
+	TokenType  string `json:"token_type,omitempty" structs:"token_type,omitempty" mapstructure:"token_type"`
 }
 
 type AuthMount struct {
@@ -105,4 +106,5 @@ type AuthConfigOutput struct {
 	DefaultLeaseTTL int    `json:"default_lease_ttl" structs:"default_lease_ttl" mapstructure:"default_lease_ttl"`
 	MaxLeaseTTL     int    `json:"max_lease_ttl" structs:"max_lease_ttl" mapstructure:"max_lease_ttl"`
 	PluginName      string `json:"plugin_name,omitempty" structs:"plugin_name,omitempty" mapstructure:"plugin_name"`
+	TokenType       string `json:"token_type,omitempty" structs:"token_type,omitempty" mapstructure:"token_type"`
 }
@@ -133,6 +133,7 @@ type MountConfigInput struct {
 	ForceNoCache    bool   `json:"force_no_cache" structs:"force_no_cache" mapstructure:"force_no_cache"`
 	PluginName      string `json:"plugin_name,omitempty" structs:"plugin_name,omitempty" mapstructure:"plugin_name"`
 	SealWrap        bool   `json:"seal_wrap" structs:"seal_wrap" mapstructure:"seal_wrap"`
+	TokenType       string `json:"token_type,omitempty" structs:"token_type,omitempty" mapstructure:"token_type"`
 }
 
 type MountOutput struct {
@@ -149,4 +150,5 @@ type MountConfigOutput struct {
 	ForceNoCache    bool   `json:"force_no_cache" structs:"force_no_cache" mapstructure:"force_no_cache"`
 	PluginName      string `json:"plugin_name,omitempty" structs:"plugin_name,omitempty" mapstructure:"plugin_name"`
 	SealWrap        bool   `json:"seal_wrap" structs:"seal_wrap" mapstructure:"seal_wrap"`
+	TokenType       string `json:"token_type,omitempty" structs:"token_type,omitempty" mapstructure:"token_type"`
 }
@@ -94,6 +94,10 @@ func Hash(salter *salt.Salt, raw interface{}) error {
 
 		s.Token = fn(s.Token)
 
+		if s.Accessor != "" {
+			s.Accessor = fn(s.Accessor)
+		}
+
 		if s.WrappedAccessor != "" {
 			s.WrappedAccessor = fn(s.WrappedAccessor)
 		}
@@ -0,0 +1,143 @@
+package jwt
+
+import (
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+const (
+	configPath string = "config"
+	rolePrefix string = "role/"
+)
+
+func Factory(conf *logical.BackendConfig) (logical.Backend, error) {
+	b := Backend()
+	if err := b.Setup(conf); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+type backend struct {
+	*framework.Backend
+
+	// l protects the cached key set and OIDC provider metadata, which are
+	// built from the config on first use
+	l        sync.RWMutex
+	keySet   keySet
+	provider *oidcProvider
+
+	// oidcRequests holds the state of the OIDC authorization code flows
+	// that are in progress, keyed by their state parameter
+	oidcRequestsLock sync.Mutex
+	oidcRequests     map[string]*oidcRequest
+}
+
+func Backend() *backend {
+	b := &backend{
+		oidcRequests: make(map[string]*oidcRequest),
+	}
+
+	b.Backend = &framework.Backend{
+		Help:        backendHelp,
+		BackendType: logical.TypeCredential,
+		AuthRenew:   b.pathLoginRenew,
+		Invalidate:  b.invalidate,
+
+		PathsSpecial: &logical.Paths{
+			Unauthenticated: []string{
+				"login",
+				"oidc/auth_url",
+				"oidc/callback",
+			},
+			SealWrapStorage: []string{
+				configPath,
+			},
+		},
+
+		Paths: framework.PathAppend(
+			[]*framework.Path{
+				pathConfig(b),
+				pathRoleList(b),
+				pathRole(b),
+				pathLogin(b),
+			},
+			pathOIDC(b),
+		),
+	}
+
+	return b
+}
+
+func (b *backend) invalidate(key string) {
+	switch key {
+	case configPath:
+		b.reset()
+	}
+}
+
+// reset drops the cached key set and OIDC provider metadata so that they
+// are rebuilt from the config on next use
+func (b *backend) reset() {
+	b.l.Lock()
+	b.keySet = nil
+	b.provider = nil
+	b.l.Unlock()
+}
+
+// oidcRequest is the state of an OIDC authorization code flow between the
+// creation of the authorization URL and the callback
+type oidcRequest struct {
+	role        string
+	nonce       string
+	redirectURI string
+	expiration  time.Time
+}
+
+// storeOIDCRequest keeps the state of a new OIDC flow, and drops the flows
+// that were never completed
+func (b *backend) storeOIDCRequest(state string, req *oidcRequest) {
+	b.oidcRequestsLock.Lock()
+	defer b.oidcRequestsLock.Unlock()
+
+	now := time.Now()
+	for k, v := range b.oidcRequests {
+		if now.After(v.expiration) {
+			delete(b.oidcRequests, k)
+		}
+	}
+
+	b.oidcRequests[state] = req
+}
+
+// takeOIDCRequest returns the state of an OIDC flow and removes it, so that
+// each state can only be used once
+func (b *backend) takeOIDCRequest(state string) *oidcRequest {
+	b.oidcRequestsLock.Lock()
+	defer b.oidcRequestsLock.Unlock()
+
+	req, ok := b.oidcRequests[state]
+	if !ok {
+		return nil
+	}
+	delete(b.oidcRequests, state)
+
+	if time.Now().After(req.expiration) {
+		return nil
+	}
+
+	return req
+}
+
+const backendHelp = `
+The JWT credential provider allows authentication using signed JSON Web
+Tokens. Tokens can be validated against a set of public keys, a JWKS URL or
+the keys of an OpenID Connect provider found through discovery.
+
+Roles define the claims a token must carry, and how the claims are mapped
+to the identity of the client. The OIDC authorization code flow can be used
+to log in through a browser when OIDC discovery is configured.
+`
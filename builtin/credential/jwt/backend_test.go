@@ -0,0 +1,634 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+	jose "gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+func getBackend(t *testing.T) (*backend, logical.Storage) {
+	defaultLeaseTTLVal := time.Hour * 12
+	maxLeaseTTLVal := time.Hour * 24
+
+	config := &logical.BackendConfig{
+		System: &logical.StaticSystemView{
+			DefaultLeaseTTLVal: defaultLeaseTTLVal,
+			MaxLeaseTTLVal:     maxLeaseTTLVal,
+		},
+		StorageView: &logical.InmemStorage{},
+	}
+	b, err := Factory(config)
+	if err != nil {
+		t.Fatalf("unable to create backend: %v", err)
+	}
+
+	return b.(*backend), config.StorageView
+}
+
+func newKey(t *testing.T) *ecdsa.PrivateKey {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return priv
+}
+
+func publicKeyPEM(t *testing.T, priv *ecdsa.PrivateKey) string {
+	der, err := x509.MarshalPKIXPublicKey(&priv.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{
+		Type:  "PUBLIC KEY",
+		Bytes: der,
+	}))
+}
+
+func signToken(t *testing.T, priv *ecdsa.PrivateKey, keyID string, claims interface{}, privateClaims interface{}) string {
+	sig, err := jose.NewSigner(jose.SigningKey{
+		Algorithm: jose.ES256,
+		Key: jose.JSONWebKey{
+			Key:   priv,
+			KeyID: keyID,
+		},
+	}, (&jose.SignerOptions{}).WithType("JWT"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	raw, err := jwt.Signed(sig).Claims(claims).Claims(privateClaims).CompactSerialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+func request(t *testing.T, b *backend, storage logical.Storage, op logical.Operation, path string, data map[string]interface{}) *logical.Response {
+	req := &logical.Request{
+		Operation: op,
+		Path:      path,
+		Storage:   storage,
+		Data:      data,
+		Connection: &logical.Connection{
+			RemoteAddr: "127.0.0.1",
+		},
+	}
+	resp, err := b.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v, resp: %#v", err, resp)
+	}
+	return resp
+}
+
+func expectError(t *testing.T, resp *logical.Response, msg string) {
+	if resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error response (%s), got: %#v", msg, resp)
+	}
+}
+
+func expectSuccess(t *testing.T, resp *logical.Response) {
+	if resp != nil && resp.IsError() {
+		t.Fatalf("unexpected error response: %#v", resp.Data)
+	}
+}
+
+func TestConfig_Validation(t *testing.T) {
+	b, storage := getBackend(t)
+	priv := newKey(t)
+
+	cases := []struct {
+		name string
+		data map[string]interface{}
+	}{
+		{"no key source", map[string]interface{}{}},
+		{"multiple key sources", map[string]interface{}{
+			"jwt_validation_pubkeys": []string{publicKeyPEM(t, priv)},
+			"jwks_url":               "https://127.0.0.1:1/keys",
+		}},
+		{"invalid key", map[string]interface{}{
+			"jwt_validation_pubkeys": []string{"not a key"},
+		}},
+		{"client ID without discovery", map[string]interface{}{
+			"jwt_validation_pubkeys": []string{publicKeyPEM(t, priv)},
+			"oidc_client_id":         "abc",
+		}},
+		{"CA without JWKS URL", map[string]interface{}{
+			"jwt_validation_pubkeys": []string{publicKeyPEM(t, priv)},
+			"jwks_ca_pem":            "foo",
+		}},
+	}
+	for _, tc := range cases {
+		resp := request(t, b, storage, logical.UpdateOperation, "config", tc.data)
+		expectError(t, resp, tc.name)
+	}
+
+	resp := request(t, b, storage, logical.UpdateOperation, "config", map[string]interface{}{
+		"jwt_validation_pubkeys": []string{publicKeyPEM(t, priv)},
+		"bound_issuer":           "https://issuer.example.com",
+		"default_role":           "dev",
+	})
+	expectSuccess(t, resp)
+
+	resp = request(t, b, storage, logical.ReadOperation, "config", nil)
+	if resp == nil {
+		t.Fatal("expected config")
+	}
+	if resp.Data["bound_issuer"] != "https://issuer.example.com" || resp.Data["default_role"] != "dev" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	if _, ok := resp.Data["oidc_client_secret"]; ok {
+		t.Fatal("client secret should not be returned")
+	}
+}
+
+func TestRole_CRUD(t *testing.T) {
+	b, storage := getBackend(t)
+
+	// A JWT role needs a bound constraint
+	resp := request(t, b, storage, logical.CreateOperation, "role/dev", map[string]interface{}{
+		"user_claim": "sub",
+	})
+	expectError(t, resp, "no bound constraint")
+
+	// An OIDC role needs allowed redirect URIs
+	resp = request(t, b, storage, logical.CreateOperation, "role/dev", map[string]interface{}{
+		"role_type":  "oidc",
+		"user_claim": "sub",
+	})
+	expectError(t, resp, "no redirect URIs")
+
+	// Two claims can't be mapped to the same metadata key
+	resp = request(t, b, storage, logical.CreateOperation, "role/dev", map[string]interface{}{
+		"user_claim":      "sub",
+		"bound_audiences": "vault",
+		"claim_mappings": map[string]interface{}{
+			"a": "foo",
+			"b": "foo",
+		},
+	})
+	expectError(t, resp, "duplicate claim mapping")
+
+	resp = request(t, b, storage, logical.CreateOperation, "role/dev", map[string]interface{}{
+		"user_claim":        "sub",
+		"bound_audiences":   "vault",
+		"bound_subject":     "user",
+		"bound_claims":      map[string]interface{}{"team": []interface{}{"a", "b"}},
+		"policies":          "dev,prod",
+		"ttl":               "1h",
+		"clock_skew_leeway": 30,
+	})
+	expectSuccess(t, resp)
+
+	resp = request(t, b, storage, logical.ReadOperation, "role/dev", nil)
+	if resp == nil {
+		t.Fatal("expected role")
+	}
+	expected := map[string]interface{}{
+		"role_type":             "jwt",
+		"policies":              []string{"dev", "prod"},
+		"ttl":                   int64(3600),
+		"max_ttl":               int64(0),
+		"period":                int64(0),
+		"bound_cidrs":           []string(nil),
+		"bound_audiences":       []string{"vault"},
+		"bound_subject":         "user",
+		"bound_claims":          map[string]interface{}{"team": []interface{}{"a", "b"}},
+		"claim_mappings":        map[string]string(nil),
+		"user_claim":            "sub",
+		"groups_claim":          "",
+		"clock_skew_leeway":     int64(30),
+		"allowed_redirect_uris": []string(nil),
+		"oidc_scopes":           []string(nil),
+	}
+	if !reflect.DeepEqual(resp.Data, expected) {
+		t.Fatalf("bad: expected:\n%#v\nactual:\n%#v", expected, resp.Data)
+	}
+
+	resp = request(t, b, storage, logical.ListOperation, "role/", nil)
+	if keys := resp.Data["keys"].([]string); !reflect.DeepEqual(keys, []string{"dev"}) {
+		t.Fatalf("bad: %#v", keys)
+	}
+
+	request(t, b, storage, logical.DeleteOperation, "role/dev", nil)
+	resp = request(t, b, storage, logical.ReadOperation, "role/dev", nil)
+	if resp != nil {
+		t.Fatalf("expected role to be deleted, got: %#v", resp)
+	}
+}
+
+func setupPubKeyBackend(t *testing.T, priv *ecdsa.PrivateKey) (*backend, logical.Storage) {
+	b, storage := getBackend(t)
+
+	resp := request(t, b, storage, logical.UpdateOperation, "config", map[string]interface{}{
+		"jwt_validation_pubkeys": []string{publicKeyPEM(t, priv)},
+		"bound_issuer":           "https://team-vault.auth0.com/",
+	})
+	expectSuccess(t, resp)
+
+	resp = request(t, b, storage, logical.CreateOperation, "role/plugin-test", map[string]interface{}{
+		"user_claim":      "https://vault/user",
+		"groups_claim":    "https://vault/groups",
+		"bound_audiences": "https://vault.plugin.auth.jwt.test",
+		"bound_subject":   "r3qXcK2bix9eFECzsU3Sbmh0K16fatW6@clients",
+		"bound_claims": map[string]interface{}{
+			"/org/team": []interface{}{"a", "b"},
+		},
+		"claim_mappings": map[string]interface{}{
+			"color":     "flavor",
+			"/org/team": "team",
+		},
+		"policies": "test",
+		"ttl":      "3h",
+	})
+	expectSuccess(t, resp)
+
+	return b, storage
+}
+
+func testClaims() (jwt.Claims, map[string]interface{}) {
+	cl := jwt.Claims{
+		Subject:   "r3qXcK2bix9eFECzsU3Sbmh0K16fatW6@clients",
+		Issuer:    "https://team-vault.auth0.com/",
+		NotBefore: jwt.NewNumericDate(time.Now().Add(-5 * time.Second)),
+		Expiry:    jwt.NewNumericDate(time.Now().Add(5 * time.Second)),
+		Audience:  jwt.Audience{"https://vault.plugin.auth.jwt.test"},
+	}
+	privateCl := map[string]interface{}{
+		"https://vault/user":   "jeff",
+		"https://vault/groups": []string{"foo", "bar"},
+		"color":                "green",
+		"org": map[string]interface{}{
+			"team": "a",
+		},
+	}
+	return cl, privateCl
+}
+
+func TestLogin_PubKey(t *testing.T) {
+	priv := newKey(t)
+	b, storage := setupPubKeyBackend(t, priv)
+
+	cl, privateCl := testClaims()
+	resp := request(t, b, storage, logical.UpdateOperation, "login", map[string]interface{}{
+		"role": "plugin-test",
+		"jwt":  signToken(t, priv, "", cl, privateCl),
+	})
+	expectSuccess(t, resp)
+
+	auth := resp.Auth
+	if auth == nil {
+		t.Fatal("expected auth")
+	}
+	if auth.Alias.Name != "jeff" || auth.DisplayName != "jeff" {
+		t.Fatalf("bad alias: %#v", auth.Alias)
+	}
+	if !reflect.DeepEqual(auth.Alias.Metadata, map[string]string{"flavor": "green", "team": "a"}) {
+		t.Fatalf("bad metadata: %#v", auth.Alias.Metadata)
+	}
+	if !reflect.DeepEqual(auth.Policies, []string{"test"}) {
+		t.Fatalf("bad policies: %#v", auth.Policies)
+	}
+	if auth.TTL != 3*time.Hour {
+		t.Fatalf("bad TTL: %v", auth.TTL)
+	}
+
+	var groups []string
+	for _, alias := range auth.GroupAliases {
+		groups = append(groups, alias.Name)
+	}
+	sort.Strings(groups)
+	if !reflect.DeepEqual(groups, []string{"bar", "foo"}) {
+		t.Fatalf("bad groups: %#v", groups)
+	}
+}
+
+func TestLogin_PubKey_Failures(t *testing.T) {
+	priv := newKey(t)
+	b, storage := setupPubKeyBackend(t, priv)
+
+	login := func(role string, token string) *logical.Response {
+		return request(t, b, storage, logical.UpdateOperation, "login", map[string]interface{}{
+			"role": role,
+			"jwt":  token,
+		})
+	}
+
+	cl, privateCl := testClaims()
+	resp := login("plugin-test", signToken(t, newKey(t), "", cl, privateCl))
+	expectError(t, resp, "unknown key")
+
+	resp = login("unknown", signToken(t, priv, "", cl, privateCl))
+	expectError(t, resp, "unknown role")
+
+	cl, privateCl = testClaims()
+	cl.Audience = jwt.Audience{"https://other.example.com"}
+	expectError(t, login("plugin-test", signToken(t, priv, "", cl, privateCl)), "bad audience")
+
+	cl, privateCl = testClaims()
+	cl.Subject = "other"
+	expectError(t, login("plugin-test", signToken(t, priv, "", cl, privateCl)), "bad subject")
+
+	cl, privateCl = testClaims()
+	cl.Issuer = "https://other.example.com/"
+	expectError(t, login("plugin-test", signToken(t, priv, "", cl, privateCl)), "bad issuer")
+
+	cl, privateCl = testClaims()
+	privateCl["org"] = map[string]interface{}{"team": "c"}
+	expectError(t, login("plugin-test", signToken(t, priv, "", cl, privateCl)), "bad bound claim")
+
+	cl, privateCl = testClaims()
+	delete(privateCl, "org")
+	expectError(t, login("plugin-test", signToken(t, priv, "", cl, privateCl)), "missing bound claim")
+
+	cl, privateCl = testClaims()
+	delete(privateCl, "https://vault/user")
+	expectError(t, login("plugin-test", signToken(t, priv, "", cl, privateCl)), "missing user claim")
+
+	cl, privateCl = testClaims()
+	cl.Expiry = 0
+	expectError(t, login("plugin-test", signToken(t, priv, "", cl, privateCl)), "missing expiry")
+}
+
+func TestLogin_ClockSkewLeeway(t *testing.T) {
+	priv := newKey(t)
+	b, storage := setupPubKeyBackend(t, priv)
+
+	cl, privateCl := testClaims()
+	cl.Expiry = jwt.NewNumericDate(time.Now().Add(-30 * time.Second))
+	token := signToken(t, priv, "", cl, privateCl)
+
+	// The default leeway of a minute accepts the expired token
+	resp := request(t, b, storage, logical.UpdateOperation, "login", map[string]interface{}{
+		"role": "plugin-test",
+		"jwt":  token,
+	})
+	expectSuccess(t, resp)
+
+	// A negative leeway disables it
+	resp = request(t, b, storage, logical.UpdateOperation, "role/plugin-test", map[string]interface{}{
+		"clock_skew_leeway": -1,
+	})
+	expectSuccess(t, resp)
+	resp = request(t, b, storage, logical.UpdateOperation, "login", map[string]interface{}{
+		"role": "plugin-test",
+		"jwt":  token,
+	})
+	expectError(t, resp, "expired token")
+
+	// A larger leeway accepts tokens that are not valid yet
+	resp = request(t, b, storage, logical.UpdateOperation, "role/plugin-test", map[string]interface{}{
+		"clock_skew_leeway": 300,
+	})
+	expectSuccess(t, resp)
+	cl, privateCl = testClaims()
+	cl.NotBefore = jwt.NewNumericDate(time.Now().Add(2 * time.Minute))
+	cl.Expiry = jwt.NewNumericDate(time.Now().Add(5 * time.Minute))
+	resp = request(t, b, storage, logical.UpdateOperation, "login", map[string]interface{}{
+		"role": "plugin-test",
+		"jwt":  signToken(t, priv, "", cl, privateCl),
+	})
+	expectSuccess(t, resp)
+}
+
+// testProvider is a minimal OIDC provider, serving a discovery document, a
+// key set and a token endpoint that returns the ID token set by the test
+type testProvider struct {
+	server *httptest.Server
+	priv   *ecdsa.PrivateKey
+
+	l       sync.Mutex
+	code    string
+	idToken string
+}
+
+func newTestProvider(t *testing.T) *testProvider {
+	p := &testProvider{
+		priv: newKey(t),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                 p.server.URL,
+			"authorization_endpoint": p.server.URL + "/auth",
+			"token_endpoint":         p.server.URL + "/token",
+			"jwks_uri":               p.server.URL + "/certs",
+		})
+	})
+	mux.HandleFunc("/certs", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(jose.JSONWebKeySet{
+			Keys: []jose.JSONWebKey{
+				{
+					Key:       &p.priv.PublicKey,
+					KeyID:     "test-key",
+					Algorithm: string(jose.ES256),
+					Use:       "sig",
+				},
+			},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		p.l.Lock()
+		defer p.l.Unlock()
+
+		if r.FormValue("code") != p.code {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"error":"invalid_grant"}`)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "access",
+			"token_type":   "Bearer",
+			"id_token":     p.idToken,
+		})
+	})
+	p.server = httptest.NewServer(mux)
+
+	return p
+}
+
+func (p *testProvider) setToken(code, idToken string) {
+	p.l.Lock()
+	defer p.l.Unlock()
+	p.code = code
+	p.idToken = idToken
+}
+
+func TestLogin_JWKS(t *testing.T) {
+	p := newTestProvider(t)
+	defer p.server.Close()
+
+	b, storage := getBackend(t)
+
+	resp := request(t, b, storage, logical.UpdateOperation, "config", map[string]interface{}{
+		"jwks_url": p.server.URL + "/certs",
+	})
+	expectSuccess(t, resp)
+
+	resp = request(t, b, storage, logical.CreateOperation, "role/test", map[string]interface{}{
+		"user_claim":    "sub",
+		"bound_subject": "jeff",
+	})
+	expectSuccess(t, resp)
+
+	cl := jwt.Claims{
+		Subject: "jeff",
+		Expiry:  jwt.NewNumericDate(time.Now().Add(time.Minute)),
+	}
+	resp = request(t, b, storage, logical.UpdateOperation, "login", map[string]interface{}{
+		"role": "test",
+		"jwt":  signToken(t, p.priv, "test-key", cl, map[string]interface{}{}),
+	})
+	expectSuccess(t, resp)
+	if resp.Auth == nil || resp.Auth.Alias.Name != "jeff" {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	resp = request(t, b, storage, logical.UpdateOperation, "login", map[string]interface{}{
+		"role": "test",
+		"jwt":  signToken(t, p.priv, "unknown-key", cl, map[string]interface{}{}),
+	})
+	expectError(t, resp, "unknown key ID")
+}
+
+func TestOIDC_AuthCodeFlow(t *testing.T) {
+	p := newTestProvider(t)
+	defer p.server.Close()
+
+	b, storage := getBackend(t)
+
+	resp := request(t, b, storage, logical.UpdateOperation, "config", map[string]interface{}{
+		"oidc_discovery_url": p.server.URL,
+		"oidc_client_id":     "vault-client",
+		"oidc_client_secret": "secret",
+		"default_role":       "test",
+	})
+	expectSuccess(t, resp)
+
+	redirectURI := "http://localhost:8250/oidc/callback"
+	resp = request(t, b, storage, logical.CreateOperation, "role/test", map[string]interface{}{
+		"role_type":             "oidc",
+		"user_claim":            "email",
+		"groups_claim":          "groups",
+		"allowed_redirect_uris": redirectURI,
+		"oidc_scopes":           "email,profile",
+	})
+	expectSuccess(t, resp)
+
+	// OIDC roles can't be used to log in with a JWT directly
+	resp = request(t, b, storage, logical.UpdateOperation, "login", map[string]interface{}{
+		"role": "test",
+		"jwt":  "foo",
+	})
+	expectError(t, resp, "oidc role on login")
+
+	resp = request(t, b, storage, logical.UpdateOperation, "oidc/auth_url", map[string]interface{}{
+		"redirect_uri": "https://evil.example.com/callback",
+	})
+	expectError(t, resp, "unauthorized redirect URI")
+
+	resp = request(t, b, storage, logical.UpdateOperation, "oidc/auth_url", map[string]interface{}{
+		"redirect_uri": redirectURI,
+	})
+	expectSuccess(t, resp)
+
+	authURL, err := url.Parse(resp.Data["auth_url"].(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := authURL.Query()
+	if authURL.Path != "/auth" || query.Get("client_id") != "vault-client" || query.Get("redirect_uri") != redirectURI {
+		t.Fatalf("bad auth_url: %s", authURL)
+	}
+	if query.Get("scope") != "openid email profile" {
+		t.Fatalf("bad scope: %q", query.Get("scope"))
+	}
+	state, nonce := query.Get("state"), query.Get("nonce")
+	if state == "" || nonce == "" {
+		t.Fatalf("missing state or nonce: %s", authURL)
+	}
+
+	cl := jwt.Claims{
+		Subject:  "jeff",
+		Issuer:   p.server.URL,
+		Audience: jwt.Audience{"vault-client"},
+		Expiry:   jwt.NewNumericDate(time.Now().Add(time.Minute)),
+	}
+	privateCl := map[string]interface{}{
+		"email":  "jeff@example.com",
+		"groups": []string{"eng"},
+		"nonce":  nonce,
+	}
+	p.setToken("abc", signToken(t, p.priv, "test-key", cl, privateCl))
+
+	resp = request(t, b, storage, logical.UpdateOperation, "oidc/callback", map[string]interface{}{
+		"state": "unknown",
+		"code":  "abc",
+	})
+	expectError(t, resp, "unknown state")
+
+	resp = request(t, b, storage, logical.UpdateOperation, "oidc/callback", map[string]interface{}{
+		"state": state,
+		"code":  "abc",
+	})
+	expectSuccess(t, resp)
+	if resp.Auth == nil || resp.Auth.Alias.Name != "jeff@example.com" {
+		t.Fatalf("bad: %#v", resp)
+	}
+	if len(resp.Auth.GroupAliases) != 1 || resp.Auth.GroupAliases[0].Name != "eng" {
+		t.Fatalf("bad group aliases: %#v", resp.Auth.GroupAliases)
+	}
+
+	// The state can only be used once
+	resp = request(t, b, storage, logical.UpdateOperation, "oidc/callback", map[string]interface{}{
+		"state": state,
+		"code":  "abc",
+	})
+	expectError(t, resp, "reused state")
+
+	// ID tokens with a wrong nonce are rejected
+	resp = request(t, b, storage, logical.UpdateOperation, "oidc/auth_url", map[string]interface{}{
+		"redirect_uri": redirectURI,
+	})
+	expectSuccess(t, resp)
+	authURL, _ = url.Parse(resp.Data["auth_url"].(string))
+	privateCl["nonce"] = "other"
+	p.setToken("def", signToken(t, p.priv, "test-key", cl, privateCl))
+	resp = request(t, b, storage, logical.UpdateOperation, "oidc/callback", map[string]interface{}{
+		"state": authURL.Query().Get("state"),
+		"code":  "def",
+	})
+	expectError(t, resp, "bad nonce")
+
+	// ID tokens issued to another client are rejected
+	resp = request(t, b, storage, logical.UpdateOperation, "oidc/auth_url", map[string]interface{}{
+		"redirect_uri": redirectURI,
+	})
+	expectSuccess(t, resp)
+	authURL, _ = url.Parse(resp.Data["auth_url"].(string))
+	privateCl["nonce"] = authURL.Query().Get("nonce")
+	cl.Audience = jwt.Audience{"other-client"}
+	p.setToken("ghi", signToken(t, p.priv, "test-key", cl, privateCl))
+	resp = request(t, b, storage, logical.UpdateOperation, "oidc/callback", map[string]interface{}{
+		"state": authURL.Query().Get("state"),
+		"code":  "ghi",
+	})
+	expectError(t, resp, "bad audience")
+}
@@ -0,0 +1,168 @@
+package jwt
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+// getClaim returns the value of a claim. Claims nested in objects can be
+// selected with a JSON pointer, such as "/groups/names". Any other name is
+// used as a top level claim.
+func getClaim(allClaims map[string]interface{}, claim string) interface{} {
+	if !strings.HasPrefix(claim, "/") {
+		return allClaims[claim]
+	}
+
+	var val interface{} = allClaims
+	for _, part := range strings.Split(claim[1:], "/") {
+		// Unescape the reference token as defined by RFC 6901
+		part = strings.Replace(part, "~1", "/", -1)
+		part = strings.Replace(part, "~0", "~", -1)
+
+		m, ok := val.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		val = m[part]
+	}
+
+	return val
+}
+
+// claimString returns the value of a claim as a string. Only strings,
+// numbers and booleans can be converted.
+func claimString(val interface{}) (string, bool) {
+	switch v := val.(type) {
+	case string:
+		return v, true
+	case json.Number:
+		return v.String(), true
+	case float64, bool:
+		return fmt.Sprint(v), true
+	default:
+		return "", false
+	}
+}
+
+// boundClaimValues returns the values that are accepted for a bound claim.
+// The value of a bound claim can be a string or a list of strings.
+func boundClaimValues(value interface{}) ([]string, error) {
+	switch v := value.(type) {
+	case string:
+		return []string{v}, nil
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, errors.New("list values must be strings")
+			}
+			values = append(values, s)
+		}
+		return values, nil
+	case []string:
+		return v, nil
+	default:
+		return nil, errors.New("value must be a string or a list of strings")
+	}
+}
+
+// validateBoundClaims checks that the claims of the token match all the
+// bound claims of the role. If a claim is a list, any of its values may
+// match.
+func validateBoundClaims(boundClaims map[string]interface{}, allClaims map[string]interface{}) error {
+	for claim, expected := range boundClaims {
+		expectedValues, err := boundClaimValues(expected)
+		if err != nil {
+			return fmt.Errorf("invalid bound claim %q: %v", claim, err)
+		}
+
+		actual := getClaim(allClaims, claim)
+		if actual == nil {
+			return fmt.Errorf("claim %q is missing", claim)
+		}
+
+		actualValues := []interface{}{actual}
+		if list, ok := actual.([]interface{}); ok {
+			actualValues = list
+		}
+
+		if !claimValuesMatch(expectedValues, actualValues) {
+			return fmt.Errorf("claim %q does not match any associated bound claim values", claim)
+		}
+	}
+
+	return nil
+}
+
+func claimValuesMatch(expected []string, actual []interface{}) bool {
+	for _, a := range actual {
+		s, ok := claimString(a)
+		if !ok {
+			continue
+		}
+		for _, e := range expected {
+			if s == e {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// extractMetadata builds the alias metadata from the claims selected by the
+// claim mappings of the role
+func extractMetadata(allClaims map[string]interface{}, claimMappings map[string]string) (map[string]string, error) {
+	metadata := make(map[string]string)
+	for source, target := range claimMappings {
+		value := getClaim(allClaims, source)
+		if value == nil {
+			continue
+		}
+
+		s, ok := claimString(value)
+		if !ok {
+			return nil, fmt.Errorf("error converting claim %q to string", source)
+		}
+		metadata[target] = s
+	}
+	return metadata, nil
+}
+
+// extractGroupAliases builds the group aliases from the groups claim, which
+// may be a single group or a list of groups
+func extractGroupAliases(allClaims map[string]interface{}, groupsClaim string) ([]*logical.Alias, error) {
+	if groupsClaim == "" {
+		return nil, nil
+	}
+
+	groupsClaimRaw := getClaim(allClaims, groupsClaim)
+	if groupsClaimRaw == nil {
+		return nil, fmt.Errorf("%q claim not found in token", groupsClaim)
+	}
+
+	groups, ok := groupsClaimRaw.([]interface{})
+	if !ok {
+		groups = []interface{}{groupsClaimRaw}
+	}
+
+	var groupAliases []*logical.Alias
+	for _, groupRaw := range groups {
+		group, ok := claimString(groupRaw)
+		if !ok {
+			return nil, fmt.Errorf("value %v in groups claim could not be parsed as string", groupRaw)
+		}
+		if group == "" {
+			continue
+		}
+		groupAliases = append(groupAliases, &logical.Alias{
+			Name: group,
+		})
+	}
+
+	return groupAliases, nil
+}
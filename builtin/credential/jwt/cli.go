@@ -0,0 +1,194 @@
+package jwt
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+)
+
+const (
+	defaultListenAddress = "localhost"
+	defaultPort          = "8250"
+
+	// defaultCallbackTimeout is how long the CLI waits for the browser flow
+	// to complete
+	defaultCallbackTimeout = 5 * time.Minute
+)
+
+type CLIHandler struct {
+	// DefaultMount is the mount used when none is given, so that the same
+	// handler can serve both the "jwt" and "oidc" methods
+	DefaultMount string
+}
+
+type loginResp struct {
+	secret *api.Secret
+	err    error
+}
+
+func (h *CLIHandler) Auth(c *api.Client, m map[string]string) (*api.Secret, error) {
+	mount, ok := m["mount"]
+	if !ok {
+		mount = h.DefaultMount
+	}
+	if mount == "" {
+		mount = "jwt"
+	}
+
+	role := m["role"]
+
+	token, ok := m["jwt"]
+	if !ok {
+		token = os.Getenv("VAULT_AUTH_JWT")
+	}
+
+	// With a token at hand, log in directly. Otherwise, go through the OIDC
+	// authorization code flow in the browser.
+	if token != "" {
+		path := fmt.Sprintf("auth/%s/login", mount)
+		secret, err := c.Logical().Write(path, map[string]interface{}{
+			"role": role,
+			"jwt":  token,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if secret == nil {
+			return nil, fmt.Errorf("empty response from credential provider")
+		}
+		return secret, nil
+	}
+
+	return h.oidcAuth(c, mount, role, m)
+}
+
+// oidcAuth runs the OIDC authorization code flow. A local HTTP server
+// receives the callback of the OIDC provider, and forwards the code and the
+// state to Vault to complete the login.
+func (h *CLIHandler) oidcAuth(c *api.Client, mount, role string, m map[string]string) (*api.Secret, error) {
+	listenAddress, ok := m["listenaddress"]
+	if !ok {
+		listenAddress = defaultListenAddress
+	}
+	port, ok := m["port"]
+	if !ok {
+		port = defaultPort
+	}
+
+	addr := net.JoinHostPort(listenAddress, port)
+	redirectURI := fmt.Sprintf("http://%s/oidc/callback", addr)
+
+	secret, err := c.Logical().Write(fmt.Sprintf("auth/%s/oidc/auth_url", mount), map[string]interface{}{
+		"role":         role,
+		"redirect_uri": redirectURI,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil {
+		return nil, fmt.Errorf("empty response from credential provider")
+	}
+	authURL, _ := secret.Data["auth_url"].(string)
+	if authURL == "" {
+		return nil, fmt.Errorf("no auth_url returned by the credential provider")
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	defer listener.Close()
+
+	doneCh := make(chan loginResp, 1)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/oidc/callback", func(w http.ResponseWriter, req *http.Request) {
+		var resp loginResp
+		defer func() {
+			select {
+			case doneCh <- resp:
+			default:
+			}
+		}()
+
+		query := req.URL.Query()
+		if errMsg := query.Get("error"); errMsg != "" {
+			resp.err = fmt.Errorf("error from the OIDC provider: %s %s", errMsg, query.Get("error_description"))
+			fmt.Fprintf(w, failurePage, html.EscapeString(resp.err.Error()))
+			return
+		}
+
+		resp.secret, resp.err = c.Logical().Write(fmt.Sprintf("auth/%s/oidc/callback", mount), map[string]interface{}{
+			"state": query.Get("state"),
+			"code":  query.Get("code"),
+		})
+		if resp.err == nil && resp.secret == nil {
+			resp.err = errors.New("empty response from credential provider")
+		}
+		if resp.err != nil {
+			fmt.Fprintf(w, failurePage, html.EscapeString(resp.err.Error()))
+			return
+		}
+		fmt.Fprint(w, successPage)
+	})
+
+	server := &http.Server{Handler: mux}
+	go server.Serve(listener)
+
+	fmt.Fprintf(os.Stderr, "Complete the login via your OIDC provider. Open the following link in your browser:\n\n    %s\n\n", authURL)
+	fmt.Fprintf(os.Stderr, "Waiting for OIDC authentication to complete...\n")
+
+	select {
+	case resp := <-doneCh:
+		return resp.secret, resp.err
+	case <-time.After(defaultCallbackTimeout):
+		return nil, errors.New("timed out waiting for the OIDC authentication to complete")
+	}
+}
+
+const successPage = `<html><body>Vault login successful. You can close this window and return to the CLI.</body></html>`
+
+const failurePage = `<html><body>Vault login failed: %s</body></html>`
+
+func (h *CLIHandler) Help() string {
+	help := `
+The JWT credential provider allows you to authenticate with a JWT, or with an
+OIDC provider through the authorization code flow in your browser.
+
+If a JWT is provided, either as "jwt" or via the VAULT_AUTH_JWT env var, it is
+used to log in directly. Otherwise, an authorization URL is printed, and a
+local HTTP server waits for the OIDC provider to redirect the browser back to
+the CLI once authenticated. The redirect URI of the local server,
+"http://localhost:8250/oidc/callback" by default, must be in the
+allowed_redirect_uris of the role.
+
+    Example: vault auth -method=jwt role=dev jwt=<token>
+
+    Example: vault auth -method=oidc role=dev
+
+Key/Value Pairs:
+
+    mount=jwt                 The mountpoint for the JWT credential provider.
+                              Defaults to "jwt", or "oidc" with -method=oidc
+
+    role=<string>             The role to log in against. Defaults to the
+                              default_role of the backend.
+
+    jwt=<string>              The JWT to log in with.
+
+    listenaddress=<string>    The address the local callback server listens
+                              on during the OIDC flow. Defaults to "localhost"
+
+    port=<string>             The port the local callback server listens on
+                              during the OIDC flow. Defaults to "8250"
+	`
+
+	return strings.TrimSpace(help)
+}
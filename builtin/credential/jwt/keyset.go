@@ -0,0 +1,231 @@
+package jwt
+
+import (
+	"crypto"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	jose "gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+// minRefreshInterval bounds how often the keys of a remote key set are
+// fetched again when a token is signed by an unknown key
+const minRefreshInterval = 1 * time.Minute
+
+// keySet verifies the signatures of tokens
+type keySet interface {
+	// verify checks the signature of the token and decodes its claims
+	// into dest
+	verify(token *jwt.JSONWebToken, dest ...interface{}) error
+}
+
+// staticKeySet verifies signatures with a fixed list of public keys
+type staticKeySet struct {
+	keys []crypto.PublicKey
+}
+
+func (s *staticKeySet) verify(token *jwt.JSONWebToken, dest ...interface{}) error {
+	for _, key := range s.keys {
+		if err := token.Claims(key, dest...); err == nil {
+			return nil
+		}
+	}
+	return errors.New("no known key successfully validated the token signature")
+}
+
+// remoteKeySet verifies signatures with the keys served at a JWKS URL. The
+// keys are cached, and fetched again when a token is signed by a key that is
+// not in the cache, so that key rotations are picked up.
+type remoteKeySet struct {
+	url    string
+	client *http.Client
+
+	l         sync.Mutex
+	keys      *jose.JSONWebKeySet
+	refreshed time.Time
+}
+
+func newRemoteKeySet(client *http.Client, url string) *remoteKeySet {
+	return &remoteKeySet{
+		url:    url,
+		client: client,
+	}
+}
+
+func (r *remoteKeySet) verify(token *jwt.JSONWebToken, dest ...interface{}) error {
+	var keyID string
+	if len(token.Headers) > 0 {
+		keyID = token.Headers[0].KeyID
+	}
+
+	keys, err := r.keysFor(keyID)
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		if err := token.Claims(key, dest...); err == nil {
+			return nil
+		}
+	}
+	return errors.New("no known key successfully validated the token signature")
+}
+
+// keysFor returns the keys that may have signed a token with the given key
+// ID. All keys are returned if the token has no key ID.
+func (r *remoteKeySet) keysFor(keyID string) ([]jose.JSONWebKey, error) {
+	r.l.Lock()
+	defer r.l.Unlock()
+
+	if r.keys != nil {
+		if keys := matchingKeys(r.keys, keyID); len(keys) > 0 {
+			return keys, nil
+		}
+		if time.Since(r.refreshed) < minRefreshInterval {
+			return nil, fmt.Errorf("no key found for key ID %q", keyID)
+		}
+	}
+
+	keySet, err := fetchJWKS(r.client, r.url)
+	if err != nil {
+		return nil, err
+	}
+	r.keys = keySet
+	r.refreshed = time.Now()
+
+	keys := matchingKeys(r.keys, keyID)
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no key found for key ID %q", keyID)
+	}
+	return keys, nil
+}
+
+func matchingKeys(keySet *jose.JSONWebKeySet, keyID string) []jose.JSONWebKey {
+	if keyID == "" {
+		return keySet.Keys
+	}
+	return keySet.Key(keyID)
+}
+
+// fetchJWKS fetches and decodes the key set served at the given URL
+func fetchJWKS(client *http.Client, url string) (*jose.JSONWebKeySet, error) {
+	var keySet jose.JSONWebKeySet
+	if err := getJSON(client, url, &keySet); err != nil {
+		return nil, err
+	}
+	if len(keySet.Keys) == 0 {
+		return nil, errors.New("no keys found in the key set")
+	}
+	return &keySet, nil
+}
+
+// oidcProvider is the metadata of an OpenID Connect provider, as returned
+// by its discovery document
+type oidcProvider struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// discoverOIDCProvider fetches the discovery document of the OIDC provider
+// at the given issuer URL
+func discoverOIDCProvider(client *http.Client, issuer string) (*oidcProvider, error) {
+	wellKnown := strings.TrimSuffix(issuer, "/") + "/.well-known/openid-configuration"
+
+	var provider oidcProvider
+	if err := getJSON(client, wellKnown, &provider); err != nil {
+		return nil, err
+	}
+
+	// The issuer of the discovery document must match the URL it was
+	// fetched from
+	if strings.TrimSuffix(provider.Issuer, "/") != strings.TrimSuffix(issuer, "/") {
+		return nil, fmt.Errorf("issuer did not match the issuer returned by provider, expected %q got %q", issuer, provider.Issuer)
+	}
+	if provider.JWKSURI == "" {
+		return nil, errors.New("provider did not return a jwks_uri")
+	}
+
+	return &provider, nil
+}
+
+func getJSON(client *http.Client, url string, out interface{}) error {
+	resp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", resp.Status, body)
+	}
+
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("failed to decode response from %s: %v", url, err)
+	}
+	return nil
+}
+
+// getKeySet returns the key set to validate tokens with, and the metadata
+// of the OIDC provider if OIDC discovery is configured
+func (b *backend) getKeySet(config *jwtConfig) (keySet, *oidcProvider, error) {
+	b.l.RLock()
+	keys, provider := b.keySet, b.provider
+	b.l.RUnlock()
+	if keys != nil {
+		return keys, provider, nil
+	}
+
+	b.l.Lock()
+	defer b.l.Unlock()
+
+	if b.keySet != nil {
+		return b.keySet, b.provider, nil
+	}
+
+	switch {
+	case config.OIDCDiscoveryURL != "":
+		client, err := httpClient(config.OIDCDiscoveryCAPEM)
+		if err != nil {
+			return nil, nil, err
+		}
+		provider, err = discoverOIDCProvider(client, config.OIDCDiscoveryURL)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error performing OIDC discovery: %v", err)
+		}
+		keys = newRemoteKeySet(client, provider.JWKSURI)
+
+	case config.JWKSURL != "":
+		client, err := httpClient(config.JWKSCAPEM)
+		if err != nil {
+			return nil, nil, err
+		}
+		keys = newRemoteKeySet(client, config.JWKSURL)
+
+	case len(config.ParsedJWTPubKeys) != 0:
+		keys = &staticKeySet{
+			keys: config.ParsedJWTPubKeys,
+		}
+
+	default:
+		return nil, nil, errors.New("unhandled backend configuration")
+	}
+
+	b.keySet = keys
+	b.provider = provider
+
+	return keys, provider, nil
+}
@@ -0,0 +1,284 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	cleanhttp "github.com/hashicorp/go-cleanhttp"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+func pathConfig(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: `config`,
+		Fields: map[string]*framework.FieldSchema{
+			"oidc_discovery_url": {
+				Type:        framework.TypeString,
+				Description: `OIDC Discovery URL, without any .well-known component (base path). Cannot be used with "jwks_url" or "jwt_validation_pubkeys".`,
+			},
+			"oidc_discovery_ca_pem": {
+				Type:        framework.TypeString,
+				Description: "The CA certificate or chain of certificates, in PEM format, to use to validate connections to the OIDC Discovery URL. If not set, system certificates are used.",
+			},
+			"oidc_client_id": {
+				Type:        framework.TypeString,
+				Description: "The OAuth Client ID configured with your OIDC provider, used by the OIDC authorization code flow.",
+			},
+			"oidc_client_secret": {
+				Type:        framework.TypeString,
+				Description: "The OAuth Client Secret configured with your OIDC provider, used by the OIDC authorization code flow.",
+			},
+			"jwks_url": {
+				Type:        framework.TypeString,
+				Description: `JWKS URL to use to authenticate signatures. Cannot be used with "oidc_discovery_url" or "jwt_validation_pubkeys".`,
+			},
+			"jwks_ca_pem": {
+				Type:        framework.TypeString,
+				Description: "The CA certificate or chain of certificates, in PEM format, to use to validate connections to the JWKS URL. If not set, system certificates are used.",
+			},
+			"jwt_validation_pubkeys": {
+				Type:        framework.TypeCommaStringSlice,
+				Description: `A list of PEM-encoded public keys to use to authenticate signatures locally. Cannot be used with "jwks_url" or "oidc_discovery_url".`,
+			},
+			"bound_issuer": {
+				Type:        framework.TypeString,
+				Description: "The value against which to match the 'iss' claim in a JWT. Optional.",
+			},
+			"default_role": {
+				Type:        framework.TypeString,
+				Description: "The default role to use if none is provided during login. If not set, a role is required during login.",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathConfigRead,
+			logical.UpdateOperation: b.pathConfigWrite,
+		},
+
+		HelpSynopsis:    confHelpSyn,
+		HelpDescription: confHelpDesc,
+	}
+}
+
+// jwtConfig is the configuration of the key source used to validate tokens
+type jwtConfig struct {
+	OIDCDiscoveryURL     string   `json:"oidc_discovery_url"`
+	OIDCDiscoveryCAPEM   string   `json:"oidc_discovery_ca_pem"`
+	OIDCClientID         string   `json:"oidc_client_id"`
+	OIDCClientSecret     string   `json:"oidc_client_secret"`
+	JWKSURL              string   `json:"jwks_url"`
+	JWKSCAPEM            string   `json:"jwks_ca_pem"`
+	JWTValidationPubKeys []string `json:"jwt_validation_pubkeys"`
+	BoundIssuer          string   `json:"bound_issuer"`
+	DefaultRole          string   `json:"default_role"`
+
+	ParsedJWTPubKeys []crypto.PublicKey `json:"-"`
+}
+
+// config returns the configuration of the backend, with the static public
+// keys parsed
+func (b *backend) config(s logical.Storage) (*jwtConfig, error) {
+	entry, err := s.Get(configPath)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var result jwtConfig
+	if err := entry.DecodeJSON(&result); err != nil {
+		return nil, err
+	}
+
+	for _, v := range result.JWTValidationPubKeys {
+		key, err := parsePublicKeyPEM([]byte(v))
+		if err != nil {
+			return nil, fmt.Errorf("error parsing public key: %v", err)
+		}
+		result.ParsedJWTPubKeys = append(result.ParsedJWTPubKeys, key)
+	}
+
+	return &result, nil
+}
+
+func (b *backend) pathConfigRead(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	config, err := b.config(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		return nil, nil
+	}
+
+	// The client secret is never returned
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"oidc_discovery_url":     config.OIDCDiscoveryURL,
+			"oidc_discovery_ca_pem":  config.OIDCDiscoveryCAPEM,
+			"oidc_client_id":         config.OIDCClientID,
+			"jwks_url":               config.JWKSURL,
+			"jwks_ca_pem":            config.JWKSCAPEM,
+			"jwt_validation_pubkeys": config.JWTValidationPubKeys,
+			"bound_issuer":           config.BoundIssuer,
+			"default_role":           config.DefaultRole,
+		},
+	}, nil
+}
+
+func (b *backend) pathConfigWrite(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	config := &jwtConfig{
+		OIDCDiscoveryURL:     d.Get("oidc_discovery_url").(string),
+		OIDCDiscoveryCAPEM:   d.Get("oidc_discovery_ca_pem").(string),
+		OIDCClientID:         d.Get("oidc_client_id").(string),
+		OIDCClientSecret:     d.Get("oidc_client_secret").(string),
+		JWKSURL:              d.Get("jwks_url").(string),
+		JWKSCAPEM:            d.Get("jwks_ca_pem").(string),
+		JWTValidationPubKeys: d.Get("jwt_validation_pubkeys").([]string),
+		BoundIssuer:          d.Get("bound_issuer").(string),
+		DefaultRole:          d.Get("default_role").(string),
+	}
+
+	// Run checks on values
+	methodCount := 0
+	if config.OIDCDiscoveryURL != "" {
+		methodCount++
+	}
+	if config.JWKSURL != "" {
+		methodCount++
+	}
+	if len(config.JWTValidationPubKeys) != 0 {
+		methodCount++
+	}
+
+	switch {
+	case methodCount != 1:
+		return logical.ErrorResponse("exactly one of 'jwt_validation_pubkeys', 'jwks_url' or 'oidc_discovery_url' must be set"), nil
+
+	case config.OIDCDiscoveryURL != "":
+		client, err := httpClient(config.OIDCDiscoveryCAPEM)
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+		if _, err := discoverOIDCProvider(client, config.OIDCDiscoveryURL); err != nil {
+			return logical.ErrorResponse(fmt.Sprintf("error checking discovery URL: %v", err)), nil
+		}
+
+	case config.OIDCClientID != "" || config.OIDCClientSecret != "":
+		return logical.ErrorResponse("'oidc_client_id' and 'oidc_client_secret' can only be set with 'oidc_discovery_url'"), nil
+
+	case config.JWKSURL != "":
+		client, err := httpClient(config.JWKSCAPEM)
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+		if _, err := fetchJWKS(client, config.JWKSURL); err != nil {
+			return logical.ErrorResponse(fmt.Sprintf("error checking jwks URL: %v", err)), nil
+		}
+
+	default:
+		for _, v := range config.JWTValidationPubKeys {
+			if _, err := parsePublicKeyPEM([]byte(v)); err != nil {
+				return logical.ErrorResponse(fmt.Sprintf("error parsing public key: %v", err)), nil
+			}
+		}
+	}
+
+	if config.OIDCDiscoveryCAPEM != "" && config.OIDCDiscoveryURL == "" {
+		return logical.ErrorResponse("'oidc_discovery_ca_pem' can only be set with 'oidc_discovery_url'"), nil
+	}
+	if config.JWKSCAPEM != "" && config.JWKSURL == "" {
+		return logical.ErrorResponse("'jwks_ca_pem' can only be set with 'jwks_url'"), nil
+	}
+
+	entry, err := logical.StorageEntryJSON(configPath, config)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(entry); err != nil {
+		return nil, err
+	}
+
+	b.reset()
+
+	return nil, nil
+}
+
+// httpClient returns a client that trusts the given PEM encoded CA
+// certificates, or the system certificates if none are given
+func httpClient(caPEM string) (*http.Client, error) {
+	client := cleanhttp.DefaultClient()
+	if caPEM == "" {
+		return client, nil
+	}
+
+	certPool := x509.NewCertPool()
+	if ok := certPool.AppendCertsFromPEM([]byte(caPEM)); !ok {
+		return nil, errors.New("could not parse the given CA certificates")
+	}
+
+	transport := cleanhttp.DefaultTransport()
+	transport.TLSClientConfig = &tls.Config{
+		RootCAs: certPool,
+	}
+	client.Transport = transport
+
+	return client, nil
+}
+
+// parsePublicKeyPEM parses an RSA or ECDSA public key, or the public key of
+// a certificate, from its PEM encoding
+func parsePublicKeyPEM(data []byte) (crypto.PublicKey, error) {
+	block, data := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("data does not contain any valid public keys")
+	}
+	if len(strings.TrimSpace(string(data))) != 0 {
+		return nil, errors.New("only one public key may be given per value")
+	}
+
+	var key interface{}
+	switch block.Type {
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		key = cert.PublicKey
+	default:
+		var err error
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	switch key.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey:
+		return key, nil
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T", key)
+	}
+}
+
+const (
+	confHelpSyn = `
+Configures the JWT authentication backend.
+`
+	confHelpDesc = `
+The JWT authentication backend validates JWTs (or OIDC ID Tokens) using the
+configured credentials. If using OIDC Discovery, the URL must be provided,
+along with (optionally) the CA cert to use for the connection. If performing
+JWT validation locally, a set of public keys must be provided. Otherwise a
+JWKS URL can be used, from which the signing keys are fetched.
+`
+)
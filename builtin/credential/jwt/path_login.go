@@ -0,0 +1,255 @@
+package jwt
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/vault/helper/cidrutil"
+	"github.com/hashicorp/vault/helper/policyutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+func pathLogin(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: `login$`,
+		Fields: map[string]*framework.FieldSchema{
+			"role": {
+				Type:        framework.TypeString,
+				Description: "The role to log in against.",
+			},
+			"jwt": {
+				Type:        framework.TypeString,
+				Description: "The signed JWT to validate.",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathLogin,
+		},
+
+		HelpSynopsis:    pathLoginHelpSyn,
+		HelpDescription: pathLoginHelpDesc,
+	}
+}
+
+func (b *backend) pathLogin(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	config, err := b.config(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		return logical.ErrorResponse("could not load configuration"), nil
+	}
+
+	roleName := d.Get("role").(string)
+	if roleName == "" {
+		roleName = config.DefaultRole
+	}
+	if roleName == "" {
+		return logical.ErrorResponse("missing role"), nil
+	}
+
+	role, err := b.role(req.Storage, roleName)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return logical.ErrorResponse(fmt.Sprintf("role %q could not be found", roleName)), nil
+	}
+	if role.RoleType == roleTypeOIDC {
+		return logical.ErrorResponse("role with oidc role_type is not allowed"), nil
+	}
+
+	if resp := validateBoundCIDRs(req, role); resp != nil {
+		return resp, nil
+	}
+
+	token := d.Get("jwt").(string)
+	if token == "" {
+		return logical.ErrorResponse("missing token"), nil
+	}
+
+	allClaims, err := b.verifyToken(config, role, token, "")
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	return b.authResponse(roleName, role, allClaims)
+}
+
+// validateBoundCIDRs returns an error response if the client address is not
+// within the CIDR blocks bound to the role
+func validateBoundCIDRs(req *logical.Request, role *jwtRole) *logical.Response {
+	if len(role.BoundCIDRs) == 0 {
+		return nil
+	}
+
+	if req.Connection == nil || req.Connection.RemoteAddr == "" {
+		return logical.ErrorResponse("failed to get connection information")
+	}
+
+	belongs, err := cidrutil.IPBelongsToCIDRBlocksSlice(req.Connection.RemoteAddr, role.BoundCIDRs)
+	if err != nil || !belongs {
+		return logical.ErrorResponse(fmt.Sprintf("source address %q unauthorized through CIDR restrictions on the role", req.Connection.RemoteAddr))
+	}
+
+	return nil
+}
+
+// verifyToken checks the signature of the token and validates its claims
+// against the config and the role. If a client ID is given, the token must
+// have been issued to that client. The claims of the token are returned.
+func (b *backend) verifyToken(config *jwtConfig, role *jwtRole, rawToken, clientID string) (map[string]interface{}, error) {
+	token, err := jwt.ParseSigned(rawToken)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing token: %v", err)
+	}
+
+	keys, provider, err := b.getKeySet(config)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := jwt.Claims{}
+	allClaims := make(map[string]interface{})
+	if err := keys.verify(token, &claims, &allClaims); err != nil {
+		return nil, fmt.Errorf("error validating signature: %v", err)
+	}
+
+	if claims.Expiry == 0 {
+		return nil, errors.New("token is missing the 'exp' claim")
+	}
+
+	expected := jwt.Expected{
+		Issuer:  config.BoundIssuer,
+		Subject: role.BoundSubject,
+		Time:    time.Now(),
+	}
+
+	// Tokens of an OIDC provider must have been issued by that provider
+	if expected.Issuer == "" && provider != nil {
+		expected.Issuer = provider.Issuer
+	}
+
+	if err := claims.ValidateWithLeeway(expected, role.leeway()); err != nil {
+		return nil, fmt.Errorf("error validating claims: %v", err)
+	}
+
+	switch {
+	case clientID != "":
+		if !claims.Audience.Contains(clientID) {
+			return nil, errors.New("aud claim does not match the configured client ID")
+		}
+
+	case len(role.BoundAudiences) != 0:
+		if !audienceMatches(claims.Audience, role.BoundAudiences) {
+			return nil, errors.New("aud claim does not match any bound audience")
+		}
+
+	case len(claims.Audience) != 0:
+		return nil, errors.New("audience claim found in JWT but no audiences bound to the role")
+	}
+
+	if err := validateBoundClaims(role.BoundClaims, allClaims); err != nil {
+		return nil, fmt.Errorf("error validating claims: %v", err)
+	}
+
+	return allClaims, nil
+}
+
+func audienceMatches(audience jwt.Audience, boundAudiences []string) bool {
+	for _, v := range boundAudiences {
+		if audience.Contains(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// authResponse builds the login response for the validated claims of a
+// token
+func (b *backend) authResponse(roleName string, role *jwtRole, allClaims map[string]interface{}) (*logical.Response, error) {
+	userClaimRaw := getClaim(allClaims, role.UserClaim)
+	if userClaimRaw == nil {
+		return logical.ErrorResponse(fmt.Sprintf("claim %q not found in token", role.UserClaim)), nil
+	}
+	userName, ok := claimString(userClaimRaw)
+	if !ok || userName == "" {
+		return logical.ErrorResponse(fmt.Sprintf("claim %q could not be converted to string", role.UserClaim)), nil
+	}
+
+	metadata, err := extractMetadata(allClaims, role.ClaimMappings)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	groupAliases, err := extractGroupAliases(allClaims, role.GroupsClaim)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	return &logical.Response{
+		Auth: &logical.Auth{
+			Policies:    role.Policies,
+			DisplayName: userName,
+			Period:      role.Period,
+			Alias: &logical.Alias{
+				Name:     userName,
+				Metadata: metadata,
+			},
+			GroupAliases: groupAliases,
+			InternalData: map[string]interface{}{
+				"role": roleName,
+			},
+			Metadata: map[string]string{
+				"role": roleName,
+			},
+			LeaseOptions: logical.LeaseOptions{
+				Renewable: true,
+				TTL:       role.TTL,
+			},
+		},
+	}, nil
+}
+
+func (b *backend) pathLoginRenew(req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	roleName, ok := req.Auth.InternalData["role"].(string)
+	if !ok || roleName == "" {
+		return nil, errors.New("failed to fetch role during renewal")
+	}
+
+	// Ensure that the Role still exists.
+	role, err := b.role(req.Storage, roleName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to validate role %s during renewal: %v", roleName, err)
+	}
+	if role == nil {
+		return nil, fmt.Errorf("role %s does not exist during renewal", roleName)
+	}
+
+	if !policyutil.EquivalentPolicies(role.Policies, req.Auth.Policies) {
+		return nil, errors.New("policies have changed, not renewing")
+	}
+
+	// If 'Period' is set on the Role, the token should never expire.
+	// Replenish the TTL with 'Period's value.
+	if role.Period > time.Duration(0) {
+		req.Auth.TTL = role.Period
+		return &logical.Response{Auth: req.Auth}, nil
+	}
+
+	return framework.LeaseExtend(role.TTL, role.MaxTTL, b.System())(req, data)
+}
+
+const (
+	pathLoginHelpSyn = `
+Authenticates to Vault using a JWT (or OIDC) token.
+`
+	pathLoginHelpDesc = `
+Authenticates JWTs. The signature of the token is validated against the
+configured keys, and its claims against the bindings of the given role.
+`
+)
@@ -0,0 +1,235 @@
+package jwt
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	uuid "github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/helper/strutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+	"golang.org/x/net/context"
+	"golang.org/x/oauth2"
+)
+
+// oidcRequestTimeout is how long the authorization code flow may take
+// between the creation of the authorization URL and the callback
+const oidcRequestTimeout = 10 * time.Minute
+
+func pathOIDC(b *backend) []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: `oidc/auth_url`,
+			Fields: map[string]*framework.FieldSchema{
+				"role": {
+					Type:        framework.TypeString,
+					Description: "The role to issue an OIDC authorization URL against.",
+				},
+				"redirect_uri": {
+					Type:        framework.TypeString,
+					Description: "The OAuth redirect_uri to use in the authorization URL.",
+				},
+			},
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.UpdateOperation: b.pathOIDCAuthURL,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(oidcHelp["auth_url"][0]),
+			HelpDescription: strings.TrimSpace(oidcHelp["auth_url"][1]),
+		},
+		{
+			Pattern: `oidc/callback`,
+			Fields: map[string]*framework.FieldSchema{
+				"state": {
+					Type:        framework.TypeString,
+					Description: "The state returned by the OIDC provider.",
+				},
+				"code": {
+					Type:        framework.TypeString,
+					Description: "The authorization code returned by the OIDC provider.",
+				},
+			},
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.UpdateOperation: b.pathOIDCCallback,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(oidcHelp["callback"][0]),
+			HelpDescription: strings.TrimSpace(oidcHelp["callback"][1]),
+		},
+	}
+}
+
+// oauth2Config returns the OAuth2 configuration of the client of the OIDC
+// provider for the given role and redirect URI
+func oauth2Config(config *jwtConfig, provider *oidcProvider, role *jwtRole, redirectURI string) *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     config.OIDCClientID,
+		ClientSecret: config.OIDCClientSecret,
+		RedirectURL:  redirectURI,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  provider.AuthorizationEndpoint,
+			TokenURL: provider.TokenEndpoint,
+		},
+		Scopes: append([]string{"openid"}, role.OIDCScopes...),
+	}
+}
+
+// oidcRole loads the config and the given OIDC role, and returns the
+// metadata of the OIDC provider. An error response is returned if the
+// backend or the role can't be used for the authorization code flow.
+func (b *backend) oidcRole(req *logical.Request, roleName string) (*jwtConfig, *jwtRole, *oidcProvider, *logical.Response, error) {
+	config, err := b.config(req.Storage)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	if config == nil {
+		return nil, nil, nil, logical.ErrorResponse("could not load configuration"), nil
+	}
+	if config.OIDCDiscoveryURL == "" || config.OIDCClientID == "" {
+		return nil, nil, nil, logical.ErrorResponse("OIDC discovery and an OIDC client ID must be configured to use the OIDC flow"), nil
+	}
+
+	if roleName == "" {
+		roleName = config.DefaultRole
+	}
+	if roleName == "" {
+		return nil, nil, nil, logical.ErrorResponse("missing role"), nil
+	}
+
+	role, err := b.role(req.Storage, roleName)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	if role == nil {
+		return nil, nil, nil, logical.ErrorResponse(fmt.Sprintf("role %q could not be found", roleName)), nil
+	}
+	if role.RoleType != roleTypeOIDC {
+		return nil, nil, nil, logical.ErrorResponse(fmt.Sprintf("role %q is not an oidc role", roleName)), nil
+	}
+
+	_, provider, err := b.getKeySet(config)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	if provider == nil {
+		return nil, nil, nil, nil, errors.New("missing OIDC provider metadata")
+	}
+
+	return config, role, provider, nil, nil
+}
+
+func (b *backend) pathOIDCAuthURL(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	roleName := d.Get("role").(string)
+	config, role, provider, resp, err := b.oidcRole(req, roleName)
+	if resp != nil || err != nil {
+		return resp, err
+	}
+	if roleName == "" {
+		roleName = config.DefaultRole
+	}
+
+	redirectURI := d.Get("redirect_uri").(string)
+	if redirectURI == "" {
+		return logical.ErrorResponse("missing redirect_uri"), nil
+	}
+	if !strutil.StrListContains(role.AllowedRedirectURIs, redirectURI) {
+		return logical.ErrorResponse(fmt.Sprintf("unauthorized redirect_uri: %s", redirectURI)), nil
+	}
+
+	state, err := uuid.GenerateUUID()
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := uuid.GenerateUUID()
+	if err != nil {
+		return nil, err
+	}
+
+	b.storeOIDCRequest(state, &oidcRequest{
+		role:        roleName,
+		nonce:       nonce,
+		redirectURI: redirectURI,
+		expiration:  time.Now().Add(oidcRequestTimeout),
+	})
+
+	authURL := oauth2Config(config, provider, role, redirectURI).AuthCodeURL(state, oauth2.SetAuthURLParam("nonce", nonce))
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"auth_url": authURL,
+		},
+	}, nil
+}
+
+func (b *backend) pathOIDCCallback(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	state := d.Get("state").(string)
+	oidcReq := b.takeOIDCRequest(state)
+	if oidcReq == nil {
+		return logical.ErrorResponse("expired or missing OAuth state"), nil
+	}
+
+	code := d.Get("code").(string)
+	if code == "" {
+		return logical.ErrorResponse("missing authorization code"), nil
+	}
+
+	config, role, provider, resp, err := b.oidcRole(req, oidcReq.role)
+	if resp != nil || err != nil {
+		return resp, err
+	}
+
+	if resp := validateBoundCIDRs(req, role); resp != nil {
+		return resp, nil
+	}
+
+	client, err := httpClient(config.OIDCDiscoveryCAPEM)
+	if err != nil {
+		return nil, err
+	}
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, client)
+
+	oauth2Token, err := oauth2Config(config, provider, role, oidcReq.redirectURI).Exchange(ctx, code)
+	if err != nil {
+		return logical.ErrorResponse(fmt.Sprintf("error exchanging oidc code: %v", err)), nil
+	}
+
+	rawIDToken, ok := oauth2Token.Extra("id_token").(string)
+	if !ok || rawIDToken == "" {
+		return logical.ErrorResponse("no id_token found in response"), nil
+	}
+
+	allClaims, err := b.verifyToken(config, role, rawIDToken, config.OIDCClientID)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	if nonce, _ := allClaims["nonce"].(string); nonce != oidcReq.nonce {
+		return logical.ErrorResponse("invalid ID token nonce"), nil
+	}
+
+	return b.authResponse(oidcReq.role, role, allClaims)
+}
+
+var oidcHelp = map[string][2]string{
+	"auth_url": {
+		"Request an authorization URL to start an OIDC login flow.",
+		`
+Returns the URL of the OIDC provider to which the user must be sent to
+authenticate. After authentication, the provider redirects the user to the
+given redirect URI with a code and a state, which must be sent to the
+callback endpoint to complete the login.
+`,
+	},
+	"callback": {
+		"Callback endpoint to complete an OIDC login.",
+		`
+Exchanges the authorization code for an ID token with the OIDC provider,
+validates the ID token against the role of the login flow and issues a
+Vault token.
+`,
+	},
+}
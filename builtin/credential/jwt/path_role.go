@@ -0,0 +1,408 @@
+package jwt
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/helper/cidrutil"
+	"github.com/hashicorp/vault/helper/policyutil"
+	"github.com/hashicorp/vault/helper/strutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+	"github.com/mitchellh/mapstructure"
+)
+
+const (
+	roleTypeJWT  = "jwt"
+	roleTypeOIDC = "oidc"
+
+	// defaultClockSkewLeeway is used when the role does not set a leeway
+	defaultClockSkewLeeway = 60 * time.Second
+)
+
+func pathRoleList(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "role/?",
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ListOperation: b.pathRoleList,
+		},
+		HelpSynopsis:    strings.TrimSpace(roleHelp["role-list"][0]),
+		HelpDescription: strings.TrimSpace(roleHelp["role-list"][1]),
+	}
+}
+
+func pathRole(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "role/" + framework.GenericNameRegex("name"),
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:        framework.TypeString,
+				Description: "Name of the role.",
+			},
+			"role_type": {
+				Type:        framework.TypeString,
+				Description: `Type of the role, either "jwt" or "oidc". Defaults to "jwt".`,
+			},
+			"policies": {
+				Type:        framework.TypeCommaStringSlice,
+				Description: "List of policies on the role.",
+			},
+			"ttl": {
+				Type:        framework.TypeDurationSecond,
+				Description: "Duration in seconds after which the issued token should expire. Defaults to 0, in which case the value will fall back to the system/mount defaults.",
+			},
+			"max_ttl": {
+				Type:        framework.TypeDurationSecond,
+				Description: "Duration in seconds after which the issued token should not be allowed to be renewed. Defaults to 0, in which case the value will fall back to the system/mount defaults.",
+			},
+			"period": {
+				Type:    framework.TypeDurationSecond,
+				Default: 0,
+				Description: `If set, indicates that the token generated using this role
+should never expire. The token should be renewed within the
+duration specified by this value. At each renewal, the token's
+TTL will be set to the value of this parameter.`,
+			},
+			"bound_cidrs": {
+				Type:        framework.TypeCommaStringSlice,
+				Description: "Comma separated string or list of CIDR blocks. If set, specifies the blocks of IP addresses which can perform the login operation.",
+			},
+			"bound_audiences": {
+				Type:        framework.TypeCommaStringSlice,
+				Description: "Comma-separated list of 'aud' claims that are valid for login; any match is sufficient.",
+			},
+			"bound_subject": {
+				Type:        framework.TypeString,
+				Description: "The 'sub' claim that is valid for login. Optional.",
+			},
+			"bound_claims": {
+				Type:        framework.TypeMap,
+				Description: "Map of claims and values which must match for login. A value may be a list, in which case any element matches.",
+			},
+			"claim_mappings": {
+				Type:        framework.TypeMap,
+				Description: "Mappings of claims (key) that will be copied to a metadata field (value) of the identity alias.",
+			},
+			"user_claim": {
+				Type:        framework.TypeString,
+				Description: "The claim to use for the Identity entity alias name.",
+			},
+			"groups_claim": {
+				Type:        framework.TypeString,
+				Description: "The claim to use for the Identity group alias names.",
+			},
+			"clock_skew_leeway": {
+				Type:        framework.TypeDurationSecond,
+				Description: "Duration in seconds of leeway when validating the 'exp' and 'nbf' claims, to account for clock skew. Defaults to 60 seconds; set to a negative value to use no leeway.",
+			},
+			"allowed_redirect_uris": {
+				Type:        framework.TypeCommaStringSlice,
+				Description: "Comma-separated list of allowed values for the redirect URI of the OIDC authorization code flow.",
+			},
+			"oidc_scopes": {
+				Type:        framework.TypeCommaStringSlice,
+				Description: "Comma-separated list of OIDC scopes, in addition to 'openid', to request in the OIDC authorization code flow.",
+			},
+		},
+		ExistenceCheck: b.pathRoleExistenceCheck,
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.CreateOperation: b.pathRoleCreateUpdate,
+			logical.UpdateOperation: b.pathRoleCreateUpdate,
+			logical.ReadOperation:   b.pathRoleRead,
+			logical.DeleteOperation: b.pathRoleDelete,
+		},
+		HelpSynopsis:    strings.TrimSpace(roleHelp["role"][0]),
+		HelpDescription: strings.TrimSpace(roleHelp["role"][1]),
+	}
+}
+
+// jwtRole is the storage entry of a role
+type jwtRole struct {
+	RoleType string `json:"role_type"`
+
+	// Policies that are to be required by the token to access this role
+	Policies []string `json:"policies"`
+
+	// TTL and MaxTTL of the issued tokens
+	TTL    time.Duration `json:"ttl"`
+	MaxTTL time.Duration `json:"max_ttl"`
+
+	// Period, if set, indicates that the token generated using this role
+	// should never expire. The token should be renewed within the duration
+	// specified by this value.
+	Period time.Duration `json:"period"`
+
+	// BoundCIDRs are the blocks of IP addresses which can log in
+	BoundCIDRs []string `json:"bound_cidrs"`
+
+	// Role binding properties
+	BoundAudiences []string               `json:"bound_audiences"`
+	BoundSubject   string                 `json:"bound_subject"`
+	BoundClaims    map[string]interface{} `json:"bound_claims"`
+	ClaimMappings  map[string]string      `json:"claim_mappings"`
+	UserClaim      string                 `json:"user_claim"`
+	GroupsClaim    string                 `json:"groups_claim"`
+
+	// ClockSkewLeeway is the leeway used to validate the time claims. Zero
+	// means the default leeway, and a negative value means no leeway.
+	ClockSkewLeeway time.Duration `json:"clock_skew_leeway"`
+
+	// OIDC authorization code flow properties
+	AllowedRedirectURIs []string `json:"allowed_redirect_uris"`
+	OIDCScopes          []string `json:"oidc_scopes"`
+}
+
+// leeway returns the leeway to use when validating the time claims
+func (r *jwtRole) leeway() time.Duration {
+	switch {
+	case r.ClockSkewLeeway == 0:
+		return defaultClockSkewLeeway
+	case r.ClockSkewLeeway < 0:
+		return 0
+	default:
+		return r.ClockSkewLeeway
+	}
+}
+
+// role takes a storage backend and the name and returns the role's storage
+// entry
+func (b *backend) role(s logical.Storage, name string) (*jwtRole, error) {
+	raw, err := s.Get(rolePrefix + strings.ToLower(name))
+	if err != nil {
+		return nil, err
+	}
+	if raw == nil {
+		return nil, nil
+	}
+
+	role := new(jwtRole)
+	if err := raw.DecodeJSON(role); err != nil {
+		return nil, err
+	}
+
+	return role, nil
+}
+
+func (b *backend) pathRoleExistenceCheck(req *logical.Request, data *framework.FieldData) (bool, error) {
+	role, err := b.role(req.Storage, data.Get("name").(string))
+	if err != nil {
+		return false, err
+	}
+	return role != nil, nil
+}
+
+func (b *backend) pathRoleList(req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	roles, err := req.Storage.List(rolePrefix)
+	if err != nil {
+		return nil, err
+	}
+	return logical.ListResponse(roles), nil
+}
+
+func (b *backend) pathRoleRead(req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	role, err := b.role(req.Storage, data.Get("name").(string))
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, nil
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"role_type":             role.RoleType,
+			"policies":              role.Policies,
+			"ttl":                   int64(role.TTL.Seconds()),
+			"max_ttl":               int64(role.MaxTTL.Seconds()),
+			"period":                int64(role.Period.Seconds()),
+			"bound_cidrs":           role.BoundCIDRs,
+			"bound_audiences":       role.BoundAudiences,
+			"bound_subject":         role.BoundSubject,
+			"bound_claims":          role.BoundClaims,
+			"claim_mappings":        role.ClaimMappings,
+			"user_claim":            role.UserClaim,
+			"groups_claim":          role.GroupsClaim,
+			"clock_skew_leeway":     int64(role.ClockSkewLeeway.Seconds()),
+			"allowed_redirect_uris": role.AllowedRedirectURIs,
+			"oidc_scopes":           role.OIDCScopes,
+		},
+	}, nil
+}
+
+func (b *backend) pathRoleDelete(req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	roleName := data.Get("name").(string)
+	if roleName == "" {
+		return logical.ErrorResponse("role name required"), nil
+	}
+
+	if err := req.Storage.Delete(rolePrefix + strings.ToLower(roleName)); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+func (b *backend) pathRoleCreateUpdate(req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	roleName := data.Get("name").(string)
+	if roleName == "" {
+		return logical.ErrorResponse("missing role name"), nil
+	}
+
+	// Check if the role already exists
+	role, err := b.role(req.Storage, roleName)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create a new entry object if this is a CreateOperation
+	if role == nil {
+		if req.Operation == logical.UpdateOperation {
+			return nil, errors.New("role entry not found during update operation")
+		}
+		role = new(jwtRole)
+	}
+
+	if roleTypeRaw, ok := data.GetOk("role_type"); ok {
+		role.RoleType = roleTypeRaw.(string)
+	}
+	switch role.RoleType {
+	case "":
+		role.RoleType = roleTypeJWT
+	case roleTypeJWT, roleTypeOIDC:
+	default:
+		return logical.ErrorResponse(fmt.Sprintf("invalid role_type %q", role.RoleType)), nil
+	}
+
+	if policiesRaw, ok := data.GetOk("policies"); ok {
+		role.Policies = policyutil.ParsePolicies(policiesRaw)
+	}
+
+	periodRaw, ok := data.GetOk("period")
+	if ok {
+		role.Period = time.Duration(periodRaw.(int)) * time.Second
+	}
+	if role.Period > b.System().MaxLeaseTTL() {
+		return logical.ErrorResponse(fmt.Sprintf("'period' of %q is greater than the backend's maximum lease TTL of %q", role.Period.String(), b.System().MaxLeaseTTL().String())), nil
+	}
+
+	if tokenTTLRaw, ok := data.GetOk("ttl"); ok {
+		role.TTL = time.Duration(tokenTTLRaw.(int)) * time.Second
+	}
+
+	if tokenMaxTTLRaw, ok := data.GetOk("max_ttl"); ok {
+		role.MaxTTL = time.Duration(tokenMaxTTLRaw.(int)) * time.Second
+	}
+
+	if boundCIDRsRaw, ok := data.GetOk("bound_cidrs"); ok {
+		role.BoundCIDRs = boundCIDRsRaw.([]string)
+	}
+	if len(role.BoundCIDRs) != 0 {
+		valid, err := cidrutil.ValidateCIDRListSlice(role.BoundCIDRs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to validate CIDR blocks: %v", err)
+		}
+		if !valid {
+			return logical.ErrorResponse("invalid CIDR blocks"), nil
+		}
+	}
+
+	if boundAudiences, ok := data.GetOk("bound_audiences"); ok {
+		role.BoundAudiences = boundAudiences.([]string)
+	}
+
+	if boundSubject, ok := data.GetOk("bound_subject"); ok {
+		role.BoundSubject = boundSubject.(string)
+	}
+
+	if boundClaimsRaw, ok := data.GetOk("bound_claims"); ok {
+		role.BoundClaims = boundClaimsRaw.(map[string]interface{})
+		for claim, value := range role.BoundClaims {
+			if _, err := boundClaimValues(value); err != nil {
+				return logical.ErrorResponse(fmt.Sprintf("invalid value for bound claim %q: %v", claim, err)), nil
+			}
+		}
+	}
+
+	if claimMappingsRaw, ok := data.GetOk("claim_mappings"); ok {
+		var claimMappings map[string]string
+		if err := mapstructure.Decode(claimMappingsRaw, &claimMappings); err != nil {
+			return logical.ErrorResponse("claim_mappings must be a map of strings"), nil
+		}
+
+		// Sanity check mappings for duplicates
+		targets := make(map[string]bool)
+		for _, metadataKey := range claimMappings {
+			if targets[metadataKey] {
+				return logical.ErrorResponse(fmt.Sprintf("multiple keys are mapped to metadata key %q", metadataKey)), nil
+			}
+			targets[metadataKey] = true
+		}
+
+		role.ClaimMappings = claimMappings
+	}
+
+	if userClaim, ok := data.GetOk("user_claim"); ok {
+		role.UserClaim = userClaim.(string)
+	}
+	if role.UserClaim == "" {
+		return logical.ErrorResponse("a user claim must be defined on the role"), nil
+	}
+
+	if groupsClaim, ok := data.GetOk("groups_claim"); ok {
+		role.GroupsClaim = groupsClaim.(string)
+	}
+
+	if clockSkewLeeway, ok := data.GetOk("clock_skew_leeway"); ok {
+		role.ClockSkewLeeway = time.Duration(clockSkewLeeway.(int)) * time.Second
+	}
+
+	if allowedRedirectURIs, ok := data.GetOk("allowed_redirect_uris"); ok {
+		role.AllowedRedirectURIs = allowedRedirectURIs.([]string)
+	}
+
+	if oidcScopes, ok := data.GetOk("oidc_scopes"); ok {
+		role.OIDCScopes = strutil.RemoveDuplicates(oidcScopes.([]string), false)
+	}
+
+	if role.RoleType == roleTypeOIDC && len(role.AllowedRedirectURIs) == 0 {
+		return logical.ErrorResponse("'allowed_redirect_uris' must be set if 'role_type' is 'oidc'"), nil
+	}
+
+	// ID tokens used with OIDC roles are always bound to the configured
+	// client ID. JWT roles must bind at least one claim, so that not every
+	// token signed by the configured keys can be used to log in.
+	if role.RoleType == roleTypeJWT && len(role.BoundAudiences) == 0 && role.BoundSubject == "" && len(role.BoundClaims) == 0 {
+		return logical.ErrorResponse("must have at least one bound constraint when creating/updating a role"), nil
+	}
+
+	entry, err := logical.StorageEntryJSON(rolePrefix+strings.ToLower(roleName), role)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(entry); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+var roleHelp = map[string][2]string{
+	"role-list": {
+		"Lists all the roles registered with the backend.",
+		"The list will contain the names of the roles.",
+	},
+	"role": {
+		"Register a role with the backend.",
+		`
+A role is required to authenticate with this backend. The role binds
+JWT token information with token policies and settings.
+The bindings, token polices and token settings can all be configured
+using this endpoint.
+
+Roles of type "oidc" are used with the OIDC authorization code flow. The
+ID tokens of these roles must be issued to the configured client ID.
+`,
+	},
+}
@@ -0,0 +1,70 @@
+package kubernetes
+
+import (
+	"strings"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+const (
+	configPath string = "config"
+	rolePrefix string = "role/"
+)
+
+func Factory(conf *logical.BackendConfig) (logical.Backend, error) {
+	b := Backend()
+	if err := b.Setup(conf); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+type backend struct {
+	*framework.Backend
+
+	// reviewFactory builds the client used to verify service account tokens
+	// with the TokenReview API. It defaults to calling the configured
+	// Kubernetes API server, and can be swapped out in tests.
+	reviewFactory tokenReviewFactory
+}
+
+func Backend() *backend {
+	b := &backend{
+		reviewFactory: tokenReviewAPIFactory,
+	}
+
+	b.Backend = &framework.Backend{
+		Help:        strings.TrimSpace(backendHelp),
+		BackendType: logical.TypeCredential,
+		AuthRenew:   b.pathLoginRenew,
+
+		PathsSpecial: &logical.Paths{
+			Unauthenticated: []string{
+				"login",
+			},
+			SealWrapStorage: []string{
+				configPath,
+			},
+		},
+
+		Paths: []*framework.Path{
+			pathConfig(b),
+			pathRoleList(b),
+			pathRole(b),
+			pathLogin(b),
+		},
+	}
+
+	return b
+}
+
+const backendHelp = `
+The Kubernetes credential provider allows authentication with Kubernetes
+service account tokens.
+
+The signature of a token may be checked against the public keys of the
+cluster, and the token is then verified with the TokenReview API of the
+Kubernetes API server. Roles bind the names and namespaces of the service
+accounts that may log in, and the policies of the issued tokens.
+`
@@ -0,0 +1,503 @@
+package kubernetes
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+	jose "gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+const (
+	testName      = "vault-auth"
+	testNamespace = "default"
+	testUID       = "d77f89bc-9055-11e7-a068-0800276d99bf"
+)
+
+func getBackend(t *testing.T) (*backend, logical.Storage) {
+	config := &logical.BackendConfig{
+		System: &logical.StaticSystemView{
+			DefaultLeaseTTLVal: time.Hour * 12,
+			MaxLeaseTTLVal:     time.Hour * 24,
+		},
+		StorageView: &logical.InmemStorage{},
+	}
+	b, err := Factory(config)
+	if err != nil {
+		t.Fatalf("unable to create backend: %v", err)
+	}
+
+	return b.(*backend), config.StorageView
+}
+
+func request(t *testing.T, b *backend, storage logical.Storage, op logical.Operation, path string, data map[string]interface{}) *logical.Response {
+	resp, err := b.HandleRequest(&logical.Request{
+		Operation: op,
+		Path:      path,
+		Storage:   storage,
+		Data:      data,
+	})
+	if err != nil {
+		t.Fatalf("err: %v, resp: %#v", err, resp)
+	}
+	return resp
+}
+
+func expectError(t *testing.T, resp *logical.Response, msg string) {
+	if resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error response (%s), got: %#v", msg, resp)
+	}
+}
+
+func expectSuccess(t *testing.T, resp *logical.Response) {
+	if resp != nil && resp.IsError() {
+		t.Fatalf("unexpected error response: %#v", resp.Data)
+	}
+}
+
+func newKey(t *testing.T) *ecdsa.PrivateKey {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return priv
+}
+
+func publicKeyPEM(t *testing.T, priv *ecdsa.PrivateKey) string {
+	der, err := x509.MarshalPKIXPublicKey(&priv.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{
+		Type:  "PUBLIC KEY",
+		Bytes: der,
+	}))
+}
+
+// serviceAccountToken signs a token with the claims Kubernetes sets on
+// service account tokens
+func serviceAccountToken(t *testing.T, priv *ecdsa.PrivateKey, name, namespace, uid string) string {
+	sig, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.ES256, Key: priv}, (&jose.SignerOptions{}).WithType("JWT"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	raw, err := jwt.Signed(sig).Claims(map[string]interface{}{
+		"iss":                                    "kubernetes/serviceaccount",
+		"sub":                                    fmt.Sprintf("system:serviceaccount:%s:%s", namespace, name),
+		"kubernetes.io/serviceaccount/namespace": namespace,
+		"kubernetes.io/serviceaccount/secret.name":          name + "-token-t8ldl",
+		"kubernetes.io/serviceaccount/service-account.name": name,
+		"kubernetes.io/serviceaccount/service-account.uid":  uid,
+	}).CompactSerialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+// testAPIServer is a stand-in for the TokenReview API of a Kubernetes API
+// server. Tokens are reviewed with the function set by the test.
+type testAPIServer struct {
+	server *httptest.Server
+
+	l        sync.Mutex
+	bearer   string
+	review   func(token string) (int, interface{})
+	reviewed []string
+}
+
+func newTestAPIServer(t *testing.T) *testAPIServer {
+	s := &testAPIServer{}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/apis/authentication.k8s.io/v1/tokenreviews", func(w http.ResponseWriter, r *http.Request) {
+		s.l.Lock()
+		defer s.l.Unlock()
+
+		var tr tokenReview
+		if err := json.NewDecoder(r.Body).Decode(&tr); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		s.bearer = r.Header.Get("Authorization")
+		s.reviewed = append(s.reviewed, tr.Spec.Token)
+
+		code, body := s.review(tr.Spec.Token)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(body)
+	})
+	s.server = httptest.NewTLSServer(mux)
+
+	// Authenticate every token as the test service account by default
+	s.review = func(string) (int, interface{}) {
+		return http.StatusCreated, reviewStatus(true, testName, testNamespace, testUID)
+	}
+
+	return s
+}
+
+func (s *testAPIServer) setReview(f func(token string) (int, interface{})) {
+	s.l.Lock()
+	defer s.l.Unlock()
+	s.review = f
+}
+
+func (s *testAPIServer) caPEM() string {
+	return string(pem.EncodeToMemory(&pem.Block{
+		Type:  "CERTIFICATE",
+		Bytes: s.server.Certificate().Raw,
+	}))
+}
+
+func reviewStatus(authenticated bool, name, namespace, uid string) *tokenReview {
+	return &tokenReview{
+		APIVersion: "authentication.k8s.io/v1",
+		Kind:       "TokenReview",
+		Status: tokenReviewStatus{
+			Authenticated: authenticated,
+			User: tokenReviewUser{
+				Username: fmt.Sprintf("system:serviceaccount:%s:%s", namespace, name),
+				UID:      uid,
+			},
+		},
+	}
+}
+
+func TestConfig(t *testing.T) {
+	b, storage := getBackend(t)
+	priv := newKey(t)
+
+	resp := request(t, b, storage, logical.UpdateOperation, "config", map[string]interface{}{
+		"pem_keys": publicKeyPEM(t, priv),
+	})
+	expectError(t, resp, "missing host")
+
+	resp = request(t, b, storage, logical.UpdateOperation, "config", map[string]interface{}{
+		"kubernetes_host": "https://192.168.99.100:8443",
+		"pem_keys":        "bad",
+	})
+	expectError(t, resp, "bad pem key")
+
+	resp = request(t, b, storage, logical.UpdateOperation, "config", map[string]interface{}{
+		"kubernetes_host":    "https://192.168.99.100:8443",
+		"token_reviewer_jwt": "bad",
+	})
+	expectError(t, resp, "bad reviewer JWT")
+
+	resp = request(t, b, storage, logical.UpdateOperation, "config", map[string]interface{}{
+		"kubernetes_host":    "https://192.168.99.100:8443",
+		"kubernetes_ca_cert": "bad",
+	})
+	expectError(t, resp, "bad CA cert")
+
+	resp = request(t, b, storage, logical.UpdateOperation, "config", map[string]interface{}{
+		"kubernetes_host":    "https://192.168.99.100:8443",
+		"pem_keys":           publicKeyPEM(t, priv),
+		"token_reviewer_jwt": serviceAccountToken(t, priv, "reviewer", "kube-system", "uid"),
+	})
+	expectSuccess(t, resp)
+
+	resp = request(t, b, storage, logical.ReadOperation, "config", nil)
+	expected := map[string]interface{}{
+		"kubernetes_host":    "https://192.168.99.100:8443",
+		"kubernetes_ca_cert": "",
+		"pem_keys":           []string{strings.TrimSpace(publicKeyPEM(t, priv))},
+	}
+	if !reflect.DeepEqual(resp.Data, expected) {
+		t.Fatalf("bad: expected:\n%#v\nactual:\n%#v", expected, resp.Data)
+	}
+}
+
+func TestRole(t *testing.T) {
+	b, storage := getBackend(t)
+
+	cases := []struct {
+		name       string
+		names      string
+		namespaces string
+	}{
+		{"no names", "", "default"},
+		{"no namespaces", "vault-auth", ""},
+		{"mixed names", "*,vault-auth", "default"},
+		{"mixed namespaces", "vault-auth", "*,default"},
+		{"all wildcards", "*", "*"},
+	}
+	for _, tc := range cases {
+		resp := request(t, b, storage, logical.CreateOperation, "role/demo", map[string]interface{}{
+			"bound_service_account_names":      tc.names,
+			"bound_service_account_namespaces": tc.namespaces,
+		})
+		expectError(t, resp, tc.name)
+	}
+
+	resp := request(t, b, storage, logical.CreateOperation, "role/demo", map[string]interface{}{
+		"bound_service_account_names":      "vault-auth",
+		"bound_service_account_namespaces": "*",
+		"ttl":                              "2h",
+		"max_ttl":                          "1h",
+	})
+	expectError(t, resp, "ttl greater than max_ttl")
+
+	resp = request(t, b, storage, logical.CreateOperation, "role/demo", map[string]interface{}{
+		"bound_service_account_names":      "vault-auth",
+		"bound_service_account_namespaces": "*",
+		"policies":                         "dev,prod",
+		"num_uses":                         12,
+		"ttl":                              "1h",
+		"max_ttl":                          "2h",
+		"period":                           "30m",
+	})
+	expectSuccess(t, resp)
+
+	resp = request(t, b, storage, logical.ReadOperation, "role/demo", nil)
+	expected := map[string]interface{}{
+		"bound_service_account_names":      []string{"vault-auth"},
+		"bound_service_account_namespaces": []string{"*"},
+		"policies":                         []string{"dev", "prod"},
+		"num_uses":                         12,
+		"ttl":                              int64(3600),
+		"max_ttl":                          int64(7200),
+		"period":                           int64(1800),
+	}
+	if !reflect.DeepEqual(resp.Data, expected) {
+		t.Fatalf("bad: expected:\n%#v\nactual:\n%#v", expected, resp.Data)
+	}
+
+	resp = request(t, b, storage, logical.ListOperation, "role/", nil)
+	if keys := resp.Data["keys"].([]string); !reflect.DeepEqual(keys, []string{"demo"}) {
+		t.Fatalf("bad: %#v", keys)
+	}
+
+	request(t, b, storage, logical.DeleteOperation, "role/demo", nil)
+	if resp = request(t, b, storage, logical.ReadOperation, "role/demo", nil); resp != nil {
+		t.Fatalf("expected role to be deleted, got: %#v", resp)
+	}
+}
+
+func setupBackend(t *testing.T, s *testAPIServer, priv *ecdsa.PrivateKey) (*backend, logical.Storage) {
+	b, storage := getBackend(t)
+
+	resp := request(t, b, storage, logical.UpdateOperation, "config", map[string]interface{}{
+		"kubernetes_host":    s.server.URL,
+		"kubernetes_ca_cert": s.caPEM(),
+		"pem_keys":           publicKeyPEM(t, priv),
+	})
+	expectSuccess(t, resp)
+
+	resp = request(t, b, storage, logical.CreateOperation, "role/demo", map[string]interface{}{
+		"bound_service_account_names":      testName,
+		"bound_service_account_namespaces": testNamespace,
+		"policies":                         "test",
+		"ttl":                              "1h",
+	})
+	expectSuccess(t, resp)
+
+	return b, storage
+}
+
+func TestLogin(t *testing.T) {
+	s := newTestAPIServer(t)
+	defer s.server.Close()
+
+	priv := newKey(t)
+	b, storage := setupBackend(t, s, priv)
+
+	token := serviceAccountToken(t, priv, testName, testNamespace, testUID)
+	resp := request(t, b, storage, logical.UpdateOperation, "login", map[string]interface{}{
+		"role": "demo",
+		"jwt":  token,
+	})
+	expectSuccess(t, resp)
+
+	auth := resp.Auth
+	if auth == nil {
+		t.Fatal("expected auth")
+	}
+	if auth.Alias.Name != testUID {
+		t.Fatalf("bad alias name: %q", auth.Alias.Name)
+	}
+	expectedMetadata := map[string]string{
+		"service_account_name":      testName,
+		"service_account_namespace": testNamespace,
+	}
+	if !reflect.DeepEqual(auth.Alias.Metadata, expectedMetadata) {
+		t.Fatalf("bad alias metadata: %#v", auth.Alias.Metadata)
+	}
+	if auth.DisplayName != testName || auth.Metadata["role"] != "demo" || auth.Metadata["service_account_uid"] != testUID {
+		t.Fatalf("bad auth: %#v", auth)
+	}
+	if !reflect.DeepEqual(auth.Policies, []string{"test"}) || auth.TTL != time.Hour {
+		t.Fatalf("bad auth: %#v", auth)
+	}
+
+	// Without a reviewer token, the token under review is used to call the
+	// TokenReview API
+	if s.bearer != "Bearer "+token {
+		t.Fatalf("bad bearer: %q", s.bearer)
+	}
+
+	resp = request(t, b, storage, logical.AliasLookaheadOperation, "login", map[string]interface{}{
+		"jwt": token,
+	})
+	expectSuccess(t, resp)
+	if resp.Auth.Alias.Name != testUID {
+		t.Fatalf("bad alias lookahead: %#v", resp.Auth.Alias)
+	}
+}
+
+func TestLogin_Failures(t *testing.T) {
+	s := newTestAPIServer(t)
+	defer s.server.Close()
+
+	priv := newKey(t)
+	b, storage := setupBackend(t, s, priv)
+
+	login := func(role, token string) *logical.Response {
+		return request(t, b, storage, logical.UpdateOperation, "login", map[string]interface{}{
+			"role": role,
+			"jwt":  token,
+		})
+	}
+
+	token := serviceAccountToken(t, priv, testName, testNamespace, testUID)
+
+	expectError(t, login("", token), "missing role")
+	expectError(t, login("unknown", token), "unknown role")
+	expectError(t, login("demo", ""), "missing jwt")
+	expectError(t, login("demo", "not.a.jwt"), "malformed jwt")
+
+	// Tokens must be signed by a configured key and bound to the role
+	expectError(t, login("demo", serviceAccountToken(t, newKey(t), testName, testNamespace, testUID)), "unknown key")
+	expectError(t, login("demo", serviceAccountToken(t, priv, "other", testNamespace, testUID)), "unbound name")
+	expectError(t, login("demo", serviceAccountToken(t, priv, testName, "other", testUID)), "unbound namespace")
+
+	numReviews := len(s.reviewed)
+	if numReviews != 0 {
+		t.Fatalf("tokens rejected locally should not be reviewed, got %d reviews", numReviews)
+	}
+
+	// The review must return the service account of the token
+	s.setReview(func(string) (int, interface{}) {
+		return http.StatusCreated, reviewStatus(true, testName, testNamespace, "other-uid")
+	})
+	expectError(t, login("demo", token), "mismatched UID")
+
+	s.setReview(func(string) (int, interface{}) {
+		return http.StatusCreated, reviewStatus(false, testName, testNamespace, testUID)
+	})
+	expectError(t, login("demo", token), "unauthenticated review")
+
+	s.setReview(func(string) (int, interface{}) {
+		return http.StatusCreated, reviewStatus(true, "system:admin", "", "")
+	})
+	expectError(t, login("demo", token), "not a service account")
+
+	s.setReview(func(string) (int, interface{}) {
+		return http.StatusUnauthorized, map[string]interface{}{
+			"kind":    "Status",
+			"status":  "Failure",
+			"message": "Unauthorized",
+			"code":    401,
+		}
+	})
+	resp := login("demo", token)
+	expectError(t, resp, "deleted service account")
+	if resp.Data["error"] != "lookup failed: service account unauthorized; this could mean it has been deleted" {
+		t.Fatalf("bad error: %v", resp.Data["error"])
+	}
+}
+
+func TestLogin_TokenReviewerJWT(t *testing.T) {
+	s := newTestAPIServer(t)
+	defer s.server.Close()
+
+	priv := newKey(t)
+	b, storage := setupBackend(t, s, priv)
+
+	reviewerJWT := serviceAccountToken(t, priv, "token-reviewer", "kube-system", "reviewer-uid")
+	resp := request(t, b, storage, logical.UpdateOperation, "config", map[string]interface{}{
+		"kubernetes_host":    s.server.URL,
+		"kubernetes_ca_cert": s.caPEM(),
+		"token_reviewer_jwt": reviewerJWT,
+	})
+	expectSuccess(t, resp)
+
+	// Without public keys, the signature of the token is left to the
+	// TokenReview API
+	resp = request(t, b, storage, logical.UpdateOperation, "login", map[string]interface{}{
+		"role": "demo",
+		"jwt":  serviceAccountToken(t, newKey(t), testName, testNamespace, testUID),
+	})
+	expectSuccess(t, resp)
+
+	if s.bearer != "Bearer "+reviewerJWT {
+		t.Fatalf("bad bearer: %q", s.bearer)
+	}
+}
+
+// mockTokenReview returns a fixed review result
+type mockTokenReview struct {
+	result *tokenReviewResult
+	err    error
+}
+
+func (m *mockTokenReview) Review(string) (*tokenReviewResult, error) {
+	return m.result, m.err
+}
+
+func TestLogin_MockTokenReview(t *testing.T) {
+	priv := newKey(t)
+	b, storage := getBackend(t)
+
+	resp := request(t, b, storage, logical.UpdateOperation, "config", map[string]interface{}{
+		"kubernetes_host": "https://127.0.0.1:1",
+		"pem_keys":        publicKeyPEM(t, priv),
+	})
+	expectSuccess(t, resp)
+	resp = request(t, b, storage, logical.CreateOperation, "role/demo", map[string]interface{}{
+		"bound_service_account_names":      "*",
+		"bound_service_account_namespaces": testNamespace,
+	})
+	expectSuccess(t, resp)
+
+	reviewer := &mockTokenReview{
+		result: &tokenReviewResult{
+			Name:      testName,
+			Namespace: testNamespace,
+			UID:       testUID,
+		},
+	}
+	b.reviewFactory = func(*kubeConfig) tokenReviewer {
+		return reviewer
+	}
+
+	token := serviceAccountToken(t, priv, testName, testNamespace, testUID)
+	resp = request(t, b, storage, logical.UpdateOperation, "login", map[string]interface{}{
+		"role": "demo",
+		"jwt":  token,
+	})
+	expectSuccess(t, resp)
+
+	reviewer.err = errors.New("lookup failed")
+	resp = request(t, b, storage, logical.UpdateOperation, "login", map[string]interface{}{
+		"role": "demo",
+		"jwt":  token,
+	})
+	expectError(t, resp, "failed review")
+}
@@ -0,0 +1,80 @@
+package kubernetes
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// defaultTokenPath is where Kubernetes mounts the token of the service
+// account of a pod
+const defaultTokenPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+type CLIHandler struct{}
+
+func (h *CLIHandler) Auth(c *api.Client, m map[string]string) (*api.Secret, error) {
+	mount, ok := m["mount"]
+	if !ok {
+		mount = "kubernetes"
+	}
+
+	role, ok := m["role"]
+	if !ok || role == "" {
+		return nil, fmt.Errorf("'role' must be specified")
+	}
+
+	token, ok := m["jwt"]
+	if !ok {
+		tokenPath, ok := m["token_path"]
+		if !ok {
+			tokenPath = defaultTokenPath
+		}
+
+		raw, err := ioutil.ReadFile(tokenPath)
+		if err != nil {
+			return nil, fmt.Errorf("error reading service account token from %q: %v", tokenPath, err)
+		}
+		token = strings.TrimSpace(string(raw))
+	}
+
+	path := fmt.Sprintf("auth/%s/login", mount)
+	secret, err := c.Logical().Write(path, map[string]interface{}{
+		"role": role,
+		"jwt":  token,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil {
+		return nil, fmt.Errorf("empty response from credential provider")
+	}
+
+	return secret, nil
+}
+
+func (h *CLIHandler) Help() string {
+	help := `
+The Kubernetes credential provider allows you to authenticate with the token
+of a Kubernetes service account. Inside a pod, the token of its service
+account is read from the default location if none is given.
+
+    Example: vault auth -method=kubernetes role=demo
+
+Key/Value Pairs:
+
+    mount=kubernetes       The mountpoint for the Kubernetes credential
+                           provider. Defaults to "kubernetes"
+
+    role=<string>          The role to log in against.
+
+    jwt=<string>           The service account token to log in with.
+
+    token_path=<string>    The file to read the service account token from
+                           if "jwt" is not set. Defaults to
+                           "/var/run/secrets/kubernetes.io/serviceaccount/token"
+	`
+
+	return strings.TrimSpace(help)
+}
@@ -0,0 +1,203 @@
+package kubernetes
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+func pathConfig(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "config$",
+		Fields: map[string]*framework.FieldSchema{
+			"kubernetes_host": {
+				Type:        framework.TypeString,
+				Description: "Host must be a host string, a host:port pair, or a URL to the base of the Kubernetes API server.",
+			},
+			"kubernetes_ca_cert": {
+				Type:        framework.TypeString,
+				Description: "PEM encoded CA cert for use by the TLS client used to talk with the Kubernetes API. If not set, system certificates are used.",
+			},
+			"token_reviewer_jwt": {
+				Type: framework.TypeString,
+				Description: `A service account JWT used to access the TokenReview
+API to validate other JWTs during login. If not set the JWT used for login
+will be used to access the API.`,
+			},
+			"pem_keys": {
+				Type: framework.TypeCommaStringSlice,
+				Description: `Optional list of PEM-formatted public keys or certificates
+used to verify the signatures of Kubernetes service account JWTs. If a
+certificate is given, its public key will be extracted. Not every
+installation of Kubernetes exposes these keys.`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathConfigRead,
+			logical.UpdateOperation: b.pathConfigWrite,
+		},
+
+		HelpSynopsis:    confHelpSyn,
+		HelpDescription: confHelpDesc,
+	}
+}
+
+// kubeConfig is the configuration of the Kubernetes API server and the
+// public keys used to verify the signatures of service account tokens
+type kubeConfig struct {
+	// Host is the URL of the Kubernetes API server
+	Host string `json:"host"`
+
+	// CACert is the CA certificate of the Kubernetes API server
+	CACert string `json:"ca_cert"`
+
+	// TokenReviewerJWT is the bearer token used to call the TokenReview API
+	TokenReviewerJWT string `json:"token_reviewer_jwt"`
+
+	// PEMKeys are the PEM encoded public keys used to verify the signatures
+	// of service account tokens
+	PEMKeys []string `json:"pem_keys"`
+
+	// PublicKeys are the parsed PEMKeys
+	PublicKeys []crypto.PublicKey `json:"-"`
+}
+
+// config returns the configuration of the backend, with its public keys
+// parsed
+func (b *backend) config(s logical.Storage) (*kubeConfig, error) {
+	raw, err := s.Get(configPath)
+	if err != nil {
+		return nil, err
+	}
+	if raw == nil {
+		return nil, nil
+	}
+
+	config := &kubeConfig{}
+	if err := raw.DecodeJSON(config); err != nil {
+		return nil, err
+	}
+
+	for _, v := range config.PEMKeys {
+		key, err := parsePublicKeyPEM([]byte(v))
+		if err != nil {
+			return nil, fmt.Errorf("error parsing public key: %v", err)
+		}
+		config.PublicKeys = append(config.PublicKeys, key)
+	}
+
+	return config, nil
+}
+
+func (b *backend) pathConfigRead(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	config, err := b.config(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		return nil, nil
+	}
+
+	// The token reviewer JWT is a credential and is never returned
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"kubernetes_host":    config.Host,
+			"kubernetes_ca_cert": config.CACert,
+			"pem_keys":           config.PEMKeys,
+		},
+	}, nil
+}
+
+func (b *backend) pathConfigWrite(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	config := &kubeConfig{
+		Host:             d.Get("kubernetes_host").(string),
+		CACert:           d.Get("kubernetes_ca_cert").(string),
+		TokenReviewerJWT: d.Get("token_reviewer_jwt").(string),
+		PEMKeys:          d.Get("pem_keys").([]string),
+	}
+
+	if config.Host == "" {
+		return logical.ErrorResponse("no host provided"), nil
+	}
+
+	if config.CACert != "" {
+		if ok := x509.NewCertPool().AppendCertsFromPEM([]byte(config.CACert)); !ok {
+			return logical.ErrorResponse("could not parse 'kubernetes_ca_cert'"), nil
+		}
+	}
+
+	if config.TokenReviewerJWT != "" {
+		if _, err := jwt.ParseSigned(config.TokenReviewerJWT); err != nil {
+			return logical.ErrorResponse(fmt.Sprintf("error parsing 'token_reviewer_jwt': %v", err)), nil
+		}
+	}
+
+	for _, v := range config.PEMKeys {
+		if _, err := parsePublicKeyPEM([]byte(v)); err != nil {
+			return logical.ErrorResponse(fmt.Sprintf("error parsing public key: %v", err)), nil
+		}
+	}
+
+	entry, err := logical.StorageEntryJSON(configPath, config)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(entry); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+// parsePublicKeyPEM parses an RSA or ECDSA public key, or the public key of
+// a certificate, from its PEM encoding
+func parsePublicKeyPEM(data []byte) (crypto.PublicKey, error) {
+	block, data := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("data does not contain any valid public keys")
+	}
+	if len(strings.TrimSpace(string(data))) != 0 {
+		return nil, errors.New("only one public key may be given per value")
+	}
+
+	var key interface{}
+	switch block.Type {
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		key = cert.PublicKey
+	default:
+		var err error
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	switch key.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey:
+		return key, nil
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T", key)
+	}
+}
+
+const confHelpSyn = `Configures the JWT public keys and Kubernetes API information.`
+const confHelpDesc = `
+The Kubernetes credential provider validates service account JWTs and
+verifies their existence with the Kubernetes TokenReview API. This endpoint
+configures the public keys used to validate the JWT signatures and the
+information needed to access the Kubernetes API.
+`
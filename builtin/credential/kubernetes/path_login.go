@@ -0,0 +1,270 @@
+package kubernetes
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/helper/policyutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+// expectedJWTIssuer is the issuer of Kubernetes service account tokens
+const expectedJWTIssuer = "kubernetes/serviceaccount"
+
+func pathLogin(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "login$",
+		Fields: map[string]*framework.FieldSchema{
+			"role": {
+				Type:        framework.TypeString,
+				Description: "Name of the role against which the login is being attempted.",
+			},
+			"jwt": {
+				Type:        framework.TypeString,
+				Description: "A signed JWT for authenticating a service account.",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation:         b.pathLogin,
+			logical.AliasLookaheadOperation: b.pathLoginAliasLookahead,
+		},
+
+		HelpSynopsis:    pathLoginHelpSyn,
+		HelpDescription: pathLoginHelpDesc,
+	}
+}
+
+// serviceAccount holds the claims of a service account token
+type serviceAccount struct {
+	Name       string `json:"kubernetes.io/serviceaccount/service-account.name"`
+	UID        string `json:"kubernetes.io/serviceaccount/service-account.uid"`
+	SecretName string `json:"kubernetes.io/serviceaccount/secret.name"`
+	Namespace  string `json:"kubernetes.io/serviceaccount/namespace"`
+}
+
+func (b *backend) pathLoginAliasLookahead(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	token := d.Get("jwt").(string)
+	if token == "" {
+		return logical.ErrorResponse("missing jwt"), nil
+	}
+
+	sa := &serviceAccount{}
+	if err := unverifiedClaims(token, sa); err != nil {
+		return logical.ErrorResponse(fmt.Sprintf("error parsing jwt claims: %v", err)), nil
+	}
+	if sa.UID == "" {
+		return logical.ErrorResponse("could not parse UID from claims"), nil
+	}
+
+	return &logical.Response{
+		Auth: &logical.Auth{
+			Alias: &logical.Alias{
+				Name: sa.UID,
+			},
+		},
+	}, nil
+}
+
+func (b *backend) pathLogin(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	roleName := d.Get("role").(string)
+	if roleName == "" {
+		return logical.ErrorResponse("missing role"), nil
+	}
+
+	token := d.Get("jwt").(string)
+	if token == "" {
+		return logical.ErrorResponse("missing jwt"), nil
+	}
+
+	role, err := b.role(req.Storage, roleName)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return logical.ErrorResponse(fmt.Sprintf("invalid role name %q", roleName)), nil
+	}
+
+	config, err := b.config(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		return nil, errors.New("could not load backend configuration")
+	}
+
+	sa, err := parseAndValidateJWT(token, config)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	if err := role.allowsServiceAccount(sa.Name, sa.Namespace); err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	// Verify with the Kubernetes API that the token is still valid and
+	// belongs to the service account of its claims
+	if err := sa.lookup(token, b.reviewFactory(config)); err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	return &logical.Response{
+		Auth: &logical.Auth{
+			Policies:    role.Policies,
+			NumUses:     role.NumUses,
+			Period:      role.Period,
+			DisplayName: sa.Name,
+			Alias: &logical.Alias{
+				Name: sa.UID,
+				Metadata: map[string]string{
+					"service_account_name":      sa.Name,
+					"service_account_namespace": sa.Namespace,
+				},
+			},
+			InternalData: map[string]interface{}{
+				"role": roleName,
+			},
+			Metadata: map[string]string{
+				"service_account_uid":         sa.UID,
+				"service_account_name":        sa.Name,
+				"service_account_namespace":   sa.Namespace,
+				"service_account_secret_name": sa.SecretName,
+				"role":                        roleName,
+			},
+			LeaseOptions: logical.LeaseOptions{
+				Renewable: true,
+				TTL:       role.TTL,
+			},
+		},
+	}, nil
+}
+
+// parseAndValidateJWT parses the service account of a token. If public keys
+// are configured, the signature of the token must match one of them.
+func parseAndValidateJWT(token string, config *kubeConfig) (*serviceAccount, error) {
+	parsed, err := jwt.ParseSigned(token)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing jwt: %v", err)
+	}
+
+	claims := jwt.Claims{}
+	sa := &serviceAccount{}
+
+	if len(config.PublicKeys) == 0 {
+		if err := unverifiedClaims(token, &claims, sa); err != nil {
+			return nil, fmt.Errorf("error parsing jwt claims: %v", err)
+		}
+	} else {
+		verified := false
+		for _, key := range config.PublicKeys {
+			if err := parsed.Claims(key, &claims, sa); err == nil {
+				verified = true
+				break
+			}
+		}
+		if !verified {
+			return nil, errors.New("no known key successfully validated the token signature")
+		}
+	}
+
+	// Service account tokens carry no time claims, so only the issuer is
+	// validated
+	if err := claims.Validate(jwt.Expected{Issuer: expectedJWTIssuer}); err != nil {
+		return nil, fmt.Errorf("error validating claims: %v", err)
+	}
+
+	if sa.UID == "" || sa.Name == "" || sa.Namespace == "" {
+		return nil, errors.New("jwt is missing service account claims")
+	}
+
+	return sa, nil
+}
+
+// unverifiedClaims decodes the claims of a compact serialized token without
+// verifying its signature
+func unverifiedClaims(token string, dest ...interface{}) error {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return errors.New("compact JWS format must have three parts")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return err
+	}
+
+	for _, d := range dest {
+		if err := json.Unmarshal(payload, d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lookup verifies the token with the TokenReview API, and checks that the
+// service account returned matches the claims of the token
+func (s *serviceAccount) lookup(token string, tr tokenReviewer) error {
+	r, err := tr.Review(token)
+	if err != nil {
+		return err
+	}
+
+	if s.Name != r.Name {
+		return errors.New("JWT names did not match")
+	}
+	if s.UID != r.UID {
+		return errors.New("JWT UIDs did not match")
+	}
+	if s.Namespace != r.Namespace {
+		return errors.New("JWT namespaces did not match")
+	}
+
+	return nil
+}
+
+func (b *backend) pathLoginRenew(req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	roleName, ok := req.Auth.InternalData["role"].(string)
+	if !ok || roleName == "" {
+		return nil, errors.New("failed to fetch role during renewal")
+	}
+
+	// Ensure that the Role still exists.
+	role, err := b.role(req.Storage, roleName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to validate role %s during renewal: %v", roleName, err)
+	}
+	if role == nil {
+		return nil, fmt.Errorf("role %s does not exist during renewal", roleName)
+	}
+
+	if !policyutil.EquivalentPolicies(role.Policies, req.Auth.Policies) {
+		return nil, errors.New("policies have changed, not renewing")
+	}
+
+	// If 'Period' is set on the Role, the token should never expire.
+	// Replenish the TTL with 'Period's value.
+	if role.Period > time.Duration(0) {
+		req.Auth.TTL = role.Period
+		return &logical.Response{Auth: req.Auth}, nil
+	}
+
+	return framework.LeaseExtend(role.TTL, role.MaxTTL, b.System())(req, data)
+}
+
+const (
+	pathLoginHelpSyn = `
+Authenticates Kubernetes service accounts with Vault.
+`
+	pathLoginHelpDesc = `
+Authenticates Kubernetes service account tokens. The token is verified with
+the TokenReview API of the configured Kubernetes API server, and the service
+account must be bound to the given role. The identity alias of the login is
+the UID of the service account.
+`
+)
@@ -0,0 +1,311 @@
+package kubernetes
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/helper/policyutil"
+	"github.com/hashicorp/vault/helper/strutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+func pathRoleList(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "role/?",
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ListOperation: b.pathRoleList,
+		},
+		HelpSynopsis:    strings.TrimSpace(roleHelp["role-list"][0]),
+		HelpDescription: strings.TrimSpace(roleHelp["role-list"][1]),
+	}
+}
+
+func pathRole(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "role/" + framework.GenericNameRegex("name"),
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:        framework.TypeString,
+				Description: "Name of the role.",
+			},
+			"bound_service_account_names": {
+				Type: framework.TypeCommaStringSlice,
+				Description: `List of service account names able to access this role. If set to "*"
+all names are allowed, both this and bound_service_account_namespaces can not
+be "*".`,
+			},
+			"bound_service_account_namespaces": {
+				Type: framework.TypeCommaStringSlice,
+				Description: `List of namespaces allowed to access this role. If set to "*" all
+namespaces are allowed, both this and bound_service_account_names can not be
+"*".`,
+			},
+			"policies": {
+				Type:        framework.TypeCommaStringSlice,
+				Description: "List of policies on the role.",
+			},
+			"num_uses": {
+				Type:        framework.TypeInt,
+				Description: "Number of times issued tokens can be used.",
+			},
+			"ttl": {
+				Type: framework.TypeDurationSecond,
+				Description: `Duration in seconds after which the issued token should expire. Defaults
+to 0, in which case the value will fall back to the system/mount defaults.`,
+			},
+			"max_ttl": {
+				Type: framework.TypeDurationSecond,
+				Description: `Duration in seconds after which the issued token should not be allowed to
+be renewed. Defaults to 0, in which case the value will fall back to the
+system/mount defaults.`,
+			},
+			"period": {
+				Type: framework.TypeDurationSecond,
+				Description: `If set, indicates that the token generated using this role
+should never expire. The token should be renewed within the
+duration specified by this value. At each renewal, the token's
+TTL will be set to the value of this parameter.`,
+			},
+		},
+
+		ExistenceCheck: b.pathRoleExistenceCheck,
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.CreateOperation: b.pathRoleCreateUpdate,
+			logical.UpdateOperation: b.pathRoleCreateUpdate,
+			logical.ReadOperation:   b.pathRoleRead,
+			logical.DeleteOperation: b.pathRoleDelete,
+		},
+
+		HelpSynopsis:    strings.TrimSpace(roleHelp["role"][0]),
+		HelpDescription: strings.TrimSpace(roleHelp["role"][1]),
+	}
+}
+
+// kubeRole binds service accounts to the properties of the issued tokens
+type kubeRole struct {
+	// Policies to be set on the issued tokens
+	Policies []string `json:"policies"`
+
+	// NumUses is the number of allowed uses of the issued tokens
+	NumUses int `json:"num_uses"`
+
+	// Duration before which an issued token must be renewed
+	TTL time.Duration `json:"ttl"`
+
+	// Duration after which an issued token should not be allowed to be
+	// renewed
+	MaxTTL time.Duration `json:"max_ttl"`
+
+	// Period, if set, indicates that the issued tokens should never expire,
+	// as long as they are renewed within the period
+	Period time.Duration `json:"period"`
+
+	// ServiceAccountNames are the names of the service accounts able to
+	// log in with the role
+	ServiceAccountNames []string `json:"bound_service_account_names"`
+
+	// ServiceAccountNamespaces are the namespaces of the service accounts
+	// able to log in with the role
+	ServiceAccountNamespaces []string `json:"bound_service_account_namespaces"`
+}
+
+// allowsServiceAccount reports whether the service account with the given
+// name and namespace is bound to the role
+func (r *kubeRole) allowsServiceAccount(name, namespace string) error {
+	if !allowedValue(r.ServiceAccountNamespaces, namespace) {
+		return errors.New("namespace not authorized")
+	}
+	if !allowedValue(r.ServiceAccountNames, name) {
+		return errors.New("service account name not authorized")
+	}
+	return nil
+}
+
+func allowedValue(allowed []string, value string) bool {
+	if len(allowed) == 1 && allowed[0] == "*" {
+		return true
+	}
+	return strutil.StrListContains(allowed, value)
+}
+
+// role takes a storage backend and the name and returns the role's storage
+// entry
+func (b *backend) role(s logical.Storage, name string) (*kubeRole, error) {
+	raw, err := s.Get(rolePrefix + strings.ToLower(name))
+	if err != nil {
+		return nil, err
+	}
+	if raw == nil {
+		return nil, nil
+	}
+
+	role := new(kubeRole)
+	if err := raw.DecodeJSON(role); err != nil {
+		return nil, err
+	}
+
+	return role, nil
+}
+
+func (b *backend) pathRoleExistenceCheck(req *logical.Request, data *framework.FieldData) (bool, error) {
+	role, err := b.role(req.Storage, data.Get("name").(string))
+	if err != nil {
+		return false, err
+	}
+	return role != nil, nil
+}
+
+func (b *backend) pathRoleList(req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	roles, err := req.Storage.List(rolePrefix)
+	if err != nil {
+		return nil, err
+	}
+	return logical.ListResponse(roles), nil
+}
+
+func (b *backend) pathRoleRead(req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	role, err := b.role(req.Storage, data.Get("name").(string))
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, nil
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"bound_service_account_names":      role.ServiceAccountNames,
+			"bound_service_account_namespaces": role.ServiceAccountNamespaces,
+			"policies":                         role.Policies,
+			"num_uses":                         role.NumUses,
+			"ttl":                              int64(role.TTL.Seconds()),
+			"max_ttl":                          int64(role.MaxTTL.Seconds()),
+			"period":                           int64(role.Period.Seconds()),
+		},
+	}, nil
+}
+
+func (b *backend) pathRoleDelete(req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	roleName := data.Get("name").(string)
+	if roleName == "" {
+		return logical.ErrorResponse("missing role name"), nil
+	}
+
+	if err := req.Storage.Delete(rolePrefix + strings.ToLower(roleName)); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+func (b *backend) pathRoleCreateUpdate(req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	roleName := data.Get("name").(string)
+	if roleName == "" {
+		return logical.ErrorResponse("missing role name"), nil
+	}
+
+	// Check if the role already exists
+	role, err := b.role(req.Storage, roleName)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create a new entry object if this is a CreateOperation
+	if role == nil {
+		if req.Operation == logical.UpdateOperation {
+			return nil, errors.New("role entry not found during update operation")
+		}
+		role = new(kubeRole)
+	}
+
+	if policiesRaw, ok := data.GetOk("policies"); ok {
+		role.Policies = policyutil.ParsePolicies(policiesRaw)
+	}
+
+	if periodRaw, ok := data.GetOk("period"); ok {
+		role.Period = time.Duration(periodRaw.(int)) * time.Second
+	}
+	if role.Period > b.System().MaxLeaseTTL() {
+		return logical.ErrorResponse(fmt.Sprintf("'period' of %q is greater than the backend's maximum lease TTL of %q", role.Period.String(), b.System().MaxLeaseTTL().String())), nil
+	}
+
+	if numUsesRaw, ok := data.GetOk("num_uses"); ok {
+		role.NumUses = numUsesRaw.(int)
+	}
+	if role.NumUses < 0 {
+		return logical.ErrorResponse("num_uses cannot be negative"), nil
+	}
+
+	if tokenTTLRaw, ok := data.GetOk("ttl"); ok {
+		role.TTL = time.Duration(tokenTTLRaw.(int)) * time.Second
+	}
+
+	if tokenMaxTTLRaw, ok := data.GetOk("max_ttl"); ok {
+		role.MaxTTL = time.Duration(tokenMaxTTLRaw.(int)) * time.Second
+	}
+
+	// Check that the TTL value provided is less than the MaxTTL. Sanitizing
+	// the TTL and MaxTTL is not required now and can be performed at
+	// credential issue time.
+	if role.MaxTTL > 0 && role.TTL > role.MaxTTL {
+		return logical.ErrorResponse("ttl should not be greater than max_ttl"), nil
+	}
+
+	var resp *logical.Response
+	if role.MaxTTL > b.System().MaxLeaseTTL() {
+		resp = &logical.Response{}
+		resp.AddWarning("max_ttl is greater than the system or backend mount's maximum TTL value; issued tokens' max TTL value will be truncated")
+	}
+
+	if names, ok := data.GetOk("bound_service_account_names"); ok {
+		role.ServiceAccountNames = names.([]string)
+	}
+	if len(role.ServiceAccountNames) == 0 {
+		return logical.ErrorResponse("'bound_service_account_names' can not be empty"), nil
+	}
+	if len(role.ServiceAccountNames) > 1 && strutil.StrListContains(role.ServiceAccountNames, "*") {
+		return logical.ErrorResponse("can not mix '*' with values in 'bound_service_account_names'"), nil
+	}
+
+	if namespaces, ok := data.GetOk("bound_service_account_namespaces"); ok {
+		role.ServiceAccountNamespaces = namespaces.([]string)
+	}
+	if len(role.ServiceAccountNamespaces) == 0 {
+		return logical.ErrorResponse("'bound_service_account_namespaces' can not be empty"), nil
+	}
+	if len(role.ServiceAccountNamespaces) > 1 && strutil.StrListContains(role.ServiceAccountNamespaces, "*") {
+		return logical.ErrorResponse("can not mix '*' with values in 'bound_service_account_namespaces'"), nil
+	}
+
+	if strutil.StrListContains(role.ServiceAccountNames, "*") && strutil.StrListContains(role.ServiceAccountNamespaces, "*") {
+		return logical.ErrorResponse("'bound_service_account_names' and 'bound_service_account_namespaces' can not both be '*'"), nil
+	}
+
+	entry, err := logical.StorageEntryJSON(rolePrefix+strings.ToLower(roleName), role)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(entry); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+var roleHelp = map[string][2]string{
+	"role-list": {
+		"Lists all the roles registered with the backend.",
+		"The list will contain the names of the roles.",
+	},
+	"role": {
+		"Register a role with the backend.",
+		`
+A role is required to authenticate with this backend. The role binds the
+names and namespaces of Kubernetes service accounts with token policies and
+settings.
+`,
+	},
+}
@@ -0,0 +1,196 @@
+package kubernetes
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	cleanhttp "github.com/hashicorp/go-cleanhttp"
+)
+
+// tokenReviewResult is the service account a token belongs to, as returned
+// by the TokenReview API
+type tokenReviewResult struct {
+	Name      string
+	Namespace string
+	UID       string
+}
+
+// tokenReviewer verifies service account tokens
+type tokenReviewer interface {
+	Review(jwt string) (*tokenReviewResult, error)
+}
+
+// tokenReviewFactory builds a tokenReviewer for the given configuration
+type tokenReviewFactory func(*kubeConfig) tokenReviewer
+
+// tokenReview is the subset of the authentication.k8s.io/v1 TokenReview
+// object used by the backend
+type tokenReview struct {
+	APIVersion string            `json:"apiVersion"`
+	Kind       string            `json:"kind"`
+	Spec       tokenReviewSpec   `json:"spec"`
+	Status     tokenReviewStatus `json:"status"`
+}
+
+type tokenReviewSpec struct {
+	Token string `json:"token"`
+}
+
+type tokenReviewStatus struct {
+	Authenticated bool            `json:"authenticated"`
+	User          tokenReviewUser `json:"user"`
+	Error         string          `json:"error"`
+}
+
+type tokenReviewUser struct {
+	Username string   `json:"username"`
+	UID      string   `json:"uid"`
+	Groups   []string `json:"groups"`
+}
+
+// apiStatus is the Status object returned by the Kubernetes API on errors
+type apiStatus struct {
+	Kind    string `json:"kind"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+	Reason  string `json:"reason"`
+	Code    int    `json:"code"`
+}
+
+// tokenReviewAPI verifies tokens with the TokenReview API of the
+// configured Kubernetes API server
+type tokenReviewAPI struct {
+	config *kubeConfig
+}
+
+func tokenReviewAPIFactory(config *kubeConfig) tokenReviewer {
+	return &tokenReviewAPI{
+		config: config,
+	}
+}
+
+func (t *tokenReviewAPI) Review(jwt string) (*tokenReviewResult, error) {
+	client, err := t.client()
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := json.Marshal(&tokenReview{
+		APIVersion: "authentication.k8s.io/v1",
+		Kind:       "TokenReview",
+		Spec: tokenReviewSpec{
+			Token: jwt,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	url := strings.TrimSuffix(t.config.Host, "/") + "/apis/authentication.k8s.io/v1/tokenreviews"
+	req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	// Use the configured reviewer token if there is one, otherwise the
+	// token under review must be allowed to access the TokenReview API
+	bearer := jwt
+	if t.config.TokenReviewerJWT != "" {
+		bearer = t.config.TokenReviewerJWT
+	}
+	req.Header.Set("Authorization", "Bearer "+bearer)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	r, err := parseResponse(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.Status.Error != "" {
+		return nil, fmt.Errorf("lookup failed: %s", r.Status.Error)
+	}
+	if !r.Status.Authenticated {
+		return nil, errors.New("lookup failed: service account jwt not valid")
+	}
+
+	// The username is of format: system:serviceaccount:(NAMESPACE):(SERVICEACCOUNT)
+	parts := strings.Split(r.Status.User.Username, ":")
+	if len(parts) != 4 {
+		return nil, errors.New("lookup failed: unexpected username format")
+	}
+	if parts[0] != "system" || parts[1] != "serviceaccount" {
+		return nil, errors.New("lookup failed: username returned is not a service account")
+	}
+
+	return &tokenReviewResult{
+		Name:      parts[3],
+		Namespace: parts[2],
+		UID:       r.Status.User.UID,
+	}, nil
+}
+
+// client returns an HTTP client that trusts the configured CA certificate
+// of the Kubernetes API server, or the system certificates if none is set
+func (t *tokenReviewAPI) client() (*http.Client, error) {
+	client := cleanhttp.DefaultClient()
+	if t.config.CACert == "" {
+		return client, nil
+	}
+
+	certPool := x509.NewCertPool()
+	if ok := certPool.AppendCertsFromPEM([]byte(t.config.CACert)); !ok {
+		return nil, errors.New("could not parse the Kubernetes CA certificate")
+	}
+
+	transport := cleanhttp.DefaultTransport()
+	transport.TLSClientConfig = &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		RootCAs:    certPool,
+	}
+	client.Transport = transport
+
+	return client, nil
+}
+
+// parseResponse decodes the TokenReview object of a response, or returns
+// the error reported by the Kubernetes API
+func parseResponse(resp *http.Response) (*tokenReview, error) {
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode > http.StatusPartialContent {
+		if resp.StatusCode == http.StatusUnauthorized {
+			// The token has likely been deleted since it was issued
+			return nil, errors.New("lookup failed: service account unauthorized; this could mean it has been deleted")
+		}
+
+		var status apiStatus
+		if err := json.Unmarshal(body, &status); err == nil && status.Kind == "Status" && status.Message != "" {
+			return nil, fmt.Errorf("lookup failed: %s", status.Message)
+		}
+		return nil, fmt.Errorf("lookup failed: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	r := &tokenReview{}
+	if err := json.Unmarshal(body, r); err != nil {
+		return nil, fmt.Errorf("failed to decode TokenReview response: %v", err)
+	}
+	return r, nil
+}
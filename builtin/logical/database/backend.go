@@ -5,15 +5,24 @@ import (
 	"net/rpc"
 	"strings"
 	"sync"
+	"time"
 
 	log "github.com/mgutz/logxi/v1"
+	"golang.org/x/net/context"
 
 	"github.com/hashicorp/vault/builtin/logical/database/dbplugin"
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/framework"
 )
 
-const databaseConfigPath = "database/config/"
+const (
+	databaseConfigPath = "database/config/"
+
+	// pluginRequestTimeout is the deadline of the plugin calls made for a
+	// single request, so that a hanging plugin fails the request instead of
+	// blocking it and the locks it holds
+	pluginRequestTimeout = 60 * time.Second
+)
 
 func Factory(conf *logical.BackendConfig) (logical.Backend, error) {
 	b := Backend(conf)
@@ -34,15 +43,22 @@ func Backend(conf *logical.BackendConfig) *databaseBackend {
 			pathListRoles(&b),
 			pathRoles(&b),
 			pathCredsCreate(&b),
+			pathListStaticRoles(&b),
+			pathStaticRoles(&b),
+			pathStaticCredsRead(&b),
 			pathResetConnection(&b),
+			pathRotateCredentials(&b),
 		},
 
 		Secrets: []*framework.Secret{
 			secretCreds(&b),
 		},
-		Clean:       b.closeAllDBs,
-		Invalidate:  b.invalidate,
-		BackendType: logical.TypeLogical,
+		Clean:             b.closeAllDBs,
+		Invalidate:        b.invalidate,
+		PeriodicFunc:      b.periodicFunc,
+		WALRollback:       b.walRollback,
+		WALRollbackMinAge: minRollbackAge,
+		BackendType:       logical.TypeLogical,
 	}
 
 	b.logger = conf.Logger
@@ -54,6 +70,10 @@ type databaseBackend struct {
 	connections map[string]dbplugin.Database
 	logger      log.Logger
 
+	// staticRoleLock serializes changes to static roles and the rotation
+	// of their passwords
+	staticRoleLock sync.Mutex
+
 	*framework.Backend
 	sync.RWMutex
 }
@@ -81,7 +101,7 @@ func (b *databaseBackend) getDBObj(name string) (dbplugin.Database, bool) {
 // This function creates a new db object from the stored configuration and
 // caches it in the connections map. The caller of this function needs to hold
 // the backend's write lock
-func (b *databaseBackend) createDBObj(s logical.Storage, name string) (dbplugin.Database, error) {
+func (b *databaseBackend) createDBObj(ctx context.Context, s logical.Storage, name string) (dbplugin.Database, error) {
 	db, ok := b.connections[name]
 	if ok {
 		return db, nil
@@ -97,7 +117,7 @@ func (b *databaseBackend) createDBObj(s logical.Storage, name string) (dbplugin.
 		return nil, err
 	}
 
-	err = db.Initialize(config.ConnectionDetails, true)
+	err = db.Initialize(ctx, config.ConnectionDetails, true)
 	if err != nil {
 		return nil, err
 	}
@@ -164,7 +184,7 @@ func (b *databaseBackend) clearConnection(name string) {
 
 func (b *databaseBackend) closeIfShutdown(name string, err error) {
 	// Plugin has shutdown, close it so next call can reconnect.
-	if err == rpc.ErrShutdown {
+	if err == rpc.ErrShutdown || err == dbplugin.ErrPluginShutdown {
 		b.Lock()
 		b.clearConnection(name)
 		b.Unlock()
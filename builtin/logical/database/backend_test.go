@@ -2,21 +2,26 @@ package database
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"reflect"
+	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/vault/builtin/logical/database/dbplugin"
 	"github.com/hashicorp/vault/helper/pluginutil"
 	vaulthttp "github.com/hashicorp/vault/http"
 	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
 	"github.com/hashicorp/vault/plugins/database/postgresql"
 	"github.com/hashicorp/vault/vault"
 	"github.com/lib/pq"
 	"github.com/mitchellh/mapstructure"
+	"golang.org/x/net/context"
 	dockertest "gopkg.in/ory-am/dockertest.v3"
 )
 
@@ -725,6 +730,332 @@ func TestBackend_allowedRoles(t *testing.T) {
 	}
 }
 
+func TestBackend_rotateRootCredentials(t *testing.T) {
+	cluster, sys := getCluster(t)
+	defer cluster.Cleanup()
+
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+	config.System = sys
+
+	b, err := Factory(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Cleanup()
+
+	cleanup, connURL := preparePostgresTestContainer(t, config.StorageView, b)
+	defer cleanup()
+
+	// Connect with a dedicated superuser, so the container admin keeps working
+	db, err := sql.Open("postgres", connURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(`CREATE ROLE "vault-root" WITH SUPERUSER LOGIN PASSWORD 'secret';`); err != nil {
+		t.Fatal(err)
+	}
+	templatedURL := strings.Replace(connURL, "postgres:secret", "{{username}}:{{password}}", 1)
+
+	// Configure a connection
+	data := map[string]interface{}{
+		"connection_url": templatedURL,
+		"plugin_name":    "postgresql-database-plugin",
+		"allowed_roles":  []string{"plugin-role-test"},
+		"username":       "vault-root",
+		"password":       "secret",
+	}
+	req := &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config/plugin-test",
+		Storage:   config.StorageView,
+		Data:      data,
+	}
+	resp, err := b.HandleRequest(req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%s resp:%#v\n", err, resp)
+	}
+
+	// Rotate the root credentials
+	req = &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "rotate-root/plugin-test",
+		Storage:   config.StorageView,
+	}
+	resp, err = b.HandleRequest(req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%s resp:%#v\n", err, resp)
+	}
+
+	// The old password no longer works
+	oldURL := strings.Replace(connURL, "postgres:secret", "vault-root:secret", 1)
+	oldDB, err := sql.Open("postgres", oldURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer oldDB.Close()
+	if err := oldDB.Ping(); err == nil {
+		t.Fatal("expected the old root password to be rotated")
+	}
+
+	// The password is not returned when reading the connection
+	req = &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "config/plugin-test",
+		Storage:   config.StorageView,
+	}
+	resp, err = b.HandleRequest(req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%s resp:%#v\n", err, resp)
+	}
+	if _, ok := resp.Data["connection_details"].(map[string]interface{})["password"]; ok {
+		t.Fatalf("password returned: %#v", resp.Data)
+	}
+
+	// The connection works with the new password
+	data = map[string]interface{}{
+		"db_name":             "plugin-test",
+		"creation_statements": testRole,
+		"default_ttl":         "5m",
+		"max_ttl":             "10m",
+	}
+	req = &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "roles/plugin-role-test",
+		Storage:   config.StorageView,
+		Data:      data,
+	}
+	resp, err = b.HandleRequest(req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%s resp:%#v\n", err, resp)
+	}
+	req = &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "creds/plugin-role-test",
+		Storage:   config.StorageView,
+	}
+	credsResp, err := b.HandleRequest(req)
+	if err != nil || (credsResp != nil && credsResp.IsError()) {
+		t.Fatalf("err:%s resp:%#v\n", err, credsResp)
+	}
+	if !testCredsExist(t, credsResp, connURL) {
+		t.Fatalf("Creds should exist")
+	}
+}
+
+// staticMockDB is a database holding the passwords of static users, which
+// can be made to fail setting them
+type staticMockDB struct {
+	passwords map[string]string
+	fail      bool
+}
+
+func (m *staticMockDB) Type() (string, error) { return "mock", nil }
+func (m *staticMockDB) CreateUser(ctx context.Context, statements dbplugin.Statements, usernameConfig dbplugin.UsernameConfig, expiration time.Time) (string, string, error) {
+	return "", "", errors.New("not supported")
+}
+func (m *staticMockDB) RenewUser(ctx context.Context, statements dbplugin.Statements, username string, expiration time.Time) error {
+	return errors.New("not supported")
+}
+func (m *staticMockDB) RevokeUser(ctx context.Context, statements dbplugin.Statements, username string) error {
+	return errors.New("not supported")
+}
+func (m *staticMockDB) SetCredentials(ctx context.Context, statements dbplugin.Statements, staticUser dbplugin.StaticUserConfig) (string, string, error) {
+	if m.fail {
+		return "", "", errors.New("database unavailable")
+	}
+	m.passwords[staticUser.Username] = staticUser.Password
+	return staticUser.Username, staticUser.Password, nil
+}
+func (m *staticMockDB) RotateRootCredentials(ctx context.Context, statements []string) (map[string]interface{}, error) {
+	return nil, errors.New("not supported")
+}
+func (m *staticMockDB) Initialize(ctx context.Context, config map[string]interface{}, verifyConnection bool) error {
+	return nil
+}
+func (m *staticMockDB) Close() error { return nil }
+
+func TestBackend_StaticRole(t *testing.T) {
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+	b := Backend(config)
+	if err := b.Setup(config); err != nil {
+		t.Fatal(err)
+	}
+	defer b.Cleanup()
+	storage := config.StorageView
+
+	// Use a connection backed by the mock database
+	entry, err := logical.StorageEntryJSON("config/mockdb", &DatabaseConfig{
+		PluginName:   "mock",
+		AllowedRoles: []string{"static"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.Put(entry); err != nil {
+		t.Fatal(err)
+	}
+	db := &staticMockDB{passwords: make(map[string]string)}
+	b.connections["mockdb"] = db
+
+	// The rotation period has a minimum
+	resp, err := b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "static-roles/static",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"db_name":         "mockdb",
+			"username":        "app",
+			"rotation_period": "30s",
+		},
+	})
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected error: err: %v resp: %#v", err, resp)
+	}
+
+	// Roles must be allowed by the connection
+	_, err = b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "static-roles/denied",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"db_name":         "mockdb",
+			"username":        "app",
+			"rotation_period": "1h",
+		},
+	})
+	if err != logical.ErrPermissionDenied {
+		t.Fatalf("expected error to be:%s got:%#v\n", logical.ErrPermissionDenied, err)
+	}
+
+	// Creating the role sets the password right away
+	resp, err = b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "static-roles/static",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"db_name":         "mockdb",
+			"username":        "app",
+			"rotation_period": "1h",
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%s resp:%#v\n", err, resp)
+	}
+
+	readCreds := func() map[string]interface{} {
+		resp, err := b.HandleRequest(&logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "static-creds/static",
+			Storage:   storage,
+		})
+		if err != nil || resp == nil || resp.IsError() {
+			t.Fatalf("err:%s resp:%#v\n", err, resp)
+		}
+		return resp.Data
+	}
+	creds := readCreds()
+	password := creds["password"].(string)
+	if creds["username"] != "app" || password == "" || db.passwords["app"] != password {
+		t.Fatalf("bad: %#v", creds)
+	}
+	if ttl := creds["ttl"].(int64); ttl <= 0 || ttl > 3600 {
+		t.Fatalf("bad ttl: %d", ttl)
+	}
+
+	// The username cannot be changed
+	resp, err = b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "static-roles/static",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"username": "other",
+		},
+	})
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected error: err: %v resp: %#v", err, resp)
+	}
+
+	// Roles that are not due are left alone
+	req := &logical.Request{
+		Operation: logical.RollbackOperation,
+		Storage:   storage,
+	}
+	if err := b.periodicFunc(req); err != nil {
+		t.Fatal(err)
+	}
+	if readCreds()["password"] != password {
+		t.Fatal("password rotated before it was due")
+	}
+
+	makeDue := func() {
+		role, err := b.StaticRole(storage, "static")
+		if err != nil {
+			t.Fatal(err)
+		}
+		role.LastVaultRotation = role.LastVaultRotation.Add(-2 * time.Hour)
+		if err := b.putStaticRole(storage, "static", role); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	makeDue()
+	if err := b.periodicFunc(req); err != nil {
+		t.Fatal(err)
+	}
+	creds = readCreds()
+	if creds["password"] == password || db.passwords["app"] != creds["password"] {
+		t.Fatalf("password not rotated: %#v", creds)
+	}
+	password = creds["password"].(string)
+
+	// A failed rotation is kept in the WAL and retried with the same password
+	makeDue()
+	db.fail = true
+	if err := b.periodicFunc(req); err == nil {
+		t.Fatal("expected error rotating the password")
+	}
+	if readCreds()["password"] != password {
+		t.Fatal("password changed by a failed rotation")
+	}
+	walIDs, err := framework.ListWAL(storage)
+	if err != nil || len(walIDs) != 1 {
+		t.Fatalf("bad: err: %v wal: %#v", err, walIDs)
+	}
+	walEntry, err := framework.GetWAL(storage, walIDs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	walPassword := walEntry.Data.(map[string]interface{})["new_password"].(string)
+
+	// The pending rotation is not started again
+	if err := b.periodicFunc(req); err != nil {
+		t.Fatal(err)
+	}
+
+	db.fail = false
+	resp, err = b.HandleRequest(&logical.Request{
+		Operation: logical.RollbackOperation,
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"immediate": true,
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%s resp:%#v\n", err, resp)
+	}
+	creds = readCreds()
+	if creds["password"] != walPassword || db.passwords["app"] != walPassword {
+		t.Fatalf("rotation not retried with the password of the WAL: %#v", creds)
+	}
+	walIDs, err = framework.ListWAL(storage)
+	if err != nil || len(walIDs) != 0 {
+		t.Fatalf("bad: err: %v wal: %#v", err, walIDs)
+	}
+}
+
 func testCredsExist(t *testing.T, resp *logical.Response, connURL string) bool {
 	var d struct {
 		Username string `mapstructure:"username"`
@@ -1,6 +1,7 @@
 package dbplugin
 
 import (
+	"errors"
 	"fmt"
 	"net/rpc"
 	"sync"
@@ -9,27 +10,29 @@ import (
 	"github.com/hashicorp/go-plugin"
 	"github.com/hashicorp/vault/helper/pluginutil"
 	log "github.com/mgutz/logxi/v1"
+	"golang.org/x/net/context"
 )
 
-// DatabasePluginClient embeds a databasePluginRPCClient and wraps it's Close
-// method to also call Kill() on the plugin.Client.
+// DatabasePluginClient embeds a databasePluginRPCClient or gRPCClient and wraps
+// it's Close method to also call Kill() on the plugin.Client.
 type DatabasePluginClient struct {
 	client *plugin.Client
 	sync.Mutex
 
-	*databasePluginRPCClient
+	Database
 }
 
 func (dc *DatabasePluginClient) Close() error {
-	err := dc.databasePluginRPCClient.Close()
+	err := dc.Database.Close()
 	dc.client.Kill()
 
 	return err
 }
 
-// newPluginClient returns a databaseRPCClient with a connection to a running
-// plugin. The client is wrapped in a DatabasePluginClient object to ensure the
-// plugin is killed on call of Close().
+// newPluginClient returns a client with a connection to a running plugin,
+// speaking whichever protocol the plugin was served with. The client is
+// wrapped in a DatabasePluginClient object to ensure the plugin is killed on
+// call of Close().
 func newPluginClient(sys pluginutil.RunnerUtil, pluginRunner *pluginutil.PluginRunner, logger log.Logger) (Database, error) {
 	// pluginMap is the map of plugins we can dispense.
 	var pluginMap = map[string]plugin.Plugin{
@@ -54,13 +57,17 @@ func newPluginClient(sys pluginutil.RunnerUtil, pluginRunner *pluginutil.PluginR
 	}
 
 	// We should have a database type now. This feels like a normal interface
-	// implementation but is in fact over an RPC connection.
-	databaseRPC := raw.(*databasePluginRPCClient)
+	// implementation but is in fact over an RPC connection, using net/rpc or
+	// gRPC depending on the protocol the plugin was served with.
+	db, ok := raw.(Database)
+	if !ok {
+		return nil, errors.New("unsupported client type")
+	}
 
 	// Wrap RPC implimentation in DatabasePluginClient
 	return &DatabasePluginClient{
-		client:                  client,
-		databasePluginRPCClient: databaseRPC,
+		client:   client,
+		Database: db,
 	}, nil
 }
 
@@ -72,6 +79,20 @@ type databasePluginRPCClient struct {
 	client *rpc.Client
 }
 
+// call makes an RPC call to the plugin. net/rpc cannot pass the context to the
+// plugin, so the plugin finishes the call on its own when the context is done,
+// but the caller stops waiting for it.
+func (dr *databasePluginRPCClient) call(ctx context.Context, serviceMethod string, args interface{}, reply interface{}) error {
+	call := dr.client.Go(serviceMethod, args, reply, make(chan *rpc.Call, 1))
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-call.Done:
+		return call.Error
+	}
+}
+
 func (dr *databasePluginRPCClient) Type() (string, error) {
 	var dbType string
 	err := dr.client.Call("Plugin.Type", struct{}{}, &dbType)
@@ -79,7 +100,7 @@ func (dr *databasePluginRPCClient) Type() (string, error) {
 	return fmt.Sprintf("plugin-%s", dbType), err
 }
 
-func (dr *databasePluginRPCClient) CreateUser(statements Statements, usernameConfig UsernameConfig, expiration time.Time) (username string, password string, err error) {
+func (dr *databasePluginRPCClient) CreateUser(ctx context.Context, statements Statements, usernameConfig UsernameConfig, expiration time.Time) (username string, password string, err error) {
 	req := CreateUserRequest{
 		Statements:     statements,
 		UsernameConfig: usernameConfig,
@@ -87,41 +108,64 @@ func (dr *databasePluginRPCClient) CreateUser(statements Statements, usernameCon
 	}
 
 	var resp CreateUserResponse
-	err = dr.client.Call("Plugin.CreateUser", req, &resp)
+	err = dr.call(ctx, "Plugin.CreateUser", req, &resp)
 
 	return resp.Username, resp.Password, err
 }
 
-func (dr *databasePluginRPCClient) RenewUser(statements Statements, username string, expiration time.Time) error {
+func (dr *databasePluginRPCClient) RenewUser(ctx context.Context, statements Statements, username string, expiration time.Time) error {
 	req := RenewUserRequest{
 		Statements: statements,
 		Username:   username,
 		Expiration: expiration,
 	}
 
-	err := dr.client.Call("Plugin.RenewUser", req, &struct{}{})
+	err := dr.call(ctx, "Plugin.RenewUser", req, &struct{}{})
 
 	return err
 }
 
-func (dr *databasePluginRPCClient) RevokeUser(statements Statements, username string) error {
+func (dr *databasePluginRPCClient) RevokeUser(ctx context.Context, statements Statements, username string) error {
 	req := RevokeUserRequest{
 		Statements: statements,
 		Username:   username,
 	}
 
-	err := dr.client.Call("Plugin.RevokeUser", req, &struct{}{})
+	err := dr.call(ctx, "Plugin.RevokeUser", req, &struct{}{})
 
 	return err
 }
 
-func (dr *databasePluginRPCClient) Initialize(conf map[string]interface{}, verifyConnection bool) error {
+func (dr *databasePluginRPCClient) SetCredentials(ctx context.Context, statements Statements, staticUser StaticUserConfig) (username string, password string, err error) {
+	req := SetCredentialsRequest{
+		Statements: statements,
+		StaticUser: staticUser,
+	}
+
+	var resp SetCredentialsResponse
+	err = dr.call(ctx, "Plugin.SetCredentials", req, &resp)
+
+	return resp.Username, resp.Password, err
+}
+
+func (dr *databasePluginRPCClient) RotateRootCredentials(ctx context.Context, statements []string) (map[string]interface{}, error) {
+	req := RotateRootCredentialsRequest{
+		Statements: statements,
+	}
+
+	var resp RotateRootCredentialsResponse
+	err := dr.call(ctx, "Plugin.RotateRootCredentials", req, &resp)
+
+	return resp.Config, err
+}
+
+func (dr *databasePluginRPCClient) Initialize(ctx context.Context, conf map[string]interface{}, verifyConnection bool) error {
 	req := InitializeRequest{
 		Config:           conf,
 		VerifyConnection: verifyConnection,
 	}
 
-	err := dr.client.Call("Plugin.Initialize", req, &struct{}{})
+	err := dr.call(ctx, "Plugin.Initialize", req, &struct{}{})
 
 	return err
 }
@@ -0,0 +1,303 @@
+package dbplugin
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/hashicorp/vault/builtin/logical/database/dbplugin/pb"
+	"github.com/hashicorp/vault/helper/jsonutil"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/status"
+)
+
+// ---- gRPC Server domain ----
+
+// gRPCServer implements the gRPC version of Database and is run inside a
+// plugin. It wraps an underlying implementation of Database and passes it the
+// context of each call, which carries the deadline set by Vault.
+type gRPCServer struct {
+	impl Database
+}
+
+func (s *gRPCServer) Type(context.Context, *pb.Empty) (*pb.TypeResponse, error) {
+	t, err := s.impl.Type()
+	if err != nil {
+		return nil, toGRPCError(err)
+	}
+
+	return &pb.TypeResponse{
+		Type: t,
+	}, nil
+}
+
+func (s *gRPCServer) CreateUser(ctx context.Context, req *pb.CreateUserRequest) (*pb.CreateUserResponse, error) {
+	e, err := ptypes.Timestamp(req.Expiration)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	u, p, err := s.impl.CreateUser(ctx, statementsFromProto(req.Statements), UsernameConfig{
+		DisplayName: req.GetUsernameConfig().GetDisplayName(),
+		RoleName:    req.GetUsernameConfig().GetRoleName(),
+	}, e)
+	if err != nil {
+		return nil, toGRPCError(err)
+	}
+
+	return &pb.CreateUserResponse{
+		Username: u,
+		Password: p,
+	}, nil
+}
+
+func (s *gRPCServer) RenewUser(ctx context.Context, req *pb.RenewUserRequest) (*pb.Empty, error) {
+	e, err := ptypes.Timestamp(req.Expiration)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	if err := s.impl.RenewUser(ctx, statementsFromProto(req.Statements), req.Username, e); err != nil {
+		return nil, toGRPCError(err)
+	}
+
+	return &pb.Empty{}, nil
+}
+
+func (s *gRPCServer) RevokeUser(ctx context.Context, req *pb.RevokeUserRequest) (*pb.Empty, error) {
+	if err := s.impl.RevokeUser(ctx, statementsFromProto(req.Statements), req.Username); err != nil {
+		return nil, toGRPCError(err)
+	}
+
+	return &pb.Empty{}, nil
+}
+
+func (s *gRPCServer) SetCredentials(ctx context.Context, req *pb.SetCredentialsRequest) (*pb.SetCredentialsResponse, error) {
+	u, p, err := s.impl.SetCredentials(ctx, statementsFromProto(req.Statements), StaticUserConfig{
+		Username: req.GetStaticUser().GetUsername(),
+		Password: req.GetStaticUser().GetPassword(),
+	})
+	if err != nil {
+		return nil, toGRPCError(err)
+	}
+
+	return &pb.SetCredentialsResponse{
+		Username: u,
+		Password: p,
+	}, nil
+}
+
+func (s *gRPCServer) RotateRootCredentials(ctx context.Context, req *pb.RotateRootCredentialsRequest) (*pb.RotateRootCredentialsResponse, error) {
+	config, err := s.impl.RotateRootCredentials(ctx, req.Statements)
+	if err != nil {
+		return nil, toGRPCError(err)
+	}
+
+	configRaw, err := jsonutil.EncodeJSON(config)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &pb.RotateRootCredentialsResponse{
+		Config: configRaw,
+	}, nil
+}
+
+func (s *gRPCServer) Initialize(ctx context.Context, req *pb.InitializeRequest) (*pb.Empty, error) {
+	var config map[string]interface{}
+	if err := jsonutil.DecodeJSON(req.Config, &config); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	if err := s.impl.Initialize(ctx, config, req.VerifyConnection); err != nil {
+		return nil, toGRPCError(err)
+	}
+
+	return &pb.Empty{}, nil
+}
+
+func (s *gRPCServer) Close(context.Context, *pb.Empty) (*pb.Empty, error) {
+	if err := s.impl.Close(); err != nil {
+		return nil, toGRPCError(err)
+	}
+
+	return &pb.Empty{}, nil
+}
+
+// ---- gRPC client domain ----
+
+// gRPCClient implements Database and is used on the client to make gRPC calls
+// to a plugin.
+type gRPCClient struct {
+	client     pb.DatabaseClient
+	clientConn *grpc.ClientConn
+}
+
+func (c *gRPCClient) Type() (string, error) {
+	resp, err := c.client.Type(context.Background(), &pb.Empty{})
+	if err != nil {
+		return "", c.fromGRPCError(err)
+	}
+
+	return fmt.Sprintf("plugin-%s", resp.Type), nil
+}
+
+func (c *gRPCClient) CreateUser(ctx context.Context, statements Statements, usernameConfig UsernameConfig, expiration time.Time) (username string, password string, err error) {
+	t, err := ptypes.TimestampProto(expiration)
+	if err != nil {
+		return "", "", err
+	}
+
+	resp, err := c.client.CreateUser(ctx, &pb.CreateUserRequest{
+		Statements: statementsToProto(statements),
+		UsernameConfig: &pb.UsernameConfig{
+			DisplayName: usernameConfig.DisplayName,
+			RoleName:    usernameConfig.RoleName,
+		},
+		Expiration: t,
+	})
+	if err != nil {
+		return "", "", c.fromGRPCError(err)
+	}
+
+	return resp.Username, resp.Password, nil
+}
+
+func (c *gRPCClient) RenewUser(ctx context.Context, statements Statements, username string, expiration time.Time) error {
+	t, err := ptypes.TimestampProto(expiration)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.client.RenewUser(ctx, &pb.RenewUserRequest{
+		Statements: statementsToProto(statements),
+		Username:   username,
+		Expiration: t,
+	})
+
+	return c.fromGRPCError(err)
+}
+
+func (c *gRPCClient) RevokeUser(ctx context.Context, statements Statements, username string) error {
+	_, err := c.client.RevokeUser(ctx, &pb.RevokeUserRequest{
+		Statements: statementsToProto(statements),
+		Username:   username,
+	})
+
+	return c.fromGRPCError(err)
+}
+
+func (c *gRPCClient) SetCredentials(ctx context.Context, statements Statements, staticUser StaticUserConfig) (username string, password string, err error) {
+	resp, err := c.client.SetCredentials(ctx, &pb.SetCredentialsRequest{
+		Statements: statementsToProto(statements),
+		StaticUser: &pb.StaticUserConfig{
+			Username: staticUser.Username,
+			Password: staticUser.Password,
+		},
+	})
+	if err != nil {
+		return "", "", c.fromGRPCError(err)
+	}
+
+	return resp.Username, resp.Password, nil
+}
+
+func (c *gRPCClient) RotateRootCredentials(ctx context.Context, statements []string) (map[string]interface{}, error) {
+	resp, err := c.client.RotateRootCredentials(ctx, &pb.RotateRootCredentialsRequest{
+		Statements: statements,
+	})
+	if err != nil {
+		return nil, c.fromGRPCError(err)
+	}
+
+	var config map[string]interface{}
+	if err := jsonutil.DecodeJSON(resp.Config, &config); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+func (c *gRPCClient) Initialize(ctx context.Context, conf map[string]interface{}, verifyConnection bool) error {
+	configRaw, err := jsonutil.EncodeJSON(conf)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.client.Initialize(ctx, &pb.InitializeRequest{
+		Config:           configRaw,
+		VerifyConnection: verifyConnection,
+	})
+
+	return c.fromGRPCError(err)
+}
+
+func (c *gRPCClient) Close() error {
+	_, err := c.client.Close(context.Background(), &pb.Empty{})
+
+	return c.fromGRPCError(err)
+}
+
+// fromGRPCError turns the status error of a gRPC call back into the error
+// returned by the plugin. Context errors are returned as the context package's
+// errors, and ErrPluginShutdown is returned if the plugin is unreachable.
+func (c *gRPCClient) fromGRPCError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	s, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+
+	switch s.Code() {
+	case codes.Canceled:
+		return context.Canceled
+	case codes.DeadlineExceeded:
+		return context.DeadlineExceeded
+	case codes.Unavailable:
+		if c.clientConn.GetState() != connectivity.Ready {
+			return ErrPluginShutdown
+		}
+	}
+
+	return errors.New(s.Message())
+}
+
+// toGRPCError turns an error returned by the plugin into a gRPC status error,
+// so that context errors keep their meaning on the client
+func toGRPCError(err error) error {
+	switch err {
+	case context.Canceled:
+		return status.Error(codes.Canceled, err.Error())
+	case context.DeadlineExceeded:
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	}
+
+	return status.Error(codes.Unknown, err.Error())
+}
+
+func statementsToProto(s Statements) *pb.Statements {
+	return &pb.Statements{
+		CreationStatements:   s.CreationStatements,
+		RevocationStatements: s.RevocationStatements,
+		RollbackStatements:   s.RollbackStatements,
+		RenewStatements:      s.RenewStatements,
+		RotationStatements:   s.RotationStatements,
+	}
+}
+
+func statementsFromProto(s *pb.Statements) Statements {
+	return Statements{
+		CreationStatements:   s.GetCreationStatements(),
+		RevocationStatements: s.GetRevocationStatements(),
+		RollbackStatements:   s.GetRollbackStatements(),
+		RenewStatements:      s.GetRenewStatements(),
+		RotationStatements:   s.GetRotationStatements(),
+	}
+}
@@ -1,23 +1,43 @@
 package dbplugin
 
 import (
+	"errors"
 	"fmt"
 	"net/rpc"
 	"time"
 
 	"github.com/hashicorp/go-plugin"
+	"github.com/hashicorp/vault/builtin/logical/database/dbplugin/pb"
 	"github.com/hashicorp/vault/helper/pluginutil"
 	log "github.com/mgutz/logxi/v1"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
 )
 
-// Database is the interface that all database objects must implement.
+// ErrPluginShutdown is returned when the plugin process has exited and the
+// connection to it has to be reestablished.
+var ErrPluginShutdown = errors.New("plugin is shut down")
+
+// Database is the interface that all database objects must implement. The
+// context passed to the functions is canceled when the request that triggered
+// the call is done or its deadline is exceeded.
 type Database interface {
 	Type() (string, error)
-	CreateUser(statements Statements, usernameConfig UsernameConfig, expiration time.Time) (username string, password string, err error)
-	RenewUser(statements Statements, username string, expiration time.Time) error
-	RevokeUser(statements Statements, username string) error
+	CreateUser(ctx context.Context, statements Statements, usernameConfig UsernameConfig, expiration time.Time) (username string, password string, err error)
+	RenewUser(ctx context.Context, statements Statements, username string, expiration time.Time) error
+	RevokeUser(ctx context.Context, statements Statements, username string) error
+
+	// SetCredentials sets the password of an existing, static user using the
+	// RotationStatements provided. It returns the username and password now
+	// in effect.
+	SetCredentials(ctx context.Context, statements Statements, staticUser StaticUserConfig) (username string, password string, err error)
+
+	// RotateRootCredentials changes the password of the user the plugin
+	// connects as, using the given statements or a default if there are none.
+	// It returns the connection configuration to store for the connection.
+	RotateRootCredentials(ctx context.Context, statements []string) (config map[string]interface{}, err error)
 
-	Initialize(config map[string]interface{}, verifyConnection bool) error
+	Initialize(ctx context.Context, config map[string]interface{}, verifyConnection bool) error
 	Close() error
 }
 
@@ -27,6 +47,7 @@ type Statements struct {
 	RevocationStatements string `json:"revocation_statements" mapstructure:"revocation_statements" structs:"revocation_statements"`
 	RollbackStatements   string `json:"rollback_statements" mapstructure:"rollback_statements" structs:"rollback_statements"`
 	RenewStatements      string `json:"renew_statements" mapstructure:"renew_statements" structs:"renew_statements"`
+	RotationStatements   string `json:"rotation_statements" mapstructure:"rotation_statements" structs:"rotation_statements"`
 }
 
 // UsernameConfig is used to configure prefixes for the username to be
@@ -36,6 +57,13 @@ type UsernameConfig struct {
 	RoleName    string
 }
 
+// StaticUserConfig is used to configure the credentials of an existing user
+// that is managed, but not created, by Vault.
+type StaticUserConfig struct {
+	Username string
+	Password string
+}
+
 // PluginFactory is used to build plugin database types. It wraps the database
 // object in a logging and metrics middleware.
 func PluginFactory(pluginName string, sys pluginutil.LookRunnerUtil, logger log.Logger) (Database, error) {
@@ -101,8 +129,9 @@ var handshakeConfig = plugin.HandshakeConfig{
 	MagicCookieValue: "926a0820-aea2-be28-51d6-83cdf00e8edb",
 }
 
-// DatabasePlugin implements go-plugin's Plugin interface. It has methods for
-// retrieving a server and a client instance of the plugin.
+// DatabasePlugin implements go-plugin's Plugin and GRPCPlugin interfaces. It
+// has methods for retrieving a server and a client instance of the plugin
+// over both net/rpc and gRPC.
 type DatabasePlugin struct {
 	impl Database
 }
@@ -115,6 +144,18 @@ func (DatabasePlugin) Client(b *plugin.MuxBroker, c *rpc.Client) (interface{}, e
 	return &databasePluginRPCClient{client: c}, nil
 }
 
+func (d DatabasePlugin) GRPCServer(s *grpc.Server) error {
+	pb.RegisterDatabaseServer(s, &gRPCServer{impl: d.impl})
+	return nil
+}
+
+func (DatabasePlugin) GRPCClient(c *grpc.ClientConn) (interface{}, error) {
+	return &gRPCClient{
+		client:     pb.NewDatabaseClient(c),
+		clientConn: c,
+	}, nil
+}
+
 // ---- RPC Request Args Domain ----
 
 type InitializeRequest struct {
@@ -139,9 +180,27 @@ type RevokeUserRequest struct {
 	Username   string
 }
 
+type RotateRootCredentialsRequest struct {
+	Statements []string
+}
+
+type SetCredentialsRequest struct {
+	Statements Statements
+	StaticUser StaticUserConfig
+}
+
 // ---- RPC Response Args Domain ----
 
 type CreateUserResponse struct {
 	Username string
 	Password string
 }
+
+type SetCredentialsResponse struct {
+	Username string
+	Password string
+}
+
+type RotateRootCredentialsResponse struct {
+	Config map[string]interface{}
+}
@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	goplugin "github.com/hashicorp/go-plugin"
 	"github.com/hashicorp/vault/builtin/logical/database/dbplugin"
 	"github.com/hashicorp/vault/helper/pluginutil"
 	vaulthttp "github.com/hashicorp/vault/http"
@@ -13,6 +14,7 @@ import (
 	"github.com/hashicorp/vault/plugins"
 	"github.com/hashicorp/vault/vault"
 	log "github.com/mgutz/logxi/v1"
+	"golang.org/x/net/context"
 )
 
 type mockPlugin struct {
@@ -20,12 +22,22 @@ type mockPlugin struct {
 }
 
 func (m *mockPlugin) Type() (string, error) { return "mock", nil }
-func (m *mockPlugin) CreateUser(statements dbplugin.Statements, usernameConf dbplugin.UsernameConfig, expiration time.Time) (username string, password string, err error) {
+func (m *mockPlugin) CreateUser(ctx context.Context, statements dbplugin.Statements, usernameConf dbplugin.UsernameConfig, expiration time.Time) (username string, password string, err error) {
 	err = errors.New("err")
 	if usernameConf.DisplayName == "" || expiration.IsZero() {
 		return "", "", err
 	}
 
+	// Simulate a hanging database
+	if usernameConf.DisplayName == "hang" {
+		select {
+		case <-ctx.Done():
+			return "", "", ctx.Err()
+		case <-time.After(10 * time.Second):
+			return "", "", err
+		}
+	}
+
 	if _, ok := m.users[usernameConf.DisplayName]; ok {
 		return "", "", err
 	}
@@ -34,7 +46,7 @@ func (m *mockPlugin) CreateUser(statements dbplugin.Statements, usernameConf dbp
 
 	return usernameConf.DisplayName, "test", nil
 }
-func (m *mockPlugin) RenewUser(statements dbplugin.Statements, username string, expiration time.Time) error {
+func (m *mockPlugin) RenewUser(ctx context.Context, statements dbplugin.Statements, username string, expiration time.Time) error {
 	err := errors.New("err")
 	if username == "" || expiration.IsZero() {
 		return err
@@ -46,7 +58,7 @@ func (m *mockPlugin) RenewUser(statements dbplugin.Statements, username string,
 
 	return nil
 }
-func (m *mockPlugin) RevokeUser(statements dbplugin.Statements, username string) error {
+func (m *mockPlugin) RevokeUser(ctx context.Context, statements dbplugin.Statements, username string) error {
 	err := errors.New("err")
 	if username == "" {
 		return err
@@ -59,7 +71,22 @@ func (m *mockPlugin) RevokeUser(statements dbplugin.Statements, username string)
 	delete(m.users, username)
 	return nil
 }
-func (m *mockPlugin) Initialize(conf map[string]interface{}, _ bool) error {
+func (m *mockPlugin) SetCredentials(ctx context.Context, statements dbplugin.Statements, staticUser dbplugin.StaticUserConfig) (username string, password string, err error) {
+	err = errors.New("err")
+	if staticUser.Username == "" || staticUser.Password == "" {
+		return "", "", err
+	}
+
+	m.users[staticUser.Username] = []string{staticUser.Password}
+
+	return staticUser.Username, staticUser.Password, nil
+}
+func (m *mockPlugin) RotateRootCredentials(ctx context.Context, statements []string) (config map[string]interface{}, err error) {
+	return map[string]interface{}{
+		"test": "rotated",
+	}, nil
+}
+func (m *mockPlugin) Initialize(ctx context.Context, conf map[string]interface{}, _ bool) error {
 	err := errors.New("err")
 	if len(conf) != 1 {
 		return err
@@ -81,6 +108,7 @@ func getCluster(t *testing.T) (*vault.TestCluster, logical.SystemView) {
 
 	sys := vault.TestDynamicSystemView(cores[0].Core)
 	vault.TestAddTestPlugin(t, cores[0].Core, "test-plugin", "TestPlugin_Main")
+	vault.TestAddTestPlugin(t, cores[0].Core, "test-plugin-netRPC", "TestPlugin_NetRPC_Main")
 
 	return cluster, sys
 }
@@ -105,6 +133,31 @@ func TestPlugin_Main(t *testing.T) {
 	plugins.Serve(plugin, apiClientMeta.GetTLSConfig())
 }
 
+// This is not an actual test case, it's a helper function that will be executed
+// by the go-plugin client via an exec call. It serves the plugin over net/rpc
+// like plugins built before gRPC support.
+func TestPlugin_NetRPC_Main(t *testing.T) {
+	if os.Getenv(pluginutil.PluginUnwrapTokenEnv) == "" {
+		return
+	}
+
+	plugin := &mockPlugin{
+		users: make(map[string][]string),
+	}
+
+	args := []string{"--tls-skip-verify=true"}
+
+	apiClientMeta := &pluginutil.APIClientMeta{}
+	flags := apiClientMeta.FlagSet()
+	flags.Parse(args)
+
+	tlsProvider := pluginutil.VaultPluginTLSProvider(apiClientMeta.GetTLSConfig())
+	serveConf := dbplugin.ServeConfig(plugin, tlsProvider)
+	serveConf.GRPCServer = nil
+
+	goplugin.Serve(serveConf)
+}
+
 func TestPlugin_Initialize(t *testing.T) {
 	cluster, sys := getCluster(t)
 	defer cluster.Cleanup()
@@ -118,7 +171,7 @@ func TestPlugin_Initialize(t *testing.T) {
 		"test": 1,
 	}
 
-	err = dbRaw.Initialize(connectionDetails, true)
+	err = dbRaw.Initialize(context.Background(), connectionDetails, true)
 	if err != nil {
 		t.Fatalf("err: %s", err)
 	}
@@ -143,7 +196,7 @@ func TestPlugin_CreateUser(t *testing.T) {
 		"test": 1,
 	}
 
-	err = db.Initialize(connectionDetails, true)
+	err = db.Initialize(context.Background(), connectionDetails, true)
 	if err != nil {
 		t.Fatalf("err: %s", err)
 	}
@@ -153,7 +206,7 @@ func TestPlugin_CreateUser(t *testing.T) {
 		RoleName:    "test",
 	}
 
-	us, pw, err := db.CreateUser(dbplugin.Statements{}, usernameConf, time.Now().Add(time.Minute))
+	us, pw, err := db.CreateUser(context.Background(), dbplugin.Statements{}, usernameConf, time.Now().Add(time.Minute))
 	if err != nil {
 		t.Fatalf("err: %s", err)
 	}
@@ -163,7 +216,7 @@ func TestPlugin_CreateUser(t *testing.T) {
 
 	// try and save the same user again to verify it saved the first time, this
 	// should return an error
-	_, _, err = db.CreateUser(dbplugin.Statements{}, usernameConf, time.Now().Add(time.Minute))
+	_, _, err = db.CreateUser(context.Background(), dbplugin.Statements{}, usernameConf, time.Now().Add(time.Minute))
 	if err == nil {
 		t.Fatal("expected an error, user wasn't created correctly")
 	}
@@ -182,7 +235,7 @@ func TestPlugin_RenewUser(t *testing.T) {
 	connectionDetails := map[string]interface{}{
 		"test": 1,
 	}
-	err = db.Initialize(connectionDetails, true)
+	err = db.Initialize(context.Background(), connectionDetails, true)
 	if err != nil {
 		t.Fatalf("err: %s", err)
 	}
@@ -192,12 +245,12 @@ func TestPlugin_RenewUser(t *testing.T) {
 		RoleName:    "test",
 	}
 
-	us, _, err := db.CreateUser(dbplugin.Statements{}, usernameConf, time.Now().Add(time.Minute))
+	us, _, err := db.CreateUser(context.Background(), dbplugin.Statements{}, usernameConf, time.Now().Add(time.Minute))
 	if err != nil {
 		t.Fatalf("err: %s", err)
 	}
 
-	err = db.RenewUser(dbplugin.Statements{}, us, time.Now().Add(time.Minute))
+	err = db.RenewUser(context.Background(), dbplugin.Statements{}, us, time.Now().Add(time.Minute))
 	if err != nil {
 		t.Fatalf("err: %s", err)
 	}
@@ -216,7 +269,7 @@ func TestPlugin_RevokeUser(t *testing.T) {
 	connectionDetails := map[string]interface{}{
 		"test": 1,
 	}
-	err = db.Initialize(connectionDetails, true)
+	err = db.Initialize(context.Background(), connectionDetails, true)
 	if err != nil {
 		t.Fatalf("err: %s", err)
 	}
@@ -226,20 +279,174 @@ func TestPlugin_RevokeUser(t *testing.T) {
 		RoleName:    "test",
 	}
 
-	us, _, err := db.CreateUser(dbplugin.Statements{}, usernameConf, time.Now().Add(time.Minute))
+	us, _, err := db.CreateUser(context.Background(), dbplugin.Statements{}, usernameConf, time.Now().Add(time.Minute))
 	if err != nil {
 		t.Fatalf("err: %s", err)
 	}
 
 	// Test default revoke statememts
-	err = db.RevokeUser(dbplugin.Statements{}, us)
+	err = db.RevokeUser(context.Background(), dbplugin.Statements{}, us)
 	if err != nil {
 		t.Fatalf("err: %s", err)
 	}
 
 	// Try adding the same username back so we can verify it was removed
-	_, _, err = db.CreateUser(dbplugin.Statements{}, usernameConf, time.Now().Add(time.Minute))
+	_, _, err = db.CreateUser(context.Background(), dbplugin.Statements{}, usernameConf, time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+func TestPlugin_SetCredentials(t *testing.T) {
+	cluster, sys := getCluster(t)
+	defer cluster.Cleanup()
+
+	db, err := dbplugin.PluginFactory("test-plugin", sys, &log.NullLogger{})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer db.Close()
+
+	connectionDetails := map[string]interface{}{
+		"test": 1,
+	}
+	err = db.Initialize(context.Background(), connectionDetails, true)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	staticUser := dbplugin.StaticUserConfig{
+		Username: "static",
+		Password: "secret",
+	}
+
+	us, pw, err := db.SetCredentials(context.Background(), dbplugin.Statements{}, staticUser)
 	if err != nil {
 		t.Fatalf("err: %s", err)
 	}
+	if us != "static" || pw != "secret" {
+		t.Fatalf("bad: username: %s password: %s", us, pw)
+	}
+
+	// Verify the user exists by revoking it
+	err = db.RevokeUser(context.Background(), dbplugin.Statements{}, us)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// A password is required
+	_, _, err = db.SetCredentials(context.Background(), dbplugin.Statements{}, dbplugin.StaticUserConfig{
+		Username: "static",
+	})
+	if err == nil {
+		t.Fatal("expected an error setting credentials without a password")
+	}
+}
+
+func TestPlugin_RotateRootCredentials(t *testing.T) {
+	cluster, sys := getCluster(t)
+	defer cluster.Cleanup()
+
+	db, err := dbplugin.PluginFactory("test-plugin", sys, &log.NullLogger{})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer db.Close()
+
+	connectionDetails := map[string]interface{}{
+		"test": 1,
+	}
+	err = db.Initialize(context.Background(), connectionDetails, true)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	config, err := db.RotateRootCredentials(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(config) != 1 || config["test"] != "rotated" {
+		t.Fatalf("bad: %#v", config)
+	}
+}
+
+func TestPlugin_NetRPC_CreateUser(t *testing.T) {
+	cluster, sys := getCluster(t)
+	defer cluster.Cleanup()
+
+	db, err := dbplugin.PluginFactory("test-plugin-netRPC", sys, &log.NullLogger{})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer db.Close()
+
+	connectionDetails := map[string]interface{}{
+		"test": 1,
+	}
+	err = db.Initialize(context.Background(), connectionDetails, true)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	usernameConf := dbplugin.UsernameConfig{
+		DisplayName: "test",
+		RoleName:    "test",
+	}
+
+	us, pw, err := db.CreateUser(context.Background(), dbplugin.Statements{}, usernameConf, time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if us != "test" || pw != "test" {
+		t.Fatal("expected username and password to be 'test'")
+	}
+
+	err = db.RevokeUser(context.Background(), dbplugin.Statements{}, us)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+func TestPlugin_Deadline(t *testing.T) {
+	cluster, sys := getCluster(t)
+	defer cluster.Cleanup()
+
+	for _, pluginName := range []string{"test-plugin", "test-plugin-netRPC"} {
+		db, err := dbplugin.PluginFactory(pluginName, sys, &log.NullLogger{})
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		defer db.Close()
+
+		connectionDetails := map[string]interface{}{
+			"test": 1,
+		}
+		err = db.Initialize(context.Background(), connectionDetails, true)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		usernameConf := dbplugin.UsernameConfig{
+			DisplayName: "hang",
+			RoleName:    "test",
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		_, _, err = db.CreateUser(ctx, dbplugin.Statements{}, usernameConf, time.Now().Add(time.Minute))
+		if err != context.DeadlineExceeded {
+			t.Fatalf("%s: expected deadline exceeded error, got: %v", pluginName, err)
+		}
+		if time.Since(start) > 5*time.Second {
+			t.Fatalf("%s: call did not return at its deadline", pluginName)
+		}
+
+		// Errors of the plugin are returned as is
+		_, _, err = db.CreateUser(context.Background(), dbplugin.Statements{}, dbplugin.UsernameConfig{}, time.Now().Add(time.Minute))
+		if err == nil || err.Error() != "err" {
+			t.Fatalf("%s: expected the error of the plugin, got: %v", pluginName, err)
+		}
+	}
 }
@@ -4,12 +4,21 @@ import (
 	"crypto/tls"
 
 	"github.com/hashicorp/go-plugin"
+	"golang.org/x/net/context"
 )
 
 // Serve is called from within a plugin and wraps the provided
-// Database implementation in a databasePluginRPCServer object and starts a
-// RPC server.
+// Database implementation in a gRPCServer object and starts a gRPC server.
+// Vault negotiates the protocol when running the plugin, so plugins built
+// before gRPC support keep being served over net/rpc.
 func Serve(db Database, tlsProvider func() (*tls.Config, error)) {
+	plugin.Serve(ServeConfig(db, tlsProvider))
+}
+
+// ServeConfig returns the configuration used to serve the provided Database
+// implementation over gRPC. Unsetting its GRPCServer serves the plugin over
+// net/rpc instead.
+func ServeConfig(db Database, tlsProvider func() (*tls.Config, error)) *plugin.ServeConfig {
 	dbPlugin := &DatabasePlugin{
 		impl: db,
 	}
@@ -19,17 +28,19 @@ func Serve(db Database, tlsProvider func() (*tls.Config, error)) {
 		"database": dbPlugin,
 	}
 
-	plugin.Serve(&plugin.ServeConfig{
+	return &plugin.ServeConfig{
 		HandshakeConfig: handshakeConfig,
 		Plugins:         pluginMap,
 		TLSProvider:     tlsProvider,
-	})
+		GRPCServer:      plugin.DefaultGRPCServer,
+	}
 }
 
 // ---- RPC server domain ----
 
 // databasePluginRPCServer implements an RPC version of Database and is run
-// inside a plugin. It wraps an underlying implementation of Database.
+// inside a plugin. It wraps an underlying implementation of Database. net/rpc
+// carries no context, so the calls are made with a background context.
 type databasePluginRPCServer struct {
 	impl Database
 }
@@ -42,25 +53,39 @@ func (ds *databasePluginRPCServer) Type(_ struct{}, resp *string) error {
 
 func (ds *databasePluginRPCServer) CreateUser(args *CreateUserRequest, resp *CreateUserResponse) error {
 	var err error
-	resp.Username, resp.Password, err = ds.impl.CreateUser(args.Statements, args.UsernameConfig, args.Expiration)
+	resp.Username, resp.Password, err = ds.impl.CreateUser(context.Background(), args.Statements, args.UsernameConfig, args.Expiration)
 
 	return err
 }
 
 func (ds *databasePluginRPCServer) RenewUser(args *RenewUserRequest, _ *struct{}) error {
-	err := ds.impl.RenewUser(args.Statements, args.Username, args.Expiration)
+	err := ds.impl.RenewUser(context.Background(), args.Statements, args.Username, args.Expiration)
 
 	return err
 }
 
 func (ds *databasePluginRPCServer) RevokeUser(args *RevokeUserRequest, _ *struct{}) error {
-	err := ds.impl.RevokeUser(args.Statements, args.Username)
+	err := ds.impl.RevokeUser(context.Background(), args.Statements, args.Username)
+
+	return err
+}
+
+func (ds *databasePluginRPCServer) SetCredentials(args *SetCredentialsRequest, resp *SetCredentialsResponse) error {
+	var err error
+	resp.Username, resp.Password, err = ds.impl.SetCredentials(context.Background(), args.Statements, args.StaticUser)
+
+	return err
+}
+
+func (ds *databasePluginRPCServer) RotateRootCredentials(args *RotateRootCredentialsRequest, resp *RotateRootCredentialsResponse) error {
+	var err error
+	resp.Config, err = ds.impl.RotateRootCredentials(context.Background(), args.Statements)
 
 	return err
 }
 
 func (ds *databasePluginRPCServer) Initialize(args *InitializeRequest, _ *struct{}) error {
-	err := ds.impl.Initialize(args.Config, args.VerifyConnection)
+	err := ds.impl.Initialize(context.Background(), args.Config, args.VerifyConnection)
 
 	return err
 }
@@ -8,6 +8,7 @@ import (
 	"github.com/hashicorp/vault/builtin/logical/database/dbplugin"
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/framework"
+	"golang.org/x/net/context"
 )
 
 var (
@@ -23,6 +24,10 @@ type DatabaseConfig struct {
 	// by each database type.
 	ConnectionDetails map[string]interface{} `json:"connection_details" structs:"connection_details" mapstructure:"connection_details"`
 	AllowedRoles      []string               `json:"allowed_roles" structs:"allowed_roles" mapstructure:"allowed_roles"`
+
+	// RootCredentialsRotateStatements are the statements the plugin uses to
+	// rotate the password of the user it connects as.
+	RootCredentialsRotateStatements []string `json:"root_credentials_rotate_statements,omitempty" structs:"root_credentials_rotate_statements,omitempty" mapstructure:"root_credentials_rotate_statements"`
 }
 
 // pathResetConnection configures a path to reset a plugin.
@@ -54,6 +59,9 @@ func (b *databaseBackend) pathConnectionReset() framework.OperationFunc {
 			return logical.ErrorResponse(respErrEmptyName), nil
 		}
 
+		ctx, cancel := context.WithTimeout(context.Background(), pluginRequestTimeout)
+		defer cancel()
+
 		// Grab the mutex lock
 		b.Lock()
 		defer b.Unlock()
@@ -62,7 +70,7 @@ func (b *databaseBackend) pathConnectionReset() framework.OperationFunc {
 		b.clearConnection(name)
 
 		// Execute plugin again, we don't need the object so throw away.
-		_, err := b.createDBObj(req.Storage, name)
+		_, err := b.createDBObj(ctx, req.Storage, name)
 		if err != nil {
 			return nil, err
 		}
@@ -102,6 +110,14 @@ func pathConfigurePluginConnection(b *databaseBackend) *framework.Path {
 				allowed to get creds from this database connection. If empty no
 				roles are allowed. If "*" all roles are allowed.`,
 			},
+
+			"root_rotation_statements": &framework.FieldSchema{
+				Type: framework.TypeStringSlice,
+				Description: `Specifies the database statements to be executed
+				to rotate the root user's credentials. See the plugin's API
+				page for more information on support and formatting for this
+				parameter.`,
+			},
 		},
 
 		Callbacks: map[logical.Operation]framework.OperationFunc{
@@ -159,6 +175,11 @@ func (b *databaseBackend) connectionReadHandler() framework.OperationFunc {
 		if err := entry.DecodeJSON(&config); err != nil {
 			return nil, err
 		}
+
+		// The password of the connection is never returned, it may have been
+		// rotated so that only Vault knows it
+		delete(config.ConnectionDetails, "password")
+
 		return &logical.Response{
 			Data: structs.New(config).Map(),
 		}, nil
@@ -211,6 +232,7 @@ func (b *databaseBackend) connectionWriteHandler() framework.OperationFunc {
 		verifyConnection := data.Get("verify_connection").(bool)
 
 		allowedRoles := data.Get("allowed_roles").([]string)
+		rootRotationStatements := data.Get("root_rotation_statements").([]string)
 
 		// Remove these entries from the data before we store it keyed under
 		// ConnectionDetails.
@@ -218,19 +240,24 @@ func (b *databaseBackend) connectionWriteHandler() framework.OperationFunc {
 		delete(data.Raw, "plugin_name")
 		delete(data.Raw, "allowed_roles")
 		delete(data.Raw, "verify_connection")
+		delete(data.Raw, "root_rotation_statements")
 
 		config := &DatabaseConfig{
-			ConnectionDetails: data.Raw,
-			PluginName:        pluginName,
-			AllowedRoles:      allowedRoles,
+			ConnectionDetails:               data.Raw,
+			PluginName:                      pluginName,
+			AllowedRoles:                    allowedRoles,
+			RootCredentialsRotateStatements: rootRotationStatements,
 		}
 
+		ctx, cancel := context.WithTimeout(context.Background(), pluginRequestTimeout)
+		defer cancel()
+
 		db, err := dbplugin.PluginFactory(config.PluginName, b.System(), b.logger)
 		if err != nil {
 			return logical.ErrorResponse(fmt.Sprintf("error creating database object: %s", err)), nil
 		}
 
-		err = db.Initialize(config.ConnectionDetails, verifyConnection)
+		err = db.Initialize(ctx, config.ConnectionDetails, verifyConnection)
 		if err != nil {
 			db.Close()
 			return logical.ErrorResponse(fmt.Sprintf("error creating database object: %s", err)), nil
@@ -256,7 +283,7 @@ func (b *databaseBackend) connectionWriteHandler() framework.OperationFunc {
 		}
 
 		resp := &logical.Response{}
-		resp.AddWarning("Read access to this endpoint should be controlled via ACLs as it will return the connection details as is, including passwords, if any.")
+		resp.AddWarning("Read access to this endpoint should be controlled via ACLs as it will return the connection details as is, including passwords embedded in the connection URL, if any.")
 
 		return resp, nil
 	}
@@ -282,6 +309,9 @@ accepts:
 	* "verify_connection" (default: true) - A boolean value denoting if the plugin should verify
 	   it is able to connect to the database using the provided connection
        details.
+
+	* "root_rotation_statements" - The statements used to rotate the password
+	   of the user the plugin connects as. See "rotate-root/<name>".
 `
 
 const pathResetConnectionHelpSyn = `
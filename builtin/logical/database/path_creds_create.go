@@ -8,6 +8,7 @@ import (
 	"github.com/hashicorp/vault/helper/strutil"
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/framework"
+	"golang.org/x/net/context"
 )
 
 func pathCredsCreate(b *databaseBackend) *framework.Path {
@@ -53,6 +54,9 @@ func (b *databaseBackend) pathCredsCreateRead() framework.OperationFunc {
 			return nil, logical.ErrPermissionDenied
 		}
 
+		ctx, cancel := context.WithTimeout(context.Background(), pluginRequestTimeout)
+		defer cancel()
+
 		// Grab the read lock
 		b.RLock()
 		var unlockFunc func() = b.RUnlock
@@ -66,7 +70,7 @@ func (b *databaseBackend) pathCredsCreateRead() framework.OperationFunc {
 			unlockFunc = b.Unlock
 
 			// Create a new DB object
-			db, err = b.createDBObj(req.Storage, role.DBName)
+			db, err = b.createDBObj(ctx, req.Storage, role.DBName)
 			if err != nil {
 				unlockFunc()
 				return nil, fmt.Errorf("cound not retrieve db with name: %s, got error: %s", role.DBName, err)
@@ -81,7 +85,7 @@ func (b *databaseBackend) pathCredsCreateRead() framework.OperationFunc {
 		}
 
 		// Create the user
-		username, password, err := db.CreateUser(role.Statements, usernameConfig, expiration)
+		username, password, err := db.CreateUser(ctx, role.Statements, usernameConfig, expiration)
 		// Unlock
 		unlockFunc()
 		if err != nil {
@@ -0,0 +1,104 @@
+package database
+
+import (
+	"fmt"
+	"net/rpc"
+
+	"github.com/hashicorp/vault/builtin/logical/database/dbplugin"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+	"golang.org/x/net/context"
+)
+
+// pathRotateCredentials configures a path to rotate the root credentials of a
+// connection.
+func pathRotateCredentials(b *databaseBackend) *framework.Path {
+	return &framework.Path{
+		Pattern: "rotate-root/" + framework.GenericNameRegex("name"),
+		Fields: map[string]*framework.FieldSchema{
+			"name": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Name of this database connection",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathRotateCredentialsUpdate(),
+		},
+
+		HelpSynopsis:    pathRotateCredentialsUpdateHelpSyn,
+		HelpDescription: pathRotateCredentialsUpdateHelpDesc,
+	}
+}
+
+// pathRotateCredentialsUpdate has the plugin change the password it connects
+// with, stores the new connection details and reinitializes the connection.
+func (b *databaseBackend) pathRotateCredentialsUpdate() framework.OperationFunc {
+	return func(req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		name := data.Get("name").(string)
+		if name == "" {
+			return logical.ErrorResponse(respErrEmptyName), nil
+		}
+
+		config, err := b.DatabaseConfig(req.Storage, name)
+		if err != nil {
+			return nil, err
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), pluginRequestTimeout)
+		defer cancel()
+
+		// Grab the mutex lock
+		b.Lock()
+		defer b.Unlock()
+
+		db, err := b.createDBObj(ctx, req.Storage, name)
+		if err != nil {
+			return nil, fmt.Errorf("could not retrieve db with name: %s, got error: %s", name, err)
+		}
+
+		connectionDetails, err := db.RotateRootCredentials(ctx, config.RootCredentialsRotateStatements)
+		if err != nil {
+			// Plugin has shutdown, close it so next call can reconnect.
+			if err == rpc.ErrShutdown || err == dbplugin.ErrPluginShutdown {
+				b.clearConnection(name)
+			}
+			return logical.ErrorResponse(fmt.Sprintf("failed to rotate root credentials: %s", err)), nil
+		}
+
+		// The password has changed on the database at this point, so the new
+		// connection details have to be stored before anything else can fail
+		config.ConnectionDetails = connectionDetails
+		entry, err := logical.StorageEntryJSON(fmt.Sprintf("config/%s", name), config)
+		if err != nil {
+			return nil, err
+		}
+		if err := req.Storage.Put(entry); err != nil {
+			return nil, err
+		}
+
+		// Close the plugin and run it again with the new connection details
+		b.clearConnection(name)
+		if _, err := b.createDBObj(ctx, req.Storage, name); err != nil {
+			return nil, err
+		}
+
+		return nil, nil
+	}
+}
+
+const pathRotateCredentialsUpdateHelpSyn = `
+Request to rotate the root credentials for a certain database connection.
+`
+
+const pathRotateCredentialsUpdateHelpDesc = `
+This path attempts to rotate the root credentials for the given database
+connection. The plugin sets a password generated by Vault on the user it
+connects as and the connection is reinitialized with it. Once rotated, the
+password is only known to Vault and is not returned when reading the
+connection configuration.
+
+The username and password of the connection have to be configured in the
+"username" and "password" fields and templated into the connection URL as
+"{{username}}" and "{{password}}", if the plugin uses one.
+`
@@ -0,0 +1,80 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/vault/helper/strutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+func pathStaticCredsRead(b *databaseBackend) *framework.Path {
+	return &framework.Path{
+		Pattern: "static-creds/" + framework.GenericNameRegex("name"),
+		Fields: map[string]*framework.FieldSchema{
+			"name": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Name of the static role.",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation: b.pathStaticCredsRead(),
+		},
+
+		HelpSynopsis:    pathStaticCredsReadHelpSyn,
+		HelpDescription: pathStaticCredsReadHelpDesc,
+	}
+}
+
+func (b *databaseBackend) pathStaticCredsRead() framework.OperationFunc {
+	return func(req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		name := data.Get("name").(string)
+
+		// Get the role
+		role, err := b.StaticRole(req.Storage, name)
+		if err != nil {
+			return nil, err
+		}
+		if role == nil {
+			return logical.ErrorResponse(fmt.Sprintf("unknown static role: %s", name)), nil
+		}
+
+		dbConfig, err := b.DatabaseConfig(req.Storage, role.DBName)
+		if err != nil {
+			return nil, err
+		}
+
+		// If role name isn't in the database's allowed roles, send back a
+		// permission denied.
+		if !strutil.StrListContains(dbConfig.AllowedRoles, "*") && !strutil.StrListContainsGlob(dbConfig.AllowedRoles, name) {
+			return nil, logical.ErrPermissionDenied
+		}
+
+		ttl := role.NextRotationTime().Sub(time.Now())
+		if ttl < 0 {
+			ttl = 0
+		}
+
+		return &logical.Response{
+			Data: map[string]interface{}{
+				"username":            role.Username,
+				"password":            role.Password,
+				"last_vault_rotation": role.LastVaultRotation,
+				"rotation_period":     role.RotationPeriod.Seconds(),
+				"ttl":                 int64(ttl.Seconds()),
+			},
+		}, nil
+	}
+}
+
+const pathStaticCredsReadHelpSyn = `
+Request the current credentials of a static role.
+`
+
+const pathStaticCredsReadHelpDesc = `
+This path reads the current credentials of an existing database user managed
+by a static role. The credentials are not leased; the "ttl" field gives the
+number of seconds until Vault rotates the password.
+`
@@ -0,0 +1,284 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/vault/builtin/logical/database/dbplugin"
+	"github.com/hashicorp/vault/helper/strutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+	"golang.org/x/net/context"
+)
+
+const (
+	staticRolePath = "static-role/"
+
+	// minStaticRotationPeriod is the shortest rotation period allowed. Due
+	// rotations are checked once a minute.
+	minStaticRotationPeriod = time.Minute
+)
+
+func pathListStaticRoles(b *databaseBackend) *framework.Path {
+	return &framework.Path{
+		Pattern: "static-roles/?$",
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ListOperation: b.pathStaticRoleList(),
+		},
+
+		HelpSynopsis:    pathStaticRoleHelpSyn,
+		HelpDescription: pathStaticRoleHelpDesc,
+	}
+}
+
+func pathStaticRoles(b *databaseBackend) *framework.Path {
+	return &framework.Path{
+		Pattern: "static-roles/" + framework.GenericNameRegex("name"),
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:        framework.TypeString,
+				Description: "Name of the role.",
+			},
+
+			"db_name": {
+				Type:        framework.TypeString,
+				Description: "Name of the database this role acts on.",
+			},
+			"username": {
+				Type: framework.TypeString,
+				Description: `Name of the existing database user whose
+				password is managed by this role. Cannot be changed once the
+				role is created.`,
+			},
+			"rotation_period": {
+				Type: framework.TypeDurationSecond,
+				Description: `Period after which the password of the user is
+				rotated. Must be at least one minute.`,
+			},
+			"rotation_statements": {
+				Type: framework.TypeString,
+				Description: `Specifies the database statements to be executed
+				to rotate the password of the user. If not set, the default
+				statements of the plugin are used. See the plugin's API page
+				for more information on support and formatting for this
+				parameter.`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathStaticRoleRead(),
+			logical.UpdateOperation: b.pathStaticRoleCreate(),
+			logical.DeleteOperation: b.pathStaticRoleDelete(),
+		},
+
+		HelpSynopsis:    pathStaticRoleHelpSyn,
+		HelpDescription: pathStaticRoleHelpDesc,
+	}
+}
+
+func (b *databaseBackend) pathStaticRoleDelete() framework.OperationFunc {
+	return func(req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		b.staticRoleLock.Lock()
+		defer b.staticRoleLock.Unlock()
+
+		err := req.Storage.Delete(staticRolePath + data.Get("name").(string))
+		if err != nil {
+			return nil, err
+		}
+
+		return nil, nil
+	}
+}
+
+func (b *databaseBackend) pathStaticRoleRead() framework.OperationFunc {
+	return func(req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		role, err := b.StaticRole(req.Storage, data.Get("name").(string))
+		if err != nil {
+			return nil, err
+		}
+		if role == nil {
+			return nil, nil
+		}
+
+		return &logical.Response{
+			Data: map[string]interface{}{
+				"db_name":             role.DBName,
+				"username":            role.Username,
+				"rotation_period":     role.RotationPeriod.Seconds(),
+				"rotation_statements": role.Statements.RotationStatements,
+				"last_vault_rotation": role.LastVaultRotation,
+			},
+		}, nil
+	}
+}
+
+func (b *databaseBackend) pathStaticRoleList() framework.OperationFunc {
+	return func(req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		entries, err := req.Storage.List(staticRolePath)
+		if err != nil {
+			return nil, err
+		}
+
+		return logical.ListResponse(entries), nil
+	}
+}
+
+func (b *databaseBackend) pathStaticRoleCreate() framework.OperationFunc {
+	return func(req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		name := data.Get("name").(string)
+		if name == "" {
+			return logical.ErrorResponse("empty role name attribute given"), nil
+		}
+
+		b.staticRoleLock.Lock()
+		defer b.staticRoleLock.Unlock()
+
+		role, err := b.StaticRole(req.Storage, name)
+		if err != nil {
+			return nil, err
+		}
+		created := role == nil
+		if created {
+			role = &staticRoleEntry{}
+		}
+
+		if dbNameRaw, ok := data.GetOk("db_name"); ok {
+			dbName := dbNameRaw.(string)
+			if !created && dbName != role.DBName {
+				return logical.ErrorResponse("cannot change the database of an existing static role"), nil
+			}
+			role.DBName = dbName
+		}
+		if role.DBName == "" {
+			return logical.ErrorResponse("empty database name attribute given"), nil
+		}
+
+		if usernameRaw, ok := data.GetOk("username"); ok {
+			username := usernameRaw.(string)
+			if !created && username != role.Username {
+				return logical.ErrorResponse("cannot change the username of an existing static role"), nil
+			}
+			role.Username = username
+		}
+		if role.Username == "" {
+			return logical.ErrorResponse("empty username attribute given"), nil
+		}
+
+		if rotationPeriodRaw, ok := data.GetOk("rotation_period"); ok {
+			role.RotationPeriod = time.Duration(rotationPeriodRaw.(int)) * time.Second
+		}
+		if role.RotationPeriod < minStaticRotationPeriod {
+			return logical.ErrorResponse(fmt.Sprintf("rotation_period must be at least %s", minStaticRotationPeriod)), nil
+		}
+
+		if rotationStmtsRaw, ok := data.GetOk("rotation_statements"); ok {
+			role.Statements.RotationStatements = rotationStmtsRaw.(string)
+		}
+
+		dbConfig, err := b.DatabaseConfig(req.Storage, role.DBName)
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+
+		// If role name isn't in the database's allowed roles, send back a
+		// permission denied.
+		if !strutil.StrListContains(dbConfig.AllowedRoles, "*") && !strutil.StrListContainsGlob(dbConfig.AllowedRoles, name) {
+			return nil, logical.ErrPermissionDenied
+		}
+
+		// Vault takes over the password of the user as soon as the role is
+		// created, rather than waiting for the first rotation period to pass
+		if created {
+			ctx, cancel := context.WithTimeout(context.Background(), pluginRequestTimeout)
+			defer cancel()
+
+			if err := b.setStaticAccount(ctx, req.Storage, name, role); err != nil {
+				return nil, fmt.Errorf("failed to set the password of the static user: %s", err)
+			}
+			return nil, nil
+		}
+
+		if err := b.putStaticRole(req.Storage, name, role); err != nil {
+			return nil, err
+		}
+
+		return nil, nil
+	}
+}
+
+// StaticRole returns the static role with the given name, or nil if it does
+// not exist
+func (b *databaseBackend) StaticRole(s logical.Storage, roleName string) (*staticRoleEntry, error) {
+	entry, err := s.Get(staticRolePath + roleName)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var result staticRoleEntry
+	if err := entry.DecodeJSON(&result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (b *databaseBackend) putStaticRole(s logical.Storage, roleName string, role *staticRoleEntry) error {
+	entry, err := logical.StorageEntryJSON(staticRolePath+roleName, role)
+	if err != nil {
+		return err
+	}
+
+	return s.Put(entry)
+}
+
+type staticRoleEntry struct {
+	DBName            string              `json:"db_name" mapstructure:"db_name" structs:"db_name"`
+	Statements        dbplugin.Statements `json:"statements" mapstructure:"statements" structs:"statements"`
+	Username          string              `json:"username" mapstructure:"username" structs:"username"`
+	Password          string              `json:"password" mapstructure:"password" structs:"password"`
+	RotationPeriod    time.Duration       `json:"rotation_period" mapstructure:"rotation_period" structs:"rotation_period"`
+	LastVaultRotation time.Time           `json:"last_vault_rotation" mapstructure:"last_vault_rotation" structs:"last_vault_rotation"`
+}
+
+// NextRotationTime returns the time at which the password of the static user
+// is due for rotation
+func (r *staticRoleEntry) NextRotationTime() time.Time {
+	return r.LastVaultRotation.Add(r.RotationPeriod)
+}
+
+const pathStaticRoleHelpSyn = `
+Manage the static roles that can be created with this backend.
+`
+
+const pathStaticRoleHelpDesc = `
+This path lets you manage the static roles of this backend. A static role maps
+to an existing database user whose password is managed by Vault, rather than
+to users created on demand.
+
+The "db_name" parameter is required and configures the name of the database
+connection to use. The connection must list the role in its "allowed_roles".
+
+The "username" parameter is required and configures the name of the existing
+database user. Vault rotates the password of the user when the role is created
+and then every "rotation_period", which is also required.
+
+The "rotation_statements" parameter customizes the statements used to set the
+password of the user. Some substitution will be done to the statement strings
+for certain keys. The names of the variables must be surrounded by "{{" and
+"}}" to be replaced.
+
+  * "name" - The username of the static user.
+
+  * "password" - The new password generated for the static user.
+
+Example of a decent rotation_statements for a postgresql database plugin:
+
+	ALTER ROLE "{{name}}" WITH PASSWORD '{{password}}';
+
+The current password of the user can be read from the "static-creds/<name>"
+endpoint.
+`
@@ -0,0 +1,242 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/vault/builtin/logical/database/dbplugin"
+	"github.com/hashicorp/vault/helper/consts"
+	"github.com/hashicorp/vault/helper/jsonutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+	"github.com/hashicorp/vault/plugins/helper/database/credsutil"
+	"golang.org/x/net/context"
+)
+
+const (
+	staticRotationWALKind = "staticRotation"
+
+	// minRollbackAge is the age a WAL entry must reach before the rotation it
+	// records is considered interrupted and retried
+	minRollbackAge = 5 * time.Minute
+)
+
+// setCredentialsWAL records a password rotation of a static role before the
+// password is changed on the database
+type setCredentialsWAL struct {
+	RoleName    string `json:"role_name"`
+	Username    string `json:"username"`
+	NewPassword string `json:"new_password"`
+
+	// LastVaultRotation is the rotation time of the role when the rotation
+	// started. If it has changed, a later rotation superseded this one.
+	LastVaultRotation time.Time `json:"last_vault_rotation"`
+}
+
+// periodicFunc rotates the passwords of the static roles that are due for
+// rotation. It is triggered once a minute by the RollbackManager.
+func (b *databaseBackend) periodicFunc(req *logical.Request) error {
+	// Static roles are rotated on the primary and replicated to secondaries
+	if b.System().ReplicationState().HasState(consts.ReplicationPerformanceSecondary) {
+		return nil
+	}
+
+	names, err := req.Storage.List(staticRolePath)
+	if err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		return nil
+	}
+
+	// Roles with an interrupted rotation are retried by the WAL rollback
+	// instead, with the password that may already be set on the database
+	pending, err := pendingStaticRotations(req.Storage)
+	if err != nil {
+		return err
+	}
+
+	var errs *multierror.Error
+	for _, name := range names {
+		if pending[name] {
+			continue
+		}
+		if err := b.rotateIfDue(req.Storage, name); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("failed to rotate static role %s: %v", name, err))
+		}
+	}
+
+	return errs.ErrorOrNil()
+}
+
+// rotateIfDue rotates the password of the named static role if its rotation
+// period has elapsed
+func (b *databaseBackend) rotateIfDue(s logical.Storage, name string) error {
+	b.staticRoleLock.Lock()
+	defer b.staticRoleLock.Unlock()
+
+	role, err := b.StaticRole(s, name)
+	if err != nil || role == nil {
+		return err
+	}
+	if time.Now().Before(role.NextRotationTime()) {
+		return nil
+	}
+
+	if b.logger.IsDebug() {
+		b.logger.Debug("database: rotating static role", "role", name)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), pluginRequestTimeout)
+	defer cancel()
+
+	return b.setStaticAccount(ctx, s, name, role)
+}
+
+// setStaticAccount generates a new password for the user of the static role
+// and sets it on the database. The new password is written to the WAL first,
+// so that a rotation interrupted after the database was changed is retried
+// with the same password. The caller must hold the static role lock.
+func (b *databaseBackend) setStaticAccount(ctx context.Context, s logical.Storage, name string, role *staticRoleEntry) error {
+	password, err := credsutil.RandomAlphaNumeric(20, true)
+	if err != nil {
+		return err
+	}
+
+	walID, err := framework.PutWAL(s, staticRotationWALKind, &setCredentialsWAL{
+		RoleName:          name,
+		Username:          role.Username,
+		NewPassword:       password,
+		LastVaultRotation: role.LastVaultRotation,
+	})
+	if err != nil {
+		return fmt.Errorf("error writing WAL entry: %s", err)
+	}
+
+	if err := b.setCredentials(ctx, s, name, role, password); err != nil {
+		return err
+	}
+
+	// The rotation is complete once the new password is stored, so the WAL
+	// entry is only removed afterwards
+	if err := framework.DeleteWAL(s, walID); err != nil {
+		return fmt.Errorf("failed to commit WAL entry: %s", err)
+	}
+
+	return nil
+}
+
+// setCredentials sets the given password on the database and stores it in
+// the static role
+func (b *databaseBackend) setCredentials(ctx context.Context, s logical.Storage, name string, role *staticRoleEntry, password string) error {
+	// Grab the read lock
+	b.RLock()
+	var unlockFunc func() = b.RUnlock
+
+	// Get the Database object
+	db, ok := b.getDBObj(role.DBName)
+	if !ok {
+		// Upgrade lock
+		b.RUnlock()
+		b.Lock()
+		unlockFunc = b.Unlock
+
+		// Create a new DB object
+		var err error
+		db, err = b.createDBObj(ctx, s, role.DBName)
+		if err != nil {
+			unlockFunc()
+			return fmt.Errorf("could not retrieve db with name: %s, got error: %s", role.DBName, err)
+		}
+	}
+
+	_, _, err := db.SetCredentials(ctx, role.Statements, dbplugin.StaticUserConfig{
+		Username: role.Username,
+		Password: password,
+	})
+	// Unlock
+	unlockFunc()
+	if err != nil {
+		b.closeIfShutdown(role.DBName, err)
+		return err
+	}
+
+	role.Password = password
+	role.LastVaultRotation = time.Now()
+
+	return b.putStaticRole(s, name, role)
+}
+
+// walRollback retries the rotation of a static role that was interrupted.
+// Returning an error keeps the WAL entry, so the rotation is retried on the
+// next rollback.
+func (b *databaseBackend) walRollback(req *logical.Request, kind string, data interface{}) error {
+	if kind != staticRotationWALKind {
+		return fmt.Errorf("unknown type to rollback")
+	}
+
+	// The WAL data is decoded from JSON into a map, so round trip it to get
+	// the time back
+	raw, err := jsonutil.EncodeJSON(data)
+	if err != nil {
+		return err
+	}
+	var entry setCredentialsWAL
+	if err := jsonutil.DecodeJSON(raw, &entry); err != nil {
+		return err
+	}
+
+	b.staticRoleLock.Lock()
+	defer b.staticRoleLock.Unlock()
+
+	role, err := b.StaticRole(req.Storage, entry.RoleName)
+	if err != nil {
+		return err
+	}
+
+	// Nothing to retry if the role was deleted, recreated for another user
+	// or rotated since
+	if role == nil || role.Username != entry.Username || !role.LastVaultRotation.Equal(entry.LastVaultRotation) {
+		return nil
+	}
+
+	if b.logger.IsDebug() {
+		b.logger.Debug("database: retrying interrupted rotation of static role", "role", entry.RoleName)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), pluginRequestTimeout)
+	defer cancel()
+
+	return b.setCredentials(ctx, req.Storage, entry.RoleName, role, entry.NewPassword)
+}
+
+// pendingStaticRotations returns the names of the static roles with a
+// rotation in the WAL
+func pendingStaticRotations(s logical.Storage) (map[string]bool, error) {
+	ids, err := framework.ListWAL(s)
+	if err != nil {
+		return nil, err
+	}
+
+	pending := make(map[string]bool)
+	for _, id := range ids {
+		entry, err := framework.GetWAL(s, id)
+		if err != nil {
+			return nil, err
+		}
+		if entry == nil || entry.Kind != staticRotationWALKind {
+			continue
+		}
+
+		data, ok := entry.Data.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if name, ok := data["role_name"].(string); ok {
+			pending[name] = true
+		}
+	}
+
+	return pending, nil
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/framework"
+	"golang.org/x/net/context"
 )
 
 const SecretCredsType = "creds"
@@ -47,6 +48,9 @@ func (b *databaseBackend) secretCredsRenew() framework.OperationFunc {
 			return nil, err
 		}
 
+		ctx, cancel := context.WithTimeout(context.Background(), pluginRequestTimeout)
+		defer cancel()
+
 		// Grab the read lock
 		b.RLock()
 		var unlockFunc func() = b.RUnlock
@@ -60,7 +64,7 @@ func (b *databaseBackend) secretCredsRenew() framework.OperationFunc {
 			unlockFunc = b.Unlock
 
 			// Create a new DB object
-			db, err = b.createDBObj(req.Storage, role.DBName)
+			db, err = b.createDBObj(ctx, req.Storage, role.DBName)
 			if err != nil {
 				unlockFunc()
 				return nil, fmt.Errorf("cound not retrieve db with name: %s, got error: %s", role.DBName, err)
@@ -69,7 +73,7 @@ func (b *databaseBackend) secretCredsRenew() framework.OperationFunc {
 
 		// Make sure we increase the VALID UNTIL endpoint for this user.
 		if expireTime := resp.Secret.ExpirationTime(); !expireTime.IsZero() {
-			err := db.RenewUser(role.Statements, username, expireTime)
+			err := db.RenewUser(ctx, role.Statements, username, expireTime)
 			// Unlock
 			unlockFunc()
 			if err != nil {
@@ -106,6 +110,9 @@ func (b *databaseBackend) secretCredsRevoke() framework.OperationFunc {
 			return nil, fmt.Errorf("error during revoke: could not find role with name %s", req.Secret.InternalData["role"])
 		}
 
+		ctx, cancel := context.WithTimeout(context.Background(), pluginRequestTimeout)
+		defer cancel()
+
 		// Grab the read lock
 		b.RLock()
 		var unlockFunc func() = b.RUnlock
@@ -119,14 +126,14 @@ func (b *databaseBackend) secretCredsRevoke() framework.OperationFunc {
 			unlockFunc = b.Unlock
 
 			// Create a new DB object
-			db, err = b.createDBObj(req.Storage, role.DBName)
+			db, err = b.createDBObj(ctx, req.Storage, role.DBName)
 			if err != nil {
 				unlockFunc()
 				return nil, fmt.Errorf("cound not retrieve db with name: %s, got error: %s", role.DBName, err)
 			}
 		}
 
-		err = db.RevokeUser(role.Statements, username)
+		err = db.RevokeUser(ctx, role.Statements, username)
 		// Unlock
 		unlockFunc()
 		if err != nil {
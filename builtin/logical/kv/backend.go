@@ -0,0 +1,267 @@
+package kv
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/helper/jsonutil"
+	"github.com/hashicorp/vault/helper/locksutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+const (
+	// defaultMaxVersions is the number of versions kept per key when neither
+	// the key metadata nor the backend configuration specify a limit
+	defaultMaxVersions = 10
+
+	configPath     = "config"
+	metadataPrefix = "metadata/"
+	versionsPrefix = "versions/"
+)
+
+func Factory(conf *logical.BackendConfig) (logical.Backend, error) {
+	b := Backend()
+	if err := b.Setup(conf); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func Backend() *backend {
+	var b backend
+	b.Backend = &framework.Backend{
+		Help: strings.TrimSpace(backendHelp),
+
+		PathsSpecial: &logical.Paths{
+			SealWrapStorage: []string{
+				versionsPrefix,
+			},
+		},
+
+		Paths: []*framework.Path{
+			pathConfig(&b),
+			pathData(&b),
+			pathDelete(&b),
+			pathUndelete(&b),
+			pathDestroy(&b),
+			pathMetadata(&b),
+		},
+
+		Secrets:     []*framework.Secret{},
+		BackendType: logical.TypeLogical,
+	}
+
+	b.locks = locksutil.CreateLocks()
+
+	return &b
+}
+
+type backend struct {
+	*framework.Backend
+
+	// locks guard the metadata and versions of a single key so that
+	// check-and-set writes are serialized
+	locks []*locksutil.LockEntry
+}
+
+// Configuration holds the mount-wide settings of the backend
+type Configuration struct {
+	MaxVersions int  `json:"max_versions" structs:"max_versions" mapstructure:"max_versions"`
+	CasRequired bool `json:"cas_required" structs:"cas_required" mapstructure:"cas_required"`
+}
+
+// KeyMetadata tracks the versions stored for a single key
+type KeyMetadata struct {
+	Key            string                      `json:"key"`
+	Versions       map[uint64]*VersionMetadata `json:"versions"`
+	CurrentVersion uint64                      `json:"current_version"`
+	OldestVersion  uint64                      `json:"oldest_version"`
+	MaxVersions    int                         `json:"max_versions"`
+	CasRequired    bool                        `json:"cas_required"`
+	CreatedTime    time.Time                   `json:"created_time"`
+	UpdatedTime    time.Time                   `json:"updated_time"`
+}
+
+// VersionMetadata holds the state of one version of a key
+type VersionMetadata struct {
+	CreatedTime  time.Time `json:"created_time"`
+	DeletionTime time.Time `json:"deletion_time"`
+	Destroyed    bool      `json:"destroyed"`
+}
+
+// Deleted returns true if the version has been soft deleted
+func (v *VersionMetadata) Deleted() bool {
+	return !v.DeletionTime.IsZero() && !v.DeletionTime.After(time.Now())
+}
+
+// versionData is the stored form of a single version of a key
+type versionData struct {
+	Data        map[string]interface{} `json:"data"`
+	CreatedTime time.Time              `json:"created_time"`
+}
+
+func (b *backend) lockForKey(key string) *locksutil.LockEntry {
+	return locksutil.LockForKey(b.locks, key)
+}
+
+func (b *backend) config(s logical.Storage) (*Configuration, error) {
+	entry, err := s.Get(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var result Configuration
+	if entry == nil {
+		return &result, nil
+	}
+
+	if err := entry.DecodeJSON(&result); err != nil {
+		return nil, fmt.Errorf("error reading configuration: %s", err)
+	}
+
+	return &result, nil
+}
+
+func (b *backend) getKeyMetadata(s logical.Storage, key string) (*KeyMetadata, error) {
+	entry, err := s.Get(metadataPrefix + key)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var meta KeyMetadata
+	if err := jsonutil.DecodeJSON(entry.Value, &meta); err != nil {
+		return nil, fmt.Errorf("error decoding metadata for key %q: %s", key, err)
+	}
+	if meta.Versions == nil {
+		meta.Versions = make(map[uint64]*VersionMetadata)
+	}
+
+	return &meta, nil
+}
+
+func (b *backend) writeKeyMetadata(s logical.Storage, meta *KeyMetadata) error {
+	entry, err := logical.StorageEntryJSON(metadataPrefix+meta.Key, meta)
+	if err != nil {
+		return err
+	}
+	return s.Put(entry)
+}
+
+// versionKey returns the storage path of the given version of a key. The key
+// name is hashed so that version entries live in a flat namespace regardless
+// of how deeply the key itself is nested.
+func versionKey(key string, version uint64) string {
+	sum := sha256.Sum256([]byte(key))
+	return versionsPrefix + hex.EncodeToString(sum[:]) + "/" + strconv.FormatUint(version, 10)
+}
+
+func (b *backend) getVersion(s logical.Storage, key string, version uint64) (*versionData, error) {
+	entry, err := s.Get(versionKey(key, version))
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var result versionData
+	if err := jsonutil.DecodeJSON(entry.Value, &result); err != nil {
+		return nil, fmt.Errorf("error decoding version %d of key %q: %s", version, key, err)
+	}
+
+	return &result, nil
+}
+
+// maxVersions returns the number of versions to keep for the given key
+func maxVersions(config *Configuration, meta *KeyMetadata) int {
+	switch {
+	case meta.MaxVersions > 0:
+		return meta.MaxVersions
+	case config.MaxVersions > 0:
+		return config.MaxVersions
+	default:
+		return defaultMaxVersions
+	}
+}
+
+// trimVersions removes versions older than the configured maximum from both
+// storage and the metadata. The caller is responsible for persisting the
+// metadata afterwards.
+func (b *backend) trimVersions(s logical.Storage, config *Configuration, meta *KeyMetadata) error {
+	if meta.OldestVersion == 0 {
+		meta.OldestVersion = 1
+	}
+
+	max := uint64(maxVersions(config, meta))
+	if meta.CurrentVersion < max {
+		return nil
+	}
+
+	newOldest := meta.CurrentVersion - max + 1
+	for v := meta.OldestVersion; v < newOldest; v++ {
+		if err := s.Delete(versionKey(meta.Key, v)); err != nil {
+			return err
+		}
+		delete(meta.Versions, v)
+	}
+	if newOldest > meta.OldestVersion {
+		meta.OldestVersion = newOldest
+	}
+
+	return nil
+}
+
+// parseVersions converts the "versions" field into a list of version numbers
+func parseVersions(d *framework.FieldData) ([]uint64, error) {
+	raw := d.Get("versions").([]string)
+	if len(raw) == 0 {
+		return nil, fmt.Errorf("no versions provided")
+	}
+
+	versions := make([]uint64, 0, len(raw))
+	for _, v := range raw {
+		version, err := strconv.ParseUint(v, 10, 64)
+		if err != nil || version == 0 {
+			return nil, fmt.Errorf("invalid version %q", v)
+		}
+		versions = append(versions, version)
+	}
+
+	return versions, nil
+}
+
+func versionMetadataResponse(version uint64, vm *VersionMetadata) map[string]interface{} {
+	return map[string]interface{}{
+		"version":       version,
+		"created_time":  vm.CreatedTime,
+		"deletion_time": formatTime(vm.DeletionTime),
+		"destroyed":     vm.Destroyed,
+	}
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339Nano)
+}
+
+const backendHelp = `
+The versioned KV backend stores arbitrary secrets and keeps a configurable
+number of previous versions of each of them.
+
+Secrets are written to and read from "data/<path>". Older versions can be
+read by passing a "version" parameter. Writes may supply a check-and-set
+version in order to avoid overwriting concurrent changes. Versions can be
+soft deleted with "delete/<path>", restored with "undelete/<path>" and
+permanently removed with "destroy/<path>". Per-key settings and version
+history are available at "metadata/<path>".
+`
@@ -0,0 +1,263 @@
+package kv
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+func getBackend(t *testing.T) (logical.Backend, logical.Storage) {
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+
+	b, err := Factory(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return b, config.StorageView
+}
+
+func writeSecret(t *testing.T, b logical.Backend, s logical.Storage, data map[string]interface{}) *logical.Response {
+	resp, err := b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "data/foo",
+		Storage:   s,
+		Data:      data,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+	return resp
+}
+
+func readSecret(t *testing.T, b logical.Backend, s logical.Storage, version int) *logical.Response {
+	req := &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "data/foo",
+		Storage:   s,
+	}
+	if version > 0 {
+		req.Data = map[string]interface{}{"version": version}
+	}
+
+	resp, err := b.HandleRequest(req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+	return resp
+}
+
+func TestVersionedKV_WriteRead(t *testing.T) {
+	b, storage := getBackend(t)
+
+	writeSecret(t, b, storage, map[string]interface{}{
+		"data": map[string]interface{}{"bar": "baz"},
+	})
+	resp := writeSecret(t, b, storage, map[string]interface{}{
+		"bar": "zap",
+	})
+	if resp.Data["version"].(uint64) != 2 {
+		t.Fatalf("bad version: %#v", resp.Data)
+	}
+
+	resp = readSecret(t, b, storage, 0)
+	if !reflect.DeepEqual(resp.Data["data"], map[string]interface{}{"bar": "zap"}) {
+		t.Fatalf("bad data: %#v", resp.Data)
+	}
+
+	resp = readSecret(t, b, storage, 1)
+	if !reflect.DeepEqual(resp.Data["data"], map[string]interface{}{"bar": "baz"}) {
+		t.Fatalf("bad data: %#v", resp.Data)
+	}
+
+	if resp = readSecret(t, b, storage, 3); resp != nil {
+		t.Fatalf("expected no response for missing version, got %#v", resp)
+	}
+}
+
+func TestVersionedKV_CheckAndSet(t *testing.T) {
+	b, storage := getBackend(t)
+
+	writeSecret(t, b, storage, map[string]interface{}{
+		"data":    map[string]interface{}{"bar": "baz"},
+		"options": map[string]interface{}{"cas": 0},
+	})
+
+	resp, err := b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "data/foo",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"data":    map[string]interface{}{"bar": "zap"},
+			"options": map[string]interface{}{"cas": 0},
+		},
+	})
+	if err != logical.ErrInvalidRequest || resp == nil || !resp.IsError() {
+		t.Fatalf("expected cas mismatch, err:%v resp:%#v", err, resp)
+	}
+
+	writeSecret(t, b, storage, map[string]interface{}{
+		"data":    map[string]interface{}{"bar": "zap"},
+		"options": map[string]interface{}{"cas": 1},
+	})
+
+	// Require cas on the key and make sure writes without it are refused
+	resp, err = b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "metadata/foo",
+		Storage:   storage,
+		Data:      map[string]interface{}{"cas_required": true},
+	})
+	if err != nil || resp != nil {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+
+	resp, err = b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "data/foo",
+		Storage:   storage,
+		Data:      map[string]interface{}{"bar": "zip"},
+	})
+	if err != logical.ErrInvalidRequest || resp == nil || !resp.IsError() {
+		t.Fatalf("expected cas to be required, err:%v resp:%#v", err, resp)
+	}
+}
+
+func TestVersionedKV_DeleteUndeleteDestroy(t *testing.T) {
+	b, storage := getBackend(t)
+
+	writeSecret(t, b, storage, map[string]interface{}{"bar": "baz"})
+	writeSecret(t, b, storage, map[string]interface{}{"bar": "zap"})
+
+	// Soft delete the latest version
+	resp, err := b.HandleRequest(&logical.Request{
+		Operation: logical.DeleteOperation,
+		Path:      "data/foo",
+		Storage:   storage,
+	})
+	if err != nil || resp != nil {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+
+	resp = readSecret(t, b, storage, 0)
+	if resp.Data["data"] != nil || resp.Data["metadata"].(map[string]interface{})["deletion_time"] == "" {
+		t.Fatalf("expected deleted version, got %#v", resp.Data)
+	}
+
+	resp, err = b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "undelete/foo",
+		Storage:   storage,
+		Data:      map[string]interface{}{"versions": "2"},
+	})
+	if err != nil || resp != nil {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+
+	resp = readSecret(t, b, storage, 0)
+	if !reflect.DeepEqual(resp.Data["data"], map[string]interface{}{"bar": "zap"}) {
+		t.Fatalf("bad data after undelete: %#v", resp.Data)
+	}
+
+	resp, err = b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "destroy/foo",
+		Storage:   storage,
+		Data:      map[string]interface{}{"versions": []string{"1"}},
+	})
+	if err != nil || resp != nil {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+
+	resp = readSecret(t, b, storage, 1)
+	if resp.Data["data"] != nil || !resp.Data["metadata"].(map[string]interface{})["destroyed"].(bool) {
+		t.Fatalf("expected destroyed version, got %#v", resp.Data)
+	}
+
+	// Destroyed versions cannot be undeleted
+	resp, err = b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "undelete/foo",
+		Storage:   storage,
+		Data:      map[string]interface{}{"versions": "1"},
+	})
+	if err != nil || resp != nil {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+	resp = readSecret(t, b, storage, 1)
+	if resp.Data["data"] != nil {
+		t.Fatalf("expected destroyed version, got %#v", resp.Data)
+	}
+}
+
+func TestVersionedKV_MaxVersions(t *testing.T) {
+	b, storage := getBackend(t)
+
+	resp, err := b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Storage:   storage,
+		Data:      map[string]interface{}{"max_versions": 2},
+	})
+	if err != nil || resp != nil {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+
+	for _, v := range []string{"a", "b", "c"} {
+		writeSecret(t, b, storage, map[string]interface{}{"bar": v})
+	}
+
+	if resp = readSecret(t, b, storage, 1); resp != nil {
+		t.Fatalf("expected version 1 to be trimmed, got %#v", resp)
+	}
+
+	resp, err = b.HandleRequest(&logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "metadata/foo",
+		Storage:   storage,
+	})
+	if err != nil || resp == nil {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+	if resp.Data["current_version"].(uint64) != 3 || resp.Data["oldest_version"].(uint64) != 2 {
+		t.Fatalf("bad metadata: %#v", resp.Data)
+	}
+	if len(resp.Data["versions"].(map[string]interface{})) != 2 {
+		t.Fatalf("bad versions: %#v", resp.Data["versions"])
+	}
+
+	keys, err := storage.List(versionsPrefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 1 {
+		t.Fatalf("bad version keys: %v", keys)
+	}
+
+	// Removing the metadata removes all the versions
+	resp, err = b.HandleRequest(&logical.Request{
+		Operation: logical.DeleteOperation,
+		Path:      "metadata/foo",
+		Storage:   storage,
+	})
+	if err != nil || resp != nil {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+
+	resp, err = b.HandleRequest(&logical.Request{
+		Operation: logical.ListOperation,
+		Path:      "metadata/",
+		Storage:   storage,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Data) != 0 {
+		t.Fatalf("expected no keys, got %#v", resp.Data)
+	}
+	if resp = readSecret(t, b, storage, 0); resp != nil {
+		t.Fatalf("expected no secret, got %#v", resp)
+	}
+}
@@ -0,0 +1,84 @@
+package kv
+
+import (
+	"github.com/fatih/structs"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+func pathConfig(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "config$",
+		Fields: map[string]*framework.FieldSchema{
+			"max_versions": {
+				Type:        framework.TypeInt,
+				Description: "The number of versions to keep for each key. Defaults to 10.",
+			},
+
+			"cas_required": {
+				Type:        framework.TypeBool,
+				Description: "If true, all keys require the cas parameter to be set on all write requests.",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathConfigRead,
+			logical.UpdateOperation: b.pathConfigWrite,
+		},
+
+		HelpSynopsis:    pathConfigHelpSyn,
+		HelpDescription: pathConfigHelpDesc,
+	}
+}
+
+func (b *backend) pathConfigRead(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	config, err := b.config(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: structs.New(config).Map(),
+	}, nil
+}
+
+func (b *backend) pathConfigWrite(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	config, err := b.config(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	if maxVersionsRaw, ok := d.GetOk("max_versions"); ok {
+		config.MaxVersions = maxVersionsRaw.(int)
+		if config.MaxVersions < 0 {
+			return logical.ErrorResponse("max_versions cannot be negative"), nil
+		}
+	}
+
+	if casRequiredRaw, ok := d.GetOk("cas_required"); ok {
+		config.CasRequired = casRequiredRaw.(bool)
+	}
+
+	entry, err := logical.StorageEntryJSON(configPath, config)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(entry); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+const pathConfigHelpSyn = `
+Configures settings for the versioned KV backend.
+`
+
+const pathConfigHelpDesc = `
+This path configures the defaults applied to every key in the backend.
+"max_versions" sets how many versions are kept for keys that do not override
+it in their metadata, and "cas_required" forces every write to supply a
+check-and-set version.
+`
@@ -0,0 +1,253 @@
+package kv
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+	"github.com/mitchellh/mapstructure"
+)
+
+func pathData(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "data/(?P<path>.+)",
+		Fields: map[string]*framework.FieldSchema{
+			"path": {
+				Type:        framework.TypeString,
+				Description: "Location of the secret.",
+			},
+
+			"version": {
+				Type:        framework.TypeInt,
+				Description: "If provided during a read, the value at the version number will be returned.",
+			},
+
+			"data": {
+				Type: framework.TypeMap,
+				Description: `The contents of the secret. If not provided, all
+top-level fields other than "options" are stored.`,
+			},
+
+			"options": {
+				Type: framework.TypeMap,
+				Description: `Options for writing a secret. "cas" sets the
+check-and-set version: the write is only allowed if
+the current version of the secret matches it, and a
+value of 0 only allows the write if the secret does
+not exist yet.`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathDataRead,
+			logical.CreateOperation: b.pathDataWrite,
+			logical.UpdateOperation: b.pathDataWrite,
+			logical.DeleteOperation: b.pathDataDelete,
+		},
+
+		ExistenceCheck: b.pathExistenceCheck,
+
+		HelpSynopsis:    pathDataHelpSyn,
+		HelpDescription: pathDataHelpDesc,
+	}
+}
+
+func (b *backend) pathExistenceCheck(
+	req *logical.Request, d *framework.FieldData) (bool, error) {
+	meta, err := b.getKeyMetadata(req.Storage, d.Get("path").(string))
+	if err != nil {
+		return false, fmt.Errorf("existence check failed: %v", err)
+	}
+
+	return meta != nil, nil
+}
+
+func (b *backend) pathDataRead(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	key := d.Get("path").(string)
+
+	lock := b.lockForKey(key)
+	lock.RLock()
+	defer lock.RUnlock()
+
+	meta, err := b.getKeyMetadata(req.Storage, key)
+	if err != nil {
+		return nil, err
+	}
+	if meta == nil {
+		return nil, nil
+	}
+
+	version := meta.CurrentVersion
+	if versionRaw, ok := d.GetOk("version"); ok && versionRaw.(int) != 0 {
+		if versionRaw.(int) < 0 {
+			return logical.ErrorResponse("version cannot be negative"), nil
+		}
+		version = uint64(versionRaw.(int))
+	}
+
+	vm, ok := meta.Versions[version]
+	if !ok {
+		return nil, nil
+	}
+
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			"data":     nil,
+			"metadata": versionMetadataResponse(version, vm),
+		},
+	}
+
+	// Deleted and destroyed versions still report their metadata so that
+	// clients can tell them apart from versions that never existed
+	if vm.Deleted() || vm.Destroyed {
+		return resp, nil
+	}
+
+	vd, err := b.getVersion(req.Storage, key, version)
+	if err != nil {
+		return nil, err
+	}
+	if vd == nil {
+		return nil, fmt.Errorf("version %d of key %q is missing from storage", version, key)
+	}
+
+	resp.Data["data"] = vd.Data
+
+	return resp, nil
+}
+
+func (b *backend) pathDataWrite(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	key := d.Get("path").(string)
+
+	var data map[string]interface{}
+	if dataRaw, ok := d.GetOk("data"); ok {
+		data = dataRaw.(map[string]interface{})
+	} else {
+		data = make(map[string]interface{}, len(req.Data))
+		for k, v := range req.Data {
+			if k == "options" {
+				continue
+			}
+			data[k] = v
+		}
+	}
+	if len(data) == 0 {
+		return logical.ErrorResponse("missing data fields"), nil
+	}
+
+	var options struct {
+		Cas *int `mapstructure:"cas"`
+	}
+	if optionsRaw, ok := d.GetOk("options"); ok {
+		if err := mapstructure.WeakDecode(optionsRaw, &options); err != nil {
+			return logical.ErrorResponse(fmt.Sprintf("error parsing options: %s", err)), nil
+		}
+	}
+
+	lock := b.lockForKey(key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	config, err := b.config(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	meta, err := b.getKeyMetadata(req.Storage, key)
+	if err != nil {
+		return nil, err
+	}
+	if meta == nil {
+		meta = &KeyMetadata{
+			Key:      key,
+			Versions: make(map[uint64]*VersionMetadata),
+		}
+	}
+
+	switch {
+	case options.Cas != nil:
+		if *options.Cas < 0 || uint64(*options.Cas) != meta.CurrentVersion {
+			return logical.ErrorResponse("check-and-set parameter did not match the current version"), logical.ErrInvalidRequest
+		}
+	case config.CasRequired || meta.CasRequired:
+		return logical.ErrorResponse("check-and-set parameter required for this call"), logical.ErrInvalidRequest
+	}
+
+	now := time.Now().UTC()
+	version := meta.CurrentVersion + 1
+
+	entry, err := logical.StorageEntryJSON(versionKey(key, version), &versionData{
+		Data:        data,
+		CreatedTime: now,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(entry); err != nil {
+		return nil, err
+	}
+
+	vm := &VersionMetadata{
+		CreatedTime: now,
+	}
+	meta.Versions[version] = vm
+	meta.CurrentVersion = version
+	if meta.CreatedTime.IsZero() {
+		meta.CreatedTime = now
+	}
+	meta.UpdatedTime = now
+
+	if err := b.trimVersions(req.Storage, config, meta); err != nil {
+		return nil, err
+	}
+
+	if err := b.writeKeyMetadata(req.Storage, meta); err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: versionMetadataResponse(version, vm),
+	}, nil
+}
+
+// pathDataDelete soft deletes the current version of the key
+func (b *backend) pathDataDelete(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	key := d.Get("path").(string)
+
+	lock := b.lockForKey(key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	meta, err := b.getKeyMetadata(req.Storage, key)
+	if err != nil {
+		return nil, err
+	}
+	if meta == nil {
+		return nil, nil
+	}
+
+	vm, ok := meta.Versions[meta.CurrentVersion]
+	if !ok || vm.Destroyed || vm.Deleted() {
+		return nil, nil
+	}
+
+	vm.DeletionTime = time.Now().UTC()
+
+	return nil, b.writeKeyMetadata(req.Storage, meta)
+}
+
+const pathDataHelpSyn = `
+Write, read, and delete versioned secrets.
+`
+
+const pathDataHelpDesc = `
+This path stores a new version of a secret on every write. Reads return the
+latest version unless a "version" parameter is given. A write can specify a
+check-and-set version in its options; it is rejected unless that version
+matches the current version of the secret. Deleting this path soft deletes
+the latest version, which can be recovered with the "undelete" endpoint.
+`
@@ -0,0 +1,126 @@
+package kv
+
+import (
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+func pathDelete(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "delete/(?P<path>.+)",
+		Fields: map[string]*framework.FieldSchema{
+			"path": {
+				Type:        framework.TypeString,
+				Description: "Location of the secret.",
+			},
+
+			"versions": {
+				Type:        framework.TypeCommaStringSlice,
+				Description: "The versions to be soft deleted.",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathDeleteWrite,
+		},
+
+		HelpSynopsis:    pathDeleteHelpSyn,
+		HelpDescription: pathDeleteHelpDesc,
+	}
+}
+
+func pathUndelete(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "undelete/(?P<path>.+)",
+		Fields: map[string]*framework.FieldSchema{
+			"path": {
+				Type:        framework.TypeString,
+				Description: "Location of the secret.",
+			},
+
+			"versions": {
+				Type:        framework.TypeCommaStringSlice,
+				Description: "The versions to be restored.",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathUndeleteWrite,
+		},
+
+		HelpSynopsis:    pathUndeleteHelpSyn,
+		HelpDescription: pathUndeleteHelpDesc,
+	}
+}
+
+func (b *backend) pathDeleteWrite(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	return b.updateVersions(req, d, func(vm *VersionMetadata) {
+		if !vm.Deleted() {
+			vm.DeletionTime = time.Now().UTC()
+		}
+	})
+}
+
+func (b *backend) pathUndeleteWrite(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	return b.updateVersions(req, d, func(vm *VersionMetadata) {
+		vm.DeletionTime = time.Time{}
+	})
+}
+
+// updateVersions applies the given function to the metadata of every
+// requested version that still exists and has not been destroyed, then
+// persists the key metadata
+func (b *backend) updateVersions(
+	req *logical.Request, d *framework.FieldData, update func(*VersionMetadata)) (*logical.Response, error) {
+	key := d.Get("path").(string)
+
+	versions, err := parseVersions(d)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
+	lock := b.lockForKey(key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	meta, err := b.getKeyMetadata(req.Storage, key)
+	if err != nil {
+		return nil, err
+	}
+	if meta == nil {
+		return nil, nil
+	}
+
+	for _, version := range versions {
+		vm, ok := meta.Versions[version]
+		if !ok || vm.Destroyed {
+			continue
+		}
+		update(vm)
+	}
+
+	return nil, b.writeKeyMetadata(req.Storage, meta)
+}
+
+const pathDeleteHelpSyn = `
+Marks one or more versions as deleted.
+`
+
+const pathDeleteHelpDesc = `
+Soft deletes the given versions of a secret. Their data is kept in storage
+but is no longer returned by reads until the versions are restored with the
+"undelete" endpoint.
+`
+
+const pathUndeleteHelpSyn = `
+Undeletes one or more versions.
+`
+
+const pathUndeleteHelpDesc = `
+Restores the given soft deleted versions of a secret so that they can be
+read again. Destroyed versions cannot be restored.
+`
@@ -0,0 +1,75 @@
+package kv
+
+import (
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+func pathDestroy(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "destroy/(?P<path>.+)",
+		Fields: map[string]*framework.FieldSchema{
+			"path": {
+				Type:        framework.TypeString,
+				Description: "Location of the secret.",
+			},
+
+			"versions": {
+				Type:        framework.TypeCommaStringSlice,
+				Description: "The versions to destroy. Their data will be permanently deleted.",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathDestroyWrite,
+		},
+
+		HelpSynopsis:    pathDestroyHelpSyn,
+		HelpDescription: pathDestroyHelpDesc,
+	}
+}
+
+func (b *backend) pathDestroyWrite(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	key := d.Get("path").(string)
+
+	versions, err := parseVersions(d)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
+	lock := b.lockForKey(key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	meta, err := b.getKeyMetadata(req.Storage, key)
+	if err != nil {
+		return nil, err
+	}
+	if meta == nil {
+		return nil, nil
+	}
+
+	for _, version := range versions {
+		vm, ok := meta.Versions[version]
+		if !ok || vm.Destroyed {
+			continue
+		}
+
+		if err := req.Storage.Delete(versionKey(key, version)); err != nil {
+			return nil, err
+		}
+		vm.Destroyed = true
+	}
+
+	return nil, b.writeKeyMetadata(req.Storage, meta)
+}
+
+const pathDestroyHelpSyn = `
+Permanently removes one or more versions.
+`
+
+const pathDestroyHelpDesc = `
+Deletes the data of the given versions of a secret from storage. The version
+metadata is kept and marked as destroyed; the data cannot be recovered.
+`
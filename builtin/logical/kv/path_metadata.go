@@ -0,0 +1,200 @@
+package kv
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+func pathMetadata(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "metadata/?(?P<path>.*)",
+		Fields: map[string]*framework.FieldSchema{
+			"path": {
+				Type:        framework.TypeString,
+				Description: "Location of the secret.",
+			},
+
+			"max_versions": {
+				Type: framework.TypeInt,
+				Description: `The number of versions to keep. If not set, the
+backend's configured max version is used.`,
+			},
+
+			"cas_required": {
+				Type: framework.TypeBool,
+				Description: `If true the key will require the cas parameter to
+be set on all write requests. If false, the backend's
+configuration will be used.`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathMetadataRead,
+			logical.CreateOperation: b.pathMetadataWrite,
+			logical.UpdateOperation: b.pathMetadataWrite,
+			logical.DeleteOperation: b.pathMetadataDelete,
+			logical.ListOperation:   b.pathMetadataList,
+		},
+
+		ExistenceCheck: b.pathExistenceCheck,
+
+		HelpSynopsis:    pathMetadataHelpSyn,
+		HelpDescription: pathMetadataHelpDesc,
+	}
+}
+
+func (b *backend) pathMetadataList(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	path := d.Get("path").(string)
+	if path != "" && !strings.HasSuffix(path, "/") {
+		path = path + "/"
+	}
+
+	keys, err := req.Storage.List(metadataPrefix + path)
+	if err != nil {
+		return nil, err
+	}
+
+	return logical.ListResponse(keys), nil
+}
+
+func (b *backend) pathMetadataRead(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	key := d.Get("path").(string)
+	if key == "" {
+		return logical.ErrorResponse("missing path"), nil
+	}
+
+	lock := b.lockForKey(key)
+	lock.RLock()
+	defer lock.RUnlock()
+
+	meta, err := b.getKeyMetadata(req.Storage, key)
+	if err != nil {
+		return nil, err
+	}
+	if meta == nil {
+		return nil, nil
+	}
+
+	versionNumbers := make([]uint64, 0, len(meta.Versions))
+	for v := range meta.Versions {
+		versionNumbers = append(versionNumbers, v)
+	}
+	sort.Slice(versionNumbers, func(i, j int) bool { return versionNumbers[i] < versionNumbers[j] })
+
+	versions := make(map[string]interface{}, len(meta.Versions))
+	for _, v := range versionNumbers {
+		vm := meta.Versions[v]
+		versions[fmt.Sprintf("%d", v)] = map[string]interface{}{
+			"created_time":  vm.CreatedTime,
+			"deletion_time": formatTime(vm.DeletionTime),
+			"destroyed":     vm.Destroyed,
+		}
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"versions":        versions,
+			"current_version": meta.CurrentVersion,
+			"oldest_version":  meta.OldestVersion,
+			"max_versions":    meta.MaxVersions,
+			"cas_required":    meta.CasRequired,
+			"created_time":    meta.CreatedTime,
+			"updated_time":    meta.UpdatedTime,
+		},
+	}, nil
+}
+
+func (b *backend) pathMetadataWrite(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	key := d.Get("path").(string)
+	if key == "" {
+		return logical.ErrorResponse("missing path"), nil
+	}
+
+	maxVersionsRaw, maxVersionsOk := d.GetOk("max_versions")
+	casRequiredRaw, casRequiredOk := d.GetOk("cas_required")
+
+	lock := b.lockForKey(key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	meta, err := b.getKeyMetadata(req.Storage, key)
+	if err != nil {
+		return nil, err
+	}
+	if meta == nil {
+		meta = &KeyMetadata{
+			Key:      key,
+			Versions: make(map[uint64]*VersionMetadata),
+		}
+	}
+
+	if maxVersionsOk {
+		meta.MaxVersions = maxVersionsRaw.(int)
+		if meta.MaxVersions < 0 {
+			return logical.ErrorResponse("max_versions cannot be negative"), nil
+		}
+	}
+	if casRequiredOk {
+		meta.CasRequired = casRequiredRaw.(bool)
+	}
+
+	// Lowering the number of versions takes effect immediately rather than
+	// on the next write
+	config, err := b.config(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if err := b.trimVersions(req.Storage, config, meta); err != nil {
+		return nil, err
+	}
+
+	return nil, b.writeKeyMetadata(req.Storage, meta)
+}
+
+// pathMetadataDelete permanently removes all versions and the metadata of a
+// key
+func (b *backend) pathMetadataDelete(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	key := d.Get("path").(string)
+	if key == "" {
+		return logical.ErrorResponse("missing path"), nil
+	}
+
+	lock := b.lockForKey(key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	meta, err := b.getKeyMetadata(req.Storage, key)
+	if err != nil {
+		return nil, err
+	}
+	if meta == nil {
+		return nil, nil
+	}
+
+	for v := range meta.Versions {
+		if err := req.Storage.Delete(versionKey(key, v)); err != nil {
+			return nil, err
+		}
+	}
+
+	return nil, req.Storage.Delete(metadataPrefix + key)
+}
+
+const pathMetadataHelpSyn = `
+Allows interaction with key metadata and settings in the versioned KV backend.
+`
+
+const pathMetadataHelpDesc = `
+This path returns the version history of a key along with its creation and
+update times. Writing to it sets the per-key "max_versions" and
+"cas_required" settings. Deleting it permanently removes every version of the
+key together with its metadata. Listing it returns the stored keys.
+`
@@ -0,0 +1,453 @@
+package pki
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+	jose "gopkg.in/square/go-jose.v2"
+)
+
+const (
+	acmeNonceLifetime      = 15 * time.Minute
+	acmeMaxNonces          = 10000
+	acmeOrderLifetime      = 24 * time.Hour
+	acmeValidationTimeout  = 10 * time.Second
+	acmeMaxChallengeLength = 1024
+
+	acmeStatusPending     = "pending"
+	acmeStatusReady       = "ready"
+	acmeStatusValid       = "valid"
+	acmeStatusInvalid     = "invalid"
+	acmeStatusDeactivated = "deactivated"
+
+	acmeChallengeHTTP01 = "http-01"
+	acmeChallengeDNS01  = "dns-01"
+
+	acmeErrorPrefix = "urn:ietf:params:acme:error:"
+)
+
+// acmeSignatureAlgorithms are the JWS algorithms accepted for ACME requests
+var acmeSignatureAlgorithms = map[string]bool{
+	string(jose.RS256): true,
+	string(jose.ES256): true,
+	string(jose.ES384): true,
+	string(jose.ES512): true,
+}
+
+// acmeResolver looks up the TXT records used to validate dns-01 challenges.
+// It is satisfied by *net.Resolver.
+type acmeResolver interface {
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+}
+
+// acmeDialer connects to the servers answering http-01 challenges
+type acmeDialer func(ctx context.Context, network, address string) (net.Conn, error)
+
+// acmeProblem is an ACME error, as defined in RFC 8555 section 6.7. It is
+// returned to clients as an RFC 7807 problem document.
+type acmeProblem struct {
+	Type   string `json:"type"`
+	Detail string `json:"detail,omitempty"`
+	Status int    `json:"status,omitempty"`
+}
+
+func (p *acmeProblem) Error() string {
+	return fmt.Sprintf("%s: %s", p.Type, p.Detail)
+}
+
+func acmeError(errType string, status int, format string, args ...interface{}) *acmeProblem {
+	return &acmeProblem{
+		Type:   acmeErrorPrefix + errType,
+		Detail: fmt.Sprintf(format, args...),
+		Status: status,
+	}
+}
+
+func acmeMalformed(format string, args ...interface{}) *acmeProblem {
+	return acmeError("malformed", http.StatusBadRequest, format, args...)
+}
+
+func acmeNotFound(format string, args ...interface{}) *acmeProblem {
+	return acmeError("malformed", http.StatusNotFound, format, args...)
+}
+
+func acmeUnauthorized(format string, args ...interface{}) *acmeProblem {
+	return acmeError("unauthorized", http.StatusForbidden, format, args...)
+}
+
+// acmeIdentifier is the identifier of an order or authorization; only "dns"
+// identifiers are supported
+type acmeIdentifier struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type acmeAccount struct {
+	ID         string           `json:"id"`
+	Role       string           `json:"role"`
+	Status     string           `json:"status"`
+	Contact    []string         `json:"contact"`
+	Key        *jose.JSONWebKey `json:"key"`
+	Thumbprint string           `json:"thumbprint"`
+	CreatedAt  time.Time        `json:"created_at"`
+}
+
+type acmeOrder struct {
+	ID               string           `json:"id"`
+	AccountID        string           `json:"account_id"`
+	Status           string           `json:"status"`
+	Expires          time.Time        `json:"expires"`
+	Identifiers      []acmeIdentifier `json:"identifiers"`
+	AuthorizationIDs []string         `json:"authorization_ids"`
+	SerialNumber     string           `json:"serial_number"`
+	Certificate      string           `json:"certificate"`
+}
+
+type acmeAuthorization struct {
+	ID         string           `json:"id"`
+	AccountID  string           `json:"account_id"`
+	Status     string           `json:"status"`
+	Expires    time.Time        `json:"expires"`
+	Identifier acmeIdentifier   `json:"identifier"`
+	Wildcard   bool             `json:"wildcard"`
+	Challenges []*acmeChallenge `json:"challenges"`
+}
+
+type acmeChallenge struct {
+	Type      string       `json:"type"`
+	Token     string       `json:"token"`
+	Status    string       `json:"status"`
+	Validated time.Time    `json:"validated"`
+	Error     *acmeProblem `json:"error"`
+}
+
+// acmeRequest carries the state of a request to the ACME server of a role
+type acmeRequest struct {
+	*logical.Request
+
+	role     *roleEntry
+	roleName string
+
+	// directoryURL is the URL of the ACME directory of the role, which the
+	// URLs of all of its resources start with
+	directoryURL string
+
+	// account is the account that signed the request when it was signed
+	// with the key ID of an account
+	account *acmeAccount
+
+	// jwk is the key that signed the request when it was embedded in the
+	// request, as it is for new accounts
+	jwk *jose.JSONWebKey
+
+	// payload is the verified payload of the JWS; it is empty for
+	// POST-as-GET requests
+	payload []byte
+}
+
+// url returns the URL of a resource of the ACME server of the role
+func (r *acmeRequest) url(path string) string {
+	return r.directoryURL + "/" + path
+}
+
+// decodePayload decodes the JSON payload of the request into out
+func (r *acmeRequest) decodePayload(out interface{}) error {
+	if len(r.payload) == 0 {
+		return acmeMalformed("request payload is empty")
+	}
+	if err := json.Unmarshal(r.payload, out); err != nil {
+		return acmeMalformed("error decoding request payload: %s", err)
+	}
+	return nil
+}
+
+// acmeResponse is the result of an ACME request, written to the client as a
+// raw HTTP response
+type acmeResponse struct {
+	status      int
+	body        interface{}
+	rawBody     []byte
+	contentType string
+	location    string
+	links       []string
+}
+
+// acmeNewNonce creates a nonce that can be used once in an ACME request
+func (b *backend) acmeNewNonce() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	nonce := base64.RawURLEncoding.EncodeToString(buf)
+
+	b.acmeNonceLock.Lock()
+	defer b.acmeNonceLock.Unlock()
+
+	now := time.Now()
+	if len(b.acmeNonces) >= acmeMaxNonces {
+		for n, expiry := range b.acmeNonces {
+			if now.After(expiry) {
+				delete(b.acmeNonces, n)
+			}
+		}
+	}
+	if len(b.acmeNonces) >= acmeMaxNonces {
+		return "", fmt.Errorf("too many outstanding nonces")
+	}
+	b.acmeNonces[nonce] = now.Add(acmeNonceLifetime)
+
+	return nonce, nil
+}
+
+// acmeUseNonce consumes a nonce, returning whether it was valid
+func (b *backend) acmeUseNonce(nonce string) bool {
+	b.acmeNonceLock.Lock()
+	defer b.acmeNonceLock.Unlock()
+
+	expiry, ok := b.acmeNonces[nonce]
+	if !ok {
+		return false
+	}
+	delete(b.acmeNonces, nonce)
+	return time.Now().Before(expiry)
+}
+
+// acmeVerifyJWS verifies the JWS that is the body of every ACME POST
+// request, as described in RFC 8555 section 6.2. Requests creating accounts
+// are signed with the embedded key of the new account, all others with the
+// key of an existing account referenced by its URL.
+func (b *backend) acmeVerifyJWS(ar *acmeRequest, embeddedKey bool) error {
+	body, ok := ar.Data[logical.HTTPRawBody].([]byte)
+	if !ok {
+		return acmeMalformed("request must be a JWS with a content type of application/jose+json")
+	}
+
+	// Only the flattened JSON serialization with all headers protected is
+	// allowed
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return acmeMalformed("error decoding JWS: %s", err)
+	}
+	if _, ok := raw["header"]; ok {
+		return acmeMalformed("JWS must not have an unprotected header")
+	}
+	if _, ok := raw["signatures"]; ok {
+		return acmeMalformed("JWS must use the flattened JSON serialization")
+	}
+
+	jws, err := jose.ParseSigned(string(body))
+	if err != nil {
+		return acmeMalformed("error parsing JWS: %s", err)
+	}
+	if len(jws.Signatures) != 1 {
+		return acmeMalformed("JWS must have exactly one signature")
+	}
+	header := jws.Signatures[0].Header
+
+	if !acmeSignatureAlgorithms[header.Algorithm] {
+		return acmeError("badSignatureAlgorithm", http.StatusBadRequest, "unsupported JWS algorithm %q", header.Algorithm)
+	}
+
+	if !b.acmeUseNonce(header.Nonce) {
+		return acmeError("badNonce", http.StatusBadRequest, "invalid or expired nonce")
+	}
+
+	url, _ := header.ExtraHeaders[jose.HeaderKey("url")].(string)
+	if url != ar.url(strings.TrimPrefix(ar.Path, "acme/"+ar.roleName+"/")) {
+		return acmeUnauthorized("JWS url %q does not match the request URL", url)
+	}
+
+	var key *jose.JSONWebKey
+	switch {
+	case embeddedKey:
+		if header.JSONWebKey == nil || header.KeyID != "" {
+			return acmeMalformed("JWS must be signed with an embedded jwk")
+		}
+		key = header.JSONWebKey
+		switch key.Key.(type) {
+		case *rsa.PublicKey, *ecdsa.PublicKey:
+		default:
+			return acmeError("badPublicKey", http.StatusBadRequest, "unsupported account key type")
+		}
+		ar.jwk = key
+
+	default:
+		if header.KeyID == "" || header.JSONWebKey != nil {
+			return acmeMalformed("JWS must be signed with the kid of an account")
+		}
+		accountPrefix := ar.url("account/")
+		if !strings.HasPrefix(header.KeyID, accountPrefix) {
+			return acmeError("accountDoesNotExist", http.StatusBadRequest, "unknown account %q", header.KeyID)
+		}
+		account, err := b.acmeAccount(ar.Storage, strings.TrimPrefix(header.KeyID, accountPrefix))
+		if err != nil {
+			return err
+		}
+		if account == nil || account.Role != ar.roleName {
+			return acmeError("accountDoesNotExist", http.StatusBadRequest, "unknown account %q", header.KeyID)
+		}
+		if account.Status != acmeStatusValid {
+			return acmeUnauthorized("account is %s", account.Status)
+		}
+		key = account.Key
+		ar.account = account
+	}
+
+	payload, err := jws.Verify(key)
+	if err != nil {
+		return acmeMalformed("JWS verification failed: %s", err)
+	}
+	ar.payload = payload
+
+	return nil
+}
+
+// acmeThumbprint returns the RFC 7638 thumbprint of an account key
+func acmeThumbprint(key *jose.JSONWebKey) (string, error) {
+	thumbprint, err := key.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(thumbprint), nil
+}
+
+// acmeRandomToken returns a random token for challenges
+func acmeRandomToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func (b *backend) acmeAccount(s logical.Storage, id string) (*acmeAccount, error) {
+	var account acmeAccount
+	ok, err := acmeGet(s, "acme/accounts/"+id, &account)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return &account, nil
+}
+
+func (b *backend) acmeOrder(s logical.Storage, accountID, id string) (*acmeOrder, error) {
+	var order acmeOrder
+	ok, err := acmeGet(s, "acme/orders/"+accountID+"/"+id, &order)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return &order, nil
+}
+
+func (b *backend) acmeAuthorization(s logical.Storage, accountID, id string) (*acmeAuthorization, error) {
+	var authz acmeAuthorization
+	ok, err := acmeGet(s, "acme/authz/"+accountID+"/"+id, &authz)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return &authz, nil
+}
+
+func acmeGet(s logical.Storage, key string, out interface{}) (bool, error) {
+	// IDs are taken from URLs, so make sure they cannot reach out of their
+	// storage prefix
+	if strings.Contains(key, "..") {
+		return false, nil
+	}
+	entry, err := s.Get(key)
+	if err != nil {
+		return false, err
+	}
+	if entry == nil {
+		return false, nil
+	}
+	if err := entry.DecodeJSON(out); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func acmePut(s logical.Storage, key string, value interface{}) error {
+	entry, err := logical.StorageEntryJSON(key, value)
+	if err != nil {
+		return err
+	}
+	return s.Put(entry)
+}
+
+// acmeKeyAuthorization returns the key authorization of a challenge token,
+// as defined in RFC 8555 section 8.1
+func acmeKeyAuthorization(token string, account *acmeAccount) string {
+	return token + "." + account.Thumbprint
+}
+
+// acmeValidateChallenge checks whether the client fulfilled a challenge,
+// returning a problem describing why not if it did not
+func (b *backend) acmeValidateChallenge(authz *acmeAuthorization, challenge *acmeChallenge, account *acmeAccount) *acmeProblem {
+	keyAuthz := acmeKeyAuthorization(challenge.Token, account)
+
+	ctx, cancel := context.WithTimeout(context.Background(), acmeValidationTimeout)
+	defer cancel()
+
+	switch challenge.Type {
+	case acmeChallengeHTTP01:
+		url := fmt.Sprintf("http://%s/.well-known/acme-challenge/%s", authz.Identifier.Value, challenge.Token)
+		httpReq, err := http.NewRequest("GET", url, nil)
+		if err != nil {
+			return acmeError("connection", http.StatusBadRequest, "error building request: %s", err)
+		}
+		client := &http.Client{
+			Transport: &http.Transport{
+				DialContext: b.acmeDialer,
+			},
+		}
+		resp, err := client.Do(httpReq.WithContext(ctx))
+		if err != nil {
+			return acmeError("connection", http.StatusBadRequest, "error fetching %s: %s", url, err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return acmeError("incorrectResponse", http.StatusForbidden, "unexpected status %d fetching %s", resp.StatusCode, url)
+		}
+		body, err := ioutil.ReadAll(io.LimitReader(resp.Body, acmeMaxChallengeLength))
+		if err != nil {
+			return acmeError("connection", http.StatusBadRequest, "error reading %s: %s", url, err)
+		}
+		if strings.TrimSpace(string(body)) != keyAuthz {
+			return acmeError("incorrectResponse", http.StatusForbidden, "key authorization at %s does not match", url)
+		}
+
+	case acmeChallengeDNS01:
+		name := "_acme-challenge." + authz.Identifier.Value
+		records, err := b.acmeResolver.LookupTXT(ctx, name)
+		if err != nil {
+			return acmeError("dns", http.StatusBadRequest, "error looking up TXT records of %s: %s", name, err)
+		}
+		digest := sha256.Sum256([]byte(keyAuthz))
+		expected := base64.RawURLEncoding.EncodeToString(digest[:])
+		for _, record := range records {
+			if record == expected {
+				return nil
+			}
+		}
+		return acmeError("incorrectResponse", http.StatusForbidden, "no TXT record of %s matches the key authorization", name)
+
+	default:
+		return acmeMalformed("unsupported challenge type %q", challenge.Type)
+	}
+
+	return nil
+}
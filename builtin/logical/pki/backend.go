@@ -1,6 +1,7 @@
 package pki
 
 import (
+	"net"
 	"strings"
 	"sync"
 	"time"
@@ -32,12 +33,17 @@ func Backend() *backend {
 				"ca",
 				"crl/pem",
 				"crl",
+				"ocsp",
+				"ocsp/*",
+				"acme/*",
 			},
 
 			LocalStorage: []string{
 				"revoked/",
 				"crl",
+				"crls/",
 				"certs/",
+				"acme/",
 			},
 
 			Root: []string{
@@ -47,10 +53,11 @@ func Backend() *backend {
 
 			SealWrapStorage: []string{
 				"config/ca_bundle",
+				"keys/",
 			},
 		},
 
-		Paths: []*framework.Path{
+		Paths: framework.PathAppend([]*framework.Path{
 			pathListRoles(&b),
 			pathRoles(&b),
 			pathGenerateRoot(&b),
@@ -59,9 +66,19 @@ func Backend() *backend {
 			pathDeleteRoot(&b),
 			pathGenerateIntermediate(&b),
 			pathSetSignedIntermediate(&b),
+			pathListIssuers(&b),
+			pathIssuer(&b),
+			pathIssuerCrossSign(&b),
+			pathImportIssuers(&b),
+			pathGenerateIssuerRoot(&b),
+			pathGenerateIssuerIntermediate(&b),
+			pathListKeys(&b),
+			pathKey(&b),
 			pathConfigCA(&b),
+			pathConfigIssuers(&b),
 			pathConfigCRL(&b),
 			pathConfigURLs(&b),
+			pathConfigACME(&b),
 			pathSignVerbatim(&b),
 			pathSign(&b),
 			pathIssue(&b),
@@ -71,10 +88,15 @@ func Backend() *backend {
 			pathFetchCRL(&b),
 			pathFetchCRLViaCertPath(&b),
 			pathFetchValid(&b),
+			pathFetchIssuer(&b),
+			pathFetchIssuerCRL(&b),
 			pathFetchListCerts(&b),
 			pathRevoke(&b),
+			pathOCSP(&b),
 			pathTidy(&b),
 		},
+			pathACME(&b),
+		),
 
 		Secrets: []*framework.Secret{
 			secretCerts(&b),
@@ -85,6 +107,12 @@ func Backend() *backend {
 
 	b.crlLifetime = time.Hour * 72
 
+	b.acmeNonces = make(map[string]time.Time)
+	b.acmeResolver = net.DefaultResolver
+	b.acmeDialer = (&net.Dialer{
+		Timeout: acmeValidationTimeout,
+	}).DialContext
+
 	return &b
 }
 
@@ -93,6 +121,23 @@ type backend struct {
 
 	crlLifetime       time.Duration
 	revokeStorageLock sync.RWMutex
+
+	// issuersLock serializes changes to the issuers and keys of the backend
+	issuersLock sync.RWMutex
+
+	// acmeLock serializes changes to ACME accounts, orders and
+	// authorizations
+	acmeLock sync.Mutex
+
+	// acmeNonces holds the outstanding nonces of the ACME server with their
+	// expiration times
+	acmeNonces    map[string]time.Time
+	acmeNonceLock sync.Mutex
+
+	// acmeResolver and acmeDialer are used to validate ACME challenges; tests
+	// replace them to avoid reaching out to the network
+	acmeResolver acmeResolver
+	acmeDialer   acmeDialer
 }
 
 const backendHelp = `
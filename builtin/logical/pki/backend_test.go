@@ -2692,7 +2692,7 @@ func TestBackend_SignSelfIssued(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	signingBundle, err := fetchCAInfo(&logical.Request{Storage: storage})
+	signingBundle, err := fetchCAInfo(b, &logical.Request{Storage: storage}, defaultIssuerRef)
 	if err != nil {
 		t.Fatal(err)
 	}
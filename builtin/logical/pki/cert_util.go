@@ -12,7 +12,9 @@ import (
 	"encoding/pem"
 	"fmt"
 	"net"
+	"net/url"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -35,19 +37,29 @@ const (
 )
 
 type creationBundle struct {
-	CommonName     string
-	OU             []string
-	Organization   []string
-	DNSNames       []string
-	EmailAddresses []string
-	IPAddresses    []net.IP
-	IsCA           bool
-	KeyType        string
-	KeyBits        int
-	SigningBundle  *caInfoBundle
-	NotAfter       time.Time
-	KeyUsage       x509.KeyUsage
-	ExtKeyUsage    certExtKeyUsage
+	CommonName        string
+	OU                []string
+	Organization      []string
+	Country           []string
+	Locality          []string
+	Province          []string
+	StreetAddress     []string
+	PostalCode        []string
+	DNSNames          []string
+	EmailAddresses    []string
+	IPAddresses       []net.IP
+	URIs              []*url.URL
+	OtherSANs         []otherNameSAN
+	IsCA              bool
+	KeyType           string
+	KeyBits           int
+	SigningBundle     *caInfoBundle
+	NotBefore         time.Time
+	NotAfter          time.Time
+	KeyUsage          x509.KeyUsage
+	ExtKeyUsage       certExtKeyUsage
+	ExtKeyUsageOIDs   []asn1.ObjectIdentifier
+	PolicyIdentifiers []asn1.ObjectIdentifier
 
 	// Only used when signing a CA cert
 	UseCSRValues        bool
@@ -62,7 +74,8 @@ type creationBundle struct {
 
 type caInfoBundle struct {
 	certutil.ParsedCertBundle
-	URLs *urlEntries
+	URLs     *urlEntries
+	IssuerID string
 }
 
 func (b *caInfoBundle) GetCAChain() []*certutil.CertBlock {
@@ -86,11 +99,23 @@ func (b *caInfoBundle) GetCAChain() []*certutil.CertBlock {
 	return chain
 }
 
+// otherNameSAN is an otherName Subject Alternative Name with a UTF-8 string
+// value, e.g. a Microsoft UPN
+type otherNameSAN struct {
+	OID   asn1.ObjectIdentifier
+	Value string
+}
+
 var (
 	hostnameRegex                = regexp.MustCompile(`^(([a-zA-Z0-9]|[a-zA-Z0-9][a-zA-Z0-9\-]*[a-zA-Z0-9])\.)*([A-Za-z0-9]|[A-Za-z0-9][A-Za-z0-9\-]*[A-Za-z0-9])$`)
 	oidExtensionBasicConstraints = []int{2, 5, 29, 19}
+	oidExtensionSubjectAltName   = []int{2, 5, 29, 17}
 )
 
+// The backdating of the validity of certificates of roles predating the
+// not_before_duration setting
+const defaultNotBeforeDuration = 30 * time.Second
+
 func oidInExtensions(oid asn1.ObjectIdentifier, extensions []pkix.Extension) bool {
 	for _, e := range extensions {
 		if e.Id.Equal(oid) {
@@ -141,22 +166,41 @@ func validateKeyTypeLength(keyType string, keyBits int) *logical.Response {
 	return nil
 }
 
-// Fetches the CA info. Unlike other certificates, the CA info is stored
-// in the backend as a CertBundle, because we are storing its private key
-func fetchCAInfo(req *logical.Request) (*caInfoBundle, error) {
-	bundleEntry, err := req.Storage.Get("config/ca_bundle")
+// Fetches the CA info of the given issuer, which may be referenced by ID, by
+// name or as "default". Unlike other certificates, the key of the CA is stored
+// in the backend as well.
+func fetchCAInfo(b *backend, req *logical.Request, issuerRef string) (*caInfoBundle, error) {
+	if err := migrateLegacyCABundle(b, req.Storage); err != nil {
+		return nil, err
+	}
+
+	issuerID, err := resolveIssuerRef(req.Storage, issuerRef)
+	if err != nil {
+		return nil, err
+	}
+	issuer, err := fetchIssuer(req.Storage, issuerID)
 	if err != nil {
-		return nil, errutil.InternalError{Err: fmt.Sprintf("unable to fetch local CA certificate/key: %v", err)}
+		return nil, err
 	}
-	if bundleEntry == nil {
-		return nil, errutil.UserError{Err: "backend must be configured with a CA certificate/key"}
+	if issuer == nil {
+		return nil, errutil.UserError{Err: fmt.Sprintf("unknown issuer %q", issuerRef)}
 	}
-
-	var bundle certutil.CertBundle
-	if err := bundleEntry.DecodeJSON(&bundle); err != nil {
-		return nil, errutil.InternalError{Err: fmt.Sprintf("unable to decode local CA certificate/key: %v", err)}
+	if issuer.KeyID == "" {
+		return nil, errutil.UserError{Err: fmt.Sprintf("issuer %s has no key in this backend and cannot be used for signing", issuer.ID)}
+	}
+	key, err := fetchKey(req.Storage, issuer.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return nil, errutil.InternalError{Err: fmt.Sprintf("key %s of issuer %s not found", issuer.KeyID, issuer.ID)}
 	}
 
+	bundle := &certutil.CertBundle{
+		Certificate:    issuer.Certificate,
+		PrivateKey:     key.PrivateKey,
+		PrivateKeyType: key.PrivateKeyType,
+	}
 	parsedBundle, err := bundle.ToParsedCertBundle()
 	if err != nil {
 		return nil, errutil.InternalError{Err: err.Error()}
@@ -166,7 +210,16 @@ func fetchCAInfo(req *logical.Request) (*caInfoBundle, error) {
 		return nil, errutil.InternalError{Err: "stored CA information not able to be parsed"}
 	}
 
-	caInfo := &caInfoBundle{*parsedBundle, nil}
+	chain, err := issuerChain(req.Storage, issuer)
+	if err != nil {
+		return nil, err
+	}
+	parsedBundle.CAChain = chain[1:]
+
+	caInfo := &caInfoBundle{
+		ParsedCertBundle: *parsedBundle,
+		IssuerID:         issuer.ID,
+	}
 
 	entries, err := getURLs(req)
 	if err != nil {
@@ -416,6 +469,131 @@ func validateNames(req *logical.Request, names []string, role *roleEntry) string
 	return ""
 }
 
+// allowedByGlobs returns whether the value matches one of the given
+// comma-separated glob patterns
+func allowedByGlobs(value, patterns string) bool {
+	for _, pattern := range strutil.ParseDedupAndSortStrings(patterns, ",") {
+		if glob.Glob(pattern, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseOIDs parses a comma-separated list of dotted OIDs
+func parseOIDs(input string) ([]asn1.ObjectIdentifier, error) {
+	oids := []asn1.ObjectIdentifier{}
+	for _, v := range strutil.ParseDedupAndSortStrings(input, ",") {
+		oid, err := parseOID(v)
+		if err != nil {
+			return nil, err
+		}
+		oids = append(oids, oid)
+	}
+	return oids, nil
+}
+
+func parseOID(input string) (asn1.ObjectIdentifier, error) {
+	parts := strings.Split(strings.TrimSpace(input), ".")
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("invalid OID %q", input)
+	}
+	oid := make(asn1.ObjectIdentifier, len(parts))
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid OID %q", input)
+		}
+		oid[i] = n
+	}
+	return oid, nil
+}
+
+// parseOtherSAN parses an otherName SAN of the form "<oid>;UTF8:<value>"
+func parseOtherSAN(input string) (otherNameSAN, error) {
+	splitInput := strings.SplitN(input, ";", 2)
+	if len(splitInput) != 2 {
+		return otherNameSAN{}, fmt.Errorf("other SAN %q is not of the form <oid>;UTF8:<value>", input)
+	}
+	oid, err := parseOID(splitInput[0])
+	if err != nil {
+		return otherNameSAN{}, err
+	}
+	splitType := strings.SplitN(splitInput[1], ":", 2)
+	if len(splitType) != 2 {
+		return otherNameSAN{}, fmt.Errorf("other SAN %q is not of the form <oid>;UTF8:<value>", input)
+	}
+	switch strings.ToUpper(splitType[0]) {
+	case "UTF8", "UTF-8":
+	default:
+		return otherNameSAN{}, fmt.Errorf("other SAN %q has an unsupported type; only UTF8 is supported", input)
+	}
+	return otherNameSAN{
+		OID:   oid,
+		Value: splitType[1],
+	}, nil
+}
+
+// otherSANAllowed returns whether the otherName SAN matches one of the
+// allowed other SANs of the role, which are of the form "<oid>;UTF8:<glob>",
+// or "*" to allow any
+func otherSANAllowed(san otherNameSAN, allowed string) bool {
+	for _, v := range strutil.ParseDedupAndSortStrings(allowed, ",") {
+		if v == "*" {
+			return true
+		}
+		pattern, err := parseOtherSAN(v)
+		if err != nil {
+			continue
+		}
+		if pattern.OID.Equal(san.OID) && glob.Glob(pattern.Value, san.Value) {
+			return true
+		}
+	}
+	return false
+}
+
+// marshalSANs builds the Subject Alternative Name extension value. The x509
+// package cannot encode otherName SANs, so when any are requested the whole
+// extension is built here instead.
+func marshalSANs(dnsNames, emailAddresses []string, ipAddresses []net.IP, uris []*url.URL, otherSANs []otherNameSAN) ([]byte, error) {
+	var rawValues []asn1.RawValue
+	for _, name := range dnsNames {
+		rawValues = append(rawValues, asn1.RawValue{Tag: 2, Class: asn1.ClassContextSpecific, Bytes: []byte(name)})
+	}
+	for _, email := range emailAddresses {
+		rawValues = append(rawValues, asn1.RawValue{Tag: 1, Class: asn1.ClassContextSpecific, Bytes: []byte(email)})
+	}
+	for _, rawIP := range ipAddresses {
+		ip := rawIP.To4()
+		if ip == nil {
+			ip = rawIP
+		}
+		rawValues = append(rawValues, asn1.RawValue{Tag: 7, Class: asn1.ClassContextSpecific, Bytes: ip})
+	}
+	for _, uri := range uris {
+		rawValues = append(rawValues, asn1.RawValue{Tag: 6, Class: asn1.ClassContextSpecific, Bytes: []byte(uri.String())})
+	}
+	for _, san := range otherSANs {
+		value, err := asn1.MarshalWithParams(san.Value, "utf8")
+		if err != nil {
+			return nil, err
+		}
+		otherName, err := asn1.MarshalWithParams(struct {
+			TypeID asn1.ObjectIdentifier
+			Value  asn1.RawValue
+		}{
+			TypeID: san.OID,
+			Value:  asn1.RawValue{Tag: 0, Class: asn1.ClassContextSpecific, IsCompound: true, Bytes: value},
+		}, "tag:0")
+		if err != nil {
+			return nil, err
+		}
+		rawValues = append(rawValues, asn1.RawValue{FullBytes: otherName})
+	}
+	return asn1.Marshal(rawValues)
+}
+
 func generateCert(b *backend,
 	role *roleEntry,
 	signingBundle *caInfoBundle,
@@ -711,6 +889,48 @@ func generateCreationBundle(b *backend,
 		}
 	}
 
+	// Get and verify any URI SANs
+	uris := []*url.URL{}
+	{
+		if csr != nil && role.UseCSRSANs {
+			uris = csr.URIs
+		} else if uriAltRaw, ok := data.GetOk("uri_sans"); ok {
+			for _, v := range strutil.ParseDedupAndSortStrings(uriAltRaw.(string), ",") {
+				parsedURI, err := url.Parse(v)
+				if err != nil || parsedURI.Scheme == "" {
+					return nil, errutil.UserError{Err: fmt.Sprintf(
+						"the value '%s' is not a valid URI", v)}
+				}
+				uris = append(uris, parsedURI)
+			}
+		}
+
+		for _, uri := range uris {
+			if !allowedByGlobs(uri.String(), role.AllowedURISANs) {
+				return nil, errutil.UserError{Err: fmt.Sprintf(
+					"URI Subject Alternative Name %s not allowed by this role", uri)}
+			}
+		}
+	}
+
+	// Get and verify any otherName SANs
+	otherSANs := []otherNameSAN{}
+	{
+		if otherAltRaw, ok := data.GetOk("other_sans"); ok {
+			for _, v := range strutil.ParseDedupAndSortStrings(otherAltRaw.(string), ",") {
+				san, err := parseOtherSAN(v)
+				if err != nil {
+					return nil, errutil.UserError{Err: err.Error()}
+				}
+				if !otherSANAllowed(san, role.AllowedOtherSANs) {
+					return nil, errutil.UserError{Err: fmt.Sprintf(
+						"other Subject Alternative Name %s not allowed by this role", v)}
+				}
+				otherSANs = append(otherSANs, san)
+			}
+		}
+	}
+
 	// Set OU (organizationalUnit) values if specified in the role
 	ou := []string{}
 	{
@@ -727,6 +947,42 @@ func generateCreationBundle(b *backend,
 		}
 	}
 
+	// Set the remaining subject values specified in the role
+	subjectValues := func(input string) []string {
+		if input == "" {
+			return []string{}
+		}
+		return strutil.RemoveDuplicates(strutil.ParseStringSlice(input, ","), false)
+	}
+	country := subjectValues(role.Country)
+	locality := subjectValues(role.Locality)
+	province := subjectValues(role.Province)
+	streetAddress := subjectValues(role.StreetAddress)
+	postalCode := subjectValues(role.PostalCode)
+
+	// Parse the custom extended key usages and policies of the role
+	extKeyUsageOIDs, err := parseOIDs(role.ExtKeyUsageOIDs)
+	if err != nil {
+		return nil, errutil.UserError{Err: fmt.Sprintf(
+			"invalid role ext_key_usage_oids: %s", err)}
+	}
+	policyIdentifiers, err := parseOIDs(role.PolicyIdentifiers)
+	if err != nil {
+		return nil, errutil.UserError{Err: fmt.Sprintf(
+			"invalid role policy_identifiers: %s", err)}
+	}
+
+	// Backdate the validity to allow for clock skew
+	notBeforeDuration := defaultNotBeforeDuration
+	if role.NotBeforeDuration != "" {
+		notBeforeDuration, err = parseutil.ParseDurationSecond(role.NotBeforeDuration)
+		if err != nil {
+			return nil, errutil.UserError{Err: fmt.Sprintf(
+				"invalid role not_before_duration: %s", err)}
+		}
+	}
+	notBefore := time.Now().Add(-notBeforeDuration)
+
 	// Get the TTL and verify it against the max allowed
 	var ttl time.Duration
 	var maxTTL time.Duration
@@ -792,18 +1048,28 @@ func generateCreationBundle(b *backend,
 	}
 
 	creationBundle := &creationBundle{
-		CommonName:     cn,
-		OU:             ou,
-		Organization:   organization,
-		DNSNames:       dnsNames,
-		EmailAddresses: emailAddresses,
-		IPAddresses:    ipAddresses,
-		KeyType:        role.KeyType,
-		KeyBits:        role.KeyBits,
-		SigningBundle:  signingBundle,
-		NotAfter:       notAfter,
-		KeyUsage:       x509.KeyUsage(parseKeyUsages(role.KeyUsage)),
-		ExtKeyUsage:    extUsage,
+		CommonName:        cn,
+		OU:                ou,
+		Organization:      organization,
+		Country:           country,
+		Locality:          locality,
+		Province:          province,
+		StreetAddress:     streetAddress,
+		PostalCode:        postalCode,
+		DNSNames:          dnsNames,
+		EmailAddresses:    emailAddresses,
+		IPAddresses:       ipAddresses,
+		URIs:              uris,
+		OtherSANs:         otherSANs,
+		KeyType:           role.KeyType,
+		KeyBits:           role.KeyBits,
+		SigningBundle:     signingBundle,
+		NotBefore:         notBefore,
+		NotAfter:          notAfter,
+		KeyUsage:          x509.KeyUsage(parseKeyUsages(role.KeyUsage)),
+		ExtKeyUsage:       extUsage,
+		ExtKeyUsageOIDs:   extKeyUsageOIDs,
+		PolicyIdentifiers: policyIdentifiers,
 	}
 
 	// Don't deal with URLs or max path length if it's self-signed, as these
@@ -860,6 +1126,46 @@ func addKeyUsages(creationInfo *creationBundle, certTemplate *x509.Certificate)
 	if creationInfo.ExtKeyUsage&emailProtectionExtKeyUsage != 0 {
 		certTemplate.ExtKeyUsage = append(certTemplate.ExtKeyUsage, x509.ExtKeyUsageEmailProtection)
 	}
+
+	certTemplate.UnknownExtKeyUsage = creationInfo.ExtKeyUsageOIDs
+}
+
+// subject returns the subject name requested in the creation information
+func (c *creationBundle) subject() pkix.Name {
+	return pkix.Name{
+		CommonName:         c.CommonName,
+		OrganizationalUnit: c.OU,
+		Organization:       c.Organization,
+		Country:            c.Country,
+		Locality:           c.Locality,
+		Province:           c.Province,
+		StreetAddress:      c.StreetAddress,
+		PostalCode:         c.PostalCode,
+	}
+}
+
+// addSANs sets the Subject Alternative Names requested in the creation
+// information on the template
+func addSANs(creationInfo *creationBundle, certTemplate *x509.Certificate) error {
+	certTemplate.DNSNames = creationInfo.DNSNames
+	certTemplate.EmailAddresses = creationInfo.EmailAddresses
+	certTemplate.IPAddresses = creationInfo.IPAddresses
+	certTemplate.URIs = creationInfo.URIs
+
+	if len(creationInfo.OtherSANs) == 0 {
+		return nil
+	}
+
+	sanBytes, err := marshalSANs(creationInfo.DNSNames, creationInfo.EmailAddresses,
+		creationInfo.IPAddresses, creationInfo.URIs, creationInfo.OtherSANs)
+	if err != nil {
+		return errutil.InternalError{Err: fmt.Sprintf("error marshaling subject alternative names: %s", err)}
+	}
+	certTemplate.ExtraExtensions = append(certTemplate.ExtraExtensions, pkix.Extension{
+		Id:    oidExtensionSubjectAltName,
+		Value: sanBytes,
+	})
+	return nil
 }
 
 // Performs the heavy lifting of creating a certificate. Returns
@@ -884,22 +1190,18 @@ func createCertificate(creationInfo *creationBundle) (*certutil.ParsedCertBundle
 		return nil, errutil.InternalError{Err: fmt.Sprintf("error getting subject key ID: %s", err)}
 	}
 
-	subject := pkix.Name{
-		CommonName:         creationInfo.CommonName,
-		OrganizationalUnit: creationInfo.OU,
-		Organization:       creationInfo.Organization,
+	certTemplate := &x509.Certificate{
+		SerialNumber:      serialNumber,
+		Subject:           creationInfo.subject(),
+		NotBefore:         creationInfo.NotBefore,
+		NotAfter:          creationInfo.NotAfter,
+		IsCA:              false,
+		SubjectKeyId:      subjKeyID,
+		PolicyIdentifiers: creationInfo.PolicyIdentifiers,
 	}
 
-	certTemplate := &x509.Certificate{
-		SerialNumber:   serialNumber,
-		Subject:        subject,
-		NotBefore:      time.Now().Add(-30 * time.Second),
-		NotAfter:       creationInfo.NotAfter,
-		IsCA:           false,
-		SubjectKeyId:   subjKeyID,
-		DNSNames:       creationInfo.DNSNames,
-		EmailAddresses: creationInfo.EmailAddresses,
-		IPAddresses:    creationInfo.IPAddresses,
+	if err := addSANs(creationInfo, certTemplate); err != nil {
+		return nil, err
 	}
 
 	// Add this before calling addKeyUsages
@@ -1063,19 +1365,14 @@ func signCertificate(creationInfo *creationBundle,
 
 	caCert := creationInfo.SigningBundle.Certificate
 
-	subject := pkix.Name{
-		CommonName:         creationInfo.CommonName,
-		OrganizationalUnit: creationInfo.OU,
-		Organization:       creationInfo.Organization,
-	}
-
 	certTemplate := &x509.Certificate{
-		SerialNumber:   serialNumber,
-		Subject:        subject,
-		NotBefore:      time.Now().Add(-30 * time.Second),
-		NotAfter:       creationInfo.NotAfter,
-		SubjectKeyId:   subjKeyID[:],
-		AuthorityKeyId: caCert.SubjectKeyId,
+		SerialNumber:      serialNumber,
+		Subject:           creationInfo.subject(),
+		NotBefore:         creationInfo.NotBefore,
+		NotAfter:          creationInfo.NotAfter,
+		SubjectKeyId:      subjKeyID[:],
+		AuthorityKeyId:    caCert.SubjectKeyId,
+		PolicyIdentifiers: creationInfo.PolicyIdentifiers,
 	}
 
 	switch creationInfo.SigningBundle.PrivateKeyType {
@@ -1091,12 +1388,13 @@ func signCertificate(creationInfo *creationBundle,
 		certTemplate.DNSNames = csr.DNSNames
 		certTemplate.EmailAddresses = csr.EmailAddresses
 		certTemplate.IPAddresses = csr.IPAddresses
+		certTemplate.URIs = csr.URIs
 
 		certTemplate.ExtraExtensions = csr.Extensions
 	} else {
-		certTemplate.DNSNames = creationInfo.DNSNames
-		certTemplate.EmailAddresses = creationInfo.EmailAddresses
-		certTemplate.IPAddresses = creationInfo.IPAddresses
+		if err := addSANs(creationInfo, certTemplate); err != nil {
+			return nil, err
+		}
 	}
 
 	addKeyUsages(creationInfo, certTemplate)
@@ -1,6 +1,7 @@
 package pki
 
 import (
+	"bytes"
 	"crypto/rand"
 	"crypto/x509"
 	"crypto/x509/pkix"
@@ -117,17 +118,24 @@ func revokeCert(b *backend, req *logical.Request, serial string, fromLease bool)
 	return resp, nil
 }
 
-// Builds a CRL by going through the list of revoked certificates and building
-// a new CRL with the stored revocation times and serial numbers.
+// Builds a CRL for every issuer holding a key by going through the list of
+// revoked certificates and building new CRLs with the stored revocation times
+// and serial numbers of the certificates each issuer signed. The default
+// issuer also signs the CRL served from the "crl" endpoints.
 func buildCRL(b *backend, req *logical.Request) error {
+	if err := migrateLegacyCABundle(b, req.Storage); err != nil {
+		return err
+	}
+
 	revokedSerials, err := req.Storage.List("revoked/")
 	if err != nil {
 		return errutil.InternalError{Err: fmt.Sprintf("Error fetching list of revoked certs: %s", err)}
 	}
 
-	revokedCerts := []pkix.RevokedCertificate{}
-	var revInfo revocationInfo
+	var revokedCerts []*x509.Certificate
+	revokedEntries := []pkix.RevokedCertificate{}
 	for _, serial := range revokedSerials {
+		var revInfo revocationInfo
 		revokedEntry, err := req.Storage.Get("revoked/" + serial)
 		if err != nil {
 			return errutil.InternalError{Err: fmt.Sprintf("Unable to fetch revoked cert with serial %s: %s", serial, err)}
@@ -149,7 +157,7 @@ func buildCRL(b *backend, req *logical.Request) error {
 
 		revokedCert, err := x509.ParseCertificate(revInfo.CertificateBytes)
 		if err != nil {
-			return errutil.InternalError{Err: fmt.Sprintf("Unable to parse stored revoked certificate with serial %s: %s", serial, err)}
+			return errutil.InternalError{Err: fmt.Sprintf("Unable to parse stored revoked cert with serial %s: %s", serial, err)}
 		}
 
 		// NOTE: We have to change this to UTC time because the CRL standard
@@ -162,15 +170,8 @@ func buildCRL(b *backend, req *logical.Request) error {
 		} else {
 			newRevCert.RevocationTime = time.Unix(revInfo.RevocationTime, 0).UTC()
 		}
-		revokedCerts = append(revokedCerts, newRevCert)
-	}
-
-	signingBundle, caErr := fetchCAInfo(req)
-	switch caErr.(type) {
-	case errutil.UserError:
-		return errutil.UserError{Err: fmt.Sprintf("Could not fetch the CA certificate: %s", caErr)}
-	case errutil.InternalError:
-		return errutil.InternalError{Err: fmt.Sprintf("Error fetching CA certificate: %s", caErr)}
+		revokedCerts = append(revokedCerts, revokedCert)
+		revokedEntries = append(revokedEntries, newRevCert)
 	}
 
 	crlLifetime := b.crlLifetime
@@ -186,17 +187,78 @@ func buildCRL(b *backend, req *logical.Request) error {
 		crlLifetime = crlDur
 	}
 
-	crlBytes, err := signingBundle.Certificate.CreateCRL(rand.Reader, signingBundle.PrivateKey, revokedCerts, time.Now(), time.Now().Add(crlLifetime))
+	config, err := fetchIssuerConfig(req.Storage)
 	if err != nil {
-		return errutil.InternalError{Err: fmt.Sprintf("Error creating new CRL: %s", err)}
+		return err
 	}
-
-	err = req.Storage.Put(&logical.StorageEntry{
-		Key:   "crl",
-		Value: crlBytes,
-	})
+	issuerIDs, err := listIssuers(req.Storage)
 	if err != nil {
-		return errutil.InternalError{Err: fmt.Sprintf("Error storing CRL: %s", err)}
+		return err
+	}
+
+	built := false
+	for _, issuerID := range issuerIDs {
+		issuer, err := fetchIssuer(req.Storage, issuerID)
+		if err != nil {
+			return err
+		}
+		if issuer == nil || issuer.KeyID == "" {
+			continue
+		}
+
+		signingBundle, caErr := fetchCAInfo(b, req, issuerID)
+		switch caErr.(type) {
+		case errutil.UserError:
+			return errutil.UserError{Err: fmt.Sprintf("Could not fetch the CA certificate: %s", caErr)}
+		case errutil.InternalError:
+			return errutil.InternalError{Err: fmt.Sprintf("Error fetching CA certificate: %s", caErr)}
+		}
+
+		revoked := []pkix.RevokedCertificate{}
+		for i, revokedCert := range revokedCerts {
+			if !bytes.Equal(revokedCert.RawIssuer, signingBundle.Certificate.RawSubject) {
+				continue
+			}
+			if revokedCert.CheckSignatureFrom(signingBundle.Certificate) != nil {
+				continue
+			}
+			revoked = append(revoked, revokedEntries[i])
+		}
+
+		crlBytes, err := signingBundle.Certificate.CreateCRL(rand.Reader, signingBundle.PrivateKey, revoked, time.Now(), time.Now().Add(crlLifetime))
+		if err != nil {
+			return errutil.InternalError{Err: fmt.Sprintf("Error creating new CRL: %s", err)}
+		}
+
+		err = req.Storage.Put(&logical.StorageEntry{
+			Key:   "crls/" + issuerID,
+			Value: crlBytes,
+		})
+		if err != nil {
+			return errutil.InternalError{Err: fmt.Sprintf("Error storing CRL: %s", err)}
+		}
+
+		// As before the backend could hold several issuers, the CRL served
+		// from the "crl" endpoints holds every revoked certificate
+		if issuerID == config.DefaultIssuerID {
+			crlBytes, err := signingBundle.Certificate.CreateCRL(rand.Reader, signingBundle.PrivateKey, revokedEntries, time.Now(), time.Now().Add(crlLifetime))
+			if err != nil {
+				return errutil.InternalError{Err: fmt.Sprintf("Error creating new CRL: %s", err)}
+			}
+
+			err = req.Storage.Put(&logical.StorageEntry{
+				Key:   "crl",
+				Value: crlBytes,
+			})
+			if err != nil {
+				return errutil.InternalError{Err: fmt.Sprintf("Error storing CRL: %s", err)}
+			}
+		}
+		built = true
+	}
+
+	if !built {
+		return errutil.UserError{Err: "Could not fetch the CA certificate: backend must be configured with a CA certificate/key"}
 	}
 
 	return nil
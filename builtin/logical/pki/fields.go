@@ -58,6 +58,21 @@ is enabled for the role, this may contain
 email addresses.`,
 	}
 
+	fields["uri_sans"] = &framework.FieldSchema{
+		Type: framework.TypeString,
+		Description: `The requested URI SANs, if any, in a
+comma-delimited list. Each must be allowed by
+the allowed_uri_sans of the role.`,
+	}
+
+	fields["other_sans"] = &framework.FieldSchema{
+		Type: framework.TypeString,
+		Description: `The requested otherName SANs, if any, in a
+comma-delimited list of the form
+"<oid>;UTF8:<value>". Each must be allowed by the
+allowed_other_sans of the role.`,
+	}
+
 	fields["ttl"] = &framework.FieldSchema{
 		Type: framework.TypeDurationSecond,
 		Description: `The requested Time To Live for the certificate;
@@ -132,6 +147,12 @@ the key_type.`,
 and "ec" are the only valid values.`,
 	}
 
+	fields["key_name"] = &framework.FieldSchema{
+		Type: framework.TypeString,
+		Description: `An optional name for the generated key, which
+can be used instead of its ID to refer to it.`,
+	}
+
 	return fields
 }
 
@@ -151,3 +172,28 @@ func addCAIssueFields(fields map[string]*framework.FieldSchema) map[string]*fram
 
 	return fields
 }
+
+// addIssuerNameField adds the name of an issuer created by the request
+func addIssuerNameField(fields map[string]*framework.FieldSchema) map[string]*framework.FieldSchema {
+	fields["issuer_name"] = &framework.FieldSchema{
+		Type: framework.TypeString,
+		Description: `An optional name for the new issuer, which can
+be used instead of its ID to refer to it.`,
+	}
+
+	return fields
+}
+
+// addIssuerRefField adds the reference to the issuer that signs a
+// certificate
+func addIssuerRefField(fields map[string]*framework.FieldSchema) map[string]*framework.FieldSchema {
+	fields["issuer_ref"] = &framework.FieldSchema{
+		Type:    framework.TypeString,
+		Default: defaultIssuerRef,
+		Description: `The ID or name of the issuer that signs the
+certificate. Defaults to "default", the default
+issuer of the backend.`,
+	}
+
+	return fields
+}
@@ -0,0 +1,618 @@
+package pki
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/helper/certutil"
+	"github.com/hashicorp/vault/helper/errutil"
+	"github.com/hashicorp/vault/logical"
+)
+
+const (
+	// defaultIssuerRef refers to the default issuer of the backend wherever
+	// an issuer reference is accepted
+	defaultIssuerRef = "default"
+
+	// legacyCABundleKey is where the CA certificate and key were stored
+	// before the backend could hold several issuers
+	legacyCABundleKey = "config/ca_bundle"
+)
+
+// issuerEntry is a CA certificate held by the backend. Several issuers may
+// share a key, for instance a root and a cross-signed copy of it.
+type issuerEntry struct {
+	ID           string   `json:"id"`
+	Name         string   `json:"name"`
+	KeyID        string   `json:"key_id"`
+	Certificate  string   `json:"certificate"`
+	CAChain      []string `json:"ca_chain"`
+	SerialNumber string   `json:"serial_number"`
+}
+
+// keyEntry is a private key held by the backend
+type keyEntry struct {
+	ID             string                  `json:"id"`
+	Name           string                  `json:"name"`
+	PrivateKeyType certutil.PrivateKeyType `json:"private_key_type"`
+	PrivateKey     string                  `json:"private_key"`
+}
+
+// issuerConfig holds the issuer used when none is given explicitly
+type issuerConfig struct {
+	DefaultIssuerID string `json:"default"`
+}
+
+func (i *issuerEntry) parseCertificate() (*x509.Certificate, error) {
+	block, _ := pem.Decode([]byte(i.Certificate))
+	if block == nil {
+		return nil, errutil.InternalError{Err: fmt.Sprintf("unable to decode the certificate of issuer %s", i.ID)}
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, errutil.InternalError{Err: fmt.Sprintf("unable to parse the certificate of issuer %s: %v", i.ID, err)}
+	}
+	return cert, nil
+}
+
+func fetchIssuerConfig(s logical.Storage) (*issuerConfig, error) {
+	entry, err := s.Get("config/issuers")
+	if err != nil {
+		return nil, errutil.InternalError{Err: fmt.Sprintf("unable to fetch the issuer configuration: %v", err)}
+	}
+
+	config := &issuerConfig{}
+	if entry == nil {
+		return config, nil
+	}
+	if err := entry.DecodeJSON(config); err != nil {
+		return nil, errutil.InternalError{Err: fmt.Sprintf("unable to decode the issuer configuration: %v", err)}
+	}
+	return config, nil
+}
+
+// setDefaultIssuer stores the default issuer, and keeps the certificate
+// served by the "ca" endpoints in sync with it
+func setDefaultIssuer(s logical.Storage, issuerID string) error {
+	entry, err := logical.StorageEntryJSON("config/issuers", &issuerConfig{
+		DefaultIssuerID: issuerID,
+	})
+	if err != nil {
+		return err
+	}
+	if err := s.Put(entry); err != nil {
+		return err
+	}
+
+	if issuerID == "" {
+		return s.Delete("ca")
+	}
+
+	issuer, err := fetchIssuer(s, issuerID)
+	if err != nil {
+		return err
+	}
+	if issuer == nil {
+		return errutil.UserError{Err: fmt.Sprintf("unknown issuer %s", issuerID)}
+	}
+	cert, err := issuer.parseCertificate()
+	if err != nil {
+		return err
+	}
+	return s.Put(&logical.StorageEntry{
+		Key:   "ca",
+		Value: cert.Raw,
+	})
+}
+
+func listIssuers(s logical.Storage) ([]string, error) {
+	ids, err := s.List("issuers/")
+	if err != nil {
+		return nil, errutil.InternalError{Err: fmt.Sprintf("unable to list issuers: %v", err)}
+	}
+	return ids, nil
+}
+
+func fetchIssuer(s logical.Storage, id string) (*issuerEntry, error) {
+	entry, err := s.Get("issuers/" + id)
+	if err != nil {
+		return nil, errutil.InternalError{Err: fmt.Sprintf("unable to fetch issuer %s: %v", id, err)}
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var issuer issuerEntry
+	if err := entry.DecodeJSON(&issuer); err != nil {
+		return nil, errutil.InternalError{Err: fmt.Sprintf("unable to decode issuer %s: %v", id, err)}
+	}
+	return &issuer, nil
+}
+
+func writeIssuer(s logical.Storage, issuer *issuerEntry) error {
+	entry, err := logical.StorageEntryJSON("issuers/"+issuer.ID, issuer)
+	if err != nil {
+		return err
+	}
+	return s.Put(entry)
+}
+
+func listKeys(s logical.Storage) ([]string, error) {
+	ids, err := s.List("keys/")
+	if err != nil {
+		return nil, errutil.InternalError{Err: fmt.Sprintf("unable to list keys: %v", err)}
+	}
+	return ids, nil
+}
+
+func fetchKey(s logical.Storage, id string) (*keyEntry, error) {
+	entry, err := s.Get("keys/" + id)
+	if err != nil {
+		return nil, errutil.InternalError{Err: fmt.Sprintf("unable to fetch key %s: %v", id, err)}
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var key keyEntry
+	if err := entry.DecodeJSON(&key); err != nil {
+		return nil, errutil.InternalError{Err: fmt.Sprintf("unable to decode key %s: %v", id, err)}
+	}
+	return &key, nil
+}
+
+func writeKey(s logical.Storage, key *keyEntry) error {
+	entry, err := logical.StorageEntryJSON("keys/"+key.ID, key)
+	if err != nil {
+		return err
+	}
+	return s.Put(entry)
+}
+
+// resolveIssuerRef turns an issuer ID, an issuer name or "default" into the
+// ID of a stored issuer
+func resolveIssuerRef(s logical.Storage, ref string) (string, error) {
+	if ref == "" || ref == defaultIssuerRef {
+		config, err := fetchIssuerConfig(s)
+		if err != nil {
+			return "", err
+		}
+		if config.DefaultIssuerID == "" {
+			return "", errutil.UserError{Err: "backend must be configured with a CA certificate/key"}
+		}
+		return config.DefaultIssuerID, nil
+	}
+
+	ids, err := listIssuers(s)
+	if err != nil {
+		return "", err
+	}
+	for _, id := range ids {
+		if id == ref {
+			return id, nil
+		}
+	}
+	for _, id := range ids {
+		issuer, err := fetchIssuer(s, id)
+		if err != nil {
+			return "", err
+		}
+		if issuer != nil && issuer.Name == ref {
+			return id, nil
+		}
+	}
+
+	return "", errutil.UserError{Err: fmt.Sprintf("unknown issuer %q", ref)}
+}
+
+// resolveKeyRef turns a key ID or name into the ID of a stored key
+func resolveKeyRef(s logical.Storage, ref string) (string, error) {
+	ids, err := listKeys(s)
+	if err != nil {
+		return "", err
+	}
+	for _, id := range ids {
+		if id == ref {
+			return id, nil
+		}
+	}
+	for _, id := range ids {
+		key, err := fetchKey(s, id)
+		if err != nil {
+			return "", err
+		}
+		if key != nil && key.Name == ref {
+			return id, nil
+		}
+	}
+
+	return "", errutil.UserError{Err: fmt.Sprintf("unknown key %q", ref)}
+}
+
+// checkIssuerName verifies that name may be given to the issuer with the
+// given ID; names must be unique and must not be mistaken for other
+// references
+func checkIssuerName(s logical.Storage, name, id string) error {
+	if name == "" {
+		return nil
+	}
+	if name == defaultIssuerRef {
+		return errutil.UserError{Err: fmt.Sprintf("%q is reserved and cannot be used as an issuer name", name)}
+	}
+
+	ids, err := listIssuers(s)
+	if err != nil {
+		return err
+	}
+	for _, otherID := range ids {
+		if otherID == id {
+			continue
+		}
+		if otherID == name {
+			return errutil.UserError{Err: fmt.Sprintf("issuer name %q is the ID of another issuer", name)}
+		}
+		issuer, err := fetchIssuer(s, otherID)
+		if err != nil {
+			return err
+		}
+		if issuer != nil && issuer.Name == name {
+			return errutil.UserError{Err: fmt.Sprintf("issuer name %q is already in use", name)}
+		}
+	}
+	return nil
+}
+
+// checkKeyName verifies that name may be given to the key with the given ID
+func checkKeyName(s logical.Storage, name, id string) error {
+	if name == "" {
+		return nil
+	}
+
+	ids, err := listKeys(s)
+	if err != nil {
+		return err
+	}
+	for _, otherID := range ids {
+		if otherID == id {
+			continue
+		}
+		if otherID == name {
+			return errutil.UserError{Err: fmt.Sprintf("key name %q is the ID of another key", name)}
+		}
+		key, err := fetchKey(s, otherID)
+		if err != nil {
+			return err
+		}
+		if key != nil && key.Name == name {
+			return errutil.UserError{Err: fmt.Sprintf("key name %q is already in use", name)}
+		}
+	}
+	return nil
+}
+
+// importKey stores the given PEM-encoded private key unless the backend
+// already holds it. It returns the stored key and whether it already existed.
+func importKey(s logical.Storage, keyPEM, name string) (*keyEntry, bool, error) {
+	parsed, err := (&certutil.CertBundle{PrivateKey: keyPEM}).ToParsedCertBundle()
+	if err != nil {
+		return nil, false, errutil.UserError{Err: fmt.Sprintf("unable to parse private key: %v", err)}
+	}
+	if parsed.PrivateKey == nil {
+		return nil, false, errutil.UserError{Err: "no private key found"}
+	}
+
+	ids, err := listKeys(s)
+	if err != nil {
+		return nil, false, err
+	}
+	for _, id := range ids {
+		key, err := fetchKey(s, id)
+		if err != nil {
+			return nil, false, err
+		}
+		if key == nil {
+			continue
+		}
+		existing, err := (&certutil.CertBundle{PrivateKey: key.PrivateKey}).ToParsedCertBundle()
+		if err != nil {
+			return nil, false, errutil.InternalError{Err: fmt.Sprintf("unable to parse key %s: %v", id, err)}
+		}
+		equal, err := certutil.ComparePublicKeys(existing.PrivateKey.Public(), parsed.PrivateKey.Public())
+		if err != nil {
+			return nil, false, errutil.InternalError{Err: err.Error()}
+		}
+		if equal {
+			return key, true, nil
+		}
+	}
+
+	if err := checkKeyName(s, name, ""); err != nil {
+		return nil, false, err
+	}
+
+	id, err := uuid.GenerateUUID()
+	if err != nil {
+		return nil, false, err
+	}
+	key := &keyEntry{
+		ID:             id,
+		Name:           name,
+		PrivateKeyType: parsed.PrivateKeyType,
+		PrivateKey:     strings.TrimSpace(keyPEM),
+	}
+	if err := writeKey(s, key); err != nil {
+		return nil, false, err
+	}
+	return key, false, nil
+}
+
+// importIssuer stores the given CA certificate unless the backend already
+// holds it, linking it to the stored key matching its public key if there is
+// one. chain holds further certificates that were provided along with it. It
+// returns the stored issuer and whether it already existed.
+func importIssuer(s logical.Storage, cert *x509.Certificate, chain []*certutil.CertBlock, name string) (*issuerEntry, bool, error) {
+	if !cert.IsCA {
+		return nil, false, errutil.UserError{Err: "the given certificate is not marked for CA use and cannot be used with this backend"}
+	}
+
+	ids, err := listIssuers(s)
+	if err != nil {
+		return nil, false, err
+	}
+	for _, id := range ids {
+		issuer, err := fetchIssuer(s, id)
+		if err != nil {
+			return nil, false, err
+		}
+		if issuer == nil {
+			continue
+		}
+		existing, err := issuer.parseCertificate()
+		if err != nil {
+			return nil, false, err
+		}
+		if bytes.Equal(existing.Raw, cert.Raw) {
+			return issuer, true, nil
+		}
+	}
+
+	if err := checkIssuerName(s, name, ""); err != nil {
+		return nil, false, err
+	}
+
+	id, err := uuid.GenerateUUID()
+	if err != nil {
+		return nil, false, err
+	}
+	issuer := &issuerEntry{
+		ID:           id,
+		Name:         name,
+		Certificate:  strings.TrimSpace(string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}))),
+		SerialNumber: certutil.GetHexFormatted(cert.SerialNumber.Bytes(), ":"),
+	}
+	for _, block := range chain {
+		issuer.CAChain = append(issuer.CAChain, strings.TrimSpace(string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: block.Bytes}))))
+	}
+
+	issuer.KeyID, err = findKeyForCertificate(s, cert)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if err := writeIssuer(s, issuer); err != nil {
+		return nil, false, err
+	}
+	return issuer, false, nil
+}
+
+// findKeyForCertificate returns the ID of the stored key matching the public
+// key of the given certificate, or an empty string if there is none
+func findKeyForCertificate(s logical.Storage, cert *x509.Certificate) (string, error) {
+	ids, err := listKeys(s)
+	if err != nil {
+		return "", err
+	}
+	for _, id := range ids {
+		key, err := fetchKey(s, id)
+		if err != nil {
+			return "", err
+		}
+		if key == nil {
+			continue
+		}
+		parsed, err := (&certutil.CertBundle{PrivateKey: key.PrivateKey}).ToParsedCertBundle()
+		if err != nil {
+			return "", errutil.InternalError{Err: fmt.Sprintf("unable to parse key %s: %v", id, err)}
+		}
+		equal, err := certutil.ComparePublicKeys(cert.PublicKey, parsed.PrivateKey.Public())
+		if err != nil {
+			return "", errutil.InternalError{Err: err.Error()}
+		}
+		if equal {
+			return id, nil
+		}
+	}
+	return "", nil
+}
+
+// storeCA stores the certificate of a CA along with its key, if given. The
+// issuer becomes the default one if makeDefault is set, or if there is no
+// default issuer yet and the backend holds its key.
+func storeCA(b *backend, s logical.Storage, bundle *certutil.ParsedCertBundle, issuerName, keyName string, makeDefault bool) (*issuerEntry, error) {
+	b.issuersLock.Lock()
+	defer b.issuersLock.Unlock()
+
+	if bundle.PrivateKey != nil {
+		cb, err := bundle.ToCertBundle()
+		if err != nil {
+			return nil, errutil.InternalError{Err: fmt.Sprintf("error converting raw values into cert bundle: %s", err)}
+		}
+		if _, _, err := importKey(s, cb.PrivateKey, keyName); err != nil {
+			return nil, err
+		}
+	}
+
+	issuer, _, err := importIssuer(s, bundle.Certificate, bundle.CAChain, issuerName)
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := fetchIssuerConfig(s)
+	if err != nil {
+		return nil, err
+	}
+	if makeDefault || (config.DefaultIssuerID == "" && issuer.KeyID != "") {
+		if err := setDefaultIssuer(s, issuer.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	return issuer, nil
+}
+
+// issuerChain returns the certificate of the issuer followed by the
+// certificates that chain it towards a root. Issuers of the backend that
+// signed a certificate of the chain are included, so that all cross-signed
+// paths are returned, followed by any certificates provided when the issuer
+// was imported.
+func issuerChain(s logical.Storage, issuer *issuerEntry) ([]*certutil.CertBlock, error) {
+	cert, err := issuer.parseCertificate()
+	if err != nil {
+		return nil, err
+	}
+
+	ids, err := listIssuers(s)
+	if err != nil {
+		return nil, err
+	}
+	var others []*x509.Certificate
+	for _, id := range ids {
+		if id == issuer.ID {
+			continue
+		}
+		other, err := fetchIssuer(s, id)
+		if err != nil {
+			return nil, err
+		}
+		if other == nil {
+			continue
+		}
+		otherCert, err := other.parseCertificate()
+		if err != nil {
+			return nil, err
+		}
+		others = append(others, otherCert)
+	}
+
+	chain := []*certutil.CertBlock{
+		&certutil.CertBlock{
+			Certificate: cert,
+			Bytes:       cert.Raw,
+		},
+	}
+	contains := func(c *x509.Certificate) bool {
+		for _, block := range chain {
+			if bytes.Equal(block.Bytes, c.Raw) {
+				return true
+			}
+		}
+		return false
+	}
+
+	for i := 0; i < len(chain); i++ {
+		child := chain[i].Certificate
+		for _, parent := range others {
+			if contains(parent) || !bytes.Equal(child.RawIssuer, parent.RawSubject) {
+				continue
+			}
+			if child.CheckSignatureFrom(parent) != nil {
+				continue
+			}
+			chain = append(chain, &certutil.CertBlock{
+				Certificate: parent,
+				Bytes:       parent.Raw,
+			})
+		}
+	}
+
+	for _, pemCert := range issuer.CAChain {
+		block, _ := pem.Decode([]byte(pemCert))
+		if block == nil {
+			return nil, errutil.InternalError{Err: fmt.Sprintf("unable to decode the chain of issuer %s", issuer.ID)}
+		}
+		chainCert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, errutil.InternalError{Err: fmt.Sprintf("unable to parse the chain of issuer %s: %v", issuer.ID, err)}
+		}
+		if contains(chainCert) {
+			continue
+		}
+		chain = append(chain, &certutil.CertBlock{
+			Certificate: chainCert,
+			Bytes:       chainCert.Raw,
+		})
+	}
+
+	return chain, nil
+}
+
+// migrateLegacyCABundle moves a CA bundle stored before the backend could
+// hold several issuers into issuer and key storage. A stored certificate
+// becomes the default issuer; a key on its own, left by generating an
+// intermediate CSR, is kept so that the signed certificate can be set later.
+func migrateLegacyCABundle(b *backend, s logical.Storage) error {
+	b.issuersLock.RLock()
+	entry, err := s.Get(legacyCABundleKey)
+	b.issuersLock.RUnlock()
+	if err != nil {
+		return errutil.InternalError{Err: fmt.Sprintf("unable to fetch local CA certificate/key: %v", err)}
+	}
+	if entry == nil {
+		return nil
+	}
+
+	b.issuersLock.Lock()
+	defer b.issuersLock.Unlock()
+
+	// Check again as another request may have migrated the bundle meanwhile
+	entry, err = s.Get(legacyCABundleKey)
+	if err != nil {
+		return errutil.InternalError{Err: fmt.Sprintf("unable to fetch local CA certificate/key: %v", err)}
+	}
+	if entry == nil {
+		return nil
+	}
+
+	var bundle certutil.CertBundle
+	if err := entry.DecodeJSON(&bundle); err != nil {
+		return errutil.InternalError{Err: fmt.Sprintf("unable to decode local CA certificate/key: %v", err)}
+	}
+	parsedBundle, err := bundle.ToParsedCertBundle()
+	if err != nil {
+		return errutil.InternalError{Err: err.Error()}
+	}
+
+	if len(bundle.PrivateKey) > 0 {
+		if _, _, err := importKey(s, bundle.PrivateKey, ""); err != nil {
+			return errutil.InternalError{Err: fmt.Sprintf("unable to migrate local CA key: %v", err)}
+		}
+	}
+	if parsedBundle.Certificate != nil {
+		issuer, _, err := importIssuer(s, parsedBundle.Certificate, parsedBundle.CAChain, "")
+		if err != nil {
+			return errutil.InternalError{Err: fmt.Sprintf("unable to migrate local CA certificate: %v", err)}
+		}
+		if err := setDefaultIssuer(s, issuer.ID); err != nil {
+			return errutil.InternalError{Err: fmt.Sprintf("unable to migrate local CA certificate: %v", err)}
+		}
+	}
+
+	if err := s.Delete(legacyCABundleKey); err != nil {
+		return errutil.InternalError{Err: fmt.Sprintf("unable to remove local CA certificate/key: %v", err)}
+	}
+	return nil
+}
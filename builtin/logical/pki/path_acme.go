@@ -0,0 +1,987 @@
+package pki
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/helper/errutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+// How the requests to an ACME endpoint are authenticated
+const (
+	// acmeAuthNone is used for the GET endpoints that need no signature
+	acmeAuthNone = iota
+
+	// acmeAuthJWK is used for requests signed with an embedded key
+	acmeAuthJWK
+
+	// acmeAuthKID is used for requests signed by an existing account
+	acmeAuthKID
+)
+
+// acmeOperation handles a request to the ACME server of a role once the
+// request has been authenticated
+type acmeOperation func(ar *acmeRequest, data *framework.FieldData) (*acmeResponse, error)
+
+func pathACME(b *backend) []*framework.Path {
+	roleField := &framework.FieldSchema{
+		Type:        framework.TypeString,
+		Description: "Name of the role whose ACME directory is used",
+	}
+	idField := func(description string) *framework.FieldSchema {
+		return &framework.FieldSchema{
+			Type:        framework.TypeString,
+			Description: description,
+		}
+	}
+
+	return []*framework.Path{
+		&framework.Path{
+			Pattern: acmePattern("directory"),
+			Fields: map[string]*framework.FieldSchema{
+				"role": roleField,
+			},
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ReadOperation: b.acmeHandler(acmeAuthNone, b.acmeDirectory),
+			},
+			HelpSynopsis:    pathACMEHelpSyn,
+			HelpDescription: pathACMEHelpDesc,
+		},
+
+		&framework.Path{
+			Pattern: acmePattern("new-nonce"),
+			Fields: map[string]*framework.FieldSchema{
+				"role": roleField,
+			},
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ReadOperation: b.acmeHandler(acmeAuthNone, b.acmeNewNonceHandler),
+			},
+			HelpSynopsis:    pathACMEHelpSyn,
+			HelpDescription: pathACMEHelpDesc,
+		},
+
+		&framework.Path{
+			Pattern: acmePattern("new-account"),
+			Fields: map[string]*framework.FieldSchema{
+				"role": roleField,
+			},
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.UpdateOperation: b.acmeHandler(acmeAuthJWK, b.acmeNewAccount),
+			},
+			HelpSynopsis:    pathACMEHelpSyn,
+			HelpDescription: pathACMEHelpDesc,
+		},
+
+		&framework.Path{
+			Pattern: acmePattern("account/" + framework.GenericNameRegex("account_id")),
+			Fields: map[string]*framework.FieldSchema{
+				"role":       roleField,
+				"account_id": idField("ID of the account"),
+			},
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.UpdateOperation: b.acmeHandler(acmeAuthKID, b.acmeAccountUpdate),
+			},
+			HelpSynopsis:    pathACMEHelpSyn,
+			HelpDescription: pathACMEHelpDesc,
+		},
+
+		&framework.Path{
+			Pattern: acmePattern("account/" + framework.GenericNameRegex("account_id") + "/orders"),
+			Fields: map[string]*framework.FieldSchema{
+				"role":       roleField,
+				"account_id": idField("ID of the account"),
+			},
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.UpdateOperation: b.acmeHandler(acmeAuthKID, b.acmeAccountOrders),
+			},
+			HelpSynopsis:    pathACMEHelpSyn,
+			HelpDescription: pathACMEHelpDesc,
+		},
+
+		&framework.Path{
+			Pattern: acmePattern("new-order"),
+			Fields: map[string]*framework.FieldSchema{
+				"role": roleField,
+			},
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.UpdateOperation: b.acmeHandler(acmeAuthKID, b.acmeNewOrder),
+			},
+			HelpSynopsis:    pathACMEHelpSyn,
+			HelpDescription: pathACMEHelpDesc,
+		},
+
+		&framework.Path{
+			Pattern: acmePattern("order/" + framework.GenericNameRegex("order_id")),
+			Fields: map[string]*framework.FieldSchema{
+				"role":     roleField,
+				"order_id": idField("ID of the order"),
+			},
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.UpdateOperation: b.acmeHandler(acmeAuthKID, b.acmeOrderRead),
+			},
+			HelpSynopsis:    pathACMEHelpSyn,
+			HelpDescription: pathACMEHelpDesc,
+		},
+
+		&framework.Path{
+			Pattern: acmePattern("order/" + framework.GenericNameRegex("order_id") + "/finalize"),
+			Fields: map[string]*framework.FieldSchema{
+				"role":     roleField,
+				"order_id": idField("ID of the order"),
+			},
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.UpdateOperation: b.acmeHandler(acmeAuthKID, b.acmeFinalize),
+			},
+			HelpSynopsis:    pathACMEHelpSyn,
+			HelpDescription: pathACMEHelpDesc,
+		},
+
+		&framework.Path{
+			Pattern: acmePattern("authz/" + framework.GenericNameRegex("authz_id")),
+			Fields: map[string]*framework.FieldSchema{
+				"role":     roleField,
+				"authz_id": idField("ID of the authorization"),
+			},
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.UpdateOperation: b.acmeHandler(acmeAuthKID, b.acmeAuthorizationRead),
+			},
+			HelpSynopsis:    pathACMEHelpSyn,
+			HelpDescription: pathACMEHelpDesc,
+		},
+
+		&framework.Path{
+			Pattern: acmePattern("challenge/" + framework.GenericNameRegex("authz_id") + "/" + framework.GenericNameRegex("challenge_type")),
+			Fields: map[string]*framework.FieldSchema{
+				"role":           roleField,
+				"authz_id":       idField("ID of the authorization"),
+				"challenge_type": idField("Type of the challenge"),
+			},
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.UpdateOperation: b.acmeHandler(acmeAuthKID, b.acmeChallengeRespond),
+			},
+			HelpSynopsis:    pathACMEHelpSyn,
+			HelpDescription: pathACMEHelpDesc,
+		},
+
+		&framework.Path{
+			Pattern: acmePattern("cert/" + framework.GenericNameRegex("order_id")),
+			Fields: map[string]*framework.FieldSchema{
+				"role":     roleField,
+				"order_id": idField("ID of the order"),
+			},
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.UpdateOperation: b.acmeHandler(acmeAuthKID, b.acmeCertificate),
+			},
+			HelpSynopsis:    pathACMEHelpSyn,
+			HelpDescription: pathACMEHelpDesc,
+		},
+	}
+}
+
+func acmePattern(path string) string {
+	return "acme/" + framework.GenericNameRegex("role") + "/" + path + "$"
+}
+
+// acmeHandler wraps an ACME operation, authenticating the request and
+// rendering the result, or the problem that occurred, as a raw HTTP response
+func (b *backend) acmeHandler(auth int, op acmeOperation) framework.OperationFunc {
+	return func(req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		ar := &acmeRequest{
+			Request:  req,
+			roleName: data.Get("role").(string),
+		}
+
+		resp, err := b.acmeHandle(ar, auth, op, data)
+		if err != nil {
+			problem, ok := err.(*acmeProblem)
+			if !ok {
+				b.Logger().Error("pki: error handling ACME request", "path", req.Path, "error", err)
+				problem = acmeError("serverInternal", http.StatusInternalServerError, "internal error")
+			}
+			resp = &acmeResponse{
+				status:      problem.Status,
+				body:        problem,
+				contentType: "application/problem+json",
+			}
+		}
+
+		return b.acmeRawResponse(ar, resp)
+	}
+}
+
+func (b *backend) acmeHandle(ar *acmeRequest, auth int, op acmeOperation, data *framework.FieldData) (*acmeResponse, error) {
+	config, err := b.acmeConfig(ar.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil || !config.Enabled {
+		return nil, acmeUnauthorized("ACME is not enabled")
+	}
+
+	role, err := b.getRole(ar.Storage, ar.roleName)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, acmeNotFound("unknown role %q", ar.roleName)
+	}
+	ar.role = role
+	ar.directoryURL = config.BaseURL + "/acme/" + ar.roleName
+
+	if auth != acmeAuthNone {
+		if err := b.acmeVerifyJWS(ar, auth == acmeAuthJWK); err != nil {
+			return nil, err
+		}
+	}
+
+	return op(ar, data)
+}
+
+// acmeRawResponse renders the result of an ACME request. Every response
+// carries a fresh nonce for the next request of the client.
+func (b *backend) acmeRawResponse(ar *acmeRequest, resp *acmeResponse) (*logical.Response, error) {
+	body := resp.rawBody
+	contentType := resp.contentType
+	if resp.body != nil {
+		var err error
+		body, err = json.Marshal(resp.body)
+		if err != nil {
+			return nil, err
+		}
+		if contentType == "" {
+			contentType = "application/json"
+		}
+	}
+
+	nonce, err := b.acmeNewNonce()
+	if err != nil {
+		return nil, err
+	}
+	headers := map[string][]string{
+		"Replay-Nonce":  []string{nonce},
+		"Cache-Control": []string{"no-store"},
+	}
+	if ar.directoryURL != "" {
+		headers["Link"] = append(headers["Link"], fmt.Sprintf(`<%s>;rel="index"`, ar.url("directory")))
+	}
+	headers["Link"] = append(headers["Link"], resp.links...)
+	if resp.location != "" {
+		headers["Location"] = []string{resp.location}
+	}
+
+	data := map[string]interface{}{
+		logical.HTTPStatusCode: resp.status,
+		logical.HTTPHeaders:    headers,
+	}
+	if resp.status != http.StatusNoContent {
+		data[logical.HTTPContentType] = contentType
+		data[logical.HTTPRawBody] = body
+	}
+
+	return &logical.Response{
+		Data: data,
+	}, nil
+}
+
+func (b *backend) acmeDirectory(ar *acmeRequest, data *framework.FieldData) (*acmeResponse, error) {
+	return &acmeResponse{
+		status: http.StatusOK,
+		body: map[string]interface{}{
+			"newNonce":   ar.url("new-nonce"),
+			"newAccount": ar.url("new-account"),
+			"newOrder":   ar.url("new-order"),
+			"meta": map[string]interface{}{
+				"externalAccountRequired": false,
+			},
+		},
+	}, nil
+}
+
+func (b *backend) acmeNewNonceHandler(ar *acmeRequest, data *framework.FieldData) (*acmeResponse, error) {
+	// The nonce itself is added to every response
+	return &acmeResponse{
+		status: http.StatusNoContent,
+	}, nil
+}
+
+func (b *backend) acmeNewAccount(ar *acmeRequest, data *framework.FieldData) (*acmeResponse, error) {
+	var payload struct {
+		Contact              []string `json:"contact"`
+		TermsOfServiceAgreed bool     `json:"termsOfServiceAgreed"`
+		OnlyReturnExisting   bool     `json:"onlyReturnExisting"`
+	}
+	if err := ar.decodePayload(&payload); err != nil {
+		return nil, err
+	}
+
+	thumbprint, err := acmeThumbprint(ar.jwk)
+	if err != nil {
+		return nil, acmeError("badPublicKey", http.StatusBadRequest, "error computing key thumbprint: %s", err)
+	}
+	keyPath := "acme/account-keys/" + ar.roleName + "/" + thumbprint
+
+	b.acmeLock.Lock()
+	defer b.acmeLock.Unlock()
+
+	// A key is bound to a single account; requests for a new account with
+	// the key of an existing one return the existing account
+	var existing struct {
+		ID string `json:"id"`
+	}
+	ok, err := acmeGet(ar.Storage, keyPath, &existing)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		account, err := b.acmeAccount(ar.Storage, existing.ID)
+		if err != nil {
+			return nil, err
+		}
+		if account != nil {
+			return &acmeResponse{
+				status:   http.StatusOK,
+				body:     acmeAccountBody(ar, account),
+				location: ar.url("account/" + account.ID),
+			}, nil
+		}
+	}
+	if payload.OnlyReturnExisting {
+		return nil, acmeError("accountDoesNotExist", http.StatusBadRequest, "no account exists with the given key")
+	}
+
+	if err := acmeValidateContact(payload.Contact); err != nil {
+		return nil, err
+	}
+
+	id, err := uuid.GenerateUUID()
+	if err != nil {
+		return nil, err
+	}
+	account := &acmeAccount{
+		ID:         id,
+		Role:       ar.roleName,
+		Status:     acmeStatusValid,
+		Contact:    payload.Contact,
+		Key:        ar.jwk,
+		Thumbprint: thumbprint,
+		CreatedAt:  time.Now().UTC(),
+	}
+	if err := acmePut(ar.Storage, "acme/accounts/"+id, account); err != nil {
+		return nil, err
+	}
+	existing.ID = id
+	if err := acmePut(ar.Storage, keyPath, existing); err != nil {
+		return nil, err
+	}
+
+	return &acmeResponse{
+		status:   http.StatusCreated,
+		body:     acmeAccountBody(ar, account),
+		location: ar.url("account/" + id),
+	}, nil
+}
+
+func (b *backend) acmeAccountUpdate(ar *acmeRequest, data *framework.FieldData) (*acmeResponse, error) {
+	if data.Get("account_id").(string) != ar.account.ID {
+		return nil, acmeUnauthorized("request is not signed by the account")
+	}
+
+	if len(ar.payload) != 0 {
+		var payload struct {
+			Status  string    `json:"status"`
+			Contact *[]string `json:"contact"`
+		}
+		if err := ar.decodePayload(&payload); err != nil {
+			return nil, err
+		}
+
+		b.acmeLock.Lock()
+		defer b.acmeLock.Unlock()
+
+		switch payload.Status {
+		case "":
+		case acmeStatusDeactivated:
+			ar.account.Status = acmeStatusDeactivated
+		default:
+			return nil, acmeMalformed("invalid account status %q", payload.Status)
+		}
+		if payload.Contact != nil {
+			if err := acmeValidateContact(*payload.Contact); err != nil {
+				return nil, err
+			}
+			ar.account.Contact = *payload.Contact
+		}
+
+		if err := acmePut(ar.Storage, "acme/accounts/"+ar.account.ID, ar.account); err != nil {
+			return nil, err
+		}
+	}
+
+	return &acmeResponse{
+		status: http.StatusOK,
+		body:   acmeAccountBody(ar, ar.account),
+	}, nil
+}
+
+func (b *backend) acmeAccountOrders(ar *acmeRequest, data *framework.FieldData) (*acmeResponse, error) {
+	if data.Get("account_id").(string) != ar.account.ID {
+		return nil, acmeUnauthorized("request is not signed by the account")
+	}
+
+	ids, err := ar.Storage.List("acme/orders/" + ar.account.ID + "/")
+	if err != nil {
+		return nil, err
+	}
+	orders := make([]string, 0, len(ids))
+	for _, id := range ids {
+		orders = append(orders, ar.url("order/"+id))
+	}
+
+	return &acmeResponse{
+		status: http.StatusOK,
+		body: map[string]interface{}{
+			"orders": orders,
+		},
+	}, nil
+}
+
+func (b *backend) acmeNewOrder(ar *acmeRequest, data *framework.FieldData) (*acmeResponse, error) {
+	var payload struct {
+		Identifiers []acmeIdentifier `json:"identifiers"`
+		NotBefore   string           `json:"notBefore"`
+		NotAfter    string           `json:"notAfter"`
+	}
+	if err := ar.decodePayload(&payload); err != nil {
+		return nil, err
+	}
+	if payload.NotBefore != "" || payload.NotAfter != "" {
+		return nil, acmeMalformed("notBefore and notAfter are not supported; the validity is set by the role")
+	}
+	if len(payload.Identifiers) == 0 {
+		return nil, acmeMalformed("order has no identifiers")
+	}
+
+	// Check the identifiers against the role up front rather than only on
+	// finalization, so that clients do not go through validation for names
+	// they will not get a certificate for
+	seen := make(map[string]bool, len(payload.Identifiers))
+	var identifiers []acmeIdentifier
+	for _, identifier := range payload.Identifiers {
+		if identifier.Type != "dns" {
+			return nil, acmeError("unsupportedIdentifier", http.StatusBadRequest, "unsupported identifier type %q", identifier.Type)
+		}
+		name := strings.ToLower(identifier.Value)
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		if badName := validateNames(ar.Request, []string{name}, ar.role); badName != "" {
+			return nil, acmeError("rejectedIdentifier", http.StatusBadRequest, "name %s not allowed by this role", badName)
+		}
+		identifiers = append(identifiers, acmeIdentifier{
+			Type:  "dns",
+			Value: name,
+		})
+	}
+
+	orderID, err := uuid.GenerateUUID()
+	if err != nil {
+		return nil, err
+	}
+	order := &acmeOrder{
+		ID:          orderID,
+		AccountID:   ar.account.ID,
+		Status:      acmeStatusPending,
+		Expires:     time.Now().UTC().Add(acmeOrderLifetime).Truncate(time.Second),
+		Identifiers: identifiers,
+	}
+
+	for _, identifier := range identifiers {
+		authzID, err := uuid.GenerateUUID()
+		if err != nil {
+			return nil, err
+		}
+		authz := &acmeAuthorization{
+			ID:        authzID,
+			AccountID: ar.account.ID,
+			Status:    acmeStatusPending,
+			Expires:   order.Expires,
+			Identifier: acmeIdentifier{
+				Type:  "dns",
+				Value: strings.TrimPrefix(identifier.Value, "*."),
+			},
+			Wildcard: strings.HasPrefix(identifier.Value, "*."),
+		}
+
+		// Wildcard names can only be proven through DNS
+		challengeTypes := []string{acmeChallengeHTTP01, acmeChallengeDNS01}
+		if authz.Wildcard {
+			challengeTypes = []string{acmeChallengeDNS01}
+		}
+		for _, challengeType := range challengeTypes {
+			token, err := acmeRandomToken()
+			if err != nil {
+				return nil, err
+			}
+			authz.Challenges = append(authz.Challenges, &acmeChallenge{
+				Type:   challengeType,
+				Token:  token,
+				Status: acmeStatusPending,
+			})
+		}
+
+		if err := acmePut(ar.Storage, "acme/authz/"+ar.account.ID+"/"+authzID, authz); err != nil {
+			return nil, err
+		}
+		order.AuthorizationIDs = append(order.AuthorizationIDs, authzID)
+	}
+
+	if err := acmePut(ar.Storage, "acme/orders/"+ar.account.ID+"/"+orderID, order); err != nil {
+		return nil, err
+	}
+
+	return &acmeResponse{
+		status:   http.StatusCreated,
+		body:     acmeOrderBody(ar, order),
+		location: ar.url("order/" + orderID),
+	}, nil
+}
+
+func (b *backend) acmeOrderRead(ar *acmeRequest, data *framework.FieldData) (*acmeResponse, error) {
+	b.acmeLock.Lock()
+	defer b.acmeLock.Unlock()
+
+	order, err := b.acmeOrder(ar.Storage, ar.account.ID, data.Get("order_id").(string))
+	if err != nil {
+		return nil, err
+	}
+	if order == nil {
+		return nil, acmeNotFound("unknown order")
+	}
+	if err := b.acmeUpdateOrderStatus(ar.Storage, order); err != nil {
+		return nil, err
+	}
+
+	return &acmeResponse{
+		status: http.StatusOK,
+		body:   acmeOrderBody(ar, order),
+	}, nil
+}
+
+func (b *backend) acmeAuthorizationRead(ar *acmeRequest, data *framework.FieldData) (*acmeResponse, error) {
+	b.acmeLock.Lock()
+	defer b.acmeLock.Unlock()
+
+	authz, err := b.acmeAuthorization(ar.Storage, ar.account.ID, data.Get("authz_id").(string))
+	if err != nil {
+		return nil, err
+	}
+	if authz == nil {
+		return nil, acmeNotFound("unknown authorization")
+	}
+
+	changed := acmeExpireAuthorization(authz)
+	if len(ar.payload) != 0 {
+		var payload struct {
+			Status string `json:"status"`
+		}
+		if err := ar.decodePayload(&payload); err != nil {
+			return nil, err
+		}
+		switch payload.Status {
+		case "":
+		case acmeStatusDeactivated:
+			authz.Status = acmeStatusDeactivated
+			changed = true
+		default:
+			return nil, acmeMalformed("invalid authorization status %q", payload.Status)
+		}
+	}
+	if changed {
+		if err := acmePut(ar.Storage, "acme/authz/"+authz.AccountID+"/"+authz.ID, authz); err != nil {
+			return nil, err
+		}
+	}
+
+	return &acmeResponse{
+		status: http.StatusOK,
+		body:   acmeAuthorizationBody(ar, authz),
+	}, nil
+}
+
+// acmeChallengeRespond validates a challenge once the client signals that it
+// is ready
+func (b *backend) acmeChallengeRespond(ar *acmeRequest, data *framework.FieldData) (*acmeResponse, error) {
+	authzID := data.Get("authz_id").(string)
+	challengeType := data.Get("challenge_type").(string)
+
+	b.acmeLock.Lock()
+	authz, challenge, err := b.acmeLoadChallenge(ar, authzID, challengeType)
+	if err != nil {
+		b.acmeLock.Unlock()
+		return nil, err
+	}
+	if acmeExpireAuthorization(authz) {
+		if err := acmePut(ar.Storage, "acme/authz/"+authz.AccountID+"/"+authz.ID, authz); err != nil {
+			b.acmeLock.Unlock()
+			return nil, err
+		}
+	}
+	b.acmeLock.Unlock()
+
+	// An empty payload only fetches the challenge, and challenges are only
+	// validated once
+	if len(ar.payload) != 0 && authz.Status == acmeStatusPending && challenge.Status == acmeStatusPending {
+		// Validation reaches out to the network, so it is done without
+		// holding the lock
+		problem := b.acmeValidateChallenge(authz, challenge, ar.account)
+
+		b.acmeLock.Lock()
+		defer b.acmeLock.Unlock()
+
+		authz, challenge, err = b.acmeLoadChallenge(ar, authzID, challengeType)
+		if err != nil {
+			return nil, err
+		}
+		if authz.Status == acmeStatusPending && challenge.Status == acmeStatusPending {
+			if problem == nil {
+				challenge.Status = acmeStatusValid
+				challenge.Validated = time.Now().UTC().Truncate(time.Second)
+				authz.Status = acmeStatusValid
+			} else {
+				challenge.Status = acmeStatusInvalid
+				challenge.Error = problem
+				authz.Status = acmeStatusInvalid
+			}
+			if err := acmePut(ar.Storage, "acme/authz/"+authz.AccountID+"/"+authz.ID, authz); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return &acmeResponse{
+		status: http.StatusOK,
+		body:   acmeChallengeBody(ar, authz, challenge),
+		links:  []string{fmt.Sprintf(`<%s>;rel="up"`, ar.url("authz/"+authz.ID))},
+	}, nil
+}
+
+func (b *backend) acmeLoadChallenge(ar *acmeRequest, authzID, challengeType string) (*acmeAuthorization, *acmeChallenge, error) {
+	authz, err := b.acmeAuthorization(ar.Storage, ar.account.ID, authzID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if authz == nil {
+		return nil, nil, acmeNotFound("unknown authorization")
+	}
+	for _, challenge := range authz.Challenges {
+		if challenge.Type == challengeType {
+			return authz, challenge, nil
+		}
+	}
+	return nil, nil, acmeNotFound("unknown challenge")
+}
+
+// acmeFinalize issues the certificate of an order whose authorizations are
+// all valid, subject to the same role checks as the sign endpoint
+func (b *backend) acmeFinalize(ar *acmeRequest, data *framework.FieldData) (*acmeResponse, error) {
+	var payload struct {
+		CSR string `json:"csr"`
+	}
+	if err := ar.decodePayload(&payload); err != nil {
+		return nil, err
+	}
+
+	b.acmeLock.Lock()
+	defer b.acmeLock.Unlock()
+
+	order, err := b.acmeOrder(ar.Storage, ar.account.ID, data.Get("order_id").(string))
+	if err != nil {
+		return nil, err
+	}
+	if order == nil {
+		return nil, acmeNotFound("unknown order")
+	}
+	if err := b.acmeUpdateOrderStatus(ar.Storage, order); err != nil {
+		return nil, err
+	}
+	if order.Status != acmeStatusReady {
+		return nil, acmeError("orderNotReady", http.StatusForbidden, "order is %s", order.Status)
+	}
+
+	csrBytes, err := base64.RawURLEncoding.DecodeString(payload.CSR)
+	if err != nil {
+		return nil, acmeError("badCSR", http.StatusBadRequest, "error decoding CSR: %s", err)
+	}
+	csr, err := x509.ParseCertificateRequest(csrBytes)
+	if err != nil {
+		return nil, acmeError("badCSR", http.StatusBadRequest, "error parsing CSR: %s", err)
+	}
+	if err := csr.CheckSignature(); err != nil {
+		return nil, acmeError("badCSR", http.StatusBadRequest, "invalid CSR signature: %s", err)
+	}
+
+	// The CSR must request exactly the names of the order
+	if len(csr.IPAddresses) != 0 || len(csr.EmailAddresses) != 0 {
+		return nil, acmeError("badCSR", http.StatusBadRequest, "CSR may only contain DNS names")
+	}
+	csrNames := map[string]bool{}
+	cn := strings.ToLower(csr.Subject.CommonName)
+	if cn != "" {
+		csrNames[cn] = true
+	}
+	for _, name := range csr.DNSNames {
+		csrNames[strings.ToLower(name)] = true
+	}
+	var names []string
+	for _, identifier := range order.Identifiers {
+		if !csrNames[identifier.Value] {
+			return nil, acmeError("badCSR", http.StatusBadRequest, "CSR does not request %s", identifier.Value)
+		}
+		names = append(names, identifier.Value)
+	}
+	if len(csrNames) != len(names) {
+		return nil, acmeError("badCSR", http.StatusBadRequest, "CSR requests names that are not part of the order")
+	}
+	sort.Strings(names)
+	if cn == "" {
+		cn = names[0]
+	}
+	var altNames []string
+	for _, name := range names {
+		if name != cn {
+			altNames = append(altNames, name)
+		}
+	}
+
+	signingBundle, err := fetchCAInfo(b, ar.Request, ar.role.IssuerRef)
+	if err != nil {
+		return nil, err
+	}
+
+	fieldData := &framework.FieldData{
+		Raw: map[string]interface{}{
+			"csr": string(pem.EncodeToMemory(&pem.Block{
+				Type:  "CERTIFICATE REQUEST",
+				Bytes: csrBytes,
+			})),
+			"common_name": cn,
+			"alt_names":   strings.Join(altNames, ","),
+		},
+		Schema: pathSign(b).Fields,
+	}
+
+	// The names were checked against the CSR above, so they are passed
+	// explicitly rather than taken from the CSR again
+	role := *ar.role
+	role.UseCSRCommonName = false
+	role.UseCSRSANs = false
+
+	parsedBundle, err := signCert(b, &role, signingBundle, false, false, ar.Request, fieldData)
+	if err != nil {
+		if _, ok := err.(errutil.UserError); ok {
+			return nil, acmeError("badCSR", http.StatusBadRequest, "%s", err)
+		}
+		return nil, err
+	}
+
+	cb, err := parsedBundle.ToCertBundle()
+	if err != nil {
+		return nil, err
+	}
+
+	if !ar.role.NoStore {
+		err = ar.Storage.Put(&logical.StorageEntry{
+			Key:   "certs/" + normalizeSerial(cb.SerialNumber),
+			Value: parsedBundle.CertificateBytes,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("unable to store certificate locally: %v", err)
+		}
+	}
+
+	chain := []string{cb.Certificate}
+	chain = append(chain, cb.CAChain...)
+	if len(cb.CAChain) == 0 {
+		// The chain of a root CA is empty, but clients expect the issuer
+		chain = append(chain, strings.TrimSpace(string(pem.EncodeToMemory(&pem.Block{
+			Type:  "CERTIFICATE",
+			Bytes: signingBundle.CertificateBytes,
+		}))))
+	}
+
+	order.Status = acmeStatusValid
+	order.SerialNumber = cb.SerialNumber
+	order.Certificate = strings.Join(chain, "\n") + "\n"
+	if err := acmePut(ar.Storage, "acme/orders/"+order.AccountID+"/"+order.ID, order); err != nil {
+		return nil, err
+	}
+
+	return &acmeResponse{
+		status:   http.StatusOK,
+		body:     acmeOrderBody(ar, order),
+		location: ar.url("order/" + order.ID),
+	}, nil
+}
+
+func (b *backend) acmeCertificate(ar *acmeRequest, data *framework.FieldData) (*acmeResponse, error) {
+	order, err := b.acmeOrder(ar.Storage, ar.account.ID, data.Get("order_id").(string))
+	if err != nil {
+		return nil, err
+	}
+	if order == nil || order.Certificate == "" {
+		return nil, acmeNotFound("unknown certificate")
+	}
+
+	return &acmeResponse{
+		status:      http.StatusOK,
+		rawBody:     []byte(order.Certificate),
+		contentType: "application/pem-certificate-chain",
+	}, nil
+}
+
+// acmeUpdateOrderStatus moves a pending order to ready once all of its
+// authorizations are valid, or to invalid once one of them failed or the
+// order expired
+func (b *backend) acmeUpdateOrderStatus(s logical.Storage, order *acmeOrder) error {
+	status := order.Status
+	switch {
+	case status != acmeStatusPending && status != acmeStatusReady:
+	case time.Now().After(order.Expires):
+		status = acmeStatusInvalid
+	case status == acmeStatusPending:
+		status = acmeStatusReady
+		for _, id := range order.AuthorizationIDs {
+			authz, err := b.acmeAuthorization(s, order.AccountID, id)
+			if err != nil {
+				return err
+			}
+			if authz == nil {
+				status = acmeStatusInvalid
+				break
+			}
+			acmeExpireAuthorization(authz)
+			if authz.Status == acmeStatusPending {
+				status = acmeStatusPending
+			} else if authz.Status != acmeStatusValid {
+				status = acmeStatusInvalid
+				break
+			}
+		}
+	}
+
+	if status == order.Status {
+		return nil
+	}
+	order.Status = status
+	return acmePut(s, "acme/orders/"+order.AccountID+"/"+order.ID, order)
+}
+
+// acmeExpireAuthorization invalidates a pending authorization that expired,
+// returning whether it did
+func acmeExpireAuthorization(authz *acmeAuthorization) bool {
+	if authz.Status != acmeStatusPending || time.Now().Before(authz.Expires) {
+		return false
+	}
+	authz.Status = acmeStatusInvalid
+	return true
+}
+
+func acmeValidateContact(contact []string) error {
+	for _, c := range contact {
+		if !strings.HasPrefix(c, "mailto:") {
+			return acmeError("unsupportedContact", http.StatusBadRequest, "unsupported contact %q; only mailto: is supported", c)
+		}
+	}
+	return nil
+}
+
+func acmeAccountBody(ar *acmeRequest, account *acmeAccount) map[string]interface{} {
+	contact := account.Contact
+	if contact == nil {
+		contact = []string{}
+	}
+	return map[string]interface{}{
+		"status":  account.Status,
+		"contact": contact,
+		"orders":  ar.url("account/" + account.ID + "/orders"),
+	}
+}
+
+func acmeOrderBody(ar *acmeRequest, order *acmeOrder) map[string]interface{} {
+	authorizations := make([]string, 0, len(order.AuthorizationIDs))
+	for _, id := range order.AuthorizationIDs {
+		authorizations = append(authorizations, ar.url("authz/"+id))
+	}
+
+	body := map[string]interface{}{
+		"status":         order.Status,
+		"expires":        order.Expires.Format(time.RFC3339),
+		"identifiers":    order.Identifiers,
+		"authorizations": authorizations,
+		"finalize":       ar.url("order/" + order.ID + "/finalize"),
+	}
+	if order.Certificate != "" {
+		body["certificate"] = ar.url("cert/" + order.ID)
+	}
+	return body
+}
+
+func acmeAuthorizationBody(ar *acmeRequest, authz *acmeAuthorization) map[string]interface{} {
+	challenges := make([]map[string]interface{}, 0, len(authz.Challenges))
+	for _, challenge := range authz.Challenges {
+		challenges = append(challenges, acmeChallengeBody(ar, authz, challenge))
+	}
+
+	body := map[string]interface{}{
+		"identifier": authz.Identifier,
+		"status":     authz.Status,
+		"expires":    authz.Expires.Format(time.RFC3339),
+		"challenges": challenges,
+	}
+	if authz.Wildcard {
+		body["wildcard"] = true
+	}
+	return body
+}
+
+func acmeChallengeBody(ar *acmeRequest, authz *acmeAuthorization, challenge *acmeChallenge) map[string]interface{} {
+	body := map[string]interface{}{
+		"type":   challenge.Type,
+		"url":    ar.url("challenge/" + authz.ID + "/" + challenge.Type),
+		"status": challenge.Status,
+		"token":  challenge.Token,
+	}
+	if !challenge.Validated.IsZero() {
+		body["validated"] = challenge.Validated.Format(time.RFC3339)
+	}
+	if challenge.Error != nil {
+		body["error"] = challenge.Error
+	}
+	return body
+}
+
+const pathACMEHelpSyn = `
+The ACME server of a role.
+`
+
+const pathACMEHelpDesc = `
+These endpoints implement an ACME (RFC 8555) server for each role, with its
+directory at "acme/<role>/directory". ACME clients create accounts, place
+orders for DNS names, prove control of the names through http-01 or dns-01
+challenges and then finalize their orders with a CSR. Certificates are
+issued with the same checks as the "sign/<role>" endpoint.
+
+The ACME server is enabled and the external URL of the backend is set at
+"config/acme".
+`
@@ -0,0 +1,469 @@
+package pki
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/helper/certutil"
+	"github.com/hashicorp/vault/logical"
+	jose "gopkg.in/square/go-jose.v2"
+)
+
+const testACMEDirectory = "https://vault.example.com/v1/pki/acme/test"
+
+type testACMEResolver map[string][]string
+
+func (r testACMEResolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	records, ok := r[name]
+	if !ok {
+		return nil, fmt.Errorf("no such host %s", name)
+	}
+	return records, nil
+}
+
+type testACMENonce string
+
+func (n testACMENonce) Nonce() (string, error) {
+	return string(n), nil
+}
+
+// testACMEClient is a minimal ACME client talking to the backend directly
+type testACMEClient struct {
+	t       *testing.T
+	b       *backend
+	storage logical.Storage
+	key     *ecdsa.PrivateKey
+	kid     string
+}
+
+type testACMEResponse struct {
+	status  int
+	headers map[string][]string
+	body    []byte
+}
+
+func (r *testACMEResponse) decode(t *testing.T) map[string]interface{} {
+	var out map[string]interface{}
+	if err := json.Unmarshal(r.body, &out); err != nil {
+		t.Fatalf("error decoding %q: %v", r.body, err)
+	}
+	return out
+}
+
+func (c *testACMEClient) do(op logical.Operation, path string, data map[string]interface{}) *testACMEResponse {
+	resp, err := c.b.HandleRequest(&logical.Request{
+		Operation: op,
+		Path:      "acme/test/" + path,
+		Storage:   c.storage,
+		Data:      data,
+	})
+	if err != nil || resp == nil {
+		c.t.Fatalf("bad: err: %v resp: %#v", err, resp)
+	}
+	ret := &testACMEResponse{
+		status:  resp.Data[logical.HTTPStatusCode].(int),
+		headers: resp.Data[logical.HTTPHeaders].(map[string][]string),
+	}
+	if body, ok := resp.Data[logical.HTTPRawBody].([]byte); ok {
+		ret.body = body
+	}
+	if len(ret.headers["Replay-Nonce"]) != 1 {
+		c.t.Fatalf("missing nonce: %#v", ret.headers)
+	}
+	return ret
+}
+
+func (c *testACMEClient) nonce() string {
+	resp := c.do(logical.ReadOperation, "new-nonce", nil)
+	if resp.status != http.StatusNoContent {
+		c.t.Fatalf("bad: %#v", resp)
+	}
+	return resp.headers["Replay-Nonce"][0]
+}
+
+func (c *testACMEClient) sign(path, nonce string, payload interface{}) []byte {
+	var payloadBytes []byte
+	if payload != nil {
+		var err error
+		if payloadBytes, err = json.Marshal(payload); err != nil {
+			c.t.Fatal(err)
+		}
+	}
+
+	opts := &jose.SignerOptions{
+		NonceSource: testACMENonce(nonce),
+		EmbedJWK:    c.kid == "",
+	}
+	opts.WithHeader("url", testACMEDirectory+"/"+path)
+	signer, err := jose.NewSigner(jose.SigningKey{
+		Algorithm: jose.ES256,
+		Key: jose.JSONWebKey{
+			Key:   c.key,
+			KeyID: c.kid,
+		},
+	}, opts)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	jws, err := signer.Sign(payloadBytes)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+
+	// The payload of POST-as-GET requests is an empty string, which the
+	// serialization would leave out
+	var body map[string]interface{}
+	if err := json.Unmarshal([]byte(jws.FullSerialize()), &body); err != nil {
+		c.t.Fatal(err)
+	}
+	if _, ok := body["payload"]; !ok {
+		body["payload"] = ""
+	}
+	ret, err := json.Marshal(body)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	return ret
+}
+
+// post sends a signed request; a nil payload sends a POST-as-GET request
+func (c *testACMEClient) post(path string, payload interface{}) *testACMEResponse {
+	return c.do(logical.UpdateOperation, path, map[string]interface{}{
+		logical.HTTPRawBody: c.sign(path, c.nonce(), payload),
+	})
+}
+
+func testACMEExpectProblem(t *testing.T, resp *testACMEResponse, status int, errType string) {
+	if resp.status != status {
+		t.Fatalf("expected status %d, got %d: %s", status, resp.status, resp.body)
+	}
+	if problem := resp.decode(t); problem["type"] != acmeErrorPrefix+errType {
+		t.Fatalf("expected %s, got %#v", errType, problem)
+	}
+}
+
+func testACMESetup(t *testing.T) (*testACMEClient, *x509.Certificate) {
+	b, storage := createBackendWithStorage(t)
+
+	resp, err := b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "root/generate/internal",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"common_name": "myvault.com",
+			"ttl":         "40h",
+		},
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("bad: err: %v resp: %#v", err, resp)
+	}
+	caCert := testParseCert(t, resp.Data["certificate"].(string))
+
+	for path, data := range map[string]map[string]interface{}{
+		"roles/test": {
+			"allowed_domains":  "example.com",
+			"allow_subdomains": true,
+			"ttl":              "5h",
+		},
+		"config/acme": {
+			"enabled":  true,
+			"base_url": "https://vault.example.com/v1/pki/",
+		},
+	} {
+		resp, err = b.HandleRequest(&logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      path,
+			Storage:   storage,
+			Data:      data,
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: err: %v resp: %#v", err, resp)
+		}
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &testACMEClient{
+		t:       t,
+		b:       b,
+		storage: storage,
+		key:     key,
+	}, caCert
+}
+
+func TestPki_ACME(t *testing.T) {
+	c, caCert := testACMESetup(t)
+
+	resp := c.do(logical.ReadOperation, "directory", nil)
+	directory := resp.decode(t)
+	if resp.status != http.StatusOK || directory["newOrder"] != testACMEDirectory+"/new-order" {
+		t.Fatalf("bad: %#v", directory)
+	}
+
+	// Create an account, and make sure the same key finds it again
+	resp = c.post("new-account", map[string]interface{}{
+		"contact":              []string{"mailto:admin@example.com"},
+		"termsOfServiceAgreed": true,
+	})
+	if resp.status != http.StatusCreated || resp.decode(t)["status"] != "valid" {
+		t.Fatalf("bad: %d %s", resp.status, resp.body)
+	}
+	accountURL := resp.headers["Location"][0]
+	resp = c.post("new-account", map[string]interface{}{
+		"onlyReturnExisting": true,
+	})
+	if resp.status != http.StatusOK || resp.headers["Location"][0] != accountURL {
+		t.Fatalf("bad: %d %#v", resp.status, resp.headers)
+	}
+	c.kid = accountURL
+
+	// Names outside of the role are rejected up front
+	resp = c.post("new-order", map[string]interface{}{
+		"identifiers": []map[string]string{{"type": "dns", "value": "foo.example.org"}},
+	})
+	testACMEExpectProblem(t, resp, http.StatusBadRequest, "rejectedIdentifier")
+
+	resp = c.post("new-order", map[string]interface{}{
+		"identifiers": []map[string]string{
+			{"type": "dns", "value": "foo.example.com"},
+			{"type": "dns", "value": "bar.example.com"},
+		},
+	})
+	if resp.status != http.StatusCreated {
+		t.Fatalf("bad: %d %s", resp.status, resp.body)
+	}
+	orderURL := resp.headers["Location"][0]
+	orderPath := strings.TrimPrefix(orderURL, testACMEDirectory+"/")
+	order := resp.decode(t)
+	if order["status"] != "pending" {
+		t.Fatalf("bad: %#v", order)
+	}
+
+	// Finalizing before the names are validated fails
+	resp = c.post(strings.TrimPrefix(order["finalize"].(string), testACMEDirectory+"/"), map[string]interface{}{
+		"csr": "",
+	})
+	testACMEExpectProblem(t, resp, http.StatusForbidden, "orderNotReady")
+
+	thumbprint, err := acmeThumbprint(&jose.JSONWebKey{Key: c.key.Public()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Serve the http-01 challenge for foo.example.com, and the dns-01
+	// challenge for bar.example.com, without touching the network
+	challenges := map[string]string{}
+	resolver := testACMEResolver{}
+	mux := http.NewServeMux()
+	for _, authzURL := range order["authorizations"].([]interface{}) {
+		resp = c.post(strings.TrimPrefix(authzURL.(string), testACMEDirectory+"/"), nil)
+		authz := resp.decode(t)
+		name := authz["identifier"].(map[string]interface{})["value"].(string)
+		for _, raw := range authz["challenges"].([]interface{}) {
+			challenge := raw.(map[string]interface{})
+			keyAuthz := challenge["token"].(string) + "." + thumbprint
+			switch {
+			case name == "foo.example.com" && challenge["type"] == "http-01":
+				mux.HandleFunc("/.well-known/acme-challenge/"+challenge["token"].(string), func(w http.ResponseWriter, r *http.Request) {
+					if r.Host != "foo.example.com" {
+						w.WriteHeader(http.StatusNotFound)
+						return
+					}
+					w.Write([]byte(keyAuthz))
+				})
+				challenges[name] = challenge["url"].(string)
+			case name == "bar.example.com" && challenge["type"] == "dns-01":
+				digest := sha256.Sum256([]byte(keyAuthz))
+				resolver["_acme-challenge."+name] = []string{"unrelated", base64.RawURLEncoding.EncodeToString(digest[:])}
+				challenges[name] = challenge["url"].(string)
+			}
+		}
+	}
+	if len(challenges) != 2 {
+		t.Fatalf("bad: %#v", challenges)
+	}
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	c.b.acmeResolver = resolver
+	c.b.acmeDialer = func(ctx context.Context, network, address string) (net.Conn, error) {
+		if address != "foo.example.com:80" {
+			return nil, fmt.Errorf("unexpected address %s", address)
+		}
+		return (&net.Dialer{}).DialContext(ctx, network, server.Listener.Addr().String())
+	}
+
+	for name, challengeURL := range challenges {
+		resp = c.post(strings.TrimPrefix(challengeURL, testACMEDirectory+"/"), map[string]interface{}{})
+		if challenge := resp.decode(t); resp.status != http.StatusOK || challenge["status"] != "valid" {
+			t.Fatalf("%s: bad: %d %#v", name, resp.status, challenge)
+		}
+	}
+
+	resp = c.post(orderPath, nil)
+	if order = resp.decode(t); order["status"] != "ready" {
+		t.Fatalf("bad: %#v", order)
+	}
+
+	csrKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	csr := func(names ...string) string {
+		der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+			Subject:  pkix.Name{CommonName: names[0]},
+			DNSNames: names,
+		}, csrKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.RawURLEncoding.EncodeToString(der)
+	}
+
+	// The CSR must match the order
+	resp = c.post(orderPath+"/finalize", map[string]interface{}{
+		"csr": csr("foo.example.com", "baz.example.com"),
+	})
+	testACMEExpectProblem(t, resp, http.StatusBadRequest, "badCSR")
+
+	resp = c.post(orderPath+"/finalize", map[string]interface{}{
+		"csr": csr("foo.example.com", "bar.example.com"),
+	})
+	if order = resp.decode(t); resp.status != http.StatusOK || order["status"] != "valid" {
+		t.Fatalf("bad: %d %#v", resp.status, order)
+	}
+
+	resp = c.post(strings.TrimPrefix(order["certificate"].(string), testACMEDirectory+"/"), nil)
+	if resp.status != http.StatusOK {
+		t.Fatalf("bad: %d %s", resp.status, resp.body)
+	}
+	block, rest := pem.Decode(resp.body)
+	if block == nil {
+		t.Fatalf("bad: %s", resp.body)
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cert.CheckSignatureFrom(caCert); err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(cert.DNSNames)
+	if !reflect.DeepEqual(cert.DNSNames, []string{"bar.example.com", "foo.example.com"}) {
+		t.Fatalf("bad: %#v", cert.DNSNames)
+	}
+	if block, _ = pem.Decode(rest); block == nil {
+		t.Fatalf("missing issuer in chain: %s", resp.body)
+	}
+
+	// The certificate is stored like any other issued certificate
+	serial := certutil.GetHexFormatted(cert.SerialNumber.Bytes(), ":")
+	if entry, err := fetchCertBySerial(&logical.Request{Storage: c.storage}, "certs/", serial); err != nil || entry == nil {
+		t.Fatalf("certificate not stored: %v %v", entry, err)
+	}
+
+	resp = c.post(strings.TrimPrefix(accountURL, testACMEDirectory+"/")+"/orders", nil)
+	if orders := resp.decode(t)["orders"].([]interface{}); len(orders) != 1 || orders[0] != orderURL {
+		t.Fatalf("bad: %#v", orders)
+	}
+}
+
+func TestPki_ACME_Failures(t *testing.T) {
+	c, _ := testACMESetup(t)
+
+	resp := c.post("new-account", map[string]interface{}{
+		"termsOfServiceAgreed": true,
+	})
+	if resp.status != http.StatusCreated {
+		t.Fatalf("bad: %d %s", resp.status, resp.body)
+	}
+	c.kid = resp.headers["Location"][0]
+
+	// Nonces can only be used once
+	nonce := c.nonce()
+	payload := map[string]interface{}{
+		"identifiers": []map[string]string{{"type": "dns", "value": "foo.example.com"}},
+	}
+	resp = c.do(logical.UpdateOperation, "new-order", map[string]interface{}{
+		logical.HTTPRawBody: c.sign("new-order", nonce, payload),
+	})
+	if resp.status != http.StatusCreated {
+		t.Fatalf("bad: %d %s", resp.status, resp.body)
+	}
+	order := resp.decode(t)
+	resp = c.do(logical.UpdateOperation, "new-order", map[string]interface{}{
+		logical.HTTPRawBody: c.sign("new-order", nonce, payload),
+	})
+	testACMEExpectProblem(t, resp, http.StatusBadRequest, "badNonce")
+
+	// Requests signed for one URL cannot be replayed against another
+	resp = c.do(logical.UpdateOperation, "new-order", map[string]interface{}{
+		logical.HTTPRawBody: c.sign("new-account", c.nonce(), payload),
+	})
+	testACMEExpectProblem(t, resp, http.StatusForbidden, "unauthorized")
+
+	// A failed challenge invalidates the authorization and the order
+	c.b.acmeResolver = testACMEResolver{"_acme-challenge.foo.example.com": []string{"wrong"}}
+	authzPath := strings.TrimPrefix(order["authorizations"].([]interface{})[0].(string), testACMEDirectory+"/")
+	resp = c.post(authzPath, nil)
+	var challengeURL string
+	for _, raw := range resp.decode(t)["challenges"].([]interface{}) {
+		if challenge := raw.(map[string]interface{}); challenge["type"] == "dns-01" {
+			challengeURL = challenge["url"].(string)
+		}
+	}
+	resp = c.post(strings.TrimPrefix(challengeURL, testACMEDirectory+"/"), map[string]interface{}{})
+	challenge := resp.decode(t)
+	if challenge["status"] != "invalid" || challenge["error"].(map[string]interface{})["type"] != acmeErrorPrefix+"incorrectResponse" {
+		t.Fatalf("bad: %#v", challenge)
+	}
+	resp = c.post(strings.TrimPrefix(order["finalize"].(string), testACMEDirectory+"/"), map[string]interface{}{
+		"csr": "",
+	})
+	testACMEExpectProblem(t, resp, http.StatusForbidden, "orderNotReady")
+
+	// Deactivated accounts cannot be used anymore
+	accountPath := strings.TrimPrefix(c.kid, testACMEDirectory+"/")
+	resp = c.post(accountPath, map[string]interface{}{
+		"status": "deactivated",
+	})
+	if resp.status != http.StatusOK || resp.decode(t)["status"] != "deactivated" {
+		t.Fatalf("bad: %d %s", resp.status, resp.body)
+	}
+	resp = c.post("new-order", payload)
+	testACMEExpectProblem(t, resp, http.StatusForbidden, "unauthorized")
+
+	// ACME can be switched off
+	if _, err := c.b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config/acme",
+		Storage:   c.storage,
+		Data: map[string]interface{}{
+			"enabled": false,
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	resp = c.do(logical.ReadOperation, "directory", nil)
+	testACMEExpectProblem(t, resp, http.StatusForbidden, "unauthorized")
+}
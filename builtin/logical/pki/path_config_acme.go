@@ -0,0 +1,124 @@
+package pki
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/asaskevich/govalidator"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+// acmeConfig holds the configuration of the ACME server of the backend
+type acmeConfig struct {
+	Enabled bool   `json:"enabled" mapstructure:"enabled" structs:"enabled"`
+	BaseURL string `json:"base_url" mapstructure:"base_url" structs:"base_url"`
+}
+
+func pathConfigACME(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "config/acme",
+		Fields: map[string]*framework.FieldSchema{
+			"enabled": &framework.FieldSchema{
+				Type: framework.TypeBool,
+				Description: `Whether the ACME directories of the roles of
+this backend are served; defaults to false`,
+			},
+
+			"base_url": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `The external URL of this backend, such as
+"https://vault.example.com/v1/pki", which ACME
+clients use to reach it`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathACMEConfigRead,
+			logical.UpdateOperation: b.pathACMEConfigWrite,
+		},
+
+		HelpSynopsis:    pathConfigACMEHelpSyn,
+		HelpDescription: pathConfigACMEHelpDesc,
+	}
+}
+
+func (b *backend) acmeConfig(s logical.Storage) (*acmeConfig, error) {
+	entry, err := s.Get("config/acme")
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var result acmeConfig
+	if err := entry.DecodeJSON(&result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (b *backend) pathACMEConfigRead(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	config, err := b.acmeConfig(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		return nil, nil
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"enabled":  config.Enabled,
+			"base_url": config.BaseURL,
+		},
+	}, nil
+}
+
+func (b *backend) pathACMEConfigWrite(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	config, err := b.acmeConfig(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		config = &acmeConfig{}
+	}
+
+	if enabledRaw, ok := data.GetOk("enabled"); ok {
+		config.Enabled = enabledRaw.(bool)
+	}
+	if baseURLRaw, ok := data.GetOk("base_url"); ok {
+		config.BaseURL = strings.TrimSuffix(baseURLRaw.(string), "/")
+	}
+
+	if config.BaseURL != "" && !govalidator.IsURL(config.BaseURL) {
+		return logical.ErrorResponse(fmt.Sprintf("invalid base_url %q", config.BaseURL)), nil
+	}
+	if config.Enabled && config.BaseURL == "" {
+		return logical.ErrorResponse("base_url must be set to enable ACME"), nil
+	}
+
+	entry, err := logical.StorageEntryJSON("config/acme", config)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(entry); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+const pathConfigACMEHelpSyn = `
+Configure the ACME server of this backend.
+`
+
+const pathConfigACMEHelpDesc = `
+This endpoint enables or disables the ACME (RFC 8555) directories served for
+each role at "acme/<role>/directory", and sets the external URL of this
+backend that is used to build the URLs handed out to ACME clients.
+`
@@ -1,8 +1,6 @@
 package pki
 
 import (
-	"fmt"
-
 	"github.com/hashicorp/vault/helper/certutil"
 	"github.com/hashicorp/vault/helper/errutil"
 	"github.com/hashicorp/vault/logical"
@@ -56,32 +54,33 @@ func (b *backend) pathCAWrite(
 		return logical.ErrorResponse("the given certificate is not marked for CA use and cannot be used with this backend"), nil
 	}
 
-	cb, err := parsedBundle.ToCertBundle()
-	if err != nil {
-		return nil, fmt.Errorf("error converting raw values into cert bundle: %s", err)
-	}
-
-	entry, err := logical.StorageEntryJSON("config/ca_bundle", cb)
-	if err != nil {
+	if err := migrateLegacyCABundle(b, req.Storage); err != nil {
 		return nil, err
 	}
-	err = req.Storage.Put(entry)
+
+	// The bundle becomes the CA of the backend, as it used to be the only one
+	issuer, err := storeCA(b, req.Storage, parsedBundle, "", "", true)
 	if err != nil {
-		return nil, err
+		switch err.(type) {
+		case errutil.UserError:
+			return logical.ErrorResponse(err.Error()), nil
+		default:
+			return nil, err
+		}
 	}
 
-	// For ease of later use, also store just the certificate at a known
-	// location, plus a fresh CRL
-	entry.Key = "ca"
-	entry.Value = parsedBundle.CertificateBytes
-	err = req.Storage.Put(entry)
+	// Also build a fresh CRL
+	err = buildCRL(b, req)
 	if err != nil {
 		return nil, err
 	}
 
-	err = buildCRL(b, req)
-
-	return nil, err
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"issuer_id": issuer.ID,
+			"key_id":    issuer.KeyID,
+		},
+	}, nil
 }
 
 const pathConfigCAHelpSyn = `
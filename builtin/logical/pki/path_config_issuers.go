@@ -0,0 +1,92 @@
+package pki
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+func pathConfigIssuers(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "config/issuers",
+		Fields: map[string]*framework.FieldSchema{
+			"default": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `The ID or name of the issuer used by roles
+and endpoints referencing the "default" issuer`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathIssuersConfigRead,
+			logical.UpdateOperation: b.pathIssuersConfigWrite,
+		},
+
+		HelpSynopsis:    pathConfigIssuersHelpSyn,
+		HelpDescription: pathConfigIssuersHelpDesc,
+	}
+}
+
+func (b *backend) pathIssuersConfigRead(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	if err := migrateLegacyCABundle(b, req.Storage); err != nil {
+		return nil, err
+	}
+
+	config, err := fetchIssuerConfig(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"default": config.DefaultIssuerID,
+		},
+	}, nil
+}
+
+func (b *backend) pathIssuersConfigWrite(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	ref := data.Get("default").(string)
+	if ref == "" || ref == defaultIssuerRef {
+		return logical.ErrorResponse(`"default" must be the ID or name of an issuer`), nil
+	}
+
+	if err := migrateLegacyCABundle(b, req.Storage); err != nil {
+		return nil, err
+	}
+
+	b.issuersLock.Lock()
+	issuer, errResp, err := b.fetchIssuerByRef(req, ref)
+	if errResp == nil && err == nil {
+		if issuer.KeyID == "" {
+			errResp = logical.ErrorResponse(fmt.Sprintf("issuer %s has no key in this backend and cannot be the default issuer", issuer.ID))
+		} else {
+			err = setDefaultIssuer(req.Storage, issuer.ID)
+		}
+	}
+	b.issuersLock.Unlock()
+	if errResp != nil || err != nil {
+		return errResp, err
+	}
+
+	// The CRL of the default issuer is also served from the "crl" endpoints
+	b.revokeStorageLock.RLock()
+	defer b.revokeStorageLock.RUnlock()
+	if err := buildCRL(b, req); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+const pathConfigIssuersHelpSyn = `
+Configure the default issuer of this backend.
+`
+
+const pathConfigIssuersHelpDesc = `
+The default issuer signs certificates of roles whose "issuer_ref" is "default",
+and is the CA served from the "ca", "ca_chain" and "crl" endpoints. Changing it
+allows moving all such roles to a new CA at once while rotating it.
+`
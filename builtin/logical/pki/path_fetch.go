@@ -3,6 +3,7 @@ package pki
 import (
 	"encoding/pem"
 	"fmt"
+	"strings"
 
 	"github.com/hashicorp/vault/helper/errutil"
 	"github.com/hashicorp/vault/logical"
@@ -87,6 +88,52 @@ func pathFetchCRLViaCertPath(b *backend) *framework.Path {
 	}
 }
 
+// Returns the certificate and CA chain of an issuer
+func pathFetchIssuer(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "cert/issuer/" + framework.GenericNameRegex("issuer_ref"),
+		Fields: map[string]*framework.FieldSchema{
+			"issuer_ref": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: `The ID or name of the issuer, or "default"`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation: b.pathFetchIssuerRead,
+		},
+
+		HelpSynopsis:    pathFetchIssuerHelpSyn,
+		HelpDescription: pathFetchIssuerHelpDesc,
+	}
+}
+
+// Returns the CRL of an issuer, in a non-raw format unless an encoding is
+// requested
+func pathFetchIssuerCRL(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "cert/issuer/" + framework.GenericNameRegex("issuer_ref") + `/crl(/(?P<encoding>der|pem))?`,
+		Fields: map[string]*framework.FieldSchema{
+			"issuer_ref": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: `The ID or name of the issuer, or "default"`,
+			},
+
+			"encoding": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: `"der" or "pem" to return the raw CRL`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation: b.pathFetchIssuerCRLRead,
+		},
+
+		HelpSynopsis:    pathFetchIssuerHelpSyn,
+		HelpDescription: pathFetchIssuerHelpDesc,
+	}
+}
+
 // This returns the list of serial numbers for certs
 func pathFetchListCerts(b *backend) *framework.Path {
 	return &framework.Path{
@@ -156,7 +203,7 @@ func (b *backend) pathFetchRead(req *logical.Request, data *framework.FieldData)
 	}
 
 	if serial == "ca_chain" {
-		caInfo, err := fetchCAInfo(req)
+		caInfo, err := fetchCAInfo(b, req, defaultIssuerRef)
 		switch err.(type) {
 		case errutil.UserError:
 			response = logical.ErrorResponse(err.Error())
@@ -257,6 +304,88 @@ reply:
 	return
 }
 
+func (b *backend) pathFetchIssuerRead(req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	if err := migrateLegacyCABundle(b, req.Storage); err != nil {
+		return nil, err
+	}
+
+	issuer, errResp, err := b.fetchIssuerByRef(req, data.Get("issuer_ref").(string))
+	if errResp != nil || err != nil {
+		return errResp, err
+	}
+
+	chain, err := issuerChain(req.Storage, issuer)
+	if err != nil {
+		return nil, err
+	}
+	var caChain []string
+	for _, block := range chain {
+		caChain = append(caChain, strings.TrimSpace(string(pem.EncodeToMemory(&pem.Block{
+			Type:  "CERTIFICATE",
+			Bytes: block.Bytes,
+		}))))
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"issuer_id":   issuer.ID,
+			"issuer_name": issuer.Name,
+			"certificate": issuer.Certificate,
+			"ca_chain":    caChain,
+		},
+	}, nil
+}
+
+func (b *backend) pathFetchIssuerCRLRead(req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	if err := migrateLegacyCABundle(b, req.Storage); err != nil {
+		return nil, err
+	}
+
+	issuer, errResp, err := b.fetchIssuerByRef(req, data.Get("issuer_ref").(string))
+	if errResp != nil || err != nil {
+		return errResp, err
+	}
+
+	entry, err := req.Storage.Get("crls/" + issuer.ID)
+	if err != nil {
+		return nil, err
+	}
+	var crl []byte
+	if entry != nil {
+		crl = entry.Value
+	}
+
+	encoding := data.Get("encoding").(string)
+	if encoding == "pem" || encoding == "" {
+		if len(crl) > 0 {
+			crl = pem.EncodeToMemory(&pem.Block{
+				Type:  "X509 CRL",
+				Bytes: crl,
+			})
+		}
+	}
+
+	if encoding == "" {
+		return &logical.Response{
+			Data: map[string]interface{}{
+				"crl": string(crl),
+			},
+		}, nil
+	}
+
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			logical.HTTPContentType: "application/pkix-crl",
+			logical.HTTPRawBody:     crl,
+			logical.HTTPStatusCode:  200,
+		},
+	}
+	if len(crl) == 0 {
+		resp.Data[logical.HTTPStatusCode] = 204
+	}
+	return resp, nil
+}
+
 const pathFetchHelpSyn = `
 Fetch a CA, CRL, CA Chain, or non-revoked certificate.
 `
@@ -268,3 +397,15 @@ Using "ca" or "crl" as the value fetches the appropriate information in DER enco
 
 Using "ca_chain" as the value fetches the certificate authority trust chain in PEM encoding.
 `
+
+const pathFetchIssuerHelpSyn = `
+Fetch the certificate, CA chain or CRL of an issuer.
+`
+
+const pathFetchIssuerHelpDesc = `
+This allows the certificate and CA chain of an issuer, referenced by ID, by
+name or as "default", to be fetched from "cert/issuer/<issuer_ref>".
+
+Its CRL is returned in PEM encoding from "cert/issuer/<issuer_ref>/crl". Add
+"/der" or "/pem" to get the raw CRL in the respective encoding.
+`
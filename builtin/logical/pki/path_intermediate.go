@@ -28,6 +28,24 @@ func pathGenerateIntermediate(b *backend) *framework.Path {
 	return ret
 }
 
+func pathGenerateIssuerIntermediate(b *backend) *framework.Path {
+	ret := &framework.Path{
+		Pattern: "issuers/generate/intermediate/" + framework.GenericNameRegex("exported"),
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathGenerateIntermediate,
+		},
+
+		HelpSynopsis:    pathGenerateIntermediateHelpSyn,
+		HelpDescription: pathGenerateIntermediateHelpDesc,
+	}
+
+	ret.Fields = addCACommonFields(map[string]*framework.FieldSchema{})
+	ret.Fields = addCAKeyGenerationFields(ret.Fields)
+
+	return ret
+}
+
 func pathSetSignedIntermediate(b *backend) *framework.Path {
 	ret := &framework.Path{
 		Pattern: "intermediate/set-signed",
@@ -50,6 +68,8 @@ endpoint.`,
 		HelpDescription: pathSetSignedIntermediateHelpDesc,
 	}
 
+	ret.Fields = addIssuerNameField(ret.Fields)
+
 	return ret
 }
 
@@ -57,11 +77,20 @@ func (b *backend) pathGenerateIntermediate(
 	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
 	var err error
 
+	if err := migrateLegacyCABundle(b, req.Storage); err != nil {
+		return nil, err
+	}
+
 	exported, format, role, errorResp := b.getGenerationParams(data)
 	if errorResp != nil {
 		return errorResp, nil
 	}
 
+	keyName := data.Get("key_name").(string)
+	if err := checkKeyName(req.Storage, keyName, ""); err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
 	var resp *logical.Response
 	parsedBundle, err := generateIntermediateCSR(b, role, nil, req, data)
 	if err != nil {
@@ -106,18 +135,15 @@ func (b *backend) pathGenerateIntermediate(
 		}
 	}
 
-	cb := &certutil.CertBundle{}
-	cb.PrivateKey = csrb.PrivateKey
-	cb.PrivateKeyType = csrb.PrivateKeyType
-
-	entry, err := logical.StorageEntryJSON("config/ca_bundle", cb)
-	if err != nil {
-		return nil, err
-	}
-	err = req.Storage.Put(entry)
+	// The key is kept until the signed certificate is set, which then
+	// becomes an issuer using it
+	b.issuersLock.Lock()
+	key, _, err := importKey(req.Storage, csrb.PrivateKey, keyName)
+	b.issuersLock.Unlock()
 	if err != nil {
 		return nil, err
 	}
+	resp.Data["key_id"] = key.ID
 
 	return resp, nil
 }
@@ -130,6 +156,10 @@ func (b *backend) pathSetSignedIntermediate(
 		return logical.ErrorResponse("no certificate provided in the \"certificate\" parameter"), nil
 	}
 
+	if err := migrateLegacyCABundle(b, req.Storage); err != nil {
+		return nil, err
+	}
+
 	inputBundle, err := certutil.ParsePEMBundle(cert)
 	if err != nil {
 		switch err.(type) {
@@ -144,36 +174,14 @@ func (b *backend) pathSetSignedIntermediate(
 		return logical.ErrorResponse("supplied certificate could not be successfully parsed"), nil
 	}
 
-	cb := &certutil.CertBundle{}
-	entry, err := req.Storage.Get("config/ca_bundle")
-	if err != nil {
-		return nil, err
-	}
-	if entry == nil {
-		return logical.ErrorResponse("could not find any existing entry with a private key"), nil
-	}
-
-	err = entry.DecodeJSON(cb)
-	if err != nil {
-		return nil, err
-	}
-
-	if len(cb.PrivateKey) == 0 || cb.PrivateKeyType == "" {
-		return logical.ErrorResponse("could not find an existing private key"), nil
-	}
-
-	parsedCB, err := cb.ToParsedCertBundle()
+	keyID, err := findKeyForCertificate(req.Storage, inputBundle.Certificate)
 	if err != nil {
 		return nil, err
 	}
-	if parsedCB.PrivateKey == nil {
-		return nil, fmt.Errorf("saved key could not be parsed successfully")
+	if keyID == "" {
+		return logical.ErrorResponse("could not find an existing private key matching the certificate"), nil
 	}
 
-	inputBundle.PrivateKey = parsedCB.PrivateKey
-	inputBundle.PrivateKeyType = parsedCB.PrivateKeyType
-	inputBundle.PrivateKeyBytes = parsedCB.PrivateKeyBytes
-
 	if !inputBundle.Certificate.IsCA {
 		return logical.ErrorResponse("the given certificate is not marked for CA use and cannot be used with this backend"), nil
 	}
@@ -182,40 +190,43 @@ func (b *backend) pathSetSignedIntermediate(
 		return nil, fmt.Errorf("verification of parsed bundle failed: %s", err)
 	}
 
-	cb, err = inputBundle.ToCertBundle()
-	if err != nil {
-		return nil, fmt.Errorf("error converting raw values into cert bundle: %s", err)
+	issuerName := data.Get("issuer_name").(string)
+	if err := checkIssuerName(req.Storage, issuerName, ""); err != nil {
+		return logical.ErrorResponse(err.Error()), nil
 	}
 
-	entry, err = logical.StorageEntryJSON("config/ca_bundle", cb)
-	if err != nil {
-		return nil, err
-	}
-	err = req.Storage.Put(entry)
+	// Setting the signed certificate makes it the CA of the backend, as it
+	// used to be the only one
+	issuer, err := storeCA(b, req.Storage, inputBundle, issuerName, "", true)
 	if err != nil {
 		return nil, err
 	}
 
-	entry.Key = "certs/" + normalizeSerial(cb.SerialNumber)
-	entry.Value = inputBundle.CertificateBytes
-	err = req.Storage.Put(entry)
+	cb, err := inputBundle.ToCertBundle()
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("error converting raw values into cert bundle: %s", err)
 	}
 
-	// For ease of later use, also store just the certificate at a known
-	// location
-	entry.Key = "ca"
-	entry.Value = inputBundle.CertificateBytes
-	err = req.Storage.Put(entry)
+	err = req.Storage.Put(&logical.StorageEntry{
+		Key:   "certs/" + normalizeSerial(cb.SerialNumber),
+		Value: inputBundle.CertificateBytes,
+	})
 	if err != nil {
 		return nil, err
 	}
 
 	// Build a fresh CRL
 	err = buildCRL(b, req)
+	if err != nil {
+		return nil, err
+	}
 
-	return nil, err
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"issuer_id": issuer.ID,
+			"key_id":    issuer.KeyID,
+		},
+	}, nil
 }
 
 const pathGenerateIntermediateHelpSyn = `
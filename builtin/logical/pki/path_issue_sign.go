@@ -138,10 +138,14 @@ func (b *backend) pathSignVerbatim(
 		KeyType:          "any",
 		UseCSRCommonName: true,
 		UseCSRSANs:       true,
+		AllowedURISANs:   "*",
+		AllowedOtherSANs: "*",
 		GenerateLease:    new(bool),
+		IssuerRef:        defaultIssuerRef,
 	}
 
 	if role != nil {
+		entry.IssuerRef = role.IssuerRef
 		if role.TTL != "" {
 			entry.TTL = role.TTL
 		}
@@ -168,7 +172,7 @@ func (b *backend) pathIssueSignCert(
 	}
 
 	var caErr error
-	signingBundle, caErr := fetchCAInfo(req)
+	signingBundle, caErr := fetchCAInfo(b, req, role.IssuerRef)
 	switch caErr.(type) {
 	case errutil.UserError:
 		return nil, errutil.UserError{Err: fmt.Sprintf(
@@ -0,0 +1,505 @@
+package pki
+
+import (
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/helper/certutil"
+	"github.com/hashicorp/vault/helper/errutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+func pathListIssuers(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "issuers/?$",
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ListOperation: b.pathIssuerList,
+		},
+
+		HelpSynopsis:    pathListIssuersHelpSyn,
+		HelpDescription: pathListIssuersHelpDesc,
+	}
+}
+
+func pathIssuer(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "issuer/" + framework.GenericNameRegex("issuer_ref"),
+		Fields: map[string]*framework.FieldSchema{
+			"issuer_ref": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: `The ID or name of the issuer, or "default"`,
+			},
+
+			"issuer_name": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: `The name of the issuer`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathIssuerRead,
+			logical.UpdateOperation: b.pathIssuerWrite,
+			logical.DeleteOperation: b.pathIssuerDelete,
+		},
+
+		HelpSynopsis:    pathIssuerHelpSyn,
+		HelpDescription: pathIssuerHelpDesc,
+	}
+}
+
+func pathIssuerCrossSign(b *backend) *framework.Path {
+	ret := &framework.Path{
+		Pattern: "issuer/" + framework.GenericNameRegex("issuer_ref") + "/cross-sign",
+		Fields: map[string]*framework.FieldSchema{
+			"issuer_ref": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: `The ID or name of the signing issuer, or "default"`,
+			},
+
+			"target_issuer": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `The ID or name of the issuer whose certificate
+is cross-signed`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathIssuerCrossSignWrite,
+		},
+
+		HelpSynopsis:    pathIssuerCrossSignHelpSyn,
+		HelpDescription: pathIssuerCrossSignHelpDesc,
+	}
+
+	ret.Fields = addIssuerNameField(ret.Fields)
+
+	return ret
+}
+
+func pathImportIssuers(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "issuers/import/bundle",
+		Fields: map[string]*framework.FieldSchema{
+			"pem_bundle": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `PEM-format, concatenated unencrypted secret
+keys and CA certificates.`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathImportIssuersWrite,
+		},
+
+		HelpSynopsis:    pathImportIssuersHelpSyn,
+		HelpDescription: pathImportIssuersHelpDesc,
+	}
+}
+
+func (b *backend) pathIssuerList(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	if err := migrateLegacyCABundle(b, req.Storage); err != nil {
+		return nil, err
+	}
+
+	ids, err := listIssuers(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	return logical.ListResponse(ids), nil
+}
+
+func (b *backend) pathIssuerRead(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	if err := migrateLegacyCABundle(b, req.Storage); err != nil {
+		return nil, err
+	}
+
+	issuer, errResp, err := b.fetchIssuerByRef(req, data.Get("issuer_ref").(string))
+	if errResp != nil || err != nil {
+		return errResp, err
+	}
+
+	chain, err := issuerChain(req.Storage, issuer)
+	if err != nil {
+		return nil, err
+	}
+	caChain := make([]string, 0, len(chain))
+	for _, block := range chain {
+		caChain = append(caChain, strings.TrimSpace(string(pem.EncodeToMemory(&pem.Block{
+			Type:  "CERTIFICATE",
+			Bytes: block.Bytes,
+		}))))
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"issuer_id":     issuer.ID,
+			"issuer_name":   issuer.Name,
+			"key_id":        issuer.KeyID,
+			"certificate":   issuer.Certificate,
+			"ca_chain":      caChain,
+			"serial_number": issuer.SerialNumber,
+		},
+	}, nil
+}
+
+func (b *backend) pathIssuerWrite(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	if err := migrateLegacyCABundle(b, req.Storage); err != nil {
+		return nil, err
+	}
+
+	b.issuersLock.Lock()
+	defer b.issuersLock.Unlock()
+
+	issuer, errResp, err := b.fetchIssuerByRef(req, data.Get("issuer_ref").(string))
+	if errResp != nil || err != nil {
+		return errResp, err
+	}
+
+	if nameRaw, ok := data.GetOk("issuer_name"); ok {
+		name := nameRaw.(string)
+		if err := checkIssuerName(req.Storage, name, issuer.ID); err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+		issuer.Name = name
+	}
+
+	if err := writeIssuer(req.Storage, issuer); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+func (b *backend) pathIssuerDelete(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	if err := migrateLegacyCABundle(b, req.Storage); err != nil {
+		return nil, err
+	}
+
+	b.issuersLock.Lock()
+	defer b.issuersLock.Unlock()
+
+	issuer, errResp, err := b.fetchIssuerByRef(req, data.Get("issuer_ref").(string))
+	if errResp != nil || err != nil {
+		// Deleting a missing issuer is not an error
+		if errResp != nil {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	config, err := fetchIssuerConfig(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if config.DefaultIssuerID == issuer.ID {
+		if err := setDefaultIssuer(req.Storage, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := req.Storage.Delete("crls/" + issuer.ID); err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Delete("issuers/" + issuer.ID); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+func (b *backend) pathIssuerCrossSignWrite(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	signingBundle, caErr := fetchCAInfo(b, req, data.Get("issuer_ref").(string))
+	switch caErr.(type) {
+	case errutil.UserError:
+		return logical.ErrorResponse(fmt.Sprintf(
+			"could not fetch the signing issuer: %s", caErr)), nil
+	case errutil.InternalError:
+		return nil, errutil.InternalError{Err: fmt.Sprintf(
+			"error fetching the signing issuer: %s", caErr)}
+	}
+
+	targetRef := data.Get("target_issuer").(string)
+	if targetRef == "" {
+		return logical.ErrorResponse(`"target_issuer" must be provided`), nil
+	}
+	target, errResp, err := b.fetchIssuerByRef(req, targetRef)
+	if errResp != nil || err != nil {
+		return errResp, err
+	}
+	if target.ID == signingBundle.IssuerID {
+		return logical.ErrorResponse("an issuer cannot cross-sign itself"), nil
+	}
+	targetCert, err := target.parseCertificate()
+	if err != nil {
+		return nil, err
+	}
+
+	issuerName := data.Get("issuer_name").(string)
+	if err := checkIssuerName(req.Storage, issuerName, ""); err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	// The target certificate is reissued as is, except for its serial
+	// number, its issuer and the URLs of the signing issuer
+	serialNumber, err := certutil.GenerateSerialNumber()
+	if err != nil {
+		return nil, err
+	}
+	template := *targetCert
+	template.SerialNumber = serialNumber
+	template.SignatureAlgorithm = x509.UnknownSignatureAlgorithm
+	template.AuthorityKeyId = signingBundle.Certificate.SubjectKeyId
+	template.IssuingCertificateURL = signingBundle.URLs.IssuingCertificates
+	template.CRLDistributionPoints = signingBundle.URLs.CRLDistributionPoints
+	template.OCSPServer = signingBundle.URLs.OCSPServers
+
+	certBytes, err := x509.CreateCertificate(rand.Reader, &template, signingBundle.Certificate, targetCert.PublicKey, signingBundle.PrivateKey)
+	if err != nil {
+		return nil, errwrap.Wrapf("error cross-signing certificate: {{err}}", err)
+	}
+	cert, err := x509.ParseCertificate(certBytes)
+	if err != nil {
+		return nil, errwrap.Wrapf("error parsing cross-signed certificate: {{err}}", err)
+	}
+
+	issuer, err := storeCA(b, req.Storage, &certutil.ParsedCertBundle{
+		Certificate:      cert,
+		CertificateBytes: certBytes,
+	}, issuerName, "", false)
+	if err != nil {
+		return nil, err
+	}
+
+	// Store the certificate by serial number as well, so it can be revoked
+	serial := certutil.GetHexFormatted(cert.SerialNumber.Bytes(), ":")
+	err = req.Storage.Put(&logical.StorageEntry{
+		Key:   "certs/" + normalizeSerial(serial),
+		Value: certBytes,
+	})
+	if err != nil {
+		return nil, errwrap.Wrapf("unable to store certificate locally: {{err}}", err)
+	}
+
+	signingCB, err := signingBundle.ToCertBundle()
+	if err != nil {
+		return nil, errwrap.Wrapf("error converting raw signing bundle to cert bundle: {{err}}", err)
+	}
+
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			"issuer_id":     issuer.ID,
+			"certificate":   issuer.Certificate,
+			"issuing_ca":    signingCB.Certificate,
+			"serial_number": serial,
+			"expiration":    cert.NotAfter.Unix(),
+		},
+	}
+	if signingBundle.Certificate.NotAfter.Before(cert.NotAfter) {
+		resp.AddWarning("The expiration time for the cross-signed certificate is after the signing issuer's expiration time. Validation paths through the signing issuer will fail past its expiration time.")
+	}
+	if time.Now().After(cert.NotAfter) {
+		resp.AddWarning("The cross-signed certificate has already expired.")
+	}
+
+	return resp, nil
+}
+
+func (b *backend) pathImportIssuersWrite(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	pemBundle := strings.TrimSpace(data.Get("pem_bundle").(string))
+	if pemBundle == "" {
+		return logical.ErrorResponse(`"pem_bundle" must be provided`), nil
+	}
+
+	var keys []string
+	var certs []*x509.Certificate
+	rest := []byte(pemBundle)
+	for len(rest) > 0 {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			return logical.ErrorResponse("unable to decode the PEM bundle"), nil
+		}
+
+		switch {
+		case strings.HasSuffix(block.Type, "PRIVATE KEY"):
+			keys = append(keys, string(pem.EncodeToMemory(block)))
+		case block.Type == "CERTIFICATE":
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return logical.ErrorResponse(fmt.Sprintf("error parsing certificate: %s", err)), nil
+			}
+			if !cert.IsCA {
+				return logical.ErrorResponse(fmt.Sprintf("certificate %q is not marked for CA use and cannot be used with this backend", cert.Subject.CommonName)), nil
+			}
+			certs = append(certs, cert)
+		default:
+			return logical.ErrorResponse(fmt.Sprintf("unsupported PEM block of type %q", block.Type)), nil
+		}
+		rest = []byte(strings.TrimSpace(string(rest)))
+	}
+
+	if err := migrateLegacyCABundle(b, req.Storage); err != nil {
+		return nil, err
+	}
+
+	importedKeys, importedIssuers, err := b.importBundle(req.Storage, keys, certs)
+	if err != nil {
+		switch err.(type) {
+		case errutil.UserError:
+			return logical.ErrorResponse(err.Error()), nil
+		default:
+			return nil, err
+		}
+	}
+
+	// Issuers holding a key get a CRL right away
+	config, err := fetchIssuerConfig(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if config.DefaultIssuerID != "" {
+		if err := buildCRL(b, req); err != nil {
+			return nil, err
+		}
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"imported_keys":    importedKeys,
+			"imported_issuers": importedIssuers,
+		},
+	}, nil
+}
+
+// importBundle stores the given PEM-encoded keys and CA certificates,
+// returning the IDs of the keys and issuers that were not held already. The
+// first new issuer holding a key becomes the default issuer if there is none
+// yet.
+func (b *backend) importBundle(s logical.Storage, keys []string, certs []*x509.Certificate) ([]string, []string, error) {
+	b.issuersLock.Lock()
+	defer b.issuersLock.Unlock()
+
+	importedKeys := []string{}
+	for _, keyPEM := range keys {
+		key, existing, err := importKey(s, keyPEM, "")
+		if err != nil {
+			return nil, nil, err
+		}
+		if !existing {
+			importedKeys = append(importedKeys, key.ID)
+		}
+	}
+
+	config, err := fetchIssuerConfig(s)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	importedIssuers := []string{}
+	for _, cert := range certs {
+		issuer, existing, err := importIssuer(s, cert, nil, "")
+		if err != nil {
+			return nil, nil, err
+		}
+		if existing {
+			continue
+		}
+		importedIssuers = append(importedIssuers, issuer.ID)
+
+		if config.DefaultIssuerID == "" && issuer.KeyID != "" {
+			if err := setDefaultIssuer(s, issuer.ID); err != nil {
+				return nil, nil, err
+			}
+			config.DefaultIssuerID = issuer.ID
+		}
+	}
+
+	return importedKeys, importedIssuers, nil
+}
+
+// fetchIssuerByRef returns the issuer referenced by ID, name or "default",
+// or an error response if there is none
+func (b *backend) fetchIssuerByRef(req *logical.Request, ref string) (*issuerEntry, *logical.Response, error) {
+	issuerID, err := resolveIssuerRef(req.Storage, ref)
+	if err != nil {
+		switch err.(type) {
+		case errutil.UserError:
+			return nil, logical.ErrorResponse(err.Error()), nil
+		default:
+			return nil, nil, err
+		}
+	}
+
+	issuer, err := fetchIssuer(req.Storage, issuerID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if issuer == nil {
+		return nil, logical.ErrorResponse(fmt.Sprintf("unknown issuer %q", ref)), nil
+	}
+
+	return issuer, nil, nil
+}
+
+const pathListIssuersHelpSyn = `
+List the IDs of the issuers of this backend.
+`
+
+const pathListIssuersHelpDesc = `
+This path lists the IDs of the CA certificates, or issuers, held by this
+backend. Use the "issuer/<issuer_ref>" endpoint to read an issuer.
+`
+
+const pathIssuerHelpSyn = `
+Read, rename or delete an issuer of this backend.
+`
+
+const pathIssuerHelpDesc = `
+An issuer is a CA certificate held by this backend along with its key, if the
+backend holds it. Issuers may be referenced by ID, by name, or as "default" for
+the default issuer set in "config/issuers".
+
+Reading an issuer returns its certificate and the chain of certificates leading
+from it to a root. Deleting an issuer keeps its key, which can be removed
+through the "key/<key_ref>" endpoint.
+`
+
+const pathIssuerCrossSignHelpSyn = `
+Cross-sign the certificate of another issuer of this backend.
+`
+
+const pathIssuerCrossSignHelpDesc = `
+This path reissues the certificate of the target issuer, signed by this issuer.
+The new certificate shares the subject and the key of the target, and is stored
+as an additional issuer. Certificates signed by the target then chain to both
+this issuer and the original issuer of the target, which allows clients to move
+between the two while a CA is rotated.
+`
+
+const pathImportIssuersHelpSyn = `
+Import keys and CA certificates into this backend.
+`
+
+const pathImportIssuersHelpDesc = `
+This path imports the PEM-format, unencrypted secret keys and CA certificates
+of the given bundle. Each certificate becomes an issuer, which uses the stored
+key matching its public key if there is one. Keys and certificates already
+held by this backend are skipped.
+
+The first imported issuer with a key becomes the default issuer if none was
+configured yet.
+`
@@ -0,0 +1,295 @@
+package pki
+
+import (
+	"crypto/x509"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/helper/certutil"
+	"github.com/hashicorp/vault/logical"
+)
+
+func TestPki_MultipleIssuers(t *testing.T) {
+	b, storage := createBackendWithStorage(t)
+
+	request := func(op logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		t.Helper()
+		resp, err := b.HandleRequest(&logical.Request{
+			Operation: op,
+			Path:      path,
+			Storage:   storage,
+			Data:      data,
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: %s: err: %v resp: %#v", path, err, resp)
+		}
+		return resp
+	}
+
+	resp := request(logical.UpdateOperation, "root/generate/internal", map[string]interface{}{
+		"common_name": "Root A",
+		"ttl":         "40h",
+	})
+	rootA := testParseCert(t, resp.Data["certificate"].(string))
+	rootAID := resp.Data["issuer_id"].(string)
+
+	resp = request(logical.UpdateOperation, "issuers/generate/root/internal", map[string]interface{}{
+		"common_name": "Root B",
+		"ttl":         "40h",
+		"issuer_name": "root-b",
+		"key_name":    "key-b",
+	})
+	rootB := testParseCert(t, resp.Data["certificate"].(string))
+	rootBID := resp.Data["issuer_id"].(string)
+
+	// The first issuer stays the default
+	resp = request(logical.ReadOperation, "config/issuers", nil)
+	if resp.Data["default"] != rootAID {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	resp = request(logical.ListOperation, "issuers/", nil)
+	if len(resp.Data["keys"].([]string)) != 2 {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// Issuer names must be unique
+	resp, err := b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "issuers/generate/root/internal",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"common_name": "Root C",
+			"issuer_name": "root-b",
+		},
+	})
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected error: err: %v resp: %#v", err, resp)
+	}
+
+	// Roles pick their issuer
+	request(logical.UpdateOperation, "roles/a", map[string]interface{}{
+		"allow_any_name": true,
+		"ttl":            "5h",
+	})
+	request(logical.UpdateOperation, "roles/b", map[string]interface{}{
+		"allow_any_name": true,
+		"ttl":            "5h",
+		"issuer_ref":     "root-b",
+	})
+	resp = request(logical.UpdateOperation, "issue/a", map[string]interface{}{
+		"common_name": "a.example.com",
+	})
+	if err := testParseCert(t, resp.Data["certificate"].(string)).CheckSignatureFrom(rootA); err != nil {
+		t.Fatal(err)
+	}
+	resp = request(logical.UpdateOperation, "issue/b", map[string]interface{}{
+		"common_name": "b.example.com",
+	})
+	leafB := testParseCert(t, resp.Data["certificate"].(string))
+	if err := leafB.CheckSignatureFrom(rootB); err != nil {
+		t.Fatal(err)
+	}
+	leafBSerial := resp.Data["serial_number"].(string)
+
+	resp, err = b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "roles/c",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"issuer_ref": "missing",
+		},
+	})
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected error: err: %v resp: %#v", err, resp)
+	}
+
+	// Cross-signing root B by root A yields a certificate with the subject
+	// and key of root B
+	resp = request(logical.UpdateOperation, "issuer/default/cross-sign", map[string]interface{}{
+		"target_issuer": "root-b",
+		"issuer_name":   "root-b-cross",
+	})
+	cross := testParseCert(t, resp.Data["certificate"].(string))
+	if err := cross.CheckSignatureFrom(rootA); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(cross.RawSubject, rootB.RawSubject) || !reflect.DeepEqual(cross.PublicKey, rootB.PublicKey) {
+		t.Fatalf("cross-signed certificate does not match root B")
+	}
+	resp = request(logical.ReadOperation, "issuer/root-b-cross", nil)
+	if resp.Data["key_id"] == "" || len(resp.Data["ca_chain"].([]string)) != 2 {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// An intermediate signed by root B chains to both roots
+	resp = request(logical.UpdateOperation, "issuers/generate/intermediate/internal", map[string]interface{}{
+		"common_name": "Intermediate",
+		"key_name":    "int",
+	})
+	csr := resp.Data["csr"].(string)
+	resp = request(logical.UpdateOperation, "root/sign-intermediate", map[string]interface{}{
+		"csr":        csr,
+		"issuer_ref": "root-b",
+		"ttl":        "20h",
+	})
+	intCert := resp.Data["certificate"].(string)
+	resp = request(logical.UpdateOperation, "issuers/import/bundle", map[string]interface{}{
+		"pem_bundle": intCert,
+	})
+	imported := resp.Data["imported_issuers"].([]string)
+	if len(imported) != 1 || len(resp.Data["imported_keys"].([]string)) != 0 {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	resp = request(logical.ReadOperation, "cert/issuer/"+imported[0], nil)
+	chain := resp.Data["ca_chain"].([]string)
+	if len(chain) != 4 || chain[0] != strings.TrimSpace(intCert) {
+		t.Fatalf("bad chain: %#v", chain)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(rootA)
+	if _, err := testParseCert(t, chain[0]).Verify(x509.VerifyOptions{
+		Roots:         pool,
+		Intermediates: testCertPool(t, chain[1:]),
+	}); err != nil {
+		t.Fatalf("intermediate does not chain to root A: %v", err)
+	}
+
+	// Importing the same certificate again is a no-op
+	resp = request(logical.UpdateOperation, "issuers/import/bundle", map[string]interface{}{
+		"pem_bundle": intCert,
+	})
+	if len(resp.Data["imported_issuers"].([]string)) != 0 {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// Revoked certificates only appear on the CRL of their issuer
+	request(logical.UpdateOperation, "revoke", map[string]interface{}{
+		"serial_number": leafBSerial,
+	})
+	testIssuerCRLHasSerial(t, b, storage, "root-b", leafB, true)
+	testIssuerCRLHasSerial(t, b, storage, rootAID, leafB, false)
+
+	// Rotating the default issuer moves the legacy endpoints to it
+	request(logical.UpdateOperation, "config/issuers", map[string]interface{}{
+		"default": "root-b",
+	})
+	resp = request(logical.ReadOperation, "ca/pem", nil)
+	if !testParseCert(t, string(resp.Data[logical.HTTPRawBody].([]byte))).Equal(rootB) {
+		t.Fatalf("ca did not move to the new default issuer")
+	}
+	resp = request(logical.ReadOperation, "issuer/default", nil)
+	if resp.Data["issuer_id"] != rootBID || resp.Data["issuer_name"] != "root-b" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// Keys in use cannot be deleted
+	resp, err = b.HandleRequest(&logical.Request{
+		Operation: logical.DeleteOperation,
+		Path:      "key/key-b",
+		Storage:   storage,
+	})
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected error: err: %v resp: %#v", err, resp)
+	}
+
+	// Roles using the default issuer follow it
+	request(logical.DeleteOperation, "issuer/"+rootAID, nil)
+	resp = request(logical.UpdateOperation, "issue/a", map[string]interface{}{
+		"common_name": "a.example.com",
+	})
+	if err := testParseCert(t, resp.Data["certificate"].(string)).CheckSignatureFrom(rootB); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPki_LegacyCABundleMigration(t *testing.T) {
+	b, storage := createBackendWithStorage(t)
+
+	resp, err := b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "root/generate/exported",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"common_name": "Legacy Root",
+			"ttl":         "40h",
+		},
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("bad: err: %v resp: %#v", err, resp)
+	}
+	cb := &certutil.CertBundle{
+		Certificate:    resp.Data["certificate"].(string),
+		PrivateKey:     resp.Data["private_key"].(string),
+		PrivateKeyType: resp.Data["private_key_type"].(certutil.PrivateKeyType),
+	}
+
+	// Write the bundle the way older versions stored it into a fresh mount
+	b, storage = createBackendWithStorage(t)
+	entry, err := logical.StorageEntryJSON(legacyCABundleKey, cb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.Put(entry); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err = b.HandleRequest(&logical.Request{
+		Operation: logical.ListOperation,
+		Path:      "issuers/",
+		Storage:   storage,
+	})
+	if err != nil || resp == nil || len(resp.Data["keys"].([]string)) != 1 {
+		t.Fatalf("bad: err: %v resp: %#v", err, resp)
+	}
+
+	resp, err = b.HandleRequest(&logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "issuer/default",
+		Storage:   storage,
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("bad: err: %v resp: %#v", err, resp)
+	}
+	if resp.Data["certificate"] != cb.Certificate || resp.Data["key_id"] == "" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	entry, err = storage.Get(legacyCABundleKey)
+	if err != nil || entry != nil {
+		t.Fatalf("legacy bundle not removed: err: %v entry: %#v", err, entry)
+	}
+}
+
+func testCertPool(t *testing.T, pemCerts []string) *x509.CertPool {
+	pool := x509.NewCertPool()
+	for _, pemCert := range pemCerts {
+		pool.AddCert(testParseCert(t, pemCert))
+	}
+	return pool
+}
+
+func testIssuerCRLHasSerial(t *testing.T, b *backend, storage logical.Storage, issuerRef string, cert *x509.Certificate, expected bool) {
+	resp, err := b.HandleRequest(&logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "cert/issuer/" + issuerRef + "/crl/der",
+		Storage:   storage,
+	})
+	if err != nil || resp == nil {
+		t.Fatalf("bad: err: %v resp: %#v", err, resp)
+	}
+	crl, err := x509.ParseCRL(resp.Data[logical.HTTPRawBody].([]byte))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	found := false
+	for _, revoked := range crl.TBSCertList.RevokedCertificates {
+		if revoked.SerialNumber.Cmp(cert.SerialNumber) == 0 {
+			found = true
+		}
+	}
+	if found != expected {
+		t.Fatalf("serial on CRL of %s: %t, expected %t", issuerRef, found, expected)
+	}
+}
@@ -0,0 +1,193 @@
+package pki
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/vault/helper/errutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+func pathListKeys(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "keys/?$",
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ListOperation: b.pathKeyList,
+		},
+
+		HelpSynopsis:    pathListKeysHelpSyn,
+		HelpDescription: pathListKeysHelpDesc,
+	}
+}
+
+func pathKey(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "key/" + framework.GenericNameRegex("key_ref"),
+		Fields: map[string]*framework.FieldSchema{
+			"key_ref": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: `The ID or name of the key`,
+			},
+
+			"key_name": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: `The name of the key`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathKeyRead,
+			logical.UpdateOperation: b.pathKeyWrite,
+			logical.DeleteOperation: b.pathKeyDelete,
+		},
+
+		HelpSynopsis:    pathKeyHelpSyn,
+		HelpDescription: pathKeyHelpDesc,
+	}
+}
+
+func (b *backend) pathKeyList(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	if err := migrateLegacyCABundle(b, req.Storage); err != nil {
+		return nil, err
+	}
+
+	ids, err := listKeys(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	return logical.ListResponse(ids), nil
+}
+
+func (b *backend) pathKeyRead(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	if err := migrateLegacyCABundle(b, req.Storage); err != nil {
+		return nil, err
+	}
+
+	key, errResp, err := b.fetchKeyByRef(req, data.Get("key_ref").(string))
+	if errResp != nil || err != nil {
+		return errResp, err
+	}
+
+	// The key itself is never returned
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"key_id":   key.ID,
+			"key_name": key.Name,
+			"key_type": key.PrivateKeyType,
+		},
+	}, nil
+}
+
+func (b *backend) pathKeyWrite(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	if err := migrateLegacyCABundle(b, req.Storage); err != nil {
+		return nil, err
+	}
+
+	b.issuersLock.Lock()
+	defer b.issuersLock.Unlock()
+
+	key, errResp, err := b.fetchKeyByRef(req, data.Get("key_ref").(string))
+	if errResp != nil || err != nil {
+		return errResp, err
+	}
+
+	if nameRaw, ok := data.GetOk("key_name"); ok {
+		name := nameRaw.(string)
+		if err := checkKeyName(req.Storage, name, key.ID); err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+		key.Name = name
+	}
+
+	if err := writeKey(req.Storage, key); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+func (b *backend) pathKeyDelete(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	if err := migrateLegacyCABundle(b, req.Storage); err != nil {
+		return nil, err
+	}
+
+	b.issuersLock.Lock()
+	defer b.issuersLock.Unlock()
+
+	key, errResp, err := b.fetchKeyByRef(req, data.Get("key_ref").(string))
+	if errResp != nil || err != nil {
+		// Deleting a missing key is not an error
+		if errResp != nil {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	issuerIDs, err := listIssuers(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	for _, issuerID := range issuerIDs {
+		issuer, err := fetchIssuer(req.Storage, issuerID)
+		if err != nil {
+			return nil, err
+		}
+		if issuer != nil && issuer.KeyID == key.ID {
+			return logical.ErrorResponse(fmt.Sprintf("key is in use by issuer %s", issuer.ID)), nil
+		}
+	}
+
+	if err := req.Storage.Delete("keys/" + key.ID); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+// fetchKeyByRef returns the key referenced by ID or name, or an error
+// response if there is none
+func (b *backend) fetchKeyByRef(req *logical.Request, ref string) (*keyEntry, *logical.Response, error) {
+	keyID, err := resolveKeyRef(req.Storage, ref)
+	if err != nil {
+		switch err.(type) {
+		case errutil.UserError:
+			return nil, logical.ErrorResponse(err.Error()), nil
+		default:
+			return nil, nil, err
+		}
+	}
+
+	key, err := fetchKey(req.Storage, keyID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if key == nil {
+		return nil, logical.ErrorResponse(fmt.Sprintf("unknown key %q", ref)), nil
+	}
+
+	return key, nil, nil
+}
+
+const pathListKeysHelpSyn = `
+List the IDs of the keys of this backend.
+`
+
+const pathListKeysHelpDesc = `
+This path lists the IDs of the private keys held by this backend, which are
+used by its issuers.
+`
+
+const pathKeyHelpSyn = `
+Read, rename or delete a key of this backend.
+`
+
+const pathKeyHelpDesc = `
+Keys may be referenced by ID or by name. For security reasons the private key
+itself cannot be read. A key cannot be deleted while an issuer uses it.
+`
@@ -0,0 +1,407 @@
+package pki
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	_ "crypto/sha1"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/hashicorp/vault/helper/certutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+// OCSP response statuses, as defined in RFC 6960 section 4.2.1
+const (
+	ocspSuccessful       asn1.Enumerated = 0
+	ocspMalformedRequest asn1.Enumerated = 1
+	ocspInternalError    asn1.Enumerated = 2
+	ocspUnauthorized     asn1.Enumerated = 6
+)
+
+const ocspResponseContentType = "application/ocsp-response"
+
+var (
+	oidOCSPBasicResponse = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 48, 1, 1}
+	oidOCSPNonce         = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 48, 1, 2}
+
+	oidSignatureSHA256WithRSA   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 11}
+	oidSignatureECDSAWithSHA256 = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}
+
+	// ocspHashes are the hash algorithms accepted for the issuer name and
+	// key hashes of a request
+	ocspHashes = map[string]crypto.Hash{
+		"1.3.14.3.2.26":          crypto.SHA1,
+		"2.16.840.1.101.3.4.2.1": crypto.SHA256,
+		"2.16.840.1.101.3.4.2.2": crypto.SHA384,
+		"2.16.840.1.101.3.4.2.3": crypto.SHA512,
+	}
+)
+
+// The following types are the ASN.1 structures of RFC 6960
+
+type ocspCertID struct {
+	HashAlgorithm  pkix.AlgorithmIdentifier
+	IssuerNameHash []byte
+	IssuerKeyHash  []byte
+	SerialNumber   *big.Int
+}
+
+type ocspSingleRequest struct {
+	CertID     ocspCertID
+	Extensions []pkix.Extension `asn1:"explicit,tag:0,optional"`
+}
+
+type ocspTBSRequest struct {
+	Version       int           `asn1:"explicit,tag:0,default:0,optional"`
+	RequestorName asn1.RawValue `asn1:"explicit,tag:1,optional"`
+	RequestList   []ocspSingleRequest
+	Extensions    []pkix.Extension `asn1:"explicit,tag:2,optional"`
+}
+
+type ocspRequest struct {
+	TBSRequest        ocspTBSRequest
+	OptionalSignature asn1.RawValue `asn1:"explicit,tag:0,optional"`
+}
+
+type ocspRevokedInfo struct {
+	RevocationTime time.Time `asn1:"generalized"`
+}
+
+type ocspSingleResponse struct {
+	CertID     ocspCertID
+	Good       asn1.Flag       `asn1:"tag:0,optional"`
+	Revoked    ocspRevokedInfo `asn1:"tag:1,optional"`
+	Unknown    asn1.Flag       `asn1:"tag:2,optional"`
+	ThisUpdate time.Time       `asn1:"generalized"`
+}
+
+type ocspResponseData struct {
+	Version     int `asn1:"explicit,tag:0,default:0,optional"`
+	ResponderID asn1.RawValue
+	ProducedAt  time.Time `asn1:"generalized"`
+	Responses   []ocspSingleResponse
+	Extensions  []pkix.Extension `asn1:"explicit,tag:1,optional"`
+}
+
+type ocspBasicResponse struct {
+	TBSResponseData    asn1.RawValue
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Signature          asn1.BitString
+}
+
+type ocspResponseBytes struct {
+	ResponseType asn1.ObjectIdentifier
+	Response     []byte
+}
+
+type ocspResponse struct {
+	Status   asn1.Enumerated
+	Response ocspResponseBytes `asn1:"explicit,tag:0,optional"`
+}
+
+func pathOCSP(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "ocsp" + framework.OptionalParamRegex("req"),
+
+		Fields: map[string]*framework.FieldSchema{
+			"req": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `The base64 encoded DER OCSP request, when
+using GET requests`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathOCSPRead,
+			logical.UpdateOperation: b.pathOCSPRead,
+		},
+
+		HelpSynopsis:    pathOCSPHelpSyn,
+		HelpDescription: pathOCSPHelpDesc,
+	}
+}
+
+// pathOCSPRead answers an OCSP request. Failures are reported through the
+// status of the OCSP response rather than as errors, since OCSP clients do not
+// understand Vault's error responses.
+func (b *backend) pathOCSPRead(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	var der []byte
+	switch req.Operation {
+	case logical.ReadOperation:
+		raw, err := base64.StdEncoding.DecodeString(data.Get("req").(string))
+		if err != nil {
+			return ocspRawResponse(ocspErrorResponse(ocspMalformedRequest)), nil
+		}
+		der = raw
+	default:
+		raw, ok := req.Data[logical.HTTPRawBody].([]byte)
+		if !ok {
+			return ocspRawResponse(ocspErrorResponse(ocspMalformedRequest)), nil
+		}
+		der = raw
+	}
+
+	var ocspReq ocspRequest
+	rest, err := asn1.Unmarshal(der, &ocspReq)
+	if err != nil || len(rest) != 0 || len(ocspReq.TBSRequest.RequestList) == 0 {
+		return ocspRawResponse(ocspErrorResponse(ocspMalformedRequest)), nil
+	}
+
+	// All certificates of a request must have been issued by the same issuer,
+	// which signs the response
+	var caInfo *caInfoBundle
+	now := time.Now().UTC().Truncate(time.Second)
+	responses := make([]ocspSingleResponse, 0, len(ocspReq.TBSRequest.RequestList))
+	for _, single := range ocspReq.TBSRequest.RequestList {
+		certID := single.CertID
+		hash, ok := ocspHashes[certID.HashAlgorithm.Algorithm.String()]
+		if !ok || !hash.Available() || certID.SerialNumber == nil {
+			return ocspRawResponse(ocspErrorResponse(ocspMalformedRequest)), nil
+		}
+
+		issuer, err := ocspFindIssuer(b, req, certID, hash)
+		if err != nil {
+			b.Logger().Error("pki: failed to find issuer for OCSP response", "error", err)
+			return ocspRawResponse(ocspErrorResponse(ocspInternalError)), nil
+		}
+		// Without a matching issuer this mount is not authoritative for the
+		// certificate
+		if issuer == nil || (caInfo != nil && caInfo.IssuerID != issuer.IssuerID) {
+			return ocspRawResponse(ocspErrorResponse(ocspUnauthorized)), nil
+		}
+		caInfo = issuer
+
+		status, err := ocspCertStatus(req, certID)
+		if err != nil {
+			b.Logger().Error("pki: failed to look up certificate status", "error", err)
+			return ocspRawResponse(ocspErrorResponse(ocspInternalError)), nil
+		}
+		status.ThisUpdate = now
+		responses = append(responses, *status)
+	}
+
+	responseData := ocspResponseData{
+		ProducedAt: now,
+		Responses:  responses,
+	}
+
+	// Echo the nonce back to protect against replays
+	for _, ext := range ocspReq.TBSRequest.Extensions {
+		if ext.Id.Equal(oidOCSPNonce) {
+			responseData.Extensions = append(responseData.Extensions, ext)
+		}
+	}
+
+	resp, err := ocspSignResponse(caInfo, responseData)
+	if err != nil {
+		b.Logger().Error("pki: failed to sign OCSP response", "error", err)
+		return ocspRawResponse(ocspErrorResponse(ocspInternalError)), nil
+	}
+	return ocspRawResponse(resp), nil
+}
+
+// ocspFindIssuer returns the issuer of the backend holding a key whose name
+// and key hashes match the given certificate ID, or nil if there is none
+func ocspFindIssuer(b *backend, req *logical.Request, certID ocspCertID, hash crypto.Hash) (*caInfoBundle, error) {
+	if err := migrateLegacyCABundle(b, req.Storage); err != nil {
+		return nil, err
+	}
+
+	issuerIDs, err := listIssuers(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	for _, issuerID := range issuerIDs {
+		issuer, err := fetchIssuer(req.Storage, issuerID)
+		if err != nil {
+			return nil, err
+		}
+		if issuer == nil || issuer.KeyID == "" {
+			continue
+		}
+
+		caInfo, err := fetchCAInfo(b, req, issuerID)
+		if err != nil {
+			return nil, err
+		}
+		nameHash, keyHash, err := ocspIssuerHashes(caInfo, hash)
+		if err != nil {
+			return nil, err
+		}
+		if bytes.Equal(nameHash, certID.IssuerNameHash) && bytes.Equal(keyHash, certID.IssuerKeyHash) {
+			return caInfo, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// ocspCertStatus looks up the status of a certificate in the same storage the
+// certificate issuance and revocation paths write to
+func ocspCertStatus(req *logical.Request, certID ocspCertID) (*ocspSingleResponse, error) {
+	ret := &ocspSingleResponse{
+		CertID: certID,
+	}
+	serial := certutil.GetHexFormatted(certID.SerialNumber.Bytes(), ":")
+
+	revokedEntry, err := fetchCertBySerial(req, "revoked/", serial)
+	if err != nil {
+		return nil, err
+	}
+	if revokedEntry != nil {
+		var revInfo revocationInfo
+		if err := revokedEntry.DecodeJSON(&revInfo); err != nil {
+			return nil, fmt.Errorf("error decoding revocation entry for serial %s: %s", serial, err)
+		}
+		ret.Revoked.RevocationTime = revInfo.RevocationTimeUTC
+		if revInfo.RevocationTimeUTC.IsZero() {
+			ret.Revoked.RevocationTime = time.Unix(revInfo.RevocationTime, 0).UTC()
+		}
+		return ret, nil
+	}
+
+	certEntry, err := fetchCertBySerial(req, "certs/", serial)
+	if err != nil {
+		return nil, err
+	}
+	if certEntry != nil {
+		ret.Good = true
+	} else {
+		ret.Unknown = true
+	}
+	return ret, nil
+}
+
+// ocspIssuerHashes returns the hashes of the name and public key of the CA,
+// which identify it as the issuer in OCSP requests
+func ocspIssuerHashes(caInfo *caInfoBundle, hash crypto.Hash) ([]byte, []byte, error) {
+	var spki struct {
+		Algorithm pkix.AlgorithmIdentifier
+		PublicKey asn1.BitString
+	}
+	if _, err := asn1.Unmarshal(caInfo.Certificate.RawSubjectPublicKeyInfo, &spki); err != nil {
+		return nil, nil, err
+	}
+
+	h := hash.New()
+	h.Write(caInfo.Certificate.RawSubject)
+	nameHash := h.Sum(nil)
+
+	h = hash.New()
+	h.Write(spki.PublicKey.RightAlign())
+	keyHash := h.Sum(nil)
+
+	return nameHash, keyHash, nil
+}
+
+// ocspSignResponse creates a basic OCSP response with the given data, signed
+// with the key of the CA
+func ocspSignResponse(caInfo *caInfoBundle, responseData ocspResponseData) ([]byte, error) {
+	_, keyHash, err := ocspIssuerHashes(caInfo, crypto.SHA1)
+	if err != nil {
+		return nil, err
+	}
+	keyHashBytes, err := asn1.Marshal(keyHash)
+	if err != nil {
+		return nil, err
+	}
+	// The responder is identified by the hash of its key: byKey [2]
+	responseData.ResponderID = asn1.RawValue{
+		Class:      asn1.ClassContextSpecific,
+		Tag:        2,
+		IsCompound: true,
+		Bytes:      keyHashBytes,
+	}
+
+	tbs, err := asn1.Marshal(responseData)
+	if err != nil {
+		return nil, err
+	}
+
+	var sigAlg pkix.AlgorithmIdentifier
+	switch caInfo.PrivateKeyType {
+	case certutil.RSAPrivateKey:
+		sigAlg.Algorithm = oidSignatureSHA256WithRSA
+		sigAlg.Parameters = asn1.NullRawValue
+	case certutil.ECPrivateKey:
+		sigAlg.Algorithm = oidSignatureECDSAWithSHA256
+	default:
+		return nil, fmt.Errorf("unsupported CA key type %q", caInfo.PrivateKeyType)
+	}
+
+	h := crypto.SHA256.New()
+	h.Write(tbs)
+	signature, err := caInfo.PrivateKey.Sign(rand.Reader, h.Sum(nil), crypto.SHA256)
+	if err != nil {
+		return nil, err
+	}
+
+	basic, err := asn1.Marshal(ocspBasicResponse{
+		TBSResponseData:    asn1.RawValue{FullBytes: tbs},
+		SignatureAlgorithm: sigAlg,
+		Signature: asn1.BitString{
+			Bytes:     signature,
+			BitLength: 8 * len(signature),
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return asn1.Marshal(ocspResponse{
+		Status: ocspSuccessful,
+		Response: ocspResponseBytes{
+			ResponseType: oidOCSPBasicResponse,
+			Response:     basic,
+		},
+	})
+}
+
+// ocspErrorResponse returns an unsigned OCSP response carrying only the given
+// error status
+func ocspErrorResponse(status asn1.Enumerated) []byte {
+	resp, err := asn1.Marshal(ocspResponse{
+		Status: status,
+	})
+	if err != nil {
+		// The encoding of a single enumerated value cannot fail
+		panic(err)
+	}
+	return resp
+}
+
+func ocspRawResponse(body []byte) *logical.Response {
+	return &logical.Response{
+		Data: map[string]interface{}{
+			logical.HTTPContentType: ocspResponseContentType,
+			logical.HTTPRawBody:     body,
+			logical.HTTPStatusCode:  200,
+		},
+	}
+}
+
+const pathOCSPHelpSyn = `
+Query the revocation status of certificates issued by this CA using OCSP.
+`
+
+const pathOCSPHelpDesc = `
+This endpoint is an OCSP responder as defined in RFC 6960. Requests can be
+sent with GET, with the base64 encoded DER request appended to the path, or
+with POST, with the DER request as the body and a content type of
+"application/ocsp-request". Responses are signed with the CA key and report
+certificates as good, revoked or unknown based on the certificates issued and
+revoked by this backend.
+
+The URL of this endpoint can be set in the "ocsp_servers" field of the
+"config/urls" endpoint, which encodes it into issued certificates.
+`
@@ -0,0 +1,251 @@
+package pki
+
+import (
+	"crypto"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/vault/helper/certutil"
+	"github.com/hashicorp/vault/logical"
+)
+
+func TestPki_OCSP(t *testing.T) {
+	b, storage := createBackendWithStorage(t)
+
+	resp, err := b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config/urls",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"ocsp_servers": "http://127.0.0.1:8200/v1/pki/ocsp",
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: err: %v resp: %#v", err, resp)
+	}
+
+	resp, err = b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "root/generate/internal",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"common_name": "myvault.com",
+			"ttl":         "40h",
+		},
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("bad: err: %v resp: %#v", err, resp)
+	}
+	caCert := testParseCert(t, resp.Data["certificate"].(string))
+
+	resp, err = b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "roles/test",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"allowed_domains":  "myvault.com",
+			"allow_subdomains": true,
+			"ttl":              "5h",
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: err: %v resp: %#v", err, resp)
+	}
+
+	resp, err = b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "issue/test",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"common_name": "foo.myvault.com",
+		},
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("bad: err: %v resp: %#v", err, resp)
+	}
+	cert := testParseCert(t, resp.Data["certificate"].(string))
+	serial := resp.Data["serial_number"].(string)
+
+	// The responder URL is embedded into issued certificates
+	if !reflect.DeepEqual(cert.OCSPServer, []string{"http://127.0.0.1:8200/v1/pki/ocsp"}) {
+		t.Fatalf("bad: %#v", cert.OCSPServer)
+	}
+
+	single := testOCSPQuery(t, b, storage, caCert, cert.SerialNumber, logical.UpdateOperation)
+	if !single.Good {
+		t.Fatalf("expected good status: %#v", single)
+	}
+
+	resp, err = b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "revoke",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"serial_number": serial,
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: err: %v resp: %#v", err, resp)
+	}
+
+	single = testOCSPQuery(t, b, storage, caCert, cert.SerialNumber, logical.ReadOperation)
+	if bool(single.Good) || bool(single.Unknown) || single.Revoked.RevocationTime.IsZero() {
+		t.Fatalf("expected revoked status: %#v", single)
+	}
+
+	single = testOCSPQuery(t, b, storage, caCert, big.NewInt(1234), logical.ReadOperation)
+	if !single.Unknown {
+		t.Fatalf("expected unknown status: %#v", single)
+	}
+
+	// Requests for certificates of other issuers are refused
+	der := testOCSPRequest(t, cert, cert.SerialNumber)
+	resp, err = b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "ocsp",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			logical.HTTPRawBody: der,
+		},
+	})
+	if err != nil || resp == nil {
+		t.Fatalf("bad: err: %v resp: %#v", err, resp)
+	}
+	var ocspResp ocspResponse
+	if _, err := asn1.Unmarshal(resp.Data[logical.HTTPRawBody].([]byte), &ocspResp); err != nil {
+		t.Fatal(err)
+	}
+	if ocspResp.Status != ocspUnauthorized {
+		t.Fatalf("expected unauthorized status, got %d", ocspResp.Status)
+	}
+
+	// Garbage is reported as a malformed request
+	resp, err = b.HandleRequest(&logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "ocsp/Zm9vYmFy",
+		Storage:   storage,
+	})
+	if err != nil || resp == nil {
+		t.Fatalf("bad: err: %v resp: %#v", err, resp)
+	}
+	if _, err := asn1.Unmarshal(resp.Data[logical.HTTPRawBody].([]byte), &ocspResp); err != nil {
+		t.Fatal(err)
+	}
+	if ocspResp.Status != ocspMalformedRequest {
+		t.Fatalf("expected malformed request status, got %d", ocspResp.Status)
+	}
+}
+
+func testParseCert(t *testing.T, pemCert string) *x509.Certificate {
+	block, _ := pem.Decode([]byte(pemCert))
+	if block == nil {
+		t.Fatalf("failed to decode certificate PEM")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+// testOCSPRequest builds a DER OCSP request for the given serial, identifying
+// the issuer by SHA1 hashes as most clients do
+func testOCSPRequest(t *testing.T, issuer *x509.Certificate, serial *big.Int) []byte {
+	nameHash, keyHash, err := ocspIssuerHashes(&caInfoBundle{
+		ParsedCertBundle: certutil.ParsedCertBundle{
+			Certificate: issuer,
+		},
+	}, crypto.SHA1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	der, err := asn1.Marshal(ocspRequest{
+		TBSRequest: ocspTBSRequest{
+			RequestList: []ocspSingleRequest{
+				{
+					CertID: ocspCertID{
+						HashAlgorithm: pkix.AlgorithmIdentifier{
+							Algorithm:  asn1.ObjectIdentifier{1, 3, 14, 3, 2, 26},
+							Parameters: asn1.NullRawValue,
+						},
+						IssuerNameHash: nameHash,
+						IssuerKeyHash:  keyHash,
+						SerialNumber:   serial,
+					},
+				},
+			},
+			Extensions: []pkix.Extension{
+				{
+					Id:    oidOCSPNonce,
+					Value: []byte{0x04, 0x02, 0xca, 0xfe},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der
+}
+
+// testOCSPQuery sends an OCSP request for the given serial, verifies the
+// signature and nonce of the response and returns its single response
+func testOCSPQuery(t *testing.T, b *backend, storage logical.Storage, caCert *x509.Certificate, serial *big.Int, op logical.Operation) ocspSingleResponse {
+	der := testOCSPRequest(t, caCert, serial)
+
+	req := &logical.Request{
+		Operation: op,
+		Storage:   storage,
+	}
+	switch op {
+	case logical.ReadOperation:
+		req.Path = "ocsp/" + base64.StdEncoding.EncodeToString(der)
+	default:
+		req.Path = "ocsp"
+		req.Data = map[string]interface{}{
+			logical.HTTPRawBody: der,
+		}
+	}
+	resp, err := b.HandleRequest(req)
+	if err != nil || resp == nil {
+		t.Fatalf("bad: err: %v resp: %#v", err, resp)
+	}
+	if resp.Data[logical.HTTPContentType] != ocspResponseContentType {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	var ocspResp ocspResponse
+	if _, err := asn1.Unmarshal(resp.Data[logical.HTTPRawBody].([]byte), &ocspResp); err != nil {
+		t.Fatal(err)
+	}
+	if ocspResp.Status != ocspSuccessful || !ocspResp.Response.ResponseType.Equal(oidOCSPBasicResponse) {
+		t.Fatalf("bad: %#v", ocspResp)
+	}
+
+	var basic ocspBasicResponse
+	if _, err := asn1.Unmarshal(ocspResp.Response.Response, &basic); err != nil {
+		t.Fatal(err)
+	}
+	if err := caCert.CheckSignature(x509.SHA256WithRSA, basic.TBSResponseData.FullBytes, basic.Signature.RightAlign()); err != nil {
+		t.Fatalf("bad signature: %v", err)
+	}
+
+	var data ocspResponseData
+	if _, err := asn1.Unmarshal(basic.TBSResponseData.FullBytes, &data); err != nil {
+		t.Fatal(err)
+	}
+	if len(data.Extensions) != 1 || !data.Extensions[0].Id.Equal(oidOCSPNonce) {
+		t.Fatalf("nonce not echoed: %#v", data.Extensions)
+	}
+	if len(data.Responses) != 1 || data.Responses[0].CertID.SerialNumber.Cmp(serial) != 0 {
+		t.Fatalf("bad: %#v", data.Responses)
+	}
+	return data.Responses[0]
+}
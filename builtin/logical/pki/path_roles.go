@@ -7,7 +7,9 @@ import (
 	"time"
 
 	"github.com/fatih/structs"
+	"github.com/hashicorp/vault/helper/errutil"
 	"github.com/hashicorp/vault/helper/parseutil"
+	"github.com/hashicorp/vault/helper/strutil"
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/framework"
 )
@@ -199,6 +201,85 @@ this value in certificates issued by this role.`,
 this value in certificates issued by this role.`,
 			},
 
+			"country": &framework.FieldSchema{
+				Type:    framework.TypeString,
+				Default: "",
+				Description: `If set, the C (Country) will be set to
+this value in certificates issued by this role.`,
+			},
+
+			"locality": &framework.FieldSchema{
+				Type:    framework.TypeString,
+				Default: "",
+				Description: `If set, the L (Locality) will be set to
+this value in certificates issued by this role.`,
+			},
+
+			"province": &framework.FieldSchema{
+				Type:    framework.TypeString,
+				Default: "",
+				Description: `If set, the ST (Province) will be set to
+this value in certificates issued by this role.`,
+			},
+
+			"street_address": &framework.FieldSchema{
+				Type:    framework.TypeString,
+				Default: "",
+				Description: `If set, the Street Address will be set to
+this value in certificates issued by this role.`,
+			},
+
+			"postal_code": &framework.FieldSchema{
+				Type:    framework.TypeString,
+				Default: "",
+				Description: `If set, the Postal Code will be set to
+this value in certificates issued by this role.`,
+			},
+
+			"allowed_uri_sans": &framework.FieldSchema{
+				Type:    framework.TypeString,
+				Default: "",
+				Description: `If set, clients can request URI Subject
+Alternative Names matching one of these
+comma-separated values, which may contain globs,
+e.g. "spiffe://example.org/*". By default no URI
+SANs are allowed.`,
+			},
+
+			"allowed_other_sans": &framework.FieldSchema{
+				Type:    framework.TypeString,
+				Default: "",
+				Description: `If set, clients can request otherName Subject
+Alternative Names matching one of these
+comma-separated values, of the form
+"<oid>;UTF8:<value>"; the value may contain globs.
+Set to "*" to allow any. By default no other SANs
+are allowed.`,
+			},
+
+			"ext_key_usage_oids": &framework.FieldSchema{
+				Type:    framework.TypeString,
+				Default: "",
+				Description: `A comma-separated set of extended key usage
+OIDs added to certificates issued by this role, in
+addition to those of the usage flags.`,
+			},
+
+			"policy_identifiers": &framework.FieldSchema{
+				Type:    framework.TypeString,
+				Default: "",
+				Description: `A comma-separated set of certificate policy
+OIDs set in certificates issued by this role.`,
+			},
+
+			"not_before_duration": &framework.FieldSchema{
+				Type:    framework.TypeDurationSecond,
+				Default: 30,
+				Description: `The duration by which to backdate the NotBefore
+time of certificates issued by this role, to allow
+for clock skew. Defaults to 30 seconds.`,
+			},
+
 			"generate_lease": &framework.FieldSchema{
 				Type:    framework.TypeBool,
 				Default: false,
@@ -212,6 +293,14 @@ to the CRL.  When large number of certificates are generated with long
 lifetimes, it is recommended that lease generation be disabled, as large amount of
 leases adversely affect the startup time of Vault.`,
 			},
+			"issuer_ref": &framework.FieldSchema{
+				Type:    framework.TypeString,
+				Default: defaultIssuerRef,
+				Description: `The ID or name of the issuer that signs
+certificates issued by this role. Defaults to
+"default", the default issuer of the backend.`,
+			},
+
 			"no_store": &framework.FieldSchema{
 				Type:    framework.TypeBool,
 				Default: false,
@@ -299,6 +388,19 @@ func (b *backend) getRole(s logical.Storage, n string) (*roleEntry, error) {
 		modified = true
 	}
 
+	// Keep the previously fixed backdating of certificates
+	if result.NotBeforeDuration == "" {
+		result.NotBeforeDuration = defaultNotBeforeDuration.String()
+		modified = true
+	}
+
+	// Roles written before the backend could hold several issuers use the
+	// default issuer
+	if result.IssuerRef == "" {
+		result.IssuerRef = defaultIssuerRef
+		modified = true
+	}
+
 	if modified {
 		jsonEntry, err := logical.StorageEntryJSON("role/"+n, &result)
 		if err != nil {
@@ -403,8 +505,19 @@ func (b *backend) pathRoleCreate(
 		KeyUsage:            data.Get("key_usage").(string),
 		OU:                  data.Get("ou").(string),
 		Organization:        data.Get("organization").(string),
+		Country:             data.Get("country").(string),
+		Locality:            data.Get("locality").(string),
+		Province:            data.Get("province").(string),
+		StreetAddress:       data.Get("street_address").(string),
+		PostalCode:          data.Get("postal_code").(string),
+		AllowedURISANs:      data.Get("allowed_uri_sans").(string),
+		AllowedOtherSANs:    data.Get("allowed_other_sans").(string),
+		ExtKeyUsageOIDs:     data.Get("ext_key_usage_oids").(string),
+		PolicyIdentifiers:   data.Get("policy_identifiers").(string),
+		NotBeforeDuration:   (time.Duration(data.Get("not_before_duration").(int)) * time.Second).String(),
 		GenerateLease:       new(bool),
 		NoStore:             data.Get("no_store").(bool),
+		IssuerRef:           data.Get("issuer_ref").(string),
 	}
 
 	// no_store implies generate_lease := false
@@ -461,6 +574,43 @@ func (b *backend) pathRoleCreate(
 		return errResp, nil
 	}
 
+	if data.Get("not_before_duration").(int) < 0 {
+		return logical.ErrorResponse(`"not_before_duration" must not be negative`), nil
+	}
+	if _, err := parseOIDs(entry.ExtKeyUsageOIDs); err != nil {
+		return logical.ErrorResponse(fmt.Sprintf(
+			"Invalid ext_key_usage_oids: %s", err)), nil
+	}
+	if _, err := parseOIDs(entry.PolicyIdentifiers); err != nil {
+		return logical.ErrorResponse(fmt.Sprintf(
+			"Invalid policy_identifiers: %s", err)), nil
+	}
+	for _, v := range strutil.ParseDedupAndSortStrings(entry.AllowedOtherSANs, ",") {
+		if v == "*" {
+			continue
+		}
+		if _, err := parseOtherSAN(v); err != nil {
+			return logical.ErrorResponse(fmt.Sprintf(
+				"Invalid allowed_other_sans: %s", err)), nil
+		}
+	}
+
+	// The default issuer is resolved when certificates are issued, as it may
+	// not be configured yet
+	if entry.IssuerRef != defaultIssuerRef {
+		if err := migrateLegacyCABundle(b, req.Storage); err != nil {
+			return nil, err
+		}
+		if _, err := resolveIssuerRef(req.Storage, entry.IssuerRef); err != nil {
+			switch err.(type) {
+			case errutil.UserError:
+				return logical.ErrorResponse(err.Error()), nil
+			default:
+				return nil, err
+			}
+		}
+	}
+
 	// Store it
 	jsonEntry, err := logical.StorageEntryJSON("role/"+name, entry)
 	if err != nil {
@@ -530,8 +680,19 @@ type roleEntry struct {
 	KeyUsage              string `json:"key_usage" structs:"key_usage" mapstructure:"key_usage"`
 	OU                    string `json:"ou" structs:"ou" mapstructure:"ou"`
 	Organization          string `json:"organization" structs:"organization" mapstructure:"organization"`
+	Country               string `json:"country" structs:"country" mapstructure:"country"`
+	Locality              string `json:"locality" structs:"locality" mapstructure:"locality"`
+	Province              string `json:"province" structs:"province" mapstructure:"province"`
+	StreetAddress         string `json:"street_address" structs:"street_address" mapstructure:"street_address"`
+	PostalCode            string `json:"postal_code" structs:"postal_code" mapstructure:"postal_code"`
+	AllowedURISANs        string `json:"allowed_uri_sans" structs:"allowed_uri_sans" mapstructure:"allowed_uri_sans"`
+	AllowedOtherSANs      string `json:"allowed_other_sans" structs:"allowed_other_sans" mapstructure:"allowed_other_sans"`
+	ExtKeyUsageOIDs       string `json:"ext_key_usage_oids" structs:"ext_key_usage_oids" mapstructure:"ext_key_usage_oids"`
+	PolicyIdentifiers     string `json:"policy_identifiers" structs:"policy_identifiers" mapstructure:"policy_identifiers"`
+	NotBeforeDuration     string `json:"not_before_duration" structs:"not_before_duration" mapstructure:"not_before_duration"`
 	GenerateLease         *bool  `json:"generate_lease,omitempty" structs:"generate_lease,omitempty"`
 	NoStore               bool   `json:"no_store" structs:"no_store" mapstructure:"no_store"`
+	IssuerRef             string `json:"issuer_ref" structs:"issuer_ref" mapstructure:"issuer_ref"`
 
 	// Used internally for signing intermediates
 	AllowExpirationPastCA bool
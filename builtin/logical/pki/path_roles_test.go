@@ -1,7 +1,11 @@
 package pki
 
 import (
+	"crypto/x509"
+	"encoding/asn1"
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/vault/logical"
 	"github.com/mitchellh/mapstructure"
@@ -308,3 +312,136 @@ func TestPki_CertsLease(t *testing.T) {
 		t.Fatalf("expected a response that contains a secret")
 	}
 }
+
+func TestPki_RoleCertificateControls(t *testing.T) {
+	b, storage := createBackendWithStorage(t)
+
+	request := func(path string, data map[string]interface{}) (*logical.Response, error) {
+		return b.HandleRequest(&logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      path,
+			Storage:   storage,
+			Data:      data,
+		})
+	}
+
+	resp, err := request("root/generate/internal", map[string]interface{}{
+		"common_name": "myvault.com",
+		"ttl":         "5h",
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: err: %v resp: %#v", err, resp)
+	}
+
+	upnOID := asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 311, 20, 2, 3}
+	resp, err = request("roles/testrole", map[string]interface{}{
+		"allowed_domains":     "myvault.com",
+		"allow_subdomains":    true,
+		"ttl":                 "1h",
+		"country":             "US",
+		"locality":            "San Francisco",
+		"province":            "CA",
+		"street_address":      "1 Main St",
+		"postal_code":         "94105",
+		"allowed_uri_sans":    "spiffe://myvault.com/*",
+		"allowed_other_sans":  "1.3.6.1.4.1.311.20.2.3;UTF8:*@myvault.com",
+		"ext_key_usage_oids":  "1.3.6.1.4.1.311.20.2.2",
+		"policy_identifiers":  "1.3.6.1.4.1.44947.1.1.1",
+		"not_before_duration": "1h",
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: err: %v resp: %#v", err, resp)
+	}
+
+	resp, err = request("issue/testrole", map[string]interface{}{
+		"common_name": "cert.myvault.com",
+		"uri_sans":    "spiffe://myvault.com/service",
+		"other_sans":  "1.3.6.1.4.1.311.20.2.3;UTF8:user@myvault.com",
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: err: %v resp: %#v", err, resp)
+	}
+	cert := testParseCert(t, resp.Data["certificate"].(string))
+
+	if !reflect.DeepEqual(cert.Subject.Country, []string{"US"}) ||
+		!reflect.DeepEqual(cert.Subject.Locality, []string{"San Francisco"}) ||
+		!reflect.DeepEqual(cert.Subject.Province, []string{"CA"}) ||
+		!reflect.DeepEqual(cert.Subject.StreetAddress, []string{"1 Main St"}) ||
+		!reflect.DeepEqual(cert.Subject.PostalCode, []string{"94105"}) {
+		t.Fatalf("bad subject: %#v", cert.Subject)
+	}
+	if len(cert.URIs) != 1 || cert.URIs[0].String() != "spiffe://myvault.com/service" {
+		t.Fatalf("bad URIs: %v", cert.URIs)
+	}
+	if !reflect.DeepEqual(cert.DNSNames, []string{"cert.myvault.com"}) {
+		t.Fatalf("bad DNS names: %v", cert.DNSNames)
+	}
+	if len(cert.UnknownExtKeyUsage) != 1 || !cert.UnknownExtKeyUsage[0].Equal(asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 311, 20, 2, 2}) {
+		t.Fatalf("bad extended key usages: %v", cert.UnknownExtKeyUsage)
+	}
+	if len(cert.PolicyIdentifiers) != 1 || !cert.PolicyIdentifiers[0].Equal(asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 44947, 1, 1, 1}) {
+		t.Fatalf("bad policy identifiers: %v", cert.PolicyIdentifiers)
+	}
+	if skew := time.Now().Sub(cert.NotBefore); skew < 59*time.Minute || skew > 61*time.Minute {
+		t.Fatalf("bad not before: %s", cert.NotBefore)
+	}
+	if upn := testOtherSAN(t, cert, upnOID); upn != "user@myvault.com" {
+		t.Fatalf("bad UPN: %q", upn)
+	}
+
+	// Requests outside of the allowed values are refused
+	for _, data := range []map[string]interface{}{
+		{"uri_sans": "spiffe://example.com/service"},
+		{"other_sans": "1.3.6.1.4.1.311.20.2.3;UTF8:user@example.com"},
+		{"other_sans": "1.2.3.4;UTF8:user@myvault.com"},
+	} {
+		data["common_name"] = "cert.myvault.com"
+		resp, err = request("issue/testrole", data)
+		if err != nil || resp == nil || !resp.IsError() {
+			t.Fatalf("expected error for %v: err: %v resp: %#v", data, err, resp)
+		}
+	}
+
+	// Invalid OIDs are refused when writing the role
+	resp, err = request("roles/badrole", map[string]interface{}{
+		"policy_identifiers": "not.an.oid",
+	})
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected error: err: %v resp: %#v", err, resp)
+	}
+}
+
+// testOtherSAN returns the value of the UTF-8 otherName SAN of the
+// certificate with the given OID
+func testOtherSAN(t *testing.T, cert *x509.Certificate, oid asn1.ObjectIdentifier) string {
+	for _, ext := range cert.Extensions {
+		if !ext.Id.Equal(oidExtensionSubjectAltName) {
+			continue
+		}
+		var names []asn1.RawValue
+		if _, err := asn1.Unmarshal(ext.Value, &names); err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range names {
+			if name.Class != asn1.ClassContextSpecific || name.Tag != 0 {
+				continue
+			}
+			var otherName struct {
+				TypeID asn1.ObjectIdentifier
+				Value  asn1.RawValue
+			}
+			if _, err := asn1.UnmarshalWithParams(name.FullBytes, &otherName, "tag:0"); err != nil {
+				t.Fatal(err)
+			}
+			if !otherName.TypeID.Equal(oid) {
+				continue
+			}
+			var value string
+			if _, err := asn1.UnmarshalWithParams(otherName.Value.Bytes, &value, "utf8"); err != nil {
+				t.Fatal(err)
+			}
+			return value
+		}
+	}
+	return ""
+}
@@ -30,6 +30,27 @@ func pathGenerateRoot(b *backend) *framework.Path {
 	ret.Fields = addCACommonFields(map[string]*framework.FieldSchema{})
 	ret.Fields = addCAKeyGenerationFields(ret.Fields)
 	ret.Fields = addCAIssueFields(ret.Fields)
+	ret.Fields = addIssuerNameField(ret.Fields)
+
+	return ret
+}
+
+func pathGenerateIssuerRoot(b *backend) *framework.Path {
+	ret := &framework.Path{
+		Pattern: "issuers/generate/root/" + framework.GenericNameRegex("exported"),
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathIssuerGenerateRoot,
+		},
+
+		HelpSynopsis:    pathGenerateIssuerRootHelpSyn,
+		HelpDescription: pathGenerateIssuerRootHelpDesc,
+	}
+
+	ret.Fields = addCACommonFields(map[string]*framework.FieldSchema{})
+	ret.Fields = addCAKeyGenerationFields(ret.Fields)
+	ret.Fields = addCAIssueFields(ret.Fields)
+	ret.Fields = addIssuerNameField(ret.Fields)
 
 	return ret
 }
@@ -63,6 +84,7 @@ func pathSignIntermediate(b *backend) *framework.Path {
 
 	ret.Fields = addCACommonFields(map[string]*framework.FieldSchema{})
 	ret.Fields = addCAIssueFields(ret.Fields)
+	ret.Fields = addIssuerRefField(ret.Fields)
 
 	ret.Fields["csr"] = &framework.FieldSchema{
 		Type:        framework.TypeString,
@@ -106,31 +128,78 @@ func pathSignSelfIssued(b *backend) *framework.Path {
 		HelpDescription: pathSignSelfIssuedHelpDesc,
 	}
 
+	ret.Fields = addIssuerRefField(ret.Fields)
+
 	return ret
 }
 
 func (b *backend) pathCADeleteRoot(
 	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	return nil, req.Storage.Delete("config/ca_bundle")
+	b.issuersLock.Lock()
+	defer b.issuersLock.Unlock()
+
+	// Remove every issuer and key, so that a new CA can be generated
+	for _, prefix := range []string{"issuers/", "keys/", "crls/"} {
+		keys, err := req.Storage.List(prefix)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range keys {
+			if err := req.Storage.Delete(prefix + key); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// The old CA certificate stays readable until a new CA is set
+	if err := req.Storage.Delete("config/issuers"); err != nil {
+		return nil, err
+	}
+
+	return nil, req.Storage.Delete(legacyCABundleKey)
 }
 
 func (b *backend) pathCAGenerateRoot(
 	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	var err error
+	if err := migrateLegacyCABundle(b, req.Storage); err != nil {
+		return nil, err
+	}
 
-	entry, err := req.Storage.Get("config/ca_bundle")
+	// Through this path a root is only generated while the backend has no
+	// CA; additional issuers are generated through "issuers/generate/root"
+	config, err := fetchIssuerConfig(req.Storage)
 	if err != nil {
 		return nil, err
 	}
-	if entry != nil {
+	if config.DefaultIssuerID != "" {
 		return nil, nil
 	}
 
+	return b.pathIssuerGenerateRoot(req, data)
+}
+
+func (b *backend) pathIssuerGenerateRoot(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	var err error
+
+	if err := migrateLegacyCABundle(b, req.Storage); err != nil {
+		return nil, err
+	}
+
 	exported, format, role, errorResp := b.getGenerationParams(data)
 	if errorResp != nil {
 		return errorResp, nil
 	}
 
+	issuerName := data.Get("issuer_name").(string)
+	keyName := data.Get("key_name").(string)
+	if err := checkIssuerName(req.Storage, issuerName, ""); err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+	if err := checkKeyName(req.Storage, keyName, ""); err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
 	maxPathLengthIface, ok := data.GetOk("max_path_length")
 	if ok {
 		maxPathLength := maxPathLengthIface.(int)
@@ -188,15 +257,13 @@ func (b *backend) pathCAGenerateRoot(
 		}
 	}
 
-	// Store it as the CA bundle
-	entry, err = logical.StorageEntryJSON("config/ca_bundle", cb)
-	if err != nil {
-		return nil, err
-	}
-	err = req.Storage.Put(entry)
+	// Store it as a new issuer
+	issuer, err := storeCA(b, req.Storage, parsedBundle, issuerName, keyName, false)
 	if err != nil {
 		return nil, err
 	}
+	resp.Data["issuer_id"] = issuer.ID
+	resp.Data["key_id"] = issuer.KeyID
 
 	// Also store it as just the certificate identified by serial number, so it
 	// can be revoked
@@ -208,15 +275,6 @@ func (b *backend) pathCAGenerateRoot(
 		return nil, errwrap.Wrapf("unable to store certificate locally: {{err}}", err)
 	}
 
-	// For ease of later use, also store just the certificate at a known
-	// location
-	entry.Key = "ca"
-	entry.Value = parsedBundle.CertificateBytes
-	err = req.Storage.Put(entry)
-	if err != nil {
-		return nil, err
-	}
-
 	// Build a fresh CRL
 	err = buildCRL(b, req)
 	if err != nil {
@@ -256,7 +314,7 @@ func (b *backend) pathCASignIntermediate(
 	}
 
 	var caErr error
-	signingBundle, caErr := fetchCAInfo(req)
+	signingBundle, caErr := fetchCAInfo(b, req, data.Get("issuer_ref").(string))
 	switch caErr.(type) {
 	case errutil.UserError:
 		return nil, errutil.UserError{Err: fmt.Sprintf(
@@ -378,7 +436,7 @@ func (b *backend) pathCASignSelfIssued(
 	}
 
 	var caErr error
-	signingBundle, caErr := fetchCAInfo(req)
+	signingBundle, caErr := fetchCAInfo(b, req, data.Get("issuer_ref").(string))
 	switch caErr.(type) {
 	case errutil.UserError:
 		return nil, errutil.UserError{Err: fmt.Sprintf(
@@ -429,8 +487,16 @@ const pathGenerateRootHelpDesc = `
 See the API documentation for more information.
 `
 
+const pathGenerateIssuerRootHelpSyn = `
+Generate a new root CA certificate and private key as an additional issuer.
+`
+
+const pathGenerateIssuerRootHelpDesc = `
+See the API documentation for more information.
+`
+
 const pathDeleteRootHelpSyn = `
-Deletes the root CA key to allow a new one to be generated.
+Deletes all CA certificates and keys to allow a new CA to be generated.
 `
 
 const pathDeleteRootHelpDesc = `
@@ -1,8 +1,13 @@
 package transit
 
 import (
+	"fmt"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/vault/helper/consts"
 	"github.com/hashicorp/vault/helper/keysutil"
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/framework"
@@ -23,6 +28,8 @@ func Backend(conf *logical.BackendConfig) *backend {
 			SealWrapStorage: []string{
 				"archive/",
 				"policy/",
+				"wrapping_key",
+				keysutil.BackupHMACKeyPath,
 			},
 		},
 
@@ -32,6 +39,8 @@ func Backend(conf *logical.BackendConfig) *backend {
 			b.pathConfig(),
 			b.pathRotate(),
 			b.pathRewrap(),
+			b.pathImport(),
+			b.pathWrappingKey(),
 			b.pathKeys(),
 			b.pathListKeys(),
 			b.pathExportKeys(),
@@ -43,11 +52,14 @@ func Backend(conf *logical.BackendConfig) *backend {
 			b.pathHMAC(),
 			b.pathSign(),
 			b.pathVerify(),
+			b.pathBackup(),
+			b.pathRestore(),
 		},
 
-		Secrets:     []*framework.Secret{},
-		Invalidate:  b.invalidate,
-		BackendType: logical.TypeLogical,
+		Secrets:      []*framework.Secret{},
+		Invalidate:   b.invalidate,
+		PeriodicFunc: b.periodicFunc,
+		BackendType:  logical.TypeLogical,
 	}
 
 	b.lm = keysutil.NewLockManager(conf.System.CachingDisabled())
@@ -55,9 +67,61 @@ func Backend(conf *logical.BackendConfig) *backend {
 	return &b
 }
 
+// The minimum period between automatic rotations of a key
+const minAutoRotatePeriod = time.Hour
+
 type backend struct {
 	*framework.Backend
 	lm *keysutil.LockManager
+
+	// Serializes the generation of the wrapping key
+	wrappingKeyLock sync.Mutex
+}
+
+// periodicFunc rotates the keys that are due for automatic rotation. It is
+// triggered once a minute by the RollbackManager.
+func (b *backend) periodicFunc(req *logical.Request) error {
+	// Keys are rotated on the primary and replicated to secondaries
+	if b.System().ReplicationState().HasState(consts.ReplicationPerformanceSecondary) {
+		return nil
+	}
+
+	names, err := req.Storage.List("policy/")
+	if err != nil {
+		return err
+	}
+
+	var errs *multierror.Error
+	for _, name := range names {
+		if err := b.rotateIfDue(req.Storage, name); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("failed to rotate key %s: %v", name, err))
+		}
+	}
+
+	return errs.ErrorOrNil()
+}
+
+// rotateIfDue rotates the named key if its auto rotate period has elapsed
+func (b *backend) rotateIfDue(storage logical.Storage, name string) error {
+	p, lock, err := b.lm.GetPolicyExclusive(storage, name)
+	if lock != nil {
+		defer lock.Unlock()
+	}
+	if err != nil || p == nil {
+		return err
+	}
+
+	if p.AutoRotatePeriod == 0 || time.Now().Before(p.NextRotationTime()) {
+		return nil
+	}
+	if p.Imported && !p.AllowImportedKeyRotation {
+		return nil
+	}
+
+	if b.Logger().IsDebug() {
+		b.Logger().Debug("transit: rotating key", "key", name)
+	}
+	return p.Rotate(storage)
 }
 
 func (b *backend) invalidate(key string) {
@@ -0,0 +1,117 @@
+package transit
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+func TestTransit_BackupRestore(t *testing.T) {
+	b, s := createBackendWithStorage(t)
+
+	request := func(op logical.Operation, path string, data map[string]interface{}) (*logical.Response, error) {
+		return b.HandleRequest(&logical.Request{
+			Operation: op,
+			Path:      path,
+			Storage:   s,
+			Data:      data,
+		})
+	}
+	mustRequest := func(op logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		resp, err := request(op, path, data)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("%s: err:%v resp:%#v", path, err, resp)
+		}
+		return resp
+	}
+	expectError := func(op logical.Operation, path string, data map[string]interface{}) {
+		resp, err := request(op, path, data)
+		if err == nil || resp == nil || !resp.IsError() {
+			t.Fatalf("%s: expected error: err:%v resp:%#v", path, err, resp)
+		}
+	}
+
+	// Keys must opt in to backups
+	mustRequest(logical.UpdateOperation, "keys/foo", map[string]interface{}{
+		"exportable": true,
+	})
+	expectError(logical.ReadOperation, "backup/foo", nil)
+	mustRequest(logical.UpdateOperation, "keys/foo/config", map[string]interface{}{
+		"allow_plaintext_backup": true,
+	})
+
+	// Create several versions, some of them archived
+	plaintext := base64.StdEncoding.EncodeToString([]byte(testPlaintext))
+	resp := mustRequest(logical.UpdateOperation, "encrypt/foo", map[string]interface{}{
+		"plaintext": plaintext,
+	})
+	oldCiphertext := resp.Data["ciphertext"]
+	mustRequest(logical.UpdateOperation, "keys/foo/rotate", nil)
+	mustRequest(logical.UpdateOperation, "keys/foo/rotate", nil)
+	mustRequest(logical.UpdateOperation, "keys/foo/config", map[string]interface{}{
+		"min_decryption_version": 2,
+	})
+	resp = mustRequest(logical.UpdateOperation, "encrypt/foo", map[string]interface{}{
+		"plaintext": plaintext,
+	})
+	ciphertext := resp.Data["ciphertext"]
+
+	resp = mustRequest(logical.ReadOperation, "backup/foo", nil)
+	backup := resp.Data["backup"].(string)
+
+	// Existing keys are only replaced when forced
+	expectError(logical.UpdateOperation, "restore", map[string]interface{}{
+		"backup": backup,
+	})
+	mustRequest(logical.UpdateOperation, "restore", map[string]interface{}{
+		"backup": backup,
+		"force":  true,
+	})
+
+	// Restore into a new name and check that all versions are present
+	mustRequest(logical.UpdateOperation, "restore/bar", map[string]interface{}{
+		"backup": backup,
+	})
+	resp = mustRequest(logical.ReadOperation, "keys/bar", nil)
+	if resp.Data["latest_version"] != 3 || resp.Data["min_decryption_version"] != 2 || resp.Data["restore_info"] == nil {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	resp = mustRequest(logical.UpdateOperation, "decrypt/bar", map[string]interface{}{
+		"ciphertext": ciphertext,
+	})
+	if resp.Data["plaintext"] != plaintext {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	mustRequest(logical.UpdateOperation, "keys/bar/config", map[string]interface{}{
+		"min_decryption_version": 1,
+	})
+	resp = mustRequest(logical.UpdateOperation, "decrypt/bar", map[string]interface{}{
+		"ciphertext": oldCiphertext,
+	})
+	if resp.Data["plaintext"] != plaintext {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// Tampered backups are refused
+	raw, _ := base64.StdEncoding.DecodeString(backup)
+	raw[len(raw)/2] ^= 1
+	expectError(logical.UpdateOperation, "restore/baz", map[string]interface{}{
+		"backup": base64.StdEncoding.EncodeToString(raw),
+	})
+
+	// Backups are authenticated with a key of the mount, so another mount
+	// cannot restore them
+	b2, s2 := createBackendWithStorage(t)
+	resp, err := b2.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "restore/foo",
+		Storage:   s2,
+		Data: map[string]interface{}{
+			"backup": backup,
+		},
+	})
+	if err == nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected error: err:%v resp:%#v", err, resp)
+	}
+}
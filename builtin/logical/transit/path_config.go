@@ -2,6 +2,7 @@ package transit
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/framework"
@@ -35,6 +36,26 @@ the latest version of the key is allowed.`,
 				Type:        framework.TypeBool,
 				Description: "Whether to allow deletion of the key",
 			},
+
+			"exportable": &framework.FieldSchema{
+				Type: framework.TypeBool,
+				Description: `Enables export of the key. Once set, this
+cannot be disabled.`,
+			},
+
+			"allow_plaintext_backup": &framework.FieldSchema{
+				Type: framework.TypeBool,
+				Description: `Enables taking backups of the key. Once set,
+this cannot be disabled.`,
+			},
+
+			"auto_rotate_period": &framework.FieldSchema{
+				Type: framework.TypeDurationSecond,
+				Description: `If set, the period after which the key is
+rotated automatically. It must be at least one
+hour. If set to zero, the key is not rotated
+automatically.`,
+			},
 		},
 
 		Callbacks: map[logical.Operation]framework.OperationFunc{
@@ -126,6 +147,41 @@ func (b *backend) pathConfigWrite(
 		}
 	}
 
+	exportableRaw, ok := d.GetOk("exportable")
+	if ok {
+		exportable := exportableRaw.(bool)
+		// Don't unset the already set value
+		if exportable && !p.Exportable {
+			p.Exportable = exportable
+			persistNeeded = true
+		}
+	}
+
+	allowPlaintextBackupRaw, ok := d.GetOk("allow_plaintext_backup")
+	if ok {
+		allowPlaintextBackup := allowPlaintextBackupRaw.(bool)
+		// Don't unset the already set value
+		if allowPlaintextBackup && !p.AllowPlaintextBackup {
+			p.AllowPlaintextBackup = allowPlaintextBackup
+			persistNeeded = true
+		}
+	}
+
+	autoRotatePeriodRaw, ok := d.GetOk("auto_rotate_period")
+	if ok {
+		autoRotatePeriod := time.Duration(autoRotatePeriodRaw.(int)) * time.Second
+		if autoRotatePeriod != 0 && autoRotatePeriod < minAutoRotatePeriod {
+			return logical.ErrorResponse(fmt.Sprintf("auto rotate period must be zero or at least %s", minAutoRotatePeriod)), nil
+		}
+		if autoRotatePeriod != 0 && p.Imported && !p.AllowImportedKeyRotation {
+			return logical.ErrorResponse("imported keys can only be rotated automatically if allow_rotation was set on import"), nil
+		}
+		if autoRotatePeriod != p.AutoRotatePeriod {
+			p.AutoRotatePeriod = autoRotatePeriod
+			persistNeeded = true
+		}
+	}
+
 	// Add this as a guard here before persisting since we now require the min
 	// decryption version to start at 1; even if it's not explicitly set here,
 	// force the upgrade
@@ -150,5 +206,7 @@ const pathConfigHelpSyn = `Configure a named encryption key`
 const pathConfigHelpDesc = `
 This path is used to configure the named key. Currently, this
 supports adjusting the minimum version of the key allowed to
-be used for decryption via the min_decryption_version paramter.
+be used for decryption via the min_decryption_version paramter,
+and scheduling automatic rotation of the key via the
+auto_rotate_period parameter.
 `
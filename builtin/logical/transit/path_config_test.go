@@ -4,6 +4,7 @@ import (
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/vault/logical"
 )
@@ -221,3 +222,82 @@ func TestTransit_ConfigSettings(t *testing.T) {
 	testHMAC(3, true)
 	testHMAC(2, false)
 }
+
+func TestTransit_AutoRotate(t *testing.T) {
+	b, storage := createBackendWithStorage(t)
+
+	doReq := func(op logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		resp, err := b.HandleRequest(&logical.Request{
+			Storage:   storage,
+			Operation: op,
+			Path:      path,
+			Data:      data,
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("%s: err:%v resp:%#v", path, err, resp)
+		}
+		return resp
+	}
+
+	doReq(logical.UpdateOperation, "keys/foo", nil)
+	doReq(logical.UpdateOperation, "keys/bar", nil)
+
+	// Periods below the minimum are refused
+	resp, err := b.HandleRequest(&logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/foo/config",
+		Data: map[string]interface{}{
+			"auto_rotate_period": "10m",
+		},
+	})
+	if err == nil && (resp == nil || !resp.IsError()) {
+		t.Fatalf("expected error: resp:%#v", resp)
+	}
+
+	doReq(logical.UpdateOperation, "keys/foo/config", map[string]interface{}{
+		"auto_rotate_period": "24h",
+	})
+	resp = doReq(logical.ReadOperation, "keys/foo", nil)
+	if resp.Data["auto_rotate_period"].(int64) != 86400 {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	lastRotation := resp.Data["last_rotation_time"].(time.Time)
+	if !resp.Data["next_rotation_time"].(time.Time).Equal(lastRotation.Add(24 * time.Hour)) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// Nothing is due yet
+	if err := b.periodicFunc(&logical.Request{Storage: storage}); err != nil {
+		t.Fatal(err)
+	}
+	resp = doReq(logical.ReadOperation, "keys/foo", nil)
+	if resp.Data["latest_version"] != 1 {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// Move the last rotation into the past so that the key is due
+	p, lock, err := b.lm.GetPolicyExclusive(storage, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.LastRotationTime = time.Now().Add(-25 * time.Hour)
+	if err := p.Persist(storage); err != nil {
+		t.Fatal(err)
+	}
+	lock.Unlock()
+
+	if err := b.periodicFunc(&logical.Request{Storage: storage}); err != nil {
+		t.Fatal(err)
+	}
+	resp = doReq(logical.ReadOperation, "keys/foo", nil)
+	if resp.Data["latest_version"] != 2 || !resp.Data["last_rotation_time"].(time.Time).After(lastRotation) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// Keys without a period are left alone
+	resp = doReq(logical.ReadOperation, "keys/bar", nil)
+	if resp.Data["latest_version"] != 1 || resp.Data["next_rotation_time"] != nil {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+}
@@ -87,8 +87,8 @@ encryption key) this nonce value is **never reused**.
 				Description: `
 This parameter is required when encryption key is expected to be created.
 When performing an upsert operation, the type of key to create. Currently,
-"aes256-gcm96" (symmetric) is the only type supported. Defaults to
-"aes256-gcm96".`,
+"aes256-gcm96", "aes128-gcm96" and "chacha20-poly1305" (symmetric) are
+supported. Defaults to "aes256-gcm96".`,
 			},
 
 			"convergent_encryption": &framework.FieldSchema{
@@ -223,13 +223,14 @@ func (b *backend) pathEncryptWrite(
 		}
 
 		keyType := d.Get("type").(string)
-		switch keyType {
-		case "aes256-gcm96":
-			polReq.KeyType = keysutil.KeyType_AES256_GCM96
-		case "ecdsa-p256":
-			return logical.ErrorResponse(fmt.Sprintf("key type %v not supported for this operation", keyType)), logical.ErrInvalidRequest
+		polReq.KeyType, err = keysutil.ParseKeyType(keyType)
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+		switch polReq.KeyType {
+		case keysutil.KeyType_AES256_GCM96, keysutil.KeyType_AES128_GCM96, keysutil.KeyType_ChaCha20_Poly1305:
 		default:
-			return logical.ErrorResponse(fmt.Sprintf("unknown key type %v", keyType)), logical.ErrInvalidRequest
+			return logical.ErrorResponse(fmt.Sprintf("key type %v not supported for this operation", keyType)), logical.ErrInvalidRequest
 		}
 
 		p, lock, upserted, err = b.lm.GetPolicyUpsert(polReq)
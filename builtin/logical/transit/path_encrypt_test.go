@@ -545,3 +545,63 @@ func TestTransit_BatchEncryptionCase12(t *testing.T) {
 		t.Fatalf("expected an error")
 	}
 }
+
+func TestTransit_EncryptDecrypt_KeyTypes(t *testing.T) {
+	b, s := createBackendWithStorage(t)
+
+	plaintext := "dGhlIHF1aWNrIGJyb3duIGZveA=="
+	for _, keyType := range []string{"aes128-gcm96", "chacha20-poly1305", "rsa-2048"} {
+		resp, err := b.HandleRequest(&logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "keys/" + keyType,
+			Storage:   s,
+			Data: map[string]interface{}{
+				"type": keyType,
+			},
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("err:%v resp:%#v", err, resp)
+		}
+
+		resp, err = b.HandleRequest(&logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "encrypt/" + keyType,
+			Storage:   s,
+			Data: map[string]interface{}{
+				"plaintext": plaintext,
+			},
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("err:%v resp:%#v", err, resp)
+		}
+
+		resp, err = b.HandleRequest(&logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "decrypt/" + keyType,
+			Storage:   s,
+			Data: map[string]interface{}{
+				"ciphertext": resp.Data["ciphertext"],
+			},
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("err:%v resp:%#v", err, resp)
+		}
+		if resp.Data["plaintext"] != plaintext {
+			t.Fatalf("%s: bad: plaintext. Expected: %q, Actual: %q", keyType, plaintext, resp.Data["plaintext"])
+		}
+	}
+
+	// Upserting asymmetric keys is not supported
+	resp, err := b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "encrypt/upserted",
+		Storage:   s,
+		Data: map[string]interface{}{
+			"plaintext": plaintext,
+			"type":      "rsa-2048",
+		},
+	})
+	if err == nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected error: err:%v resp:%#v", err, resp)
+	}
+}
@@ -0,0 +1,186 @@
+package transit
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/base64"
+	"fmt"
+
+	"github.com/hashicorp/vault/helper/errutil"
+	"github.com/hashicorp/vault/helper/keysutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+// The ephemeral AES key wrapping the imported key material is AES-256
+const ephemeralKeySize = 32
+
+func (b *backend) pathImport() *framework.Path {
+	return &framework.Path{
+		Pattern: "keys/" + framework.GenericNameRegex("name") + "/import",
+		Fields: map[string]*framework.FieldSchema{
+			"name": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Name of the key",
+			},
+
+			"ciphertext": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `The base64-encoded key material to import.
+It is an ephemeral AES-256 key encrypted with the
+wrapping key of the mount using RSA-OAEP, followed
+by the key material wrapped with the ephemeral key
+using AES key wrap with padding (RFC 5649).`,
+			},
+
+			"hash_function": &framework.FieldSchema{
+				Type:    framework.TypeString,
+				Default: "SHA256",
+				Description: `The hash function used for RSA-OAEP when
+encrypting the ephemeral key. Valid values are
+"SHA1", "SHA224", "SHA256", "SHA384" and "SHA512".
+Defaults to "SHA256".`,
+			},
+
+			"type": &framework.FieldSchema{
+				Type:    framework.TypeString,
+				Default: "aes256-gcm96",
+				Description: `The type of key being imported. Symmetric
+key material is the raw key; asymmetric key
+material is a PKCS#8 encoded private key.
+Defaults to "aes256-gcm96".`,
+			},
+
+			"derived": &framework.FieldSchema{
+				Type: framework.TypeBool,
+				Description: `Enables key derivation mode. This
+allows for per-transaction unique
+keys for encryption operations.`,
+			},
+
+			"convergent_encryption": &framework.FieldSchema{
+				Type: framework.TypeBool,
+				Description: `Whether to support convergent encryption.
+This is only supported when using a key with
+key derivation enabled.`,
+			},
+
+			"exportable": &framework.FieldSchema{
+				Type: framework.TypeBool,
+				Description: `Enables keys to be exportable.
+This allows for all the valid keys
+in the key ring to be exported.`,
+			},
+
+			"allow_plaintext_backup": &framework.FieldSchema{
+				Type: framework.TypeBool,
+				Description: `Enables taking backups of the key. The
+backup contains all versions of the key in
+plaintext. Once set, this cannot be disabled.`,
+			},
+
+			"allow_rotation": &framework.FieldSchema{
+				Type: framework.TypeBool,
+				Description: `Whether the key may be rotated. Rotating
+replaces the imported key material with a key
+generated by Vault.`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathImportWrite,
+		},
+
+		HelpSynopsis:    pathImportHelpSyn,
+		HelpDescription: pathImportHelpDesc,
+	}
+}
+
+func (b *backend) pathImportWrite(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+	derived := d.Get("derived").(bool)
+	convergent := d.Get("convergent_encryption").(bool)
+
+	if !derived && convergent {
+		return logical.ErrorResponse("convergent encryption requires derivation to be enabled"), nil
+	}
+
+	keyType, err := keysutil.ParseKeyType(d.Get("type").(string))
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
+	var hashFunc crypto.Hash
+	switch d.Get("hash_function").(string) {
+	case "SHA1":
+		hashFunc = crypto.SHA1
+	case "SHA224":
+		hashFunc = crypto.SHA224
+	case "SHA256":
+		hashFunc = crypto.SHA256
+	case "SHA384":
+		hashFunc = crypto.SHA384
+	case "SHA512":
+		hashFunc = crypto.SHA512
+	default:
+		return logical.ErrorResponse(fmt.Sprintf("unsupported hash function %s", d.Get("hash_function").(string))), logical.ErrInvalidRequest
+	}
+
+	ciphertext, err := base64.StdEncoding.DecodeString(d.Get("ciphertext").(string))
+	if err != nil {
+		return logical.ErrorResponse(fmt.Sprintf("unable to decode ciphertext as base64: %s", err)), logical.ErrInvalidRequest
+	}
+
+	wrappingKey, err := b.getWrappingKey(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	// The ephemeral key is encrypted to the size of the wrapping key
+	if len(ciphertext) <= wrappingKey.Size() {
+		return logical.ErrorResponse("ciphertext is too short"), logical.ErrInvalidRequest
+	}
+	ephemeralKey, err := rsa.DecryptOAEP(hashFunc.New(), rand.Reader, wrappingKey, ciphertext[:wrappingKey.Size()], nil)
+	if err != nil || len(ephemeralKey) != ephemeralKeySize {
+		return logical.ErrorResponse("failed to decrypt the ephemeral key"), logical.ErrInvalidRequest
+	}
+
+	key, err := keysutil.KeyUnwrapWithPadding(ephemeralKey, ciphertext[wrappingKey.Size():])
+	if err != nil {
+		return logical.ErrorResponse(fmt.Sprintf("failed to unwrap the key material: %s", err)), logical.ErrInvalidRequest
+	}
+
+	err = b.lm.ImportPolicy(keysutil.PolicyRequest{
+		Storage:                  req.Storage,
+		Name:                     name,
+		KeyType:                  keyType,
+		Derived:                  derived,
+		Convergent:               convergent,
+		Exportable:               d.Get("exportable").(bool),
+		AllowImportedKeyRotation: d.Get("allow_rotation").(bool),
+		AllowPlaintextBackup:     d.Get("allow_plaintext_backup").(bool),
+	}, key)
+	if err != nil {
+		switch err.(type) {
+		case errutil.UserError:
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		default:
+			return nil, err
+		}
+	}
+
+	return nil, nil
+}
+
+const pathImportHelpSyn = `Imports an externally generated key into a new named key`
+
+const pathImportHelpDesc = `
+This path is used to import key material generated outside of Vault. The key
+material must be wrapped for the wrapping key of the mount, which can be read
+from the "wrapping_key" endpoint, so that it is never sent in plaintext.
+Imported keys cannot be rotated unless allow_rotation is set.
+`
@@ -0,0 +1,165 @@
+package transit
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/helper/keysutil"
+	"github.com/hashicorp/vault/logical"
+)
+
+func TestTransit_Import(t *testing.T) {
+	b, s := createBackendWithStorage(t)
+
+	resp, err := b.HandleRequest(&logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "wrapping_key",
+		Storage:   s,
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+	block, _ := pem.Decode([]byte(resp.Data["public_key"].(string)))
+	if block == nil {
+		t.Fatal("failed to decode wrapping key")
+	}
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrappingKey := pub.(*rsa.PublicKey)
+
+	importKey := func(name, keyType string, key []byte, data map[string]interface{}) *logical.Response {
+		ephemeralKey := make([]byte, 32)
+		if _, err := rand.Read(ephemeralKey); err != nil {
+			t.Fatal(err)
+		}
+		wrappedEphemeralKey, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, wrappingKey, ephemeralKey, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		wrappedKey, err := keysutil.KeyWrapWithPadding(ephemeralKey, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if data == nil {
+			data = map[string]interface{}{}
+		}
+		data["type"] = keyType
+		data["ciphertext"] = base64.StdEncoding.EncodeToString(append(wrappedEphemeralKey, wrappedKey...))
+		resp, err := b.HandleRequest(&logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "keys/" + name + "/import",
+			Storage:   s,
+			Data:      data,
+		})
+		if resp == nil && err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	// Import an AES key and check that it is the one used for encryption
+	aesKey := make([]byte, 16)
+	if _, err := rand.Read(aesKey); err != nil {
+		t.Fatal(err)
+	}
+	if resp := importKey("aes", "aes128-gcm96", aesKey, map[string]interface{}{
+		"exportable": true,
+	}); resp != nil {
+		t.Fatalf("bad: %#v", resp)
+	}
+	resp, err = b.HandleRequest(&logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "export/encryption-key/aes/1",
+		Storage:   s,
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+	if resp.Data["keys"].(map[string]string)["1"] != base64.StdEncoding.EncodeToString(aesKey) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// Keys of the wrong size or existing keys are refused
+	if resp := importKey("aes2", "aes256-gcm96", aesKey, nil); resp == nil || !resp.IsError() {
+		t.Fatalf("expected error: %#v", resp)
+	}
+	if resp := importKey("aes", "aes128-gcm96", aesKey, nil); resp == nil || !resp.IsError() {
+		t.Fatalf("expected error: %#v", resp)
+	}
+
+	// Imported keys cannot be rotated by default
+	resp, err = b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "keys/aes/rotate",
+		Storage:   s,
+	})
+	if err == nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected error: err:%v resp:%#v", err, resp)
+	}
+
+	// Import an ECDSA key and verify a signature made with it
+	ecKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecDer, err := x509.MarshalPKCS8PrivateKey(ecKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp := importKey("ec", "ecdsa-p384", ecDer, map[string]interface{}{
+		"allow_rotation": true,
+	}); resp != nil {
+		t.Fatalf("bad: %#v", resp)
+	}
+	if resp := importKey("ec2", "ecdsa-p256", ecDer, nil); resp == nil || !resp.IsError() {
+		t.Fatalf("expected error: %#v", resp)
+	}
+
+	resp, err = b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "sign/ec",
+		Storage:   s,
+		Data: map[string]interface{}{
+			"input": base64.StdEncoding.EncodeToString([]byte(testPlaintext)),
+		},
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(resp.Data["signature"].(string), "vault:v1:"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ecSig struct {
+		R, S *big.Int
+	}
+	if _, err := asn1.Unmarshal(sig, &ecSig); err != nil {
+		t.Fatal(err)
+	}
+	digest := sha256.Sum256([]byte(testPlaintext))
+	if !ecdsa.Verify(&ecKey.PublicKey, digest[:], ecSig.R, ecSig.S) {
+		t.Fatal("signature does not verify with the imported key")
+	}
+
+	resp, err = b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "keys/ec/rotate",
+		Storage:   s,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+}
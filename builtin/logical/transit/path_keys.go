@@ -1,7 +1,6 @@
 package transit
 
 import (
-	"crypto/elliptic"
 	"encoding/base64"
 	"fmt"
 	"strconv"
@@ -41,8 +40,9 @@ func (b *backend) pathKeys() *framework.Path {
 				Type:    framework.TypeString,
 				Default: "aes256-gcm96",
 				Description: `The type of key to create. Currently,
-"aes256-gcm96" (symmetric) and "ecdsa-p256" (asymmetric), and
-'ed25519' (asymmetric) are supported. Defaults to "aes256-gcm96".`,
+"aes256-gcm96", "aes128-gcm96" and "chacha20-poly1305" (symmetric),
+and "ecdsa-p256", "ecdsa-p384", "ecdsa-p521", "ed25519", "rsa-2048"
+and "rsa-4096" (asymmetric) are supported. Defaults to "aes256-gcm96".`,
 			},
 
 			"derived": &framework.FieldSchema{
@@ -75,6 +75,13 @@ This allows for all the valid keys
 in the key ring to be exported.`,
 			},
 
+			"allow_plaintext_backup": &framework.FieldSchema{
+				Type: framework.TypeBool,
+				Description: `Enables taking backups of the key. The
+backup contains all versions of the key in
+plaintext. Once set, this cannot be disabled.`,
+			},
+
 			"context": &framework.FieldSchema{
 				Type: framework.TypeString,
 				Description: `Base64 encoded context for key derivation.
@@ -112,27 +119,25 @@ func (b *backend) pathPolicyWrite(
 	convergent := d.Get("convergent_encryption").(bool)
 	keyType := d.Get("type").(string)
 	exportable := d.Get("exportable").(bool)
+	allowPlaintextBackup := d.Get("allow_plaintext_backup").(bool)
 
 	if !derived && convergent {
 		return logical.ErrorResponse("convergent encryption requires derivation to be enabled"), nil
 	}
 
+	var err error
 	polReq := keysutil.PolicyRequest{
 		Storage:    req.Storage,
 		Name:       name,
 		Derived:    derived,
 		Convergent: convergent,
 		Exportable: exportable,
+
+		AllowPlaintextBackup: allowPlaintextBackup,
 	}
-	switch keyType {
-	case "aes256-gcm96":
-		polReq.KeyType = keysutil.KeyType_AES256_GCM96
-	case "ecdsa-p256":
-		polReq.KeyType = keysutil.KeyType_ECDSA_P256
-	case "ed25519":
-		polReq.KeyType = keysutil.KeyType_ED25519
-	default:
-		return logical.ErrorResponse(fmt.Sprintf("unknown key type %v", keyType)), logical.ErrInvalidRequest
+	polReq.KeyType, err = keysutil.ParseKeyType(keyType)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
 	}
 
 	p, lock, upserted, err := b.lm.GetPolicyUpsert(polReq)
@@ -187,6 +192,8 @@ func (b *backend) pathPolicyRead(
 			"min_encryption_version": p.MinEncryptionVersion,
 			"latest_version":         p.LatestVersion,
 			"exportable":             p.Exportable,
+			"imported":               p.Imported,
+			"allow_plaintext_backup": p.AllowPlaintextBackup,
 			"supports_encryption":    p.Type.EncryptionSupported(),
 			"supports_decryption":    p.Type.DecryptionSupported(),
 			"supports_signing":       p.Type.SigningSupported(),
@@ -217,15 +224,40 @@ func (b *backend) pathPolicyRead(
 		}
 	}
 
+	if p.BackupInfo != nil {
+		resp.Data["backup_info"] = map[string]interface{}{
+			"time":    p.BackupInfo.Time,
+			"version": p.BackupInfo.Version,
+		}
+	}
+	if p.RestoreInfo != nil {
+		resp.Data["restore_info"] = map[string]interface{}{
+			"time":    p.RestoreInfo.Time,
+			"version": p.RestoreInfo.Version,
+		}
+	}
+
+	resp.Data["auto_rotate_period"] = int64(p.AutoRotatePeriod.Seconds())
+	if p.AutoRotatePeriod != 0 {
+		resp.Data["next_rotation_time"] = p.NextRotationTime()
+	}
+	if !p.LastRotationTime.IsZero() {
+		resp.Data["last_rotation_time"] = p.LastRotationTime
+	}
+
+	if p.Imported {
+		resp.Data["allow_imported_key_rotation"] = p.AllowImportedKeyRotation
+	}
+
 	switch p.Type {
-	case keysutil.KeyType_AES256_GCM96:
+	case keysutil.KeyType_AES256_GCM96, keysutil.KeyType_AES128_GCM96, keysutil.KeyType_ChaCha20_Poly1305:
 		retKeys := map[string]int64{}
 		for k, v := range p.Keys {
 			retKeys[strconv.Itoa(k)] = v.DeprecatedCreationTime
 		}
 		resp.Data["keys"] = retKeys
 
-	case keysutil.KeyType_ECDSA_P256, keysutil.KeyType_ED25519:
+	default:
 		retKeys := map[string]map[string]interface{}{}
 		for k, v := range p.Keys {
 			key := asymKey{
@@ -237,8 +269,10 @@ func (b *backend) pathPolicyRead(
 			}
 
 			switch p.Type {
-			case keysutil.KeyType_ECDSA_P256:
-				key.Name = elliptic.P256().Params().Name
+			case keysutil.KeyType_ECDSA_P256, keysutil.KeyType_ECDSA_P384, keysutil.KeyType_ECDSA_P521:
+				key.Name = p.Type.ECDSACurve().Params().Name
+			case keysutil.KeyType_RSA2048, keysutil.KeyType_RSA4096:
+				key.Name = p.Type.String()
 			case keysutil.KeyType_ED25519:
 				if p.Derived {
 					if len(context) == 0 {
@@ -0,0 +1,65 @@
+package transit
+
+import (
+	"github.com/hashicorp/vault/helper/errutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+func (b *backend) pathRestore() *framework.Path {
+	return &framework.Path{
+		Pattern: "restore(/" + framework.GenericNameRegex("name") + ")?",
+		Fields: map[string]*framework.FieldSchema{
+			"name": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `If set, the name of the restored key. Defaults
+to the name of the backed up key.`,
+			},
+
+			"backup": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "The backup of the key, as returned by the backup endpoint",
+			},
+
+			"force": &framework.FieldSchema{
+				Type:        framework.TypeBool,
+				Description: "Whether to overwrite an existing key of the same name",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathRestoreUpdate,
+		},
+
+		HelpSynopsis:    pathRestoreHelpSyn,
+		HelpDescription: pathRestoreHelpDesc,
+	}
+}
+
+func (b *backend) pathRestoreUpdate(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	backup := d.Get("backup").(string)
+	if backup == "" {
+		return logical.ErrorResponse("'backup' must be supplied"), logical.ErrInvalidRequest
+	}
+
+	err := b.lm.RestorePolicy(req.Storage, d.Get("name").(string), backup, d.Get("force").(bool))
+	if err != nil {
+		switch err.(type) {
+		case errutil.UserError:
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		default:
+			return nil, err
+		}
+	}
+
+	return nil, nil
+}
+
+const pathRestoreHelpSyn = `Restore the named key`
+
+const pathRestoreHelpDesc = `
+This path is used to restore a key from a backup created by the backup
+endpoint, optionally under a new name. An existing key is only replaced if
+force is set.
+`
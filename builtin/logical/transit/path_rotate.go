@@ -39,6 +39,9 @@ func (b *backend) pathRotateWrite(
 	if p == nil {
 		return logical.ErrorResponse("key not found"), logical.ErrInvalidRequest
 	}
+	if p.Imported && !p.AllowImportedKeyRotation {
+		return logical.ErrorResponse("imported keys can only be rotated if allow_rotation was set on import"), logical.ErrInvalidRequest
+	}
 
 	// Rotate the policy
 	err = p.Rotate(req.Storage)
@@ -1,11 +1,11 @@
 package transit
 
 import (
-	"crypto/sha256"
-	"crypto/sha512"
+	"crypto"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
 	"encoding/base64"
 	"fmt"
-	"hash"
 
 	"github.com/hashicorp/vault/helper/errutil"
 	"github.com/hashicorp/vault/logical"
@@ -47,6 +47,12 @@ Defaults to "sha2-256". Not valid for all key types,
 including ed25519.`,
 			},
 
+			"signature_algorithm": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `The signature algorithm to use for RSA keys.
+Valid values are "pss" (the default) and "pkcs1v15".`,
+			},
+
 			"urlalgorithm": &framework.FieldSchema{
 				Type:        framework.TypeString,
 				Description: `Hash algorithm to use (POST URL parameter)`,
@@ -117,6 +123,12 @@ derivation is enabled; currently only available with ed25519 keys.`,
 
 Defaults to "sha2-256". Not valid for all key types.`,
 			},
+
+			"signature_algorithm": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `The signature algorithm to use for RSA keys.
+Valid values are "pss" (the default) and "pkcs1v15".`,
+			},
 		},
 
 		Callbacks: map[logical.Operation]framework.OperationFunc{
@@ -137,6 +149,7 @@ func (b *backend) pathSignWrite(
 	if algorithm == "" {
 		algorithm = d.Get("algorithm").(string)
 	}
+	sigAlgorithm := d.Get("signature_algorithm").(string)
 
 	input, err := base64.StdEncoding.DecodeString(inputB64)
 	if err != nil {
@@ -168,27 +181,33 @@ func (b *backend) pathSignWrite(
 		}
 	}
 
+	var hashAlgorithm crypto.Hash
 	if p.Type.HashSignatureInput() && algorithm != "none" {
-		var hf hash.Hash
 		switch algorithm {
 		case "sha2-224":
-			hf = sha256.New224()
+			hashAlgorithm = crypto.SHA224
 		case "sha2-256":
-			hf = sha256.New()
+			hashAlgorithm = crypto.SHA256
 		case "sha2-384":
-			hf = sha512.New384()
+			hashAlgorithm = crypto.SHA384
 		case "sha2-512":
-			hf = sha512.New()
+			hashAlgorithm = crypto.SHA512
 		default:
 			return logical.ErrorResponse(fmt.Sprintf("unsupported algorithm %s", algorithm)), nil
 		}
+		hf := hashAlgorithm.New()
 		hf.Write(input)
 		input = hf.Sum(nil)
 	}
 
-	sig, err := p.Sign(ver, context, input)
+	sig, err := p.Sign(ver, context, input, hashAlgorithm, sigAlgorithm)
 	if err != nil {
-		return nil, err
+		switch err.(type) {
+		case errutil.UserError:
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		default:
+			return nil, err
+		}
 	}
 	if sig == nil {
 		return nil, fmt.Errorf("signature could not be computed")
@@ -230,6 +249,7 @@ func (b *backend) pathVerifyWrite(
 	if algorithm == "" {
 		algorithm = d.Get("algorithm").(string)
 	}
+	sigAlgorithm := d.Get("signature_algorithm").(string)
 
 	input, err := base64.StdEncoding.DecodeString(inputB64)
 	if err != nil {
@@ -261,25 +281,26 @@ func (b *backend) pathVerifyWrite(
 		}
 	}
 
+	var hashAlgorithm crypto.Hash
 	if p.Type.HashSignatureInput() && algorithm != "none" {
-		var hf hash.Hash
 		switch algorithm {
 		case "sha2-224":
-			hf = sha256.New224()
+			hashAlgorithm = crypto.SHA224
 		case "sha2-256":
-			hf = sha256.New()
+			hashAlgorithm = crypto.SHA256
 		case "sha2-384":
-			hf = sha512.New384()
+			hashAlgorithm = crypto.SHA384
 		case "sha2-512":
-			hf = sha512.New()
+			hashAlgorithm = crypto.SHA512
 		default:
 			return logical.ErrorResponse(fmt.Sprintf("unsupported algorithm %s", algorithm)), nil
 		}
+		hf := hashAlgorithm.New()
 		hf.Write(input)
 		input = hf.Sum(nil)
 	}
 
-	valid, err := p.VerifySignature(context, input, sig)
+	valid, err := p.VerifySignature(context, input, sig, hashAlgorithm, sigAlgorithm)
 	if err != nil {
 		switch err.(type) {
 		case errutil.UserError:
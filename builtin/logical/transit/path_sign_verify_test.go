@@ -2,6 +2,7 @@ package transit
 
 import (
 	"encoding/base64"
+	"fmt"
 	"strings"
 	"testing"
 
@@ -415,3 +416,86 @@ func TestTransit_SignVerify_ED25519(t *testing.T) {
 	verifyRequest(req, false, "bar", sig)
 	verifyRequest(req, true, "bar", v1sig)
 }
+
+func TestTransit_SignVerify_KeyTypes(t *testing.T) {
+	b, storage := createBackendWithStorage(t)
+
+	cases := []struct {
+		keyType      string
+		sigAlgorithm string
+		algorithm    string
+	}{
+		{"ecdsa-p384", "", "sha2-384"},
+		{"ecdsa-p521", "", "sha2-512"},
+		{"rsa-2048", "pss", "sha2-256"},
+		{"rsa-2048", "pkcs1v15", "sha2-256"},
+		{"rsa-2048", "pkcs1v15", "none"},
+		{"rsa-4096", "", "sha2-512"},
+	}
+
+	for i, c := range cases {
+		name := fmt.Sprintf("key%d", i)
+		resp, err := b.HandleRequest(&logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      "keys/" + name,
+			Data: map[string]interface{}{
+				"type": c.keyType,
+			},
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: err: %v resp: %#v", err, resp)
+		}
+
+		data := map[string]interface{}{
+			"input":               "dGhlIHF1aWNrIGJyb3duIGZveA==",
+			"algorithm":           c.algorithm,
+			"signature_algorithm": c.sigAlgorithm,
+		}
+		resp, err = b.HandleRequest(&logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      "sign/" + name,
+			Data:      data,
+		})
+		if err != nil || resp == nil || resp.IsError() {
+			t.Fatalf("%s: bad: err: %v resp: %#v", c.keyType, err, resp)
+		}
+		data["signature"] = resp.Data["signature"]
+
+		resp, err = b.HandleRequest(&logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      "verify/" + name,
+			Data:      data,
+		})
+		if err != nil || resp == nil || resp.IsError() || !resp.Data["valid"].(bool) {
+			t.Fatalf("%s: bad: err: %v resp: %#v", c.keyType, err, resp)
+		}
+
+		data["input"] = "dGhlIHF1aWNrIGJyb3duIGZveg=="
+		resp, err = b.HandleRequest(&logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      "verify/" + name,
+			Data:      data,
+		})
+		if err != nil || resp == nil || resp.IsError() || resp.Data["valid"].(bool) {
+			t.Fatalf("%s: expected invalid signature: err: %v resp: %#v", c.keyType, err, resp)
+		}
+	}
+
+	// PSS signatures require hashing the input
+	resp, err := b.HandleRequest(&logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "sign/key2",
+		Data: map[string]interface{}{
+			"input":     "dGhlIHF1aWNrIGJyb3duIGZveA==",
+			"algorithm": "none",
+		},
+	})
+	if err == nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected error: err: %v resp: %#v", err, resp)
+	}
+}
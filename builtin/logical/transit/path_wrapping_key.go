@@ -0,0 +1,103 @@
+package transit
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+const (
+	wrappingKeyPath = "wrapping_key"
+	wrappingKeyBits = 4096
+)
+
+// wrappingKeyEntry is the storage entry of the wrapping key of the mount
+type wrappingKeyEntry struct {
+	Key *rsa.PrivateKey `json:"key"`
+}
+
+func (b *backend) pathWrappingKey() *framework.Path {
+	return &framework.Path{
+		Pattern: "wrapping_key",
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation: b.pathWrappingKeyRead,
+		},
+
+		HelpSynopsis:    pathWrappingKeyHelpSyn,
+		HelpDescription: pathWrappingKeyHelpDesc,
+	}
+}
+
+func (b *backend) pathWrappingKeyRead(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	key, err := b.getWrappingKey(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	derBytes, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling public key: %s", err)
+	}
+	pemBlock := &pem.Block{
+		Type:  "PUBLIC KEY",
+		Bytes: derBytes,
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"public_key": strings.TrimSpace(string(pem.EncodeToMemory(pemBlock))),
+		},
+	}, nil
+}
+
+// getWrappingKey returns the wrapping key of the mount, generating it on
+// first use
+func (b *backend) getWrappingKey(storage logical.Storage) (*rsa.PrivateKey, error) {
+	b.wrappingKeyLock.Lock()
+	defer b.wrappingKeyLock.Unlock()
+
+	entry, err := storage.Get(wrappingKeyPath)
+	if err != nil {
+		return nil, err
+	}
+	if entry != nil {
+		var result wrappingKeyEntry
+		if err := entry.DecodeJSON(&result); err != nil {
+			return nil, err
+		}
+		return result.Key, nil
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, wrappingKeyBits)
+	if err != nil {
+		return nil, err
+	}
+
+	entry, err = logical.StorageEntryJSON(wrappingKeyPath, &wrappingKeyEntry{
+		Key: key,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := storage.Put(entry); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+const pathWrappingKeyHelpSyn = `Returns the public key to use for wrapping imported keys`
+
+const pathWrappingKeyHelpDesc = `
+This path returns the public component of the RSA-4096 key that wraps key
+material imported into this backend. The key is generated on first use and is
+unique to the mount.
+`
@@ -10,12 +10,13 @@ import (
 	"github.com/hashicorp/vault/version"
 
 	credGcp "github.com/hashicorp/vault-plugin-auth-gcp/plugin"
-	credKube "github.com/hashicorp/vault-plugin-auth-kubernetes"
 	credAppId "github.com/hashicorp/vault/builtin/credential/app-id"
 	credAppRole "github.com/hashicorp/vault/builtin/credential/approle"
 	credAws "github.com/hashicorp/vault/builtin/credential/aws"
 	credCert "github.com/hashicorp/vault/builtin/credential/cert"
 	credGitHub "github.com/hashicorp/vault/builtin/credential/github"
+	credJWT "github.com/hashicorp/vault/builtin/credential/jwt"
+	credKube "github.com/hashicorp/vault/builtin/credential/kubernetes"
 	credLdap "github.com/hashicorp/vault/builtin/credential/ldap"
 	credOkta "github.com/hashicorp/vault/builtin/credential/okta"
 	credRadius "github.com/hashicorp/vault/builtin/credential/radius"
@@ -34,6 +35,7 @@ import (
 	physMSSQL "github.com/hashicorp/vault/physical/mssql"
 	physMySQL "github.com/hashicorp/vault/physical/mysql"
 	physPostgreSQL "github.com/hashicorp/vault/physical/postgresql"
+	physRaft "github.com/hashicorp/vault/physical/raft"
 	physS3 "github.com/hashicorp/vault/physical/s3"
 	physSwift "github.com/hashicorp/vault/physical/swift"
 	physZooKeeper "github.com/hashicorp/vault/physical/zookeeper"
@@ -42,6 +44,7 @@ import (
 	"github.com/hashicorp/vault/builtin/logical/cassandra"
 	"github.com/hashicorp/vault/builtin/logical/consul"
 	"github.com/hashicorp/vault/builtin/logical/database"
+	"github.com/hashicorp/vault/builtin/logical/kv"
 	"github.com/hashicorp/vault/builtin/logical/mongodb"
 	"github.com/hashicorp/vault/builtin/logical/mssql"
 	"github.com/hashicorp/vault/builtin/logical/mysql"
@@ -97,6 +100,8 @@ func Commands(metaPtr *meta.Meta) map[string]cli.CommandFactory {
 					"app-id":     credAppId.Factory,
 					"gcp":        credGcp.Factory,
 					"github":     credGitHub.Factory,
+					"jwt":        credJWT.Factory,
+					"oidc":       credJWT.Factory,
 					"userpass":   credUserpass.Factory,
 					"ldap":       credLdap.Factory,
 					"okta":       credOkta.Factory,
@@ -118,6 +123,7 @@ func Commands(metaPtr *meta.Meta) map[string]cli.CommandFactory {
 					"rabbitmq":   rabbitmq.Factory,
 					"database":   database.Factory,
 					"totp":       totp.Factory,
+					"kv-v2":      kv.Factory,
 					"plugin":     plugin.Factory,
 				},
 
@@ -144,6 +150,7 @@ func Commands(metaPtr *meta.Meta) map[string]cli.CommandFactory {
 				"mssql":                  physMSSQL.NewMSSQLBackend,
 				"mysql":                  physMySQL.NewMySQLBackend,
 				"postgresql":             physPostgreSQL.NewPostgreSQLBackend,
+				"raft":                   physRaft.NewRaftBackend,
 				"s3":                     physS3.NewS3Backend,
 				"swift":                  physSwift.NewSwiftBackend,
 				"zookeeper":              physZooKeeper.NewZooKeeperBackend,
@@ -152,6 +159,13 @@ func Commands(metaPtr *meta.Meta) map[string]cli.CommandFactory {
 			return c, nil
 		},
 
+		"agent": func() (cli.Command, error) {
+			return &command.AgentCommand{
+				Meta:       *metaPtr,
+				ShutdownCh: command.MakeShutdownCh(),
+			}, nil
+		},
+
 		"ssh": func() (cli.Command, error) {
 			return &command.SSHCommand{
 				Meta: *metaPtr,
@@ -168,13 +182,16 @@ func Commands(metaPtr *meta.Meta) map[string]cli.CommandFactory {
 			return &command.AuthCommand{
 				Meta: *metaPtr,
 				Handlers: map[string]command.AuthHandler{
-					"github":   &credGitHub.CLIHandler{},
-					"userpass": &credUserpass.CLIHandler{DefaultMount: "userpass"},
-					"ldap":     &credLdap.CLIHandler{},
-					"okta":     &credOkta.CLIHandler{},
-					"cert":     &credCert.CLIHandler{},
-					"aws":      &credAws.CLIHandler{},
-					"radius":   &credUserpass.CLIHandler{DefaultMount: "radius"},
+					"github":     &credGitHub.CLIHandler{},
+					"userpass":   &credUserpass.CLIHandler{DefaultMount: "userpass"},
+					"ldap":       &credLdap.CLIHandler{},
+					"okta":       &credOkta.CLIHandler{},
+					"cert":       &credCert.CLIHandler{},
+					"aws":        &credAws.CLIHandler{},
+					"radius":     &credUserpass.CLIHandler{DefaultMount: "radius"},
+					"jwt":        &credJWT.CLIHandler{DefaultMount: "jwt"},
+					"oidc":       &credJWT.CLIHandler{DefaultMount: "oidc"},
+					"kubernetes": &credKube.CLIHandler{},
 				},
 			}, nil
 		},
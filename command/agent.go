@@ -0,0 +1,418 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	colorable "github.com/mattn/go-colorable"
+	log "github.com/mgutz/logxi/v1"
+	"github.com/posener/complete"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/command/agent/auth"
+	"github.com/hashicorp/vault/command/agent/auth/approle"
+	"github.com/hashicorp/vault/command/agent/auth/aws"
+	"github.com/hashicorp/vault/command/agent/auth/cert"
+	"github.com/hashicorp/vault/command/agent/auth/jwt"
+	"github.com/hashicorp/vault/command/agent/auth/kubernetes"
+	"github.com/hashicorp/vault/command/agent/cache"
+	"github.com/hashicorp/vault/command/agent/config"
+	"github.com/hashicorp/vault/command/agent/sink"
+	"github.com/hashicorp/vault/command/agent/sink/file"
+	"github.com/hashicorp/vault/command/agent/sink/inmem"
+	"github.com/hashicorp/vault/command/agent/template"
+	"github.com/hashicorp/vault/command/server"
+	"github.com/hashicorp/vault/helper/logformat"
+	"github.com/hashicorp/vault/meta"
+)
+
+// AgentCommand is a Command that starts the Vault agent.
+type AgentCommand struct {
+	meta.Meta
+
+	ShutdownCh chan struct{}
+
+	logger log.Logger
+
+	// startedCh is closed once the agent is running, for tests
+	startedCh chan struct{}
+}
+
+func (c *AgentCommand) Run(args []string) int {
+	var configPath, logLevel string
+	var exitAfterRender bool
+	flags := c.Meta.FlagSet("agent", meta.FlagSetNone)
+	flags.StringVar(&configPath, "config", "", "")
+	flags.StringVar(&logLevel, "log-level", "info", "")
+	flags.BoolVar(&exitAfterRender, "exit-after-render", false, "")
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	var level int
+	logLevel = strings.ToLower(strings.TrimSpace(logLevel))
+	switch logLevel {
+	case "trace":
+		level = log.LevelTrace
+	case "debug":
+		level = log.LevelDebug
+	case "info":
+		level = log.LevelInfo
+	case "notice":
+		level = log.LevelNotice
+	case "warn":
+		level = log.LevelWarn
+	case "err":
+		level = log.LevelError
+	default:
+		c.Ui.Output(fmt.Sprintf("Unknown log level %s", logLevel))
+		return 1
+	}
+	logWriter := colorable.NewColorable(os.Stderr)
+	c.logger = logformat.NewVaultLoggerWithWriter(logWriter, level)
+
+	if configPath == "" {
+		c.Ui.Output("A config path must be specified with -config")
+		flags.Usage()
+		return 1
+	}
+
+	// Load the configuration
+	agentConfig, err := config.LoadConfig(configPath)
+	if err != nil {
+		c.Ui.Output(fmt.Sprintf(
+			"Error loading configuration from %s: %s", configPath, err))
+		return 1
+	}
+
+	if exitAfterRender && len(agentConfig.Templates) == 0 {
+		c.Ui.Output("-exit-after-render requires templates to be configured")
+		return 1
+	}
+
+	client, err := c.agentClient(agentConfig.Vault)
+	if err != nil {
+		c.Ui.Output(fmt.Sprintf("Error creating Vault client: %s", err))
+		return 1
+	}
+
+	info := map[string]string{
+		"vault address": client.Address(),
+	}
+	infoKeys := []string{"vault address"}
+
+	var method auth.AuthMethod
+	var sinks []*sink.SinkConfig
+	if agentConfig.AutoAuth != nil {
+		mc := agentConfig.AutoAuth.Method
+		authConfig := &auth.AuthConfig{
+			Logger:    c.logger,
+			MountPath: mc.MountPath,
+			Config:    mc.Config,
+		}
+		switch mc.Type {
+		case "approle":
+			method, err = approle.NewApproleAuthMethod(authConfig)
+		case "aws":
+			method, err = aws.NewAWSAuthMethod(authConfig)
+		case "cert":
+			method, err = cert.NewCertAuthMethod(authConfig)
+		case "jwt":
+			method, err = jwt.NewJWTAuthMethod(authConfig)
+		case "kubernetes":
+			method, err = kubernetes.NewKubernetesAuthMethod(authConfig)
+		default:
+			err = fmt.Errorf("unknown auth method %q", mc.Type)
+		}
+		if err != nil {
+			c.Ui.Output(fmt.Sprintf("Error creating %s auth method: %s", mc.Type, err))
+			return 1
+		}
+		info["auto-auth method"] = fmt.Sprintf("%s (%s)", mc.Type, mc.MountPath)
+		infoKeys = append(infoKeys, "auto-auth method")
+
+		for _, sc := range agentConfig.AutoAuth.Sinks {
+			sinkConfig := &sink.SinkConfig{
+				Logger:  c.logger,
+				Config:  sc.Config,
+				WrapTTL: sc.WrapTTL,
+			}
+			switch sc.Type {
+			case "file":
+				sinkConfig.Sink, err = file.NewFileSink(sinkConfig)
+			default:
+				err = fmt.Errorf("unknown sink type %q", sc.Type)
+			}
+			if err != nil {
+				c.Ui.Output(fmt.Sprintf("Error creating %s sink: %s", sc.Type, err))
+				return 1
+			}
+			sinks = append(sinks, sinkConfig)
+		}
+	}
+
+	// The templates are rendered with the auto-auth token, which the
+	// template server receives as a sink
+	var ts *template.Server
+	if len(agentConfig.Templates) > 0 {
+		ts = template.NewServer(&template.ServerConfig{
+			Logger:          c.logger,
+			Client:          client,
+			ExitAfterRender: exitAfterRender,
+		})
+		sinks = append(sinks, &sink.SinkConfig{
+			Sink:   ts,
+			Logger: c.logger,
+		})
+		info["templates"] = fmt.Sprintf("%d", len(agentConfig.Templates))
+		infoKeys = append(infoKeys, "templates")
+	}
+
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	defer cancelFunc()
+
+	// Start the caching proxy listeners
+	var servers []*http.Server
+	if agentConfig.Cache != nil {
+		proxier, err := cache.NewLeaseCache(&cache.LeaseCacheConfig{
+			Client:      client,
+			BaseContext: ctx,
+			Proxier: cache.NewAPIProxy(&cache.APIProxyConfig{
+				Client: client,
+				Logger: c.logger,
+			}),
+			Logger: c.logger,
+		})
+		if err != nil {
+			c.Ui.Output(fmt.Sprintf("Error creating lease cache: %s", err))
+			return 1
+		}
+
+		// The auto-auth token is written to an in-memory sink for the
+		// proxy to read it from
+		var tokenSink sink.SinkReader
+		if agentConfig.Cache.UseAutoAuthToken {
+			tokenSink = inmem.New()
+			sinks = append(sinks, &sink.SinkConfig{
+				Sink:   tokenSink,
+				Logger: c.logger,
+			})
+		}
+
+		handler := cache.Handler(c.logger, proxier, tokenSink)
+		for i, lnConfig := range agentConfig.Listeners {
+			ln, props, _, err := server.NewListener(lnConfig.Type, lnConfig.Config, logWriter)
+			if err != nil {
+				c.Ui.Output(fmt.Sprintf(
+					"Error initializing listener of type %s: %s",
+					lnConfig.Type, err))
+				return 1
+			}
+
+			srv := &http.Server{
+				Handler: handler,
+			}
+			servers = append(servers, srv)
+			go srv.Serve(ln)
+			defer ln.Close()
+
+			key := fmt.Sprintf("listener %d", i+1)
+			propsList := make([]string, 0, len(props))
+			for k, v := range props {
+				propsList = append(propsList, fmt.Sprintf(
+					"%s: %q", k, v))
+			}
+			sort.Strings(propsList)
+			infoKeys = append(infoKeys, key)
+			info[key] = fmt.Sprintf(
+				"%s (%s)", lnConfig.Type, strings.Join(propsList, ", "))
+		}
+	}
+
+	// Write out the PID to the file now that the agent has actually started
+	if err := storePidFile(agentConfig.PidFile); err != nil {
+		c.Ui.Output(fmt.Sprintf("Error storing PID: %v", err))
+		return 1
+	}
+	defer func() {
+		if err := removePidFile(agentConfig.PidFile); err != nil {
+			c.Ui.Output(fmt.Sprintf("Error deleting the PID file: %v", err))
+		}
+	}()
+
+	var wg sync.WaitGroup
+	if method != nil {
+		ah := auth.NewAuthHandler(&auth.AuthHandlerConfig{
+			Logger: c.logger,
+			Client: client,
+		})
+		ss := sink.NewSinkServer(&sink.SinkServerConfig{
+			Logger: c.logger,
+			Client: client,
+		})
+
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			ah.Run(ctx, method)
+		}()
+		go func() {
+			defer wg.Done()
+			ss.Run(ctx, ah.OutputCh, sinks)
+		}()
+	}
+
+	// renderedCh is closed once the templates have all been rendered, if
+	// the agent exits after rendering them
+	var renderedCh chan struct{}
+	templateErrCh := make(chan error, 1)
+	if ts != nil {
+		if exitAfterRender {
+			renderedCh = make(chan struct{})
+		}
+		go func() {
+			err := ts.Run(ctx, agentConfig.Templates)
+			if err != nil {
+				templateErrCh <- err
+				return
+			}
+			if renderedCh != nil && ctx.Err() == nil {
+				close(renderedCh)
+			}
+		}()
+	}
+
+	// Server configuration output
+	padding := 18
+	c.Ui.Output("==> Vault agent configuration:\n")
+	sort.Strings(infoKeys)
+	for _, k := range infoKeys {
+		c.Ui.Output(fmt.Sprintf(
+			"%s%s: %s",
+			strings.Repeat(" ", padding-len(k)),
+			strings.Title(k),
+			info[k]))
+	}
+	c.Ui.Output("")
+	c.Ui.Output("==> Vault agent started! Log data will stream in below:\n")
+
+	if c.startedCh != nil {
+		close(c.startedCh)
+	}
+
+	exitCode := 0
+	select {
+	case <-c.ShutdownCh:
+		c.Ui.Output("==> Vault agent shutdown triggered")
+	case <-renderedCh:
+		c.Ui.Output("==> Vault agent templates rendered, exiting")
+	case err := <-templateErrCh:
+		c.Ui.Output(fmt.Sprintf("Error running template server: %s", err))
+		exitCode = 1
+	}
+
+	for _, srv := range servers {
+		srv.Close()
+	}
+	cancelFunc()
+	wg.Wait()
+
+	return exitCode
+}
+
+// agentClient returns the client the agent talks to Vault with. The
+// environment is read first, and the settings of the vault block of the
+// configuration take precedence over it.
+func (c *AgentCommand) agentClient(vaultConfig *config.Vault) (*api.Client, error) {
+	clientConfig := api.DefaultConfig()
+	if err := clientConfig.ReadEnvironment(); err != nil {
+		return nil, fmt.Errorf("error reading environment: %s", err)
+	}
+
+	if vaultConfig != nil {
+		if vaultConfig.Address != "" {
+			clientConfig.Address = vaultConfig.Address
+		}
+
+		if vaultConfig.CACert != "" || vaultConfig.CAPath != "" || vaultConfig.ClientCert != "" ||
+			vaultConfig.ClientKey != "" || vaultConfig.TLSSkipVerify {
+			t := &api.TLSConfig{
+				CACert:     vaultConfig.CACert,
+				CAPath:     vaultConfig.CAPath,
+				ClientCert: vaultConfig.ClientCert,
+				ClientKey:  vaultConfig.ClientKey,
+				Insecure:   vaultConfig.TLSSkipVerify,
+			}
+			if err := clientConfig.ConfigureTLS(t); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	client, err := api.NewClient(clientConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	// The agent only uses the tokens it authenticates for, or the ones given
+	// to it by clients of the cache
+	client.ClearToken()
+
+	return client, nil
+}
+
+func (c *AgentCommand) Synopsis() string {
+	return "Start a Vault agent"
+}
+
+func (c *AgentCommand) Help() string {
+	helpText := `
+Usage: vault agent [options]
+
+  Start a Vault agent.
+
+  The agent authenticates with Vault using the configured auth method, and
+  writes the resulting token to the configured sinks. The token is renewed
+  for as long as possible, and the agent authenticates again when it can no
+  longer be renewed.
+
+  If a cache is configured, the agent also listens for requests, which it
+  forwards to Vault. Responses containing leased secrets or tokens are
+  cached, renewed, and evicted when they are revoked through the agent.
+
+  If templates are configured, the agent renders them to files with the
+  secrets they reference, using the auto-auth token. Secrets are renewed, and
+  the templates rendered again whenever a secret changes.
+
+General Options:
+
+  -config=<path>          Path to the configuration file.
+
+  -exit-after-render      Exit once all the templates have been rendered,
+                          rather than keeping them up to date. This is useful
+                          to render secrets from an init container.
+
+  -log-level=info         Log verbosity. Defaults to "info", will be output to
+                          stderr. Supported values: "trace", "debug", "info",
+                          "warn", "err"
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *AgentCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictNothing
+}
+
+func (c *AgentCommand) AutocompleteFlags() complete.Flags {
+	return complete.Flags{
+		"-config":            complete.PredictOr(complete.PredictFiles("*.hcl"), complete.PredictFiles("*.json")),
+		"-log-level":         complete.PredictSet("trace", "debug", "info", "warn", "err"),
+		"-exit-after-render": complete.PredictNothing,
+	}
+}
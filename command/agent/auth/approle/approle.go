@@ -0,0 +1,129 @@
+package approle
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/command/agent/auth"
+	"github.com/hashicorp/vault/helper/parseutil"
+	log "github.com/mgutz/logxi/v1"
+)
+
+type approleMethod struct {
+	logger    log.Logger
+	mountPath string
+
+	roleIDFilePath                 string
+	secretIDFilePath               string
+	removeSecretIDFileAfterReading bool
+
+	// The secret ID is cached so that the agent can authenticate again after
+	// its file has been removed
+	l              sync.Mutex
+	cachedRoleID   string
+	cachedSecretID string
+}
+
+// NewApproleAuthMethod returns an AuthMethod logging in with a role ID and
+// secret ID read from files
+func NewApproleAuthMethod(conf *auth.AuthConfig) (auth.AuthMethod, error) {
+	if conf == nil {
+		return nil, errors.New("empty config")
+	}
+	if conf.Config == nil {
+		return nil, errors.New("empty config data")
+	}
+
+	a := &approleMethod{
+		logger:                         conf.Logger,
+		mountPath:                      conf.MountPath,
+		removeSecretIDFileAfterReading: true,
+	}
+
+	roleIDFilePathRaw, ok := conf.Config["role_id_file_path"]
+	if !ok {
+		return nil, errors.New("missing 'role_id_file_path' value")
+	}
+	a.roleIDFilePath, ok = roleIDFilePathRaw.(string)
+	if !ok {
+		return nil, errors.New("could not convert 'role_id_file_path' config value to string")
+	}
+	if a.roleIDFilePath == "" {
+		return nil, errors.New("'role_id_file_path' value is empty")
+	}
+
+	if secretIDFilePathRaw, ok := conf.Config["secret_id_file_path"]; ok {
+		a.secretIDFilePath, ok = secretIDFilePathRaw.(string)
+		if !ok {
+			return nil, errors.New("could not convert 'secret_id_file_path' config value to string")
+		}
+	}
+
+	if removeRaw, ok := conf.Config["remove_secret_id_file_after_reading"]; ok {
+		remove, err := parseutil.ParseBool(removeRaw)
+		if err != nil {
+			return nil, errwrap.Wrapf("error parsing 'remove_secret_id_file_after_reading' value: {{err}}", err)
+		}
+		a.removeSecretIDFileAfterReading = remove
+	}
+
+	return a, nil
+}
+
+func (a *approleMethod) Authenticate(client *api.Client) (string, map[string]interface{}, error) {
+	a.l.Lock()
+	defer a.l.Unlock()
+
+	roleID, err := readFile(a.roleIDFilePath)
+	switch {
+	case err != nil && a.cachedRoleID == "":
+		return "", nil, errwrap.Wrapf("error reading role ID file: {{err}}", err)
+	case err != nil:
+		a.logger.Warn("auth.approle: error reading role ID file, using cached role ID", "error", err)
+	case roleID == "" && a.cachedRoleID == "":
+		return "", nil, errors.New("role ID file is empty")
+	case roleID != "":
+		a.cachedRoleID = roleID
+	}
+
+	data := map[string]interface{}{
+		"role_id": a.cachedRoleID,
+	}
+
+	if a.secretIDFilePath != "" {
+		secretID, err := readFile(a.secretIDFilePath)
+		switch {
+		case err != nil && os.IsNotExist(err) && a.cachedSecretID != "":
+			// The file was removed after reading it, use the cached value
+		case err != nil:
+			return "", nil, errwrap.Wrapf("error reading secret ID file: {{err}}", err)
+		case secretID == "" && a.cachedSecretID == "":
+			return "", nil, errors.New("secret ID file is empty")
+		case secretID != "":
+			a.cachedSecretID = secretID
+			if a.removeSecretIDFileAfterReading {
+				if err := os.Remove(a.secretIDFilePath); err != nil {
+					a.logger.Error("auth.approle: error removing secret ID file after reading", "error", err)
+				}
+			}
+		}
+
+		data["secret_id"] = a.cachedSecretID
+	}
+
+	return fmt.Sprintf("%s/login", a.mountPath), data, nil
+}
+
+func readFile(path string) (string, error) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(raw)), nil
+}
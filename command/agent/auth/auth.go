@@ -0,0 +1,179 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	log "github.com/mgutz/logxi/v1"
+)
+
+const (
+	initialBackoff = 1 * time.Second
+	maxBackoff     = 5 * time.Minute
+)
+
+// AuthMethod is a method the agent can authenticate with. It returns the
+// path and data of the login request to send to Vault.
+type AuthMethod interface {
+	Authenticate(*api.Client) (string, map[string]interface{}, error)
+}
+
+// AuthConfig is the configuration given to the factory of an AuthMethod
+type AuthConfig struct {
+	Logger    log.Logger
+	MountPath string
+	Config    map[string]interface{}
+}
+
+// AuthHandler authenticates with an AuthMethod, keeps the resulting token
+// renewed, and authenticates again when the token can no longer be renewed.
+// Each new token is sent on OutputCh.
+type AuthHandler struct {
+	OutputCh chan string
+
+	logger log.Logger
+	client *api.Client
+}
+
+// AuthHandlerConfig is the configuration of an AuthHandler
+type AuthHandlerConfig struct {
+	Logger log.Logger
+	Client *api.Client
+}
+
+// NewAuthHandler returns an AuthHandler for the given configuration
+func NewAuthHandler(conf *AuthHandlerConfig) *AuthHandler {
+	return &AuthHandler{
+		// This is buffered so that if we try to output after the sink server
+		// has been shut down, during agent shutdown, we won't block
+		OutputCh: make(chan string, 1),
+		logger:   conf.Logger,
+		client:   conf.Client,
+	}
+}
+
+// Run authenticates with the given method until the context is canceled
+func (ah *AuthHandler) Run(ctx context.Context, am AuthMethod) {
+	if am == nil {
+		panic("nil auth method")
+	}
+
+	ah.logger.Info("auth.handler: starting auth handler")
+	defer ah.logger.Info("auth.handler: auth handler stopped")
+
+	backoff := initialBackoff
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		secret, err := ah.authenticate(am)
+		if err != nil {
+			ah.logger.Error("auth.handler: error authenticating", "error", err, "backoff", backoff.String())
+			if !sleep(ctx, backoff) {
+				return
+			}
+			backoff = nextBackoff(backoff)
+			continue
+		}
+		backoff = initialBackoff
+
+		ah.logger.Info("auth.handler: authentication successful, sending token to sinks")
+		select {
+		case ah.OutputCh <- secret.Auth.ClientToken:
+		case <-ctx.Done():
+			return
+		}
+
+		if !ah.watch(ctx, secret) {
+			return
+		}
+	}
+}
+
+// authenticate logs in with the given method
+func (ah *AuthHandler) authenticate(am AuthMethod) (*api.Secret, error) {
+	path, data, err := am.Authenticate(ah.client)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := ah.client.Logical().Write(path, data)
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil || secret.Auth == nil || secret.Auth.ClientToken == "" {
+		return nil, errors.New("authentication returned nil auth info")
+	}
+
+	return secret, nil
+}
+
+// watch keeps the token of the secret renewed for as long as possible. It
+// returns false if the context was canceled, and true once the token needs
+// to be replaced.
+func (ah *AuthHandler) watch(ctx context.Context, secret *api.Secret) bool {
+	if !secret.Auth.Renewable {
+		ttl := time.Duration(secret.Auth.LeaseDuration) * time.Second
+		if ttl == 0 {
+			ah.logger.Info("auth.handler: token has no ttl, not renewing")
+			<-ctx.Done()
+			return false
+		}
+
+		// Authenticate again when two thirds of the TTL have elapsed
+		ah.logger.Info("auth.handler: token is not renewable, re-authenticating before it expires")
+		return sleep(ctx, ttl*2/3)
+	}
+
+	renewer, err := ah.client.NewRenewer(&api.RenewerInput{
+		Secret: secret,
+	})
+	if err != nil {
+		ah.logger.Error("auth.handler: error creating renewer, re-authenticating", "error", err)
+		return true
+	}
+	go renewer.Renew()
+	defer renewer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+
+		case err := <-renewer.DoneCh():
+			if err != nil {
+				ah.logger.Error("auth.handler: error renewing token, re-authenticating", "error", err)
+			} else {
+				ah.logger.Info("auth.handler: token reached its maximum ttl, re-authenticating")
+			}
+			return true
+
+		case <-renewer.RenewCh():
+			ah.logger.Info("auth.handler: renewed auth token")
+		}
+	}
+}
+
+// sleep waits for the given duration, returning false if the context was
+// canceled in the meantime
+func sleep(ctx context.Context, d time.Duration) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
+
+func nextBackoff(backoff time.Duration) time.Duration {
+	backoff *= 2
+	if backoff > maxBackoff {
+		backoff = maxBackoff
+	}
+	return backoff
+}
@@ -0,0 +1,142 @@
+package aws
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws/ec2metadata"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/api"
+	awsauth "github.com/hashicorp/vault/builtin/credential/aws"
+	"github.com/hashicorp/vault/command/agent/auth"
+	log "github.com/mgutz/logxi/v1"
+)
+
+const (
+	typeEC2 = "ec2"
+	typeIAM = "iam"
+)
+
+type awsMethod struct {
+	logger    log.Logger
+	authType  string
+	mountPath string
+	role      string
+
+	accessKey    string
+	secretKey    string
+	sessionToken string
+	headerValue  string
+
+	// The nonce of the first ec2 login is kept so that the agent can log in
+	// again with the same instance identity document
+	l     sync.Mutex
+	nonce string
+}
+
+// NewAWSAuthMethod returns an AuthMethod logging in with either the IAM
+// credentials of the agent, or the identity document of its EC2 instance
+func NewAWSAuthMethod(conf *auth.AuthConfig) (auth.AuthMethod, error) {
+	if conf == nil {
+		return nil, errors.New("empty config")
+	}
+	if conf.Config == nil {
+		return nil, errors.New("empty config data")
+	}
+
+	a := &awsMethod{
+		logger:    conf.Logger,
+		mountPath: conf.MountPath,
+	}
+
+	values := map[string]*string{
+		"type":          &a.authType,
+		"role":          &a.role,
+		"access_key":    &a.accessKey,
+		"secret_key":    &a.secretKey,
+		"session_token": &a.sessionToken,
+		"header_value":  &a.headerValue,
+	}
+	for k, v := range values {
+		raw, ok := conf.Config[k]
+		if !ok {
+			continue
+		}
+		s, ok := raw.(string)
+		if !ok {
+			return nil, fmt.Errorf("could not convert '%s' config value to string", k)
+		}
+		*v = s
+	}
+
+	if a.role == "" {
+		return nil, errors.New("'role' value is empty")
+	}
+
+	a.authType = strings.ToLower(a.authType)
+	switch a.authType {
+	case "":
+		a.authType = typeIAM
+	case typeEC2, typeIAM:
+	default:
+		return nil, fmt.Errorf("unknown auth type %q, must be %q or %q", a.authType, typeEC2, typeIAM)
+	}
+
+	if a.authType == typeEC2 && (a.accessKey != "" || a.secretKey != "" || a.sessionToken != "" || a.headerValue != "") {
+		return nil, errors.New("credentials and 'header_value' can only be set with the iam auth type")
+	}
+
+	return a, nil
+}
+
+func (a *awsMethod) Authenticate(client *api.Client) (string, map[string]interface{}, error) {
+	var data map[string]interface{}
+
+	switch a.authType {
+	case typeEC2:
+		sess, err := session.NewSession()
+		if err != nil {
+			return "", nil, errwrap.Wrapf("error creating session to probe EC2 metadata: {{err}}", err)
+		}
+		metadataSvc := ec2metadata.New(sess)
+		if !metadataSvc.Available() {
+			return "", nil, errors.New("session available, but ec2 metadata service is not")
+		}
+
+		pkcs7, err := metadataSvc.GetDynamicData("/instance-identity/pkcs7")
+		if err != nil {
+			return "", nil, errwrap.Wrapf("error retrieving pkcs7 from ec2 metadata: {{err}}", err)
+		}
+
+		a.l.Lock()
+		if a.nonce == "" {
+			a.nonce, err = uuid.GenerateUUID()
+			if err != nil {
+				a.l.Unlock()
+				return "", nil, errwrap.Wrapf("error generating nonce: {{err}}", err)
+			}
+		}
+		nonce := a.nonce
+		a.l.Unlock()
+
+		data = map[string]interface{}{
+			"pkcs7": strings.Replace(strings.TrimSpace(pkcs7), "\n", "", -1),
+			"nonce": nonce,
+		}
+
+	default:
+		var err error
+		data, err = awsauth.GenerateLoginData(a.accessKey, a.secretKey, a.sessionToken, a.headerValue)
+		if err != nil {
+			return "", nil, errwrap.Wrapf("error creating login value: {{err}}", err)
+		}
+	}
+
+	data["role"] = a.role
+
+	return fmt.Sprintf("%s/login", a.mountPath), data, nil
+}
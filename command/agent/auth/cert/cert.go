@@ -0,0 +1,49 @@
+package cert
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/command/agent/auth"
+	log "github.com/mgutz/logxi/v1"
+)
+
+type certMethod struct {
+	logger    log.Logger
+	mountPath string
+	name      string
+}
+
+// NewCertAuthMethod returns an AuthMethod logging in with the client
+// certificate the agent is configured to connect to Vault with
+func NewCertAuthMethod(conf *auth.AuthConfig) (auth.AuthMethod, error) {
+	if conf == nil {
+		return nil, errors.New("empty config")
+	}
+
+	c := &certMethod{
+		logger:    conf.Logger,
+		mountPath: conf.MountPath,
+	}
+
+	if conf.Config != nil {
+		if nameRaw, ok := conf.Config["name"]; ok {
+			c.name, ok = nameRaw.(string)
+			if !ok {
+				return nil, errors.New("could not convert 'name' config value to string")
+			}
+		}
+	}
+
+	return c, nil
+}
+
+func (c *certMethod) Authenticate(client *api.Client) (string, map[string]interface{}, error) {
+	data := map[string]interface{}{}
+	if c.name != "" {
+		data["name"] = c.name
+	}
+
+	return fmt.Sprintf("%s/login", c.mountPath), data, nil
+}
@@ -0,0 +1,80 @@
+package jwt
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/command/agent/auth"
+	log "github.com/mgutz/logxi/v1"
+)
+
+type jwtMethod struct {
+	logger    log.Logger
+	mountPath string
+	role      string
+	path      string
+}
+
+// NewJWTAuthMethod returns an AuthMethod logging in with a JWT read from a
+// file. The file is read again on every login, so that it can be updated
+// with fresh tokens.
+func NewJWTAuthMethod(conf *auth.AuthConfig) (auth.AuthMethod, error) {
+	if conf == nil {
+		return nil, errors.New("empty config")
+	}
+	if conf.Config == nil {
+		return nil, errors.New("empty config data")
+	}
+
+	j := &jwtMethod{
+		logger:    conf.Logger,
+		mountPath: conf.MountPath,
+	}
+
+	pathRaw, ok := conf.Config["path"]
+	if !ok {
+		return nil, errors.New("missing 'path' value")
+	}
+	j.path, ok = pathRaw.(string)
+	if !ok {
+		return nil, errors.New("could not convert 'path' config value to string")
+	}
+	if j.path == "" {
+		return nil, errors.New("'path' value is empty")
+	}
+
+	roleRaw, ok := conf.Config["role"]
+	if !ok {
+		return nil, errors.New("missing 'role' value")
+	}
+	j.role, ok = roleRaw.(string)
+	if !ok {
+		return nil, errors.New("could not convert 'role' config value to string")
+	}
+	if j.role == "" {
+		return nil, errors.New("'role' value is empty")
+	}
+
+	return j, nil
+}
+
+func (j *jwtMethod) Authenticate(client *api.Client) (string, map[string]interface{}, error) {
+	raw, err := ioutil.ReadFile(j.path)
+	if err != nil {
+		return "", nil, errwrap.Wrapf("error reading JWT file: {{err}}", err)
+	}
+
+	token := strings.TrimSpace(string(raw))
+	if token == "" {
+		return "", nil, errors.New("JWT file is empty")
+	}
+
+	return fmt.Sprintf("%s/login", j.mountPath), map[string]interface{}{
+		"role": j.role,
+		"jwt":  token,
+	}, nil
+}
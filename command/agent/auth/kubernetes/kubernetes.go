@@ -0,0 +1,74 @@
+package kubernetes
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/command/agent/auth"
+	log "github.com/mgutz/logxi/v1"
+)
+
+// serviceAccountFile is where Kubernetes mounts the token of the service
+// account of a pod
+const serviceAccountFile = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+type kubernetesMethod struct {
+	logger    log.Logger
+	mountPath string
+	role      string
+	tokenPath string
+}
+
+// NewKubernetesAuthMethod returns an AuthMethod logging in with the service
+// account token of the pod the agent runs in
+func NewKubernetesAuthMethod(conf *auth.AuthConfig) (auth.AuthMethod, error) {
+	if conf == nil {
+		return nil, errors.New("empty config")
+	}
+	if conf.Config == nil {
+		return nil, errors.New("empty config data")
+	}
+
+	k := &kubernetesMethod{
+		logger:    conf.Logger,
+		mountPath: conf.MountPath,
+		tokenPath: serviceAccountFile,
+	}
+
+	roleRaw, ok := conf.Config["role"]
+	if !ok {
+		return nil, errors.New("missing 'role' value")
+	}
+	k.role, ok = roleRaw.(string)
+	if !ok {
+		return nil, errors.New("could not convert 'role' config value to string")
+	}
+	if k.role == "" {
+		return nil, errors.New("'role' value is empty")
+	}
+
+	if tokenPathRaw, ok := conf.Config["token_path"]; ok {
+		k.tokenPath, ok = tokenPathRaw.(string)
+		if !ok {
+			return nil, errors.New("could not convert 'token_path' config value to string")
+		}
+	}
+
+	return k, nil
+}
+
+func (k *kubernetesMethod) Authenticate(client *api.Client) (string, map[string]interface{}, error) {
+	raw, err := ioutil.ReadFile(k.tokenPath)
+	if err != nil {
+		return "", nil, errwrap.Wrapf("error reading service account token: {{err}}", err)
+	}
+
+	return fmt.Sprintf("%s/login", k.mountPath), map[string]interface{}{
+		"role": k.role,
+		"jwt":  strings.TrimSpace(string(raw)),
+	}, nil
+}
@@ -0,0 +1,66 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/hashicorp/vault/command/agent/sink"
+	log "github.com/mgutz/logxi/v1"
+)
+
+// maxRequestSize is the maximum size of the body of a request, the same as
+// the one enforced by Vault
+const maxRequestSize = 32 * 1024 * 1024
+
+// Handler returns an http.Handler sending the requests it receives with the
+// given Proxier. If tokenSink is not nil, requests without a token are sent
+// with the latest token written to it by auto-auth.
+func Handler(logger log.Logger, proxier Proxier, tokenSink sink.SinkReader) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logger.Trace("cache.handler: received request", "method", r.Method, "path", r.URL.Path)
+
+		token := r.Header.Get("X-Vault-Token")
+		if token == "" && tokenSink != nil {
+			token = tokenSink.Token()
+		}
+
+		body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestSize))
+		if err != nil {
+			respondError(w, http.StatusBadRequest, fmt.Errorf("failed to read request body: %v", err))
+			return
+		}
+
+		resp, err := proxier.Send(&SendRequest{
+			Token:       token,
+			Request:     r,
+			RequestBody: body,
+		})
+		if err != nil {
+			logger.Error("cache.handler: error sending request", "error", err)
+			respondError(w, http.StatusBadGateway, err)
+			return
+		}
+
+		for k, v := range resp.Header {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(resp.StatusCode)
+		w.Write(resp.Body)
+	})
+}
+
+func respondError(w http.ResponseWriter, status int, err error) {
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	resp := struct {
+		Errors []string `json:"errors"`
+	}{
+		Errors: []string{err.Error()},
+	}
+
+	enc := json.NewEncoder(w)
+	enc.Encode(resp)
+}
@@ -0,0 +1,373 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/helper/jsonutil"
+	log "github.com/mgutz/logxi/v1"
+)
+
+// cacheEntry is a cached response of Vault containing a lease or a token
+type cacheEntry struct {
+	key string
+
+	// token is the token the request was made with
+	token string
+
+	// leaseID is the ID of the lease of the response, if any
+	leaseID string
+
+	// clientToken and accessor are the token, and its accessor, issued in
+	// the response, if any
+	clientToken string
+	accessor    string
+
+	response *SendResponse
+	cancel   context.CancelFunc
+}
+
+// LeaseCache is a Proxier caching the responses of Vault that contain leased
+// secrets or tokens. Cached leases and tokens are renewed for as long as
+// possible, and evicted when they expire or are revoked through the agent.
+type LeaseCache struct {
+	proxier Proxier
+	client  *api.Client
+	logger  log.Logger
+	baseCtx context.Context
+
+	l       sync.RWMutex
+	entries map[string]*cacheEntry
+}
+
+// LeaseCacheConfig is the configuration of a LeaseCache
+type LeaseCacheConfig struct {
+	Client      *api.Client
+	BaseContext context.Context
+	Proxier     Proxier
+	Logger      log.Logger
+}
+
+// NewLeaseCache returns a LeaseCache for the given configuration
+func NewLeaseCache(conf *LeaseCacheConfig) (*LeaseCache, error) {
+	if conf == nil {
+		return nil, errors.New("nil configuration provided")
+	}
+	if conf.Proxier == nil || conf.Logger == nil {
+		return nil, errors.New("missing configuration required params")
+	}
+	if conf.Client == nil {
+		return nil, errors.New("nil API client")
+	}
+
+	baseCtx := conf.BaseContext
+	if baseCtx == nil {
+		baseCtx = context.Background()
+	}
+
+	return &LeaseCache{
+		proxier: conf.Proxier,
+		client:  conf.Client,
+		logger:  conf.Logger,
+		baseCtx: baseCtx,
+		entries: make(map[string]*cacheEntry),
+	}, nil
+}
+
+// Send returns the cached response of the request if there is one, and
+// otherwise forwards the request to Vault, caching its response if it
+// contains a lease or a token
+func (c *LeaseCache) Send(req *SendRequest) (*SendResponse, error) {
+	key := computeKey(req)
+
+	c.l.RLock()
+	entry, ok := c.entries[key]
+	c.l.RUnlock()
+	if ok {
+		c.logger.Debug("cache: returning cached response", "path", req.Request.URL.Path)
+		return entry.response, nil
+	}
+
+	resp, err := c.proxier.Send(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		c.handleRevocation(req)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return resp, nil
+	}
+
+	secret, err := api.ParseSecret(bytes.NewReader(resp.Body))
+	if err != nil || secret == nil || secret.WrapInfo != nil {
+		// Wrapped responses can only be unwrapped once, so are never
+		// cached
+		return resp, nil
+	}
+
+	entry = &cacheEntry{
+		key:      key,
+		token:    req.Token,
+		response: resp,
+	}
+
+	switch {
+	case secret.LeaseID != "":
+		entry.leaseID = secret.LeaseID
+	case secret.Auth != nil && secret.Auth.ClientToken != "":
+		entry.clientToken = secret.Auth.ClientToken
+		entry.accessor = secret.Auth.Accessor
+	default:
+		return resp, nil
+	}
+
+	ctx, cancel := context.WithCancel(c.baseCtx)
+	entry.cancel = cancel
+
+	c.l.Lock()
+	if _, ok := c.entries[key]; ok {
+		// A concurrent request cached its response first
+		c.l.Unlock()
+		cancel()
+		return resp, nil
+	}
+	c.entries[key] = entry
+	c.l.Unlock()
+
+	c.logger.Debug("cache: cached response", "path", req.Request.URL.Path)
+	go c.watch(ctx, entry, secret)
+
+	return resp, nil
+}
+
+// watch renews the lease or token of a cached entry, and evicts the entry
+// once it can no longer be renewed
+func (c *LeaseCache) watch(ctx context.Context, entry *cacheEntry, secret *api.Secret) {
+	defer c.evict(entry)
+
+	renewable, ttl := secret.Renewable, secret.LeaseDuration
+	if secret.Auth != nil {
+		renewable, ttl = secret.Auth.Renewable, secret.Auth.LeaseDuration
+	}
+
+	if !renewable {
+		if ttl == 0 {
+			<-ctx.Done()
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(time.Duration(ttl) * time.Second):
+		}
+		return
+	}
+
+	client, err := c.client.Clone()
+	if err != nil {
+		c.logger.Error("cache: error creating client for renewal", "error", err)
+		return
+	}
+	client.SetToken(entry.token)
+
+	renewer, err := client.NewRenewer(&api.RenewerInput{
+		Secret: secret,
+	})
+	if err != nil {
+		c.logger.Error("cache: error creating renewer", "error", err)
+		return
+	}
+	go renewer.Renew()
+	defer renewer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case err := <-renewer.DoneCh():
+			if err != nil {
+				c.logger.Debug("cache: error renewing cached secret, evicting", "error", err)
+			}
+			return
+		case <-renewer.RenewCh():
+			c.logger.Trace("cache: renewed cached secret")
+		}
+	}
+}
+
+// evict removes the entry from the cache and stops watching it
+func (c *LeaseCache) evict(entry *cacheEntry) {
+	c.l.Lock()
+	if c.entries[entry.key] == entry {
+		delete(c.entries, entry.key)
+	}
+	c.l.Unlock()
+
+	entry.cancel()
+}
+
+// evictWhere evicts all the entries matching the given function
+func (c *LeaseCache) evictWhere(match func(*cacheEntry) bool) {
+	var evicted []*cacheEntry
+
+	c.l.Lock()
+	for key, entry := range c.entries {
+		if match(entry) {
+			delete(c.entries, key)
+			evicted = append(evicted, entry)
+		}
+	}
+	c.l.Unlock()
+
+	for _, entry := range evicted {
+		entry.cancel()
+	}
+}
+
+// evictToken evicts the cached token, and the leases and tokens created
+// with it. If recursive is false, the tokens created with the token are
+// kept, as they are orphaned rather than revoked.
+func (c *LeaseCache) evictToken(token string, recursive bool) {
+	if token == "" {
+		return
+	}
+
+	revoked := map[string]struct{}{token: struct{}{}}
+	if recursive {
+		c.l.RLock()
+		for found := true; found; {
+			found = false
+			for _, entry := range c.entries {
+				if entry.clientToken == "" {
+					continue
+				}
+				if _, ok := revoked[entry.clientToken]; ok {
+					continue
+				}
+				if _, ok := revoked[entry.token]; ok {
+					revoked[entry.clientToken] = struct{}{}
+					found = true
+				}
+			}
+		}
+		c.l.RUnlock()
+	}
+
+	c.evictWhere(func(entry *cacheEntry) bool {
+		if _, ok := revoked[entry.clientToken]; ok {
+			return true
+		}
+		if _, ok := revoked[entry.token]; ok {
+			return entry.leaseID != "" || recursive
+		}
+		return false
+	})
+}
+
+// tokenForAccessor returns the cached token with the given accessor
+func (c *LeaseCache) tokenForAccessor(accessor string) string {
+	if accessor == "" {
+		return ""
+	}
+
+	c.l.RLock()
+	defer c.l.RUnlock()
+	for _, entry := range c.entries {
+		if entry.accessor == accessor {
+			return entry.clientToken
+		}
+	}
+	return ""
+}
+
+// handleRevocation evicts the entries of the leases and tokens revoked by
+// the request, which was successfully processed by Vault
+func (c *LeaseCache) handleRevocation(req *SendRequest) {
+	if req.Request.Method != "POST" && req.Request.Method != "PUT" {
+		return
+	}
+
+	path := strings.TrimPrefix(req.Request.URL.Path, "/v1/")
+
+	var body map[string]interface{}
+	if len(req.RequestBody) > 0 {
+		// A body that can't be parsed was rejected by Vault, and didn't
+		// revoke anything
+		if err := jsonutil.DecodeJSON(req.RequestBody, &body); err != nil {
+			return
+		}
+	}
+
+	// param returns the named parameter of the request, given either in
+	// the path after the prefix or in the body
+	param := func(prefix, name string) string {
+		if v := strings.TrimPrefix(strings.TrimPrefix(path, prefix), "/"); v != "" {
+			return v
+		}
+		v, _ := body[name].(string)
+		return v
+	}
+
+	switch {
+	case path == "auth/token/revoke-self":
+		c.evictToken(req.Token, true)
+
+	case strings.HasPrefix(path, "auth/token/revoke-accessor"):
+		token := c.tokenForAccessor(param("auth/token/revoke-accessor", "accessor"))
+		c.evictToken(token, true)
+
+	case strings.HasPrefix(path, "auth/token/revoke-orphan"):
+		c.evictToken(param("auth/token/revoke-orphan", "token"), false)
+
+	case strings.HasPrefix(path, "auth/token/revoke"):
+		c.evictToken(param("auth/token/revoke", "token"), true)
+
+	case strings.HasPrefix(path, "sys/revoke-prefix/"),
+		strings.HasPrefix(path, "sys/leases/revoke-prefix/"),
+		strings.HasPrefix(path, "sys/revoke-force/"),
+		strings.HasPrefix(path, "sys/leases/revoke-force/"):
+		prefix := path[strings.Index(path, "revoke-")+len("revoke-"):]
+		prefix = prefix[strings.Index(prefix, "/")+1:]
+		c.evictWhere(func(entry *cacheEntry) bool {
+			return entry.leaseID != "" && strings.HasPrefix(entry.leaseID, prefix)
+		})
+
+	case strings.HasPrefix(path, "sys/revoke"),
+		strings.HasPrefix(path, "sys/leases/revoke"):
+		leaseID := param(strings.SplitAfter(path, "revoke")[0], "lease_id")
+		if leaseID == "" {
+			return
+		}
+		c.evictWhere(func(entry *cacheEntry) bool {
+			return entry.leaseID == leaseID
+		})
+	}
+}
+
+// computeKey returns the cache key of the request, which is derived from
+// the token, method, path, query and body of the request
+func computeKey(req *SendRequest) string {
+	h := sha256.New()
+	for _, v := range []string{
+		req.Token,
+		req.Request.Method,
+		req.Request.URL.Path,
+		req.Request.URL.Query().Encode(),
+	} {
+		h.Write([]byte(v))
+		h.Write([]byte{0})
+	}
+	h.Write(req.RequestBody)
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,223 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/helper/logformat"
+	log "github.com/mgutz/logxi/v1"
+)
+
+// mockProxier returns the next of its responses on every request
+type mockProxier struct {
+	l         sync.Mutex
+	responses []*SendResponse
+	requests  int
+}
+
+func (p *mockProxier) Send(req *SendRequest) (*SendResponse, error) {
+	p.l.Lock()
+	defer p.l.Unlock()
+
+	if p.requests >= len(p.responses) {
+		return nil, fmt.Errorf("no response left for request %d", p.requests)
+	}
+	resp := p.responses[p.requests]
+	p.requests++
+	return resp, nil
+}
+
+func newResponse(status int, body string) *SendResponse {
+	return &SendResponse{
+		StatusCode: status,
+		Header:     http.Header{},
+		Body:       []byte(body),
+	}
+}
+
+func testLeaseCache(t *testing.T, responses ...*SendResponse) (*LeaseCache, *mockProxier, context.CancelFunc) {
+	client, err := api.NewClient(api.DefaultConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	proxier := &mockProxier{responses: responses}
+	lc, err := NewLeaseCache(&LeaseCacheConfig{
+		Client:      client,
+		BaseContext: ctx,
+		Proxier:     proxier,
+		Logger:      logformat.NewVaultLogger(log.LevelTrace),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return lc, proxier, cancel
+}
+
+func testRequest(t *testing.T, token, method, path, body string) *SendRequest {
+	return &SendRequest{
+		Token:       token,
+		Request:     httptest.NewRequest(method, path, strings.NewReader(body)),
+		RequestBody: []byte(body),
+	}
+}
+
+func TestLeaseCache_Send(t *testing.T) {
+	lc, proxier, cancel := testLeaseCache(t,
+		newResponse(http.StatusOK, `{"lease_id": "foo/creds/bar/1", "lease_duration": 600, "data": {"value": "first"}}`),
+		newResponse(http.StatusOK, `{"data": {"value": "not leased"}}`),
+		newResponse(http.StatusOK, `{"data": {"value": "not leased"}}`),
+		newResponse(http.StatusBadRequest, `{"errors": ["bad request"]}`),
+		newResponse(http.StatusOK, `{"lease_id": "foo/creds/bar/2", "lease_duration": 600, "data": {"value": "other token"}}`),
+	)
+	defer cancel()
+
+	// Leased responses are cached
+	for i := 0; i < 2; i++ {
+		resp, err := lc.Send(testRequest(t, "token", "GET", "/v1/foo/creds/bar", ""))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(resp.Body), "first") {
+			t.Fatalf("bad: %s", resp.Body)
+		}
+	}
+	if proxier.requests != 1 {
+		t.Fatalf("expected 1 request to be proxied, got %d", proxier.requests)
+	}
+
+	// Responses without leases are not cached
+	for i := 0; i < 2; i++ {
+		if _, err := lc.Send(testRequest(t, "token", "GET", "/v1/secret/foo", "")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if proxier.requests != 3 {
+		t.Fatalf("expected 3 requests to be proxied, got %d", proxier.requests)
+	}
+
+	// Error responses are returned as is
+	resp, err := lc.Send(testRequest(t, "token", "GET", "/v1/foo/creds/baz", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	// The cache key includes the token
+	resp, err = lc.Send(testRequest(t, "other", "GET", "/v1/foo/creds/bar", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(resp.Body), "other token") {
+		t.Fatalf("bad: %s", resp.Body)
+	}
+}
+
+func TestLeaseCache_RevokeLease(t *testing.T) {
+	lc, _, cancel := testLeaseCache(t,
+		newResponse(http.StatusOK, `{"lease_id": "foo/creds/bar/1", "lease_duration": 600, "data": {}}`),
+		newResponse(http.StatusOK, `{"lease_id": "foo/creds/baz/1", "lease_duration": 600, "data": {}}`),
+		newResponse(http.StatusNoContent, ``),
+		newResponse(http.StatusOK, `{"lease_id": "foo/creds/bar/2", "lease_duration": 600, "data": {}}`),
+		newResponse(http.StatusNoContent, ``),
+		newResponse(http.StatusOK, `{"lease_id": "foo/creds/baz/2", "lease_duration": 600, "data": {}}`),
+	)
+	defer cancel()
+
+	send := func(method, path, body string) *SendResponse {
+		resp, err := lc.Send(testRequest(t, "token", method, path, body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	send("GET", "/v1/foo/creds/bar", "")
+	send("GET", "/v1/foo/creds/baz", "")
+
+	// Revoking a lease by ID evicts it
+	send("PUT", "/v1/sys/leases/revoke", `{"lease_id": "foo/creds/bar/1"}`)
+	if resp := send("GET", "/v1/foo/creds/bar", ""); !strings.Contains(string(resp.Body), "foo/creds/bar/2") {
+		t.Fatalf("expected lease to be evicted, got: %s", resp.Body)
+	}
+	if resp := send("GET", "/v1/foo/creds/baz", ""); !strings.Contains(string(resp.Body), "foo/creds/baz/1") {
+		t.Fatalf("expected lease to be cached, got: %s", resp.Body)
+	}
+
+	// Revoking by prefix evicts all the leases under the prefix
+	send("PUT", "/v1/sys/revoke-prefix/foo/creds/baz", "")
+	if resp := send("GET", "/v1/foo/creds/baz", ""); !strings.Contains(string(resp.Body), "foo/creds/baz/2") {
+		t.Fatalf("expected lease to be evicted, got: %s", resp.Body)
+	}
+	if resp := send("GET", "/v1/foo/creds/bar", ""); !strings.Contains(string(resp.Body), "foo/creds/bar/2") {
+		t.Fatalf("expected lease to be cached, got: %s", resp.Body)
+	}
+}
+
+func TestLeaseCache_RevokeToken(t *testing.T) {
+	lc, _, cancel := testLeaseCache(t,
+		// The child token is created by the parent, and creates a lease
+		newResponse(http.StatusOK, `{"auth": {"client_token": "child", "accessor": "child-accessor", "lease_duration": 600}}`),
+		newResponse(http.StatusOK, `{"lease_id": "foo/creds/bar/1", "lease_duration": 600, "data": {}}`),
+		newResponse(http.StatusOK, `{"lease_id": "foo/creds/baz/1", "lease_duration": 600, "data": {}}`),
+	)
+	defer cancel()
+
+	if _, err := lc.Send(testRequest(t, "parent", "POST", "/v1/auth/token/create", "")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := lc.Send(testRequest(t, "child", "GET", "/v1/foo/creds/bar", "")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := lc.Send(testRequest(t, "parent", "GET", "/v1/foo/creds/baz", "")); err != nil {
+		t.Fatal(err)
+	}
+	if len(lc.entries) != 3 {
+		t.Fatalf("expected 3 cached entries, got %d", len(lc.entries))
+	}
+
+	// Orphaning revocation of the parent only evicts its own leases
+	lc.handleRevocation(testRequest(t, "root", "PUT", "/v1/auth/token/revoke-orphan", `{"token": "parent"}`))
+	if len(lc.entries) != 2 {
+		t.Fatalf("expected 2 cached entries, got %d", len(lc.entries))
+	}
+
+	// Revoking the child by accessor evicts it and its leases
+	lc.handleRevocation(testRequest(t, "root", "POST", "/v1/auth/token/revoke-accessor/child-accessor", ""))
+	if len(lc.entries) != 0 {
+		t.Fatalf("expected no cached entries, got %d", len(lc.entries))
+	}
+}
+
+func TestLeaseCache_RevokeTokenRecursive(t *testing.T) {
+	lc, _, cancel := testLeaseCache(t,
+		newResponse(http.StatusOK, `{"auth": {"client_token": "child", "lease_duration": 600}}`),
+		newResponse(http.StatusOK, `{"auth": {"client_token": "grandchild", "lease_duration": 600}}`),
+		newResponse(http.StatusOK, `{"lease_id": "foo/creds/bar/1", "lease_duration": 600, "data": {}}`),
+	)
+	defer cancel()
+
+	for _, token := range []string{"parent", "child"} {
+		if _, err := lc.Send(testRequest(t, token, "POST", "/v1/auth/token/create", "")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := lc.Send(testRequest(t, "grandchild", "GET", "/v1/foo/creds/bar", "")); err != nil {
+		t.Fatal(err)
+	}
+
+	lc.handleRevocation(testRequest(t, "parent", "PUT", "/v1/auth/token/revoke-self", ""))
+	if len(lc.entries) != 0 {
+		t.Fatalf("expected no cached entries, got %d", len(lc.entries))
+	}
+}
@@ -0,0 +1,118 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/api"
+	log "github.com/mgutz/logxi/v1"
+)
+
+// hopHeaders are the headers of the received request that only apply to its
+// connection to the agent, and are not forwarded to Vault
+var hopHeaders = map[string]struct{}{
+	"Connection":          struct{}{},
+	"Content-Length":      struct{}{},
+	"Keep-Alive":          struct{}{},
+	"Proxy-Authenticate":  struct{}{},
+	"Proxy-Authorization": struct{}{},
+	"Te":                  struct{}{},
+	"Trailer":             struct{}{},
+	"Transfer-Encoding":   struct{}{},
+	"Upgrade":             struct{}{},
+}
+
+// SendRequest is a request received by the agent, to be sent to Vault
+type SendRequest struct {
+	Token       string
+	Request     *http.Request
+	RequestBody []byte
+}
+
+// SendResponse is the response of Vault to a SendRequest. Responses may be
+// returned from the cache, so they must not be modified.
+type SendResponse struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// Proxier is the interface implemented by the types that send requests to
+// Vault on behalf of the agent
+type Proxier interface {
+	Send(*SendRequest) (*SendResponse, error)
+}
+
+// APIProxy is a Proxier forwarding requests to Vault with an API client
+type APIProxy struct {
+	client *api.Client
+	logger log.Logger
+}
+
+// APIProxyConfig is the configuration of an APIProxy
+type APIProxyConfig struct {
+	Client *api.Client
+	Logger log.Logger
+}
+
+// NewAPIProxy returns an APIProxy for the given configuration
+func NewAPIProxy(conf *APIProxyConfig) *APIProxy {
+	return &APIProxy{
+		client: conf.Client,
+		logger: conf.Logger,
+	}
+}
+
+func (ap *APIProxy) Send(req *SendRequest) (*SendResponse, error) {
+	client, err := ap.client.Clone()
+	if err != nil {
+		return nil, err
+	}
+	client.SetToken(req.Token)
+
+	// The wrap TTL of the request is forwarded with its headers, so the
+	// client must not set one of its own
+	client.SetWrappingLookupFunc(func(string, string) string {
+		return ""
+	})
+
+	fwReq := client.NewRequest(req.Request.Method, req.Request.URL.Path)
+	fwReq.Params = req.Request.URL.Query()
+	fwReq.Headers = make(http.Header, len(req.Request.Header))
+	for k, v := range req.Request.Header {
+		if _, ok := hopHeaders[k]; ok {
+			continue
+		}
+		fwReq.Headers[k] = v
+	}
+	if len(req.RequestBody) > 0 {
+		// Setting the body as JSON allows it to be sent again if Vault
+		// redirects the request
+		if err := fwReq.SetJSONBody(json.RawMessage(req.RequestBody)); err != nil {
+			fwReq.Body = bytes.NewReader(req.RequestBody)
+			fwReq.BodySize = int64(len(req.RequestBody))
+		}
+	}
+
+	// Error responses are returned as is to the client, so the error of the
+	// request only matters if there is no response
+	resp, err := client.RawRequest(fwReq)
+	if resp == nil {
+		return nil, errwrap.Wrapf("error forwarding request to Vault: {{err}}", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errwrap.Wrapf("error reading response from Vault: {{err}}", err)
+	}
+
+	return &SendResponse{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Body:       body,
+	}, nil
+}
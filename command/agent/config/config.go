@@ -0,0 +1,533 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/hcl"
+	"github.com/hashicorp/hcl/hcl/ast"
+	"github.com/hashicorp/vault/helper/parseutil"
+)
+
+const (
+	// defaultTemplatePerms is the mode of rendered templates if none is
+	// configured
+	defaultTemplatePerms os.FileMode = 0644
+
+	// defaultTemplateCommandTimeout is how long the command of a template
+	// may run if no timeout is configured
+	defaultTemplateCommandTimeout = 30 * time.Second
+)
+
+// Config is the configuration for the vault agent.
+type Config struct {
+	AutoAuth  *AutoAuth   `hcl:"-"`
+	Cache     *Cache      `hcl:"-"`
+	Listeners []*Listener `hcl:"-"`
+	Templates []*Template `hcl:"-"`
+	Vault     *Vault      `hcl:"-"`
+
+	PidFile string `hcl:"pid_file"`
+}
+
+// Vault contains configuration for connecting to the Vault server.
+type Vault struct {
+	Address          string      `hcl:"address"`
+	CACert           string      `hcl:"ca_cert"`
+	CAPath           string      `hcl:"ca_path"`
+	ClientCert       string      `hcl:"client_cert"`
+	ClientKey        string      `hcl:"client_key"`
+	TLSSkipVerify    bool        `hcl:"-"`
+	TLSSkipVerifyRaw interface{} `hcl:"tls_skip_verify"`
+}
+
+// AutoAuth is the configured authentication method and sinks
+type AutoAuth struct {
+	Method *Method `hcl:"-"`
+	Sinks  []*Sink `hcl:"-"`
+}
+
+// Method represents the configuration for the authentication backend
+type Method struct {
+	Type      string
+	MountPath string                 `hcl:"mount_path"`
+	Config    map[string]interface{} `hcl:"config"`
+}
+
+// Sink defines a location to write the authenticated token
+type Sink struct {
+	Type       string
+	WrapTTLRaw interface{}            `hcl:"wrap_ttl"`
+	WrapTTL    time.Duration          `hcl:"-"`
+	Config     map[string]interface{} `hcl:"config"`
+}
+
+// Cache contains the configuration of the caching proxy
+type Cache struct {
+	UseAutoAuthToken    bool        `hcl:"-"`
+	UseAutoAuthTokenRaw interface{} `hcl:"use_auto_auth_token"`
+}
+
+// Listener is a listener the caching proxy serves requests on
+type Listener struct {
+	Type   string
+	Config map[string]interface{}
+}
+
+// Template is a template rendered with the data of Vault to a file
+type Template struct {
+	Source      string `hcl:"source"`
+	Contents    string `hcl:"contents"`
+	Destination string `hcl:"destination"`
+
+	Perms    os.FileMode `hcl:"-"`
+	PermsRaw interface{} `hcl:"perms"`
+
+	Command           string        `hcl:"command"`
+	CommandTimeout    time.Duration `hcl:"-"`
+	CommandTimeoutRaw interface{}   `hcl:"command_timeout"`
+}
+
+// LoadConfig loads the configuration at the given path
+func LoadConfig(path string) (*Config, error) {
+	d, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseConfig(string(d))
+}
+
+// ParseConfig parses the given configuration string
+func ParseConfig(d string) (*Config, error) {
+	// Parse!
+	obj, err := hcl.Parse(d)
+	if err != nil {
+		return nil, err
+	}
+
+	// Start building the result
+	var result Config
+	if err := hcl.DecodeObject(&result, obj); err != nil {
+		return nil, err
+	}
+
+	list, ok := obj.Node.(*ast.ObjectList)
+	if !ok {
+		return nil, fmt.Errorf("error parsing: file doesn't contain a root object")
+	}
+
+	valid := []string{
+		"pid_file",
+		"vault",
+		"auto_auth",
+		"cache",
+		"listener",
+		"template",
+	}
+	if err := checkHCLKeys(list, valid); err != nil {
+		return nil, err
+	}
+
+	if err := parseVault(&result, list); err != nil {
+		return nil, fmt.Errorf("error parsing 'vault': %s", err)
+	}
+
+	if err := parseAutoAuth(&result, list); err != nil {
+		return nil, fmt.Errorf("error parsing 'auto_auth': %s", err)
+	}
+
+	if err := parseCache(&result, list); err != nil {
+		return nil, fmt.Errorf("error parsing 'cache': %s", err)
+	}
+
+	if o := list.Filter("listener"); len(o.Items) > 0 {
+		if err := parseListeners(&result, o); err != nil {
+			return nil, fmt.Errorf("error parsing 'listener': %s", err)
+		}
+	}
+
+	if o := list.Filter("template"); len(o.Items) > 0 {
+		if err := parseTemplates(&result, o); err != nil {
+			return nil, fmt.Errorf("error parsing 'template': %s", err)
+		}
+	}
+
+	if len(result.Templates) > 0 && result.AutoAuth == nil {
+		return nil, fmt.Errorf("'auto_auth' must be configured to render templates")
+	}
+
+	if result.Cache != nil {
+		if len(result.Listeners) == 0 {
+			return nil, fmt.Errorf("at least one listener must be defined when the cache is enabled")
+		}
+		if result.Cache.UseAutoAuthToken && result.AutoAuth == nil {
+			return nil, fmt.Errorf("'use_auto_auth_token' is set but 'auto_auth' is not configured")
+		}
+	} else if len(result.Listeners) > 0 {
+		return nil, fmt.Errorf("listeners can only be defined when the cache is enabled")
+	}
+
+	switch {
+	case result.AutoAuth == nil && result.Cache == nil:
+		return nil, fmt.Errorf("at least one of 'auto_auth' or 'cache' must be configured")
+	case result.AutoAuth != nil && len(result.AutoAuth.Sinks) == 0 && result.Cache == nil && len(result.Templates) == 0:
+		return nil, fmt.Errorf("at least one 'sink' block must be provided if neither the cache nor templates are enabled")
+	}
+
+	return &result, nil
+}
+
+func parseVault(result *Config, list *ast.ObjectList) error {
+	name := "vault"
+
+	vaultList := list.Filter(name)
+	if len(vaultList.Items) == 0 {
+		return nil
+	}
+	if len(vaultList.Items) > 1 {
+		return fmt.Errorf("one and only one %q block is allowed", name)
+	}
+
+	item := vaultList.Items[0]
+
+	valid := []string{
+		"address",
+		"ca_cert",
+		"ca_path",
+		"client_cert",
+		"client_key",
+		"tls_skip_verify",
+	}
+	if err := checkHCLKeys(item.Val, valid); err != nil {
+		return multierror.Prefix(err, fmt.Sprintf("%s:", name))
+	}
+
+	var v Vault
+	if err := hcl.DecodeObject(&v, item.Val); err != nil {
+		return err
+	}
+
+	if v.TLSSkipVerifyRaw != nil {
+		var err error
+		if v.TLSSkipVerify, err = parseutil.ParseBool(v.TLSSkipVerifyRaw); err != nil {
+			return err
+		}
+	}
+
+	result.Vault = &v
+	return nil
+}
+
+func parseAutoAuth(result *Config, list *ast.ObjectList) error {
+	name := "auto_auth"
+
+	autoAuthList := list.Filter(name)
+	if len(autoAuthList.Items) == 0 {
+		return nil
+	}
+	if len(autoAuthList.Items) > 1 {
+		return fmt.Errorf("one and only one %q block is allowed", name)
+	}
+
+	// Get our item
+	item := autoAuthList.Items[0]
+
+	valid := []string{
+		"method",
+		"sink",
+	}
+	if err := checkHCLKeys(item.Val, valid); err != nil {
+		return multierror.Prefix(err, fmt.Sprintf("%s:", name))
+	}
+
+	var a AutoAuth
+	result.AutoAuth = &a
+
+	subs, ok := item.Val.(*ast.ObjectType)
+	if !ok {
+		return fmt.Errorf("could not parse %q as an object", name)
+	}
+	subList := subs.List
+
+	if err := parseMethod(result, subList); err != nil {
+		return fmt.Errorf("error parsing 'method': %s", err)
+	}
+
+	if err := parseSinks(result, subList); err != nil {
+		return fmt.Errorf("error parsing 'sink' stanzas: %s", err)
+	}
+
+	return nil
+}
+
+func parseMethod(result *Config, list *ast.ObjectList) error {
+	name := "method"
+
+	methodList := list.Filter(name)
+	if len(methodList.Items) != 1 {
+		return fmt.Errorf("one and only one %q block is required", name)
+	}
+
+	// Get our item
+	item := methodList.Items[0]
+	if len(item.Keys) == 0 {
+		return fmt.Errorf("%q block must have a type", name)
+	}
+
+	valid := []string{
+		"mount_path",
+		"config",
+	}
+	if err := checkHCLKeys(item.Val, valid); err != nil {
+		return multierror.Prefix(err, fmt.Sprintf("%s:", name))
+	}
+
+	var m Method
+	if err := hcl.DecodeObject(&m, item.Val); err != nil {
+		return err
+	}
+
+	m.Type = strings.ToLower(item.Keys[0].Token.Value().(string))
+
+	// Default to the mount path the method is enabled at by default
+	if m.MountPath == "" {
+		m.MountPath = fmt.Sprintf("auth/%s", m.Type)
+	}
+	m.MountPath = strings.TrimSuffix(m.MountPath, "/")
+	if m.Config == nil {
+		m.Config = make(map[string]interface{})
+	}
+
+	result.AutoAuth.Method = &m
+	return nil
+}
+
+func parseSinks(result *Config, list *ast.ObjectList) error {
+	name := "sink"
+
+	sinkList := list.Filter(name)
+	if len(sinkList.Items) < 1 {
+		return nil
+	}
+
+	var ts []*Sink
+
+	for _, item := range sinkList.Items {
+		if len(item.Keys) == 0 {
+			return fmt.Errorf("%q block must have a type", name)
+		}
+
+		valid := []string{
+			"wrap_ttl",
+			"config",
+		}
+		if err := checkHCLKeys(item.Val, valid); err != nil {
+			return multierror.Prefix(err, fmt.Sprintf("%s:", name))
+		}
+
+		var s Sink
+		if err := hcl.DecodeObject(&s, item.Val); err != nil {
+			return err
+		}
+
+		s.Type = strings.ToLower(item.Keys[0].Token.Value().(string))
+
+		if s.WrapTTLRaw != nil {
+			var err error
+			if s.WrapTTL, err = parseutil.ParseDurationSecond(s.WrapTTLRaw); err != nil {
+				return multierror.Prefix(err, fmt.Sprintf("%s.%s:", name, s.Type))
+			}
+		}
+		if s.Config == nil {
+			s.Config = make(map[string]interface{})
+		}
+
+		ts = append(ts, &s)
+	}
+
+	result.AutoAuth.Sinks = ts
+	return nil
+}
+
+func parseCache(result *Config, list *ast.ObjectList) error {
+	name := "cache"
+
+	cacheList := list.Filter(name)
+	if len(cacheList.Items) == 0 {
+		return nil
+	}
+	if len(cacheList.Items) > 1 {
+		return fmt.Errorf("one and only one %q block is allowed", name)
+	}
+
+	item := cacheList.Items[0]
+
+	valid := []string{
+		"use_auto_auth_token",
+	}
+	if err := checkHCLKeys(item.Val, valid); err != nil {
+		return multierror.Prefix(err, fmt.Sprintf("%s:", name))
+	}
+
+	var c Cache
+	if err := hcl.DecodeObject(&c, item.Val); err != nil {
+		return err
+	}
+
+	if c.UseAutoAuthTokenRaw != nil {
+		var err error
+		if c.UseAutoAuthToken, err = parseutil.ParseBool(c.UseAutoAuthTokenRaw); err != nil {
+			return err
+		}
+	}
+
+	result.Cache = &c
+	return nil
+}
+
+func parseListeners(result *Config, list *ast.ObjectList) error {
+	listeners := make([]*Listener, 0, len(list.Items))
+	for _, item := range list.Items {
+		key := "listener"
+		if len(item.Keys) > 0 {
+			key = item.Keys[0].Token.Value().(string)
+		}
+
+		valid := []string{
+			"address",
+			"tls_disable",
+			"tls_cert_file",
+			"tls_key_file",
+			"tls_min_version",
+			"tls_cipher_suites",
+			"tls_prefer_server_cipher_suites",
+			"tls_require_and_verify_client_cert",
+			"tls_disable_client_certs",
+			"tls_client_ca_file",
+		}
+		if err := checkHCLKeys(item.Val, valid); err != nil {
+			return multierror.Prefix(err, fmt.Sprintf("listeners.%s:", key))
+		}
+
+		var m map[string]interface{}
+		if err := hcl.DecodeObject(&m, item.Val); err != nil {
+			return multierror.Prefix(err, fmt.Sprintf("listeners.%s:", key))
+		}
+
+		lnType := strings.ToLower(key)
+		switch lnType {
+		case "tcp":
+		default:
+			return fmt.Errorf("unsupported listener type %q", lnType)
+		}
+
+		if _, ok := m["address"]; !ok {
+			return fmt.Errorf("listeners.%s: 'address' must be set", key)
+		}
+
+		listeners = append(listeners, &Listener{
+			Type:   lnType,
+			Config: m,
+		})
+	}
+
+	result.Listeners = listeners
+	return nil
+}
+
+func parseTemplates(result *Config, list *ast.ObjectList) error {
+	templates := make([]*Template, 0, len(list.Items))
+	for i, item := range list.Items {
+		key := fmt.Sprintf("template.%d:", i)
+
+		valid := []string{
+			"source",
+			"contents",
+			"destination",
+			"perms",
+			"command",
+			"command_timeout",
+		}
+		if err := checkHCLKeys(item.Val, valid); err != nil {
+			return multierror.Prefix(err, key)
+		}
+
+		var t Template
+		if err := hcl.DecodeObject(&t, item.Val); err != nil {
+			return multierror.Prefix(err, key)
+		}
+
+		if t.Destination == "" {
+			return fmt.Errorf("%s 'destination' must be set", key)
+		}
+		if (t.Source == "") == (t.Contents == "") {
+			return fmt.Errorf("%s one and only one of 'source' or 'contents' must be set", key)
+		}
+
+		t.Perms = defaultTemplatePerms
+		if t.PermsRaw != nil {
+			switch perms := t.PermsRaw.(type) {
+			case int:
+				t.Perms = os.FileMode(perms)
+			case string:
+				p, err := strconv.ParseUint(perms, 8, 32)
+				if err != nil {
+					return fmt.Errorf("%s could not parse 'perms': %s", key, err)
+				}
+				t.Perms = os.FileMode(p)
+			default:
+				return fmt.Errorf("%s could not parse 'perms' of type %T", key, t.PermsRaw)
+			}
+			if t.Perms&^os.ModePerm != 0 {
+				return fmt.Errorf("%s invalid 'perms' %o", key, t.Perms)
+			}
+		}
+
+		t.CommandTimeout = defaultTemplateCommandTimeout
+		if t.CommandTimeoutRaw != nil {
+			var err error
+			if t.CommandTimeout, err = parseutil.ParseDurationSecond(t.CommandTimeoutRaw); err != nil {
+				return multierror.Prefix(err, key)
+			}
+		}
+
+		templates = append(templates, &t)
+	}
+
+	result.Templates = templates
+	return nil
+}
+
+func checkHCLKeys(node ast.Node, valid []string) error {
+	var list *ast.ObjectList
+	switch n := node.(type) {
+	case *ast.ObjectList:
+		list = n
+	case *ast.ObjectType:
+		list = n.List
+	default:
+		return fmt.Errorf("cannot check HCL keys of type %T", n)
+	}
+
+	validMap := make(map[string]struct{}, len(valid))
+	for _, v := range valid {
+		validMap[v] = struct{}{}
+	}
+
+	var result error
+	for _, item := range list.Items {
+		key := item.Keys[0].Token.Value().(string)
+		if _, ok := validMap[key]; !ok {
+			result = multierror.Append(result, fmt.Errorf(
+				"invalid key '%s' on line %d", key, item.Assign.Line))
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,240 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadConfig(t *testing.T) {
+	config, err := LoadConfig("./test-fixtures/config.hcl")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := &Config{
+		AutoAuth: &AutoAuth{
+			Method: &Method{
+				Type:      "approle",
+				MountPath: "auth/approle-agent",
+				Config: map[string]interface{}{
+					"role_id_file_path":   "/etc/vault/role_id",
+					"secret_id_file_path": "/etc/vault/secret_id",
+				},
+			},
+			Sinks: []*Sink{
+				&Sink{
+					Type: "file",
+					Config: map[string]interface{}{
+						"path": "/tmp/token",
+					},
+				},
+				&Sink{
+					Type:       "file",
+					WrapTTLRaw: "5m",
+					WrapTTL:    5 * time.Minute,
+					Config: map[string]interface{}{
+						"path": "/tmp/token-wrapped",
+						"mode": 0600,
+					},
+				},
+			},
+		},
+
+		Cache: &Cache{
+			UseAutoAuthToken:    true,
+			UseAutoAuthTokenRaw: true,
+		},
+
+		Listeners: []*Listener{
+			&Listener{
+				Type: "tcp",
+				Config: map[string]interface{}{
+					"address":     "127.0.0.1:8300",
+					"tls_disable": true,
+				},
+			},
+		},
+
+		Vault: &Vault{
+			Address:          "https://127.0.0.1:8200",
+			CACert:           "/etc/vault/ca.pem",
+			TLSSkipVerify:    true,
+			TLSSkipVerifyRaw: "true",
+		},
+
+		PidFile: "./pidfile",
+	}
+	if !reflect.DeepEqual(config, expected) {
+		t.Fatalf("expected \n\n%#v\n\n to be \n\n%#v\n\n", config, expected)
+	}
+}
+
+func TestLoadConfig_SinksOnly(t *testing.T) {
+	config, err := LoadConfig("./test-fixtures/config-sinks-only.hcl")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if config.Cache != nil || len(config.Listeners) != 0 {
+		t.Fatalf("expected no cache, got %#v", config)
+	}
+	if config.AutoAuth.Method.MountPath != "auth/kubernetes" {
+		t.Fatalf("bad mount path: %q", config.AutoAuth.Method.MountPath)
+	}
+	if len(config.AutoAuth.Sinks) != 1 {
+		t.Fatalf("bad sinks: %#v", config.AutoAuth.Sinks)
+	}
+}
+
+func TestLoadConfig_Templates(t *testing.T) {
+	config, err := LoadConfig("./test-fixtures/config-templates.hcl")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := []*Template{
+		&Template{
+			Source:         "/etc/vault/app.conf.tmpl",
+			Destination:    "/etc/app/app.conf",
+			PermsRaw:       "0600",
+			Perms:          0600,
+			Command:        "systemctl reload app",
+			CommandTimeout: 30 * time.Second,
+		},
+		&Template{
+			Contents:          `{{ with secret "secret/db" }}{{ .Data.password }}{{ end }}`,
+			Destination:       "/etc/app/db-password",
+			Perms:             0644,
+			CommandTimeoutRaw: "5s",
+			CommandTimeout:    5 * time.Second,
+		},
+	}
+	if !reflect.DeepEqual(config.Templates, expected) {
+		t.Fatalf("expected \n\n%#v\n\n to be \n\n%#v\n\n", config.Templates, expected)
+	}
+	if len(config.AutoAuth.Sinks) != 0 {
+		t.Fatalf("expected no sinks, got %#v", config.AutoAuth.Sinks)
+	}
+}
+
+func TestParseConfig_Invalid(t *testing.T) {
+	cases := map[string]struct {
+		config string
+		err    string
+	}{
+		"empty": {
+			``,
+			"at least one of 'auto_auth' or 'cache' must be configured",
+		},
+		"no method": {
+			`auto_auth {
+  sink "file" {
+    config = { path = "/tmp/token" }
+  }
+}`,
+			`one and only one "method" block is required`,
+		},
+		"no sinks": {
+			`auto_auth {
+  method "approle" {}
+}`,
+			"at least one 'sink' block must be provided",
+		},
+		"cache without listener": {
+			`cache {}`,
+			"at least one listener must be defined",
+		},
+		"listener without cache": {
+			`auto_auth {
+  method "approle" {}
+  sink "file" {}
+}
+listener "tcp" {
+  address = "127.0.0.1:8300"
+}`,
+			"listeners can only be defined when the cache is enabled",
+		},
+		"auto auth token without auto auth": {
+			`cache {
+  use_auto_auth_token = true
+}
+listener "tcp" {
+  address = "127.0.0.1:8300"
+}`,
+			"'use_auto_auth_token' is set",
+		},
+		"invalid key": {
+			`auto_auth {
+  method "approle" {
+    foo = "bar"
+  }
+  sink "file" {}
+}`,
+			"invalid key 'foo'",
+		},
+		"listener without address": {
+			`cache {}
+listener "tcp" {}`,
+			"'address' must be set",
+		},
+		"unsupported listener": {
+			`cache {}
+listener "unix" {}`,
+			`unsupported listener type "unix"`,
+		},
+		"template without auto auth": {
+			`cache {}
+listener "tcp" {
+  address = "127.0.0.1:8300"
+}
+template {
+  contents    = "foo"
+  destination = "/tmp/foo"
+}`,
+			"'auto_auth' must be configured to render templates",
+		},
+		"template without destination": {
+			`auto_auth {
+  method "approle" {}
+}
+template {
+  contents = "foo"
+}`,
+			"'destination' must be set",
+		},
+		"template with source and contents": {
+			`auto_auth {
+  method "approle" {}
+}
+template {
+  source      = "/tmp/foo.tmpl"
+  contents    = "foo"
+  destination = "/tmp/foo"
+}`,
+			"one and only one of 'source' or 'contents' must be set",
+		},
+		"template with invalid perms": {
+			`auto_auth {
+  method "approle" {}
+}
+template {
+  contents    = "foo"
+  destination = "/tmp/foo"
+  perms       = "4755"
+}`,
+			"invalid 'perms'",
+		},
+	}
+
+	for name, tc := range cases {
+		_, err := ParseConfig(tc.config)
+		if err == nil {
+			t.Fatalf("%s: expected error", name)
+		}
+		if !strings.Contains(err.Error(), tc.err) {
+			t.Fatalf("%s: expected error containing %q, got: %v", name, tc.err, err)
+		}
+	}
+}
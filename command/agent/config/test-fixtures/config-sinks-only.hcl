@@ -0,0 +1,13 @@
+auto_auth {
+  method "kubernetes" {
+    config = {
+      role = "demo"
+    }
+  }
+
+  sink "file" {
+    config = {
+      path = "/tmp/token"
+    }
+  }
+}
@@ -0,0 +1,20 @@
+auto_auth {
+  method "approle" {
+    config = {
+      role_id_file_path = "/etc/vault/role_id"
+    }
+  }
+}
+
+template {
+  source      = "/etc/vault/app.conf.tmpl"
+  destination = "/etc/app/app.conf"
+  perms       = "0600"
+  command     = "systemctl reload app"
+}
+
+template {
+  contents        = "{{ with secret \"secret/db\" }}{{ .Data.password }}{{ end }}"
+  destination     = "/etc/app/db-password"
+  command_timeout = "5s"
+}
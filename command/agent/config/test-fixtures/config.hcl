@@ -0,0 +1,40 @@
+pid_file = "./pidfile"
+
+vault {
+  address         = "https://127.0.0.1:8200"
+  ca_cert         = "/etc/vault/ca.pem"
+  tls_skip_verify = "true"
+}
+
+auto_auth {
+  method "AppRole" {
+    mount_path = "auth/approle-agent/"
+    config = {
+      role_id_file_path   = "/etc/vault/role_id"
+      secret_id_file_path = "/etc/vault/secret_id"
+    }
+  }
+
+  sink "file" {
+    config = {
+      path = "/tmp/token"
+    }
+  }
+
+  sink "file" {
+    wrap_ttl = "5m"
+    config = {
+      path = "/tmp/token-wrapped"
+      mode = 0600
+    }
+  }
+}
+
+cache {
+  use_auto_auth_token = true
+}
+
+listener "tcp" {
+  address     = "127.0.0.1:8300"
+  tls_disable = true
+}
@@ -0,0 +1,104 @@
+package file
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/command/agent/sink"
+	log "github.com/mgutz/logxi/v1"
+)
+
+// defaultMode is the mode of the token file if none is configured
+const defaultMode os.FileMode = 0640
+
+// fileSink is a Sink implementation that writes a token to a file
+type fileSink struct {
+	path   string
+	mode   os.FileMode
+	logger log.Logger
+}
+
+// NewFileSink creates a new file sink with the given configuration
+func NewFileSink(conf *sink.SinkConfig) (sink.Sink, error) {
+	if conf.Logger == nil {
+		return nil, errors.New("nil logger provided")
+	}
+
+	f := &fileSink{
+		logger: conf.Logger,
+		mode:   defaultMode,
+	}
+
+	pathRaw, ok := conf.Config["path"]
+	if !ok {
+		return nil, errors.New("'path' not specified for file sink")
+	}
+	f.path, ok = pathRaw.(string)
+	if !ok {
+		return nil, errors.New("could not parse 'path' as string")
+	}
+	if f.path == "" {
+		return nil, errors.New("'path' value is empty")
+	}
+
+	if modeRaw, ok := conf.Config["mode"]; ok {
+		switch mode := modeRaw.(type) {
+		case int:
+			f.mode = os.FileMode(mode)
+		case string:
+			m, err := strconv.ParseUint(mode, 8, 32)
+			if err != nil {
+				return nil, errwrap.Wrapf("could not parse 'mode': {{err}}", err)
+			}
+			f.mode = os.FileMode(m)
+		default:
+			return nil, fmt.Errorf("could not parse 'mode' of type %T", modeRaw)
+		}
+		if f.mode&^os.ModePerm != 0 {
+			return nil, fmt.Errorf("invalid 'mode' %o", f.mode)
+		}
+	}
+
+	return f, nil
+}
+
+// WriteToken writes the token to the file. The token is written to a
+// temporary file first which is then renamed, so that readers never see a
+// partially written token.
+func (f *fileSink) WriteToken(token string) error {
+	f.logger.Trace("sink.file: writing token to file", "path", f.path)
+
+	tmp, err := ioutil.TempFile(filepath.Dir(f.path), filepath.Base(f.path)+".tmp.")
+	if err != nil {
+		return errwrap.Wrapf("error creating temporary file: {{err}}", err)
+	}
+	tmpPath := tmp.Name()
+
+	if _, err := tmp.WriteString(token); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return errwrap.Wrapf("error writing token to temporary file: {{err}}", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return errwrap.Wrapf("error closing temporary file: {{err}}", err)
+	}
+
+	if err := os.Chmod(tmpPath, f.mode); err != nil {
+		os.Remove(tmpPath)
+		return errwrap.Wrapf("error setting mode of temporary file: {{err}}", err)
+	}
+
+	if err := os.Rename(tmpPath, f.path); err != nil {
+		os.Remove(tmpPath)
+		return errwrap.Wrapf("error moving temporary file to sink path: {{err}}", err)
+	}
+
+	f.logger.Info("sink.file: token written", "path", f.path)
+	return nil
+}
@@ -0,0 +1,90 @@
+package file
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/vault/command/agent/sink"
+	"github.com/hashicorp/vault/helper/logformat"
+	log "github.com/mgutz/logxi/v1"
+)
+
+func TestFileSink(t *testing.T) {
+	td, err := ioutil.TempDir("", "vault-file-sink-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(td)
+
+	path := filepath.Join(td, "token")
+	fs, err := NewFileSink(&sink.SinkConfig{
+		Logger: logformat.NewVaultLogger(log.LevelTrace),
+		Config: map[string]interface{}{
+			"path": path,
+			"mode": 0600,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, token := range []string{"first", "second"} {
+		if err := fs.WriteToken(token); err != nil {
+			t.Fatal(err)
+		}
+
+		raw, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(raw) != token {
+			t.Fatalf("expected %q, got %q", token, raw)
+		}
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode() != 0600 {
+		t.Fatalf("bad mode: %o", info.Mode())
+	}
+
+	// No temporary files are left behind
+	files, err := ioutil.ReadDir(td)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("expected only the token file, got %d files", len(files))
+	}
+}
+
+func TestFileSink_Config(t *testing.T) {
+	logger := logformat.NewVaultLogger(log.LevelTrace)
+
+	cases := map[string]map[string]interface{}{
+		"missing path": {},
+		"empty path":   {"path": ""},
+		"bad mode":     {"path": "/tmp/token", "mode": "abc"},
+		"mode bits":    {"path": "/tmp/token", "mode": 01000777},
+	}
+	for name, config := range cases {
+		if _, err := NewFileSink(&sink.SinkConfig{Logger: logger, Config: config}); err == nil {
+			t.Fatalf("%s: expected error", name)
+		}
+	}
+
+	fs, err := NewFileSink(&sink.SinkConfig{
+		Logger: logger,
+		Config: map[string]interface{}{"path": "/tmp/token", "mode": "0400"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fs.(*fileSink).mode != 0400 {
+		t.Fatalf("bad mode: %o", fs.(*fileSink).mode)
+	}
+}
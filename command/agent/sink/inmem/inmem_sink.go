@@ -0,0 +1,29 @@
+package inmem
+
+import (
+	"sync/atomic"
+
+	"github.com/hashicorp/vault/command/agent/sink"
+)
+
+// inmemSink retains the latest token in memory, for the caching proxy to
+// use on requests without a token
+type inmemSink struct {
+	token atomic.Value
+}
+
+// New creates a new in-memory sink
+func New() sink.SinkReader {
+	s := &inmemSink{}
+	s.token.Store("")
+	return s
+}
+
+func (s *inmemSink) WriteToken(token string) error {
+	s.token.Store(token)
+	return nil
+}
+
+func (s *inmemSink) Token() string {
+	return s.token.Load().(string)
+}
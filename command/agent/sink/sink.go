@@ -0,0 +1,138 @@
+package sink
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/helper/jsonutil"
+	log "github.com/mgutz/logxi/v1"
+)
+
+const (
+	initialBackoff = 1 * time.Second
+	maxBackoff     = 5 * time.Minute
+)
+
+// Sink is a destination the agent writes tokens to
+type Sink interface {
+	WriteToken(string) error
+}
+
+// SinkReader is a Sink the latest token written to it can be read back from
+type SinkReader interface {
+	Sink
+	Token() string
+}
+
+// SinkConfig is a Sink and the options of how tokens are written to it
+type SinkConfig struct {
+	Sink
+	Logger  log.Logger
+	Config  map[string]interface{}
+	WrapTTL time.Duration
+}
+
+// SinkServer writes the tokens it receives to its sinks
+type SinkServer struct {
+	logger log.Logger
+	client *api.Client
+}
+
+// SinkServerConfig is the configuration of a SinkServer
+type SinkServerConfig struct {
+	Logger log.Logger
+	Client *api.Client
+}
+
+// NewSinkServer returns a SinkServer for the given configuration
+func NewSinkServer(conf *SinkServerConfig) *SinkServer {
+	return &SinkServer{
+		logger: conf.Logger,
+		client: conf.Client,
+	}
+}
+
+// Run writes each token received on the incoming channel to all the sinks,
+// until the context is canceled. Writes that fail are retried with a backoff
+// until they succeed or a new token is received.
+func (ss *SinkServer) Run(ctx context.Context, incoming chan string, sinks []*SinkConfig) {
+	if incoming == nil {
+		panic("incoming channel is nil")
+	}
+
+	ss.logger.Info("sink.server: starting sink server")
+	defer ss.logger.Info("sink.server: sink server stopped")
+
+	var token string
+	var pending []*SinkConfig
+	var retryCh <-chan time.Time
+	backoff := initialBackoff
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case token = <-incoming:
+			pending = sinks
+			backoff = initialBackoff
+
+		case <-retryCh:
+			backoff *= 2
+			if backoff > maxBackoff {
+				backoff = maxBackoff
+			}
+		}
+
+		var failed []*SinkConfig
+		for _, s := range pending {
+			if err := ss.writeSink(s, token); err != nil {
+				ss.logger.Error("sink.server: error writing token to sink", "error", err, "backoff", backoff.String())
+				failed = append(failed, s)
+			}
+		}
+
+		pending = failed
+		retryCh = nil
+		if len(pending) > 0 {
+			retryCh = time.After(backoff)
+		}
+	}
+}
+
+// writeSink writes the token to the sink, response-wrapping it first if the
+// sink has a wrap TTL
+func (ss *SinkServer) writeSink(s *SinkConfig, token string) error {
+	if s.WrapTTL == 0 {
+		return s.WriteToken(token)
+	}
+
+	wrapClient, err := ss.client.Clone()
+	if err != nil {
+		return errwrap.Wrapf("error creating client for wrapping token: {{err}}", err)
+	}
+	wrapClient.SetToken(token)
+	wrapClient.SetWrappingLookupFunc(func(string, string) string {
+		return s.WrapTTL.String()
+	})
+
+	secret, err := wrapClient.Logical().Write("sys/wrapping/wrap", map[string]interface{}{
+		"token": token,
+	})
+	if err != nil {
+		return errwrap.Wrapf("error wrapping token: {{err}}", err)
+	}
+	if secret == nil || secret.WrapInfo == nil {
+		return errors.New("nil wrap info returned when wrapping token")
+	}
+
+	wrapped, err := jsonutil.EncodeJSON(secret.WrapInfo)
+	if err != nil {
+		return errwrap.Wrapf("error marshaling wrap info: {{err}}", err)
+	}
+
+	return s.WriteToken(string(wrapped))
+}
@@ -0,0 +1,104 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/helper/logformat"
+	vaulthttp "github.com/hashicorp/vault/http"
+	"github.com/hashicorp/vault/vault"
+	log "github.com/mgutz/logxi/v1"
+)
+
+// testSink records the tokens written to it, and fails the given number of
+// writes first
+type testSink struct {
+	l        sync.Mutex
+	failures int
+	tokens   []string
+	written  chan struct{}
+}
+
+func (s *testSink) WriteToken(token string) error {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	if s.failures > 0 {
+		s.failures--
+		return errors.New("failed to write token")
+	}
+	s.tokens = append(s.tokens, token)
+	s.written <- struct{}{}
+	return nil
+}
+
+func (s *testSink) waitForWrite(t *testing.T) {
+	select {
+	case <-s.written:
+	case <-time.After(10 * time.Second):
+		t.Fatal("token was not written to the sink")
+	}
+}
+
+func TestSinkServer(t *testing.T) {
+	core, _, token := vault.TestCoreUnsealed(t)
+	ln, addr := vaulthttp.TestServer(t, core)
+	defer ln.Close()
+
+	config := api.DefaultConfig()
+	config.Address = addr
+	client, err := api.NewClient(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SetToken(token)
+
+	logger := logformat.NewVaultLogger(log.LevelTrace)
+	plain := &testSink{failures: 1, written: make(chan struct{}, 1)}
+	wrapped := &testSink{written: make(chan struct{}, 1)}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	incoming := make(chan string)
+	ss := NewSinkServer(&SinkServerConfig{
+		Logger: logger,
+		Client: client,
+	})
+	go ss.Run(ctx, incoming, []*SinkConfig{
+		&SinkConfig{Sink: plain, Logger: logger},
+		&SinkConfig{Sink: wrapped, Logger: logger, WrapTTL: 5 * time.Minute},
+	})
+
+	incoming <- token
+
+	// The write to the plain sink is retried after its failure
+	plain.waitForWrite(t)
+	wrapped.waitForWrite(t)
+
+	if len(plain.tokens) != 1 || plain.tokens[0] != token {
+		t.Fatalf("bad tokens: %v", plain.tokens)
+	}
+
+	// The wrapped sink gets the wrap info of a token wrapping the original
+	var wrapInfo api.SecretWrapInfo
+	if err := json.Unmarshal([]byte(wrapped.tokens[0]), &wrapInfo); err != nil {
+		t.Fatal(err)
+	}
+	if wrapInfo.TTL != 300 {
+		t.Fatalf("bad wrap ttl: %d", wrapInfo.TTL)
+	}
+
+	secret, err := client.Logical().Unwrap(wrapInfo.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret.Data["token"] != token {
+		t.Fatalf("bad unwrapped data: %#v", secret.Data)
+	}
+}
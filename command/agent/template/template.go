@@ -0,0 +1,513 @@
+package template
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/command/agent/config"
+	log "github.com/mgutz/logxi/v1"
+)
+
+const (
+	// defaultStaticSecretInterval is how often secrets that are read and
+	// can't be renewed are fetched again
+	defaultStaticSecretInterval = 5 * time.Minute
+
+	initialBackoff = 1 * time.Second
+	maxBackoff     = 5 * time.Minute
+)
+
+// ServerConfig is the configuration of a template Server
+type ServerConfig struct {
+	Logger log.Logger
+	Client *api.Client
+
+	// ExitAfterRender makes the server return once all the templates have
+	// been rendered once
+	ExitAfterRender bool
+
+	// StaticSecretInterval is how often secrets that are read and can't be
+	// renewed, such as the ones of kv, are fetched again to render the
+	// templates with their new data
+	StaticSecretInterval time.Duration
+}
+
+// Server renders templates with the data of Vault. It is a Sink of the token
+// of auto-auth, which it fetches secrets with. Secrets are renewed for as
+// long as possible, and fetched again when they expire, or every
+// StaticSecretInterval for secrets that are read and can't be renewed. The
+// templates are rendered again every time a secret changes.
+type Server struct {
+	logger               log.Logger
+	client               *api.Client
+	exitAfterRender      bool
+	staticSecretInterval time.Duration
+
+	tokenL  sync.Mutex
+	token   string
+	tokenCh chan struct{}
+
+	eventCh chan *event
+}
+
+// event is sent by the watcher of a dependency when it is renewed, or when
+// it must be fetched again
+type event struct {
+	dep     *dependency
+	expired bool
+	secret  *api.Secret
+}
+
+// dependency is a secret used by at least one template
+type dependency struct {
+	key    string
+	path   string
+	data   map[string]interface{}
+	secret *api.Secret
+	cancel context.CancelFunc
+}
+
+// renderTemplate is a parsed template and its configuration
+type renderTemplate struct {
+	config *config.Template
+	tmpl   *template.Template
+
+	// deps is set when the template is executed
+	deps map[string]*dependency
+}
+
+// NewServer returns a template Server for the given configuration
+func NewServer(conf *ServerConfig) *Server {
+	interval := conf.StaticSecretInterval
+	if interval == 0 {
+		interval = defaultStaticSecretInterval
+	}
+
+	return &Server{
+		logger:               conf.Logger,
+		client:               conf.Client,
+		exitAfterRender:      conf.ExitAfterRender,
+		staticSecretInterval: interval,
+		tokenCh:              make(chan struct{}, 1),
+		eventCh:              make(chan *event),
+	}
+}
+
+// WriteToken implements sink.Sink. The templates are rendered again with
+// the secrets fetched with the new token.
+func (ts *Server) WriteToken(token string) error {
+	ts.tokenL.Lock()
+	ts.token = token
+	ts.tokenL.Unlock()
+
+	select {
+	case ts.tokenCh <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (ts *Server) currentToken() string {
+	ts.tokenL.Lock()
+	defer ts.tokenL.Unlock()
+	return ts.token
+}
+
+// Run renders the templates until the context is canceled, or until they
+// have all been rendered once if ExitAfterRender is set
+func (ts *Server) Run(ctx context.Context, templates []*config.Template) error {
+	rts := make([]*renderTemplate, 0, len(templates))
+	for _, t := range templates {
+		rt, err := ts.parseTemplate(t)
+		if err != nil {
+			return err
+		}
+		rts = append(rts, rt)
+	}
+
+	ts.logger.Info("template.server: starting template server")
+	defer ts.logger.Info("template.server: template server stopped")
+
+	// Wait for the first token
+	select {
+	case <-ctx.Done():
+		return nil
+	case <-ts.tokenCh:
+	}
+
+	deps := make(map[string]*dependency)
+	defer func() {
+		for _, dep := range deps {
+			dep.cancel()
+		}
+	}()
+
+	backoff := initialBackoff
+	var retryCh <-chan time.Time
+	for {
+		err := ts.renderAll(ctx, rts, deps)
+		switch {
+		case err != nil:
+			ts.logger.Error("template.server: error rendering templates", "error", err, "backoff", backoff.String())
+			retryCh = time.After(backoff)
+			backoff *= 2
+			if backoff > maxBackoff {
+				backoff = maxBackoff
+			}
+
+		case ts.exitAfterRender:
+			ts.logger.Info("template.server: all templates rendered, exiting")
+			return nil
+
+		default:
+			retryCh = nil
+			backoff = initialBackoff
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case <-retryCh:
+
+		case <-ts.tokenCh:
+			// Secrets are fetched again with the new token
+			ts.logger.Info("template.server: received new token, rendering templates")
+			for key, dep := range deps {
+				dep.cancel()
+				delete(deps, key)
+			}
+
+		case ev := <-ts.eventCh:
+			// Events of dependencies that were replaced in the meantime are
+			// ignored, rendering the templates again is then a no-op
+			if deps[ev.dep.key] != ev.dep {
+				break
+			}
+			if ev.expired {
+				ev.dep.cancel()
+				delete(deps, ev.dep.key)
+			} else {
+				ev.dep.secret = ev.secret
+			}
+		}
+	}
+}
+
+// renderAll renders all the templates, fetching the secrets that are not
+// in deps. Secrets that are no longer used by any template are removed from
+// deps.
+func (ts *Server) renderAll(ctx context.Context, rts []*renderTemplate, deps map[string]*dependency) error {
+	used := make(map[string]struct{})
+
+	var result error
+	for _, rt := range rts {
+		if err := ts.render(ctx, rt, deps); err != nil {
+			result = errwrap.Wrapf(fmt.Sprintf("error rendering %q: {{err}}", rt.config.Destination), err)
+			break
+		}
+		for key := range rt.deps {
+			used[key] = struct{}{}
+		}
+	}
+
+	if result == nil {
+		for key, dep := range deps {
+			if _, ok := used[key]; !ok {
+				dep.cancel()
+				delete(deps, key)
+			}
+		}
+	}
+
+	return result
+}
+
+// render executes the template and writes it to its destination if its
+// contents changed, running its command if it has one
+func (ts *Server) render(ctx context.Context, rt *renderTemplate, deps map[string]*dependency) error {
+	rt.deps = make(map[string]*dependency)
+
+	funcs := template.FuncMap{
+		"secret": func(path string, args ...string) (*api.Secret, error) {
+			dep, err := ts.dependency(ctx, path, args, deps)
+			if err != nil {
+				return nil, err
+			}
+			rt.deps[dep.key] = dep
+			return dep.secret, nil
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := rt.tmpl.Funcs(funcs).Execute(&buf, nil); err != nil {
+		return err
+	}
+
+	existing, err := ioutil.ReadFile(rt.config.Destination)
+	if err == nil && bytes.Equal(existing, buf.Bytes()) {
+		ts.logger.Trace("template.server: template unchanged", "destination", rt.config.Destination)
+		return nil
+	}
+
+	if err := writeFile(rt.config.Destination, buf.Bytes(), rt.config.Perms); err != nil {
+		return err
+	}
+	ts.logger.Info("template.server: rendered template", "destination", rt.config.Destination)
+
+	if rt.config.Command != "" {
+		ts.runCommand(ctx, rt.config)
+	}
+
+	return nil
+}
+
+// dependency returns the secret at the path, fetching it if it isn't
+// already a dependency. If args are given, they are written to the path as
+// key=value pairs, otherwise the path is read.
+func (ts *Server) dependency(ctx context.Context, path string, args []string, deps map[string]*dependency) (*dependency, error) {
+	path = strings.Trim(path, "/")
+
+	data := make(map[string]interface{}, len(args))
+	for _, arg := range args {
+		parts := strings.SplitN(arg, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid argument %q, must be of the form key=value", arg)
+		}
+		data[parts[0]] = parts[1]
+	}
+
+	key := dependencyKey(path, args)
+	if dep, ok := deps[key]; ok {
+		return dep, nil
+	}
+
+	client, err := ts.client.Clone()
+	if err != nil {
+		return nil, err
+	}
+	client.SetToken(ts.currentToken())
+
+	var secret *api.Secret
+	if len(args) == 0 {
+		secret, err = client.Logical().Read(path)
+	} else {
+		secret, err = client.Logical().Write(path, data)
+	}
+	if err != nil {
+		return nil, errwrap.Wrapf(fmt.Sprintf("error fetching secret %q: {{err}}", path), err)
+	}
+	if secret == nil {
+		return nil, fmt.Errorf("no secret exists at %q", path)
+	}
+
+	depCtx, cancel := context.WithCancel(ctx)
+	dep := &dependency{
+		key:    key,
+		path:   path,
+		data:   data,
+		secret: secret,
+		cancel: cancel,
+	}
+	deps[key] = dep
+
+	go ts.watch(depCtx, client, dep)
+
+	return dep, nil
+}
+
+// watch sends an event when the secret of the dependency is renewed, and
+// when it must be fetched again
+func (ts *Server) watch(ctx context.Context, client *api.Client, dep *dependency) {
+	secret := dep.secret
+
+	send := func(ev *event) {
+		select {
+		case ts.eventCh <- ev:
+		case <-ctx.Done():
+		}
+	}
+
+	switch {
+	case secret.LeaseID != "" && secret.Renewable:
+		renewer, err := client.NewRenewer(&api.RenewerInput{
+			Secret: secret,
+		})
+		if err != nil {
+			ts.logger.Error("template.server: error creating renewer", "path", dep.path, "error", err)
+			send(&event{dep: dep, expired: true})
+			return
+		}
+		go renewer.Renew()
+		defer renewer.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+
+			case err := <-renewer.DoneCh():
+				if err != nil {
+					ts.logger.Error("template.server: error renewing secret", "path", dep.path, "error", err)
+				}
+				ts.logger.Info("template.server: secret can no longer be renewed, fetching it again", "path", dep.path)
+				send(&event{dep: dep, expired: true})
+				return
+
+			case renewal := <-renewer.RenewCh():
+				ts.logger.Debug("template.server: renewed secret", "path", dep.path)
+
+				// The renewal carries the new lease, but not the data of the
+				// secret
+				renewed := *secret
+				if renewal.Secret != nil {
+					renewed.LeaseDuration = renewal.Secret.LeaseDuration
+					renewed.Renewable = renewal.Secret.Renewable
+				}
+				send(&event{dep: dep, secret: &renewed})
+			}
+		}
+
+	default:
+		// Secrets that can't be renewed are fetched again before they
+		// expire. Secrets that are read, such as the ones of kv whose TTL is
+		// only advisory, are also fetched again periodically to pick up
+		// changes; written ones issue new credentials every time, so are
+		// only fetched again once needed.
+		var interval time.Duration
+		if secret.LeaseDuration > 0 {
+			interval = time.Duration(secret.LeaseDuration) * time.Second * 2 / 3
+		}
+		if len(dep.data) == 0 && (interval == 0 || interval > ts.staticSecretInterval) {
+			interval = ts.staticSecretInterval
+		}
+		if interval == 0 {
+			<-ctx.Done()
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(interval):
+			send(&event{dep: dep, expired: true})
+		}
+	}
+}
+
+// runCommand runs the command of the template with a shell
+func (ts *Server) runCommand(ctx context.Context, t *config.Template) {
+	cmdCtx, cancel := context.WithTimeout(ctx, t.CommandTimeout)
+	defer cancel()
+
+	shell, flag := "/bin/sh", "-c"
+	if runtime.GOOS == "windows" {
+		shell, flag = "cmd", "/C"
+	}
+
+	ts.logger.Info("template.server: running command", "destination", t.Destination, "command", t.Command)
+	output, err := exec.CommandContext(cmdCtx, shell, flag, t.Command).CombinedOutput()
+	if err != nil {
+		ts.logger.Error("template.server: error running command", "command", t.Command, "error", err, "output", string(output))
+		return
+	}
+	ts.logger.Debug("template.server: command completed", "command", t.Command, "output", string(output))
+}
+
+// parseTemplate parses the contents of the template, or the file it is
+// read from
+func (ts *Server) parseTemplate(t *config.Template) (*renderTemplate, error) {
+	contents, name := t.Contents, t.Destination
+	if t.Source != "" {
+		raw, err := ioutil.ReadFile(t.Source)
+		if err != nil {
+			return nil, errwrap.Wrapf(fmt.Sprintf("error reading template %q: {{err}}", t.Source), err)
+		}
+		contents, name = string(raw), t.Source
+	}
+
+	// The secret function is replaced when the template is executed, it
+	// only needs to be defined to parse the template
+	tmpl, err := template.New(name).Option("missingkey=error").Funcs(template.FuncMap{
+		"secret": func(string, ...string) (*api.Secret, error) {
+			return nil, errors.New("secret is not available")
+		},
+		"env":    os.Getenv,
+		"toJSON": toJSON,
+	}).Parse(contents)
+	if err != nil {
+		return nil, errwrap.Wrapf(fmt.Sprintf("error parsing template %q: {{err}}", name), err)
+	}
+
+	return &renderTemplate{
+		config: t,
+		tmpl:   tmpl,
+	}, nil
+}
+
+// dependencyKey returns the key identifying the secret at the path fetched
+// with the args
+func dependencyKey(path string, args []string) string {
+	sorted := make([]string, len(args))
+	copy(sorted, args)
+	sort.Strings(sorted)
+	return path + "\x00" + strings.Join(sorted, "\x00")
+}
+
+func toJSON(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// writeFile writes the contents to a temporary file which is then renamed
+// to the path, so that readers never see a partially rendered template
+func writeFile(path string, contents []byte, perms os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return errwrap.Wrapf("error creating destination directory: {{err}}", err)
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp.")
+	if err != nil {
+		return errwrap.Wrapf("error creating temporary file: {{err}}", err)
+	}
+	tmpPath := tmp.Name()
+
+	if _, err := tmp.Write(contents); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return errwrap.Wrapf("error writing temporary file: {{err}}", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return errwrap.Wrapf("error closing temporary file: {{err}}", err)
+	}
+
+	if err := os.Chmod(tmpPath, perms); err != nil {
+		os.Remove(tmpPath)
+		return errwrap.Wrapf("error setting mode of temporary file: {{err}}", err)
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return errwrap.Wrapf("error moving temporary file to destination: {{err}}", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,208 @@
+package template
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/command/agent/config"
+	"github.com/hashicorp/vault/helper/logformat"
+	vaulthttp "github.com/hashicorp/vault/http"
+	"github.com/hashicorp/vault/vault"
+	log "github.com/mgutz/logxi/v1"
+)
+
+func testClient(t *testing.T) (*api.Client, string, func()) {
+	core, _, token := vault.TestCoreUnsealed(t)
+	ln, addr := vaulthttp.TestServer(t, core)
+
+	config := api.DefaultConfig()
+	config.Address = addr
+	client, err := api.NewClient(config)
+	if err != nil {
+		ln.Close()
+		t.Fatal(err)
+	}
+	client.SetToken(token)
+
+	return client, token, func() { ln.Close() }
+}
+
+// waitForContents waits for the file at the path to have the given contents
+func waitForContents(t *testing.T, path, expected string) {
+	var actual string
+	for i := 0; i < 100; i++ {
+		raw, err := ioutil.ReadFile(path)
+		if err == nil {
+			actual = string(raw)
+			if actual == expected {
+				return
+			}
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Fatalf("expected %q to contain %q, got %q", path, expected, actual)
+}
+
+func TestServer_Render(t *testing.T) {
+	client, token, closer := testClient(t)
+	defer closer()
+
+	if _, err := client.Logical().Write("secret/foo", map[string]interface{}{
+		"user":     "admin",
+		"password": "hunter2",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	td, err := ioutil.TempDir("", "vault-agent-template")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(td)
+
+	sourcePath := filepath.Join(td, "source.tmpl")
+	if err := ioutil.WriteFile(sourcePath, []byte(`{{ with secret "secret/foo" }}{{ .Data | toJSON }}{{ end }}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	templates := []*config.Template{
+		{
+			Contents:       `{{ with secret "secret/foo" }}{{ .Data.user }}:{{ .Data.password }}{{ end }}`,
+			Destination:    filepath.Join(td, "contents"),
+			Perms:          0600,
+			Command:        "touch " + filepath.Join(td, "command-ran"),
+			CommandTimeout: 5 * time.Second,
+		},
+		{
+			Source:      sourcePath,
+			Destination: filepath.Join(td, "nested", "source"),
+			Perms:       0644,
+		},
+	}
+
+	ts := NewServer(&ServerConfig{
+		Logger:               logformat.NewVaultLogger(log.LevelTrace),
+		Client:               client,
+		StaticSecretInterval: 100 * time.Millisecond,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- ts.Run(ctx, templates)
+	}()
+
+	if err := ts.WriteToken(token); err != nil {
+		t.Fatal(err)
+	}
+
+	waitForContents(t, templates[0].Destination, "admin:hunter2")
+	waitForContents(t, templates[1].Destination, `{"password":"hunter2","user":"admin"}`)
+
+	info, err := os.Stat(templates[0].Destination)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Fatalf("bad mode: %v", info.Mode())
+	}
+
+	if _, err := os.Stat(filepath.Join(td, "command-ran")); err != nil {
+		t.Fatalf("expected command to have run: %v", err)
+	}
+
+	// The templates are rendered again when the secret changes
+	if _, err := client.Logical().Write("secret/foo", map[string]interface{}{
+		"user":     "admin",
+		"password": "correcthorse",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	waitForContents(t, templates[0].Destination, "admin:correcthorse")
+
+	cancel()
+	select {
+	case err := <-errCh:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("template server did not stop")
+	}
+}
+
+func TestServer_ExitAfterRender(t *testing.T) {
+	client, token, closer := testClient(t)
+	defer closer()
+
+	td, err := ioutil.TempDir("", "vault-agent-template")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(td)
+
+	// Secrets can be written to, to issue credentials
+	dest := filepath.Join(td, "token")
+	templates := []*config.Template{
+		{
+			Contents:    `{{ with secret "auth/token/create" "policies=default" "ttl=1h" }}{{ .Auth.Policies }}{{ end }}`,
+			Destination: dest,
+			Perms:       0600,
+		},
+	}
+
+	ts := NewServer(&ServerConfig{
+		Logger:          logformat.NewVaultLogger(log.LevelTrace),
+		Client:          client,
+		ExitAfterRender: true,
+	})
+	if err := ts.WriteToken(token); err != nil {
+		t.Fatal(err)
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- ts.Run(context.Background(), templates)
+	}()
+
+	select {
+	case err := <-errCh:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("template server did not exit after rendering")
+	}
+
+	raw, err := ioutil.ReadFile(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(raw) != "[default]" {
+		t.Fatalf("bad: %q", raw)
+	}
+}
+
+func TestServer_InvalidTemplate(t *testing.T) {
+	ts := NewServer(&ServerConfig{
+		Logger: logformat.NewVaultLogger(log.LevelTrace),
+	})
+
+	err := ts.Run(context.Background(), []*config.Template{
+		{
+			Contents:    `{{ with secret "secret/foo" }}`,
+			Destination: "/nonexistent",
+		},
+	})
+	if err == nil || !strings.Contains(err.Error(), "error parsing template") {
+		t.Fatalf("expected parse error, got: %v", err)
+	}
+}
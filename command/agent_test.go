@@ -0,0 +1,328 @@
+package command
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	credAppRole "github.com/hashicorp/vault/builtin/credential/approle"
+	"github.com/hashicorp/vault/http"
+	"github.com/hashicorp/vault/meta"
+	"github.com/hashicorp/vault/vault"
+	"github.com/mitchellh/cli"
+)
+
+const agentTestPolicy = `
+path "auth/token/*" {
+	capabilities = ["create", "read", "update"]
+}
+`
+
+// testAgentAppRole configures an approle role for the agent, and writes its
+// role and secret IDs to files in the directory
+func testAgentAppRole(t *testing.T, client *api.Client, dir string) (string, string) {
+	if err := client.Sys().PutPolicy("agent", agentTestPolicy); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Sys().EnableAuth("approle", "approle", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Logical().Write("auth/approle/role/agent", map[string]interface{}{
+		"policies": "agent",
+		"period":   "1h",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	secret, err := client.Logical().Read("auth/approle/role/agent/role-id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	roleID := secret.Data["role_id"].(string)
+
+	secret, err = client.Logical().Write("auth/approle/role/agent/secret-id", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secretID := secret.Data["secret_id"].(string)
+
+	roleIDPath := filepath.Join(dir, "role_id")
+	secretIDPath := filepath.Join(dir, "secret_id")
+	if err := ioutil.WriteFile(roleIDPath, []byte(roleID), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(secretIDPath, []byte(secretID), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return roleIDPath, secretIDPath
+}
+
+func TestAgent_AutoAuthCache(t *testing.T) {
+	if err := vault.AddTestCredentialBackend("approle", credAppRole.Factory); err != nil {
+		t.Fatal(err)
+	}
+
+	core, _, token := vault.TestCoreUnsealed(t)
+	ln, addr := http.TestServer(t, core)
+	defer ln.Close()
+
+	client := testClient(t, addr, token)
+
+	td, err := ioutil.TempDir("", "vault-agent-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(td)
+
+	roleIDPath, secretIDPath := testAgentAppRole(t, client, td)
+	sinkPath := filepath.Join(td, "token")
+	configPath := filepath.Join(td, "agent.hcl")
+
+	// Find a free port for the listener of the agent
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	agentAddr := l.Addr().String()
+	l.Close()
+
+	config := fmt.Sprintf(`
+vault {
+  address = "%s"
+}
+
+auto_auth {
+  method "approle" {
+    config = {
+      role_id_file_path   = "%s"
+      secret_id_file_path = "%s"
+    }
+  }
+
+  sink "file" {
+    config = {
+      path = "%s"
+    }
+  }
+}
+
+cache {
+  use_auto_auth_token = true
+}
+
+listener "tcp" {
+  address     = "%s"
+  tls_disable = true
+}
+`, addr, roleIDPath, secretIDPath, sinkPath, agentAddr)
+	if err := ioutil.WriteFile(configPath, []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	ui := new(cli.MockUi)
+	cmd := &AgentCommand{
+		Meta: meta.Meta{
+			Ui: ui,
+		},
+		ShutdownCh: make(chan struct{}),
+		startedCh:  make(chan struct{}),
+	}
+
+	doneCh := make(chan int)
+	go func() {
+		doneCh <- cmd.Run([]string{"-config", configPath})
+	}()
+
+	select {
+	case <-cmd.startedCh:
+	case code := <-doneCh:
+		t.Fatalf("agent exited with %d: %s", code, ui.OutputWriter.String())
+	}
+
+	// Wait for the token to be written to the sink
+	var agentToken string
+	for i := 0; i < 50; i++ {
+		raw, err := ioutil.ReadFile(sinkPath)
+		if err == nil {
+			agentToken = strings.TrimSpace(string(raw))
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if agentToken == "" {
+		t.Fatalf("token was not written to the sink")
+	}
+
+	secret, err := client.Auth().Token().Lookup(agentToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(fmt.Sprintf("%v", secret.Data["policies"]), "agent") {
+		t.Fatalf("bad policies: %v", secret.Data["policies"])
+	}
+
+	// The secret ID file is removed after being read by default
+	if _, err := os.Stat(secretIDPath); !os.IsNotExist(err) {
+		t.Fatalf("expected secret ID file to be removed, got: %v", err)
+	}
+
+	// Requests without a token are sent through the cache with the auto-auth
+	// token, and tokens created through it are cached
+	agentConfig := api.DefaultConfig()
+	agentConfig.Address = "http://" + agentAddr
+	agentClient, err := api.NewClient(agentConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	agentClient.ClearToken()
+
+	createToken := func() string {
+		secret, err := agentClient.Logical().Write("auth/token/create", map[string]interface{}{
+			"policies": "default",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if secret == nil || secret.Auth == nil {
+			t.Fatalf("bad: %#v", secret)
+		}
+		return secret.Auth.ClientToken
+	}
+
+	first := createToken()
+	if second := createToken(); second != first {
+		t.Fatalf("expected cached token %q, got %q", first, second)
+	}
+
+	secret, err = client.Auth().Token().Lookup(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret.Data["id"] != first {
+		t.Fatalf("bad: %#v", secret.Data)
+	}
+
+	// Revoking the token through the agent evicts it from the cache
+	if err := agentClient.Auth().Token().RevokeTree(first); err != nil {
+		t.Fatal(err)
+	}
+	if third := createToken(); third == first {
+		t.Fatalf("expected revoked token to be evicted from the cache")
+	}
+
+	// Error responses of Vault are returned as is
+	_, err = agentClient.Logical().Read("sys/mounts")
+	if err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Fatalf("expected permission denied error, got: %v", err)
+	}
+
+	close(cmd.ShutdownCh)
+	select {
+	case code := <-doneCh:
+		if code != 0 {
+			t.Fatalf("bad exit code: %d\n\n%s", code, ui.OutputWriter.String())
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatalf("agent did not shut down")
+	}
+}
+
+func TestAgent_ExitAfterRender(t *testing.T) {
+	if err := vault.AddTestCredentialBackend("approle", credAppRole.Factory); err != nil {
+		t.Fatal(err)
+	}
+
+	core, _, token := vault.TestCoreUnsealed(t)
+	ln, addr := http.TestServer(t, core)
+	defer ln.Close()
+
+	client := testClient(t, addr, token)
+	if err := client.Sys().PutPolicy("agent-template", `
+path "secret/app" {
+	capabilities = ["read"]
+}
+`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Logical().Write("secret/app", map[string]interface{}{
+		"password": "hunter2",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	td, err := ioutil.TempDir("", "vault-agent-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(td)
+
+	roleIDPath, secretIDPath := testAgentAppRole(t, client, td)
+	if _, err := client.Logical().Write("auth/approle/role/agent", map[string]interface{}{
+		"policies": "agent,agent-template",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	destPath := filepath.Join(td, "app.conf")
+	configPath := filepath.Join(td, "agent.hcl")
+	config := fmt.Sprintf(`
+vault {
+  address = "%s"
+}
+
+auto_auth {
+  method "approle" {
+    config = {
+      role_id_file_path   = "%s"
+      secret_id_file_path = "%s"
+    }
+  }
+}
+
+template {
+  contents    = "password={{ with secret \"secret/app\" }}{{ .Data.password }}{{ end }}"
+  destination = "%s"
+  perms       = "0600"
+}
+`, addr, roleIDPath, secretIDPath, destPath)
+	if err := ioutil.WriteFile(configPath, []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	ui := new(cli.MockUi)
+	cmd := &AgentCommand{
+		Meta: meta.Meta{
+			Ui: ui,
+		},
+		ShutdownCh: make(chan struct{}),
+	}
+
+	doneCh := make(chan int)
+	go func() {
+		doneCh <- cmd.Run([]string{"-config", configPath, "-exit-after-render"})
+	}()
+
+	select {
+	case code := <-doneCh:
+		if code != 0 {
+			t.Fatalf("bad exit code: %d\n\n%s", code, ui.OutputWriter.String())
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatalf("agent did not exit after rendering the templates")
+	}
+
+	raw, err := ioutil.ReadFile(destPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(raw) != "password=hunter2" {
+		t.Fatalf("bad: %q", raw)
+	}
+}
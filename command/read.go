@@ -3,6 +3,7 @@ package command
 import (
 	"flag"
 	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/hashicorp/vault/api"
@@ -18,12 +19,14 @@ type ReadCommand struct {
 func (c *ReadCommand) Run(args []string) int {
 	var format string
 	var field string
+	var version int
 	var err error
 	var secret *api.Secret
 	var flags *flag.FlagSet
 	flags = c.Meta.FlagSet("read", meta.FlagSetDefault)
 	flags.StringVar(&format, "format", "table", "")
 	flags.StringVar(&field, "field", "", "")
+	flags.IntVar(&version, "version", 0, "")
 	flags.Usage = func() { c.Ui.Error(c.Help()) }
 	if err := flags.Parse(args); err != nil {
 		return 1
@@ -48,7 +51,14 @@ func (c *ReadCommand) Run(args []string) int {
 		return 2
 	}
 
-	secret, err = client.Logical().Read(path)
+	var data map[string][]string
+	if version > 0 {
+		data = map[string][]string{
+			"version": []string{strconv.Itoa(version)},
+		}
+	}
+
+	secret, err = client.Logical().ReadWithData(path, data)
 	if err != nil {
 		c.Ui.Error(fmt.Sprintf(
 			"Error reading %s: %s", path, err))
@@ -93,6 +103,10 @@ Read Options:
   -field=field            If included, the raw value of the specified field
                           will be output raw to stdout.
 
+  -version=version        If included, the given version of the secret is
+                          requested. This is used by versioned backends such
+                          as the versioned KV backend ("kv-v2").
+
 `
 	return strings.TrimSpace(helpText)
 }
@@ -103,7 +117,8 @@ func (c *ReadCommand) AutocompleteArgs() complete.Predictor {
 
 func (c *ReadCommand) AutocompleteFlags() complete.Flags {
 	return complete.Flags{
-		"-format": predictFormat,
-		"-field":  complete.PredictNothing,
+		"-format":  predictFormat,
+		"-field":   complete.PredictNothing,
+		"-version": complete.PredictNothing,
 	}
 }
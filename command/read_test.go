@@ -1,8 +1,11 @@
 package command
 
 import (
+	"strconv"
+	"strings"
 	"testing"
 
+	"github.com/hashicorp/vault/builtin/logical/kv"
 	"github.com/hashicorp/vault/http"
 	"github.com/hashicorp/vault/meta"
 	"github.com/hashicorp/vault/vault"
@@ -135,3 +138,85 @@ func TestRead_field_notFound(t *testing.T) {
 		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
 	}
 }
+
+func TestRead_version(t *testing.T) {
+	// Add the versioned KV backend to the unsealed test core.
+	// This should be done before the unsealed core is created.
+	if err := vault.AddTestLogicalBackend("kv-v2", kv.Factory); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	core, _, token := vault.TestCoreUnsealed(t)
+	ln, addr := http.TestServer(t, core)
+	defer ln.Close()
+
+	ui := new(cli.MockUi)
+	mountCmd := &MountCommand{
+		Meta: meta.Meta{
+			ClientToken: token,
+			Ui:          ui,
+		},
+	}
+	if code := mountCmd.Run([]string{"-address", addr, "-path", "versioned", "kv-v2"}); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	for i, value := range []string{"bar", "baz"} {
+		ui := new(cli.MockUi)
+		c := &WriteCommand{
+			Meta: meta.Meta{
+				ClientToken: token,
+				Ui:          ui,
+			},
+		}
+
+		args := []string{
+			"-address", addr,
+			"-cas", strconv.Itoa(i),
+			"versioned/data/foo",
+			"value=" + value,
+		}
+		if code := c.Run(args); code != 0 {
+			t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+		}
+	}
+
+	// A stale check-and-set version must be rejected
+	ui = new(cli.MockUi)
+	w := &WriteCommand{
+		Meta: meta.Meta{
+			ClientToken: token,
+			Ui:          ui,
+		},
+	}
+	args := []string{
+		"-address", addr,
+		"-cas", "1",
+		"versioned/data/foo",
+		"value=zip",
+	}
+	if code := w.Run(args); code == 0 {
+		t.Fatalf("expected stale cas write to fail")
+	}
+
+	ui = new(cli.MockUi)
+	c := &ReadCommand{
+		Meta: meta.Meta{
+			ClientToken: token,
+			Ui:          ui,
+		},
+	}
+	args = []string{
+		"-address", addr,
+		"-version", "1",
+		"-format", "json",
+		"versioned/data/foo",
+	}
+	if code := c.Run(args); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	output := ui.OutputWriter.String()
+	if !strings.Contains(output, `"value": "bar"`) || strings.Contains(output, "baz") {
+		t.Fatalf("bad output: %s", output)
+	}
+}
@@ -37,6 +37,7 @@ import (
 	"github.com/hashicorp/vault/helper/flag-slice"
 	"github.com/hashicorp/vault/helper/gated-writer"
 	"github.com/hashicorp/vault/helper/logformat"
+	"github.com/hashicorp/vault/helper/metricsutil"
 	"github.com/hashicorp/vault/helper/mlock"
 	"github.com/hashicorp/vault/helper/parseutil"
 	"github.com/hashicorp/vault/helper/reload"
@@ -205,7 +206,8 @@ func (c *ServerCommand) Run(args []string) int {
 		c.Ui.Output("  Vault on an mlockall(2) enabled system is much more secure.\n")
 	}
 
-	if err := c.setupTelemetry(config); err != nil {
+	metricsHelper, err := c.setupTelemetry(config)
+	if err != nil {
 		c.Ui.Output(fmt.Sprintf("Error initializing telemetry: %s", err))
 		return 1
 	}
@@ -231,6 +233,25 @@ func (c *ServerCommand) Run(args []string) int {
 
 	var seal vault.Seal = &vault.DefaultSeal{}
 
+	// The seal that is not in use is kept to migrate the keys away from it
+	// if storage is still sealed with it
+	var migrationSeal vault.Seal
+	if config.Seal != nil {
+		autoSeal, err := configureSeal(config.Seal, c.logger)
+		if err != nil {
+			c.Ui.Output(fmt.Sprintf(
+				"Error configuring seal of type %s: %s",
+				config.Seal.Type, err))
+			return 1
+		}
+		if config.Seal.Disabled {
+			migrationSeal = autoSeal
+		} else {
+			seal = autoSeal
+			migrationSeal = &vault.DefaultSeal{}
+		}
+	}
+
 	// Ensure that the seal finalizer is called, even if using verify-only
 	defer func() {
 		if seal != nil {
@@ -239,6 +260,12 @@ func (c *ServerCommand) Run(args []string) int {
 				c.Ui.Error(fmt.Sprintf("Error finalizing seals: %v", err))
 			}
 		}
+		if migrationSeal != nil {
+			err = migrationSeal.Finalize()
+			if err != nil {
+				c.Ui.Error(fmt.Sprintf("Error finalizing seals: %v", err))
+			}
+		}
 	}()
 
 	if seal == nil {
@@ -247,22 +274,28 @@ func (c *ServerCommand) Run(args []string) int {
 	}
 
 	coreConfig := &vault.CoreConfig{
-		Physical:           backend,
-		RedirectAddr:       config.Storage.RedirectAddr,
-		HAPhysical:         nil,
-		Seal:               seal,
-		AuditBackends:      c.AuditBackends,
-		CredentialBackends: c.CredentialBackends,
-		LogicalBackends:    c.LogicalBackends,
-		Logger:             c.logger,
-		DisableCache:       config.DisableCache,
-		DisableMlock:       config.DisableMlock,
-		MaxLeaseTTL:        config.MaxLeaseTTL,
-		DefaultLeaseTTL:    config.DefaultLeaseTTL,
-		ClusterName:        config.ClusterName,
-		CacheSize:          config.CacheSize,
-		PluginDirectory:    config.PluginDirectory,
-		EnableRaw:          config.EnableRawEndpoint,
+		Physical:                   backend,
+		RedirectAddr:               config.Storage.RedirectAddr,
+		HAPhysical:                 nil,
+		Seal:                       seal,
+		MigrationSeal:              migrationSeal,
+		AuditBackends:              c.AuditBackends,
+		CredentialBackends:         c.CredentialBackends,
+		LogicalBackends:            c.LogicalBackends,
+		Logger:                     c.logger,
+		DisableCache:               config.DisableCache,
+		DisableMlock:               config.DisableMlock,
+		MaxLeaseTTL:                config.MaxLeaseTTL,
+		DefaultLeaseTTL:            config.DefaultLeaseTTL,
+		ClusterName:                config.ClusterName,
+		LeaseRevocationMaxAttempts: config.LeaseRevocationMaxAttempts,
+		CacheSize:                  config.CacheSize,
+		PluginDirectory:            config.PluginDirectory,
+		EnableRaw:                  config.EnableRawEndpoint,
+		MetricsHelper:              metricsHelper,
+	}
+	if config.Telemetry != nil {
+		coreConfig.UnauthenticatedMetricsAccess = config.Telemetry.UnauthenticatedMetricsAccess
 	}
 
 	if dev {
@@ -434,7 +467,11 @@ CLUSTER_SYNTHESIS_COMPLETE:
 	info["mlock"] = fmt.Sprintf(
 		"supported: %v, enabled: %v",
 		mlock.Supported(), !config.DisableMlock && mlock.Supported())
-	infoKeys = append(infoKeys, "log level", "mlock", "storage")
+	info["seal"] = seal.BarrierType()
+	if config.Seal != nil && config.Seal.Disabled {
+		info["seal"] += fmt.Sprintf(" (migrating from %s)", config.Seal.Type)
+	}
+	infoKeys = append(infoKeys, "log level", "mlock", "storage", "seal")
 
 	if coreConfig.ClusterAddr != "" {
 		info["cluster address"] = coreConfig.ClusterAddr
@@ -689,13 +726,13 @@ CLUSTER_SYNTHESIS_COMPLETE:
 	c.logGate.Flush()
 
 	// Write out the PID to the file now that server has successfully started
-	if err := c.storePidFile(config.PidFile); err != nil {
+	if err := storePidFile(config.PidFile); err != nil {
 		c.Ui.Output(fmt.Sprintf("Error storing PID: %v", err))
 		return 1
 	}
 
 	defer func() {
-		if err := c.removePidFile(config.PidFile); err != nil {
+		if err := removePidFile(config.PidFile); err != nil {
 			c.Ui.Output(fmt.Sprintf("Error deleting the PID file: %v", err))
 		}
 	}()
@@ -1095,8 +1132,9 @@ func (c *ServerCommand) detectRedirect(detect physical.RedirectDetect,
 	return url.String(), nil
 }
 
-// setupTelemetry is used to setup the telemetry sub-systems
-func (c *ServerCommand) setupTelemetry(config *server.Config) error {
+// setupTelemetry is used to setup the telemetry sub-systems. The returned
+// helper serves the metrics kept in memory from sys/metrics.
+func (c *ServerCommand) setupTelemetry(config *server.Config) (*metricsutil.MetricsHelper, error) {
 	/* Setup telemetry
 	Aggregate on 10 second intervals for 1 minute. Expose the
 	metrics over stderr when there is a SIGUSR1 received.
@@ -1119,7 +1157,7 @@ func (c *ServerCommand) setupTelemetry(config *server.Config) error {
 	if telConfig.StatsiteAddr != "" {
 		sink, err := metrics.NewStatsiteSink(telConfig.StatsiteAddr)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		fanout = append(fanout, sink)
 	}
@@ -1128,7 +1166,7 @@ func (c *ServerCommand) setupTelemetry(config *server.Config) error {
 	if telConfig.StatsdAddr != "" {
 		sink, err := metrics.NewStatsdSink(telConfig.StatsdAddr)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		fanout = append(fanout, sink)
 	}
@@ -1164,7 +1202,7 @@ func (c *ServerCommand) setupTelemetry(config *server.Config) error {
 
 		sink, err := circonus.NewCirconusSink(cfg)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		sink.Start()
 		fanout = append(fanout, sink)
@@ -1179,21 +1217,36 @@ func (c *ServerCommand) setupTelemetry(config *server.Config) error {
 
 		sink, err := datadog.NewDogStatsdSink(telConfig.DogStatsDAddr, metricsConf.HostName)
 		if err != nil {
-			return fmt.Errorf("failed to start DogStatsD sink. Got: %s", err)
+			return nil, fmt.Errorf("failed to start DogStatsD sink. Got: %s", err)
 		}
 		sink.SetTags(tags)
 		fanout = append(fanout, sink)
 	}
 
+	// Configure the Prometheus sink, which is scraped from sys/metrics
+	var prometheusSink *metricsutil.PrometheusSink
+	if telConfig.PrometheusRetentionTime > 0 {
+		prometheusSink = metricsutil.NewPrometheusSink(telConfig.PrometheusRetentionTime)
+	}
+
 	// Initialize the global sink
 	if len(fanout) > 0 {
 		fanout = append(fanout, inm)
+		if prometheusSink != nil {
+			fanout = append(fanout, prometheusSink)
+		}
 		metrics.NewGlobal(metricsConf, fanout)
 	} else {
+		// The hostname is only of use to tell apart the metrics pushed to
+		// a shared sink
 		metricsConf.EnableHostname = false
-		metrics.NewGlobal(metricsConf, inm)
+		if prometheusSink != nil {
+			metrics.NewGlobal(metricsConf, metrics.FanoutSink{inm, prometheusSink})
+		} else {
+			metrics.NewGlobal(metricsConf, inm)
+		}
 	}
-	return nil
+	return metricsutil.NewMetricsHelper(inm, prometheusSink), nil
 }
 
 func (c *ServerCommand) Reload(lock *sync.RWMutex, reloadFuncs *map[string][]reload.ReloadFunc, configPath []string) error {
@@ -1290,7 +1343,7 @@ func (c *ServerCommand) AutocompleteFlags() complete.Flags {
 }
 
 // storePidFile is used to write out our PID to a file if necessary
-func (c *ServerCommand) storePidFile(pidPath string) error {
+func storePidFile(pidPath string) error {
 	// Quit fast if no pidfile
 	if pidPath == "" {
 		return nil
@@ -1313,7 +1366,7 @@ func (c *ServerCommand) storePidFile(pidPath string) error {
 }
 
 // removePidFile is used to cleanup the PID file if necessary
-func (c *ServerCommand) removePidFile(pidPath string) error {
+func removePidFile(pidPath string) error {
 	if pidPath == "" {
 		return nil
 	}
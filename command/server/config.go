@@ -42,6 +42,8 @@ type Config struct {
 	DefaultLeaseTTL    time.Duration `hcl:"-"`
 	DefaultLeaseTTLRaw interface{}   `hcl:"default_lease_ttl"`
 
+	LeaseRevocationMaxAttempts int `hcl:"lease_revocation_max_attempts"`
+
 	ClusterName         string `hcl:"cluster_name"`
 	ClusterCipherSuites string `hcl:"cluster_cipher_suites"`
 
@@ -117,7 +119,12 @@ func (b *Storage) GoString() string {
 
 // Seal contains Seal configuration for the server
 type Seal struct {
-	Type   string
+	Type string
+
+	// Disabled marks a seal that is only used to migrate away from, back to
+	// Shamir unseal keys
+	Disabled bool
+
 	Config map[string]string
 }
 
@@ -211,6 +218,16 @@ type Telemetry struct {
 	// DogStatsdTags are the global tags that should be sent with each packet to dogstatsd
 	// It is a list of strings, where each string looks like "my_tag_name:my_tag_value"
 	DogStatsDTags []string `hcl:"dogstatsd_tags"`
+
+	// Prometheus:
+	// PrometheusRetentionTime is how long a metric is kept for sys/metrics
+	// after its last update. Prometheus metrics are disabled if it is zero.
+	PrometheusRetentionTime    time.Duration `hcl:"-"`
+	PrometheusRetentionTimeRaw interface{}   `hcl:"prometheus_retention_time"`
+
+	// UnauthenticatedMetricsAccess allows sys/metrics to be read without a
+	// token
+	UnauthenticatedMetricsAccess bool `hcl:"unauthenticated_metrics_access"`
 }
 
 func (s *Telemetry) GoString() string {
@@ -278,6 +295,12 @@ func (c *Config) Merge(c2 *Config) *Config {
 		result.DefaultLeaseTTL = c2.DefaultLeaseTTL
 	}
 
+	// a later file overrides the revocation attempts, so they can be lowered
+	result.LeaseRevocationMaxAttempts = c.LeaseRevocationMaxAttempts
+	if c2.LeaseRevocationMaxAttempts != 0 {
+		result.LeaseRevocationMaxAttempts = c2.LeaseRevocationMaxAttempts
+	}
+
 	result.ClusterName = c.ClusterName
 	if c2.ClusterName != "" {
 		result.ClusterName = c2.ClusterName
@@ -403,6 +426,7 @@ func ParseConfig(d string, logger log.Logger) (*Config, error) {
 		"telemetry",
 		"default_lease_ttl",
 		"max_lease_ttl",
+		"lease_revocation_max_attempts",
 		"cluster_name",
 		"cluster_cipher_suites",
 		"plugin_directory",
@@ -689,10 +713,29 @@ func parseSeal(result *Config, list *ast.ObjectList, blockName string) error {
 			"kms_key_id",
 			"max_parallel",
 		}
+	case "transit":
+		valid = []string{
+			"address",
+			"token",
+			"mount_path",
+			"key_name",
+			"tls_ca_cert",
+			"tls_client_cert",
+			"tls_client_key",
+			"tls_server_name",
+			"tls_skip_verify",
+		}
+	case "keyfile":
+		valid = []string{
+			"path",
+			"generate_key",
+		}
 	default:
 		return fmt.Errorf("invalid seal type %q", key)
 	}
 
+	valid = append(valid, "disabled")
+
 	if err := checkHCLKeys(item.Val, valid); err != nil {
 		return multierror.Prefix(err, fmt.Sprintf("%s.%s:", blockName, key))
 	}
@@ -702,9 +745,20 @@ func parseSeal(result *Config, list *ast.ObjectList, blockName string) error {
 		return multierror.Prefix(err, fmt.Sprintf("%s.%s:", blockName, key))
 	}
 
+	var disabled bool
+	if v, ok := m["disabled"]; ok {
+		var err error
+		disabled, err = strconv.ParseBool(v)
+		if err != nil {
+			return multierror.Prefix(err, fmt.Sprintf("%s.%s:", blockName, key))
+		}
+		delete(m, "disabled")
+	}
+
 	result.Seal = &Seal{
-		Type:   strings.ToLower(key),
-		Config: m,
+		Type:     strings.ToLower(key),
+		Disabled: disabled,
+		Config:   m,
 	}
 
 	return nil
@@ -784,8 +838,10 @@ func parseTelemetry(result *Config, list *ast.ObjectList) error {
 		"disable_hostname",
 		"dogstatsd_addr",
 		"dogstatsd_tags",
+		"prometheus_retention_time",
 		"statsd_address",
 		"statsite_address",
+		"unauthenticated_metrics_access",
 	}
 	if err := checkHCLKeys(item.Val, valid); err != nil {
 		return multierror.Prefix(err, "telemetry:")
@@ -803,6 +859,13 @@ func parseTelemetry(result *Config, list *ast.ObjectList) error {
 	if err := hcl.DecodeObject(&result.Telemetry, item.Val); err != nil {
 		return multierror.Prefix(err, "telemetry:")
 	}
+
+	if result.Telemetry.PrometheusRetentionTimeRaw != nil {
+		var err error
+		if result.Telemetry.PrometheusRetentionTime, err = parseutil.ParseDurationSecond(result.Telemetry.PrometheusRetentionTimeRaw); err != nil {
+			return multierror.Prefix(err, "telemetry.prometheus_retention_time:")
+		}
+	}
 	return nil
 }
 
@@ -259,6 +259,22 @@ func TestLoadConfigDir(t *testing.T) {
 	}
 }
 
+func TestConfig_Merge_leaseRevocationMaxAttempts(t *testing.T) {
+	base := &Config{LeaseRevocationMaxAttempts: 10}
+
+	// A later file can lower the limit
+	merged := base.Merge(&Config{LeaseRevocationMaxAttempts: 3})
+	if merged.LeaseRevocationMaxAttempts != 3 {
+		t.Fatalf("expected 3 attempts, got %d", merged.LeaseRevocationMaxAttempts)
+	}
+
+	// A file that does not set it keeps the earlier value
+	merged = base.Merge(&Config{})
+	if merged.LeaseRevocationMaxAttempts != 10 {
+		t.Fatalf("expected 10 attempts, got %d", merged.LeaseRevocationMaxAttempts)
+	}
+}
+
 func TestParseListeners(t *testing.T) {
 	obj, _ := hcl.Parse(strings.TrimSpace(`
 listener "tcp" {
@@ -380,3 +396,77 @@ telemetry {
 		t.Errorf("bad error: %q", err)
 	}
 }
+
+func TestParseConfig_seal(t *testing.T) {
+	logger := logformat.NewVaultLogger(log.LevelTrace)
+
+	config, err := ParseConfig(strings.TrimSpace(`
+seal "transit" {
+	address  = "https://vault.example.com:8200"
+	key_name = "unseal"
+	disabled = "true"
+}
+`), logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := &Seal{
+		Type:     "transit",
+		Disabled: true,
+		Config: map[string]string{
+			"address":  "https://vault.example.com:8200",
+			"key_name": "unseal",
+		},
+	}
+	if !reflect.DeepEqual(config.Seal, expected) {
+		t.Fatalf("expected \n\n%#v\n\n to be \n\n%#v\n\n", config.Seal, expected)
+	}
+
+	_, err = ParseConfig(strings.TrimSpace(`
+seal "keyfile" {
+	path = "/etc/vault/kek"
+	bad  = "one"
+}
+`), logger)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if !strings.Contains(err.Error(), "seal.keyfile: invalid key 'bad' on line 3") {
+		t.Errorf("bad error: %q", err)
+	}
+}
+
+func TestParseConfig_prometheusTelemetry(t *testing.T) {
+	logger := logformat.NewVaultLogger(log.LevelTrace)
+
+	config, err := ParseConfig(strings.TrimSpace(`
+telemetry {
+	prometheus_retention_time      = "12h"
+	unauthenticated_metrics_access = true
+	disable_hostname               = true
+}
+`), logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := &Telemetry{
+		PrometheusRetentionTime:      12 * time.Hour,
+		PrometheusRetentionTimeRaw:   "12h",
+		UnauthenticatedMetricsAccess: true,
+		DisableHostname:              true,
+	}
+	if !reflect.DeepEqual(config.Telemetry, expected) {
+		t.Fatalf("expected \n\n%#v\n\n to be \n\n%#v\n\n", config.Telemetry, expected)
+	}
+
+	_, err = ParseConfig(strings.TrimSpace(`
+telemetry {
+	prometheus_retention_time = "forever"
+}
+`), logger)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+}
@@ -0,0 +1,37 @@
+package command
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/vault/command/server"
+	"github.com/hashicorp/vault/vault"
+	vaultseal "github.com/hashicorp/vault/vault/seal"
+	"github.com/hashicorp/vault/vault/seal/keyfile"
+	"github.com/hashicorp/vault/vault/seal/transit"
+	log "github.com/mgutz/logxi/v1"
+)
+
+// configureSeal creates the auto seal described by the seal stanza of the
+// configuration
+func configureSeal(config *server.Seal, logger log.Logger) (vault.Seal, error) {
+	var access vaultseal.Access
+	var err error
+
+	switch config.Type {
+	case vaultseal.Transit:
+		access, err = transit.NewSeal(config.Config, logger)
+	case vaultseal.KeyFile:
+		access, err = keyfile.NewSeal(config.Config, logger)
+	default:
+		return nil, fmt.Errorf("seal type %q is not supported by this version of Vault", config.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := access.Init(); err != nil {
+		return nil, err
+	}
+
+	return vault.NewAutoSeal(access), nil
+}
@@ -17,7 +17,7 @@ type TokenCreateCommand struct {
 
 func (c *TokenCreateCommand) Run(args []string) int {
 	var format string
-	var id, displayName, lease, ttl, explicitMaxTTL, period, role string
+	var id, displayName, lease, ttl, explicitMaxTTL, period, role, tokenType string
 	var orphan, noDefaultPolicy, renewable bool
 	var metadata map[string]string
 	var numUses int
@@ -31,6 +31,7 @@ func (c *TokenCreateCommand) Run(args []string) int {
 	flags.StringVar(&explicitMaxTTL, "explicit-max-ttl", "", "")
 	flags.StringVar(&period, "period", "", "")
 	flags.StringVar(&role, "role", "", "")
+	flags.StringVar(&tokenType, "type", "", "")
 	flags.BoolVar(&orphan, "orphan", false, "")
 	flags.BoolVar(&renewable, "renewable", true, "")
 	flags.BoolVar(&noDefaultPolicy, "no-default-policy", false, "")
@@ -73,6 +74,7 @@ func (c *TokenCreateCommand) Run(args []string) int {
 		Renewable:       new(bool),
 		ExplicitMaxTTL:  explicitMaxTTL,
 		Period:          period,
+		Type:            tokenType,
 	}
 	*tcr.Renewable = renewable
 
@@ -145,7 +147,12 @@ Token Options:
   -renewable=true         Whether or not the token is renewable to extend its
                           TTL up to Vault's configured maximum TTL for tokens.
                           This defaults to true; set to false to disable
-                          renewal of this token.
+                          renewal of this token. Batch tokens are never
+                          renewable.
+
+  -type="service"         The type of the token, "service" or "batch". Batch
+                          tokens are not persisted to storage, but cannot be
+                          renewed or revoked, and have no accessor.
 
   -metadata="key=value"   Metadata to associate with the token. This shows
                           up in the audit log. This can be specified multiple
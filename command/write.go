@@ -22,11 +22,13 @@ type WriteCommand struct {
 func (c *WriteCommand) Run(args []string) int {
 	var field, format string
 	var force bool
+	var cas int
 	flags := c.Meta.FlagSet("write", meta.FlagSetDefault)
 	flags.StringVar(&format, "format", "table", "")
 	flags.StringVar(&field, "field", "", "")
 	flags.BoolVar(&force, "force", false, "")
 	flags.BoolVar(&force, "f", false, "")
+	flags.IntVar(&cas, "cas", -1, "")
 	flags.Usage = func() { c.Ui.Error(c.Help()) }
 	if err := flags.Parse(args); err != nil {
 		return 1
@@ -57,6 +59,15 @@ func (c *WriteCommand) Run(args []string) int {
 		return 1
 	}
 
+	if cas >= 0 {
+		if data == nil {
+			data = make(map[string]interface{})
+		}
+		data["options"] = map[string]interface{}{
+			"cas": cas,
+		}
+	}
+
 	client, err := c.Client()
 	if err != nil {
 		c.Ui.Error(fmt.Sprintf(
@@ -137,6 +148,13 @@ Write Options:
   -field=field            If included, the raw value of the specified field
                           will be output raw to stdout.
 
+  -cas=version            If included, the write is sent with the given
+                          check-and-set version. Versioned backends such as
+                          the versioned KV backend ("kv-v2") only accept the
+                          write if the current version of the secret matches.
+                          A value of 0 only allows the write if the secret
+                          does not exist yet.
+
 `
 	return strings.TrimSpace(helpText)
 }
@@ -150,5 +168,6 @@ func (c *WriteCommand) AutocompleteFlags() complete.Flags {
 		"-force":  complete.PredictNothing,
 		"-format": predictFormat,
 		"-field":  complete.PredictNothing,
+		"-cas":    complete.PredictNothing,
 	}
 }
@@ -51,6 +51,8 @@ func (p *Alias) SentinelGet(key string) (interface{}, error) {
 		return ptypes.TimestampString(p.LastUpdateTime), nil
 	case "merged_from_entity_ids":
 		return p.MergedFromEntityIDs, nil
+	case "canonical_id":
+		return p.CanonicalID, nil
 	}
 
 	return nil, nil
@@ -77,6 +79,10 @@ func (g *Group) SentinelGet(key string) (interface{}, error) {
 		return ptypes.TimestampString(g.CreationTime), nil
 	case "last_update_time":
 		return ptypes.TimestampString(g.LastUpdateTime), nil
+	case "type":
+		return g.Type, nil
+	case "alias":
+		return g.Alias, nil
 	}
 
 	return nil, nil
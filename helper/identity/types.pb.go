@@ -11,6 +11,7 @@ It has these top-level messages:
 	Group
 	Entity
 	Alias
+	MFASecret
 */
 package identity
 
@@ -59,6 +60,14 @@ type Group struct {
 	// the groups belonging to a particular bucket during invalidation of the
 	// storage key.
 	BucketKeyHash string `sentinel:"" protobuf:"bytes,10,opt,name=bucket_key_hash,json=bucketKeyHash" json:"bucket_key_hash,omitempty"`
+	// Alias is used to mark this group as an external group by tying it to
+	// a group in an authentication source. External groups can have only
+	// one alias.
+	Alias *Alias `sentinel:"" protobuf:"bytes,11,opt,name=alias" json:"alias,omitempty"`
+	// Type indicates if this group is an internal group or an external
+	// group. The members of external groups are set on login, based on the
+	// groups the authentication source reports for the entity.
+	Type string `sentinel:"" protobuf:"bytes,12,opt,name=type" json:"type,omitempty"`
 }
 
 func (m *Group) Reset()                    { *m = Group{} }
@@ -136,6 +145,20 @@ func (m *Group) GetBucketKeyHash() string {
 	return ""
 }
 
+func (m *Group) GetAlias() *Alias {
+	if m != nil {
+		return m.Alias
+	}
+	return nil
+}
+
+func (m *Group) GetType() string {
+	if m != nil {
+		return m.Type
+	}
+	return ""
+}
+
 // Entity represents an entity that gets persisted and indexed.
 // Entity is fundamentally composed of zero or many aliases.
 type Entity struct {
@@ -177,6 +200,9 @@ type Entity struct {
 	// the entities belonging to a particular bucket during invalidation of the
 	// storage key.
 	BucketKeyHash string `sentinel:"" protobuf:"bytes,9,opt,name=bucket_key_hash,json=bucketKeyHash" json:"bucket_key_hash,omitempty"`
+	// MFASecrets holds the MFA secrets of the entity indexed by the name of
+	// the MFA method they belong to.
+	MFASecrets map[string]*MFASecret `sentinel:"" protobuf:"bytes,10,rep,name=mfa_secrets,json=mfaSecrets" json:"mfa_secrets,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
 }
 
 func (m *Entity) Reset()                    { *m = Entity{} }
@@ -247,6 +273,13 @@ func (m *Entity) GetBucketKeyHash() string {
 	return ""
 }
 
+func (m *Entity) GetMFASecrets() map[string]*MFASecret {
+	if m != nil {
+		return m.MFASecrets
+	}
+	return nil
+}
+
 // Alias represents the alias that gets stored inside of the
 // entity object in storage and also represents in an in-memory index of an
 // alias object.
@@ -285,6 +318,9 @@ type Alias struct {
 	// which this alias is transfered over to the entity to which it
 	// currently belongs to.
 	MergedFromEntityIDs []string `sentinel:"" protobuf:"bytes,10,rep,name=merged_from_entity_ids,json=mergedFromEntityIDs" json:"merged_from_entity_ids,omitempty"`
+	// CanonicalID is the identifier of the group to which a group alias
+	// belongs. It is not used by entity aliases.
+	CanonicalID string `sentinel:"" protobuf:"bytes,11,opt,name=canonical_id,json=canonicalID" json:"canonical_id,omitempty"`
 }
 
 func (m *Alias) Reset()                    { *m = Alias{} }
@@ -362,50 +398,134 @@ func (m *Alias) GetMergedFromEntityIDs() []string {
 	return nil
 }
 
+func (m *Alias) GetCanonicalID() string {
+	if m != nil {
+		return m.CanonicalID
+	}
+	return ""
+}
+
+// MFASecret is the secret of an entity for a TOTP MFA method. The TOTP
+// parameters of the method are copied into the secret when it is generated,
+// so that changes to the method do not invalidate existing secrets.
+type MFASecret struct {
+	// MethodName is the name of the MFA method this secret belongs to
+	MethodName string `sentinel:"" protobuf:"bytes,1,opt,name=method_name,json=methodName" json:"method_name,omitempty"`
+	// Key is the base32 encoded TOTP key
+	Key string `sentinel:"" protobuf:"bytes,2,opt,name=key" json:"key,omitempty"`
+	// Period is the number of seconds a passcode is valid for
+	Period uint32 `sentinel:"" protobuf:"varint,3,opt,name=period" json:"period,omitempty"`
+	// Algorithm is the hash algorithm used to generate passcodes
+	Algorithm string `sentinel:"" protobuf:"bytes,4,opt,name=algorithm" json:"algorithm,omitempty"`
+	// Digits is the number of digits in a passcode
+	Digits uint32 `sentinel:"" protobuf:"varint,5,opt,name=digits" json:"digits,omitempty"`
+	// Skew is the number of periods before and after the current one whose
+	// passcodes are accepted
+	Skew uint32 `sentinel:"" protobuf:"varint,6,opt,name=skew" json:"skew,omitempty"`
+}
+
+func (m *MFASecret) Reset()                    { *m = MFASecret{} }
+func (m *MFASecret) String() string            { return proto.CompactTextString(m) }
+func (*MFASecret) ProtoMessage()               {}
+func (*MFASecret) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
+
+func (m *MFASecret) GetMethodName() string {
+	if m != nil {
+		return m.MethodName
+	}
+	return ""
+}
+
+func (m *MFASecret) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *MFASecret) GetPeriod() uint32 {
+	if m != nil {
+		return m.Period
+	}
+	return 0
+}
+
+func (m *MFASecret) GetAlgorithm() string {
+	if m != nil {
+		return m.Algorithm
+	}
+	return ""
+}
+
+func (m *MFASecret) GetDigits() uint32 {
+	if m != nil {
+		return m.Digits
+	}
+	return 0
+}
+
+func (m *MFASecret) GetSkew() uint32 {
+	if m != nil {
+		return m.Skew
+	}
+	return 0
+}
+
 func init() {
 	proto.RegisterType((*Group)(nil), "identity.Group")
 	proto.RegisterType((*Entity)(nil), "identity.Entity")
 	proto.RegisterType((*Alias)(nil), "identity.Alias")
+	proto.RegisterType((*MFASecret)(nil), "identity.MFASecret")
 }
 
 func init() { proto.RegisterFile("types.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 570 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x94, 0xcd, 0x6e, 0xd3, 0x40,
-	0x10, 0xc7, 0xe5, 0x38, 0x1f, 0xf6, 0xa4, 0x4d, 0xcb, 0x82, 0x90, 0x15, 0x54, 0x08, 0x95, 0x40,
-	0x86, 0x83, 0x2b, 0xb5, 0x17, 0x28, 0x07, 0x54, 0x89, 0x02, 0x15, 0x42, 0x42, 0x56, 0x39, 0x5b,
-	0x9b, 0x78, 0x9a, 0xac, 0x1a, 0x7b, 0x2d, 0xef, 0x1a, 0xe1, 0x27, 0xe4, 0x39, 0x38, 0xf1, 0x1a,
-	0xc8, 0xb3, 0x76, 0x62, 0x08, 0x5f, 0x15, 0xb9, 0xd9, 0xff, 0x99, 0x1d, 0xcf, 0xce, 0xff, 0x37,
-	0x86, 0xa1, 0x2e, 0x33, 0x54, 0x41, 0x96, 0x4b, 0x2d, 0x99, 0x23, 0x62, 0x4c, 0xb5, 0xd0, 0xe5,
-	0xf8, 0xc1, 0x5c, 0xca, 0xf9, 0x12, 0x8f, 0x48, 0x9f, 0x16, 0x57, 0x47, 0x5a, 0x24, 0xa8, 0x34,
-	0x4f, 0x32, 0x93, 0x7a, 0xf8, 0xcd, 0x86, 0xde, 0x9b, 0x5c, 0x16, 0x19, 0x1b, 0x41, 0x47, 0xc4,
-	0x9e, 0x35, 0xb1, 0x7c, 0x37, 0xec, 0x88, 0x98, 0x31, 0xe8, 0xa6, 0x3c, 0x41, 0xaf, 0x43, 0x0a,
-	0x3d, 0xb3, 0x31, 0x38, 0x99, 0x5c, 0x8a, 0x99, 0x40, 0xe5, 0xd9, 0x13, 0xdb, 0x77, 0xc3, 0xd5,
-	0x3b, 0xf3, 0x61, 0x3f, 0xe3, 0x39, 0xa6, 0x3a, 0x9a, 0x57, 0xf5, 0x22, 0x11, 0x2b, 0xaf, 0x4b,
-	0x39, 0x23, 0xa3, 0xd3, 0x67, 0x2e, 0x62, 0xc5, 0x9e, 0xc2, 0xad, 0x04, 0x93, 0x29, 0xe6, 0x91,
-	0xe9, 0x92, 0x52, 0x7b, 0x94, 0xba, 0x67, 0x02, 0xe7, 0xa4, 0x57, 0xb9, 0xcf, 0xc1, 0x49, 0x50,
-	0xf3, 0x98, 0x6b, 0xee, 0xf5, 0x27, 0xb6, 0x3f, 0x3c, 0x3e, 0x08, 0x9a, 0xdb, 0x05, 0x54, 0x31,
-	0x78, 0x5f, 0xc7, 0xcf, 0x53, 0x9d, 0x97, 0xe1, 0x2a, 0x9d, 0xbd, 0x84, 0xdd, 0x59, 0x8e, 0x5c,
-	0x0b, 0x99, 0x46, 0xd5, 0xb5, 0xbd, 0xc1, 0xc4, 0xf2, 0x87, 0xc7, 0xe3, 0xc0, 0xcc, 0x24, 0x68,
-	0x66, 0x12, 0x5c, 0x36, 0x33, 0x09, 0x77, 0x9a, 0x03, 0x95, 0xc4, 0x5e, 0xc1, 0xfe, 0x92, 0x2b,
-	0x1d, 0x15, 0x59, 0xcc, 0x35, 0x9a, 0x1a, 0xce, 0x5f, 0x6b, 0x8c, 0xaa, 0x33, 0x1f, 0xe9, 0x08,
-	0x55, 0x79, 0x08, 0x3b, 0x89, 0x8c, 0xc5, 0x55, 0x19, 0x89, 0x34, 0xc6, 0xcf, 0x9e, 0x3b, 0xb1,
-	0xfc, 0x6e, 0x38, 0x34, 0xda, 0x45, 0x25, 0xb1, 0xc7, 0xb0, 0x37, 0x2d, 0x66, 0xd7, 0xa8, 0xa3,
-	0x6b, 0x2c, 0xa3, 0x05, 0x57, 0x0b, 0x0f, 0x68, 0xea, 0xbb, 0x46, 0x7e, 0x87, 0xe5, 0x5b, 0xae,
-	0x16, 0xe3, 0x17, 0xb0, 0xfb, 0xc3, 0x65, 0xd9, 0x3e, 0xd8, 0xd7, 0x58, 0xd6, 0xa6, 0x55, 0x8f,
-	0xec, 0x0e, 0xf4, 0x3e, 0xf1, 0x65, 0xd1, 0xd8, 0x66, 0x5e, 0x4e, 0x3b, 0xcf, 0xac, 0xc3, 0x2f,
-	0x36, 0xf4, 0xcd, 0x5c, 0xd9, 0x13, 0x18, 0xf0, 0xa5, 0xe0, 0x0a, 0x95, 0x67, 0xd1, 0x4c, 0xf7,
-	0xd6, 0x33, 0x3d, 0xab, 0x02, 0x61, 0x13, 0xaf, 0xa9, 0xe8, 0x6c, 0x50, 0x61, 0xb7, 0xa8, 0x38,
-	0x6d, 0x79, 0xd4, 0xa5, 0x7a, 0xf7, 0xd7, 0xf5, 0xcc, 0x27, 0xff, 0xdd, 0xa4, 0xde, 0x16, 0x4c,
-	0xea, 0xdf, 0xd8, 0x24, 0x42, 0x32, 0x9f, 0x63, 0xdc, 0x46, 0x72, 0xd0, 0x20, 0x59, 0x05, 0xd6,
-	0x48, 0xb6, 0x97, 0xc0, 0xf9, 0x69, 0x09, 0x7e, 0xe1, 0xa4, 0xbb, 0x75, 0x27, 0xbf, 0xda, 0xd0,
-	0x23, 0x9b, 0x36, 0x76, 0xf6, 0x1e, 0xb8, 0xab, 0xfe, 0xeb, 0x73, 0x0e, 0xd6, 0x8d, 0xb3, 0x03,
-	0x80, 0x44, 0x16, 0xa9, 0x8e, 0xaa, 0x5f, 0x45, 0x6d, 0xa0, 0x4b, 0xca, 0x65, 0x99, 0x21, 0x7b,
-	0x04, 0x23, 0x13, 0xe6, 0xb3, 0x19, 0x2a, 0x25, 0x73, 0xaf, 0x6b, 0x3a, 0x27, 0xf5, 0xac, 0x16,
-	0xd7, 0x55, 0x32, 0xae, 0x17, 0xe4, 0x56, 0x53, 0xe5, 0x03, 0xd7, 0x8b, 0x3f, 0xef, 0x2b, 0x35,
-	0xfd, 0x5b, 0x14, 0x1a, 0xb4, 0x06, 0x2d, 0xb4, 0x36, 0xf0, 0x70, 0xb6, 0x80, 0x87, 0x7b, 0x63,
-	0x3c, 0x4e, 0xe0, 0x6e, 0x8d, 0xc7, 0x55, 0x2e, 0x93, 0x36, 0x23, 0x40, 0x00, 0xdc, 0x36, 0xd1,
-	0xd7, 0xb9, 0x4c, 0x56, 0x9c, 0xfc, 0x97, 0xc7, 0xd3, 0x3e, 0x75, 0x75, 0xf2, 0x3d, 0x00, 0x00,
-	0xff, 0xff, 0x17, 0x1c, 0xfc, 0x89, 0xd8, 0x05, 0x00, 0x00,
+	// 725 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xad, 0x94, 0x5d, 0x6f, 0xd3, 0x30,
+	0x14, 0x86, 0xd5, 0xef, 0xe6, 0x64, 0x6d, 0x87, 0x87, 0xa6, 0xa8, 0x30, 0x36, 0x26, 0x0d, 0x6d,
+	0x5c, 0x64, 0xd2, 0x76, 0x03, 0xe3, 0x02, 0x55, 0x82, 0xc1, 0x84, 0x86, 0x50, 0x18, 0xd7, 0x91,
+	0xdb, 0xb8, 0xad, 0xb5, 0xa4, 0x8e, 0x12, 0x17, 0xe8, 0x3d, 0x3f, 0x04, 0xf1, 0xc3, 0xf8, 0x2d,
+	0xd8, 0xc7, 0x49, 0x13, 0xd6, 0xf1, 0x31, 0x6d, 0x77, 0xce, 0x7b, 0xce, 0x79, 0x6d, 0x9f, 0xf3,
+	0xc4, 0x60, 0xcb, 0x45, 0xcc, 0x52, 0x37, 0x4e, 0x84, 0x14, 0xa4, 0xcd, 0x03, 0x36, 0x93, 0x5c,
+	0x2e, 0xfa, 0xdb, 0x13, 0x21, 0x26, 0x21, 0x3b, 0x44, 0x7d, 0x38, 0x1f, 0x1f, 0x4a, 0x1e, 0xb1,
+	0x54, 0xd2, 0x28, 0x36, 0xa9, 0xbb, 0x3f, 0xea, 0xd0, 0x78, 0x93, 0x88, 0x79, 0x4c, 0xba, 0x50,
+	0xe5, 0x81, 0x53, 0xd9, 0xa9, 0xec, 0x5b, 0x9e, 0x5a, 0x11, 0x02, 0xf5, 0x19, 0x8d, 0x98, 0x53,
+	0x45, 0x05, 0xd7, 0xa4, 0x0f, 0xed, 0x58, 0x84, 0x7c, 0xc4, 0x59, 0xea, 0xd4, 0x76, 0x6a, 0x4a,
+	0x5f, 0x7e, 0x93, 0x7d, 0x58, 0x8f, 0x69, 0xa2, 0xf6, 0xf5, 0x27, 0xda, 0xcf, 0xe7, 0x41, 0xea,
+	0xd4, 0x31, 0xa7, 0x6b, 0x74, 0xdc, 0xe6, 0x2c, 0x48, 0xc9, 0x53, 0xb8, 0x17, 0xb1, 0x68, 0xc8,
+	0x12, 0xdf, 0x9c, 0x12, 0x53, 0x1b, 0x98, 0xda, 0x33, 0x81, 0xd7, 0xa8, 0xeb, 0xdc, 0xe7, 0xd0,
+	0x8e, 0x98, 0xa4, 0x01, 0x95, 0xd4, 0x69, 0xaa, 0x14, 0xfb, 0x68, 0xcb, 0xcd, 0x6f, 0xe7, 0xa2,
+	0xa3, 0x7b, 0x9e, 0xc5, 0x55, 0x51, 0xb2, 0xf0, 0x96, 0xe9, 0xe4, 0x25, 0x74, 0x46, 0x09, 0xa3,
+	0x92, 0x8b, 0x99, 0xaf, 0xaf, 0xed, 0xb4, 0xd4, 0x4d, 0xec, 0xa3, 0xbe, 0x6b, 0x7a, 0xe2, 0xe6,
+	0x3d, 0x71, 0x2f, 0xf2, 0x9e, 0x78, 0x6b, 0x79, 0x81, 0x96, 0xc8, 0x2b, 0x58, 0x0f, 0x69, 0x2a,
+	0xfd, 0x79, 0xac, 0xfc, 0x98, 0xf1, 0x68, 0xff, 0xd3, 0xa3, 0xab, 0x6b, 0x3e, 0x61, 0x09, 0xba,
+	0x3c, 0x86, 0xb5, 0x48, 0x04, 0x7c, 0xac, 0xae, 0x39, 0x0b, 0xd8, 0x57, 0xc7, 0x52, 0x0e, 0x75,
+	0xcf, 0x36, 0xda, 0x99, 0x96, 0xc8, 0x13, 0xe8, 0x0d, 0xe7, 0xa3, 0x4b, 0x26, 0xfd, 0x4b, 0xb6,
+	0xf0, 0xa7, 0x34, 0x9d, 0x3a, 0x80, 0x5d, 0xef, 0x18, 0xf9, 0x1d, 0x5b, 0xbc, 0x55, 0x22, 0xd9,
+	0x83, 0x06, 0x0d, 0x39, 0x4d, 0x1d, 0x1b, 0x4f, 0xd1, 0x2b, 0x3a, 0x31, 0xd0, 0xb2, 0x67, 0xa2,
+	0x7a, 0x72, 0x9a, 0x06, 0x67, 0xcd, 0x4c, 0x4e, 0xaf, 0xfb, 0x2f, 0xa0, 0xf3, 0x5b, 0x9f, 0xc8,
+	0x3a, 0xd4, 0xd4, 0x66, 0xd9, 0xbc, 0xf5, 0x92, 0xdc, 0x87, 0xc6, 0x67, 0x1a, 0xce, 0xf3, 0x89,
+	0x9b, 0x8f, 0x93, 0xea, 0xb3, 0xca, 0xee, 0xcf, 0x3a, 0x34, 0xcd, 0x48, 0xc8, 0x01, 0xb4, 0x70,
+	0x13, 0x05, 0x40, 0x05, 0xc7, 0xb1, 0x72, 0x88, 0x3c, 0x9e, 0x01, 0x55, 0x5d, 0x01, 0xaa, 0x56,
+	0x02, 0xea, 0xa4, 0x34, 0xde, 0x3a, 0xfa, 0x3d, 0x2a, 0xfc, 0xcc, 0x96, 0xff, 0x3f, 0xdf, 0xc6,
+	0x1d, 0xcc, 0xb7, 0x79, 0xe3, 0xf9, 0x22, 0xcd, 0xc9, 0x84, 0x05, 0x65, 0x9a, 0x5b, 0x39, 0xcd,
+	0x3a, 0x50, 0xd0, 0x5c, 0xfe, 0x7f, 0xda, 0x57, 0xfe, 0x9f, 0x6b, 0x20, 0xb0, 0xae, 0x83, 0x60,
+	0x00, 0x76, 0x34, 0xa6, 0x7e, 0xca, 0xd4, 0x5d, 0x64, 0xaa, 0x40, 0xd1, 0x5d, 0xdb, 0x59, 0xed,
+	0xda, 0x98, 0x7e, 0x34, 0x29, 0xa6, 0x6f, 0x10, 0x2d, 0x85, 0x5b, 0xc1, 0xd0, 0xf7, 0xa0, 0x77,
+	0xc5, 0xfb, 0x9a, 0xf2, 0x83, 0x72, 0xb9, 0x7d, 0xb4, 0x51, 0x1c, 0xef, 0xfc, 0x74, 0x60, 0x6a,
+	0xcb, 0x80, 0x7d, 0x53, 0xaf, 0x10, 0xd2, 0xb3, 0xf2, 0x0a, 0x3d, 0x00, 0x6b, 0xd9, 0xd6, 0xec,
+	0x2c, 0x6d, 0x96, 0xf5, 0x93, 0x6c, 0x01, 0x44, 0x62, 0xae, 0x5e, 0x1c, 0xc4, 0xdd, 0x70, 0x65,
+	0xa1, 0x72, 0xa1, 0x04, 0xf5, 0xbb, 0x74, 0x4d, 0x98, 0x8e, 0x46, 0x2c, 0x4d, 0x45, 0xa2, 0x10,
+	0xc3, 0x86, 0xa2, 0x3a, 0xc8, 0xc4, 0xc2, 0x25, 0xa6, 0x72, 0x8a, 0x10, 0xe5, 0x2e, 0x1f, 0x94,
+	0xf0, 0xf7, 0x17, 0x08, 0x0f, 0xfd, 0x47, 0x42, 0x73, 0xe2, 0x5b, 0x25, 0xe2, 0x57, 0xa8, 0x6d,
+	0xdf, 0x01, 0xb5, 0xd6, 0x8d, 0xa9, 0x3d, 0x86, 0xcd, 0x8c, 0xda, 0x71, 0x22, 0xa2, 0x32, 0xba,
+	0x80, 0x5c, 0x6e, 0x98, 0xe8, 0xa9, 0x0a, 0x16, 0xf8, 0xaa, 0xa7, 0x6c, 0x44, 0x67, 0x62, 0xc6,
+	0x47, 0x34, 0xd4, 0xf3, 0xb0, 0xf1, 0x5e, 0xf6, 0x52, 0x3b, 0x0b, 0x6e, 0xf7, 0xce, 0x7c, 0xaf,
+	0x80, 0xb5, 0xe4, 0x83, 0x6c, 0x2b, 0xd0, 0x99, 0x9c, 0x8a, 0xc0, 0xc7, 0x26, 0x1a, 0x07, 0x30,
+	0xd2, 0x7b, 0xdd, 0xca, 0xcc, 0xba, 0x5a, 0x58, 0x6f, 0x42, 0x33, 0x66, 0x09, 0x17, 0x01, 0xc2,
+	0xd0, 0xf1, 0xb2, 0x2f, 0xf2, 0x10, 0x2c, 0x1a, 0x4e, 0x44, 0xc2, 0xe5, 0x34, 0xca, 0x20, 0x28,
+	0x04, 0x5d, 0x15, 0xf0, 0x09, 0x97, 0x29, 0x0e, 0x5f, 0x55, 0x99, 0x2f, 0x3d, 0xbe, 0xf4, 0x92,
+	0x7d, 0xc1, 0x37, 0xa1, 0xe3, 0xe1, 0x7a, 0xd8, 0xc4, 0xde, 0x1e, 0xff, 0x02, 0x02, 0x5e, 0x2a,
+	0x26, 0x70, 0x07, 0x00, 0x00,
 }
@@ -0,0 +1,113 @@
+package keysutil
+
+import (
+	"crypto/aes"
+	"crypto/subtle"
+	"encoding/binary"
+	"fmt"
+)
+
+// kwpIV is the alternative initial value of AES key wrap with padding, see
+// RFC 5649
+var kwpIV = []byte{0xA6, 0x59, 0x59, 0xA6}
+
+// KeyWrapWithPadding wraps the plaintext with the given AES key using AES key
+// wrap with padding (RFC 5649)
+func KeyWrapWithPadding(kek, plaintext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+	if len(plaintext) == 0 {
+		return nil, fmt.Errorf("plaintext to wrap is empty")
+	}
+
+	n := (len(plaintext) + 7) / 8
+	a := make([]byte, 8)
+	copy(a, kwpIV)
+	binary.BigEndian.PutUint32(a[4:], uint32(len(plaintext)))
+
+	padded := make([]byte, 8*n)
+	copy(padded, plaintext)
+
+	buf := make([]byte, 16)
+	if n == 1 {
+		copy(buf, a)
+		copy(buf[8:], padded)
+		block.Encrypt(buf, buf)
+		return buf, nil
+	}
+
+	for j := 0; j < 6; j++ {
+		for i := 0; i < n; i++ {
+			copy(buf, a)
+			copy(buf[8:], padded[8*i:8*i+8])
+			block.Encrypt(buf, buf)
+			copy(a, buf[:8])
+			xorCounter(a, uint64(n*j+i+1))
+			copy(padded[8*i:], buf[8:])
+		}
+	}
+
+	return append(a, padded...), nil
+}
+
+// KeyUnwrapWithPadding unwraps the ciphertext with the given AES key using AES
+// key wrap with padding (RFC 5649) and checks its integrity
+func KeyUnwrapWithPadding(kek, ciphertext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < 16 || len(ciphertext)%8 != 0 {
+		return nil, fmt.Errorf("invalid wrapped key length %d", len(ciphertext))
+	}
+
+	n := len(ciphertext)/8 - 1
+	a := make([]byte, 8)
+	r := make([]byte, 8*n)
+
+	buf := make([]byte, 16)
+	if n == 1 {
+		block.Decrypt(buf, ciphertext)
+		copy(a, buf[:8])
+		copy(r, buf[8:])
+	} else {
+		copy(a, ciphertext[:8])
+		copy(r, ciphertext[8:])
+		for j := 5; j >= 0; j-- {
+			for i := n - 1; i >= 0; i-- {
+				xorCounter(a, uint64(n*j+i+1))
+				copy(buf, a)
+				copy(buf[8:], r[8*i:8*i+8])
+				block.Decrypt(buf, buf)
+				copy(a, buf[:8])
+				copy(r[8*i:], buf[8:])
+			}
+		}
+	}
+
+	if subtle.ConstantTimeCompare(a[:4], kwpIV) != 1 {
+		return nil, fmt.Errorf("wrapped key failed integrity check")
+	}
+	mli := int(binary.BigEndian.Uint32(a[4:]))
+	if mli <= 8*(n-1) || mli > 8*n {
+		return nil, fmt.Errorf("wrapped key failed integrity check")
+	}
+	for _, b := range r[mli:] {
+		if b != 0 {
+			return nil, fmt.Errorf("wrapped key failed integrity check")
+		}
+	}
+
+	return r[:mli], nil
+}
+
+// xorCounter XORs the big-endian counter t into the 64-bit register a
+func xorCounter(a []byte, t uint64) {
+	var tb [8]byte
+	binary.BigEndian.PutUint64(tb[:], t)
+	for k := range a {
+		a[k] ^= tb[k]
+	}
+}
@@ -0,0 +1,51 @@
+package keysutil
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func TestKeyWrapWithPadding(t *testing.T) {
+	// Test vectors from RFC 5649 section 6
+	kek, _ := hex.DecodeString("5840df6e29b02af1ab493b705bf16ea1ae8338f4dcc176a8")
+	cases := []struct {
+		plaintext  string
+		ciphertext string
+	}{
+		{
+			"c37b7e6492584340bed12207808941155068f738",
+			"138bdeaa9b8fa7fc61f97742e72248ee5ae6ae5360d1ae6a5f54f373fa543b6a",
+		},
+		{
+			"466f7250617369",
+			"afbeb0f07dfbf5419200f2ccb50bb24f",
+		},
+	}
+
+	for _, c := range cases {
+		plaintext, _ := hex.DecodeString(c.plaintext)
+		expected, _ := hex.DecodeString(c.ciphertext)
+
+		ciphertext, err := KeyWrapWithPadding(kek, plaintext)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(ciphertext, expected) {
+			t.Fatalf("bad ciphertext: expected %x, got %x", expected, ciphertext)
+		}
+
+		unwrapped, err := KeyUnwrapWithPadding(kek, ciphertext)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(unwrapped, plaintext) {
+			t.Fatalf("bad plaintext: expected %x, got %x", plaintext, unwrapped)
+		}
+
+		ciphertext[len(ciphertext)-1] ^= 1
+		if _, err := KeyUnwrapWithPadding(kek, ciphertext); err == nil {
+			t.Fatal("expected integrity check failure")
+		}
+	}
+}
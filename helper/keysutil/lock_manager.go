@@ -1,10 +1,18 @@
 package keysutil
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"sync"
+	"time"
 
+	uuid "github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/helper/errutil"
 	"github.com/hashicorp/vault/helper/jsonutil"
 	"github.com/hashicorp/vault/logical"
 )
@@ -12,6 +20,10 @@ import (
 const (
 	shared    = false
 	exclusive = true
+
+	// BackupHMACKeyPath is where the key authenticating the backups of a
+	// mount is stored
+	BackupHMACKeyPath = "backup_hmac_key"
 )
 
 var (
@@ -39,6 +51,12 @@ type PolicyRequest struct {
 	// Whether to allow export
 	Exportable bool
 
+	// Whether to allow rotation of an imported key
+	AllowImportedKeyRotation bool
+
+	// Whether to allow plaintext backups
+	AllowPlaintextBackup bool
+
 	// Whether to upsert
 	Upsert bool
 }
@@ -55,6 +73,9 @@ type LockManager struct {
 
 	// Used for global locking, and as the cache map mutex
 	cacheMutex sync.RWMutex
+
+	// Guards the creation of the backup HMAC key
+	backupKeyMutex sync.Mutex
 }
 
 func NewLockManager(cacheDisabled bool) *LockManager {
@@ -240,41 +261,12 @@ func (lm *LockManager) getPolicyCommon(req PolicyRequest, lockType bool) (*Polic
 			return nil, nil, false, errNeedExclusiveLock
 		}
 
-		switch req.KeyType {
-		case KeyType_AES256_GCM96:
-			if req.Convergent && !req.Derived {
-				lm.UnlockPolicy(lock, lockType)
-				return nil, nil, false, fmt.Errorf("convergent encryption requires derivation to be enabled")
-			}
-
-		case KeyType_ECDSA_P256:
-			if req.Derived || req.Convergent {
-				lm.UnlockPolicy(lock, lockType)
-				return nil, nil, false, fmt.Errorf("key derivation and convergent encryption not supported for keys of type %v", req.KeyType)
-			}
-
-		case KeyType_ED25519:
-			if req.Convergent {
-				lm.UnlockPolicy(lock, lockType)
-				return nil, nil, false, fmt.Errorf("convergent encryption not not supported for keys of type %v", req.KeyType)
-			}
-
-		default:
+		if err := validatePolicyRequest(req); err != nil {
 			lm.UnlockPolicy(lock, lockType)
-			return nil, nil, false, fmt.Errorf("unsupported key type %v", req.KeyType)
+			return nil, nil, false, err
 		}
 
-		p = &Policy{
-			Name:       req.Name,
-			Type:       req.KeyType,
-			Derived:    req.Derived,
-			Exportable: req.Exportable,
-		}
-		if req.Derived {
-			p.KDF = Kdf_hkdf_sha256
-			p.ConvergentEncryption = req.Convergent
-			p.ConvergentVersion = 2
-		}
+		p = newPolicy(req)
 
 		err = p.Rotate(req.Storage)
 		if err != nil {
@@ -334,6 +326,246 @@ func (lm *LockManager) getPolicyCommon(req PolicyRequest, lockType bool) (*Polic
 	return p, lock, false, nil
 }
 
+// ImportPolicy creates a new policy holding the given key material; see
+// Policy.Import for its format. It is an error if the policy already exists.
+func (lm *LockManager) ImportPolicy(req PolicyRequest, key []byte) error {
+	lock := lm.policyLock(req.Name, exclusive)
+	defer lock.Unlock()
+
+	var p *Policy
+	var err error
+	if lm.CacheActive() {
+		lm.cacheMutex.RLock()
+		p = lm.cache[req.Name]
+		lm.cacheMutex.RUnlock()
+	}
+	if p == nil {
+		p, err = lm.getStoredPolicy(req.Storage, req.Name)
+		if err != nil {
+			return err
+		}
+	}
+	if p != nil {
+		return errutil.UserError{Err: fmt.Sprintf("key %q already exists", req.Name)}
+	}
+
+	if err := validatePolicyRequest(req); err != nil {
+		return errutil.UserError{Err: err.Error()}
+	}
+
+	p = newPolicy(req)
+	p.Imported = true
+	p.AllowImportedKeyRotation = req.AllowImportedKeyRotation
+
+	if err := p.Import(req.Storage, key); err != nil {
+		return err
+	}
+
+	if lm.CacheActive() {
+		lm.cacheMutex.Lock()
+		lm.cache[req.Name] = p
+		lm.cacheMutex.Unlock()
+	}
+
+	return nil
+}
+
+// keyData is the content of a policy backup
+type keyData struct {
+	Policy       *Policy       `json:"policy"`
+	ArchivedKeys *archivedKeys `json:"archived_keys"`
+}
+
+// policyBackup is the serialized form of a policy backup. The HMAC, keyed by
+// the backup HMAC key of the mount, protects the key data against tampering.
+type policyBackup struct {
+	KeyData json.RawMessage `json:"key_data"`
+	HMAC    string          `json:"hmac"`
+}
+
+// backupHMACKey returns the key authenticating the backups of the mount the
+// storage belongs to. If create is set, the key is generated if the mount has
+// none yet; otherwise nil is returned in that case.
+func (lm *LockManager) backupHMACKey(storage logical.Storage, create bool) ([]byte, error) {
+	lm.backupKeyMutex.Lock()
+	defer lm.backupKeyMutex.Unlock()
+
+	entry, err := storage.Get(BackupHMACKeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup HMAC key: %v", err)
+	}
+	if entry != nil {
+		return entry.Value, nil
+	}
+	if !create {
+		return nil, nil
+	}
+
+	key, err := uuid.GenerateRandomBytes(32)
+	if err != nil {
+		return nil, err
+	}
+	if err := storage.Put(&logical.StorageEntry{
+		Key:   BackupHMACKeyPath,
+		Value: key,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to persist backup HMAC key: %v", err)
+	}
+	return key, nil
+}
+
+// backupHMAC returns the HMAC of the key data of a backup
+func backupHMAC(key, data []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+// BackupPolicy returns a backup of the named policy, including all key
+// versions and the archive, as a base64-encoded string. Only policies that are
+// both exportable and allow plaintext backups may be backed up.
+func (lm *LockManager) BackupPolicy(storage logical.Storage, name string) (string, error) {
+	p, lock, _, err := lm.getPolicyCommon(PolicyRequest{
+		Storage: storage,
+		Name:    name,
+	}, exclusive)
+	if lock != nil {
+		defer lock.Unlock()
+	}
+	if err != nil {
+		return "", err
+	}
+	if p == nil {
+		return "", errutil.UserError{Err: fmt.Sprintf("key %q not found", name)}
+	}
+
+	if !p.Exportable || !p.AllowPlaintextBackup {
+		return "", errutil.UserError{Err: "backing up a key requires it to be exportable and to allow plaintext backups"}
+	}
+
+	archive, err := p.LoadArchive(storage)
+	if err != nil {
+		return "", err
+	}
+
+	p.BackupInfo = &BackupInfo{
+		Time:    time.Now(),
+		Version: p.LatestVersion,
+	}
+	if err := p.Persist(storage); err != nil {
+		return "", fmt.Errorf("failed to persist key data after backup: %v", err)
+	}
+
+	data, err := json.Marshal(&keyData{
+		Policy:       p,
+		ArchivedKeys: archive,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	hmacKey, err := lm.backupHMACKey(storage, true)
+	if err != nil {
+		return "", err
+	}
+
+	backup, err := json.Marshal(&policyBackup{
+		KeyData: data,
+		HMAC:    hex.EncodeToString(backupHMAC(hmacKey, data)),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(backup), nil
+}
+
+// RestorePolicy restores a backup created by BackupPolicy under the given
+// name, or under the name of the backed up policy if name is empty. An
+// existing policy is only replaced if force is set.
+func (lm *LockManager) RestorePolicy(storage logical.Storage, name, backup string, force bool) error {
+	backupBytes, err := base64.StdEncoding.DecodeString(backup)
+	if err != nil {
+		return errutil.UserError{Err: "failed to decode backup as base64"}
+	}
+
+	var pb policyBackup
+	if err := jsonutil.DecodeJSON(backupBytes, &pb); err != nil {
+		return errutil.UserError{Err: "failed to decode backup"}
+	}
+	hmacKey, err := lm.backupHMACKey(storage, false)
+	if err != nil {
+		return err
+	}
+	if hmacKey == nil {
+		return errutil.UserError{Err: "no backups were created by this mount"}
+	}
+	actual, err := hex.DecodeString(pb.HMAC)
+	if err != nil || !hmac.Equal(backupHMAC(hmacKey, pb.KeyData), actual) {
+		return errutil.UserError{Err: "backup failed integrity check"}
+	}
+
+	data := keyData{
+		Policy: &Policy{
+			Keys: keyEntryMap{},
+		},
+	}
+	if err := jsonutil.DecodeJSON(pb.KeyData, &data); err != nil {
+		return errutil.UserError{Err: "failed to decode key data of backup"}
+	}
+	if data.Policy == nil || data.Policy.LatestVersion == 0 || data.ArchivedKeys == nil {
+		return errutil.UserError{Err: "backup does not contain a key"}
+	}
+
+	if name == "" {
+		name = data.Policy.Name
+	}
+
+	lock := lm.policyLock(name, exclusive)
+	defer lock.Unlock()
+
+	if !force {
+		var p *Policy
+		if lm.CacheActive() {
+			lm.cacheMutex.RLock()
+			p = lm.cache[name]
+			lm.cacheMutex.RUnlock()
+		}
+		if p == nil {
+			p, err = lm.getStoredPolicy(storage, name)
+			if err != nil {
+				return err
+			}
+		}
+		if p != nil {
+			return errutil.UserError{Err: fmt.Sprintf("key %q already exists", name)}
+		}
+	}
+
+	p := data.Policy
+	p.Name = name
+	p.RestoreInfo = &RestoreInfo{
+		Time:       time.Now(),
+		Version:    p.LatestVersion,
+		BackupInfo: p.BackupInfo,
+	}
+
+	if err := p.storeArchive(data.ArchivedKeys, storage); err != nil {
+		return err
+	}
+	if err := p.Persist(storage); err != nil {
+		return err
+	}
+
+	if lm.CacheActive() {
+		lm.cacheMutex.Lock()
+		lm.cache[name] = p
+		lm.cacheMutex.Unlock()
+	}
+
+	return nil
+}
+
 func (lm *LockManager) DeletePolicy(storage logical.Storage, name string) error {
 	lm.cacheMutex.Lock()
 	lock := lm.policyLock(name, exclusive)
@@ -398,3 +630,47 @@ func (lm *LockManager) getStoredPolicy(storage logical.Storage, name string) (*P
 
 	return policy, nil
 }
+
+// validatePolicyRequest checks that the options of a new policy are supported
+// by its key type
+func validatePolicyRequest(req PolicyRequest) error {
+	switch req.KeyType {
+	case KeyType_AES256_GCM96, KeyType_AES128_GCM96, KeyType_ChaCha20_Poly1305:
+		if req.Convergent && !req.Derived {
+			return fmt.Errorf("convergent encryption requires derivation to be enabled")
+		}
+
+	case KeyType_ECDSA_P256, KeyType_ECDSA_P384, KeyType_ECDSA_P521, KeyType_RSA2048, KeyType_RSA4096:
+		if req.Derived || req.Convergent {
+			return fmt.Errorf("key derivation and convergent encryption not supported for keys of type %v", req.KeyType)
+		}
+
+	case KeyType_ED25519:
+		if req.Convergent {
+			return fmt.Errorf("convergent encryption not not supported for keys of type %v", req.KeyType)
+		}
+
+	default:
+		return fmt.Errorf("unsupported key type %v", req.KeyType)
+	}
+
+	return nil
+}
+
+// newPolicy returns a policy without keys for the given request
+func newPolicy(req PolicyRequest) *Policy {
+	p := &Policy{
+		Name:       req.Name,
+		Type:       req.KeyType,
+		Derived:    req.Derived,
+		Exportable: req.Exportable,
+
+		AllowPlaintextBackup: req.AllowPlaintextBackup,
+	}
+	if req.Derived {
+		p.KDF = Kdf_hkdf_sha256
+		p.ConvergentEncryption = req.Convergent
+		p.ConvergentVersion = 2
+	}
+	return p
+}
@@ -9,8 +9,10 @@ import (
 	"crypto/elliptic"
 	"crypto/hmac"
 	"crypto/rand"
+	"crypto/rsa"
 	"crypto/sha256"
 	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/asn1"
 	"encoding/base64"
 	"encoding/json"
@@ -22,6 +24,7 @@ import (
 	"strings"
 	"time"
 
+	"golang.org/x/crypto/chacha20poly1305"
 	"golang.org/x/crypto/ed25519"
 	"golang.org/x/crypto/hkdf"
 
@@ -44,6 +47,12 @@ const (
 	KeyType_AES256_GCM96 = iota
 	KeyType_ECDSA_P256
 	KeyType_ED25519
+	KeyType_ECDSA_P384
+	KeyType_ECDSA_P521
+	KeyType_AES128_GCM96
+	KeyType_ChaCha20_Poly1305
+	KeyType_RSA2048
+	KeyType_RSA4096
 )
 
 const ErrTooOld = "ciphertext or signature version is disallowed by policy (too old)"
@@ -61,7 +70,7 @@ type KeyType int
 
 func (kt KeyType) EncryptionSupported() bool {
 	switch kt {
-	case KeyType_AES256_GCM96:
+	case KeyType_AES256_GCM96, KeyType_AES128_GCM96, KeyType_ChaCha20_Poly1305, KeyType_RSA2048, KeyType_RSA4096:
 		return true
 	}
 	return false
@@ -69,7 +78,7 @@ func (kt KeyType) EncryptionSupported() bool {
 
 func (kt KeyType) DecryptionSupported() bool {
 	switch kt {
-	case KeyType_AES256_GCM96:
+	case KeyType_AES256_GCM96, KeyType_AES128_GCM96, KeyType_ChaCha20_Poly1305, KeyType_RSA2048, KeyType_RSA4096:
 		return true
 	}
 	return false
@@ -77,7 +86,7 @@ func (kt KeyType) DecryptionSupported() bool {
 
 func (kt KeyType) SigningSupported() bool {
 	switch kt {
-	case KeyType_ECDSA_P256, KeyType_ED25519:
+	case KeyType_ECDSA_P256, KeyType_ECDSA_P384, KeyType_ECDSA_P521, KeyType_ED25519, KeyType_RSA2048, KeyType_RSA4096:
 		return true
 	}
 	return false
@@ -85,7 +94,7 @@ func (kt KeyType) SigningSupported() bool {
 
 func (kt KeyType) HashSignatureInput() bool {
 	switch kt {
-	case KeyType_ECDSA_P256:
+	case KeyType_ECDSA_P256, KeyType_ECDSA_P384, KeyType_ECDSA_P521, KeyType_RSA2048, KeyType_RSA4096:
 		return true
 	}
 	return false
@@ -93,25 +102,84 @@ func (kt KeyType) HashSignatureInput() bool {
 
 func (kt KeyType) DerivationSupported() bool {
 	switch kt {
-	case KeyType_AES256_GCM96, KeyType_ED25519:
+	case KeyType_AES256_GCM96, KeyType_AES128_GCM96, KeyType_ChaCha20_Poly1305, KeyType_ED25519:
 		return true
 	}
 	return false
 }
 
+// SymmetricKeySize returns the size in bytes of the key of symmetric key
+// types, or zero for asymmetric ones
+func (kt KeyType) SymmetricKeySize() int {
+	switch kt {
+	case KeyType_AES256_GCM96, KeyType_ChaCha20_Poly1305:
+		return 32
+	case KeyType_AES128_GCM96:
+		return 16
+	}
+	return 0
+}
+
+// ECDSACurve returns the curve of ECDSA key types, or nil for other ones
+func (kt KeyType) ECDSACurve() elliptic.Curve {
+	switch kt {
+	case KeyType_ECDSA_P256:
+		return elliptic.P256()
+	case KeyType_ECDSA_P384:
+		return elliptic.P384()
+	case KeyType_ECDSA_P521:
+		return elliptic.P521()
+	}
+	return nil
+}
+
+// RSAKeyBits returns the size in bits of the modulus of RSA key types, or
+// zero for other ones
+func (kt KeyType) RSAKeyBits() int {
+	switch kt {
+	case KeyType_RSA2048:
+		return 2048
+	case KeyType_RSA4096:
+		return 4096
+	}
+	return 0
+}
+
 func (kt KeyType) String() string {
 	switch kt {
 	case KeyType_AES256_GCM96:
 		return "aes256-gcm96"
+	case KeyType_AES128_GCM96:
+		return "aes128-gcm96"
+	case KeyType_ChaCha20_Poly1305:
+		return "chacha20-poly1305"
 	case KeyType_ECDSA_P256:
 		return "ecdsa-p256"
+	case KeyType_ECDSA_P384:
+		return "ecdsa-p384"
+	case KeyType_ECDSA_P521:
+		return "ecdsa-p521"
 	case KeyType_ED25519:
 		return "ed25519"
+	case KeyType_RSA2048:
+		return "rsa-2048"
+	case KeyType_RSA4096:
+		return "rsa-4096"
 	}
 
 	return "[unknown]"
 }
 
+// ParseKeyType returns the key type with the given name
+func ParseKeyType(name string) (KeyType, error) {
+	for kt := KeyType(KeyType_AES256_GCM96); kt <= KeyType_RSA4096; kt++ {
+		if kt.String() == name {
+			return kt, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown key type %v", name)
+}
+
 // KeyEntry stores the key and metadata
 type KeyEntry struct {
 	// AES or some other kind that is a pure byte slice like ED25519
@@ -127,6 +195,8 @@ type KeyEntry struct {
 	EC_Y *big.Int `json:"ec_y"`
 	EC_D *big.Int `json:"ec_d"`
 
+	RSAKey *rsa.PrivateKey `json:"rsa_key"`
+
 	// The public key in an appropriate format for the type of key
 	FormattedPublicKey string `json:"public_key"`
 
@@ -201,6 +271,38 @@ type Policy struct {
 
 	// The type of key
 	Type KeyType `json:"type"`
+
+	// Whether the key material was imported rather than generated, and
+	// whether Vault may then rotate it by generating new key material
+	Imported                 bool `json:"imported"`
+	AllowImportedKeyRotation bool `json:"allow_imported_key_rotation"`
+
+	// Whether the key, including its key material, may be backed up. Like
+	// exportability, this cannot be disabled once enabled.
+	AllowPlaintextBackup bool `json:"allow_plaintext_backup"`
+
+	// Information about the last backup of the key, and about the backup the
+	// key was restored from, if any
+	BackupInfo  *BackupInfo  `json:"backup_info"`
+	RestoreInfo *RestoreInfo `json:"restore_info"`
+
+	// The period after which the key is rotated automatically, or zero if it
+	// is never rotated automatically, and the time of the last rotation
+	AutoRotatePeriod time.Duration `json:"auto_rotate_period"`
+	LastRotationTime time.Time     `json:"last_rotation_time"`
+}
+
+// BackupInfo holds the time and key version of a backup
+type BackupInfo struct {
+	Time    time.Time `json:"time"`
+	Version int       `json:"version"`
+}
+
+// RestoreInfo holds the time of a restore and the backup it used
+type RestoreInfo struct {
+	Time       time.Time   `json:"time"`
+	Version    int         `json:"version"`
+	BackupInfo *BackupInfo `json:"backup_info"`
 }
 
 // ArchivedKeys stores old keys. This is used to keep the key loading time sane
@@ -477,22 +579,26 @@ func (p *Policy) DeriveKey(context []byte, ver int) ([]byte, error) {
 		return kdf.CounterMode(prf, prfLen, p.Keys[ver].Key, context, 256)
 
 	case Kdf_hkdf_sha256:
+		keySize := 32
+		if p.Type.SymmetricKeySize() != 0 {
+			keySize = p.Type.SymmetricKeySize()
+		}
 		reader := hkdf.New(sha256.New, p.Keys[ver].Key, nil, context)
 		derBytes := bytes.NewBuffer(nil)
-		derBytes.Grow(32)
+		derBytes.Grow(keySize)
 		limReader := &io.LimitedReader{
 			R: reader,
-			N: 32,
+			N: int64(keySize),
 		}
 
 		switch p.Type {
-		case KeyType_AES256_GCM96:
+		case KeyType_AES256_GCM96, KeyType_AES128_GCM96, KeyType_ChaCha20_Poly1305:
 			n, err := derBytes.ReadFrom(limReader)
 			if err != nil {
 				return nil, errutil.InternalError{Err: fmt.Sprintf("error reading returned derived bytes: %v", err)}
 			}
-			if n != 32 {
-				return nil, errutil.InternalError{Err: fmt.Sprintf("unable to read enough derived bytes, needed 32, got %d", n)}
+			if n != int64(keySize) {
+				return nil, errutil.InternalError{Err: fmt.Sprintf("unable to read enough derived bytes, needed %d, got %d", keySize, n)}
 			}
 			return derBytes.Bytes(), nil
 
@@ -519,13 +625,6 @@ func (p *Policy) Encrypt(ver int, context, nonce []byte, value string) (string,
 		return "", errutil.UserError{Err: fmt.Sprintf("message encryption not supported for key type %v", p.Type)}
 	}
 
-	// Guard against a potentially invalid key type
-	switch p.Type {
-	case KeyType_AES256_GCM96:
-	default:
-		return "", errutil.InternalError{Err: fmt.Sprintf("unsupported key type %v", p.Type)}
-	}
-
 	// Decode the plaintext value
 	plaintext, err := base64.StdEncoding.DecodeString(value)
 	if err != nil {
@@ -543,58 +642,58 @@ func (p *Policy) Encrypt(ver int, context, nonce []byte, value string) (string,
 		return "", errutil.UserError{Err: "requested version for encryption is less than the minimum encryption key version"}
 	}
 
-	// Derive the key that should be used
-	key, err := p.DeriveKey(context, ver)
-	if err != nil {
-		return "", err
-	}
-
-	// Guard against a potentially invalid key type
+	var full []byte
 	switch p.Type {
-	case KeyType_AES256_GCM96:
-	default:
-		return "", errutil.InternalError{Err: fmt.Sprintf("unsupported key type %v", p.Type)}
-	}
-
-	// Setup the cipher
-	aesCipher, err := aes.NewCipher(key)
-	if err != nil {
-		return "", errutil.InternalError{Err: err.Error()}
-	}
+	case KeyType_AES256_GCM96, KeyType_AES128_GCM96, KeyType_ChaCha20_Poly1305:
+		// Derive the key that should be used
+		key, err := p.DeriveKey(context, ver)
+		if err != nil {
+			return "", err
+		}
 
-	// Setup the GCM AEAD
-	gcm, err := cipher.NewGCM(aesCipher)
-	if err != nil {
-		return "", errutil.InternalError{Err: err.Error()}
-	}
+		aead, err := p.aead(key)
+		if err != nil {
+			return "", err
+		}
 
-	if p.ConvergentEncryption {
-		switch p.ConvergentVersion {
-		case 1:
-			if len(nonce) != gcm.NonceSize() {
-				return "", errutil.UserError{Err: fmt.Sprintf("base64-decoded nonce must be %d bytes long when using convergent encryption with this key", gcm.NonceSize())}
+		if p.ConvergentEncryption {
+			switch p.ConvergentVersion {
+			case 1:
+				if len(nonce) != aead.NonceSize() {
+					return "", errutil.UserError{Err: fmt.Sprintf("base64-decoded nonce must be %d bytes long when using convergent encryption with this key", aead.NonceSize())}
+				}
+			default:
+				nonceHmac := hmac.New(sha256.New, context)
+				nonceHmac.Write(plaintext)
+				nonceSum := nonceHmac.Sum(nil)
+				nonce = nonceSum[:aead.NonceSize()]
+			}
+		} else {
+			// Compute random nonce
+			nonce, err = uuid.GenerateRandomBytes(aead.NonceSize())
+			if err != nil {
+				return "", errutil.InternalError{Err: err.Error()}
 			}
-		default:
-			nonceHmac := hmac.New(sha256.New, context)
-			nonceHmac.Write(plaintext)
-			nonceSum := nonceHmac.Sum(nil)
-			nonce = nonceSum[:gcm.NonceSize()]
-		}
-	} else {
-		// Compute random nonce
-		nonce, err = uuid.GenerateRandomBytes(gcm.NonceSize())
-		if err != nil {
-			return "", errutil.InternalError{Err: err.Error()}
 		}
-	}
 
-	// Encrypt and tag with GCM
-	out := gcm.Seal(nil, nonce, plaintext, nil)
+		// Encrypt and tag
+		out := aead.Seal(nil, nonce, plaintext, nil)
+
+		// Place the encrypted data after the nonce
+		full = out
+		if !p.ConvergentEncryption || p.ConvergentVersion > 1 {
+			full = append(nonce, out...)
+		}
+
+	case KeyType_RSA2048, KeyType_RSA4096:
+		key := p.Keys[ver].RSAKey
+		full, err = rsa.EncryptOAEP(sha256.New(), rand.Reader, &key.PublicKey, plaintext, nil)
+		if err != nil {
+			return "", errutil.UserError{Err: fmt.Sprintf("failed to RSA encrypt the plaintext: %v", err)}
+		}
 
-	// Place the encrypted data after the nonce
-	full := out
-	if !p.ConvergentEncryption || p.ConvergentVersion > 1 {
-		full = append(nonce, out...)
+	default:
+		return "", errutil.InternalError{Err: fmt.Sprintf("unsupported key type %v", p.Type)}
 	}
 
 	// Convert to base64
@@ -644,53 +743,83 @@ func (p *Policy) Decrypt(context, nonce []byte, value string) (string, error) {
 		return "", errutil.UserError{Err: ErrTooOld}
 	}
 
-	// Derive the key that should be used
-	key, err := p.DeriveKey(context, ver)
+	// Decode the base64
+	decoded, err := base64.StdEncoding.DecodeString(splitVerCiphertext[1])
 	if err != nil {
-		return "", err
+		return "", errutil.UserError{Err: "invalid ciphertext: could not decode base64"}
 	}
 
-	// Guard against a potentially invalid key type
+	var plain []byte
 	switch p.Type {
-	case KeyType_AES256_GCM96:
+	case KeyType_AES256_GCM96, KeyType_AES128_GCM96, KeyType_ChaCha20_Poly1305:
+		// Derive the key that should be used
+		key, err := p.DeriveKey(context, ver)
+		if err != nil {
+			return "", err
+		}
+
+		aead, err := p.aead(key)
+		if err != nil {
+			return "", err
+		}
+
+		// Extract the nonce and ciphertext
+		var ciphertext []byte
+		if p.ConvergentEncryption && p.ConvergentVersion < 2 {
+			ciphertext = decoded
+		} else {
+			if len(decoded) < aead.NonceSize() {
+				return "", errutil.UserError{Err: "invalid ciphertext length"}
+			}
+			nonce = decoded[:aead.NonceSize()]
+			ciphertext = decoded[aead.NonceSize():]
+		}
+
+		// Verify and Decrypt
+		plain, err = aead.Open(nil, nonce, ciphertext, nil)
+		if err != nil {
+			return "", errutil.UserError{Err: "invalid ciphertext: unable to decrypt"}
+		}
+
+	case KeyType_RSA2048, KeyType_RSA4096:
+		plain, err = rsa.DecryptOAEP(sha256.New(), rand.Reader, p.Keys[ver].RSAKey, decoded, nil)
+		if err != nil {
+			return "", errutil.UserError{Err: "invalid ciphertext: unable to decrypt"}
+		}
+
 	default:
 		return "", errutil.InternalError{Err: fmt.Sprintf("unsupported key type %v", p.Type)}
 	}
 
-	// Decode the base64
-	decoded, err := base64.StdEncoding.DecodeString(splitVerCiphertext[1])
-	if err != nil {
-		return "", errutil.UserError{Err: "invalid ciphertext: could not decode base64"}
-	}
-
-	// Setup the cipher
-	aesCipher, err := aes.NewCipher(key)
-	if err != nil {
-		return "", errutil.InternalError{Err: err.Error()}
-	}
+	return base64.StdEncoding.EncodeToString(plain), nil
+}
 
-	// Setup the GCM AEAD
-	gcm, err := cipher.NewGCM(aesCipher)
-	if err != nil {
-		return "", errutil.InternalError{Err: err.Error()}
-	}
+// aead returns the AEAD of symmetric key types for the given key
+func (p *Policy) aead(key []byte) (cipher.AEAD, error) {
+	switch p.Type {
+	case KeyType_AES256_GCM96, KeyType_AES128_GCM96:
+		// Setup the cipher
+		aesCipher, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, errutil.InternalError{Err: err.Error()}
+		}
 
-	// Extract the nonce and ciphertext
-	var ciphertext []byte
-	if p.ConvergentEncryption && p.ConvergentVersion < 2 {
-		ciphertext = decoded
-	} else {
-		nonce = decoded[:gcm.NonceSize()]
-		ciphertext = decoded[gcm.NonceSize():]
-	}
+		// Setup the GCM AEAD
+		gcm, err := cipher.NewGCM(aesCipher)
+		if err != nil {
+			return nil, errutil.InternalError{Err: err.Error()}
+		}
+		return gcm, nil
 
-	// Verify and Decrypt
-	plain, err := gcm.Open(nil, nonce, ciphertext, nil)
-	if err != nil {
-		return "", errutil.UserError{Err: "invalid ciphertext: unable to decrypt"}
+	case KeyType_ChaCha20_Poly1305:
+		aead, err := chacha20poly1305.New(key)
+		if err != nil {
+			return nil, errutil.InternalError{Err: err.Error()}
+		}
+		return aead, nil
 	}
 
-	return base64.StdEncoding.EncodeToString(plain), nil
+	return nil, errutil.InternalError{Err: fmt.Sprintf("unsupported key type %v", p.Type)}
 }
 
 func (p *Policy) HMACKey(version int) ([]byte, error) {
@@ -708,7 +837,11 @@ func (p *Policy) HMACKey(version int) ([]byte, error) {
 	return p.Keys[version].HMACKey, nil
 }
 
-func (p *Policy) Sign(ver int, context, input []byte) (*SigningResult, error) {
+// Sign signs the input with the given key version. For ECDSA and RSA keys
+// the input is expected to be the digest computed with hashAlgorithm, which
+// may be zero only when the input is not hashed; sigAlgorithm selects the RSA
+// signature scheme ("pss", the default, or "pkcs1v15").
+func (p *Policy) Sign(ver int, context, input []byte, hashAlgorithm crypto.Hash, sigAlgorithm string) (*SigningResult, error) {
 	if !p.Type.SigningSupported() {
 		return nil, fmt.Errorf("message signing not supported for key type %v", p.Type)
 	}
@@ -728,11 +861,11 @@ func (p *Policy) Sign(ver int, context, input []byte) (*SigningResult, error) {
 	var pubKey []byte
 	var err error
 	switch p.Type {
-	case KeyType_ECDSA_P256:
+	case KeyType_ECDSA_P256, KeyType_ECDSA_P384, KeyType_ECDSA_P521:
 		keyParams := p.Keys[ver]
 		key := &ecdsa.PrivateKey{
 			PublicKey: ecdsa.PublicKey{
-				Curve: elliptic.P256(),
+				Curve: p.Type.ECDSACurve(),
 				X:     keyParams.EC_X,
 				Y:     keyParams.EC_Y,
 			},
@@ -773,6 +906,23 @@ func (p *Policy) Sign(ver int, context, input []byte) (*SigningResult, error) {
 			return nil, err
 		}
 
+	case KeyType_RSA2048, KeyType_RSA4096:
+		key := p.Keys[ver].RSAKey
+		switch sigAlgorithm {
+		case "pss", "":
+			if hashAlgorithm == 0 {
+				return nil, errutil.UserError{Err: "a hash algorithm is required for pss signatures"}
+			}
+			sig, err = rsa.SignPSS(rand.Reader, key, hashAlgorithm, input, nil)
+		case "pkcs1v15":
+			sig, err = rsa.SignPKCS1v15(rand.Reader, key, hashAlgorithm, input)
+		default:
+			return nil, errutil.UserError{Err: fmt.Sprintf("unsupported rsa signature algorithm %s", sigAlgorithm)}
+		}
+		if err != nil {
+			return nil, errutil.UserError{Err: fmt.Sprintf("failed to sign the input: %v", err)}
+		}
+
 	default:
 		return nil, fmt.Errorf("unsupported key type %v", p.Type)
 	}
@@ -788,7 +938,9 @@ func (p *Policy) Sign(ver int, context, input []byte) (*SigningResult, error) {
 	return res, nil
 }
 
-func (p *Policy) VerifySignature(context, input []byte, sig string) (bool, error) {
+// VerifySignature verifies a signature created by Sign; hashAlgorithm and
+// sigAlgorithm must match the values used for signing.
+func (p *Policy) VerifySignature(context, input []byte, sig string, hashAlgorithm crypto.Hash, sigAlgorithm string) (bool, error) {
 	if !p.Type.SigningSupported() {
 		return false, errutil.UserError{Err: fmt.Sprintf("message verification not supported for key type %v", p.Type)}
 	}
@@ -822,7 +974,7 @@ func (p *Policy) VerifySignature(context, input []byte, sig string) (bool, error
 	}
 
 	switch p.Type {
-	case KeyType_ECDSA_P256:
+	case KeyType_ECDSA_P256, KeyType_ECDSA_P384, KeyType_ECDSA_P521:
 		var ecdsaSig ecdsaSignature
 		rest, err := asn1.Unmarshal(sigBytes, &ecdsaSig)
 		if err != nil {
@@ -834,7 +986,7 @@ func (p *Policy) VerifySignature(context, input []byte, sig string) (bool, error
 
 		keyParams := p.Keys[ver]
 		key := &ecdsa.PublicKey{
-			Curve: elliptic.P256(),
+			Curve: p.Type.ECDSACurve(),
 			X:     keyParams.EC_X,
 			Y:     keyParams.EC_Y,
 		}
@@ -857,6 +1009,21 @@ func (p *Policy) VerifySignature(context, input []byte, sig string) (bool, error
 
 		return ed25519.Verify(key.Public().(ed25519.PublicKey), input, sigBytes), nil
 
+	case KeyType_RSA2048, KeyType_RSA4096:
+		key := &p.Keys[ver].RSAKey.PublicKey
+		switch sigAlgorithm {
+		case "pss", "":
+			if hashAlgorithm == 0 {
+				return false, errutil.UserError{Err: "a hash algorithm is required for pss signatures"}
+			}
+			err = rsa.VerifyPSS(key, hashAlgorithm, input, sigBytes, nil)
+		case "pkcs1v15":
+			err = rsa.VerifyPKCS1v15(key, hashAlgorithm, input, sigBytes)
+		default:
+			return false, errutil.UserError{Err: fmt.Sprintf("unsupported rsa signature algorithm %s", sigAlgorithm)}
+		}
+		return err == nil, nil
+
 	default:
 		return false, errutil.InternalError{Err: fmt.Sprintf("unsupported key type %v", p.Type)}
 	}
@@ -865,56 +1032,28 @@ func (p *Policy) VerifySignature(context, input []byte, sig string) (bool, error
 }
 
 func (p *Policy) Rotate(storage logical.Storage) error {
-	if p.Keys == nil {
-		// This is an initial key rotation when generating a new policy. We
-		// don't need to call migrate here because if we've called getPolicy to
-		// get the policy in the first place it will have been run.
-		p.Keys = keyEntryMap{}
-	}
-
-	p.LatestVersion += 1
-	now := time.Now()
-	entry := KeyEntry{
-		CreationTime:           now,
-		DeprecatedCreationTime: now.Unix(),
-	}
-
-	hmacKey, err := uuid.GenerateRandomBytes(32)
+	entry, err := p.newKeyEntry()
 	if err != nil {
 		return err
 	}
-	entry.HMACKey = hmacKey
 
 	switch p.Type {
-	case KeyType_AES256_GCM96:
-		// Generate a 256bit key
-		newKey, err := uuid.GenerateRandomBytes(32)
+	case KeyType_AES256_GCM96, KeyType_AES128_GCM96, KeyType_ChaCha20_Poly1305:
+		// Generate a key of the size of the cipher
+		newKey, err := uuid.GenerateRandomBytes(p.Type.SymmetricKeySize())
 		if err != nil {
 			return err
 		}
 		entry.Key = newKey
 
-	case KeyType_ECDSA_P256:
-		privKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case KeyType_ECDSA_P256, KeyType_ECDSA_P384, KeyType_ECDSA_P521:
+		privKey, err := ecdsa.GenerateKey(p.Type.ECDSACurve(), rand.Reader)
 		if err != nil {
 			return err
 		}
-		entry.EC_D = privKey.D
-		entry.EC_X = privKey.X
-		entry.EC_Y = privKey.Y
-		derBytes, err := x509.MarshalPKIXPublicKey(privKey.Public())
-		if err != nil {
-			return fmt.Errorf("error marshaling public key: %s", err)
-		}
-		pemBlock := &pem.Block{
-			Type:  "PUBLIC KEY",
-			Bytes: derBytes,
-		}
-		pemBytes := pem.EncodeToMemory(pemBlock)
-		if pemBytes == nil || len(pemBytes) == 0 {
-			return fmt.Errorf("error PEM-encoding public key")
+		if err := entry.setECDSAKey(privKey); err != nil {
+			return err
 		}
-		entry.FormattedPublicKey = string(pemBytes)
 
 	case KeyType_ED25519:
 		pub, pri, err := ed25519.GenerateKey(rand.Reader)
@@ -923,9 +1062,141 @@ func (p *Policy) Rotate(storage logical.Storage) error {
 		}
 		entry.Key = pri
 		entry.FormattedPublicKey = base64.StdEncoding.EncodeToString(pub)
+
+	case KeyType_RSA2048, KeyType_RSA4096:
+		privKey, err := rsa.GenerateKey(rand.Reader, p.Type.RSAKeyBits())
+		if err != nil {
+			return err
+		}
+		if err := entry.setRSAKey(privKey); err != nil {
+			return err
+		}
+	}
+
+	return p.addKeyEntry(storage, entry)
+}
+
+// Import adds the given key material as a new version of the policy. The key
+// is the raw key for symmetric key types and a PKCS#8 encoded private key for
+// asymmetric key types.
+func (p *Policy) Import(storage logical.Storage, key []byte) error {
+	entry, err := p.newKeyEntry()
+	if err != nil {
+		return err
+	}
+
+	if size := p.Type.SymmetricKeySize(); size != 0 {
+		if len(key) != size {
+			return errutil.UserError{Err: fmt.Sprintf("key material for type %v must be %d bytes long", p.Type, size)}
+		}
+		entry.Key = key
+		return p.addKeyEntry(storage, entry)
+	}
+
+	if p.Type == KeyType_ED25519 {
+		privKey, err := parsePKCS8Ed25519Key(key)
+		if err != nil {
+			return errutil.UserError{Err: fmt.Sprintf("key material is not a %v private key: %v", p.Type, err)}
+		}
+		entry.Key = privKey
+		entry.FormattedPublicKey = base64.StdEncoding.EncodeToString(privKey.Public().(ed25519.PublicKey))
+		return p.addKeyEntry(storage, entry)
+	}
+
+	parsedKey, err := x509.ParsePKCS8PrivateKey(key)
+	if err != nil {
+		return errutil.UserError{Err: fmt.Sprintf("error parsing PKCS#8 private key: %v", err)}
+	}
+
+	switch p.Type {
+	case KeyType_ECDSA_P256, KeyType_ECDSA_P384, KeyType_ECDSA_P521:
+		privKey, ok := parsedKey.(*ecdsa.PrivateKey)
+		if !ok || privKey.Curve != p.Type.ECDSACurve() {
+			return errutil.UserError{Err: fmt.Sprintf("key material is not a %v private key", p.Type)}
+		}
+		if err := entry.setECDSAKey(privKey); err != nil {
+			return err
+		}
+
+	case KeyType_RSA2048, KeyType_RSA4096:
+		privKey, ok := parsedKey.(*rsa.PrivateKey)
+		if !ok || privKey.N.BitLen() != p.Type.RSAKeyBits() {
+			return errutil.UserError{Err: fmt.Sprintf("key material is not a %v private key", p.Type)}
+		}
+		if err := entry.setRSAKey(privKey); err != nil {
+			return err
+		}
+
+	default:
+		return errutil.UserError{Err: fmt.Sprintf("importing keys of type %v is not supported", p.Type)}
 	}
 
+	return p.addKeyEntry(storage, entry)
+}
+
+// oidEd25519 is the algorithm identifier of Ed25519 keys, see RFC 8410
+var oidEd25519 = asn1.ObjectIdentifier{1, 3, 101, 112}
+
+// pkcs8 is the PKCS#8 PrivateKeyInfo structure
+type pkcs8 struct {
+	Version    int
+	Algo       pkix.AlgorithmIdentifier
+	PrivateKey []byte
+}
+
+// parsePKCS8Ed25519Key parses a PKCS#8 encoded Ed25519 private key, which
+// x509.ParsePKCS8PrivateKey does not support
+func parsePKCS8Ed25519Key(der []byte) (ed25519.PrivateKey, error) {
+	var privKey pkcs8
+	if _, err := asn1.Unmarshal(der, &privKey); err != nil {
+		return nil, err
+	}
+	if !privKey.Algo.Algorithm.Equal(oidEd25519) {
+		return nil, fmt.Errorf("unexpected key algorithm %v", privKey.Algo.Algorithm)
+	}
+
+	var seed []byte
+	if _, err := asn1.Unmarshal(privKey.PrivateKey, &seed); err != nil {
+		return nil, err
+	}
+	if len(seed) != 32 {
+		return nil, fmt.Errorf("invalid seed length %d", len(seed))
+	}
+
+	_, key, err := ed25519.GenerateKey(bytes.NewReader(seed))
+	return key, err
+}
+
+// newKeyEntry returns a key entry for the next version of the policy with a
+// fresh HMAC key
+func (p *Policy) newKeyEntry() (KeyEntry, error) {
+	now := time.Now()
+	entry := KeyEntry{
+		CreationTime:           now,
+		DeprecatedCreationTime: now.Unix(),
+	}
+
+	hmacKey, err := uuid.GenerateRandomBytes(32)
+	if err != nil {
+		return entry, err
+	}
+	entry.HMACKey = hmacKey
+
+	return entry, nil
+}
+
+// addKeyEntry stores the entry as the latest version of the policy
+func (p *Policy) addKeyEntry(storage logical.Storage, entry KeyEntry) error {
+	if p.Keys == nil {
+		// This is an initial key rotation when generating a new policy. We
+		// don't need to call migrate here because if we've called getPolicy to
+		// get the policy in the first place it will have been run.
+		p.Keys = keyEntryMap{}
+	}
+
+	p.LatestVersion += 1
 	p.Keys[p.LatestVersion] = entry
+	p.LastRotationTime = entry.CreationTime
 
 	// This ensures that with new key creations min decryption version is set
 	// to 1 rather than the int default of 0, since keys start at 1 (either
@@ -937,6 +1208,53 @@ func (p *Policy) Rotate(storage logical.Storage) error {
 	return p.Persist(storage)
 }
 
+func (ke *KeyEntry) setECDSAKey(privKey *ecdsa.PrivateKey) error {
+	ke.EC_D = privKey.D
+	ke.EC_X = privKey.X
+	ke.EC_Y = privKey.Y
+	return ke.setFormattedPublicKey(privKey.Public())
+}
+
+func (ke *KeyEntry) setRSAKey(privKey *rsa.PrivateKey) error {
+	ke.RSAKey = privKey
+	return ke.setFormattedPublicKey(privKey.Public())
+}
+
+// setFormattedPublicKey sets the PEM encoded PKIX public key of the entry
+func (ke *KeyEntry) setFormattedPublicKey(pubKey crypto.PublicKey) error {
+	derBytes, err := x509.MarshalPKIXPublicKey(pubKey)
+	if err != nil {
+		return fmt.Errorf("error marshaling public key: %s", err)
+	}
+	pemBlock := &pem.Block{
+		Type:  "PUBLIC KEY",
+		Bytes: derBytes,
+	}
+	pemBytes := pem.EncodeToMemory(pemBlock)
+	if pemBytes == nil || len(pemBytes) == 0 {
+		return fmt.Errorf("error PEM-encoding public key")
+	}
+	ke.FormattedPublicKey = string(pemBytes)
+	return nil
+}
+
+// NextRotationTime returns when the key is due for automatic rotation, or the
+// zero time if it is not rotated automatically
+func (p *Policy) NextRotationTime() time.Time {
+	if p.AutoRotatePeriod == 0 {
+		return time.Time{}
+	}
+
+	// Keys created before the last rotation time was recorded use the
+	// creation time of their latest version
+	lastRotationTime := p.LastRotationTime
+	if lastRotationTime.IsZero() {
+		lastRotationTime = p.Keys[p.LatestVersion].CreationTime
+	}
+
+	return lastRotationTime.Add(p.AutoRotatePeriod)
+}
+
 func (p *Policy) MigrateKeyToKeysMap() {
 	now := time.Now()
 	p.Keys = keyEntryMap{
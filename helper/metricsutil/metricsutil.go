@@ -0,0 +1,90 @@
+package metricsutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	metrics "github.com/armon/go-metrics"
+	"github.com/hashicorp/vault/logical"
+)
+
+const (
+	// PrometheusMetricFormat is the Prometheus text exposition format
+	PrometheusMetricFormat = "prometheus"
+
+	// JSONMetricFormat is the go-metrics summary of the last interval
+	JSONMetricFormat = "json"
+
+	// PrometheusContentType is the content type of the Prometheus text
+	// exposition format
+	PrometheusContentType = "text/plain; version=0.0.4"
+)
+
+// MetricsHelper gives access to the metrics kept in memory by this node
+type MetricsHelper struct {
+	inmemSink      *metrics.InmemSink
+	prometheusSink *PrometheusSink
+}
+
+// NewMetricsHelper returns a helper for the given sinks. The Prometheus sink
+// may be nil if Prometheus metrics are not enabled.
+func NewMetricsHelper(inmem *metrics.InmemSink, prometheus *PrometheusSink) *MetricsHelper {
+	return &MetricsHelper{
+		inmemSink:      inmem,
+		prometheusSink: prometheus,
+	}
+}
+
+// PrometheusEnabled returns whether metrics can be read in the Prometheus
+// format
+func (m *MetricsHelper) PrometheusEnabled() bool {
+	return m.prometheusSink != nil
+}
+
+// ResponseForFormat returns a raw response with the metrics in the given
+// format. If no format is given, the Prometheus format is used if it is
+// enabled, and JSON otherwise.
+func (m *MetricsHelper) ResponseForFormat(format string) (*logical.Response, error) {
+	if format == "" {
+		format = JSONMetricFormat
+		if m.PrometheusEnabled() {
+			format = PrometheusMetricFormat
+		}
+	}
+
+	switch format {
+	case PrometheusMetricFormat:
+		if !m.PrometheusEnabled() {
+			return logical.ErrorResponse("prometheus metrics are not enabled; set prometheus_retention_time in the telemetry configuration"), logical.ErrInvalidRequest
+		}
+		var buf bytes.Buffer
+		m.prometheusSink.Write(&buf)
+		return rawResponse(PrometheusContentType, buf.Bytes()), nil
+
+	case JSONMetricFormat:
+		summary, err := m.inmemSink.DisplayMetrics(nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		body, err := json.Marshal(summary)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode metrics: %v", err)
+		}
+		return rawResponse("application/json", body), nil
+
+	default:
+		return logical.ErrorResponse(fmt.Sprintf("unsupported metrics format %q", format)), logical.ErrInvalidRequest
+	}
+}
+
+func rawResponse(contentType string, body []byte) *logical.Response {
+	return &logical.Response{
+		Data: map[string]interface{}{
+			logical.HTTPContentType: contentType,
+			logical.HTTPRawBody:     body,
+			logical.HTTPStatusCode:  http.StatusOK,
+		},
+	}
+}
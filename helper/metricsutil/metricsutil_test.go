@@ -0,0 +1,54 @@
+package metricsutil
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	metrics "github.com/armon/go-metrics"
+	"github.com/hashicorp/vault/logical"
+)
+
+func TestMetricsHelper_ResponseForFormat(t *testing.T) {
+	inm := metrics.NewInmemSink(10*time.Second, time.Minute)
+	inm.SetGauge([]string{"foo"}, 1)
+
+	// Without the Prometheus sink, JSON is the default
+	helper := NewMetricsHelper(inm, nil)
+	resp, err := helper.ResponseForFormat("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Data[logical.HTTPContentType] != "application/json" {
+		t.Fatalf("bad content type: %v", resp.Data[logical.HTTPContentType])
+	}
+	var summary metrics.MetricsSummary
+	if err := json.Unmarshal(resp.Data[logical.HTTPRawBody].([]byte), &summary); err != nil {
+		t.Fatal(err)
+	}
+	if len(summary.Gauges) != 1 || summary.Gauges[0].Name != "foo" {
+		t.Fatalf("bad summary: %#v", summary)
+	}
+
+	if _, err := helper.ResponseForFormat(PrometheusMetricFormat); err != logical.ErrInvalidRequest {
+		t.Fatalf("expected invalid request, got %v", err)
+	}
+	if _, err := helper.ResponseForFormat("xml"); err != logical.ErrInvalidRequest {
+		t.Fatalf("expected invalid request, got %v", err)
+	}
+
+	// With it, Prometheus is the default
+	prom := NewPrometheusSink(time.Hour)
+	prom.SetGauge([]string{"foo"}, 1)
+	helper = NewMetricsHelper(inm, prom)
+	resp, err = helper.ResponseForFormat("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Data[logical.HTTPContentType] != PrometheusContentType {
+		t.Fatalf("bad content type: %v", resp.Data[logical.HTTPContentType])
+	}
+	if string(resp.Data[logical.HTTPRawBody].([]byte)) != "# TYPE foo gauge\nfoo 1\n" {
+		t.Fatalf("bad body: %q", resp.Data[logical.HTTPRawBody])
+	}
+}
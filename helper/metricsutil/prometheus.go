@@ -0,0 +1,244 @@
+package metricsutil
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	metrics "github.com/armon/go-metrics"
+)
+
+// PrometheusSink is a metrics.MetricSink that keeps the latest value of each
+// metric so that they can be scraped in the Prometheus text exposition
+// format. Gauges keep their last value, counters are cumulative and samples
+// are exposed as summaries with a count and a sum. Series that have not been
+// updated for longer than the retention time are dropped.
+type PrometheusSink struct {
+	retention time.Duration
+
+	l         sync.Mutex
+	gauges    map[string]*promSeries
+	counters  map[string]*promSeries
+	summaries map[string]*promSeries
+}
+
+// promSeries is a single metric name and label set
+type promSeries struct {
+	name    string
+	labels  []metrics.Label
+	value   float64
+	count   uint64
+	updated time.Time
+}
+
+// NewPrometheusSink returns a sink that drops series after the given
+// retention time
+func NewPrometheusSink(retention time.Duration) *PrometheusSink {
+	return &PrometheusSink{
+		retention: retention,
+		gauges:    make(map[string]*promSeries),
+		counters:  make(map[string]*promSeries),
+		summaries: make(map[string]*promSeries),
+	}
+}
+
+func (p *PrometheusSink) SetGauge(key []string, val float32) {
+	p.SetGaugeWithLabels(key, val, nil)
+}
+
+func (p *PrometheusSink) SetGaugeWithLabels(key []string, val float32, labels []metrics.Label) {
+	p.l.Lock()
+	defer p.l.Unlock()
+
+	s := p.series(p.gauges, key, labels)
+	s.value = float64(val)
+}
+
+// EmitKey is not supported by Prometheus, so these values are dropped
+func (p *PrometheusSink) EmitKey(key []string, val float32) {
+}
+
+func (p *PrometheusSink) IncrCounter(key []string, val float32) {
+	p.IncrCounterWithLabels(key, val, nil)
+}
+
+func (p *PrometheusSink) IncrCounterWithLabels(key []string, val float32, labels []metrics.Label) {
+	p.l.Lock()
+	defer p.l.Unlock()
+
+	s := p.series(p.counters, key, labels)
+	s.value += float64(val)
+}
+
+func (p *PrometheusSink) AddSample(key []string, val float32) {
+	p.AddSampleWithLabels(key, val, nil)
+}
+
+func (p *PrometheusSink) AddSampleWithLabels(key []string, val float32, labels []metrics.Label) {
+	p.l.Lock()
+	defer p.l.Unlock()
+
+	s := p.series(p.summaries, key, labels)
+	s.value += float64(val)
+	s.count++
+}
+
+// series returns the series for the key and labels, creating it if needed,
+// and marks it as updated. The lock must be held.
+func (p *PrometheusSink) series(set map[string]*promSeries, key []string, labels []metrics.Label) *promSeries {
+	name := promName(key)
+	labels = promLabels(labels)
+	id := seriesID(name, labels)
+
+	s, ok := set[id]
+	if !ok {
+		s = &promSeries{
+			name:   name,
+			labels: labels,
+		}
+		set[id] = s
+	}
+	s.updated = time.Now()
+	return s
+}
+
+// Write writes all current series in the Prometheus text exposition format
+func (p *PrometheusSink) Write(buf *bytes.Buffer) {
+	p.l.Lock()
+	defer p.l.Unlock()
+
+	p.expire()
+
+	writeSeries(buf, "gauge", p.gauges, func(s *promSeries) {
+		writeSample(buf, s.name, s.labels, s.value)
+	})
+	writeSeries(buf, "counter", p.counters, func(s *promSeries) {
+		writeSample(buf, s.name, s.labels, s.value)
+	})
+	writeSeries(buf, "summary", p.summaries, func(s *promSeries) {
+		writeSample(buf, s.name+"_sum", s.labels, s.value)
+		writeSample(buf, s.name+"_count", s.labels, float64(s.count))
+	})
+}
+
+// expire drops the series that have not been updated within the retention
+// time. The lock must be held.
+func (p *PrometheusSink) expire() {
+	if p.retention <= 0 {
+		return
+	}
+	cutoff := time.Now().Add(-p.retention)
+	for _, set := range []map[string]*promSeries{p.gauges, p.counters, p.summaries} {
+		for id, s := range set {
+			if s.updated.Before(cutoff) {
+				delete(set, id)
+			}
+		}
+	}
+}
+
+// writeSeries writes the series of one type sorted by name and labels, with
+// a TYPE line before each metric name
+func writeSeries(buf *bytes.Buffer, typ string, set map[string]*promSeries, write func(*promSeries)) {
+	series := make([]*promSeries, 0, len(set))
+	for _, s := range set {
+		series = append(series, s)
+	}
+	sort.Slice(series, func(i, j int) bool {
+		if series[i].name != series[j].name {
+			return series[i].name < series[j].name
+		}
+		return seriesID("", series[i].labels) < seriesID("", series[j].labels)
+	})
+
+	var last string
+	for _, s := range series {
+		if s.name != last {
+			fmt.Fprintf(buf, "# TYPE %s %s\n", s.name, typ)
+			last = s.name
+		}
+		write(s)
+	}
+}
+
+func writeSample(buf *bytes.Buffer, name string, labels []metrics.Label, value float64) {
+	buf.WriteString(name)
+	writeLabels(buf, labels)
+	buf.WriteByte(' ')
+	buf.WriteString(strconv.FormatFloat(value, 'g', -1, 64))
+	buf.WriteByte('\n')
+}
+
+func writeLabels(buf *bytes.Buffer, labels []metrics.Label) {
+	if len(labels) == 0 {
+		return
+	}
+	buf.WriteByte('{')
+	for i, label := range labels {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteString(label.Name)
+		buf.WriteString(`="`)
+		buf.WriteString(labelValueReplacer.Replace(label.Value))
+		buf.WriteByte('"')
+	}
+	buf.WriteByte('}')
+}
+
+// seriesID uniquely identifies a series by its name and sorted labels, as
+// they are written out
+func seriesID(name string, labels []metrics.Label) string {
+	var buf bytes.Buffer
+	buf.WriteString(name)
+	writeLabels(&buf, labels)
+	return buf.String()
+}
+
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// promName joins the parts of a key into a valid Prometheus metric name
+func promName(key []string) string {
+	return sanitize(strings.Join(key, "_"), true)
+}
+
+// promLabels returns a copy of the labels with valid names, sorted by name
+func promLabels(labels []metrics.Label) []metrics.Label {
+	if len(labels) == 0 {
+		return nil
+	}
+	ret := make([]metrics.Label, 0, len(labels))
+	for _, label := range labels {
+		ret = append(ret, metrics.Label{
+			Name:  sanitize(label.Name, false),
+			Value: label.Value,
+		})
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Name < ret[j].Name
+	})
+	return ret
+}
+
+// sanitize replaces the characters that are not allowed in metric or label
+// names with underscores. Colons are only allowed in metric names.
+func sanitize(name string, allowColon bool) string {
+	out := []byte(name)
+	for i, c := range out {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c == '_':
+		case c >= '0' && c <= '9' && i > 0:
+		case c == ':' && allowColon:
+		default:
+			out[i] = '_'
+		}
+	}
+	if len(out) == 0 {
+		return "_"
+	}
+	return string(out)
+}
@@ -0,0 +1,83 @@
+package metricsutil
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	metrics "github.com/armon/go-metrics"
+)
+
+func TestPrometheusSink(t *testing.T) {
+	sink := NewPrometheusSink(time.Hour)
+
+	sink.SetGauge([]string{"vault", "expire", "num_leases"}, 5)
+	sink.SetGauge([]string{"vault", "expire", "num_leases"}, 3)
+	sink.IncrCounterWithLabels([]string{"vault", "audit", "log_request_failure"}, 1, nil)
+	sink.IncrCounterWithLabels([]string{"vault", "audit", "log_request_failure"}, 2, nil)
+	sink.AddSampleWithLabels([]string{"vault", "route", "read"}, 1.5, []metrics.Label{{Name: "mount", Value: "secret-"}})
+	sink.AddSampleWithLabels([]string{"vault", "route", "read"}, 2.5, []metrics.Label{{Name: "mount", Value: "secret-"}})
+	sink.AddSampleWithLabels([]string{"vault", "route", "read"}, 1, []metrics.Label{{Name: "mount", Value: `a"b`}})
+	sink.AddSample([]string{"vault", "expire", "revoke-prefix"}, 4)
+	sink.EmitKey([]string{"vault", "ignored"}, 1)
+
+	var buf bytes.Buffer
+	sink.Write(&buf)
+
+	expected := strings.TrimLeft(`
+# TYPE vault_expire_num_leases gauge
+vault_expire_num_leases 3
+# TYPE vault_audit_log_request_failure counter
+vault_audit_log_request_failure 3
+# TYPE vault_expire_revoke_prefix summary
+vault_expire_revoke_prefix_sum 4
+vault_expire_revoke_prefix_count 1
+# TYPE vault_route_read summary
+vault_route_read_sum{mount="a\"b"} 1
+vault_route_read_count{mount="a\"b"} 1
+vault_route_read_sum{mount="secret-"} 4
+vault_route_read_count{mount="secret-"} 2
+`, "\n")
+	if buf.String() != expected {
+		t.Fatalf("expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+}
+
+func TestPrometheusSink_retention(t *testing.T) {
+	sink := NewPrometheusSink(time.Hour)
+	sink.SetGauge([]string{"old"}, 1)
+	sink.SetGauge([]string{"new"}, 1)
+	for _, s := range sink.gauges {
+		if s.name == "old" {
+			s.updated = time.Now().Add(-2 * time.Hour)
+		}
+	}
+
+	var buf bytes.Buffer
+	sink.Write(&buf)
+	if buf.String() != "# TYPE new gauge\nnew 1\n" {
+		t.Fatalf("bad: %q", buf.String())
+	}
+}
+
+func TestPrometheusSink_sanitize(t *testing.T) {
+	cases := map[string]string{
+		"vault_route_read":  "vault_route_read",
+		"revoke-prefix":     "revoke_prefix",
+		"0day":              "_day",
+		"a.b/c":             "a_b_c",
+		"colon:ok":          "colon:ok",
+		"":                  "_",
+		"with space":        "with_space",
+		"consul_put_latest": "consul_put_latest",
+	}
+	for in, expected := range cases {
+		if out := sanitize(in, true); out != expected {
+			t.Errorf("sanitize(%q) = %q, expected %q", in, out, expected)
+		}
+	}
+	if out := sanitize("colon:no", false); out != "colon_no" {
+		t.Errorf("bad label name: %q", out)
+	}
+}
@@ -119,6 +119,12 @@ func (r *PluginRunner) runCommon(wrapper RunnerUtil, pluginMap map[string]plugin
 		SecureConfig:    secureConfig,
 		TLSConfig:       clientTLSConfig,
 		Logger:          namedLogger,
+		// The plugin picks the protocol it is served with, accept plugins
+		// speaking net/rpc as well as gRPC
+		AllowedProtocols: []plugin.Protocol{
+			plugin.ProtocolNetRPC,
+			plugin.ProtocolGRPC,
+		},
 	}
 
 	client := plugin.NewClient(clientConfig)
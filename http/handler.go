@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"net/url"
 	"strings"
@@ -45,6 +46,10 @@ const (
 	// soft-mandatory Sentinel policies.
 	PolicyOverrideHeaderName = "X-Vault-Policy-Override"
 
+	// NamespaceHeaderName is the name of the header selecting the namespace
+	// a request is made in. Its value is prepended to the request path.
+	NamespaceHeaderName = "X-Vault-Namespace"
+
 	// MaxRequestSize is the maximum accepted request size. This is to prevent
 	// a denial of service attack where no Content-Length is provided and the server
 	// is fed ever more data until it exhausts memory.
@@ -53,6 +58,14 @@ const (
 
 var (
 	ReplicationStaleReadTimeout = 2 * time.Second
+
+	// rawRequestContentTypes are the content types of request bodies that
+	// are passed to backends verbatim, in the logical.HTTPRawBody field of
+	// the request data, instead of being parsed as JSON
+	rawRequestContentTypes = map[string]bool{
+		"application/ocsp-request": true,
+		"application/jose+json":    true,
+	}
 )
 
 // Handler returns an http.Handler for the API. This can be used on
@@ -67,6 +80,8 @@ func Handler(core *vault.Core) http.Handler {
 	mux.Handle("/v1/sys/unseal", handleSysUnseal(core))
 	mux.Handle("/v1/sys/leader", handleSysLeader(core))
 	mux.Handle("/v1/sys/health", handleSysHealth(core))
+	mux.Handle("/v1/sys/storage/raft/join", handleSysRaftJoin(core))
+	mux.Handle("/v1/sys/metrics", handleSysMetrics(core))
 	mux.Handle("/v1/sys/generate-root/attempt", handleRequestForwarding(core, handleSysGenerateRootAttempt(core)))
 	mux.Handle("/v1/sys/generate-root/update", handleRequestForwarding(core, handleSysGenerateRootUpdate(core)))
 	mux.Handle("/v1/sys/rekey/init", handleRequestForwarding(core, handleSysRekeyInit(core, false)))
@@ -151,6 +166,17 @@ func parseRequest(r *http.Request, w http.ResponseWriter, out interface{}) error
 	return err
 }
 
+// parseRawRequest reads the body of a request that is passed to the backend
+// verbatim rather than parsed as JSON
+func parseRawRequest(r *http.Request, w http.ResponseWriter) ([]byte, error) {
+	limit := http.MaxBytesReader(w, r.Body, MaxRequestSize)
+	body, err := ioutil.ReadAll(limit)
+	if err != nil {
+		return nil, errwrap.Wrapf("failed to read request body: {{err}}", err)
+	}
+	return body, nil
+}
+
 // handleRequestForwarding determines whether to forward a request or not,
 // falling back on the older behavior of redirecting the client
 func handleRequestForwarding(core *vault.Core, handler http.Handler) http.Handler {
@@ -329,6 +355,41 @@ func requestWrapInfo(r *http.Request, req *logical.Request) (*logical.Request, e
 	return req, nil
 }
 
+// parseMFAHeader adds the MFA credentials of the X-Vault-MFA headers to the
+// logical.Request. Each header holds a method name, optionally followed by a
+// colon and the credential for the method, such as a TOTP passcode.
+func parseMFAHeader(req *logical.Request) error {
+	if req.Headers == nil {
+		return nil
+	}
+
+	for _, value := range req.Headers[canonicalMFAHeaderName] {
+		if value == "" {
+			continue
+		}
+		if req.MFACreds == nil {
+			req.MFACreds = make(logical.MFACreds)
+		}
+
+		// Push methods can be given without a credential
+		if !strings.Contains(value, ":") {
+			req.MFACreds[value] = append(req.MFACreds[value], "")
+			continue
+		}
+
+		parts := strings.SplitN(value, ":", 2)
+		if parts[0] == "" {
+			return fmt.Errorf("missing MFA method name")
+		}
+		if parts[1] == "" {
+			return fmt.Errorf("missing credential of MFA method %q", parts[0])
+		}
+		req.MFACreds[parts[0]] = append(req.MFACreds[parts[0]], parts[1])
+	}
+
+	return nil
+}
+
 func respondError(w http.ResponseWriter, status int, err error) {
 	logical.AdjustErrorStatusCode(&status, err)
 
@@ -313,6 +313,12 @@ func TestSysMounts_headerAuth_Wrapped(t *testing.T) {
 	}
 	expected["wrap_info"].(map[string]interface{})["token"] = actualToken
 
+	actualAccessor, ok := actual["wrap_info"].(map[string]interface{})["accessor"]
+	if !ok || actualAccessor == "" {
+		t.Fatal("accessor missing in wrap info")
+	}
+	expected["wrap_info"].(map[string]interface{})["accessor"] = actualAccessor
+
 	actualCreationTime, ok := actual["wrap_info"].(map[string]interface{})["creation_time"]
 	if !ok || actualCreationTime == "" {
 		t.Fatal("creation_time missing in wrap info")
@@ -374,3 +380,33 @@ func TestHandler_error(t *testing.T) {
 	}
 
 }
+
+func TestHandler_parseMFAHeader(t *testing.T) {
+	req := &logical.Request{
+		Headers: http.Header{
+			canonicalMFAHeaderName: []string{"my_totp:695452", "my_okta", "my_duo:passcode=123456"},
+		},
+	}
+	if err := parseMFAHeader(req); err != nil {
+		t.Fatal(err)
+	}
+	expected := logical.MFACreds{
+		"my_totp": []string{"695452"},
+		"my_okta": []string{""},
+		"my_duo":  []string{"passcode=123456"},
+	}
+	if !reflect.DeepEqual(req.MFACreds, expected) {
+		t.Fatalf("bad: expected %#v, got %#v", expected, req.MFACreds)
+	}
+
+	for _, value := range []string{":695452", "my_totp:"} {
+		req = &logical.Request{
+			Headers: http.Header{
+				canonicalMFAHeaderName: []string{value},
+			},
+		}
+		if err := parseMFAHeader(req); err == nil {
+			t.Fatalf("expected error for %q", value)
+		}
+	}
+}
@@ -26,12 +26,18 @@ func buildLogicalRequest(core *vault.Core, w http.ResponseWriter, r *http.Reques
 		return nil, http.StatusNotFound, nil
 	}
 
+	// Requests within a namespace are reached by prefixing the path with the
+	// path of the namespace
+	if ns := strings.Trim(r.Header.Get(NamespaceHeaderName), "/"); ns != "" {
+		path = ns + "/" + path
+	}
+
 	// Determine the operation
 	var op logical.Operation
 	switch r.Method {
 	case "DELETE":
 		op = logical.DeleteOperation
-	case "GET":
+	case "GET", "HEAD":
 		op = logical.ReadOperation
 		// Need to call ParseForm to get query params loaded
 		queryVals := r.URL.Query()
@@ -62,7 +68,21 @@ func buildLogicalRequest(core *vault.Core, w http.ResponseWriter, r *http.Reques
 
 	// Parse the request if we can
 	var data map[string]interface{}
-	if op == logical.UpdateOperation {
+	switch op {
+	case logical.UpdateOperation:
+		// Bodies of some binary protocols, such as OCSP, are handed to the
+		// backend as they are
+		if rawRequestContentTypes[r.Header.Get("Content-Type")] {
+			body, err := parseRawRequest(r, w)
+			if err != nil {
+				return nil, http.StatusBadRequest, err
+			}
+			data = map[string]interface{}{
+				logical.HTTPRawBody: body,
+			}
+			break
+		}
+
 		err := parseRequest(r, w, &data)
 		if err == io.EOF {
 			data = nil
@@ -71,6 +91,18 @@ func buildLogicalRequest(core *vault.Core, w http.ResponseWriter, r *http.Reques
 		if err != nil {
 			return nil, http.StatusBadRequest, err
 		}
+
+	case logical.ReadOperation:
+		// Query parameters are passed to the backend as request data so
+		// that reads can be parameterized, e.g. by a version
+		queryVals := r.URL.Query()
+		if len(queryVals) > 0 {
+			data = make(map[string]interface{}, len(queryVals))
+			for k, v := range queryVals {
+				// Uses the first value if there are multiple
+				data[k] = v[0]
+			}
+		}
 	}
 
 	var err error
@@ -93,6 +125,10 @@ func buildLogicalRequest(core *vault.Core, w http.ResponseWriter, r *http.Reques
 		return nil, http.StatusBadRequest, errwrap.Wrapf("error parsing X-Vault-Wrap-TTL header: {{err}}", err)
 	}
 
+	if err := parseMFAHeader(req); err != nil {
+		return nil, http.StatusBadRequest, errwrap.Wrapf("error parsing X-Vault-MFA header: {{err}}", err)
+	}
+
 	return req, 0, nil
 }
 
@@ -151,6 +187,7 @@ func respondLogical(w http.ResponseWriter, r *http.Request, req *logical.Request
 			httpResp = &logical.HTTPResponse{
 				WrapInfo: &logical.HTTPWrapInfo{
 					Token:           resp.WrapInfo.Token,
+					Accessor:        resp.WrapInfo.Accessor,
 					TTL:             int(resp.WrapInfo.TTL.Seconds()),
 					CreationTime:    resp.WrapInfo.CreationTime.Format(time.RFC3339Nano),
 					CreationPath:    resp.WrapInfo.CreationPath,
@@ -238,7 +275,22 @@ func respondRaw(w http.ResponseWriter, r *http.Request, resp *logical.Response)
 		}
 	}
 
+	// Get any additional headers
+	var headers map[string][]string
+	if headersRaw, ok := resp.Data[logical.HTTPHeaders]; ok {
+		headers, ok = headersRaw.(map[string][]string)
+		if !ok {
+			retErr(w, "cannot decode headers")
+			return
+		}
+	}
+
 	// Write the response
+	for name, values := range headers {
+		for _, value := range values {
+			w.Header().Add(name, value)
+		}
+	}
 	if contentType != "" {
 		w.Header().Set("Content-Type", contentType)
 	}
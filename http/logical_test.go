@@ -158,6 +158,7 @@ func TestLogical_StandbyRedirect(t *testing.T) {
 			"explicit_max_ttl": json.Number("0"),
 			"expire_time":      nil,
 			"entity_id":        "",
+			"type":             "service",
 		},
 		"warnings":  nilWarnings,
 		"wrap_info": nil,
@@ -207,6 +208,7 @@ func TestLogical_CreateToken(t *testing.T) {
 			"metadata":       nil,
 			"lease_duration": json.Number("0"),
 			"renewable":      false,
+			"token_type":     "service",
 			"entity_id":      "",
 		},
 		"warnings": nilWarnings,
@@ -300,3 +302,21 @@ func TestLogical_ListSuffix(t *testing.T) {
 		t.Fatal("trailing slash not found on path")
 	}
 }
+
+func TestLogical_NamespaceHeader(t *testing.T) {
+	core, _, _ := vault.TestCoreUnsealed(t)
+	for _, header := range []string{"team-a", "/team-a/", "team-a/dev"} {
+		req, _ := http.NewRequest("GET", "http://127.0.0.1:8200/v1/secret/foo", nil)
+		req.Header.Set(NamespaceHeaderName, header)
+		lreq, status, err := buildLogicalRequest(core, nil, req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if status != 0 {
+			t.Fatalf("got status %d", status)
+		}
+		if expected := strings.Trim(header, "/") + "/secret/foo"; lreq.Path != expected {
+			t.Fatalf("expected path %q, got %q", expected, lreq.Path)
+		}
+	}
+}
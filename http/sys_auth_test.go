@@ -31,6 +31,7 @@ func TestSysAuth(t *testing.T) {
 				"config": map[string]interface{}{
 					"default_lease_ttl": json.Number("0"),
 					"max_lease_ttl":     json.Number("0"),
+					"token_type":        "default-service",
 				},
 				"local": false,
 			},
@@ -41,6 +42,7 @@ func TestSysAuth(t *testing.T) {
 			"config": map[string]interface{}{
 				"default_lease_ttl": json.Number("0"),
 				"max_lease_ttl":     json.Number("0"),
+				"token_type":        "default-service",
 			},
 			"local": false,
 		},
@@ -91,6 +93,7 @@ func TestSysEnableAuth(t *testing.T) {
 				"config": map[string]interface{}{
 					"default_lease_ttl": json.Number("0"),
 					"max_lease_ttl":     json.Number("0"),
+					"token_type":        "default-service",
 				},
 				"local": false,
 			},
@@ -100,6 +103,7 @@ func TestSysEnableAuth(t *testing.T) {
 				"config": map[string]interface{}{
 					"default_lease_ttl": json.Number("0"),
 					"max_lease_ttl":     json.Number("0"),
+					"token_type":        "default-service",
 				},
 				"local": false,
 			},
@@ -110,6 +114,7 @@ func TestSysEnableAuth(t *testing.T) {
 			"config": map[string]interface{}{
 				"default_lease_ttl": json.Number("0"),
 				"max_lease_ttl":     json.Number("0"),
+				"token_type":        "default-service",
 			},
 			"local": false,
 		},
@@ -119,6 +124,7 @@ func TestSysEnableAuth(t *testing.T) {
 			"config": map[string]interface{}{
 				"default_lease_ttl": json.Number("0"),
 				"max_lease_ttl":     json.Number("0"),
+				"token_type":        "default-service",
 			},
 			"local": false,
 		},
@@ -170,6 +176,7 @@ func TestSysDisableAuth(t *testing.T) {
 				"config": map[string]interface{}{
 					"default_lease_ttl": json.Number("0"),
 					"max_lease_ttl":     json.Number("0"),
+					"token_type":        "default-service",
 				},
 				"description": "token based credentials",
 				"type":        "token",
@@ -180,6 +187,7 @@ func TestSysDisableAuth(t *testing.T) {
 			"config": map[string]interface{}{
 				"default_lease_ttl": json.Number("0"),
 				"max_lease_ttl":     json.Number("0"),
+				"token_type":        "default-service",
 			},
 			"description": "token based credentials",
 			"type":        "token",
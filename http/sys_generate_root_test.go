@@ -314,6 +314,7 @@ func TestSysGenerateRoot_Update_OTP(t *testing.T) {
 		"explicit_max_ttl": json.Number("0"),
 		"expire_time":      nil,
 		"entity_id":        "",
+		"type":             "service",
 	}
 
 	resp = testHttpGet(t, newRootToken, addr+"/v1/auth/token/lookup-self")
@@ -405,6 +406,7 @@ func TestSysGenerateRoot_Update_PGP(t *testing.T) {
 		"explicit_max_ttl": json.Number("0"),
 		"expire_time":      nil,
 		"entity_id":        "",
+		"type":             "service",
 	}
 
 	resp = testHttpGet(t, newRootToken, addr+"/v1/auth/token/lookup-self")
@@ -0,0 +1,39 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/vault"
+)
+
+// handleSysMetrics serves the metrics of this node directly if they may be
+// read without a token, so that each node of a cluster can be scraped.
+// Otherwise the request goes through the system backend like any other.
+func handleSysMetrics(core *vault.Core) http.Handler {
+	authenticated := handleRequestForwarding(core, handleLogical(core, false, nil))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		helper := core.MetricsHelper()
+		if helper == nil || !core.UnauthenticatedMetricsAccess() {
+			authenticated.ServeHTTP(w, r)
+			return
+		}
+
+		if r.Method != "GET" {
+			respondError(w, http.StatusMethodNotAllowed, nil)
+			return
+		}
+
+		req := &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "sys/metrics",
+		}
+		resp, err := helper.ResponseForFormat(r.URL.Query().Get("format"))
+		if respondErrorCommon(w, req, resp, err) {
+			return
+		}
+
+		respondRaw(w, r, resp)
+	})
+}
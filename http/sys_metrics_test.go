@@ -0,0 +1,73 @@
+package http
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	metrics "github.com/armon/go-metrics"
+	"github.com/hashicorp/vault/helper/metricsutil"
+	"github.com/hashicorp/vault/vault"
+)
+
+func testMetricsHelper() *metricsutil.MetricsHelper {
+	inm := metrics.NewInmemSink(10*time.Second, time.Minute)
+	prom := metricsutil.NewPrometheusSink(time.Hour)
+	fanout := metrics.FanoutSink{inm, prom}
+	fanout.AddSampleWithLabels([]string{"vault", "route", "read"}, 1, []metrics.Label{{Name: "mount", Value: "secret-"}})
+	return metricsutil.NewMetricsHelper(inm, prom)
+}
+
+func TestSysMetrics(t *testing.T) {
+	core, _, token := vault.TestCoreUnsealedWithMetrics(t, testMetricsHelper(), false)
+	ln, addr := TestServer(t, core)
+	defer ln.Close()
+
+	// A token is required
+	resp := testHttpGet(t, "", addr+"/v1/sys/metrics")
+	testResponseStatus(t, resp, 400)
+
+	resp = testHttpGet(t, token, addr+"/v1/sys/metrics")
+	testResponseStatus(t, resp, 200)
+	if ct := resp.Header.Get("Content-Type"); ct != metricsutil.PrometheusContentType {
+		t.Fatalf("bad content type: %q", ct)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(body), `vault_route_read_count{mount="secret-"} 1`) {
+		t.Fatalf("bad body: %s", body)
+	}
+
+	resp = testHttpGet(t, token, addr+"/v1/sys/metrics?format=json")
+	testResponseStatus(t, resp, 200)
+	var summary metrics.MetricsSummary
+	testResponseBody(t, resp, &summary)
+	if len(summary.Samples) != 1 || summary.Samples[0].Name != "vault.route.read" || summary.Samples[0].DisplayLabels["mount"] != "secret-" {
+		t.Fatalf("bad summary: %#v", summary)
+	}
+
+	resp = testHttpGet(t, token, addr+"/v1/sys/metrics?format=xml")
+	testResponseStatus(t, resp, 400)
+}
+
+func TestSysMetrics_unauthenticated(t *testing.T) {
+	core, _, _ := vault.TestCoreUnsealedWithMetrics(t, testMetricsHelper(), true)
+	ln, addr := TestServer(t, core)
+	defer ln.Close()
+
+	resp, err := http.Get(addr + "/v1/sys/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testResponseStatus(t, resp, 200)
+	if ct := resp.Header.Get("Content-Type"); ct != metricsutil.PrometheusContentType {
+		t.Fatalf("bad content type: %q", ct)
+	}
+
+	resp = testHttpPut(t, "", addr+"/v1/sys/metrics", nil)
+	testResponseStatus(t, resp, 405)
+}
@@ -0,0 +1,57 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/hashicorp/vault/vault"
+)
+
+func handleSysRaftJoin(core *vault.Core) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "PUT", "POST":
+			handleSysRaftJoinPut(core, w, r)
+		default:
+			respondError(w, http.StatusMethodNotAllowed, nil)
+		}
+	})
+}
+
+func handleSysRaftJoinPut(core *vault.Core, w http.ResponseWriter, r *http.Request) {
+	// Parse the request
+	var req JoinRequest
+	if err := parseRequest(r, w, &req); err != nil {
+		respondError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// The token is not checked here, as this node has no data yet; it is
+	// passed on to the leader, which uses it to authorize the join
+	err := core.JoinRaftCluster(&vault.RaftJoinRequest{
+		LeaderAPIAddr: req.LeaderAPIAddr,
+		LeaderCACert:  req.LeaderCACert,
+		Token:         r.Header.Get(AuthHeaderName),
+	})
+	switch err {
+	case nil:
+	case vault.ErrNotRaftStorage, vault.ErrAlreadyInit:
+		respondError(w, http.StatusBadRequest, err)
+		return
+	default:
+		respondError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	respondOk(w, &JoinResponse{
+		Joined: true,
+	})
+}
+
+type JoinRequest struct {
+	LeaderAPIAddr string `json:"leader_api_addr"`
+	LeaderCACert  string `json:"leader_ca_cert"`
+}
+
+type JoinResponse struct {
+	Joined bool `json:"joined"`
+}
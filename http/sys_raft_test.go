@@ -0,0 +1,222 @@
+package http
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/helper/logformat"
+	"github.com/hashicorp/vault/physical/raft"
+	"github.com/hashicorp/vault/vault"
+	log "github.com/mgutz/logxi/v1"
+)
+
+type raftTestNode struct {
+	core        *vault.Core
+	backend     *raft.RaftBackend
+	addr        string
+	clusterAddr *net.TCPAddr
+	ln          net.Listener
+	dir         string
+}
+
+func (n *raftTestNode) cleanup() {
+	n.core.Shutdown()
+	n.ln.Close()
+	os.RemoveAll(n.dir)
+}
+
+func testRaftNode(t *testing.T) *raftTestNode {
+	t.Helper()
+	logger := logformat.NewVaultLogger(log.LevelTrace)
+
+	dir, err := ioutil.TempDir("", "vault-raft")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	raw, err := raft.NewRaftBackend(map[string]string{"path": dir}, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	backend := raw.(*raft.RaftBackend)
+
+	// Reserve a port for the cluster listener
+	clusterLn, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	clusterAddr := clusterLn.Addr().(*net.TCPAddr)
+	clusterLn.Close()
+
+	ln, addr := TestListener(t)
+	core, err := vault.NewCore(&vault.CoreConfig{
+		Physical:     backend,
+		HAPhysical:   backend,
+		RedirectAddr: addr,
+		ClusterAddr:  fmt.Sprintf("https://%s", clusterAddr),
+		DisableMlock: true,
+		Logger:       logger,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	core.SetClusterListenerAddrs([]*net.TCPAddr{clusterAddr})
+	TestServerWithListener(t, ln, addr, core)
+
+	return &raftTestNode{
+		core:        core,
+		backend:     backend,
+		addr:        addr,
+		clusterAddr: clusterAddr,
+		ln:          ln,
+		dir:         dir,
+	}
+}
+
+func waitFor(t *testing.T, desc string, check func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(20 * time.Second)
+	for !check() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", desc)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+func TestSysRaft_JoinAndReplicate(t *testing.T) {
+	leader := testRaftNode(t)
+	defer leader.cleanup()
+
+	keys, _, token := vault.TestCoreInitClusterWrapperSetup(t, leader.core, []*net.TCPAddr{leader.clusterAddr}, nil)
+	for _, key := range keys {
+		if _, err := leader.core.Unseal(vault.TestKeyCopy(key)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	waitFor(t, "leader to become active", func() bool {
+		standby, err := leader.core.Standby()
+		return err == nil && !standby
+	})
+
+	resp := testHttpPut(t, token, leader.addr+"/v1/secret/foo", map[string]interface{}{
+		"data": "bar",
+	})
+	testResponseStatus(t, resp, 204)
+
+	var followers []*raftTestNode
+	for i := 0; i < 2; i++ {
+		follower := testRaftNode(t)
+		defer follower.cleanup()
+
+		// Joining requires a token valid on the leader
+		resp = testHttpPut(t, "", follower.addr+"/v1/sys/storage/raft/join", map[string]interface{}{
+			"leader_api_addr": leader.addr,
+		})
+		testResponseStatus(t, resp, 500)
+
+		resp = testHttpPut(t, token, follower.addr+"/v1/sys/storage/raft/join", map[string]interface{}{
+			"leader_api_addr": leader.addr,
+		})
+		testResponseStatus(t, resp, 200)
+
+		waitFor(t, "follower to receive data", func() bool {
+			init, err := follower.core.Initialized()
+			return err == nil && init
+		})
+
+		for _, key := range keys {
+			if _, err := follower.core.Unseal(vault.TestKeyCopy(key)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if sealed, _ := follower.core.Sealed(); sealed {
+			t.Fatal("follower should be unsealed")
+		}
+		followers = append(followers, follower)
+	}
+
+	for _, follower := range followers {
+		standby, err := follower.core.Standby()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !standby {
+			t.Fatal("follower should be a standby")
+		}
+
+		waitFor(t, "follower to find the leader", func() bool {
+			isLeader, leaderAddr, _, err := follower.core.Leader()
+			return err == nil && !isLeader && leaderAddr == leader.addr
+		})
+	}
+
+	// The joined nodes are promoted to voters once they have caught up
+	waitFor(t, "followers to become voters", func() bool {
+		resp := testHttpGet(t, token, leader.addr+"/v1/sys/storage/raft/configuration")
+		testResponseStatus(t, resp, 200)
+		var actual map[string]interface{}
+		testResponseBody(t, resp, &actual)
+		servers := actual["data"].(map[string]interface{})["servers"].([]interface{})
+		if len(servers) != 3 {
+			t.Fatalf("bad servers: %#v", servers)
+		}
+		for _, server := range servers {
+			if !server.(map[string]interface{})["voter"].(bool) {
+				return false
+			}
+		}
+		return true
+	})
+
+	// A snapshot can be taken from the active node
+	resp = testHttpGet(t, token, leader.addr+"/v1/sys/storage/raft/snapshot")
+	testResponseStatus(t, resp, 200)
+	gz, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ioutil.ReadAll(gz); err != nil {
+		t.Fatal(err)
+	}
+
+	resp = testHttpPut(t, token, leader.addr+"/v1/sys/storage/raft/remove-peer", map[string]interface{}{
+		"server_id": followers[1].backend.NodeID(),
+	})
+	testResponseStatus(t, resp, 204)
+
+	peers, err := leader.backend.Peers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(peers) != 2 {
+		t.Fatalf("bad peers: %#v", peers)
+	}
+}
+
+func TestSysRaft_JoinNotRaft(t *testing.T) {
+	core := vault.TestCore(t)
+	ln, addr := TestServer(t, core)
+	defer ln.Close()
+
+	resp := testHttpPut(t, "", addr+"/v1/sys/storage/raft/join", map[string]interface{}{
+		"leader_api_addr": "http://127.0.0.1:8200",
+	})
+	testResponseStatus(t, resp, 400)
+
+	// The management endpoints are only mounted with raft storage
+	core, _, token := vault.TestCoreUnsealed(t)
+	ln, addr = TestServer(t, core)
+	defer ln.Close()
+
+	resp = testHttpGet(t, token, addr+"/v1/sys/storage/raft/configuration")
+	if resp.StatusCode == http.StatusOK {
+		t.Fatal("expected raft configuration to be unavailable")
+	}
+}
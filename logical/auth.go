@@ -56,9 +56,19 @@ type Auth struct {
 	// identity of the authenticating client belongs to.
 	EntityID string `json:"entity_id" mapstructure:"entity_id" structs:"entity_id"`
 
+	// TokenType is the type of the issued token. Auth backends can set it to
+	// request a type, which is honored unless the mount is tuned to only
+	// issue tokens of the other type. It is then filled in by Vault core.
+	TokenType TokenType `json:"token_type" mapstructure:"token_type" structs:"token_type"`
+
 	// Alias is the information about the authenticated client returned by
 	// the auth backend
 	Alias *Alias `json:"alias" structs:"alias" mapstructure:"alias"`
+
+	// GroupAliases are the information about the groups the authenticated
+	// client belongs to in its authentication source. They are used to
+	// update the memberships of external groups in the identity store.
+	GroupAliases []*Alias `json:"group_aliases" structs:"group_aliases" mapstructure:"group_aliases"`
 }
 
 func (a *Auth) GoString() string {
@@ -13,4 +13,8 @@ type Alias struct {
 
 	// Name is the identifier of this identity in its authentication source
 	Name string `json:"name" structs:"name" mapstructure:"name"`
+
+	// Metadata is used to add metadata to the alias in the identity store,
+	// such as claims of the authenticating client
+	Metadata map[string]string `json:"metadata" structs:"metadata" mapstructure:"metadata"`
 }
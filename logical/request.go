@@ -33,6 +33,10 @@ func (r *RequestWrapInfo) SentinelGet(key string) (interface{}, error) {
 	return nil, nil
 }
 
+// MFACreds maps MFA method names to the credentials, such as passcodes,
+// supplied for them
+type MFACreds map[string][]string
+
 // Request is a struct that stores the parameters and context of a request
 // being made to Vault. It is used to abstract the details of the higher level
 // request protocol from the handlers.
@@ -125,6 +129,15 @@ type Request struct {
 	// soft-mandatory Sentinel policies
 	PolicyOverride bool `json:"policy_override" structs:"policy_override" mapstructure:"policy_override"`
 
+	// MFACreds holds the MFA credentials supplied with the request, keyed by
+	// MFA method name
+	MFACreds MFACreds `json:"mfa_creds" structs:"mfa_creds" mapstructure:"mfa_creds" sentinel:""`
+
+	// ControlGroupAuthorized is set by the core when it performs a request
+	// that was held until its control group authorized it. It is never set
+	// from client input.
+	ControlGroupAuthorized bool `json:"-" structs:"-" mapstructure:"-" sentinel:""`
+
 	// Whether the request is unauthenticated, as in, had no client token
 	// attached. Useful in some situations where the client token is not made
 	// accessible.
@@ -256,4 +269,12 @@ var (
 
 	// ErrPermissionDenied is returned if the client is not authorized
 	ErrPermissionDenied = errors.New("permission denied")
+
+	// ErrRateLimitQuotaExceeded is returned if a request is rejected by a
+	// rate-limit quota
+	ErrRateLimitQuotaExceeded = errors.New("rate limit quota exceeded")
+
+	// ErrLeaseCountQuotaExceeded is returned if a request would create a
+	// lease beyond the limit of a lease-count quota
+	ErrLeaseCountQuotaExceeded = errors.New("lease count quota exceeded")
 )
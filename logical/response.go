@@ -15,6 +15,8 @@ const (
 	HTTPContentType = "http_content_type"
 
 	// HTTPRawBody is the raw content of the HTTP body that goes with the HTTPContentType.
+	// It is also set in the Data of requests whose body is of a content type
+	// that is passed to the backend verbatim, such as OCSP requests.
 	// This can only be specified for non-secrets, and should should be similarly
 	// avoided like the HTTPContentType. The value must be a byte slice.
 	HTTPRawBody = "http_raw_body"
@@ -23,6 +25,12 @@ const (
 	// This can only be specified for non-secrets, and should should be similarly
 	// avoided like the HTTPContentType. The value must be an integer.
 	HTTPStatusCode = "http_status_code"
+
+	// HTTPHeaders are additional headers of the HTTP response that goes with
+	// the HTTPContentType. This can only be specified for non-secrets, and
+	// should be similarly avoided like the HTTPContentType. The value must be
+	// a map[string][]string.
+	HTTPHeaders = "http_headers"
 )
 
 // Response is a struct that stores the response of a request.
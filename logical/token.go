@@ -0,0 +1,113 @@
+package logical
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// TokenType is the type of a token: service tokens are persisted to storage
+// and tracked by the expiration manager, batch tokens are encrypted blobs
+// that carry their own state and are never persisted.
+type TokenType uint8
+
+const (
+	// TokenTypeDefault is the zero value. For token entries it means service,
+	// for mounts it means default-service.
+	TokenTypeDefault TokenType = iota
+	TokenTypeService
+	TokenTypeBatch
+
+	// TokenTypeDefaultService and TokenTypeDefaultBatch are only used in the
+	// configuration of mounts and token roles, where they allow the type to
+	// be overridden by the auth backend or the creation request
+	TokenTypeDefaultService
+	TokenTypeDefaultBatch
+)
+
+// ParseTokenType parses the name of a token type, an empty name being the
+// default type
+func ParseTokenType(str string) (TokenType, error) {
+	switch str {
+	case "", "default":
+		return TokenTypeDefault, nil
+	case "service":
+		return TokenTypeService, nil
+	case "batch":
+		return TokenTypeBatch, nil
+	case "default-service":
+		return TokenTypeDefaultService, nil
+	case "default-batch":
+		return TokenTypeDefaultBatch, nil
+	default:
+		return TokenTypeDefault, fmt.Errorf("invalid token type %q", str)
+	}
+}
+
+func (t TokenType) String() string {
+	switch t {
+	case TokenTypeDefault:
+		return "default"
+	case TokenTypeService:
+		return "service"
+	case TokenTypeBatch:
+		return "batch"
+	case TokenTypeDefaultService:
+		return "default-service"
+	case TokenTypeDefaultBatch:
+		return "default-batch"
+	default:
+		return fmt.Sprintf("unknown (%d)", t)
+	}
+}
+
+// Resolve returns the type of a token created with the given configured
+// type, and the type requested by the auth backend or the creation request.
+// An error is returned if the requested type conflicts with a configured
+// type that can't be overridden.
+func (t TokenType) Resolve(requested TokenType) (TokenType, error) {
+	switch t {
+	case TokenTypeService, TokenTypeBatch:
+		if requested != TokenTypeDefault && requested != t {
+			return TokenTypeDefault, fmt.Errorf("token type %q was requested but only %q tokens can be created", requested, t)
+		}
+		return t, nil
+
+	case TokenTypeDefaultBatch:
+		if requested == TokenTypeService {
+			return TokenTypeService, nil
+		}
+		return TokenTypeBatch, nil
+
+	default:
+		if requested == TokenTypeBatch {
+			return TokenTypeBatch, nil
+		}
+		return TokenTypeService, nil
+	}
+}
+
+// MarshalJSON encodes the type as its name
+func (t TokenType) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.String())
+}
+
+// UnmarshalJSON decodes the type from its name, or from its number for
+// compatibility
+func (t *TokenType) UnmarshalJSON(b []byte) error {
+	var n uint8
+	if err := json.Unmarshal(b, &n); err == nil {
+		*t = TokenType(n)
+		return nil
+	}
+
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	parsed, err := ParseTokenType(s)
+	if err != nil {
+		return err
+	}
+	*t = parsed
+	return nil
+}
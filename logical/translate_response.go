@@ -34,6 +34,7 @@ func LogicalResponseToHTTPResponse(input *Response) *HTTPResponse {
 			LeaseDuration: int(input.Auth.TTL.Seconds()),
 			Renewable:     input.Auth.Renewable,
 			EntityID:      input.Auth.EntityID,
+			TokenType:     input.Auth.TokenType.String(),
 		}
 	}
 
@@ -64,6 +65,7 @@ func HTTPResponseToLogicalResponse(input *HTTPResponse) *Response {
 		}
 		logicalResp.Auth.Renewable = input.Auth.Renewable
 		logicalResp.Auth.TTL = time.Second * time.Duration(input.Auth.LeaseDuration)
+		logicalResp.Auth.TokenType, _ = ParseTokenType(input.Auth.TokenType)
 	}
 
 	return logicalResp
@@ -88,10 +90,12 @@ type HTTPAuth struct {
 	LeaseDuration int               `json:"lease_duration"`
 	Renewable     bool              `json:"renewable"`
 	EntityID      string            `json:"entity_id"`
+	TokenType     string            `json:"token_type"`
 }
 
 type HTTPWrapInfo struct {
 	Token           string `json:"token"`
+	Accessor        string `json:"accessor"`
 	TTL             int    `json:"ttl"`
 	CreationTime    string `json:"creation_time"`
 	CreationPath    string `json:"creation_path"`
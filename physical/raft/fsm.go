@@ -0,0 +1,300 @@
+package raft
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/boltdb/bolt"
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/raft"
+	"github.com/hashicorp/vault/helper/jsonutil"
+	"github.com/hashicorp/vault/physical"
+	log "github.com/mgutz/logxi/v1"
+)
+
+const (
+	deleteOp uint32 = 1 << iota
+	putOp
+)
+
+var (
+	dataBucketName   = []byte("data")
+	configBucketName = []byte("config")
+	latestIndexKey   = []byte("latest_index")
+)
+
+// LogOperation represents a single storage operation carried by a raft log
+type LogOperation struct {
+	// OpType is the operation type, either put or delete
+	OpType uint32 `json:"op_type"`
+
+	// Key is the storage key the operation acts on
+	Key string `json:"key"`
+
+	// Value is only set for put operations
+	Value []byte `json:"value,omitempty"`
+}
+
+// LogData is the payload of a raft log. All of its operations are applied to
+// the FSM in a single transaction.
+type LogData struct {
+	Operations []*LogOperation `json:"operations"`
+}
+
+var _ raft.FSM = (*FSM)(nil)
+
+// FSM is the raft finite state machine. It stores the replicated data in a
+// BoltDB file so that it survives restarts and can be read while Vault is
+// sealed.
+type FSM struct {
+	// l protects the db handle, which is swapped out during a restore
+	l      sync.RWMutex
+	path   string
+	logger log.Logger
+	db     *bolt.DB
+}
+
+// NewFSM opens, and creates if needed, the FSM database in the given
+// directory
+func NewFSM(path string, logger log.Logger) (*FSM, error) {
+	f := &FSM{
+		path:   path,
+		logger: logger,
+	}
+
+	if err := f.openDBFile(); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+func (f *FSM) dbPath() string {
+	return filepath.Join(f.path, "vault.db")
+}
+
+func (f *FSM) openDBFile() error {
+	db, err := bolt.Open(f.dbPath(), 0600, nil)
+	if err != nil {
+		return errwrap.Wrapf("failed to open raft storage database: {{err}}", err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(dataBucketName); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucketIfNotExists(configBucketName)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return errwrap.Wrapf("failed to create raft storage buckets: {{err}}", err)
+	}
+
+	f.db = db
+	return nil
+}
+
+// Close closes the underlying database
+func (f *FSM) Close() error {
+	f.l.Lock()
+	defer f.l.Unlock()
+
+	return f.db.Close()
+}
+
+// LatestIndex returns the index of the last log applied to the FSM
+func (f *FSM) LatestIndex() (uint64, error) {
+	f.l.RLock()
+	defer f.l.RUnlock()
+
+	var index uint64
+	err := f.db.View(func(tx *bolt.Tx) error {
+		index = latestIndex(tx)
+		return nil
+	})
+
+	return index, err
+}
+
+func latestIndex(tx *bolt.Tx) uint64 {
+	val := tx.Bucket(configBucketName).Get(latestIndexKey)
+	if len(val) != 8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(val)
+}
+
+// Get returns the entry stored at the given key
+func (f *FSM) Get(key string) (*physical.Entry, error) {
+	f.l.RLock()
+	defer f.l.RUnlock()
+
+	var entry *physical.Entry
+	err := f.db.View(func(tx *bolt.Tx) error {
+		val := tx.Bucket(dataBucketName).Get([]byte(key))
+		if val == nil {
+			return nil
+		}
+
+		// Bolt values are only valid for the life of the transaction
+		entry = &physical.Entry{
+			Key:   key,
+			Value: make([]byte, len(val)),
+		}
+		copy(entry.Value, val)
+		return nil
+	})
+
+	return entry, err
+}
+
+// List returns the keys directly under the given prefix. Deeper keys are
+// returned as a "folder" with a trailing slash.
+func (f *FSM) List(prefix string) ([]string, error) {
+	f.l.RLock()
+	defer f.l.RUnlock()
+
+	var keys []string
+	err := f.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(dataBucketName).Cursor()
+
+		prefixBytes := []byte(prefix)
+		for k, _ := c.Seek(prefixBytes); k != nil && bytes.HasPrefix(k, prefixBytes); k, _ = c.Next() {
+			key := strings.TrimPrefix(string(k), prefix)
+			if i := strings.Index(key, "/"); i != -1 {
+				key = key[:i+1]
+				if len(keys) > 0 && keys[len(keys)-1] == key {
+					continue
+				}
+			}
+			keys = append(keys, key)
+		}
+		return nil
+	})
+
+	return keys, err
+}
+
+// Apply is called by raft once a log has been committed. Logs that have
+// already been applied, e.g. when they are replayed after a restart, are
+// skipped.
+func (f *FSM) Apply(l *raft.Log) interface{} {
+	var command LogData
+	if err := jsonutil.DecodeJSON(l.Data, &command); err != nil {
+		f.logger.Error("raft: failed to decode log", "index", l.Index, "error", err)
+		return err
+	}
+
+	f.l.RLock()
+	defer f.l.RUnlock()
+
+	err := f.db.Update(func(tx *bolt.Tx) error {
+		if latestIndex(tx) >= l.Index {
+			return nil
+		}
+
+		b := tx.Bucket(dataBucketName)
+		for _, op := range command.Operations {
+			var err error
+			switch op.OpType {
+			case putOp:
+				err = b.Put([]byte(op.Key), op.Value)
+			case deleteOp:
+				err = b.Delete([]byte(op.Key))
+			default:
+				err = fmt.Errorf("unknown operation type %d", op.OpType)
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		index := make([]byte, 8)
+		binary.BigEndian.PutUint64(index, l.Index)
+		return tx.Bucket(configBucketName).Put(latestIndexKey, index)
+	})
+	if err != nil {
+		f.logger.Error("raft: failed to apply log", "index", l.Index, "error", err)
+		return err
+	}
+
+	return nil
+}
+
+// Snapshot returns a snapshot of the FSM. The snapshot is backed by a read
+// transaction so that writes can continue while it is being persisted.
+func (f *FSM) Snapshot() (raft.FSMSnapshot, error) {
+	f.l.RLock()
+	defer f.l.RUnlock()
+
+	tx, err := f.db.Begin(false)
+	if err != nil {
+		return nil, err
+	}
+
+	return &fsmSnapshot{
+		tx: tx,
+	}, nil
+}
+
+// Restore replaces the contents of the FSM with the given snapshot
+func (f *FSM) Restore(r io.ReadCloser) error {
+	defer r.Close()
+
+	restorePath := f.dbPath() + ".restore"
+	file, err := os.OpenFile(restorePath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(file, r); err != nil {
+		file.Close()
+		os.Remove(restorePath)
+		return errwrap.Wrapf("failed to write snapshot: {{err}}", err)
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		os.Remove(restorePath)
+		return err
+	}
+	file.Close()
+
+	f.l.Lock()
+	defer f.l.Unlock()
+
+	if err := f.db.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(restorePath, f.dbPath()); err != nil {
+		return err
+	}
+
+	f.logger.Info("raft: restored storage from snapshot")
+
+	return f.openDBFile()
+}
+
+// fsmSnapshot implements raft.FSMSnapshot by writing out the whole database
+// as seen by its read transaction
+type fsmSnapshot struct {
+	tx *bolt.Tx
+}
+
+func (s *fsmSnapshot) Persist(sink raft.SnapshotSink) error {
+	if _, err := s.tx.WriteTo(sink); err != nil {
+		sink.Cancel()
+		return err
+	}
+
+	return sink.Close()
+}
+
+func (s *fsmSnapshot) Release() {
+	s.tx.Rollback()
+}
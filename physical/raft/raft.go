@@ -0,0 +1,870 @@
+package raft
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	stdlog "log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/armon/go-metrics"
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/raft"
+	"github.com/hashicorp/raft-boltdb"
+	"github.com/hashicorp/vault/helper/jsonutil"
+	"github.com/hashicorp/vault/physical"
+	log "github.com/mgutz/logxi/v1"
+)
+
+const (
+	// snapshotsRetained is the number of snapshots kept on disk
+	snapshotsRetained = 2
+
+	// raftLogCacheSize is the number of recent logs kept in memory
+	raftLogCacheSize = 512
+
+	// applyTimeout bounds how long a write waits to be enqueued by raft
+	applyTimeout = 10 * time.Second
+
+	// nodeIDFileName stores the generated node ID when none is configured
+	nodeIDFileName = "node-id"
+)
+
+var (
+	// ErrNotLeader is returned for writes made on a node that is not the
+	// raft leader
+	ErrNotLeader = errors.New("raft storage is not the leader; writes must be made on the active node")
+
+	// ErrNotInitialized is returned when storage is used before the raft
+	// cluster has been set up
+	ErrNotInitialized = errors.New("raft storage is not initialized")
+
+	_ physical.Backend       = (*RaftBackend)(nil)
+	_ physical.HABackend     = (*RaftBackend)(nil)
+	_ physical.Transactional = (*RaftBackend)(nil)
+	_ physical.Lock          = (*RaftLock)(nil)
+)
+
+// RaftBackend is a physical backend that replicates its data between Vault
+// nodes using the raft consensus protocol. Reads are served from the local
+// FSM; writes are only accepted on the raft leader, which is also the node
+// holding the HA lock.
+type RaftBackend struct {
+	logger    log.Logger
+	stdLogger *stdlog.Logger
+
+	// l protects the raft state below, which is torn down and set up again
+	// as the node is sealed and unsealed
+	l              sync.RWMutex
+	raft           *raft.Raft
+	raftTransport  raft.Transport
+	stopWatchCh    chan struct{}
+	bootstrapPeers []Peer
+
+	fsm         *FSM
+	dataDir     string
+	localID     string
+	logStore    raft.LogStore
+	stableStore raft.StableStore
+	boltStore   *raftboltdb.BoltStore
+	snapStore   raft.SnapshotStore
+	permitPool  *physical.PermitPool
+
+	// stateLock protects the channel used to wake up lock waiters and the
+	// set of locks held by this node
+	stateLock   sync.Mutex
+	stateCh     chan struct{}
+	locksHeld   map[string]bool
+	leaderState bool
+}
+
+// Peer is a member of the raft cluster
+type Peer struct {
+	ID      string `json:"id"`
+	Address string `json:"address"`
+}
+
+// RaftServer describes a server in the current raft configuration
+type RaftServer struct {
+	NodeID  string `json:"node_id" structs:"node_id" mapstructure:"node_id"`
+	Address string `json:"address" structs:"address" mapstructure:"address"`
+	Leader  bool   `json:"leader" structs:"leader" mapstructure:"leader"`
+	Voter   bool   `json:"voter" structs:"voter" mapstructure:"voter"`
+}
+
+// SetupOpts are the options used to start raft on this node
+type SetupOpts struct {
+	// StreamLayer carries the raft traffic to and from other nodes
+	StreamLayer raft.StreamLayer
+}
+
+// NewRaftBackend constructs a RaftBackend storing its data under the
+// configured path
+func NewRaftBackend(conf map[string]string, logger log.Logger) (physical.Backend, error) {
+	path, ok := conf["path"]
+	if !ok {
+		return nil, fmt.Errorf("'path' must be set")
+	}
+
+	if err := os.MkdirAll(filepath.Join(path, "raft"), 0700); err != nil {
+		return nil, errwrap.Wrapf("failed to create raft storage directory: {{err}}", err)
+	}
+
+	localID, err := nodeID(path, conf["node_id"])
+	if err != nil {
+		return nil, err
+	}
+
+	stdLogger := stdlog.New(&logWriter{logger: logger}, "", 0)
+
+	fsm, err := NewFSM(path, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	boltStore, err := raftboltdb.NewBoltStore(filepath.Join(path, "raft", "raft.db"))
+	if err != nil {
+		fsm.Close()
+		return nil, errwrap.Wrapf("failed to open raft log store: {{err}}", err)
+	}
+
+	logStore, err := raft.NewLogCache(raftLogCacheSize, boltStore)
+	if err != nil {
+		fsm.Close()
+		boltStore.Close()
+		return nil, err
+	}
+
+	snapStore, err := raft.NewFileSnapshotStoreWithLogger(filepath.Join(path, "raft"), snapshotsRetained, stdLogger)
+	if err != nil {
+		fsm.Close()
+		boltStore.Close()
+		return nil, errwrap.Wrapf("failed to create raft snapshot store: {{err}}", err)
+	}
+
+	return &RaftBackend{
+		logger:      logger,
+		stdLogger:   stdLogger,
+		fsm:         fsm,
+		dataDir:     path,
+		localID:     localID,
+		logStore:    logStore,
+		stableStore: boltStore,
+		boltStore:   boltStore,
+		snapStore:   snapStore,
+		permitPool:  physical.NewPermitPool(physical.DefaultParallelOperations),
+		stateCh:     make(chan struct{}),
+		locksHeld:   make(map[string]bool),
+	}, nil
+}
+
+// nodeID returns the configured node ID or, if none is set, the one persisted
+// in the data directory, generating it the first time
+func nodeID(path, configured string) (string, error) {
+	if configured != "" {
+		return configured, nil
+	}
+
+	idPath := filepath.Join(path, nodeIDFileName)
+	existing, err := ioutil.ReadFile(idPath)
+	switch {
+	case err == nil:
+		return strings.TrimSpace(string(existing)), nil
+	case !os.IsNotExist(err):
+		return "", err
+	}
+
+	id, err := uuid.GenerateUUID()
+	if err != nil {
+		return "", err
+	}
+	if err := ioutil.WriteFile(idPath, []byte(id), 0600); err != nil {
+		return "", errwrap.Wrapf("failed to persist raft node id: {{err}}", err)
+	}
+
+	return id, nil
+}
+
+// NodeID returns the ID of the local node
+func (b *RaftBackend) NodeID() string {
+	return b.localID
+}
+
+// HasState returns whether this node already has raft state, either because
+// it bootstrapped a cluster or because it joined one
+func (b *RaftBackend) HasState() (bool, error) {
+	return raft.HasExistingState(b.logStore, b.stableStore, b.snapStore)
+}
+
+// Bootstrap sets the initial members of a new cluster. It takes effect the
+// next time SetupCluster is called and only if the node has no raft state yet.
+func (b *RaftBackend) Bootstrap(peers []Peer) error {
+	hasState, err := b.HasState()
+	if err != nil {
+		return err
+	}
+	if hasState {
+		return errors.New("raft storage is already initialized")
+	}
+
+	b.l.Lock()
+	b.bootstrapPeers = peers
+	b.l.Unlock()
+
+	return nil
+}
+
+// Initialized returns whether raft is running on this node
+func (b *RaftBackend) Initialized() bool {
+	b.l.RLock()
+	defer b.l.RUnlock()
+
+	return b.raft != nil
+}
+
+// SetupCluster starts raft on this node using the given stream layer to talk
+// to the other nodes
+func (b *RaftBackend) SetupCluster(opts SetupOpts) error {
+	b.l.Lock()
+	defer b.l.Unlock()
+
+	if b.raft != nil {
+		return errors.New("raft storage is already running")
+	}
+	if opts.StreamLayer == nil {
+		return errors.New("no stream layer provided")
+	}
+
+	config := raft.DefaultConfig()
+	config.LocalID = raft.ServerID(b.localID)
+	config.Logger = b.stdLogger
+	notifyCh := make(chan bool, 10)
+	config.NotifyCh = notifyCh
+
+	transport := raft.NewNetworkTransportWithConfig(&raft.NetworkTransportConfig{
+		Stream:  opts.StreamLayer,
+		MaxPool: 3,
+		Timeout: 10 * time.Second,
+		Logger:  b.stdLogger,
+	})
+
+	if len(b.bootstrapPeers) > 0 {
+		hasState, err := b.HasState()
+		if err != nil {
+			transport.Close()
+			return err
+		}
+		if !hasState {
+			var configuration raft.Configuration
+			for _, peer := range b.bootstrapPeers {
+				configuration.Servers = append(configuration.Servers, raft.Server{
+					ID:      raft.ServerID(peer.ID),
+					Address: raft.ServerAddress(peer.Address),
+				})
+			}
+			if err := raft.BootstrapCluster(config, b.logStore, b.stableStore, b.snapStore, transport, configuration); err != nil {
+				transport.Close()
+				return errwrap.Wrapf("failed to bootstrap raft cluster: {{err}}", err)
+			}
+		}
+		b.bootstrapPeers = nil
+	}
+
+	raftObj, err := raft.NewRaft(config, b.fsm, b.logStore, b.stableStore, b.snapStore, transport)
+	if err != nil {
+		transport.Close()
+		return errwrap.Wrapf("failed to start raft: {{err}}", err)
+	}
+
+	b.raft = raftObj
+	b.raftTransport = transport
+	b.stopWatchCh = make(chan struct{})
+	go b.watchLeadership(notifyCh, b.stopWatchCh)
+
+	b.logger.Info("raft: storage cluster started", "node_id", b.localID)
+
+	return nil
+}
+
+// TeardownCluster stops raft on this node. The data stays readable.
+func (b *RaftBackend) TeardownCluster() error {
+	b.l.Lock()
+	defer b.l.Unlock()
+
+	if b.raft == nil {
+		return nil
+	}
+
+	close(b.stopWatchCh)
+	err := b.raft.Shutdown().Error()
+	if closer, ok := b.raftTransport.(raft.WithClose); ok {
+		closer.Close()
+	}
+
+	b.raft = nil
+	b.raftTransport = nil
+	b.setLeaderState(false)
+
+	b.logger.Info("raft: storage cluster stopped", "node_id", b.localID)
+
+	return err
+}
+
+// watchLeadership tracks leadership changes so that lock holders and waiters
+// can be woken up
+func (b *RaftBackend) watchLeadership(notifyCh <-chan bool, stopCh <-chan struct{}) {
+	for {
+		select {
+		case isLeader := <-notifyCh:
+			if b.logger.IsDebug() {
+				b.logger.Debug("raft: leadership changed", "leader", isLeader)
+			}
+			b.setLeaderState(isLeader)
+		case <-stopCh:
+			return
+		}
+	}
+}
+
+func (b *RaftBackend) setLeaderState(isLeader bool) {
+	b.stateLock.Lock()
+	b.leaderState = isLeader
+	b.notifyStateChangeLocked()
+	b.stateLock.Unlock()
+}
+
+// notifyStateChangeLocked wakes up everything waiting on the state channel.
+// The state lock must be held.
+func (b *RaftBackend) notifyStateChangeLocked() {
+	close(b.stateCh)
+	b.stateCh = make(chan struct{})
+}
+
+// IsLeader returns whether this node is the raft leader
+func (b *RaftBackend) IsLeader() bool {
+	b.stateLock.Lock()
+	defer b.stateLock.Unlock()
+
+	return b.leaderState
+}
+
+// WaitForLeader blocks until this node is the leader or the timeout expires
+func (b *RaftBackend) WaitForLeader(timeout time.Duration) error {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		b.stateLock.Lock()
+		isLeader := b.leaderState
+		stateCh := b.stateCh
+		b.stateLock.Unlock()
+
+		if isLeader {
+			return nil
+		}
+
+		select {
+		case <-stateCh:
+		case <-timer.C:
+			return errors.New("timed out waiting for raft leadership")
+		}
+	}
+}
+
+// AddPeer adds a server to the cluster. It is added as a non-voter first so
+// that the change commits before the new node is running; the promotion to
+// voter happens in the background and commits once the new node has caught
+// up.
+func (b *RaftBackend) AddPeer(peerID, clusterAddr string) error {
+	b.l.RLock()
+	defer b.l.RUnlock()
+
+	if b.raft == nil {
+		return ErrNotInitialized
+	}
+
+	id := raft.ServerID(peerID)
+	addr := raft.ServerAddress(clusterAddr)
+	if err := b.raft.AddNonvoter(id, addr, 0, 0).Error(); err != nil {
+		return b.wrapRaftErr(err)
+	}
+
+	raftObj := b.raft
+	go func() {
+		if err := raftObj.AddVoter(id, addr, 0, 0).Error(); err != nil {
+			b.logger.Error("raft: failed to promote peer to voter", "node_id", peerID, "error", err)
+			return
+		}
+		b.logger.Info("raft: added peer", "node_id", peerID, "address", clusterAddr)
+	}()
+
+	return nil
+}
+
+// RemovePeer removes a server from the cluster
+func (b *RaftBackend) RemovePeer(peerID string) error {
+	b.l.RLock()
+	defer b.l.RUnlock()
+
+	if b.raft == nil {
+		return ErrNotInitialized
+	}
+
+	return b.wrapRaftErr(b.raft.RemoveServer(raft.ServerID(peerID), 0, 0).Error())
+}
+
+// Peers returns the members of the current raft configuration
+func (b *RaftBackend) Peers() ([]Peer, error) {
+	servers, err := b.GetConfiguration()
+	if err != nil {
+		return nil, err
+	}
+
+	peers := make([]Peer, 0, len(servers))
+	for _, server := range servers {
+		peers = append(peers, Peer{
+			ID:      server.NodeID,
+			Address: server.Address,
+		})
+	}
+	return peers, nil
+}
+
+// GetConfiguration returns the servers in the current raft configuration
+func (b *RaftBackend) GetConfiguration() ([]*RaftServer, error) {
+	b.l.RLock()
+	defer b.l.RUnlock()
+
+	if b.raft == nil {
+		return nil, ErrNotInitialized
+	}
+
+	future := b.raft.GetConfiguration()
+	if err := future.Error(); err != nil {
+		return nil, err
+	}
+
+	leader := b.raft.Leader()
+	var servers []*RaftServer
+	for _, server := range future.Configuration().Servers {
+		servers = append(servers, &RaftServer{
+			NodeID:  string(server.ID),
+			Address: string(server.Address),
+			Leader:  server.Address == leader,
+			Voter:   server.Suffrage == raft.Voter,
+		})
+	}
+
+	return servers, nil
+}
+
+// Snapshot writes a compressed snapshot of the storage to the given writer.
+// The snapshot is checksummed so that corruption is detected on restore.
+func (b *RaftBackend) Snapshot(out io.Writer) error {
+	b.l.RLock()
+	defer b.l.RUnlock()
+
+	if b.raft == nil {
+		return ErrNotInitialized
+	}
+
+	// Configuration changes are not passed to the FSM, so raft refuses to
+	// snapshot until a later entry has been applied. A barrier is one.
+	if b.raft.State() == raft.Leader {
+		if err := b.raft.Barrier(applyTimeout).Error(); err != nil {
+			return err
+		}
+	}
+
+	future := b.raft.Snapshot()
+	var meta *raft.SnapshotMeta
+	var snap io.ReadCloser
+	switch err := future.Error(); err {
+	case nil:
+		var openErr error
+		meta, snap, openErr = future.Open()
+		if openErr != nil {
+			return openErr
+		}
+	case raft.ErrNothingNewToSnapshot:
+		// Nothing changed since the last snapshot so use that instead
+		snapshots, err := b.snapStore.List()
+		if err != nil {
+			return err
+		}
+		if len(snapshots) == 0 {
+			return errors.New("no snapshot available")
+		}
+		meta, snap, err = b.snapStore.Open(snapshots[0].ID)
+		if err != nil {
+			return err
+		}
+	default:
+		return err
+	}
+	defer snap.Close()
+
+	metaBytes, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(out)
+	header := make([]byte, 8)
+	binary.BigEndian.PutUint64(header, uint64(len(metaBytes)))
+	if _, err := gz.Write(header); err != nil {
+		return err
+	}
+	if _, err := gz.Write(metaBytes); err != nil {
+		return err
+	}
+	if _, err := io.Copy(gz, snap); err != nil {
+		return err
+	}
+
+	return gz.Close()
+}
+
+// RestoreSnapshot replaces the cluster's storage with the contents of a
+// snapshot created by Snapshot. It must be run on the leader.
+func (b *RaftBackend) RestoreSnapshot(in io.Reader) error {
+	b.l.RLock()
+	defer b.l.RUnlock()
+
+	if b.raft == nil {
+		return ErrNotInitialized
+	}
+
+	gz, err := gzip.NewReader(in)
+	if err != nil {
+		return errwrap.Wrapf("invalid snapshot: {{err}}", err)
+	}
+	defer gz.Close()
+
+	header := make([]byte, 8)
+	if _, err := io.ReadFull(gz, header); err != nil {
+		return errwrap.Wrapf("invalid snapshot: {{err}}", err)
+	}
+	metaBytes := make([]byte, binary.BigEndian.Uint64(header))
+	if _, err := io.ReadFull(gz, metaBytes); err != nil {
+		return errwrap.Wrapf("invalid snapshot: {{err}}", err)
+	}
+
+	var meta raft.SnapshotMeta
+	if err := jsonutil.DecodeJSON(metaBytes, &meta); err != nil {
+		return errwrap.Wrapf("invalid snapshot metadata: {{err}}", err)
+	}
+
+	// Raft checks the size of the data against the metadata and the gzip
+	// reader fails on a checksum mismatch, so a corrupt snapshot is rejected
+	// before the FSM is touched
+	return b.wrapRaftErr(b.raft.Restore(&meta, bufio.NewReader(gz), 0))
+}
+
+// Get is used to fetch an entry
+func (b *RaftBackend) Get(key string) (*physical.Entry, error) {
+	defer metrics.MeasureSince([]string{"raft", "get"}, time.Now())
+
+	b.permitPool.Acquire()
+	defer b.permitPool.Release()
+
+	return b.fsm.Get(key)
+}
+
+// List is used to list all the keys under a given prefix, up to the next
+// prefix
+func (b *RaftBackend) List(prefix string) ([]string, error) {
+	defer metrics.MeasureSince([]string{"raft", "list"}, time.Now())
+
+	b.permitPool.Acquire()
+	defer b.permitPool.Release()
+
+	return b.fsm.List(prefix)
+}
+
+// Put is used to insert or update an entry
+func (b *RaftBackend) Put(entry *physical.Entry) error {
+	defer metrics.MeasureSince([]string{"raft", "put"}, time.Now())
+
+	return b.applyLog(&LogData{
+		Operations: []*LogOperation{
+			&LogOperation{
+				OpType: putOp,
+				Key:    entry.Key,
+				Value:  entry.Value,
+			},
+		},
+	})
+}
+
+// Delete is used to permanently delete an entry
+func (b *RaftBackend) Delete(key string) error {
+	defer metrics.MeasureSince([]string{"raft", "delete"}, time.Now())
+
+	return b.applyLog(&LogData{
+		Operations: []*LogOperation{
+			&LogOperation{
+				OpType: deleteOp,
+				Key:    key,
+			},
+		},
+	})
+}
+
+// Transaction applies all the given operations in a single raft log, so
+// they are committed atomically
+func (b *RaftBackend) Transaction(txns []*physical.TxnEntry) error {
+	defer metrics.MeasureSince([]string{"raft", "transaction"}, time.Now())
+
+	command := &LogData{
+		Operations: make([]*LogOperation, 0, len(txns)),
+	}
+	for _, txn := range txns {
+		op := &LogOperation{
+			Key: txn.Entry.Key,
+		}
+		switch txn.Operation {
+		case physical.PutOperation:
+			op.OpType = putOp
+			op.Value = txn.Entry.Value
+		case physical.DeleteOperation:
+			op.OpType = deleteOp
+		default:
+			return fmt.Errorf("%q is not a supported transaction operation", txn.Operation)
+		}
+		command.Operations = append(command.Operations, op)
+	}
+
+	return b.applyLog(command)
+}
+
+// applyLog commits the operations through raft and waits for them to be
+// applied to the local FSM
+func (b *RaftBackend) applyLog(command *LogData) error {
+	b.permitPool.Acquire()
+	defer b.permitPool.Release()
+
+	b.l.RLock()
+	defer b.l.RUnlock()
+
+	if b.raft == nil {
+		return ErrNotInitialized
+	}
+
+	cmdBytes, err := json.Marshal(command)
+	if err != nil {
+		return err
+	}
+
+	future := b.raft.Apply(cmdBytes, applyTimeout)
+	if err := future.Error(); err != nil {
+		return b.wrapRaftErr(err)
+	}
+
+	if err, ok := future.Response().(error); ok {
+		return err
+	}
+
+	return nil
+}
+
+func (b *RaftBackend) wrapRaftErr(err error) error {
+	if err == raft.ErrNotLeader {
+		return ErrNotLeader
+	}
+	return err
+}
+
+// LockWith is used for mutual exclusion based on the given key. Only the
+// raft leader can hold a lock.
+func (b *RaftBackend) LockWith(key, value string) (physical.Lock, error) {
+	return &RaftLock{
+		key:   key,
+		value: value,
+		b:     b,
+	}, nil
+}
+
+// HAEnabled indicates whether the HA functionality should be exposed.
+// Currently always returns true.
+func (b *RaftBackend) HAEnabled() bool {
+	return true
+}
+
+// RaftLock is a lock that can only be held by the raft leader. Its value is
+// written to storage so that the other nodes can find the lock holder.
+type RaftLock struct {
+	key   string
+	value string
+	b     *RaftBackend
+
+	l      sync.Mutex
+	held   bool
+	stopCh chan struct{}
+}
+
+// Lock blocks until this node is the raft leader and no other lock on the
+// same key is held locally
+func (l *RaftLock) Lock(stopCh <-chan struct{}) (<-chan struct{}, error) {
+	l.l.Lock()
+	defer l.l.Unlock()
+
+	if l.held {
+		return nil, fmt.Errorf("lock already held")
+	}
+
+	for {
+		l.b.stateLock.Lock()
+		stateCh := l.b.stateCh
+		acquired := l.b.leaderState && !l.b.locksHeld[l.key]
+		if acquired {
+			l.b.locksHeld[l.key] = true
+		}
+		l.b.stateLock.Unlock()
+
+		if acquired {
+			break
+		}
+
+		select {
+		case <-stateCh:
+		case <-stopCh:
+			return nil, nil
+		}
+	}
+
+	// Make sure everything committed by a previous leader has been applied
+	// before advertising ourselves
+	err := l.b.barrier()
+	if err == nil {
+		err = l.b.Put(&physical.Entry{
+			Key:   l.key,
+			Value: []byte(l.value),
+		})
+	}
+	if err != nil {
+		l.b.releaseLock(l.key)
+		return nil, err
+	}
+
+	l.held = true
+	l.stopCh = make(chan struct{})
+	leaderLostCh := make(chan struct{})
+	go l.monitorLeadership(leaderLostCh, l.stopCh)
+
+	return leaderLostCh, nil
+}
+
+// monitorLeadership closes the leader lost channel when this node loses raft
+// leadership or the lock is released
+func (l *RaftLock) monitorLeadership(leaderLostCh chan struct{}, stopCh <-chan struct{}) {
+	defer close(leaderLostCh)
+
+	for {
+		l.b.stateLock.Lock()
+		stateCh := l.b.stateCh
+		isLeader := l.b.leaderState
+		l.b.stateLock.Unlock()
+
+		if !isLeader {
+			return
+		}
+
+		select {
+		case <-stateCh:
+		case <-stopCh:
+			return
+		}
+	}
+}
+
+// Unlock releases the lock, removing its value from storage if this node is
+// still the leader
+func (l *RaftLock) Unlock() error {
+	l.l.Lock()
+	defer l.l.Unlock()
+
+	if !l.held {
+		return nil
+	}
+
+	close(l.stopCh)
+	l.held = false
+
+	var err error
+	if l.b.IsLeader() {
+		err = l.b.Delete(l.key)
+		if err == ErrNotLeader || err == ErrNotInitialized {
+			err = nil
+		}
+	}
+
+	l.b.releaseLock(l.key)
+
+	return err
+}
+
+// Value returns the current lock holder as last written to storage
+func (l *RaftLock) Value() (bool, string, error) {
+	entry, err := l.b.Get(l.key)
+	if err != nil {
+		return false, "", err
+	}
+	if entry == nil {
+		return false, "", nil
+	}
+
+	return true, string(entry.Value), nil
+}
+
+func (b *RaftBackend) releaseLock(key string) {
+	b.stateLock.Lock()
+	delete(b.locksHeld, key)
+	b.notifyStateChangeLocked()
+	b.stateLock.Unlock()
+}
+
+func (b *RaftBackend) barrier() error {
+	b.l.RLock()
+	defer b.l.RUnlock()
+
+	if b.raft == nil {
+		return ErrNotInitialized
+	}
+
+	return b.wrapRaftErr(b.raft.Barrier(0).Error())
+}
+
+// logWriter sends the output of raft's standard library logger to the Vault
+// logger at the level raft tagged the message with
+type logWriter struct {
+	logger log.Logger
+}
+
+func (w *logWriter) Write(p []byte) (int, error) {
+	msg := string(bytes.TrimSpace(p))
+	switch {
+	case strings.HasPrefix(msg, "[ERR]"):
+		w.logger.Error(strings.TrimSpace(strings.TrimPrefix(msg, "[ERR]")))
+	case strings.HasPrefix(msg, "[WARN]"):
+		w.logger.Warn(strings.TrimSpace(strings.TrimPrefix(msg, "[WARN]")))
+	case strings.HasPrefix(msg, "[INFO]"):
+		w.logger.Info(strings.TrimSpace(strings.TrimPrefix(msg, "[INFO]")))
+	case strings.HasPrefix(msg, "[DEBUG]"):
+		w.logger.Debug(strings.TrimSpace(strings.TrimPrefix(msg, "[DEBUG]")))
+	default:
+		w.logger.Trace(msg)
+	}
+	return len(p), nil
+}
@@ -0,0 +1,293 @@
+package raft
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/helper/logformat"
+	"github.com/hashicorp/vault/physical"
+	log "github.com/mgutz/logxi/v1"
+)
+
+type testNode struct {
+	backend  *RaftBackend
+	dir      string
+	listener net.Listener
+}
+
+func (n *testNode) cleanup() {
+	n.backend.TeardownCluster()
+	n.listener.Close()
+	n.backend.fsm.Close()
+	n.backend.boltStore.Close()
+	os.RemoveAll(n.dir)
+}
+
+// serveCluster plays the part of Vault's cluster listener, handing TLS
+// connections off to the raft stream layer
+func serveCluster(ln net.Listener, layer *RaftLayer) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+
+		tlsConfig, err := layer.ServerTLSConfig()
+		if err != nil {
+			conn.Close()
+			continue
+		}
+
+		tlsConn := tls.Server(conn, tlsConfig)
+		if err := tlsConn.Handshake(); err != nil {
+			conn.Close()
+			continue
+		}
+		if err := layer.Handoff(tlsConn); err != nil {
+			conn.Close()
+			return
+		}
+	}
+}
+
+func getRaftNode(t *testing.T, tlsKey *TLSKey, bootstrap bool) *testNode {
+	t.Helper()
+	logger := logformat.NewVaultLogger(log.LevelTrace)
+
+	dir, err := ioutil.TempDir("", "vault-raft")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	raw, err := NewRaftBackend(map[string]string{"path": dir}, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := raw.(*RaftBackend)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	layer, err := NewRaftLayer(logger, tlsKey, ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	go serveCluster(ln, layer)
+
+	if bootstrap {
+		if err := b.Bootstrap([]Peer{{ID: b.NodeID(), Address: ln.Addr().String()}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := b.SetupCluster(SetupOpts{StreamLayer: layer}); err != nil {
+		t.Fatal(err)
+	}
+
+	if bootstrap {
+		if err := b.WaitForLeader(10 * time.Second); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return &testNode{
+		backend:  b,
+		dir:      dir,
+		listener: ln,
+	}
+}
+
+func getTLSKey(t *testing.T) *TLSKey {
+	t.Helper()
+	key, err := GenerateTLSKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func waitForValue(t *testing.T, b *RaftBackend, key, value string) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		entry, err := b.Get(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if entry != nil && string(entry.Value) == value {
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Fatalf("key %q was not replicated to node %s", key, b.NodeID())
+}
+
+func TestRaft_Backend(t *testing.T) {
+	node := getRaftNode(t, getTLSKey(t), true)
+	defer node.cleanup()
+
+	physical.ExerciseBackend(t, node.backend)
+	physical.ExerciseBackend_ListPrefix(t, node.backend)
+}
+
+func TestRaft_TransactionalBackend(t *testing.T) {
+	node := getRaftNode(t, getTLSKey(t), true)
+	defer node.cleanup()
+
+	physical.ExerciseTransactionalBackend(t, node.backend)
+}
+
+func TestRaft_HABackend(t *testing.T) {
+	node := getRaftNode(t, getTLSKey(t), true)
+	defer node.cleanup()
+
+	physical.ExerciseHABackend(t, node.backend, node.backend)
+}
+
+func TestRaft_Recovery(t *testing.T) {
+	node := getRaftNode(t, getTLSKey(t), true)
+	defer node.cleanup()
+
+	if err := node.backend.Put(&physical.Entry{Key: "foo", Value: []byte("bar")}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Data stays readable once raft is stopped, as it is while sealed, but
+	// can no longer be written
+	if err := node.backend.TeardownCluster(); err != nil {
+		t.Fatal(err)
+	}
+	waitForValue(t, node.backend, "foo", "bar")
+	if err := node.backend.Put(&physical.Entry{Key: "foo", Value: []byte("baz")}); err != ErrNotInitialized {
+		t.Fatalf("expected not initialized error, got %v", err)
+	}
+
+	hasState, err := node.backend.HasState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hasState {
+		t.Fatal("expected existing raft state")
+	}
+}
+
+func TestRaft_MultiNode(t *testing.T) {
+	tlsKey := getTLSKey(t)
+
+	leader := getRaftNode(t, tlsKey, true)
+	defer leader.cleanup()
+
+	var followers []*testNode
+	for i := 0; i < 2; i++ {
+		follower := getRaftNode(t, tlsKey, false)
+		defer follower.cleanup()
+
+		if err := leader.backend.AddPeer(follower.backend.NodeID(), follower.listener.Addr().String()); err != nil {
+			t.Fatal(err)
+		}
+		followers = append(followers, follower)
+	}
+
+	for i := 0; i < 10; i++ {
+		if err := leader.backend.Put(&physical.Entry{
+			Key:   fmt.Sprintf("foo/%d", i),
+			Value: []byte("bar"),
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, follower := range followers {
+		waitForValue(t, follower.backend, "foo/9", "bar")
+
+		if err := follower.backend.Put(&physical.Entry{Key: "foo", Value: []byte("bar")}); err != ErrNotLeader {
+			t.Fatalf("expected not leader error, got %v", err)
+		}
+	}
+
+	// Every node should eventually see three voters
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		servers, err := leader.backend.GetConfiguration()
+		if err != nil {
+			t.Fatal(err)
+		}
+		voters := 0
+		for _, server := range servers {
+			if server.Voter {
+				voters++
+			}
+		}
+		if voters == 3 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("bad configuration: %#v", servers)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	// The lock value written by the leader is visible on the followers
+	lock, _ := leader.backend.LockWith("core/lock", "leader")
+	if _, err := lock.Lock(nil); err != nil {
+		t.Fatal(err)
+	}
+	defer lock.Unlock()
+	waitForValue(t, followers[0].backend, "core/lock", "leader")
+
+	if err := leader.backend.RemovePeer(followers[1].backend.NodeID()); err != nil {
+		t.Fatal(err)
+	}
+	peers, err := leader.backend.Peers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(peers) != 2 {
+		t.Fatalf("bad peers: %#v", peers)
+	}
+}
+
+func TestRaft_SnapshotRestore(t *testing.T) {
+	node := getRaftNode(t, getTLSKey(t), true)
+	defer node.cleanup()
+
+	if err := node.backend.Put(&physical.Entry{Key: "foo", Value: []byte("bar")}); err != nil {
+		t.Fatal(err)
+	}
+
+	snap := new(bytes.Buffer)
+	if err := node.backend.Snapshot(snap); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := node.backend.Put(&physical.Entry{Key: "foo", Value: []byte("baz")}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A corrupted snapshot must be refused
+	corrupt := append([]byte{}, snap.Bytes()...)
+	corrupt[len(corrupt)/2] ^= 0xff
+	if err := node.backend.RestoreSnapshot(bytes.NewReader(corrupt)); err == nil {
+		t.Fatal("expected error restoring corrupt snapshot")
+	}
+	waitForValue(t, node.backend, "foo", "baz")
+
+	if err := node.backend.RestoreSnapshot(snap); err != nil {
+		t.Fatal(err)
+	}
+	waitForValue(t, node.backend, "foo", "bar")
+
+	// Writes keep working after the restore
+	if err := node.backend.Put(&physical.Entry{Key: "zip", Value: []byte("zap")}); err != nil {
+		t.Fatal(err)
+	}
+	waitForValue(t, node.backend, "zip", "zap")
+}
@@ -0,0 +1,289 @@
+package raft
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"fmt"
+	"math/big"
+	mathrand "math/rand"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/raft"
+	log "github.com/mgutz/logxi/v1"
+)
+
+// RaftALPN is the ALPN protocol raft connections negotiate on Vault's cluster
+// port
+const RaftALPN = "raft_storage_v1"
+
+var (
+	// ErrLayerClosed is returned when a connection is handed off to a stream
+	// layer that has been shut down
+	ErrLayerClosed = errors.New("raft stream layer is closed")
+
+	_ raft.StreamLayer = (*RaftLayer)(nil)
+)
+
+// TLSKey is the certificate and key shared by all the nodes of a raft
+// cluster. It is used on both sides of the mutually authenticated TLS
+// connections between peers.
+type TLSKey struct {
+	// ID is a unique identifier for the key
+	ID string `json:"id"`
+
+	// CertBytes is the DER encoded self-signed certificate
+	CertBytes []byte `json:"cluster_cert"`
+
+	// KeyBytes is the DER encoded EC private key
+	KeyBytes []byte `json:"cluster_key"`
+}
+
+// GenerateTLSKey creates a new self-signed certificate and key used to secure
+// raft connections
+func GenerateTLSKey() (*TLSKey, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := uuid.GenerateUUID()
+	if err != nil {
+		return nil, err
+	}
+
+	host := fmt.Sprintf("raft-%s", id)
+	template := &x509.Certificate{
+		Subject: pkix.Name{
+			CommonName: host,
+		},
+		DNSNames: []string{host},
+		ExtKeyUsage: []x509.ExtKeyUsage{
+			x509.ExtKeyUsageServerAuth,
+			x509.ExtKeyUsageClientAuth,
+		},
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment | x509.KeyUsageKeyAgreement | x509.KeyUsageCertSign,
+		SerialNumber: big.NewInt(mathrand.Int63()),
+		NotBefore:    time.Now().Add(-30 * time.Second),
+		// The key lives as long as the cluster does
+		NotAfter:              time.Now().Add(262980 * time.Hour),
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	certBytes, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		return nil, errwrap.Wrapf("unable to generate raft certificate: {{err}}", err)
+	}
+
+	keyBytes, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TLSKey{
+		ID:        id,
+		CertBytes: certBytes,
+		KeyBytes:  keyBytes,
+	}, nil
+}
+
+// parsedTLSKey holds the parsed form of a TLSKey
+type parsedTLSKey struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pool *x509.CertPool
+}
+
+func (k *TLSKey) parse() (*parsedTLSKey, error) {
+	cert, err := x509.ParseCertificate(k.CertBytes)
+	if err != nil {
+		return nil, errwrap.Wrapf("error parsing raft certificate: {{err}}", err)
+	}
+
+	key, err := x509.ParseECPrivateKey(k.KeyBytes)
+	if err != nil {
+		return nil, errwrap.Wrapf("error parsing raft key: {{err}}", err)
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+
+	return &parsedTLSKey{
+		cert: cert,
+		key:  key,
+		pool: pool,
+	}, nil
+}
+
+func (p *parsedTLSKey) certificate() tls.Certificate {
+	return tls.Certificate{
+		Certificate: [][]byte{p.cert.Raw},
+		PrivateKey:  p.key,
+		Leaf:        p.cert,
+	}
+}
+
+// raftAddr is the advertised address of the local node. It is kept as given
+// rather than resolved so that host names survive in the raft configuration.
+type raftAddr string
+
+func (a raftAddr) Network() string { return "tcp" }
+func (a raftAddr) String() string  { return string(a) }
+
+// RaftLayer implements raft.StreamLayer on top of Vault's cluster port.
+// Rather than listening itself it is handed the connections that negotiated
+// RaftALPN by the cluster listener.
+type RaftLayer struct {
+	addr   net.Addr
+	logger log.Logger
+
+	connCh    chan net.Conn
+	closeCh   chan struct{}
+	closeLock sync.Mutex
+	closed    bool
+
+	keyLock sync.RWMutex
+	tlsKey  *parsedTLSKey
+}
+
+// NewRaftLayer returns a stream layer advertising the given cluster address,
+// in host:port form. The TLS key may be nil and set later, for instance when a
+// node bootstraps a cluster before the key can be created.
+func NewRaftLayer(logger log.Logger, tlsKey *TLSKey, clusterAddr string) (*RaftLayer, error) {
+	if clusterAddr == "" {
+		return nil, errors.New("cluster address is required for raft storage")
+	}
+
+	layer := &RaftLayer{
+		addr:    raftAddr(clusterAddr),
+		logger:  logger,
+		connCh:  make(chan net.Conn),
+		closeCh: make(chan struct{}),
+	}
+
+	if tlsKey != nil {
+		if err := layer.SetTLSKey(tlsKey); err != nil {
+			return nil, err
+		}
+	}
+
+	return layer, nil
+}
+
+// SetTLSKey sets the key used to secure connections to and from peers
+func (l *RaftLayer) SetTLSKey(tlsKey *TLSKey) error {
+	parsed, err := tlsKey.parse()
+	if err != nil {
+		return err
+	}
+
+	l.keyLock.Lock()
+	l.tlsKey = parsed
+	l.keyLock.Unlock()
+
+	return nil
+}
+
+func (l *RaftLayer) parsedKey() (*parsedTLSKey, error) {
+	l.keyLock.RLock()
+	defer l.keyLock.RUnlock()
+
+	if l.tlsKey == nil {
+		return nil, errors.New("no raft tls key available")
+	}
+	return l.tlsKey, nil
+}
+
+// ServerTLSConfig returns the TLS configuration the cluster listener uses for
+// connections offering RaftALPN
+func (l *RaftLayer) ServerTLSConfig() (*tls.Config, error) {
+	key, err := l.parsedKey()
+	if err != nil {
+		return nil, err
+	}
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{key.certificate()},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    key.pool,
+		NextProtos:   []string{RaftALPN},
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
+
+// Handoff passes a connection accepted by the cluster listener to raft
+func (l *RaftLayer) Handoff(conn net.Conn) error {
+	l.closeLock.Lock()
+	closed := l.closed
+	l.closeLock.Unlock()
+
+	if closed {
+		return ErrLayerClosed
+	}
+
+	select {
+	case l.connCh <- conn:
+	case <-l.closeCh:
+		return ErrLayerClosed
+	}
+
+	return nil
+}
+
+// Accept is used to return a connection handed off by the cluster listener
+func (l *RaftLayer) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.connCh:
+		return conn, nil
+	case <-l.closeCh:
+		return nil, ErrLayerClosed
+	}
+}
+
+// Close is used to stop accepting connections
+func (l *RaftLayer) Close() error {
+	l.closeLock.Lock()
+	defer l.closeLock.Unlock()
+
+	if !l.closed {
+		l.closed = true
+		close(l.closeCh)
+	}
+	return nil
+}
+
+// Addr returns the advertised address of the local node
+func (l *RaftLayer) Addr() net.Addr {
+	return l.addr
+}
+
+// Dial opens a mutually authenticated TLS connection to the cluster port of a
+// peer
+func (l *RaftLayer) Dial(address raft.ServerAddress, timeout time.Duration) (net.Conn, error) {
+	key, err := l.parsedKey()
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{key.certificate()},
+		RootCAs:      key.pool,
+		ServerName:   key.cert.Subject.CommonName,
+		NextProtos:   []string{RaftALPN},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	dialer := &net.Dialer{
+		Timeout: timeout,
+	}
+	return tls.DialWithDialer(dialer, "tcp", string(address), tlsConfig)
+}
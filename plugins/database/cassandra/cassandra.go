@@ -13,11 +13,14 @@ import (
 	"github.com/hashicorp/vault/plugins/helper/database/connutil"
 	"github.com/hashicorp/vault/plugins/helper/database/credsutil"
 	"github.com/hashicorp/vault/plugins/helper/database/dbutil"
+	"golang.org/x/net/context"
 )
 
 const (
 	defaultUserCreationCQL = `CREATE USER '{{username}}' WITH PASSWORD '{{password}}' NOSUPERUSER;`
 	defaultUserDeletionCQL = `DROP USER '{{username}}';`
+	defaultUserRotationCQL = `ALTER USER '{{username}}' WITH PASSWORD '{{password}}';`
+	defaultRootRotationCQL = `ALTER USER '{{username}}' WITH PASSWORD '{{password}}';`
 	cassandraTypeName      = "cassandra"
 )
 
@@ -64,8 +67,8 @@ func (c *Cassandra) Type() (string, error) {
 	return cassandraTypeName, nil
 }
 
-func (c *Cassandra) getConnection() (*gocql.Session, error) {
-	session, err := c.Connection()
+func (c *Cassandra) getConnection(ctx context.Context) (*gocql.Session, error) {
+	session, err := c.Connection(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -75,13 +78,13 @@ func (c *Cassandra) getConnection() (*gocql.Session, error) {
 
 // CreateUser generates the username/password on the underlying Cassandra secret backend as instructed by
 // the CreationStatement provided.
-func (c *Cassandra) CreateUser(statements dbplugin.Statements, usernameConfig dbplugin.UsernameConfig, expiration time.Time) (username string, password string, err error) {
+func (c *Cassandra) CreateUser(ctx context.Context, statements dbplugin.Statements, usernameConfig dbplugin.UsernameConfig, expiration time.Time) (username string, password string, err error) {
 	// Grab the lock
 	c.Lock()
 	defer c.Unlock()
 
 	// Get the connection
-	session, err := c.getConnection()
+	session, err := c.getConnection(ctx)
 	if err != nil {
 		return "", "", err
 	}
@@ -118,7 +121,7 @@ func (c *Cassandra) CreateUser(statements dbplugin.Statements, usernameConfig db
 		err = session.Query(dbutil.QueryHelper(query, map[string]string{
 			"username": username,
 			"password": password,
-		})).Exec()
+		})).WithContext(ctx).Exec()
 		if err != nil {
 			for _, query := range strutil.ParseArbitraryStringSlice(rollbackCQL, ";") {
 				query = strings.TrimSpace(query)
@@ -138,18 +141,18 @@ func (c *Cassandra) CreateUser(statements dbplugin.Statements, usernameConfig db
 }
 
 // RenewUser is not supported on Cassandra, so this is a no-op.
-func (c *Cassandra) RenewUser(statements dbplugin.Statements, username string, expiration time.Time) error {
+func (c *Cassandra) RenewUser(ctx context.Context, statements dbplugin.Statements, username string, expiration time.Time) error {
 	// NOOP
 	return nil
 }
 
 // RevokeUser attempts to drop the specified user.
-func (c *Cassandra) RevokeUser(statements dbplugin.Statements, username string) error {
+func (c *Cassandra) RevokeUser(ctx context.Context, statements dbplugin.Statements, username string) error {
 	// Grab the lock
 	c.Lock()
 	defer c.Unlock()
 
-	session, err := c.getConnection()
+	session, err := c.getConnection(ctx)
 	if err != nil {
 		return err
 	}
@@ -168,10 +171,93 @@ func (c *Cassandra) RevokeUser(statements dbplugin.Statements, username string)
 
 		err := session.Query(dbutil.QueryHelper(query, map[string]string{
 			"username": username,
-		})).Exec()
+		})).WithContext(ctx).Exec()
 
 		result = multierror.Append(result, err)
 	}
 
 	return result.ErrorOrNil()
 }
+
+// SetCredentials sets the password of the existing user given in staticUser
+// using the RotationStatements provided.
+func (c *Cassandra) SetCredentials(ctx context.Context, statements dbplugin.Statements, staticUser dbplugin.StaticUserConfig) (username string, password string, err error) {
+	if staticUser.Username == "" || staticUser.Password == "" {
+		return "", "", dbutil.ErrEmptyStaticUser
+	}
+
+	// Grab the lock
+	c.Lock()
+	defer c.Unlock()
+
+	session, err := c.getConnection(ctx)
+	if err != nil {
+		return "", "", err
+	}
+
+	rotationCQL := statements.RotationStatements
+	if rotationCQL == "" {
+		rotationCQL = defaultUserRotationCQL
+	}
+
+	for _, query := range strutil.ParseArbitraryStringSlice(rotationCQL, ";") {
+		query = strings.TrimSpace(query)
+		if len(query) == 0 {
+			continue
+		}
+
+		err := session.Query(dbutil.QueryHelper(query, map[string]string{
+			"username": staticUser.Username,
+			"password": staticUser.Password,
+		})).WithContext(ctx).Exec()
+		if err != nil {
+			return "", "", err
+		}
+	}
+
+	return staticUser.Username, staticUser.Password, nil
+}
+
+// RotateRootCredentials changes the password of the user the plugin connects
+// as and returns the updated connection configuration.
+func (c *Cassandra) RotateRootCredentials(ctx context.Context, statements []string) (map[string]interface{}, error) {
+	// Grab the lock
+	c.Lock()
+	defer c.Unlock()
+
+	connProducer := c.ConnectionProducer.(*cassandraConnectionProducer)
+
+	session, err := c.getConnection(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rotateStatements := statements
+	if len(rotateStatements) == 0 {
+		rotateStatements = []string{defaultRootRotationCQL}
+	}
+
+	password, err := c.GeneratePassword()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, rotateStmt := range rotateStatements {
+		for _, query := range strutil.ParseArbitraryStringSlice(rotateStmt, ";") {
+			query = strings.TrimSpace(query)
+			if len(query) == 0 {
+				continue
+			}
+
+			err := session.Query(dbutil.QueryHelper(query, map[string]string{
+				"username": connProducer.Username,
+				"password": password,
+			})).WithContext(ctx).Exec()
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return connProducer.setRootPassword(password), nil
+}
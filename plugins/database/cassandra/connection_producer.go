@@ -14,6 +14,7 @@ import (
 	"github.com/hashicorp/vault/helper/parseutil"
 	"github.com/hashicorp/vault/helper/tlsutil"
 	"github.com/hashicorp/vault/plugins/helper/database/connutil"
+	"golang.org/x/net/context"
 )
 
 // cassandraConnectionProducer implements ConnectionProducer and provides an
@@ -39,14 +40,17 @@ type cassandraConnectionProducer struct {
 
 	Initialized bool
 	Type        string
+	rawConfig   map[string]interface{}
 	session     *gocql.Session
 	sync.Mutex
 }
 
-func (c *cassandraConnectionProducer) Initialize(conf map[string]interface{}, verifyConnection bool) error {
+func (c *cassandraConnectionProducer) Initialize(ctx context.Context, conf map[string]interface{}, verifyConnection bool) error {
 	c.Lock()
 	defer c.Unlock()
 
+	c.rawConfig = conf
+
 	err := mapstructure.WeakDecode(conf, c)
 	if err != nil {
 		return err
@@ -106,7 +110,7 @@ func (c *cassandraConnectionProducer) Initialize(conf map[string]interface{}, ve
 	c.Initialized = true
 
 	if verifyConnection {
-		if _, err := c.Connection(); err != nil {
+		if _, err := c.Connection(ctx); err != nil {
 			return fmt.Errorf("error verifying connection: %s", err)
 		}
 	}
@@ -114,7 +118,7 @@ func (c *cassandraConnectionProducer) Initialize(conf map[string]interface{}, ve
 	return nil
 }
 
-func (c *cassandraConnectionProducer) Connection() (interface{}, error) {
+func (c *cassandraConnectionProducer) Connection(ctx context.Context) (interface{}, error) {
 	if !c.Initialized {
 		return nil, connutil.ErrNotInitialized
 	}
@@ -149,6 +153,21 @@ func (c *cassandraConnectionProducer) Close() error {
 	return nil
 }
 
+// setRootPassword replaces the password of the connection after it has been
+// changed on the database, and returns the configuration to store for the
+// connection. The caller needs to hold the lock.
+func (c *cassandraConnectionProducer) setRootPassword(password string) map[string]interface{} {
+	c.Password = password
+
+	if c.session != nil {
+		c.session.Close()
+	}
+	c.session = nil
+
+	c.rawConfig = connutil.UpdatedConfig(c.rawConfig, "password", password)
+	return c.rawConfig
+}
+
 func (c *cassandraConnectionProducer) createSession() (*gocql.Session, error) {
 	hosts := strings.Split(c.Hosts, ",")
 	clusterConfig := gocql.NewCluster(hosts...)
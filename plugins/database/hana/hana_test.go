@@ -10,6 +10,7 @@ import (
 
 	"github.com/hashicorp/vault/builtin/logical/database/dbplugin"
 	"github.com/hashicorp/vault/plugins/helper/database/connutil"
+	"golang.org/x/net/context"
 )
 
 func TestHANA_Initialize(t *testing.T) {
@@ -25,7 +26,7 @@ func TestHANA_Initialize(t *testing.T) {
 	dbRaw, _ := New()
 	db := dbRaw.(*HANA)
 
-	err := db.Initialize(connectionDetails, true)
+	err := db.Initialize(context.Background(), connectionDetails, true)
 	if err != nil {
 		t.Fatalf("err: %s", err)
 	}
@@ -55,7 +56,7 @@ func TestHANA_CreateUser(t *testing.T) {
 	dbRaw, _ := New()
 	db := dbRaw.(*HANA)
 
-	err := db.Initialize(connectionDetails, true)
+	err := db.Initialize(context.Background(), connectionDetails, true)
 	if err != nil {
 		t.Fatalf("err: %s", err)
 	}
@@ -66,7 +67,7 @@ func TestHANA_CreateUser(t *testing.T) {
 	}
 
 	// Test with no configured Creation Statememt
-	_, _, err = db.CreateUser(dbplugin.Statements{}, usernameConfig, time.Now().Add(time.Hour))
+	_, _, err = db.CreateUser(context.Background(), dbplugin.Statements{}, usernameConfig, time.Now().Add(time.Hour))
 	if err == nil {
 		t.Fatal("Expected error when no creation statement is provided")
 	}
@@ -75,7 +76,7 @@ func TestHANA_CreateUser(t *testing.T) {
 		CreationStatements: testHANARole,
 	}
 
-	username, password, err := db.CreateUser(statements, usernameConfig, time.Now().Add(time.Hour))
+	username, password, err := db.CreateUser(context.Background(), statements, usernameConfig, time.Now().Add(time.Hour))
 	if err != nil {
 		t.Fatalf("err: %s", err)
 	}
@@ -98,7 +99,7 @@ func TestHANA_RevokeUser(t *testing.T) {
 	dbRaw, _ := New()
 	db := dbRaw.(*HANA)
 
-	err := db.Initialize(connectionDetails, true)
+	err := db.Initialize(context.Background(), connectionDetails, true)
 	if err != nil {
 		t.Fatalf("err: %s", err)
 	}
@@ -113,7 +114,7 @@ func TestHANA_RevokeUser(t *testing.T) {
 	}
 
 	// Test default revoke statememts
-	username, password, err := db.CreateUser(statements, usernameConfig, time.Now().Add(time.Hour))
+	username, password, err := db.CreateUser(context.Background(), statements, usernameConfig, time.Now().Add(time.Hour))
 	if err != nil {
 		t.Fatalf("err: %s", err)
 	}
@@ -121,7 +122,7 @@ func TestHANA_RevokeUser(t *testing.T) {
 		t.Fatalf("Could not connect with new credentials: %s", err)
 	}
 
-	err = db.RevokeUser(statements, username)
+	err = db.RevokeUser(context.Background(), statements, username)
 	if err != nil {
 		t.Fatalf("err: %s", err)
 	}
@@ -130,7 +131,7 @@ func TestHANA_RevokeUser(t *testing.T) {
 	}
 
 	// Test custom revoke statememt
-	username, password, err = db.CreateUser(statements, usernameConfig, time.Now().Add(time.Hour))
+	username, password, err = db.CreateUser(context.Background(), statements, usernameConfig, time.Now().Add(time.Hour))
 	if err != nil {
 		t.Fatalf("err: %s", err)
 	}
@@ -139,7 +140,7 @@ func TestHANA_RevokeUser(t *testing.T) {
 	}
 
 	statements.RevocationStatements = testHANADrop
-	err = db.RevokeUser(statements, username)
+	err = db.RevokeUser(context.Background(), statements, username)
 	if err != nil {
 		t.Fatalf("err: %s", err)
 	}
@@ -12,8 +12,10 @@ import (
 	"time"
 
 	"github.com/hashicorp/vault/plugins/helper/database/connutil"
+	"github.com/hashicorp/vault/plugins/helper/database/dbutil"
 	"github.com/mitchellh/mapstructure"
 
+	"golang.org/x/net/context"
 	"gopkg.in/mgo.v2"
 )
 
@@ -21,18 +23,23 @@ import (
 // interface for databases to make connections.
 type mongoDBConnectionProducer struct {
 	ConnectionURL string `json:"connection_url" structs:"connection_url" mapstructure:"connection_url"`
+	Username      string `json:"username" structs:"username" mapstructure:"username"`
+	Password      string `json:"password" structs:"password" mapstructure:"password"`
 
 	Initialized bool
 	Type        string
+	rawConfig   map[string]interface{}
 	session     *mgo.Session
 	sync.Mutex
 }
 
 // Initialize parses connection configuration.
-func (c *mongoDBConnectionProducer) Initialize(conf map[string]interface{}, verifyConnection bool) error {
+func (c *mongoDBConnectionProducer) Initialize(ctx context.Context, conf map[string]interface{}, verifyConnection bool) error {
 	c.Lock()
 	defer c.Unlock()
 
+	c.rawConfig = conf
+
 	err := mapstructure.WeakDecode(conf, c)
 	if err != nil {
 		return err
@@ -47,7 +54,7 @@ func (c *mongoDBConnectionProducer) Initialize(conf map[string]interface{}, veri
 	c.Initialized = true
 
 	if verifyConnection {
-		if _, err := c.Connection(); err != nil {
+		if _, err := c.Connection(ctx); err != nil {
 			return fmt.Errorf("error verifying connection: %s", err)
 		}
 
@@ -60,7 +67,7 @@ func (c *mongoDBConnectionProducer) Initialize(conf map[string]interface{}, veri
 }
 
 // Connection creates a database connection.
-func (c *mongoDBConnectionProducer) Connection() (interface{}, error) {
+func (c *mongoDBConnectionProducer) Connection(ctx context.Context) (interface{}, error) {
 	if !c.Initialized {
 		return nil, connutil.ErrNotInitialized
 	}
@@ -69,7 +76,7 @@ func (c *mongoDBConnectionProducer) Connection() (interface{}, error) {
 		return c.session, nil
 	}
 
-	dialInfo, err := parseMongoURL(c.ConnectionURL)
+	dialInfo, err := parseMongoURL(c.connectionURL())
 	if err != nil {
 		return nil, err
 	}
@@ -81,7 +88,7 @@ func (c *mongoDBConnectionProducer) Connection() (interface{}, error) {
 	c.session.SetSyncTimeout(1 * time.Minute)
 	c.session.SetSocketTimeout(1 * time.Minute)
 
-	return nil, nil
+	return c.session, nil
 }
 
 // Close terminates the database connection.
@@ -98,6 +105,30 @@ func (c *mongoDBConnectionProducer) Close() error {
 	return nil
 }
 
+// connectionURL returns the connection URL with the username and password
+// templated in
+func (c *mongoDBConnectionProducer) connectionURL() string {
+	return dbutil.QueryHelper(c.ConnectionURL, map[string]string{
+		"username": c.Username,
+		"password": c.Password,
+	})
+}
+
+// setRootPassword replaces the password of the connection after it has been
+// changed on the database, and returns the configuration to store for the
+// connection. The caller needs to hold the lock.
+func (c *mongoDBConnectionProducer) setRootPassword(password string) map[string]interface{} {
+	c.Password = password
+
+	if c.session != nil {
+		c.session.Close()
+	}
+	c.session = nil
+
+	c.rawConfig = connutil.UpdatedConfig(c.rawConfig, "password", password)
+	return c.rawConfig
+}
+
 func parseMongoURL(rawURL string) (*mgo.DialInfo, error) {
 	url, err := url.Parse(rawURL)
 	if err != nil {
@@ -16,6 +16,7 @@ import (
 	"github.com/hashicorp/vault/plugins/helper/database/connutil"
 	"github.com/hashicorp/vault/plugins/helper/database/credsutil"
 	"github.com/hashicorp/vault/plugins/helper/database/dbutil"
+	"golang.org/x/net/context"
 	"gopkg.in/mgo.v2"
 )
 
@@ -63,8 +64,8 @@ func (m *MongoDB) Type() (string, error) {
 	return mongoDBTypeName, nil
 }
 
-func (m *MongoDB) getConnection() (*mgo.Session, error) {
-	session, err := m.Connection()
+func (m *MongoDB) getConnection(ctx context.Context) (*mgo.Session, error) {
+	session, err := m.Connection(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -80,7 +81,7 @@ func (m *MongoDB) getConnection() (*mgo.Session, error) {
 //
 // JSON Example:
 //  { "db": "admin", "roles": [{ "role": "readWrite" }, {"role": "read", "db": "foo"}] }
-func (m *MongoDB) CreateUser(statements dbplugin.Statements, usernameConfig dbplugin.UsernameConfig, expiration time.Time) (username string, password string, err error) {
+func (m *MongoDB) CreateUser(ctx context.Context, statements dbplugin.Statements, usernameConfig dbplugin.UsernameConfig, expiration time.Time) (username string, password string, err error) {
 	// Grab the lock
 	m.Lock()
 	defer m.Unlock()
@@ -89,7 +90,7 @@ func (m *MongoDB) CreateUser(statements dbplugin.Statements, usernameConfig dbpl
 		return "", "", dbutil.ErrEmptyCreationStatement
 	}
 
-	session, err := m.getConnection()
+	session, err := m.getConnection(ctx)
 	if err != nil {
 		return "", "", err
 	}
@@ -133,7 +134,7 @@ func (m *MongoDB) CreateUser(statements dbplugin.Statements, usernameConfig dbpl
 		if err := m.ConnectionProducer.Close(); err != nil {
 			return "", "", errwrap.Wrapf("error closing EOF'd mongo connection: {{err}}", err)
 		}
-		session, err := m.getConnection()
+		session, err := m.getConnection(ctx)
 		if err != nil {
 			return "", "", err
 		}
@@ -149,15 +150,15 @@ func (m *MongoDB) CreateUser(statements dbplugin.Statements, usernameConfig dbpl
 }
 
 // RenewUser is not supported on MongoDB, so this is a no-op.
-func (m *MongoDB) RenewUser(statements dbplugin.Statements, username string, expiration time.Time) error {
+func (m *MongoDB) RenewUser(ctx context.Context, statements dbplugin.Statements, username string, expiration time.Time) error {
 	// NOOP
 	return nil
 }
 
 // RevokeUser drops the specified user from the authentication databse. If none is provided
 // in the revocation statement, the default "admin" authentication database will be assumed.
-func (m *MongoDB) RevokeUser(statements dbplugin.Statements, username string) error {
-	session, err := m.getConnection()
+func (m *MongoDB) RevokeUser(ctx context.Context, statements dbplugin.Statements, username string) error {
+	session, err := m.getConnection(ctx)
 	if err != nil {
 		return err
 	}
@@ -188,7 +189,7 @@ func (m *MongoDB) RevokeUser(statements dbplugin.Statements, username string) er
 		if err := m.ConnectionProducer.Close(); err != nil {
 			return errwrap.Wrapf("error closing EOF'd mongo connection: {{err}}", err)
 		}
-		session, err := m.getConnection()
+		session, err := m.getConnection(ctx)
 		if err != nil {
 			return err
 		}
@@ -202,3 +203,128 @@ func (m *MongoDB) RevokeUser(statements dbplugin.Statements, username string) er
 
 	return nil
 }
+
+// SetCredentials sets the password of the existing user given in staticUser.
+// The rotation statement is a JSON blob that may contain the authentication
+// database of the user as its db value. If none is provided, the default
+// "admin" authentication database will be assumed.
+//
+// JSON Example:
+//  { "db": "admin" }
+func (m *MongoDB) SetCredentials(ctx context.Context, statements dbplugin.Statements, staticUser dbplugin.StaticUserConfig) (username string, password string, err error) {
+	if staticUser.Username == "" || staticUser.Password == "" {
+		return "", "", dbutil.ErrEmptyStaticUser
+	}
+
+	// Grab the lock
+	m.Lock()
+	defer m.Unlock()
+
+	session, err := m.getConnection(ctx)
+	if err != nil {
+		return "", "", err
+	}
+
+	// If no rotation statements provided, pass in empty JSON
+	rotationStatement := statements.RotationStatements
+	if rotationStatement == "" {
+		rotationStatement = `{}`
+	}
+
+	var mongoCS mongoDBStatement
+	err = json.Unmarshal([]byte(rotationStatement), &mongoCS)
+	if err != nil {
+		return "", "", err
+	}
+
+	db := mongoCS.DB
+	// If db is not specified, use the default authenticationDatabase "admin"
+	if db == "" {
+		db = "admin"
+	}
+
+	updateUserCmd := updateUserCommand{
+		Username: staticUser.Username,
+		Password: staticUser.Password,
+	}
+
+	err = session.DB(db).Run(updateUserCmd, nil)
+	switch {
+	case err == nil:
+	case err == io.EOF, strings.Contains(err.Error(), "EOF"):
+		if err := m.ConnectionProducer.Close(); err != nil {
+			return "", "", errwrap.Wrapf("error closing EOF'd mongo connection: {{err}}", err)
+		}
+		session, err := m.getConnection(ctx)
+		if err != nil {
+			return "", "", err
+		}
+		err = session.DB(db).Run(updateUserCmd, nil)
+		if err != nil {
+			return "", "", err
+		}
+	default:
+		return "", "", err
+	}
+
+	return staticUser.Username, staticUser.Password, nil
+}
+
+// RotateRootCredentials changes the password of the user the plugin connects
+// as and returns the updated connection configuration. The first rotation
+// statement, if any, is a JSON blob that may contain the authentication
+// database of the user as its db value. If none is provided, the
+// authentication database of the connection URL is used.
+func (m *MongoDB) RotateRootCredentials(ctx context.Context, statements []string) (map[string]interface{}, error) {
+	// Grab the lock
+	m.Lock()
+	defer m.Unlock()
+
+	connProducer := m.ConnectionProducer.(*mongoDBConnectionProducer)
+	if err := connutil.CanRotateRootCredentials(connProducer.ConnectionURL, connProducer.Username, connProducer.Password); err != nil {
+		return nil, err
+	}
+
+	var mongoCS mongoDBStatement
+	if len(statements) > 0 {
+		if err := json.Unmarshal([]byte(statements[0]), &mongoCS); err != nil {
+			return nil, err
+		}
+	}
+
+	db := mongoCS.DB
+	if db == "" {
+		dialInfo, err := parseMongoURL(connProducer.connectionURL())
+		if err != nil {
+			return nil, err
+		}
+		db = dialInfo.Source
+		if db == "" {
+			db = dialInfo.Database
+		}
+	}
+	// If db is not specified, use the default authenticationDatabase "admin"
+	if db == "" {
+		db = "admin"
+	}
+
+	session, err := m.getConnection(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	password, err := m.GeneratePassword()
+	if err != nil {
+		return nil, err
+	}
+
+	err = session.DB(db).Run(updateUserCommand{
+		Username: connProducer.Username,
+		Password: password,
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return connProducer.setRootPassword(password), nil
+}
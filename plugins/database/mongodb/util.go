@@ -5,6 +5,10 @@ type createUserCommand struct {
 	Password string        `bson:"pwd"`
 	Roles    []interface{} `bson:"roles"`
 }
+type updateUserCommand struct {
+	Username string `bson:"updateUser"`
+	Password string `bson:"pwd"`
+}
 type mongodbRole struct {
 	Role string `json:"role" bson:"role"`
 	DB   string `json:"db"   bson:"db"`
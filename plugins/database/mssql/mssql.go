@@ -14,9 +14,14 @@ import (
 	"github.com/hashicorp/vault/plugins/helper/database/connutil"
 	"github.com/hashicorp/vault/plugins/helper/database/credsutil"
 	"github.com/hashicorp/vault/plugins/helper/database/dbutil"
+	"golang.org/x/net/context"
 )
 
-const msSQLTypeName = "mssql"
+const (
+	msSQLTypeName                        = "mssql"
+	defaultMSSQLRotationStmt             = `ALTER LOGIN [{{name}}] WITH PASSWORD = '{{password}}';`
+	defaultMSSQLRotateRootCredentialsSQL = `ALTER LOGIN [{{username}}] WITH PASSWORD = '{{password}}';`
+)
 
 // MSSQL is an implementation of Database interface
 type MSSQL struct {
@@ -60,8 +65,8 @@ func (m *MSSQL) Type() (string, error) {
 	return msSQLTypeName, nil
 }
 
-func (m *MSSQL) getConnection() (*sql.DB, error) {
-	db, err := m.Connection()
+func (m *MSSQL) getConnection(ctx context.Context) (*sql.DB, error) {
+	db, err := m.Connection(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -71,13 +76,13 @@ func (m *MSSQL) getConnection() (*sql.DB, error) {
 
 // CreateUser generates the username/password on the underlying MSSQL secret backend as instructed by
 // the CreationStatement provided.
-func (m *MSSQL) CreateUser(statements dbplugin.Statements, usernameConfig dbplugin.UsernameConfig, expiration time.Time) (username string, password string, err error) {
+func (m *MSSQL) CreateUser(ctx context.Context, statements dbplugin.Statements, usernameConfig dbplugin.UsernameConfig, expiration time.Time) (username string, password string, err error) {
 	// Grab the lock
 	m.Lock()
 	defer m.Unlock()
 
 	// Get the connection
-	db, err := m.getConnection()
+	db, err := m.getConnection(ctx)
 	if err != nil {
 		return "", "", err
 	}
@@ -102,7 +107,7 @@ func (m *MSSQL) CreateUser(statements dbplugin.Statements, usernameConfig dbplug
 	}
 
 	// Start a transaction
-	tx, err := db.Begin()
+	tx, err := db.BeginTx(ctx, nil)
 	if err != nil {
 		return "", "", err
 	}
@@ -115,7 +120,7 @@ func (m *MSSQL) CreateUser(statements dbplugin.Statements, usernameConfig dbplug
 			continue
 		}
 
-		stmt, err := tx.Prepare(dbutil.QueryHelper(query, map[string]string{
+		stmt, err := tx.PrepareContext(ctx, dbutil.QueryHelper(query, map[string]string{
 			"name":       username,
 			"password":   password,
 			"expiration": expirationStr,
@@ -124,7 +129,7 @@ func (m *MSSQL) CreateUser(statements dbplugin.Statements, usernameConfig dbplug
 			return "", "", err
 		}
 		defer stmt.Close()
-		if _, err := stmt.Exec(); err != nil {
+		if _, err := stmt.ExecContext(ctx); err != nil {
 			return "", "", err
 		}
 	}
@@ -138,27 +143,85 @@ func (m *MSSQL) CreateUser(statements dbplugin.Statements, usernameConfig dbplug
 }
 
 // RenewUser is not supported on MSSQL, so this is a no-op.
-func (m *MSSQL) RenewUser(statements dbplugin.Statements, username string, expiration time.Time) error {
+func (m *MSSQL) RenewUser(ctx context.Context, statements dbplugin.Statements, username string, expiration time.Time) error {
 	// NOOP
 	return nil
 }
 
+// SetCredentials sets the password of the existing user given in staticUser
+// using the RotationStatements provided. By default the password of the login
+// is altered.
+func (m *MSSQL) SetCredentials(ctx context.Context, statements dbplugin.Statements, staticUser dbplugin.StaticUserConfig) (username string, password string, err error) {
+	if staticUser.Username == "" || staticUser.Password == "" {
+		return "", "", dbutil.ErrEmptyStaticUser
+	}
+
+	rotationStmts := statements.RotationStatements
+	if rotationStmts == "" {
+		rotationStmts = defaultMSSQLRotationStmt
+	}
+
+	// Grab the lock
+	m.Lock()
+	defer m.Unlock()
+
+	// Get the connection
+	db, err := m.getConnection(ctx)
+	if err != nil {
+		return "", "", err
+	}
+
+	// Start a transaction
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", "", err
+	}
+	defer tx.Rollback()
+
+	// Execute each query
+	for _, query := range strutil.ParseArbitraryStringSlice(rotationStmts, ";") {
+		query = strings.TrimSpace(query)
+		if len(query) == 0 {
+			continue
+		}
+
+		stmt, err := tx.PrepareContext(ctx, dbutil.QueryHelper(query, map[string]string{
+			"name":     staticUser.Username,
+			"password": staticUser.Password,
+		}))
+		if err != nil {
+			return "", "", err
+		}
+		defer stmt.Close()
+		if _, err := stmt.ExecContext(ctx); err != nil {
+			return "", "", err
+		}
+	}
+
+	// Commit the transaction
+	if err := tx.Commit(); err != nil {
+		return "", "", err
+	}
+
+	return staticUser.Username, staticUser.Password, nil
+}
+
 // RevokeUser attempts to drop the specified user. It will first attempt to disable login,
 // then kill pending connections from that user, and finally drop the user and login from the
 // database instance.
-func (m *MSSQL) RevokeUser(statements dbplugin.Statements, username string) error {
+func (m *MSSQL) RevokeUser(ctx context.Context, statements dbplugin.Statements, username string) error {
 	if statements.RevocationStatements == "" {
-		return m.revokeUserDefault(username)
+		return m.revokeUserDefault(ctx, username)
 	}
 
 	// Get connection
-	db, err := m.getConnection()
+	db, err := m.getConnection(ctx)
 	if err != nil {
 		return err
 	}
 
 	// Start a transaction
-	tx, err := db.Begin()
+	tx, err := db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
@@ -171,14 +234,14 @@ func (m *MSSQL) RevokeUser(statements dbplugin.Statements, username string) erro
 			continue
 		}
 
-		stmt, err := tx.Prepare(dbutil.QueryHelper(query, map[string]string{
+		stmt, err := tx.PrepareContext(ctx, dbutil.QueryHelper(query, map[string]string{
 			"name": username,
 		}))
 		if err != nil {
 			return err
 		}
 		defer stmt.Close()
-		if _, err := stmt.Exec(); err != nil {
+		if _, err := stmt.ExecContext(ctx); err != nil {
 			return err
 		}
 	}
@@ -191,20 +254,20 @@ func (m *MSSQL) RevokeUser(statements dbplugin.Statements, username string) erro
 	return nil
 }
 
-func (m *MSSQL) revokeUserDefault(username string) error {
+func (m *MSSQL) revokeUserDefault(ctx context.Context, username string) error {
 	// Get connection
-	db, err := m.getConnection()
+	db, err := m.getConnection(ctx)
 	if err != nil {
 		return err
 	}
 
 	// First disable server login
-	disableStmt, err := db.Prepare(fmt.Sprintf("ALTER LOGIN [%s] DISABLE;", username))
+	disableStmt, err := db.PrepareContext(ctx, fmt.Sprintf("ALTER LOGIN [%s] DISABLE;", username))
 	if err != nil {
 		return err
 	}
 	defer disableStmt.Close()
-	if _, err := disableStmt.Exec(); err != nil {
+	if _, err := disableStmt.ExecContext(ctx); err != nil {
 		return err
 	}
 
@@ -212,14 +275,14 @@ func (m *MSSQL) revokeUserDefault(username string) error {
 	// sessions.  There cannot be any active sessions before we drop the logins
 	// This isn't done in a transaction because even if we fail along the way,
 	// we want to remove as much access as possible
-	sessionStmt, err := db.Prepare(fmt.Sprintf(
+	sessionStmt, err := db.PrepareContext(ctx, fmt.Sprintf(
 		"SELECT session_id FROM sys.dm_exec_sessions WHERE login_name = '%s';", username))
 	if err != nil {
 		return err
 	}
 	defer sessionStmt.Close()
 
-	sessionRows, err := sessionStmt.Query()
+	sessionRows, err := sessionStmt.QueryContext(ctx)
 	if err != nil {
 		return err
 	}
@@ -240,13 +303,13 @@ func (m *MSSQL) revokeUserDefault(username string) error {
 	// we need to drop the database users before we can drop the login and the role
 	// This isn't done in a transaction because even if we fail along the way,
 	// we want to remove as much access as possible
-	stmt, err := db.Prepare(fmt.Sprintf("EXEC master.dbo.sp_msloginmappings '%s';", username))
+	stmt, err := db.PrepareContext(ctx, fmt.Sprintf("EXEC master.dbo.sp_msloginmappings '%s';", username))
 	if err != nil {
 		return err
 	}
 	defer stmt.Close()
 
-	rows, err := stmt.Query()
+	rows, err := stmt.QueryContext(ctx)
 	if err != nil {
 		return err
 	}
@@ -266,13 +329,13 @@ func (m *MSSQL) revokeUserDefault(username string) error {
 	// many permissions as possible right now
 	var lastStmtError error
 	for _, query := range revokeStmts {
-		stmt, err := db.Prepare(query)
+		stmt, err := db.PrepareContext(ctx, query)
 		if err != nil {
 			lastStmtError = err
 			continue
 		}
 		defer stmt.Close()
-		_, err = stmt.Exec()
+		_, err = stmt.ExecContext(ctx)
 		if err != nil {
 			lastStmtError = err
 		}
@@ -287,12 +350,12 @@ func (m *MSSQL) revokeUserDefault(username string) error {
 	}
 
 	// Drop this login
-	stmt, err = db.Prepare(fmt.Sprintf(dropLoginSQL, username, username))
+	stmt, err = db.PrepareContext(ctx, fmt.Sprintf(dropLoginSQL, username, username))
 	if err != nil {
 		return err
 	}
 	defer stmt.Close()
-	if _, err := stmt.Exec(); err != nil {
+	if _, err := stmt.ExecContext(ctx); err != nil {
 		return err
 	}
 
@@ -319,3 +382,68 @@ BEGIN
   DROP LOGIN [%s]
 END
 `
+
+// RotateRootCredentials changes the password of the login the plugin connects
+// as and returns the updated connection configuration.
+func (m *MSSQL) RotateRootCredentials(ctx context.Context, statements []string) (map[string]interface{}, error) {
+	// Grab the lock
+	m.Lock()
+	defer m.Unlock()
+
+	connProducer := m.ConnectionProducer.(*connutil.SQLConnectionProducer)
+	if err := connProducer.CanRotateRootCredentials(); err != nil {
+		return nil, err
+	}
+
+	rotateStatements := statements
+	if len(rotateStatements) == 0 {
+		rotateStatements = []string{defaultMSSQLRotateRootCredentialsSQL}
+	}
+
+	// Get the connection
+	db, err := m.getConnection(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	password, err := m.GeneratePassword()
+	if err != nil {
+		return nil, err
+	}
+
+	// Start a transaction
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Execute each query
+	for _, rotateStmt := range rotateStatements {
+		for _, query := range strutil.ParseArbitraryStringSlice(rotateStmt, ";") {
+			query = strings.TrimSpace(query)
+			if len(query) == 0 {
+				continue
+			}
+
+			stmt, err := tx.PrepareContext(ctx, dbutil.QueryHelper(query, map[string]string{
+				"username": connProducer.Username,
+				"password": password,
+			}))
+			if err != nil {
+				return nil, err
+			}
+			defer stmt.Close()
+			if _, err := stmt.ExecContext(ctx); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// Commit the transaction
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return connProducer.SetRootPassword(password), nil
+}
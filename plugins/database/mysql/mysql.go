@@ -13,6 +13,7 @@ import (
 	"github.com/hashicorp/vault/plugins/helper/database/connutil"
 	"github.com/hashicorp/vault/plugins/helper/database/credsutil"
 	"github.com/hashicorp/vault/plugins/helper/database/dbutil"
+	"golang.org/x/net/context"
 )
 
 const (
@@ -20,6 +21,12 @@ const (
 		REVOKE ALL PRIVILEGES, GRANT OPTION FROM '{{name}}'@'%'; 
 		DROP USER '{{name}}'@'%'
 	`
+	defaultMysqlRotationStmts = `
+		ALTER USER '{{name}}'@'%' IDENTIFIED BY '{{password}}';
+	`
+	defaultMysqlRotateRootCredentialsSQL = `
+		ALTER USER '{{username}}'@'%' IDENTIFIED BY '{{password}}';
+	`
 	mySQLTypeName = "mysql"
 )
 
@@ -88,8 +95,8 @@ func (m *MySQL) Type() (string, error) {
 	return mySQLTypeName, nil
 }
 
-func (m *MySQL) getConnection() (*sql.DB, error) {
-	db, err := m.Connection()
+func (m *MySQL) getConnection(ctx context.Context) (*sql.DB, error) {
+	db, err := m.Connection(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -97,13 +104,13 @@ func (m *MySQL) getConnection() (*sql.DB, error) {
 	return db.(*sql.DB), nil
 }
 
-func (m *MySQL) CreateUser(statements dbplugin.Statements, usernameConfig dbplugin.UsernameConfig, expiration time.Time) (username string, password string, err error) {
+func (m *MySQL) CreateUser(ctx context.Context, statements dbplugin.Statements, usernameConfig dbplugin.UsernameConfig, expiration time.Time) (username string, password string, err error) {
 	// Grab the lock
 	m.Lock()
 	defer m.Unlock()
 
 	// Get the connection
-	db, err := m.getConnection()
+	db, err := m.getConnection(ctx)
 	if err != nil {
 		return "", "", err
 	}
@@ -128,7 +135,7 @@ func (m *MySQL) CreateUser(statements dbplugin.Statements, usernameConfig dbplug
 	}
 
 	// Start a transaction
-	tx, err := db.Begin()
+	tx, err := db.BeginTx(ctx, nil)
 	if err != nil {
 		return "", "", err
 	}
@@ -141,7 +148,7 @@ func (m *MySQL) CreateUser(statements dbplugin.Statements, usernameConfig dbplug
 			continue
 		}
 
-		stmt, err := tx.Prepare(dbutil.QueryHelper(query, map[string]string{
+		stmt, err := tx.PrepareContext(ctx, dbutil.QueryHelper(query, map[string]string{
 			"name":       username,
 			"password":   password,
 			"expiration": expirationStr,
@@ -150,7 +157,7 @@ func (m *MySQL) CreateUser(statements dbplugin.Statements, usernameConfig dbplug
 			return "", "", err
 		}
 		defer stmt.Close()
-		if _, err := stmt.Exec(); err != nil {
+		if _, err := stmt.ExecContext(ctx); err != nil {
 			return "", "", err
 		}
 	}
@@ -164,17 +171,17 @@ func (m *MySQL) CreateUser(statements dbplugin.Statements, usernameConfig dbplug
 }
 
 // NOOP
-func (m *MySQL) RenewUser(statements dbplugin.Statements, username string, expiration time.Time) error {
+func (m *MySQL) RenewUser(ctx context.Context, statements dbplugin.Statements, username string, expiration time.Time) error {
 	return nil
 }
 
-func (m *MySQL) RevokeUser(statements dbplugin.Statements, username string) error {
+func (m *MySQL) RevokeUser(ctx context.Context, statements dbplugin.Statements, username string) error {
 	// Grab the read lock
 	m.Lock()
 	defer m.Unlock()
 
 	// Get the connection
-	db, err := m.getConnection()
+	db, err := m.getConnection(ctx)
 	if err != nil {
 		return err
 	}
@@ -186,7 +193,7 @@ func (m *MySQL) RevokeUser(statements dbplugin.Statements, username string) erro
 	}
 
 	// Start a transaction
-	tx, err := db.Begin()
+	tx, err := db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
@@ -202,7 +209,7 @@ func (m *MySQL) RevokeUser(statements dbplugin.Statements, username string) erro
 		// 1295: This command is not supported in the prepared statement protocol yet
 		// Reference https://mariadb.com/kb/en/mariadb/prepare-statement/
 		query = strings.Replace(query, "{{name}}", username, -1)
-		_, err = tx.Exec(query)
+		_, err = tx.ExecContext(ctx, query)
 		if err != nil {
 			return err
 		}
@@ -216,3 +223,128 @@ func (m *MySQL) RevokeUser(statements dbplugin.Statements, username string) erro
 
 	return nil
 }
+
+// SetCredentials sets the password of the existing user given in staticUser
+// using the RotationStatements provided. By default the password of the user
+// at host '%' is altered; MySQL versions older than 5.7.6 need custom
+// statements using SET PASSWORD.
+func (m *MySQL) SetCredentials(ctx context.Context, statements dbplugin.Statements, staticUser dbplugin.StaticUserConfig) (username string, password string, err error) {
+	if staticUser.Username == "" || staticUser.Password == "" {
+		return "", "", dbutil.ErrEmptyStaticUser
+	}
+
+	rotationStmts := statements.RotationStatements
+	if rotationStmts == "" {
+		rotationStmts = defaultMysqlRotationStmts
+	}
+
+	// Grab the lock
+	m.Lock()
+	defer m.Unlock()
+
+	// Get the connection
+	db, err := m.getConnection(ctx)
+	if err != nil {
+		return "", "", err
+	}
+
+	// Start a transaction
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", "", err
+	}
+	defer tx.Rollback()
+
+	// Execute each query
+	for _, query := range strutil.ParseArbitraryStringSlice(rotationStmts, ";") {
+		query = strings.TrimSpace(query)
+		if len(query) == 0 {
+			continue
+		}
+
+		stmt, err := tx.PrepareContext(ctx, dbutil.QueryHelper(query, map[string]string{
+			"name":     staticUser.Username,
+			"password": staticUser.Password,
+		}))
+		if err != nil {
+			return "", "", err
+		}
+		defer stmt.Close()
+		if _, err := stmt.ExecContext(ctx); err != nil {
+			return "", "", err
+		}
+	}
+
+	// Commit the transaction
+	if err := tx.Commit(); err != nil {
+		return "", "", err
+	}
+
+	return staticUser.Username, staticUser.Password, nil
+}
+
+// RotateRootCredentials changes the password of the user the plugin connects
+// as and returns the updated connection configuration. By default the user at
+// host '%' is altered.
+func (m *MySQL) RotateRootCredentials(ctx context.Context, statements []string) (map[string]interface{}, error) {
+	// Grab the lock
+	m.Lock()
+	defer m.Unlock()
+
+	connProducer := m.ConnectionProducer.(*connutil.SQLConnectionProducer)
+	if err := connProducer.CanRotateRootCredentials(); err != nil {
+		return nil, err
+	}
+
+	rotateStatements := statements
+	if len(rotateStatements) == 0 {
+		rotateStatements = []string{defaultMysqlRotateRootCredentialsSQL}
+	}
+
+	// Get the connection
+	db, err := m.getConnection(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	password, err := m.GeneratePassword()
+	if err != nil {
+		return nil, err
+	}
+
+	// Start a transaction
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Execute each query
+	for _, rotateStmt := range rotateStatements {
+		for _, query := range strutil.ParseArbitraryStringSlice(rotateStmt, ";") {
+			query = strings.TrimSpace(query)
+			if len(query) == 0 {
+				continue
+			}
+
+			stmt, err := tx.PrepareContext(ctx, dbutil.QueryHelper(query, map[string]string{
+				"username": connProducer.Username,
+				"password": password,
+			}))
+			if err != nil {
+				return nil, err
+			}
+			defer stmt.Close()
+			if _, err := stmt.ExecContext(ctx); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// Commit the transaction
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return connProducer.SetRootPassword(password), nil
+}
@@ -2,11 +2,15 @@ package connutil
 
 import (
 	"errors"
+	"strings"
 	"sync"
+
+	"golang.org/x/net/context"
 )
 
 var (
 	ErrNotInitialized = errors.New("connection has not been initalized")
+	ErrRootRotation   = errors.New("rotating the root credentials requires the username and password to be set and templated into the connection URL as {{username}} and {{password}}")
 )
 
 // ConnectionProducer can be used as an embeded interface in the Database
@@ -14,8 +18,32 @@ var (
 // connections and is used in all the builtin database types.
 type ConnectionProducer interface {
 	Close() error
-	Initialize(map[string]interface{}, bool) error
-	Connection() (interface{}, error)
+	Initialize(context.Context, map[string]interface{}, bool) error
+	Connection(context.Context) (interface{}, error)
 
 	sync.Locker
 }
+
+// CanRotateRootCredentials returns an error unless the username and password
+// of a connection are set and templated into its connection URL, so that the
+// connection keeps working with a rotated password.
+func CanRotateRootCredentials(connectionURL, username, password string) error {
+	if username == "" || password == "" ||
+		!strings.Contains(connectionURL, "{{username}}") || !strings.Contains(connectionURL, "{{password}}") {
+		return ErrRootRotation
+	}
+
+	return nil
+}
+
+// UpdatedConfig returns a copy of the connection configuration with the given
+// key set to value
+func UpdatedConfig(config map[string]interface{}, key string, value interface{}) map[string]interface{} {
+	updated := make(map[string]interface{}, len(config)+1)
+	for k, v := range config {
+		updated[k] = v
+	}
+	updated[key] = value
+
+	return updated
+}
@@ -8,7 +8,9 @@ import (
 	"time"
 
 	"github.com/hashicorp/vault/helper/parseutil"
+	"github.com/hashicorp/vault/plugins/helper/database/dbutil"
 	"github.com/mitchellh/mapstructure"
+	"golang.org/x/net/context"
 )
 
 // SQLConnectionProducer implements ConnectionProducer and provides a generic producer for most sql databases
@@ -17,18 +19,23 @@ type SQLConnectionProducer struct {
 	MaxOpenConnections       int         `json:"max_open_connections" structs:"max_open_connections" mapstructure:"max_open_connections"`
 	MaxIdleConnections       int         `json:"max_idle_connections" structs:"max_idle_connections" mapstructure:"max_idle_connections"`
 	MaxConnectionLifetimeRaw interface{} `json:"max_connection_lifetime" structs:"max_connection_lifetime" mapstructure:"max_connection_lifetime"`
+	Username                 string      `json:"username" structs:"username" mapstructure:"username"`
+	Password                 string      `json:"password" structs:"password" mapstructure:"password"`
 
 	Type                  string
+	rawConfig             map[string]interface{}
 	maxConnectionLifetime time.Duration
 	Initialized           bool
 	db                    *sql.DB
 	sync.Mutex
 }
 
-func (c *SQLConnectionProducer) Initialize(conf map[string]interface{}, verifyConnection bool) error {
+func (c *SQLConnectionProducer) Initialize(ctx context.Context, conf map[string]interface{}, verifyConnection bool) error {
 	c.Lock()
 	defer c.Unlock()
 
+	c.rawConfig = conf
+
 	err := mapstructure.WeakDecode(conf, c)
 	if err != nil {
 		return err
@@ -62,11 +69,11 @@ func (c *SQLConnectionProducer) Initialize(conf map[string]interface{}, verifyCo
 	c.Initialized = true
 
 	if verifyConnection {
-		if _, err := c.Connection(); err != nil {
+		if _, err := c.Connection(ctx); err != nil {
 			return fmt.Errorf("error verifying connection: %s", err)
 		}
 
-		if err := c.db.Ping(); err != nil {
+		if err := c.db.PingContext(ctx); err != nil {
 			return fmt.Errorf("error verifying connection: %s", err)
 		}
 	}
@@ -74,14 +81,14 @@ func (c *SQLConnectionProducer) Initialize(conf map[string]interface{}, verifyCo
 	return nil
 }
 
-func (c *SQLConnectionProducer) Connection() (interface{}, error) {
+func (c *SQLConnectionProducer) Connection(ctx context.Context) (interface{}, error) {
 	if !c.Initialized {
 		return nil, ErrNotInitialized
 	}
 
 	// If we already have a DB, test it and return
 	if c.db != nil {
-		if err := c.db.Ping(); err == nil {
+		if err := c.db.PingContext(ctx); err == nil {
 			return c.db, nil
 		}
 		// If the ping was unsuccessful, close it and ignore errors as we'll be
@@ -96,7 +103,10 @@ func (c *SQLConnectionProducer) Connection() (interface{}, error) {
 	}
 
 	// Otherwise, attempt to make connection
-	conn := c.ConnectionURL
+	conn := dbutil.QueryHelper(c.ConnectionURL, map[string]string{
+		"username": c.Username,
+		"password": c.Password,
+	})
 
 	// Ensure timezone is set to UTC for all the conenctions
 	if strings.HasPrefix(conn, "postgres://") || strings.HasPrefix(conn, "postgresql://") {
@@ -136,3 +146,26 @@ func (c *SQLConnectionProducer) Close() error {
 
 	return nil
 }
+
+// CanRotateRootCredentials returns an error if the root credentials of the
+// connection cannot be rotated. Rotating requires the username and password
+// to be configured separately and templated into the connection URL.
+func (c *SQLConnectionProducer) CanRotateRootCredentials() error {
+	return CanRotateRootCredentials(c.ConnectionURL, c.Username, c.Password)
+}
+
+// SetRootPassword replaces the password of the connection after it has been
+// changed on the database, and returns the configuration to store for the
+// connection. The existing connection is closed so the next one uses the new
+// password. The caller needs to hold the lock.
+func (c *SQLConnectionProducer) SetRootPassword(password string) map[string]interface{} {
+	c.Password = password
+
+	if c.db != nil {
+		c.db.Close()
+	}
+	c.db = nil
+
+	c.rawConfig = UpdatedConfig(c.rawConfig, "password", password)
+	return c.rawConfig
+}
@@ -8,6 +8,7 @@ import (
 
 var (
 	ErrEmptyCreationStatement = errors.New("empty creation statements")
+	ErrEmptyStaticUser        = errors.New("empty username or password for static user")
 )
 
 // Query templates a query for us.
@@ -36,13 +36,18 @@ type AuthResults struct {
 	Allowed    bool
 	RootPrivs  bool
 	Error      *multierror.Error
+
+	// ControlGroup is set when the request is otherwise allowed but must be
+	// held until the control group authorizes it
+	ControlGroup *ControlGroup
 }
 
 type ACLResults struct {
-	Allowed    bool
-	RootPrivs  bool
-	IsRoot     bool
-	MFAMethods []string
+	Allowed      bool
+	RootPrivs    bool
+	IsRoot       bool
+	MFAMethods   []string
+	ControlGroup *ControlGroup
 }
 
 // New is used to construct a policy based ACL from a set of policies.
@@ -103,6 +108,8 @@ func NewACL(policies []*Policy) (*ACL, error) {
 				existingPerms.CapabilitiesBitmap = DenyCapabilityInt
 				existingPerms.AllowedParameters = nil
 				existingPerms.DeniedParameters = nil
+				existingPerms.MFAMethods = nil
+				existingPerms.ControlGroup = nil
 				goto INSERT
 
 			default:
@@ -169,6 +176,28 @@ func NewACL(policies []*Policy) (*ACL, error) {
 				}
 			}
 
+			// MFA methods required by any of the policies are all required
+			if len(pc.Permissions.MFAMethods) > 0 {
+				existingPerms.MFAMethods = strutil.RemoveDuplicates(append(existingPerms.MFAMethods, pc.Permissions.MFAMethods...), false)
+			}
+
+			// The factors of all of the control groups must authorize a
+			// request, which waits no longer than the lesser of their TTLs
+			if pc.Permissions.ControlGroup != nil {
+				merged := &ControlGroup{
+					TTL: pc.Permissions.ControlGroup.TTL,
+				}
+				if existingPerms.ControlGroup != nil {
+					merged.Factors = append(merged.Factors, existingPerms.ControlGroup.Factors...)
+					if existingPerms.ControlGroup.TTL > 0 &&
+						(merged.TTL == 0 || existingPerms.ControlGroup.TTL < merged.TTL) {
+						merged.TTL = existingPerms.ControlGroup.TTL
+					}
+				}
+				merged.Factors = append(merged.Factors, pc.Permissions.ControlGroup.Factors...)
+				existingPerms.ControlGroup = merged
+			}
+
 		INSERT:
 			tree.Insert(pc.Prefix, existingPerms)
 		}
@@ -301,6 +330,11 @@ CHECK:
 		return
 	}
 
+	// The MFA methods and control group are checked by the caller once the
+	// request is otherwise allowed
+	ret.MFAMethods = permissions.MFAMethods
+	ret.ControlGroup = permissions.ControlGroup
+
 	if permissions.MaxWrappingTTL > 0 {
 		if req.WrapInfo == nil || req.WrapInfo.TTL > permissions.MaxWrappingTTL {
 			return
@@ -399,6 +433,19 @@ func (c *Core) performPolicyChecks(acl *ACL, te *TokenEntry, req *logical.Reques
 		if !ret.RootPrivs && opts.RootPrivsRequired {
 			return
 		}
+
+		// MFA was validated when a request held by a control group was made
+		if len(ret.ACLResults.MFAMethods) > 0 && !req.ControlGroupAuthorized {
+			if err := c.validateRequestMFA(req, inEntity, ret.ACLResults.MFAMethods); err != nil {
+				ret.Error = multierror.Append(ret.Error, err, logical.ErrPermissionDenied)
+				return
+			}
+		}
+
+		if ret.ACLResults.ControlGroup != nil && !req.ControlGroupAuthorized {
+			ret.ControlGroup = ret.ACLResults.ControlGroup
+			return
+		}
 	}
 
 	ret.Allowed = true
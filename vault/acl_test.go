@@ -272,6 +272,111 @@ func TestACL_PolicyMerge(t *testing.T) {
 	}
 }
 
+func TestACL_MFAMethods(t *testing.T) {
+	policy1, err := ParseACLPolicy(`
+name = "seal"
+path "sys/seal" {
+	capabilities = ["update", "sudo"]
+	mfa_methods = ["my_totp"]
+}
+path "secret/*" {
+	capabilities = ["read"]
+}
+`)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	policy2, err := ParseACLPolicy(`
+name = "seal-duo"
+path "sys/seal" {
+	capabilities = ["update"]
+	mfa_methods = ["my_duo", "my_totp"]
+}
+`)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	acl, err := NewACL([]*Policy{policy1, policy2})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// MFA methods of all matching policies are required
+	authResults := acl.AllowOperation(&logical.Request{Operation: logical.UpdateOperation, Path: "sys/seal"})
+	if !authResults.Allowed {
+		t.Fatalf("bad: %#v", authResults)
+	}
+	if expected := []string{"my_duo", "my_totp"}; !reflect.DeepEqual(authResults.MFAMethods, expected) {
+		t.Fatalf("bad: expected %#v, got %#v", expected, authResults.MFAMethods)
+	}
+
+	authResults = acl.AllowOperation(&logical.Request{Operation: logical.ReadOperation, Path: "secret/foo"})
+	if !authResults.Allowed || len(authResults.MFAMethods) != 0 {
+		t.Fatalf("bad: %#v", authResults)
+	}
+}
+
+func TestACL_ControlGroup(t *testing.T) {
+	policy1, err := ParseACLPolicy(`
+name = "managers"
+path "prod/root" {
+	capabilities = ["read"]
+	control_group = {
+		ttl = "4h"
+		factor "managers" {
+			identity {
+				group_names = ["managers"]
+				approvals = 2
+			}
+		}
+	}
+}
+`)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	policy2, err := ParseACLPolicy(`
+name = "auditors"
+path "prod/root" {
+	capabilities = ["list"]
+	control_group = {
+		ttl = "1h"
+		factor "auditors" {
+			identity {
+				group_names = ["auditors"]
+			}
+		}
+	}
+}
+path "prod/root" {
+	capabilities = ["read"]
+}
+`)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	acl, err := NewACL([]*Policy{policy1, policy2})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The factors of all matching policies must authorize the request,
+	// within the lowest TTL
+	authResults := acl.AllowOperation(&logical.Request{Operation: logical.ReadOperation, Path: "prod/root"})
+	if !authResults.Allowed || authResults.ControlGroup == nil {
+		t.Fatalf("bad: %#v", authResults)
+	}
+	cg := authResults.ControlGroup
+	if cg.TTL != time.Hour || len(cg.Factors) != 2 || cg.Factors[0].Name != "managers" || cg.Factors[1].Name != "auditors" {
+		t.Fatalf("bad: %#v", cg)
+	}
+
+	// Merging does not modify the control groups of the policies
+	if len(policy1.Paths[0].Permissions.ControlGroup.Factors) != 1 || policy1.Paths[0].Permissions.ControlGroup.TTL != 4*time.Hour {
+		t.Fatalf("bad: %#v", policy1.Paths[0].Permissions.ControlGroup)
+	}
+}
+
 func TestACL_AllowOperation(t *testing.T) {
 	policy, err := ParseACLPolicy(permissionsPolicy)
 	if err != nil {
@@ -55,27 +55,42 @@ func (c *Core) enableCredential(entry *MountEntry) error {
 		return fmt.Errorf("backend path must be specified")
 	}
 
+	// Ensure the token backend is a singleton
+	if entry.Type == "token" {
+		return fmt.Errorf("token credential backend cannot be instantiated")
+	}
+
+	if err := c.setEntryNamespace(entry); err != nil {
+		return err
+	}
+
+	return c.enableCredentialInternal(entry)
+}
+
+func (c *Core) enableCredentialInternal(entry *MountEntry) error {
 	c.authLock.Lock()
 	defer c.authLock.Unlock()
 
+	path := entry.APIPath()
+
 	// Look for matching name
 	for _, ent := range c.auth.Entries {
 		switch {
 		// Existing is oauth/github/ new is oauth/ or
 		// existing is oauth/ and new is oauth/github/
-		case strings.HasPrefix(ent.Path, entry.Path):
+		case strings.HasPrefix(ent.APIPath(), path):
 			fallthrough
-		case strings.HasPrefix(entry.Path, ent.Path):
+		case strings.HasPrefix(path, ent.APIPath()):
 			return logical.CodedError(409, "path is already in use")
 		}
 	}
 
-	// Ensure the token backend is a singleton
-	if entry.Type == "token" {
-		return fmt.Errorf("token credential backend cannot be instantiated")
+	// Ensure the path does not reach into a nested namespace
+	if ns := c.namespaceByPath(path); ns != entry.Namespace() {
+		return logical.CodedError(409, fmt.Sprintf("path is in use by namespace %s", ns.Path))
 	}
 
-	if match := c.router.MatchingMount(credentialRoutePrefix + entry.Path); match != "" {
+	if match := c.router.MatchingMount(path); match != "" {
 		return logical.CodedError(409, fmt.Sprintf("existing mount at %s", match))
 	}
 
@@ -104,23 +119,28 @@ func (c *Core) enableCredential(entry *MountEntry) error {
 		conf["plugin_name"] = entry.Config.PluginName
 	}
 
-	// Create the new backend
-	backend, err = c.newCredentialBackend(entry.Type, sysView, view, conf)
-	if err != nil {
-		return err
-	}
-	if backend == nil {
-		return fmt.Errorf("nil backend returned from %q factory", entry.Type)
-	}
+	if entry.Type == "token" {
+		// Tokens of all namespaces are kept by the single token store
+		backend = c.tokenStore
+	} else {
+		// Create the new backend
+		backend, err = c.newCredentialBackend(entry.Type, sysView, view, conf)
+		if err != nil {
+			return err
+		}
+		if backend == nil {
+			return fmt.Errorf("nil backend returned from %q factory", entry.Type)
+		}
 
-	// Check for the correct backend type
-	backendType := backend.Type()
-	if entry.Type == "plugin" && backendType != logical.TypeCredential {
-		return fmt.Errorf("cannot mount '%s' of type '%s' as an auth backend", entry.Config.PluginName, backendType)
-	}
+		// Check for the correct backend type
+		backendType := backend.Type()
+		if entry.Type == "plugin" && backendType != logical.TypeCredential {
+			return fmt.Errorf("cannot mount '%s' of type '%s' as an auth backend", entry.Config.PluginName, backendType)
+		}
 
-	if err := backend.Initialize(); err != nil {
-		return err
+		if err := backend.Initialize(); err != nil {
+			return err
+		}
 	}
 
 	// Update the auth table
@@ -132,20 +152,26 @@ func (c *Core) enableCredential(entry *MountEntry) error {
 
 	c.auth = newTable
 
-	path := credentialRoutePrefix + entry.Path
 	if err := c.router.Mount(backend, path, entry, view); err != nil {
 		return err
 	}
 
 	if c.logger.IsInfo() {
-		c.logger.Info("core: enabled credential backend", "path", entry.Path, "type", entry.Type)
+		c.logger.Info("core: enabled credential backend", "path", path, "type", entry.Type)
 	}
 	return nil
 }
 
-// disableCredential is used to disable an existing credential backend; the
-// boolean indicates if it existed
+// disableCredential is used to disable an existing credential backend of the
+// root namespace
 func (c *Core) disableCredential(path string) error {
+	return c.disableNamespaceCredential(rootNamespace, path)
+}
+
+// disableNamespaceCredential is used to disable an existing credential
+// backend of the given namespace; the path is relative to the auth/ prefix of
+// the namespace
+func (c *Core) disableNamespaceCredential(ns *Namespace, path string) error {
 	// Ensure we end the path in a slash
 	if !strings.HasSuffix(path, "/") {
 		path += "/"
@@ -156,8 +182,13 @@ func (c *Core) disableCredential(path string) error {
 		return fmt.Errorf("token credential backend cannot be disabled")
 	}
 
+	return c.disableCredentialInternal(ns.Path + credentialRoutePrefix + path)
+}
+
+// disableCredentialInternal disables the credential backend mounted at the
+// given full path, such as auth/github/ or team-a/auth/github/
+func (c *Core) disableCredentialInternal(fullPath string) error {
 	// Store the view for this backend
-	fullPath := credentialRoutePrefix + path
 	view := c.router.MatchingStorageByAPIPath(fullPath)
 	if view == nil {
 		return fmt.Errorf("no matching backend %s", fullPath)
@@ -169,7 +200,7 @@ func (c *Core) disableCredential(path string) error {
 	entry := c.router.MatchingMountEntry(fullPath)
 
 	// Mark the entry as tainted
-	if err := c.taintCredEntry(path); err != nil {
+	if err := c.taintCredEntry(fullPath); err != nil {
 		return err
 	}
 
@@ -184,8 +215,11 @@ func (c *Core) disableCredential(path string) error {
 			return err
 		}
 
-		// Call cleanup function if it exists
-		backend.Cleanup()
+		// Call cleanup function if it exists; the token store is shared by
+		// all namespaces so it is left alone
+		if entry.Type != "token" {
+			backend.Cleanup()
+		}
 	}
 
 	// Unmount the backend
@@ -197,18 +231,18 @@ func (c *Core) disableCredential(path string) error {
 	case entry.Local, !c.replicationState.HasState(consts.ReplicationPerformanceSecondary):
 		// Have writable storage, remove the whole thing
 		if err := logical.ClearView(view); err != nil {
-			c.logger.Error("core: failed to clear view for path being unmounted", "error", err, "path", path)
+			c.logger.Error("core: failed to clear view for path being unmounted", "error", err, "path", fullPath)
 			return err
 		}
 
 	}
 
 	// Remove the mount table entry
-	if err := c.removeCredEntry(path); err != nil {
+	if err := c.removeCredEntry(fullPath); err != nil {
 		return err
 	}
 	if c.logger.IsInfo() {
-		c.logger.Info("core: disabled credential backend", "path", path)
+		c.logger.Info("core: disabled credential backend", "path", fullPath)
 	}
 	return nil
 }
@@ -251,7 +285,7 @@ func (c *Core) remountCredEntryForce(path string) error {
 		return err
 	}
 
-	if err := c.disableCredential(path); err != nil {
+	if err := c.disableNamespaceCredential(me.Namespace(), me.Path); err != nil {
 		return err
 	}
 	return c.enableCredential(me)
@@ -327,6 +361,9 @@ func (c *Core) loadCredentials() error {
 
 		// Upgrade to table-scoped entries
 		for _, entry := range c.auth.Entries {
+			if err := c.setEntryNamespace(entry); err != nil {
+				return err
+			}
 			if entry.Table == "" {
 				entry.Table = c.auth.Type
 				needPersist = true
@@ -437,8 +474,17 @@ func (c *Core) setupCredentials() error {
 	c.authLock.Lock()
 	defer c.authLock.Unlock()
 
+	// The token auth methods of namespaces share the token store, so they
+	// are routed once the root one has been created
+	var namespaceTokenEntries []*MountEntry
+
 	for _, entry := range c.auth.Entries {
 		var backend logical.Backend
+		if entry.Type == "token" && entry.NamespaceID != "" {
+			namespaceTokenEntries = append(namespaceTokenEntries, entry)
+			continue
+		}
+
 		// Work around some problematic code that existed in master for a while
 		if strings.HasPrefix(entry.Path, credentialRoutePrefix) {
 			entry.Path = strings.TrimPrefix(entry.Path, credentialRoutePrefix)
@@ -481,7 +527,7 @@ func (c *Core) setupCredentials() error {
 		}
 	ROUTER_MOUNT:
 		// Mount the backend
-		path := credentialRoutePrefix + entry.Path
+		path := entry.APIPath()
 		err = c.router.Mount(backend, path, entry, view)
 		if err != nil {
 			c.logger.Error("core: failed to mount auth entry", "path", entry.Path, "error", err)
@@ -503,6 +549,18 @@ func (c *Core) setupCredentials() error {
 		}
 	}
 
+	for _, entry := range namespaceTokenEntries {
+		path := entry.APIPath()
+		view = NewBarrierView(c.barrier, credentialBarrierPrefix+entry.UUID+"/")
+		if err := c.router.Mount(c.tokenStore, path, entry, view); err != nil {
+			c.logger.Error("core: failed to mount auth entry", "path", path, "error", err)
+			return errLoadAuthFailed
+		}
+		if entry.Tainted {
+			c.router.Taint(path)
+		}
+	}
+
 	if persistNeeded {
 		return c.persistAuth(c.auth, false)
 	}
@@ -519,7 +577,10 @@ func (c *Core) teardownCredentials() error {
 	if c.auth != nil {
 		authTable := c.auth.shallowClone()
 		for _, e := range authTable.Entries {
-			backend := c.router.MatchingBackend(credentialRoutePrefix + e.Path)
+			if e.Type == "token" && e.NamespaceID != "" {
+				continue
+			}
+			backend := c.router.MatchingBackend(e.APIPath())
 			if backend != nil {
 				backend.Cleanup()
 			}
@@ -28,9 +28,16 @@ func (c *Core) Capabilities(token, path string) ([]string, error) {
 		return []string{DenyCapability}, nil
 	}
 
+	// Policies are resolved within the namespace of the token
+	ns := c.namespaceByID(te.NamespaceID)
+	if ns == nil {
+		return []string{DenyCapability}, nil
+	}
+	policyStore := c.namespacePolicyStore(ns)
+
 	var policies []*Policy
 	for _, tePolicy := range te.Policies {
-		policy, err := c.policyStore.GetPolicy(tePolicy, PolicyTypeToken)
+		policy, err := policyStore.GetPolicy(tePolicy, PolicyTypeToken)
 		if err != nil {
 			return nil, err
 		}
@@ -20,6 +20,7 @@ import (
 	"github.com/hashicorp/errwrap"
 	"github.com/hashicorp/go-uuid"
 	"github.com/hashicorp/vault/helper/jsonutil"
+	"github.com/hashicorp/vault/physical/raft"
 )
 
 const (
@@ -297,6 +298,13 @@ func (c *Core) startClusterListener() error {
 		return fmt.Errorf("cluster addresses not found")
 	}
 
+	// With raft storage the listeners are started at unseal and may already
+	// be running when this node becomes active
+	if c.clusterListenersRunning {
+		c.logger.Trace("core: cluster listeners already running")
+		return nil
+	}
+
 	c.logger.Trace("core: starting cluster listeners")
 
 	err := c.startForwarding()
@@ -396,6 +404,21 @@ func (c *Core) ClusterTLSConfig() (*tls.Config, error) {
 
 	serverConfigLookup := func(clientHello *tls.ClientHelloInfo) (*tls.Config, error) {
 		//c.logger.Trace("core: performing server config lookup")
+		for _, v := range clientHello.SupportedProtos {
+			if v == raft.RaftALPN {
+				// Raft connections are secured with the raft cluster's
+				// own key rather than the local cluster cert
+				c.clusterParamsLock.RLock()
+				layer := c.raftLayer
+				c.clusterParamsLock.RUnlock()
+
+				if layer == nil {
+					return nil, fmt.Errorf("got raft connection but raft storage is not running")
+				}
+				return layer.ServerTLSConfig()
+			}
+		}
+
 		for _, v := range clientHello.SupportedProtos {
 			switch v {
 			case "h2", requestForwardingALPN:
@@ -0,0 +1,365 @@
+package vault
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/hashicorp/vault/helper/jsonutil"
+	"github.com/hashicorp/vault/helper/strutil"
+	"github.com/hashicorp/vault/helper/wrapping"
+	"github.com/hashicorp/vault/logical"
+)
+
+const (
+	// controlGroupCubbyholePath is where a held request is stored in the
+	// cubbyhole of its wrapping token, so that it is destroyed along with the
+	// token. The response-wrapping policy does not grant access to it.
+	controlGroupCubbyholePath = "cubbyhole/control-group"
+
+	// defaultControlGroupTTL is how long a request waits for authorization
+	// when its control group does not set a TTL
+	defaultControlGroupTTL = 24 * time.Hour
+)
+
+// controlGroupRequiredError is returned by checkToken when a request is
+// allowed but must be held until its control group authorizes it
+type controlGroupRequiredError struct {
+	controlGroup *ControlGroup
+}
+
+func (e *controlGroupRequiredError) Error() string {
+	return "request requires control group authorization"
+}
+
+// controlGroupRequest is a request held until its control group authorizes
+// it. The requester and authorizers are tracked by their identity entities;
+// the request is performed with the requesting token once it is unwrapped.
+type controlGroupRequest struct {
+	Operation           logical.Operation            `json:"operation"`
+	Path                string                       `json:"path"`
+	Data                map[string]interface{}       `json:"data"`
+	ClientTokenAccessor string                       `json:"client_token_accessor"`
+	RequestEntityID     string                       `json:"request_entity_id"`
+	ControlGroup        *ControlGroup                `json:"control_group"`
+	Authorizations      []*controlGroupAuthorization `json:"authorizations"`
+	CreationTime        time.Time                    `json:"creation_time"`
+}
+
+// controlGroupAuthorization records the authorization of a request by an
+// entity, along with the factors that the entity is an authorizer for
+type controlGroupAuthorization struct {
+	EntityID string    `json:"entity_id"`
+	Factors  []string  `json:"factors"`
+	Time     time.Time `json:"time"`
+}
+
+// approved returns whether every factor of the control group has as many
+// authorizations as it requires
+func (r *controlGroupRequest) approved() bool {
+	for _, factor := range r.ControlGroup.Factors {
+		var approvals int
+		for _, authz := range r.Authorizations {
+			if strutil.StrListContains(authz.Factors, factor.Name) {
+				approvals++
+			}
+		}
+		if approvals < factor.Identity.ApprovalsRequired {
+			return false
+		}
+	}
+	return true
+}
+
+// authorizedBy returns whether the given entity has authorized the request
+func (r *controlGroupRequest) authorizedBy(entityID string) bool {
+	for _, authz := range r.Authorizations {
+		if authz.EntityID == entityID {
+			return true
+		}
+	}
+	return false
+}
+
+// holdControlGroupRequest stores a request in the cubbyhole of a new wrapping
+// token instead of performing it, and returns the wrapping token. The token
+// is not limited to a single use, so that unwrapping the request before it is
+// authorized does not discard it.
+func (c *Core) holdControlGroupRequest(req *logical.Request, auth *logical.Auth, te *TokenEntry, cg *ControlGroup) (*logical.Response, error) {
+	if te == nil || te.EntityID == "" {
+		return logical.ErrorResponse("requests requiring control group authorization must be made with a token that has an entity"), logical.ErrPermissionDenied
+	}
+	if te.Accessor == "" {
+		return logical.ErrorResponse("requests requiring control group authorization cannot be made with batch tokens"), logical.ErrPermissionDenied
+	}
+
+	if err := c.auditBroker.LogRequest(auth, req, c.auditedHeaders, nil); err != nil {
+		c.logger.Error("core: failed to audit request", "path", req.Path, "error", err)
+		return nil, ErrInternalError
+	}
+
+	ttl := cg.TTL
+	if ttl == 0 {
+		ttl = defaultControlGroupTTL
+	}
+	resp := &logical.Response{
+		WrapInfo: &wrapping.ResponseWrapInfo{
+			TTL: ttl,
+		},
+	}
+	if req.WrapInfo != nil {
+		resp.WrapInfo.Format = req.WrapInfo.Format
+	}
+	if cubbyResp, err := c.wrapInCubbyhole(req, resp, auth); cubbyResp != nil || err != nil {
+		return cubbyResp, err
+	}
+
+	aEntry, err := c.tokenStore.lookupByAccessor(resp.WrapInfo.Accessor, false)
+	if err != nil {
+		c.logger.Error("core: failed to look up control group wrapping token", "error", err)
+		return nil, ErrInternalError
+	}
+	wrapTE, err := c.tokenStore.Lookup(aEntry.TokenID)
+	if err != nil || wrapTE == nil {
+		c.logger.Error("core: failed to look up control group wrapping token", "error", err)
+		return nil, ErrInternalError
+	}
+	wrapTE.NumUses = 0
+	if err := c.tokenStore.store(wrapTE); err != nil {
+		c.tokenStore.Revoke(wrapTE.ID)
+		c.logger.Error("core: failed to store control group wrapping token", "error", err)
+		return nil, ErrInternalError
+	}
+
+	cgReq := &controlGroupRequest{
+		Operation:           req.Operation,
+		Path:                req.Path,
+		Data:                req.Data,
+		ClientTokenAccessor: te.Accessor,
+		RequestEntityID:     te.EntityID,
+		ControlGroup:        cg,
+		CreationTime:        resp.WrapInfo.CreationTime,
+	}
+	if err := c.storeControlGroupRequest(wrapTE.ID, cgReq); err != nil {
+		c.tokenStore.Revoke(wrapTE.ID)
+		c.logger.Error("core: failed to store control group request", "error", err)
+		return nil, ErrInternalError
+	}
+
+	return &logical.Response{
+		WrapInfo: resp.WrapInfo,
+	}, nil
+}
+
+// controlGroupRequest returns the request held in the cubbyhole of the given
+// wrapping token, or nil if the token does not hold one
+func (c *Core) controlGroupRequest(tokenID string) (*controlGroupRequest, error) {
+	cubbyResp, err := c.router.Route(&logical.Request{
+		Operation:   logical.ReadOperation,
+		Path:        controlGroupCubbyholePath,
+		ClientToken: tokenID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if cubbyResp == nil || cubbyResp.Data == nil {
+		return nil, nil
+	}
+	if cubbyResp.IsError() {
+		return nil, cubbyResp.Error()
+	}
+
+	raw, ok := cubbyResp.Data["request"].(string)
+	if !ok {
+		return nil, fmt.Errorf("could not decode control group request")
+	}
+	var cgReq controlGroupRequest
+	if err := jsonutil.DecodeJSON([]byte(raw), &cgReq); err != nil {
+		return nil, err
+	}
+	return &cgReq, nil
+}
+
+func (c *Core) storeControlGroupRequest(tokenID string, cgReq *controlGroupRequest) error {
+	// Like wrapped responses, the request is stored as a string so that its
+	// values survive the round trip through the cubbyhole unchanged
+	raw, err := json.Marshal(cgReq)
+	if err != nil {
+		return err
+	}
+
+	cubbyResp, err := c.router.Route(&logical.Request{
+		Operation:   logical.UpdateOperation,
+		Path:        controlGroupCubbyholePath,
+		ClientToken: tokenID,
+		Data: map[string]interface{}{
+			"request": string(raw),
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if cubbyResp != nil && cubbyResp.IsError() {
+		return cubbyResp.Error()
+	}
+	return nil
+}
+
+// controlGroupRequestByAccessor returns the ID of the wrapping token with the
+// given accessor and the request held by it
+func (c *Core) controlGroupRequestByAccessor(accessor string) (string, *controlGroupRequest, error) {
+	if accessor == "" {
+		return "", nil, logical.CodedError(http.StatusBadRequest, "missing accessor")
+	}
+
+	aEntry, err := c.tokenStore.lookupByAccessor(accessor, false)
+	if err != nil || aEntry.TokenID == "" {
+		return "", nil, logical.CodedError(http.StatusBadRequest, "no control group request found for the accessor")
+	}
+	cgReq, err := c.controlGroupRequest(aEntry.TokenID)
+	if err != nil {
+		return "", nil, err
+	}
+	if cgReq == nil {
+		return "", nil, logical.CodedError(http.StatusBadRequest, "no control group request found for the accessor")
+	}
+	return aEntry.TokenID, cgReq, nil
+}
+
+// authorizeControlGroupRequest records the authorization of the request held
+// by the wrapping token with the given accessor. The entity must be a member
+// of the identity groups of at least one factor of the control group, and
+// cannot authorize its own requests.
+func (c *Core) authorizeControlGroupRequest(accessor, entityID string) (*controlGroupRequest, error) {
+	if entityID == "" {
+		return nil, logical.CodedError(http.StatusForbidden, "authorizing control group requests requires a token with an entity")
+	}
+
+	c.controlGroupLock.Lock()
+	defer c.controlGroupLock.Unlock()
+
+	tokenID, cgReq, err := c.controlGroupRequestByAccessor(accessor)
+	if err != nil {
+		return nil, err
+	}
+	if cgReq.RequestEntityID == entityID {
+		return nil, logical.CodedError(http.StatusForbidden, "requesters cannot authorize their own requests")
+	}
+	if cgReq.authorizedBy(entityID) {
+		return cgReq, nil
+	}
+
+	iStore, _, err := c.mfaIdentityStore(entityID)
+	if err != nil {
+		return nil, err
+	}
+	if iStore == nil {
+		return nil, logical.CodedError(http.StatusForbidden, "entity of the token not found")
+	}
+	groups, err := iStore.transitiveGroupsByEntityID(entityID)
+	if err != nil {
+		return nil, err
+	}
+
+	var factors []string
+	for _, factor := range cgReq.ControlGroup.Factors {
+		for _, group := range groups {
+			if strutil.StrListContains(factor.Identity.GroupIDs, group.ID) ||
+				strutil.StrListContains(factor.Identity.GroupNames, group.Name) {
+				factors = append(factors, factor.Name)
+				break
+			}
+		}
+	}
+	if len(factors) == 0 {
+		return nil, logical.CodedError(http.StatusForbidden, "entity is not an authorizer of the request")
+	}
+
+	cgReq.Authorizations = append(cgReq.Authorizations, &controlGroupAuthorization{
+		EntityID: entityID,
+		Factors:  factors,
+		Time:     time.Now(),
+	})
+	if err := c.storeControlGroupRequest(tokenID, cgReq); err != nil {
+		return nil, err
+	}
+
+	if c.logger.IsInfo() {
+		c.logger.Info("core: control group request authorized", "request_path", cgReq.Path, "entity_id", entityID, "approved", cgReq.approved())
+	}
+	return cgReq, nil
+}
+
+// performControlGroupRequest performs the request held by the given wrapping
+// token once it has been approved, using the token that made the request. It
+// returns a nil response and error if the token does not hold a request.
+func (c *Core) performControlGroupRequest(req *logical.Request, tokenID string) (*logical.Response, error) {
+	cgReq, clientToken, errResp, err := c.takeControlGroupRequest(tokenID)
+	if cgReq == nil || errResp != nil || err != nil {
+		return errResp, err
+	}
+
+	resp, _, err := c.handleRequest(&logical.Request{
+		ID:                     req.ID,
+		Operation:              cgReq.Operation,
+		Path:                   cgReq.Path,
+		Data:                   cgReq.Data,
+		Connection:             req.Connection,
+		ClientToken:            clientToken,
+		ControlGroupAuthorized: true,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		return resp, err
+	}
+
+	wrapped := &logical.Response{
+		Data: map[string]interface{}{},
+	}
+	if resp == nil {
+		wrapped.Data[logical.HTTPStatusCode] = http.StatusNoContent
+		return wrapped, nil
+	}
+
+	httpResp := logical.LogicalResponseToHTTPResponse(resp)
+	httpResp.RequestID = req.ID
+	body, err := json.Marshal(httpResp)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding control group response: %v", err)
+	}
+	wrapped.Data[logical.HTTPStatusCode] = http.StatusOK
+	wrapped.Data[logical.HTTPRawBody] = body
+	wrapped.Data[logical.HTTPContentType] = "application/json"
+	return wrapped, nil
+}
+
+// takeControlGroupRequest returns the request held by the given wrapping token
+// and the token that made it, once the request has been approved. The
+// wrapping token is revoked, as with any other unwrapping, so that the request
+// is performed only once. The lock is not held while the request is
+// performed, as the request may itself be subject to a control group.
+func (c *Core) takeControlGroupRequest(tokenID string) (*controlGroupRequest, string, *logical.Response, error) {
+	c.controlGroupLock.Lock()
+	defer c.controlGroupLock.Unlock()
+
+	cgReq, err := c.controlGroupRequest(tokenID)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("error looking up control group request: %v", err)
+	}
+	if cgReq == nil {
+		return nil, "", nil, nil
+	}
+	if !cgReq.approved() {
+		return nil, "", logical.ErrorResponse("request needs further approval"), logical.ErrInvalidRequest
+	}
+
+	aEntry, err := c.tokenStore.lookupByAccessor(cgReq.ClientTokenAccessor, false)
+	if err != nil || aEntry.TokenID == "" {
+		return nil, "", logical.ErrorResponse("the token that made the request is no longer valid"), logical.ErrPermissionDenied
+	}
+
+	if err := c.tokenStore.Revoke(tokenID); err != nil {
+		return nil, "", nil, fmt.Errorf("error revoking control group wrapping token: %v", err)
+	}
+	return cgReq, aEntry.TokenID, nil, nil
+}
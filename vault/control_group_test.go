@@ -0,0 +1,154 @@
+package vault
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+func TestCore_ControlGroup(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+
+	handle := func(req *logical.Request) *logical.Response {
+		resp, err := c.HandleRequest(req)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("path %q: err: %v, resp: %#v", req.Path, err, resp)
+		}
+		return resp
+	}
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "sys/policy/requester")
+	req.Data["rules"] = `
+path "secret/foo" {
+	capabilities = ["read"]
+	control_group = {
+		ttl = "1h"
+		factor "managers" {
+			identity {
+				group_names = ["managers"]
+				approvals = 1
+			}
+		}
+	}
+}
+path "sys/control-group/authorize" {
+	capabilities = ["update"]
+}
+`
+	req.ClientToken = root
+	handle(req)
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "sys/policy/authorizer")
+	req.Data["rules"] = `
+path "sys/control-group/authorize" {
+	capabilities = ["update"]
+}
+`
+	req.ClientToken = root
+	handle(req)
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "secret/foo")
+	req.Data["foo"] = "bar"
+	req.ClientToken = root
+	handle(req)
+
+	createEntity := func(name string) string {
+		req := logical.TestRequest(t, logical.UpdateOperation, "identity/entity")
+		req.Data["name"] = name
+		req.ClientToken = root
+		return handle(req).Data["id"].(string)
+	}
+	requesterID := createEntity("requester")
+	managerID := createEntity("manager")
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "identity/group")
+	req.Data["name"] = "managers"
+	req.Data["member_entity_ids"] = []string{managerID}
+	req.ClientToken = root
+	handle(req)
+
+	createToken := func(entityID, policy string) string {
+		te := &TokenEntry{
+			Path:     "test",
+			Policies: []string{"default", policy},
+			EntityID: entityID,
+		}
+		if err := c.tokenStore.create(te); err != nil {
+			t.Fatal(err)
+		}
+		return te.ID
+	}
+	requesterToken := createToken(requesterID, "requester")
+	managerToken := createToken(managerID, "authorizer")
+
+	// The read is held and returns a wrapping token instead
+	req = logical.TestRequest(t, logical.ReadOperation, "secret/foo")
+	req.ClientToken = requesterToken
+	resp := handle(req)
+	if resp.WrapInfo == nil || resp.WrapInfo.Token == "" || resp.WrapInfo.Accessor == "" || resp.Data != nil {
+		t.Fatalf("bad: %#v", resp)
+	}
+	wrapToken, accessor := resp.WrapInfo.Token, resp.WrapInfo.Accessor
+
+	unwrap := func() (*logical.Response, error) {
+		req := logical.TestRequest(t, logical.UpdateOperation, "sys/wrapping/unwrap")
+		req.Data["token"] = wrapToken
+		req.ClientToken = requesterToken
+		return c.HandleRequest(req)
+	}
+
+	// Unwrapping before the request is approved fails, but keeps the request
+	if resp, err := unwrap(); err == nil {
+		t.Fatalf("expected error unwrapping an unapproved request, got %#v", resp)
+	}
+
+	authorize := func(token string) (*logical.Response, error) {
+		req := logical.TestRequest(t, logical.UpdateOperation, "sys/control-group/authorize")
+		req.Data["accessor"] = accessor
+		req.ClientToken = token
+		return c.HandleRequest(req)
+	}
+
+	// Requesters cannot authorize their own requests
+	if resp, err := authorize(requesterToken); err == nil {
+		t.Fatalf("expected error authorizing own request, got %#v", resp)
+	}
+
+	resp, err := authorize(managerToken)
+	if err != nil || resp == nil || resp.Data["approved"] != true {
+		t.Fatalf("err: %v, resp: %#v", err, resp)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "sys/control-group/request")
+	req.Data["accessor"] = accessor
+	req.ClientToken = requesterToken
+	resp = handle(req)
+	if resp.Data["approved"] != true || resp.Data["request_path"] != "secret/foo" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	if entity := resp.Data["request_entity"].(map[string]interface{}); entity["id"] != requesterID || entity["name"] != "requester" {
+		t.Fatalf("bad: %#v", entity)
+	}
+	if authz := resp.Data["authorizations"].([]map[string]interface{}); len(authz) != 1 || authz[0]["entity_id"] != managerID {
+		t.Fatalf("bad: %#v", authz)
+	}
+
+	// Once approved, unwrapping performs the request
+	resp, err = unwrap()
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("err: %v, resp: %#v", err, resp)
+	}
+	var httpResp logical.HTTPResponse
+	if err := json.Unmarshal(resp.Data[logical.HTTPRawBody].([]byte), &httpResp); err != nil {
+		t.Fatal(err)
+	}
+	if httpResp.Data["foo"] != "bar" {
+		t.Fatalf("bad: %#v", httpResp)
+	}
+
+	// The request can only be performed once
+	if resp, err := unwrap(); err == nil {
+		t.Fatalf("expected error unwrapping twice, got %#v", resp)
+	}
+}
@@ -30,11 +30,13 @@ import (
 	"github.com/hashicorp/vault/helper/identity"
 	"github.com/hashicorp/vault/helper/jsonutil"
 	"github.com/hashicorp/vault/helper/logformat"
+	"github.com/hashicorp/vault/helper/metricsutil"
 	"github.com/hashicorp/vault/helper/mlock"
 	"github.com/hashicorp/vault/helper/reload"
 	"github.com/hashicorp/vault/helper/tlsutil"
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/physical"
+	"github.com/hashicorp/vault/physical/raft"
 	"github.com/hashicorp/vault/shamir"
 	cache "github.com/patrickmn/go-cache"
 )
@@ -175,6 +177,11 @@ type Core struct {
 	// Our Seal, for seal configuration information
 	seal Seal
 
+	// pendingSeal is the configured seal while storage is still sealed with
+	// the migration seal. The keys are moved over to it once this node is
+	// unsealed and active.
+	pendingSeal Seal
+
 	// barrier is the security barrier wrapping the physical backend
 	barrier SecurityBarrier
 
@@ -199,6 +206,12 @@ type Core struct {
 	standbyStopCh    chan struct{}
 	manualStepDownCh chan struct{}
 
+	// raftStorage is set when the integrated raft storage backend is used
+	raftStorage *raft.RaftBackend
+	// raftLayer carries raft traffic over the cluster port while raft is
+	// running; it is protected by clusterParamsLock
+	raftLayer *raft.RaftLayer
+
 	// unlockInfo has the keys provided to Unseal until the threshold number of parts is available, as well as the operation nonce
 	unlockInfo *unlockInformation
 
@@ -259,6 +272,17 @@ type Core struct {
 	// renewal, expiration and revocation
 	expiration *ExpirationManager
 
+	// quotas enforces the rate-limit and lease-count quotas
+	quotas *QuotaManager
+
+	// mfa holds the MFA methods and login enforcements, and the logins
+	// waiting for MFA validation
+	mfa *MFAManager
+
+	// controlGroupLock serializes updates of the requests held by control
+	// groups
+	controlGroupLock sync.Mutex
+
 	// rollback manager is used to run rollbacks periodically
 	rollback *RollbackManager
 
@@ -271,6 +295,14 @@ type Core struct {
 	// identityStore is used to manage client entities
 	identityStore *IdentityStore
 
+	// namespaces holds the namespaces other than the root namespace, keyed
+	// by their path
+	namespaces map[string]*Namespace
+
+	// namespacesLock is used to ensure that the namespaces do not change
+	// underneath a calling function
+	namespacesLock sync.RWMutex
+
 	// metricsCh is used to stop the metrics streaming
 	metricsCh chan struct{}
 
@@ -281,6 +313,11 @@ type Core struct {
 	defaultLeaseTTL time.Duration
 	maxLeaseTTL     time.Duration
 
+	// leaseRevocationMaxAttempts is the number of times the revocation of an
+	// expired lease is attempted before it is marked irrevocable, or zero
+	// for the default
+	leaseRevocationMaxAttempts int
+
 	logger log.Logger
 
 	// cachingDisabled indicates whether caches are disabled
@@ -367,6 +404,13 @@ type Core struct {
 	// rawEnabled indicates whether the Raw endpoint is enabled
 	rawEnabled bool
 
+	// metricsHelper serves the metrics kept in memory by this node; it is
+	// nil if metrics are not available
+	metricsHelper *metricsutil.MetricsHelper
+
+	// unauthenticatedMetricsAccess allows reading the metrics without a token
+	unauthenticatedMetricsAccess bool
+
 	// pluginDirectory is the location vault will look for plugin binaries
 	pluginDirectory string
 
@@ -398,6 +442,11 @@ type CoreConfig struct {
 
 	Seal Seal `json:"seal" structs:"seal" mapstructure:"seal"`
 
+	// MigrationSeal is the seal storage may still be sealed with from before
+	// Seal was configured. If it is, Vault is unsealed with it and the keys
+	// are then migrated to Seal.
+	MigrationSeal Seal `json:"migration_seal" structs:"migration_seal" mapstructure:"migration_seal"`
+
 	Logger log.Logger `json:"logger" structs:"logger" mapstructure:"logger"`
 
 	// Disables the LRU cache on the physical backend
@@ -419,6 +468,10 @@ type CoreConfig struct {
 
 	MaxLeaseTTL time.Duration `json:"max_lease_ttl" structs:"max_lease_ttl" mapstructure:"max_lease_ttl"`
 
+	// Number of revoke attempts of an expired lease before it is marked
+	// irrevocable, or zero for the default
+	LeaseRevocationMaxAttempts int `json:"lease_revocation_max_attempts" structs:"lease_revocation_max_attempts" mapstructure:"lease_revocation_max_attempts"`
+
 	ClusterName string `json:"cluster_name" structs:"cluster_name" mapstructure:"cluster_name"`
 
 	ClusterCipherSuites string `json:"cluster_cipher_suites" structs:"cluster_cipher_suites" mapstructure:"cluster_cipher_suites"`
@@ -430,6 +483,12 @@ type CoreConfig struct {
 
 	PluginDirectory string `json:"plugin_directory" structs:"plugin_directory" mapstructure:"plugin_directory"`
 
+	// MetricsHelper serves the metrics read from sys/metrics
+	MetricsHelper *metricsutil.MetricsHelper `json:"metrics_helper" structs:"metrics_helper" mapstructure:"metrics_helper"`
+
+	// Allow reading sys/metrics without a token
+	UnauthenticatedMetricsAccess bool `json:"unauthenticated_metrics_access" structs:"unauthenticated_metrics_access" mapstructure:"unauthenticated_metrics_access"`
+
 	ReloadFuncs     *map[string][]reload.ReloadFunc
 	ReloadFuncsLock *sync.RWMutex
 }
@@ -451,6 +510,9 @@ func NewCore(conf *CoreConfig) (*Core, error) {
 	if conf.DefaultLeaseTTL > conf.MaxLeaseTTL {
 		return nil, fmt.Errorf("cannot have DefaultLeaseTTL larger than MaxLeaseTTL")
 	}
+	if conf.LeaseRevocationMaxAttempts < 0 {
+		return nil, fmt.Errorf("cannot have a negative LeaseRevocationMaxAttempts")
+	}
 
 	// Validate the advertise addr if its given to us
 	if conf.RedirectAddr != "" {
@@ -483,6 +545,7 @@ func NewCore(conf *CoreConfig) (*Core, error) {
 		logger:                           conf.Logger,
 		defaultLeaseTTL:                  conf.DefaultLeaseTTL,
 		maxLeaseTTL:                      conf.MaxLeaseTTL,
+		leaseRevocationMaxAttempts:       conf.LeaseRevocationMaxAttempts,
 		cachingDisabled:                  conf.DisableCache,
 		clusterName:                      conf.ClusterName,
 		clusterListenerShutdownCh:        make(chan struct{}),
@@ -490,6 +553,8 @@ func NewCore(conf *CoreConfig) (*Core, error) {
 		clusterPeerClusterAddrsCache:     cache.New(3*heartbeatInterval, time.Second),
 		enableMlock:                      !conf.DisableMlock,
 		rawEnabled:                       conf.EnableRaw,
+		metricsHelper:                    conf.MetricsHelper,
+		unauthenticatedMetricsAccess:     conf.UnauthenticatedMetricsAccess,
 		atomicPrimaryClusterAddrs:        new(atomic.Value),
 		atomicPrimaryFailoverAddrs:       new(atomic.Value),
 	}
@@ -523,6 +588,10 @@ func NewCore(conf *CoreConfig) (*Core, error) {
 		}
 	}
 
+	if err := c.setupSealMigration(conf.MigrationSeal); err != nil {
+		return nil, fmt.Errorf("seal migration setup failed: %v", err)
+	}
+
 	if !conf.DisableMlock {
 		// Ensure our memory usage is locked into physical RAM
 		if err := mlock.LockMemory(); err != nil {
@@ -557,6 +626,10 @@ func NewCore(conf *CoreConfig) (*Core, error) {
 		c.ha = conf.HAPhysical
 	}
 
+	if raftStorage, ok := conf.Physical.(*raft.RaftBackend); ok {
+		c.raftStorage = raftStorage
+	}
+
 	// We create the funcs here, then populate the given config with it so that
 	// the caller can share state
 	conf.ReloadFuncsLock = &c.reloadFuncsLock
@@ -686,6 +759,18 @@ func (c *Core) fetchACLTokenEntryAndEntity(clientToken string) (*ACL, *TokenEntr
 		return nil, nil, nil, logical.ErrPermissionDenied
 	}
 
+	// Tokens are evaluated against the policies and identity store of the
+	// namespace they were created in
+	ns := c.namespaceByID(te.NamespaceID)
+	if ns == nil {
+		return nil, nil, nil, logical.ErrPermissionDenied
+	}
+	policyStore := c.namespacePolicyStore(ns)
+	if policyStore == nil {
+		c.logger.Error("core: policy store of namespace is unavailable", "namespace", ns.Path)
+		return nil, nil, nil, ErrInternalError
+	}
+
 	tokenPolicies := te.Policies
 
 	var entity *identity.Entity
@@ -694,8 +779,14 @@ func (c *Core) fetchACLTokenEntryAndEntity(clientToken string) (*ACL, *TokenEntr
 	// off of the combined list.
 	if te.EntityID != "" {
 		//c.logger.Debug("core: entity set on the token", "entity_id", te.EntityID)
+		identityStore := c.namespaceIdentityStore(ns)
+		if identityStore == nil {
+			c.logger.Error("core: identity store of namespace is unavailable", "namespace", ns.Path)
+			return nil, nil, nil, ErrInternalError
+		}
+
 		// Fetch entity for the entity ID in the token entry
-		entity, err = c.identityStore.memDBEntityByID(te.EntityID, false)
+		entity, err = identityStore.memDBEntityByID(te.EntityID, false)
 		if err != nil {
 			c.logger.Error("core: failed to lookup entity using its ID", "error", err)
 			return nil, nil, nil, ErrInternalError
@@ -705,7 +796,7 @@ func (c *Core) fetchACLTokenEntryAndEntity(clientToken string) (*ACL, *TokenEntr
 			// If there was no corresponding entity object found, it is
 			// possible that the entity got merged into another entity. Try
 			// finding entity based on the merged entity index.
-			entity, err = c.identityStore.memDBEntityByMergedEntityID(te.EntityID, false)
+			entity, err = identityStore.memDBEntityByMergedEntityID(te.EntityID, false)
 			if err != nil {
 				c.logger.Error("core: failed to lookup entity in merged entity ID index", "error", err)
 				return nil, nil, nil, ErrInternalError
@@ -717,7 +808,7 @@ func (c *Core) fetchACLTokenEntryAndEntity(clientToken string) (*ACL, *TokenEntr
 			// Attach the policies on the entity to the policies tied to the token
 			tokenPolicies = append(tokenPolicies, entity.Policies...)
 
-			groupPolicies, err := c.identityStore.groupPoliciesByEntityID(entity.ID)
+			groupPolicies, err := identityStore.groupPoliciesByEntityID(entity.ID)
 			if err != nil {
 				c.logger.Error("core: failed to fetch group policies", "error", err)
 				return nil, nil, nil, ErrInternalError
@@ -730,7 +821,7 @@ func (c *Core) fetchACLTokenEntryAndEntity(clientToken string) (*ACL, *TokenEntr
 	}
 
 	// Construct the corresponding ACL object
-	acl, err := c.policyStore.ACL(tokenPolicies...)
+	acl, err := policyStore.ACL(tokenPolicies...)
 	if err != nil {
 		c.logger.Error("core: failed to construct ACL", "error", err)
 		return nil, nil, nil, ErrInternalError
@@ -760,6 +851,15 @@ func (c *Core) checkToken(req *logical.Request, unauth bool) (*logical.Auth, *To
 		}
 	}
 
+	// Tokens can only be used within their own namespace and the namespaces
+	// nested underneath it
+	if te != nil && !unauth {
+		tokenNS := c.namespaceByID(te.NamespaceID)
+		if tokenNS == nil || !c.namespaceByPath(req.Path).HasParent(tokenNS) {
+			return nil, te, logical.ErrPermissionDenied
+		}
+	}
+
 	// Check if this is a root protected path
 	rootPath := c.router.RootPath(req.Path)
 
@@ -827,6 +927,9 @@ func (c *Core) checkToken(req *logical.Request, unauth bool) (*logical.Auth, *To
 	if authResults.Error.ErrorOrNil() != nil {
 		return auth, te, authResults.Error
 	}
+	if authResults.ControlGroup != nil {
+		return auth, te, &controlGroupRequiredError{controlGroup: authResults.ControlGroup}
+	}
 	if !authResults.Allowed {
 		// Return auth for audit logging even if not allowed
 		return auth, te, logical.ErrPermissionDenied
@@ -1088,6 +1191,15 @@ func (c *Core) unsealInternal(masterKey []byte) (bool, error) {
 		c.logger.Info("core: vault is unsealed")
 	}
 
+	// Raft must be running before going to standby so that this node can
+	// take part in leader election
+	if err := c.startRaftStorage(); err != nil {
+		c.logger.Error("core: failed to start raft storage", "error", err)
+		c.barrier.Seal()
+		c.logger.Warn("core: vault is sealed")
+		return false, err
+	}
+
 	// Do post-unseal setup if HA is not enabled
 	if c.ha == nil {
 		// We still need to set up cluster info even if it's not part of a
@@ -1411,6 +1523,10 @@ func (c *Core) sealInternal() error {
 		return err
 	}
 
+	if err := c.stopRaftStorage(); err != nil {
+		c.logger.Error("core: error stopping raft storage", "error", err)
+	}
+
 	if c.ha != nil {
 		sd, ok := c.ha.(physical.ServiceDiscovery)
 		if ok {
@@ -1452,6 +1568,12 @@ func (c *Core) postUnseal() (retErr error) {
 		purgable.Purge()
 	}
 
+	// Migrate the seal now that we are the active node
+	if err := c.migrateSeal(); err != nil {
+		c.logger.Error("core: seal migration failed", "error", err)
+		return err
+	}
+
 	// Purge these for safety in case of a rekey
 	c.seal.SetBarrierConfig(nil)
 	if c.seal.RecoveryKeySupported() {
@@ -1467,6 +1589,9 @@ func (c *Core) postUnseal() (retErr error) {
 	if err := c.setupPluginCatalog(); err != nil {
 		return err
 	}
+	if err := c.loadNamespaces(); err != nil {
+		return err
+	}
 	if err := c.loadMounts(); err != nil {
 		return err
 	}
@@ -1488,6 +1613,9 @@ func (c *Core) postUnseal() (retErr error) {
 	if err := c.startRollback(); err != nil {
 		return err
 	}
+	if err := c.setupQuotas(); err != nil {
+		return err
+	}
 	if err := c.setupExpiration(); err != nil {
 		return err
 	}
@@ -1500,6 +1628,9 @@ func (c *Core) postUnseal() (retErr error) {
 	if err := c.loadIdentityStoreArtifacts(); err != nil {
 		return err
 	}
+	if err := c.setupMFA(); err != nil {
+		return err
+	}
 	if err := c.setupAuditedHeadersConfig(); err != nil {
 		return err
 	}
@@ -1533,7 +1664,11 @@ func (c *Core) preSeal() error {
 	}
 	var result error
 
-	c.stopClusterListener()
+	// With raft storage the cluster listener also carries raft traffic, so
+	// it keeps running until the node is sealed
+	if c.raftStorage == nil {
+		c.stopClusterListener()
+	}
 
 	if err := c.teardownAudits(); err != nil {
 		result = multierror.Append(result, errwrap.Wrapf("error tearing down audits: {{err}}", err))
@@ -1541,6 +1676,12 @@ func (c *Core) preSeal() error {
 	if err := c.stopExpiration(); err != nil {
 		result = multierror.Append(result, errwrap.Wrapf("error stopping expiration: {{err}}", err))
 	}
+	if err := c.teardownQuotas(); err != nil {
+		result = multierror.Append(result, errwrap.Wrapf("error tearing down quotas: {{err}}", err))
+	}
+	if err := c.teardownMFA(); err != nil {
+		result = multierror.Append(result, errwrap.Wrapf("error tearing down MFA: {{err}}", err))
+	}
 	if err := c.teardownCredentials(); err != nil {
 		result = multierror.Append(result, errwrap.Wrapf("error tearing down credentials: {{err}}", err))
 	}
@@ -1553,6 +1694,9 @@ func (c *Core) preSeal() error {
 	if err := c.unloadMounts(); err != nil {
 		result = multierror.Append(result, errwrap.Wrapf("error unloading mounts: {{err}}", err))
 	}
+	if err := c.teardownNamespaces(); err != nil {
+		result = multierror.Append(result, errwrap.Wrapf("error tearing down namespaces: {{err}}", err))
+	}
 	if err := enterprisePreSeal(c); err != nil {
 		result = multierror.Append(result, err)
 	}
@@ -1990,6 +2134,9 @@ func (c *Core) emitMetrics(stopCh chan struct{}) {
 			if c.expiration != nil {
 				c.expiration.emitMetrics()
 			}
+			if c.quotas != nil {
+				c.quotas.emitMetrics()
+			}
 			c.metricsMutex.Unlock()
 		case <-stopCh:
 			return
@@ -2011,6 +2158,18 @@ func (c *Core) Logger() log.Logger {
 	return c.logger
 }
 
+// MetricsHelper returns the helper serving the metrics of this node, or nil
+// if metrics are not available
+func (c *Core) MetricsHelper() *metricsutil.MetricsHelper {
+	return c.metricsHelper
+}
+
+// UnauthenticatedMetricsAccess returns whether the metrics of this node may
+// be read without a token
+func (c *Core) UnauthenticatedMetricsAccess() bool {
+	return c.unauthenticatedMetricsAccess
+}
+
 func (c *Core) BarrierKeyLength() (min, max int) {
 	min, max = c.barrier.KeyLength()
 	max += shamir.ShareOverhead
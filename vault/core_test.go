@@ -695,6 +695,7 @@ func TestCore_HandleLogin_Token(t *testing.T) {
 		DisplayName:  "foo-armon",
 		TTL:          time.Hour * 24,
 		CreationTime: te.CreationTime,
+		Type:         logical.TokenTypeService,
 	}
 
 	if !reflect.DeepEqual(te, expect) {
@@ -895,6 +896,7 @@ func TestCore_HandleRequest_CreateToken_Lease(t *testing.T) {
 		DisplayName:  "token",
 		CreationTime: te.CreationTime,
 		TTL:          time.Hour * 24 * 32,
+		Type:         logical.TokenTypeService,
 	}
 	if !reflect.DeepEqual(te, expect) {
 		t.Fatalf("Bad: %#v expect: %#v", te, expect)
@@ -940,6 +942,7 @@ func TestCore_HandleRequest_CreateToken_NoDefaultPolicy(t *testing.T) {
 		DisplayName:  "token",
 		CreationTime: te.CreationTime,
 		TTL:          time.Hour * 24 * 32,
+		Type:         logical.TokenTypeService,
 	}
 	if !reflect.DeepEqual(te, expect) {
 		t.Fatalf("Bad: %#v expect: %#v", te, expect)
@@ -2140,3 +2143,81 @@ func TestCore_Standby_Rotate(t *testing.T) {
 		t.Fatalf("bad: %#v", resp)
 	}
 }
+
+func TestCore_HandleLogin_BatchToken(t *testing.T) {
+	noop := &NoopBackend{
+		Login: []string{"login"},
+		Response: &logical.Response{
+			Auth: &logical.Auth{
+				Policies: []string{"foo"},
+				LeaseOptions: logical.LeaseOptions{
+					TTL: time.Hour,
+				},
+			},
+		},
+	}
+	c, _, root := TestCoreUnsealed(t)
+	c.credentialBackends["noop"] = func(conf *logical.BackendConfig) (logical.Backend, error) {
+		return noop, nil
+	}
+
+	// Enable the credential backend, issuing batch tokens
+	req := logical.TestRequest(t, logical.UpdateOperation, "sys/auth/foo")
+	req.Data["type"] = "noop"
+	req.Data["config"] = map[string]interface{}{
+		"token_type": "batch",
+	}
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	lresp, err := c.HandleRequest(&logical.Request{
+		Path: "auth/foo/login",
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if lresp.Auth.TokenType != logical.TokenTypeBatch || lresp.Auth.Accessor != "" {
+		t.Fatalf("bad: %#v", lresp.Auth)
+	}
+
+	te, err := c.tokenStore.Lookup(lresp.Auth.ClientToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if te == nil || te.Type != logical.TokenTypeBatch || te.Path != "auth/foo/login" {
+		t.Fatalf("bad: %#v", te)
+	}
+
+	// No lease is registered for the token
+	if leases, err := c.expiration.lookupByToken(te.ID); err != nil || len(leases) != 0 {
+		t.Fatalf("bad: %v %v", leases, err)
+	}
+
+	// The backend can't request a service token
+	noop.Response.Auth.TokenType = logical.TokenTypeService
+	lresp, err = c.HandleRequest(&logical.Request{
+		Path: "auth/foo/login",
+	})
+	if err == nil || lresp == nil || !lresp.IsError() {
+		t.Fatalf("expected error, got: %#v %v", lresp, err)
+	}
+
+	// After tuning to default-service, the backend can choose
+	req = logical.TestRequest(t, logical.UpdateOperation, "sys/auth/foo/tune")
+	req.Data["token_type"] = "default-service"
+	req.ClientToken = root
+	if resp, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	lresp, err = c.HandleRequest(&logical.Request{
+		Path: "auth/foo/login",
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if lresp.Auth.TokenType != logical.TokenTypeService || lresp.Auth.Accessor == "" {
+		t.Fatalf("bad: %#v", lresp.Auth)
+	}
+}
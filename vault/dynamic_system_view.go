@@ -41,8 +41,14 @@ func (d dynamicSystemView) SudoPrivilege(path string, token string) bool {
 		return false
 	}
 
-	// Construct the corresponding ACL object
-	acl, err := d.core.policyStore.ACL(te.Policies...)
+	// Construct the corresponding ACL object from the policies of the
+	// token's namespace
+	ns := d.core.namespaceByID(te.NamespaceID)
+	if ns == nil {
+		d.core.logger.Error("core: namespace of token not found", "namespace_id", te.NamespaceID)
+		return false
+	}
+	acl, err := d.core.namespacePolicyStore(ns).ACL(te.Policies...)
 	if err != nil {
 		d.core.logger.Error("failed to retrieve ACL for token's policies", "token_policies", te.Policies, "error", err)
 		return false
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"path"
+	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -33,12 +34,17 @@ const (
 	// tokenViewPrefix is the prefix used for the token based lookup of leases.
 	tokenViewPrefix = "token/"
 
-	// maxRevokeAttempts limits how many revoke attempts are made
-	maxRevokeAttempts = 6
+	// defaultMaxRevokeAttempts limits how many revoke attempts are made
+	// before a lease is marked irrevocable, unless configured otherwise
+	defaultMaxRevokeAttempts = 6
 
 	// revokeRetryBase is a baseline retry time
 	revokeRetryBase = 10 * time.Second
 
+	// maxRevokeRetryInterval caps the exponential backoff between revoke
+	// attempts
+	maxRevokeRetryInterval = time.Hour
+
 	// maxLeaseDuration is the default maximum lease duration
 	maxLeaseTTL = 32 * 24 * time.Hour
 
@@ -55,11 +61,23 @@ type ExpirationManager struct {
 	idView     *BarrierView
 	tokenView  *BarrierView
 	tokenStore *TokenStore
+	quotas     *QuotaManager
 	logger     log.Logger
 
 	pending     map[string]*time.Timer
 	pendingLock sync.RWMutex
 
+	// revokeAttempts counts the failed revocations of expired leases that
+	// are waiting to be retried. It is protected by pendingLock.
+	revokeAttempts    map[string]uint
+	maxRevokeAttempts uint
+	revokeRetryBase   time.Duration
+
+	// irrevocable holds the leases for which revocation was given up after
+	// maxRevokeAttempts, keyed by lease ID
+	irrevocable     map[string]*leaseEntry
+	irrevocableLock sync.RWMutex
+
 	tidyLock int32
 
 	restoreMode        int32
@@ -85,6 +103,11 @@ func NewExpirationManager(router *Router, view *BarrierView, ts *TokenStore, log
 		logger:     logger,
 		pending:    make(map[string]*time.Timer),
 
+		revokeAttempts:    make(map[string]uint),
+		maxRevokeAttempts: defaultMaxRevokeAttempts,
+		revokeRetryBase:   revokeRetryBase,
+		irrevocable:       make(map[string]*leaseEntry),
+
 		// new instances of the expiration manager will go immediately into
 		// restore mode
 		restoreMode:  1,
@@ -104,6 +127,10 @@ func (c *Core) setupExpiration() error {
 
 	// Create the manager
 	mgr := NewExpirationManager(c.router, view, c.tokenStore, c.logger)
+	if c.leaseRevocationMaxAttempts > 0 {
+		mgr.maxRevokeAttempts = uint(c.leaseRevocationMaxAttempts)
+	}
+	mgr.quotas = c.quotas
 	c.expiration = mgr
 
 	// Link the token store to this
@@ -435,6 +462,7 @@ func (m *ExpirationManager) Stop() error {
 		timer.Stop()
 	}
 	m.pending = make(map[string]*time.Timer)
+	m.revokeAttempts = make(map[string]uint)
 	m.pendingLock.Unlock()
 
 	close(m.quitCh)
@@ -490,6 +518,7 @@ func (m *ExpirationManager) revokeCommon(leaseID string, force, skipToken bool)
 	if err := m.deleteEntry(leaseID); err != nil {
 		return err
 	}
+	m.quotas.releaseLease(leaseID)
 
 	// Delete the secondary index, but only if it's a leased secret (not auth)
 	if le.Secret != nil {
@@ -504,7 +533,12 @@ func (m *ExpirationManager) revokeCommon(leaseID string, force, skipToken bool)
 		timer.Stop()
 		delete(m.pending, leaseID)
 	}
+	delete(m.revokeAttempts, leaseID)
 	m.pendingLock.Unlock()
+
+	m.irrevocableLock.Lock()
+	delete(m.irrevocable, leaseID)
+	m.irrevocableLock.Unlock()
 	return nil
 }
 
@@ -786,6 +820,17 @@ func (m *ExpirationManager) Register(req *logical.Request, resp *logical.Respons
 
 	leaseID := path.Join(req.Path, leaseUUID)
 
+	// Count the lease against its lease-count quota before persisting it. If
+	// the quota is exceeded, the generated secret is revoked below.
+	if err := m.quotas.reserveLease(leaseID); err != nil {
+		if revResp, revErr := m.router.Route(logical.RevokeRequest(req.Path, resp.Secret, resp.Data)); revErr != nil {
+			err = multierror.Append(err, errwrap.Wrapf("an additional internal error was encountered revoking the newly-generated secret: {{err}}", revErr))
+		} else if revResp != nil && revResp.IsError() {
+			err = multierror.Append(err, errwrap.Wrapf("an additional error was encountered revoking the newly-generated secret: {{err}}", revResp.Error()))
+		}
+		return "", err
+	}
+
 	defer func() {
 		// If there is an error we want to rollback as much as possible (note
 		// that errors here are ignored to do as much cleanup as we can). We
@@ -806,6 +851,8 @@ func (m *ExpirationManager) Register(req *logical.Request, resp *logical.Respons
 			if err := m.removeIndexByToken(req.ClientToken, leaseID); err != nil {
 				retErr = multierror.Append(retErr, errwrap.Wrapf("an additional error was encountered removing lease indexes associated with the newly-generated secret: {{err}}", err))
 			}
+
+			m.quotas.releaseLease(leaseID)
 		}
 	}()
 
@@ -865,8 +912,15 @@ func (m *ExpirationManager) RegisterAuth(source string, auth *logical.Auth) erro
 		ExpireTime:  auth.ExpirationTime(),
 	}
 
+	// Count the lease against its lease-count quota, the caller revokes the
+	// token if it is exceeded
+	if err := m.quotas.reserveLease(le.LeaseID); err != nil {
+		return err
+	}
+
 	// Encode the entry
 	if err := m.persistEntry(&le); err != nil {
+		m.quotas.releaseLease(le.LeaseID)
 		return err
 	}
 
@@ -908,6 +962,7 @@ func (m *ExpirationManager) FetchLeaseTimes(leaseID string) (*leaseEntry, error)
 		IssueTime:       le.IssueTime,
 		ExpireTime:      le.ExpireTime,
 		LastRenewalTime: le.LastRenewalTime,
+		RevokeErr:       le.RevokeErr,
 	}
 	if le.Secret != nil {
 		ret.Secret = &logical.Secret{}
@@ -955,31 +1010,123 @@ func (m *ExpirationManager) updatePending(le *leaseEntry, leaseTotal time.Durati
 	timer.Reset(leaseTotal)
 }
 
-// expireID is invoked when a given ID is expired
+// expireID is invoked when a given ID is expired. If the revocation fails it
+// is retried with an exponential backoff, and the lease is marked irrevocable
+// once maxRevokeAttempts is reached.
 func (m *ExpirationManager) expireID(leaseID string) {
 	// Clear from the pending expiration
 	m.pendingLock.Lock()
 	delete(m.pending, leaseID)
+	attempt := m.revokeAttempts[leaseID]
 	m.pendingLock.Unlock()
 
-	for attempt := uint(0); attempt < maxRevokeAttempts; attempt++ {
-		select {
-		case <-m.quitCh:
-			m.logger.Error("expiration: shutting down, not attempting further revocation of lease", "lease_id", leaseID)
-			return
-		default:
+	select {
+	case <-m.quitCh:
+		m.logger.Error("expiration: shutting down, not attempting further revocation of lease", "lease_id", leaseID)
+		return
+	default:
+	}
+
+	err := m.Revoke(leaseID)
+	if err == nil {
+		if m.logger.IsInfo() {
+			m.logger.Info("expiration: revoked lease", "lease_id", leaseID)
 		}
-		err := m.Revoke(leaseID)
-		if err == nil {
-			if m.logger.IsInfo() {
-				m.logger.Info("expiration: revoked lease", "lease_id", leaseID)
-			}
-			return
+		return
+	}
+
+	attempt++
+	m.logger.Error("expiration: failed to revoke lease", "lease_id", leaseID, "attempt", attempt, "error", err)
+
+	if attempt >= m.maxRevokeAttempts {
+		m.logger.Error("expiration: maximum revoke attempts reached, marking lease irrevocable", "lease_id", leaseID)
+		if err := m.markIrrevocable(leaseID, err); err != nil {
+			m.logger.Error("expiration: failed to mark lease irrevocable", "lease_id", leaseID, "error", err)
+		}
+		return
+	}
+
+	metrics.IncrCounter([]string{"expire", "revoke", "retry"}, 1)
+
+	m.pendingLock.Lock()
+	defer m.pendingLock.Unlock()
+
+	// Don't schedule a retry if the manager was stopped in the meantime
+	select {
+	case <-m.quitCh:
+		return
+	default:
+	}
+
+	m.revokeAttempts[leaseID] = attempt
+	m.pending[leaseID] = time.AfterFunc(m.revokeRetryInterval(attempt), func() {
+		m.expireID(leaseID)
+	})
+}
+
+// revokeRetryInterval returns how long to wait before retrying a revocation
+// that has failed the given number of times
+func (m *ExpirationManager) revokeRetryInterval(attempt uint) time.Duration {
+	interval := m.revokeRetryBase
+	for i := uint(1); i < attempt && interval < maxRevokeRetryInterval; i++ {
+		interval *= 2
+	}
+	if interval > maxRevokeRetryInterval {
+		interval = maxRevokeRetryInterval
+	}
+	return interval
+}
+
+// markIrrevocable stores the error of the last revoke attempt on the lease
+// entry, so that the lease stays irrevocable across restarts, and stops
+// retrying the revocation. Operators can find these leases through
+// sys/leases/irrevocable.
+func (m *ExpirationManager) markIrrevocable(leaseID string, revokeErr error) error {
+	m.pendingLock.Lock()
+	delete(m.revokeAttempts, leaseID)
+	m.pendingLock.Unlock()
+
+	le, err := m.loadEntry(leaseID)
+	if err != nil {
+		return err
+	}
+	if le == nil {
+		return nil
+	}
+
+	le.RevokeErr = revokeErr.Error()
+	if err := m.persistEntry(le); err != nil {
+		return err
+	}
+
+	metrics.IncrCounter([]string{"expire", "revoke", "irrevocable"}, 1)
+	m.addIrrevocable(le)
+	return nil
+}
+
+// addIrrevocable tracks an irrevocable lease entry
+func (m *ExpirationManager) addIrrevocable(le *leaseEntry) {
+	m.irrevocableLock.Lock()
+	m.irrevocable[le.LeaseID] = le
+	m.irrevocableLock.Unlock()
+}
+
+// IrrevocableLeases returns the irrevocable leases whose ID starts with the
+// given prefix, sorted by lease ID
+func (m *ExpirationManager) IrrevocableLeases(prefix string) []*leaseEntry {
+	m.irrevocableLock.RLock()
+	defer m.irrevocableLock.RUnlock()
+
+	leases := make([]*leaseEntry, 0, len(m.irrevocable))
+	for leaseID, le := range m.irrevocable {
+		if strings.HasPrefix(leaseID, prefix) {
+			leases = append(leases, le)
 		}
-		m.logger.Error("expiration: failed to revoke lease", "lease_id", leaseID, "error", err)
-		time.Sleep((1 << attempt) * revokeRetryBase)
 	}
-	m.logger.Error("expiration: maximum revoke attempts reached", "lease_id", leaseID)
+	sort.Slice(leases, func(i, j int) bool {
+		return leases[i].LeaseID < leases[j].LeaseID
+	})
+	return leases
 }
 
 // revokeEntry is used to attempt revocation of an internal entry
@@ -1024,7 +1171,12 @@ func (m *ExpirationManager) renewAuthEntry(req *logical.Request, le *leaseEntry,
 	auth := *le.Auth
 	auth.IssueTime = le.IssueTime
 	auth.Increment = increment
-	if strings.HasPrefix(le.Path, "auth/token/") {
+	// The token store is mounted in every namespace
+	path := le.Path
+	if me := m.router.MatchingMountEntry(path); me != nil {
+		path = strings.TrimPrefix(path, me.Namespace().Path)
+	}
+	if strings.HasPrefix(path, "auth/token/") {
 		auth.ClientToken = le.ClientToken
 	} else {
 		auth.ClientToken = ""
@@ -1085,6 +1237,12 @@ func (m *ExpirationManager) loadEntryInternal(leaseID string, restoreMode bool,
 		// the lazy loaded restore process
 		m.restoreLoaded.Store(le.LeaseID, struct{}{})
 
+		// Irrevocable leases are not retried until an operator revokes them
+		if le.isIrrevocable() {
+			m.addIrrevocable(le)
+			return le, nil
+		}
+
 		// Setup revocation timer
 		m.updatePending(le, le.ExpireTime.Sub(time.Now()))
 	}
@@ -1214,6 +1372,11 @@ func (m *ExpirationManager) emitMetrics() {
 	num := len(m.pending)
 	m.pendingLock.RUnlock()
 	metrics.SetGauge([]string{"expire", "num_leases"}, float32(num))
+
+	m.irrevocableLock.RLock()
+	numIrrevocable := len(m.irrevocable)
+	m.irrevocableLock.RUnlock()
+	metrics.SetGauge([]string{"expire", "num_irrevocable_leases"}, float32(numIrrevocable))
 }
 
 // leaseEntry is used to structure the values the expiration
@@ -1228,6 +1391,10 @@ type leaseEntry struct {
 	IssueTime       time.Time              `json:"issue_time"`
 	ExpireTime      time.Time              `json:"expire_time"`
 	LastRenewalTime time.Time              `json:"last_renewal_time"`
+
+	// RevokeErr is the error of the last revoke attempt of a lease that
+	// could not be revoked after the maximum number of attempts
+	RevokeErr string `json:"revoke_err"`
 }
 
 // encode is used to JSON encode the lease entry
@@ -1244,6 +1411,8 @@ func (le *leaseEntry) renewable() (bool, error) {
 	// Determine if the lease is expired
 	case le.ExpireTime.Before(time.Now()):
 		err = fmt.Errorf("lease expired")
+	case le.isIrrevocable():
+		err = fmt.Errorf("lease is irrevocable")
 	// Determine if the lease is renewable
 	case le.Secret != nil && !le.Secret.Renewable:
 		err = fmt.Errorf("lease is not renewable")
@@ -1257,6 +1426,11 @@ func (le *leaseEntry) renewable() (bool, error) {
 	return true, nil
 }
 
+// isIrrevocable returns if revocation of the lease was given up
+func (le *leaseEntry) isIrrevocable() bool {
+	return le.RevokeErr != ""
+}
+
 func (le *leaseEntry) ttl() int64 {
 	return int64(le.ExpireTime.Sub(time.Now().Round(time.Second)).Seconds())
 }
@@ -623,6 +623,89 @@ func TestExpiration_RevokeOnExpire(t *testing.T) {
 	}
 }
 
+func TestExpiration_RevokeOnExpire_Irrevocable(t *testing.T) {
+	exp := mockExpiration(t)
+	exp.maxRevokeAttempts = 3
+	exp.revokeRetryBase = 10 * time.Millisecond
+
+	noop := &NoopBackend{
+		Response: logical.ErrorResponse("database unreachable"),
+	}
+	_, barrier, _ := mockBarrier(t)
+	view := NewBarrierView(barrier, "logical/")
+	meUUID, err := uuid.GenerateUUID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = exp.router.Mount(noop, "prod/aws/", &MountEntry{Path: "prod/aws/", Type: "noop", UUID: meUUID, Accessor: "noop-accessor"}, view)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := &logical.Request{
+		Operation:   logical.ReadOperation,
+		Path:        "prod/aws/foo",
+		ClientToken: "foobar",
+	}
+	resp := &logical.Response{
+		Secret: &logical.Secret{
+			LeaseOptions: logical.LeaseOptions{
+				TTL: 20 * time.Millisecond,
+			},
+		},
+	}
+
+	id, err := exp.Register(req, resp)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	start := time.Now()
+	for len(exp.IrrevocableLeases("")) == 0 {
+		if time.Now().Sub(start) > 5*time.Second {
+			t.Fatal("lease was not marked irrevocable")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	noop.Lock()
+	attempts := len(noop.Requests)
+	noop.Unlock()
+	if attempts != 3 {
+		t.Fatalf("expected 3 revoke attempts, got %d", attempts)
+	}
+
+	// The error is persisted with the lease and no retries are pending
+	le, err := exp.loadEntry(id)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if le == nil || !strings.Contains(le.RevokeErr, "database unreachable") {
+		t.Fatalf("bad: %#v", le)
+	}
+	exp.pendingLock.RLock()
+	_, pending := exp.pending[id]
+	exp.pendingLock.RUnlock()
+	if pending {
+		t.Fatal("irrevocable lease should not have a pending revocation")
+	}
+
+	if _, err := exp.Renew(id, 0); err == nil {
+		t.Fatal("expected an error renewing an irrevocable lease")
+	}
+
+	// Once the backend recovers, revoking the lease removes it
+	noop.Lock()
+	noop.Response = nil
+	noop.Unlock()
+	if err := exp.Revoke(id); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if leases := exp.IrrevocableLeases(""); len(leases) != 0 {
+		t.Fatalf("bad: %#v", leases)
+	}
+}
+
 func TestExpiration_RevokePrefix(t *testing.T) {
 	exp := mockExpiration(t)
 	noop := &NoopBackend{}
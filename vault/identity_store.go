@@ -2,6 +2,7 @@ package vault
 
 import (
 	"fmt"
+	"reflect"
 	"strings"
 
 	"github.com/golang/protobuf/ptypes"
@@ -51,6 +52,7 @@ func NewIdentityStore(core *Core, config *logical.BackendConfig) (*IdentityStore
 			entityPaths(iStore),
 			aliasPaths(iStore),
 			groupPaths(iStore),
+			groupAliasPaths(iStore),
 			lookupPaths(iStore),
 			upgradePaths(iStore),
 		),
@@ -301,6 +303,7 @@ func (i *IdentityStore) CreateEntity(alias *logical.Alias) (*identity.Entity, er
 		MountAccessor: alias.MountAccessor,
 		MountPath:     mountValidationResp.MountPath,
 		MountType:     mountValidationResp.MountType,
+		Metadata:      alias.Metadata,
 	}
 
 	err = i.sanitizeAlias(newAlias)
@@ -321,3 +324,47 @@ func (i *IdentityStore) CreateEntity(alias *logical.Alias) (*identity.Entity, er
 
 	return entity, nil
 }
+
+// UpdateAliasMetadata updates the metadata of the alias of an entity with the
+// metadata returned by the auth backend during login, if it has changed
+func (i *IdentityStore) UpdateAliasMetadata(entityID string, alias *logical.Alias) error {
+	if entityID == "" {
+		return fmt.Errorf("empty entity ID")
+	}
+
+	if alias == nil {
+		return fmt.Errorf("alias is nil")
+	}
+
+	lock := locksutil.LockForKey(i.entityLocks, entityID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	entity, err := i.memDBEntityByID(entityID, true)
+	if err != nil {
+		return err
+	}
+	if entity == nil {
+		return fmt.Errorf("invalid entity ID %q", entityID)
+	}
+
+	for _, item := range entity.Aliases {
+		if item.MountAccessor != alias.MountAccessor || item.Name != alias.Name {
+			continue
+		}
+
+		if reflect.DeepEqual(item.Metadata, alias.Metadata) || (len(item.Metadata) == 0 && len(alias.Metadata) == 0) {
+			return nil
+		}
+
+		item.Metadata = alias.Metadata
+		err = i.sanitizeAlias(item)
+		if err != nil {
+			return err
+		}
+
+		return i.upsertEntityNonLocked(entity, nil, true)
+	}
+
+	return nil
+}
@@ -227,6 +227,17 @@ func (i *IdentityStore) pathEntityMergeID(req *logical.Request, d *framework.Fie
 			toEntity.Aliases = append(toEntity.Aliases, alias)
 		}
 
+		// Keep the MFA secrets of the entity merged from, unless the entity
+		// merged into has its own secret for the method
+		for methodName, secret := range fromEntity.MFASecrets {
+			if toEntity.MFASecrets == nil {
+				toEntity.MFASecrets = make(map[string]*identity.MFASecret)
+			}
+			if _, ok := toEntity.MFASecrets[methodName]; !ok {
+				toEntity.MFASecrets[methodName] = secret
+			}
+		}
+
 		// If the entity from which we are merging from was already a merged
 		// entity, transfer over the Merged set to the entity we are
 		// merging into.
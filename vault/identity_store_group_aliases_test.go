@@ -0,0 +1,394 @@
+package vault
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+func TestIdentityStore_GroupAliases_CRUD(t *testing.T) {
+	var resp *logical.Response
+	var err error
+	is, githubAccessor, _ := testIdentityStoreWithGithubAuth(t)
+
+	// Aliases can't be set on internal groups
+	resp, err = is.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "group",
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+	internalGroupID := resp.Data["id"].(string)
+
+	groupAliasReq := &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "group-alias",
+		Data: map[string]interface{}{
+			"name":           "testgroupalias",
+			"mount_accessor": githubAccessor,
+			"canonical_id":   internalGroupID,
+		},
+	}
+	resp, err = is.HandleRequest(groupAliasReq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error setting an alias on an internal group")
+	}
+
+	resp, err = is.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "group",
+		Data: map[string]interface{}{
+			"type": "external",
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+	groupID := resp.Data["id"].(string)
+
+	groupAliasReq.Data["canonical_id"] = groupID
+	resp, err = is.HandleRequest(groupAliasReq)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+	groupAliasID := resp.Data["id"].(string)
+
+	// The same factors can't be used by another group alias
+	resp, err = is.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "group",
+		Data: map[string]interface{}{
+			"type": "external",
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+	groupAliasReq.Data["canonical_id"] = resp.Data["id"]
+	resp, err = is.HandleRequest(groupAliasReq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error reusing the factors of a group alias")
+	}
+
+	resp, err = is.HandleRequest(&logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "group-alias/id/" + groupAliasID,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+	if resp.Data["name"] != "testgroupalias" || resp.Data["canonical_id"] != groupID || resp.Data["mount_accessor"] != githubAccessor || resp.Data["mount_type"] != "github" {
+		t.Fatalf("bad: group alias: %#v", resp.Data)
+	}
+
+	resp, err = is.HandleRequest(&logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "group/id/" + groupID,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+	if resp.Data["type"] != "external" || resp.Data["alias"].(map[string]interface{})["id"] != groupAliasID {
+		t.Fatalf("bad: group: %#v", resp.Data)
+	}
+
+	// Rename the alias
+	resp, err = is.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "group-alias/id/" + groupAliasID,
+		Data: map[string]interface{}{
+			"name":           "updatedgroupalias",
+			"mount_accessor": githubAccessor,
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+	groupAlias, err := is.memDBGroupAliasByFactors(githubAccessor, "updatedgroupalias", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if groupAlias == nil || groupAlias.ID != groupAliasID {
+		t.Fatalf("bad: group alias: %#v", groupAlias)
+	}
+	groupAlias, err = is.memDBGroupAliasByFactors(githubAccessor, "testgroupalias", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if groupAlias != nil {
+		t.Fatalf("expected the old factors to be removed")
+	}
+
+	resp, err = is.HandleRequest(&logical.Request{
+		Operation: logical.ListOperation,
+		Path:      "group-alias/id",
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+	if !reflect.DeepEqual(resp.Data["keys"], []string{groupAliasID}) {
+		t.Fatalf("bad: keys: %#v", resp.Data["keys"])
+	}
+
+	resp, err = is.HandleRequest(&logical.Request{
+		Operation: logical.DeleteOperation,
+		Path:      "group-alias/id/" + groupAliasID,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+
+	group, err := is.memDBGroupByID(groupID, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if group.Alias != nil {
+		t.Fatalf("expected the alias to be removed from the group")
+	}
+	groupAlias, err = is.memDBGroupAliasByID(groupAliasID, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if groupAlias != nil {
+		t.Fatalf("expected the group alias to be deleted")
+	}
+}
+
+func TestIdentityStore_ExternalGroups(t *testing.T) {
+	var resp *logical.Response
+	var err error
+	is, githubAccessor, _ := testIdentityStoreWithGithubAuth(t)
+
+	// The type of a group can't be changed
+	resp, err = is.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "group",
+		Data: map[string]interface{}{
+			"type": "external",
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+	groupID := resp.Data["id"].(string)
+
+	resp, err = is.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "group/id/" + groupID,
+		Data: map[string]interface{}{
+			"type": "internal",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error changing the group type")
+	}
+
+	// Members of external groups can't be set manually
+	resp, err = is.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "entity",
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+	resp, err = is.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "group/id/" + groupID,
+		Data: map[string]interface{}{
+			"member_entity_ids": resp.Data["id"],
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error setting the members of an external group")
+	}
+
+	resp, err = is.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "group",
+		Data: map[string]interface{}{
+			"type": "invalid",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error for an invalid group type")
+	}
+
+	// Deleting an external group removes its alias
+	resp, err = is.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "group-alias",
+		Data: map[string]interface{}{
+			"name":           "testgroupalias",
+			"mount_accessor": githubAccessor,
+			"canonical_id":   groupID,
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+	groupAliasID := resp.Data["id"].(string)
+
+	resp, err = is.HandleRequest(&logical.Request{
+		Operation: logical.DeleteOperation,
+		Path:      "group/id/" + groupID,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+	groupAlias, err := is.memDBGroupAliasByID(groupAliasID, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if groupAlias != nil {
+		t.Fatalf("expected the group alias to be deleted along with the group")
+	}
+}
+
+func TestIdentityStore_ExternalGroupMemberships_Login(t *testing.T) {
+	noop := &NoopBackend{
+		Login: []string{"login"},
+	}
+	c, _, _ := TestCoreUnsealed(t)
+	c.credentialBackends["noop"] = func(*logical.BackendConfig) (logical.Backend, error) {
+		return noop, nil
+	}
+
+	me := &MountEntry{
+		Table: credentialTableType,
+		Path:  "noop/",
+		Type:  "noop",
+	}
+	err := c.enableCredential(me)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	is := c.identityStore
+
+	// Create two external groups tied to groups of the auth backend
+	var groupIDs []string
+	for _, name := range []string{"devs", "ops"} {
+		resp, err := is.HandleRequest(&logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "group",
+			Data: map[string]interface{}{
+				"type":     "external",
+				"policies": name + "-policy",
+			},
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+		}
+		groupID := resp.Data["id"].(string)
+		groupIDs = append(groupIDs, groupID)
+
+		resp, err = is.HandleRequest(&logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "group-alias",
+			Data: map[string]interface{}{
+				"name":           name,
+				"mount_accessor": me.Accessor,
+				"canonical_id":   groupID,
+			},
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+		}
+	}
+
+	login := func(metadata map[string]string, groups ...string) string {
+		auth := &logical.Auth{
+			Policies: []string{"default"},
+			Alias: &logical.Alias{
+				Name:     "testuser",
+				Metadata: metadata,
+			},
+		}
+		for _, group := range groups {
+			auth.GroupAliases = append(auth.GroupAliases, &logical.Alias{
+				Name: group,
+			})
+		}
+		noop.Response = &logical.Response{
+			Auth: auth,
+		}
+
+		resp, err := c.HandleRequest(&logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "auth/noop/login",
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+		}
+		if resp.Auth.EntityID == "" {
+			t.Fatalf("missing entity ID")
+		}
+		return resp.Auth.EntityID
+	}
+
+	entityID := login(map[string]string{"role": "a"}, "devs", "ops", "unknown")
+
+	policies, err := is.groupPoliciesByEntityID(entityID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(policies)
+	if !reflect.DeepEqual(policies, []string{"devs-policy", "ops-policy"}) {
+		t.Fatalf("bad: policies: %#v", policies)
+	}
+
+	entity, err := is.memDBEntityByID(entityID, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(entity.Aliases[0].Metadata, map[string]string{"role": "a"}) {
+		t.Fatalf("bad: alias metadata: %#v", entity.Aliases[0].Metadata)
+	}
+
+	// The entity is removed from the groups the auth backend no longer
+	// reports, and the alias metadata is updated
+	if login(map[string]string{"role": "b"}, "ops") != entityID {
+		t.Fatalf("expected the same entity on subsequent logins")
+	}
+
+	group, err := is.memDBGroupByID(groupIDs[0], false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(group.MemberEntityIDs) != 0 {
+		t.Fatalf("bad: members: %#v", group.MemberEntityIDs)
+	}
+	group, err = is.memDBGroupByID(groupIDs[1], false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(group.MemberEntityIDs, []string{entityID}) {
+		t.Fatalf("bad: members: %#v", group.MemberEntityIDs)
+	}
+
+	entity, err = is.memDBEntityByID(entityID, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(entity.Aliases[0].Metadata, map[string]string{"role": "b"}) {
+		t.Fatalf("bad: alias metadata: %#v", entity.Aliases[0].Metadata)
+	}
+}
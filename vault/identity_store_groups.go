@@ -11,6 +11,11 @@ import (
 	"github.com/hashicorp/vault/logical/framework"
 )
 
+const (
+	groupTypeInternal = "internal"
+	groupTypeExternal = "external"
+)
+
 func groupPaths(i *IdentityStore) []*framework.Path {
 	return []*framework.Path{
 		{
@@ -40,6 +45,10 @@ func groupPaths(i *IdentityStore) []*framework.Path {
 					Type:        framework.TypeCommaStringSlice,
 					Description: "Entity IDs to be assigned as group members.",
 				},
+				"type": {
+					Type:        framework.TypeString,
+					Description: "Type of the group, 'internal' or 'external'. Defaults to 'internal'",
+				},
 			},
 			Callbacks: map[logical.Operation]framework.OperationFunc{
 				logical.UpdateOperation: i.pathGroupRegister,
@@ -75,6 +84,10 @@ func groupPaths(i *IdentityStore) []*framework.Path {
 					Type:        framework.TypeCommaStringSlice,
 					Description: "Entity IDs to be assigned as group members.",
 				},
+				"type": {
+					Type:        framework.TypeString,
+					Description: "Type of the group, 'internal' or 'external'. Defaults to 'internal'",
+				},
 			},
 			Callbacks: map[logical.Operation]framework.OperationFunc{
 				logical.UpdateOperation: i.pathGroupIDUpdate,
@@ -163,6 +176,24 @@ func (i *IdentityStore) handleGroupUpdateCommon(req *logical.Request, d *framewo
 		group.Name = groupName
 	}
 
+	// The type of an existing group can't be changed; groups created before
+	// group types were introduced are internal groups
+	currentGroupType := group.Type
+	if currentGroupType == "" {
+		currentGroupType = groupTypeInternal
+	}
+	groupType := d.Get("type").(string)
+	switch {
+	case groupType == "":
+		group.Type = currentGroupType
+	case groupType != groupTypeInternal && groupType != groupTypeExternal:
+		return logical.ErrorResponse(fmt.Sprintf("invalid group type %q", groupType)), nil
+	case !newGroup && groupType != currentGroupType:
+		return logical.ErrorResponse("group type cannot be changed"), nil
+	default:
+		group.Type = groupType
+	}
+
 	metadataRaw, ok := d.GetOk("metadata")
 	if ok {
 		group.Metadata, err = parseMetadata(metadataRaw.([]string))
@@ -173,6 +204,9 @@ func (i *IdentityStore) handleGroupUpdateCommon(req *logical.Request, d *framewo
 
 	memberEntityIDsRaw, ok := d.GetOk("member_entity_ids")
 	if ok {
+		if group.Type == groupTypeExternal {
+			return logical.ErrorResponse("member entities can't be set manually for external groups"), nil
+		}
 		group.MemberEntityIDs = memberEntityIDsRaw.([]string)
 		if len(group.MemberEntityIDs) > 512 {
 			return logical.ErrorResponse("member entity IDs exceeding the limit of 512"), nil
@@ -230,6 +264,25 @@ func (i *IdentityStore) handleGroupReadCommon(group *identity.Group) (*logical.R
 	respData["creation_time"] = ptypes.TimestampString(group.CreationTime)
 	respData["last_update_time"] = ptypes.TimestampString(group.LastUpdateTime)
 	respData["modify_index"] = group.ModifyIndex
+	respData["type"] = group.Type
+	if respData["type"] == "" {
+		respData["type"] = groupTypeInternal
+	}
+
+	aliasMap := map[string]interface{}{}
+	if group.Alias != nil {
+		aliasMap["id"] = group.Alias.ID
+		aliasMap["canonical_id"] = group.Alias.CanonicalID
+		aliasMap["mount_type"] = group.Alias.MountType
+		aliasMap["mount_accessor"] = group.Alias.MountAccessor
+		aliasMap["mount_path"] = group.Alias.MountPath
+		aliasMap["metadata"] = group.Alias.Metadata
+		aliasMap["name"] = group.Alias.Name
+		aliasMap["merged_from_entity_ids"] = group.Alias.MergedFromEntityIDs
+		aliasMap["creation_time"] = ptypes.TimestampString(group.Alias.CreationTime)
+		aliasMap["last_update_time"] = ptypes.TimestampString(group.Alias.LastUpdateTime)
+	}
+	respData["alias"] = aliasMap
 
 	memberGroupIDs, err := i.memberGroupIDsByID(group.ID)
 	if err != nil {
@@ -15,6 +15,7 @@ func identityStoreSchema() *memdb.DBSchema {
 		entityTableSchema,
 		aliasesTableSchema,
 		groupTableSchema,
+		groupAliasesTableSchema,
 	}
 
 	for _, schemaFunc := range schemas {
@@ -79,6 +80,42 @@ func aliasesTableSchema() *memdb.TableSchema {
 	}
 }
 
+func groupAliasesTableSchema() *memdb.TableSchema {
+	return &memdb.TableSchema{
+		Name: "group_aliases",
+		Indexes: map[string]*memdb.IndexSchema{
+			"id": &memdb.IndexSchema{
+				Name:   "id",
+				Unique: true,
+				Indexer: &memdb.StringFieldIndex{
+					Field: "ID",
+				},
+			},
+			"canonical_id": &memdb.IndexSchema{
+				Name:   "canonical_id",
+				Unique: true,
+				Indexer: &memdb.StringFieldIndex{
+					Field: "CanonicalID",
+				},
+			},
+			"factors": &memdb.IndexSchema{
+				Name:   "factors",
+				Unique: true,
+				Indexer: &memdb.CompoundIndex{
+					Indexes: []memdb.Indexer{
+						&memdb.StringFieldIndex{
+							Field: "MountAccessor",
+						},
+						&memdb.StringFieldIndex{
+							Field: "Name",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
 func entityTableSchema() *memdb.TableSchema {
 	return &memdb.TableSchema{
 		Name: "entities",
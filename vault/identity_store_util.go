@@ -13,6 +13,7 @@ import (
 	"github.com/hashicorp/vault/helper/locksutil"
 	"github.com/hashicorp/vault/helper/storagepacker"
 	"github.com/hashicorp/vault/helper/strutil"
+	"github.com/hashicorp/vault/logical"
 )
 
 // parseMetadata takes in a slice of string and parses each item as a key value pair separated by an '=' sign.
@@ -39,14 +40,30 @@ func (c *Core) loadIdentityStoreArtifacts() error {
 		return fmt.Errorf("identity store is not setup")
 	}
 
-	err = c.identityStore.loadEntities()
-	if err != nil {
-		return err
+	iStores := []*IdentityStore{c.identityStore}
+
+	// Each namespace has an identity store of its own
+	c.namespacesLock.RLock()
+	for _, ns := range c.namespaces {
+		iStore := c.namespaceIdentityStore(ns)
+		if iStore == nil {
+			c.namespacesLock.RUnlock()
+			return fmt.Errorf("identity store of namespace %q is not setup", ns.Path)
+		}
+		iStores = append(iStores, iStore)
 	}
+	c.namespacesLock.RUnlock()
 
-	err = c.identityStore.loadGroups()
-	if err != nil {
-		return err
+	for _, iStore := range iStores {
+		err = iStore.loadEntities()
+		if err != nil {
+			return err
+		}
+
+		err = iStore.loadGroups()
+		if err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -1594,6 +1611,18 @@ func (i *IdentityStore) memDBUpsertGroupInTxn(txn *memdb.Txn, group *identity.Gr
 		return fmt.Errorf("failed to update group into memdb: %v", err)
 	}
 
+	// Keep the index of the group alias in sync with the group
+	err = i.memDBDeleteGroupAliasByCanonicalIDInTxn(txn, group.ID)
+	if err != nil {
+		return err
+	}
+
+	if group.Alias != nil {
+		if err := txn.Insert("group_aliases", group.Alias); err != nil {
+			return fmt.Errorf("failed to update group alias into memdb: %v", err)
+		}
+	}
+
 	return nil
 }
 
@@ -1664,7 +1693,7 @@ func (i *IdentityStore) memDBDeleteGroupByIDInTxn(txn *memdb.Txn, groupID string
 		return fmt.Errorf("failed to delete group from memdb: %v", err)
 	}
 
-	return nil
+	return i.memDBDeleteGroupAliasByCanonicalIDInTxn(txn, group.ID)
 }
 
 func (i *IdentityStore) deleteGroupByName(groupName string) error {
@@ -1735,7 +1764,7 @@ func (i *IdentityStore) memDBDeleteGroupByNameInTxn(txn *memdb.Txn, groupName st
 		return fmt.Errorf("failed to delete group from memdb: %v", err)
 	}
 
-	return nil
+	return i.memDBDeleteGroupAliasByCanonicalIDInTxn(txn, group.ID)
 }
 
 func (i *IdentityStore) memDBGroupByIDInTxn(txn *memdb.Txn, groupID string, clone bool) (*identity.Group, error) {
@@ -1876,6 +1905,35 @@ func (i *IdentityStore) memDBGroupsByMemberEntityID(entityID string, clone bool)
 	return groups, nil
 }
 
+func (i *IdentityStore) memDBGroupsByMemberEntityIDInTxn(txn *memdb.Txn, entityID string, clone bool) ([]*identity.Group, error) {
+	if entityID == "" {
+		return nil, fmt.Errorf("missing entity ID")
+	}
+
+	if txn == nil {
+		return nil, fmt.Errorf("txn is nil")
+	}
+
+	groupsIter, err := txn.Get("groups", "member_entity_ids", entityID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lookup groups using entity ID: %v", err)
+	}
+
+	var groups []*identity.Group
+	for group := groupsIter.Next(); group != nil; group = groupsIter.Next() {
+		entry := group.(*identity.Group)
+		if clone {
+			entry, err = entry.Clone()
+			if err != nil {
+				return nil, err
+			}
+		}
+		groups = append(groups, entry)
+	}
+
+	return groups, nil
+}
+
 func (i *IdentityStore) groupPoliciesByEntityID(entityID string) ([]string, error) {
 	if entityID == "" {
 		return nil, fmt.Errorf("empty entity ID")
@@ -1889,7 +1947,7 @@ func (i *IdentityStore) groupPoliciesByEntityID(entityID string) ([]string, erro
 	visited := make(map[string]bool)
 	var policies []string
 	for _, group := range groups {
-		policies, err = i.collectPoliciesReverseDFS(group, visited, nil)
+		policies, err = i.collectPoliciesReverseDFS(group, visited, policies)
 		if err != nil {
 			return nil, err
 		}
@@ -1911,7 +1969,7 @@ func (i *IdentityStore) transitiveGroupsByEntityID(entityID string) ([]*identity
 	visited := make(map[string]bool)
 	var tGroups []*identity.Group
 	for _, group := range groups {
-		tGroups, err = i.collectGroupsReverseDFS(group, visited, nil)
+		tGroups, err = i.collectGroupsReverseDFS(group, visited, tGroups)
 		if err != nil {
 			return nil, err
 		}
@@ -2120,3 +2178,201 @@ func (i *IdentityStore) memDBGroupsByBucketEntryKeyHashInTxn(txn *memdb.Txn, has
 
 	return groups, nil
 }
+
+func (i *IdentityStore) memDBGroupAliasByIDInTxn(txn *memdb.Txn, aliasID string, clone bool) (*identity.Alias, error) {
+	if aliasID == "" {
+		return nil, fmt.Errorf("missing group alias ID")
+	}
+
+	if txn == nil {
+		return nil, fmt.Errorf("txn is nil")
+	}
+
+	aliasRaw, err := txn.First("group_aliases", "id", aliasID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch group alias from memdb using alias ID: %v", err)
+	}
+
+	if aliasRaw == nil {
+		return nil, nil
+	}
+
+	alias, ok := aliasRaw.(*identity.Alias)
+	if !ok {
+		return nil, fmt.Errorf("failed to declare the type of fetched group alias")
+	}
+
+	if clone {
+		return alias.Clone()
+	}
+
+	return alias, nil
+}
+
+func (i *IdentityStore) memDBGroupAliasByID(aliasID string, clone bool) (*identity.Alias, error) {
+	if aliasID == "" {
+		return nil, fmt.Errorf("missing group alias ID")
+	}
+
+	txn := i.db.Txn(false)
+
+	return i.memDBGroupAliasByIDInTxn(txn, aliasID, clone)
+}
+
+func (i *IdentityStore) memDBGroupAliasByFactorsInTxn(txn *memdb.Txn, mountAccessor, aliasName string, clone bool) (*identity.Alias, error) {
+	if aliasName == "" {
+		return nil, fmt.Errorf("missing group alias name")
+	}
+
+	if mountAccessor == "" {
+		return nil, fmt.Errorf("missing mount accessor")
+	}
+
+	if txn == nil {
+		return nil, fmt.Errorf("txn is nil")
+	}
+
+	aliasRaw, err := txn.First("group_aliases", "factors", mountAccessor, aliasName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch group alias from memdb using factors: %v", err)
+	}
+
+	if aliasRaw == nil {
+		return nil, nil
+	}
+
+	alias, ok := aliasRaw.(*identity.Alias)
+	if !ok {
+		return nil, fmt.Errorf("failed to declare the type of fetched group alias")
+	}
+
+	if clone {
+		return alias.Clone()
+	}
+
+	return alias, nil
+}
+
+func (i *IdentityStore) memDBGroupAliasByFactors(mountAccessor, aliasName string, clone bool) (*identity.Alias, error) {
+	txn := i.db.Txn(false)
+
+	return i.memDBGroupAliasByFactorsInTxn(txn, mountAccessor, aliasName, clone)
+}
+
+func (i *IdentityStore) memDBDeleteGroupAliasByCanonicalIDInTxn(txn *memdb.Txn, groupID string) error {
+	if txn == nil {
+		return fmt.Errorf("txn is nil")
+	}
+
+	aliasRaw, err := txn.First("group_aliases", "canonical_id", groupID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch group alias from memdb using canonical ID: %v", err)
+	}
+
+	if aliasRaw == nil {
+		return nil
+	}
+
+	err = txn.Delete("group_aliases", aliasRaw)
+	if err != nil {
+		return fmt.Errorf("failed to delete group alias from memdb: %v", err)
+	}
+
+	return nil
+}
+
+func (i *IdentityStore) memDBGroupAliases(ws memdb.WatchSet) (memdb.ResultIterator, error) {
+	txn := i.db.Txn(false)
+
+	iter, err := txn.Get("group_aliases", "id")
+	if err != nil {
+		return nil, err
+	}
+
+	ws.Add(iter.WatchCh())
+
+	return iter, nil
+}
+
+// refreshExternalGroupMembershipsByEntityID updates the memberships of the
+// given entity in the external groups whose aliases belong to the given
+// mount. The entity is added to the groups matching the group aliases
+// returned by the auth backend, and removed from the other external groups
+// of that mount.
+func (i *IdentityStore) refreshExternalGroupMembershipsByEntityID(entityID string, groupAliases []*logical.Alias, mountAccessor string) error {
+	if entityID == "" {
+		return fmt.Errorf("empty entity ID")
+	}
+
+	i.groupLock.Lock()
+	defer i.groupLock.Unlock()
+
+	txn := i.db.Txn(true)
+	defer txn.Abort()
+
+	newGroupIDs := make(map[string]bool)
+	for _, groupAlias := range groupAliases {
+		if groupAlias == nil || groupAlias.Name == "" {
+			continue
+		}
+
+		alias, err := i.memDBGroupAliasByFactorsInTxn(txn, mountAccessor, groupAlias.Name, false)
+		if err != nil {
+			return err
+		}
+		if alias == nil {
+			continue
+		}
+		newGroupIDs[alias.CanonicalID] = true
+	}
+
+	existingGroups, err := i.memDBGroupsByMemberEntityIDInTxn(txn, entityID, false)
+	if err != nil {
+		return err
+	}
+
+	// Remove the entity from the external groups of this mount which are no
+	// longer reported by the auth backend
+	for _, group := range existingGroups {
+		if group.Type != groupTypeExternal || group.Alias == nil || group.Alias.MountAccessor != mountAccessor {
+			continue
+		}
+		if newGroupIDs[group.ID] {
+			delete(newGroupIDs, group.ID)
+			continue
+		}
+
+		group, err = group.Clone()
+		if err != nil {
+			return err
+		}
+		group.MemberEntityIDs = strutil.StrListDelete(group.MemberEntityIDs, entityID)
+
+		err = i.upsertGroupInTxn(txn, group, true)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Add the entity to the groups it is not yet a member of
+	for groupID := range newGroupIDs {
+		group, err := i.memDBGroupByIDInTxn(txn, groupID, true)
+		if err != nil {
+			return err
+		}
+		if group == nil || group.Type != groupTypeExternal {
+			continue
+		}
+
+		group.MemberEntityIDs = append(group.MemberEntityIDs, entityID)
+
+		err = i.upsertGroupInTxn(txn, group, true)
+		if err != nil {
+			return err
+		}
+	}
+
+	txn.Commit()
+
+	return nil
+}
@@ -127,6 +127,13 @@ func (c *Core) Initialize(initParams *InitParams) (*InitResult, error) {
 		return nil, ErrAlreadyInit
 	}
 
+	// With raft storage nothing can be written until this node has formed a
+	// cluster of its own
+	if err := c.bootstrapRaft(); err != nil {
+		c.logger.Error("core: failed to bootstrap raft storage", "error", err)
+		return nil, fmt.Errorf("error bootstrapping raft storage: %v", err)
+	}
+
 	err = c.seal.Init()
 	if err != nil {
 		c.logger.Error("core: failed to initialize seal", "error", err)
@@ -164,6 +171,11 @@ func (c *Core) Initialize(initParams *InitParams) (*InitResult, error) {
 		}
 	}()
 
+	if err := c.initRaftTLS(); err != nil {
+		c.logger.Error("core: failed to create raft tls key", "error", err)
+		return nil, fmt.Errorf("error creating raft tls key: %v", err)
+	}
+
 	err = c.seal.SetBarrierConfig(barrierConfig)
 	if err != nil {
 		c.logger.Error("core: failed to save barrier configuration", "error", err)
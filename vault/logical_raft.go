@@ -0,0 +1,216 @@
+package vault
+
+import (
+	"bytes"
+	"encoding/base64"
+	"net/http"
+	"strings"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+	"github.com/hashicorp/vault/physical/raft"
+)
+
+// raftStoragePaths returns the paths used to manage the integrated raft
+// storage. They are only registered when raft storage is in use.
+func (b *SystemBackend) raftStoragePaths() []*framework.Path {
+	return []*framework.Path{
+		&framework.Path{
+			Pattern: "storage/raft/bootstrap",
+
+			Fields: map[string]*framework.FieldSchema{
+				"server_id": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "The raft node ID of the joining server.",
+				},
+				"cluster_addr": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "The cluster address of the joining server, in host:port form.",
+				},
+			},
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.UpdateOperation: b.handleRaftBootstrapAnswer,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["raft-bootstrap"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["raft-bootstrap"][1]),
+		},
+
+		&framework.Path{
+			Pattern: "storage/raft/configuration",
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ReadOperation: b.handleRaftConfigurationRead,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["raft-configuration"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["raft-configuration"][1]),
+		},
+
+		&framework.Path{
+			Pattern: "storage/raft/remove-peer",
+
+			Fields: map[string]*framework.FieldSchema{
+				"server_id": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "The raft node ID of the server to remove.",
+				},
+			},
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.UpdateOperation: b.handleRaftRemovePeerUpdate,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["raft-remove-peer"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["raft-remove-peer"][1]),
+		},
+
+		&framework.Path{
+			Pattern: "storage/raft/snapshot",
+
+			Fields: map[string]*framework.FieldSchema{
+				"snapshot": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "Base64 encoded snapshot to restore, as returned by a read of this path.",
+				},
+			},
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ReadOperation:   b.handleRaftSnapshotRead,
+				logical.UpdateOperation: b.handleRaftSnapshotRestore,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["raft-snapshot"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["raft-snapshot"][1]),
+		},
+	}
+}
+
+// handleRaftBootstrapAnswer adds a joining node to the cluster and hands it
+// the key it needs to talk to the other nodes
+func (b *SystemBackend) handleRaftBootstrapAnswer(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	serverID := d.Get("server_id").(string)
+	if serverID == "" {
+		return logical.ErrorResponse("no server id provided"), logical.ErrInvalidRequest
+	}
+	clusterAddr := d.Get("cluster_addr").(string)
+	if clusterAddr == "" {
+		return logical.ErrorResponse("no cluster address provided"), logical.ErrInvalidRequest
+	}
+
+	tlsKey, peers, err := b.Core.raftBootstrapAnswer(serverID, clusterAddr)
+	if err != nil {
+		return handleError(err)
+	}
+
+	b.Backend.Logger().Info("sys: added raft peer", "server_id", serverID, "cluster_addr", clusterAddr)
+
+	// The key material is passed as strings so that it is hashed in audit
+	// logs
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"tls_key": map[string]interface{}{
+				"id":           tlsKey.ID,
+				"cluster_cert": base64.StdEncoding.EncodeToString(tlsKey.CertBytes),
+				"cluster_key":  base64.StdEncoding.EncodeToString(tlsKey.KeyBytes),
+			},
+			"peers": peers,
+		},
+	}, nil
+}
+
+// handleRaftConfigurationRead returns the servers in the raft configuration
+func (b *SystemBackend) handleRaftConfigurationRead(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	servers, err := b.Core.raftStorage.GetConfiguration()
+	if err != nil {
+		return handleError(err)
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"servers": servers,
+		},
+	}, nil
+}
+
+// handleRaftRemovePeerUpdate removes a server from the raft configuration
+func (b *SystemBackend) handleRaftRemovePeerUpdate(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	serverID := d.Get("server_id").(string)
+	if serverID == "" {
+		return logical.ErrorResponse("no server id provided"), logical.ErrInvalidRequest
+	}
+
+	if err := b.Core.raftStorage.RemovePeer(serverID); err != nil {
+		return handleError(err)
+	}
+
+	b.Backend.Logger().Info("sys: removed raft peer", "server_id", serverID)
+
+	return nil, nil
+}
+
+// handleRaftSnapshotRead returns a snapshot of the storage as a gzipped body
+func (b *SystemBackend) handleRaftSnapshotRead(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	var buf bytes.Buffer
+	if err := b.Core.raftStorage.Snapshot(&buf); err != nil {
+		return handleError(err)
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			logical.HTTPContentType: "application/gzip",
+			logical.HTTPRawBody:     buf.Bytes(),
+			logical.HTTPStatusCode:  http.StatusOK,
+		},
+	}, nil
+}
+
+// handleRaftSnapshotRestore replaces the storage of the whole cluster with
+// the given snapshot. The active node then steps down so that its state is
+// rebuilt from the restored data.
+func (b *SystemBackend) handleRaftSnapshotRestore(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	snapshotB64 := d.Get("snapshot").(string)
+	if snapshotB64 == "" {
+		return logical.ErrorResponse("no snapshot provided"), logical.ErrInvalidRequest
+	}
+
+	snapshot, err := base64.StdEncoding.DecodeString(snapshotB64)
+	if err != nil {
+		return logical.ErrorResponse("snapshot must be base64 encoded"), logical.ErrInvalidRequest
+	}
+
+	if err := b.Core.raftStorage.RestoreSnapshot(bytes.NewReader(snapshot)); err != nil {
+		if err == raft.ErrNotLeader {
+			return handleError(err)
+		}
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
+	// The restored data may use different encryption keys
+	if err := b.Core.barrier.ReloadKeyring(); err != nil {
+		b.Backend.Logger().Error("sys: failed to reload keyring after snapshot restore", "error", err)
+		go b.Core.Shutdown()
+		return nil, err
+	}
+	if err := b.Core.barrier.ReloadMasterKey(); err != nil {
+		b.Backend.Logger().Error("sys: failed to reload master key after snapshot restore", "error", err)
+		go b.Core.Shutdown()
+		return nil, err
+	}
+
+	b.Backend.Logger().Info("sys: restored raft snapshot, stepping down")
+
+	select {
+	case b.Core.manualStepDownCh <- struct{}{}:
+	default:
+		b.Backend.Logger().Warn("sys: manual step-down operation already queued")
+	}
+
+	return nil, nil
+}
@@ -80,12 +80,16 @@ func NewSystemBackend(core *Core) *SystemBackend {
 				"leases/revoke-prefix/*",
 				"leases/revoke-force/*",
 				"leases/lookup/*",
+				"leases/irrevocable",
+				"storage/raft/*",
+				"quotas/*",
 			},
 
 			Unauthenticated: []string{
 				"wrapping/lookup",
 				"wrapping/pubkey",
 				"replication/status",
+				"mfa/validate",
 			},
 		},
 
@@ -243,6 +247,10 @@ func NewSystemBackend(core *Core) *SystemBackend {
 						Type:        framework.TypeString,
 						Description: strings.TrimSpace(sysHelp["auth_desc"][0]),
 					},
+					"token_type": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["token_type"][0]),
+					},
 				},
 				Callbacks: map[logical.Operation]framework.OperationFunc{
 					logical.ReadOperation:   b.handleAuthTuneRead,
@@ -476,6 +484,17 @@ func NewSystemBackend(core *Core) *SystemBackend {
 				HelpDescription: strings.TrimSpace(sysHelp["revoke-prefix"][1]),
 			},
 
+			&framework.Path{
+				Pattern: "leases/irrevocable$",
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ReadOperation: b.handleLeasesIrrevocable,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["leases-irrevocable"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["leases-irrevocable"][1]),
+			},
+
 			&framework.Path{
 				Pattern: "leases/tidy$",
 
@@ -949,10 +968,35 @@ func NewSystemBackend(core *Core) *SystemBackend {
 				HelpSynopsis:    strings.TrimSpace(sysHelp["random"][0]),
 				HelpDescription: strings.TrimSpace(sysHelp["random"][1]),
 			},
+
+			&framework.Path{
+				Pattern: "metrics",
+				Fields: map[string]*framework.FieldSchema{
+					"format": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: `Format to return the metrics in. Can be "prometheus" or "json". Defaults to "prometheus" if Prometheus metrics are enabled, and "json" otherwise.`,
+					},
+				},
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ReadOperation: b.handleMetricsRead,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["metrics"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["metrics"][1]),
+			},
 		},
 	}
 
 	b.Backend.Paths = append(b.Backend.Paths, replicationPaths(b)...)
+	b.Backend.Paths = append(b.Backend.Paths, b.namespacePaths()...)
+	b.Backend.Paths = append(b.Backend.Paths, b.quotaPaths()...)
+	b.Backend.Paths = append(b.Backend.Paths, b.mfaPaths()...)
+	b.Backend.Paths = append(b.Backend.Paths, b.controlGroupPaths()...)
+
+	if core.raftStorage != nil {
+		b.Backend.Paths = append(b.Backend.Paths, b.raftStoragePaths()...)
+	}
 
 	if core.rawEnabled {
 		b.Backend.Paths = append(b.Backend.Paths, &framework.Path{
@@ -1229,7 +1273,7 @@ func (b *SystemBackend) handleCapabilities(req *logical.Request, d *framework.Fi
 	if token == "" {
 		token = req.ClientToken
 	}
-	capabilities, err := b.Core.Capabilities(token, d.Get("path").(string))
+	capabilities, err := b.Core.Capabilities(token, b.namespace(req).Path+d.Get("path").(string))
 	if err != nil {
 		return nil, err
 	}
@@ -1254,7 +1298,7 @@ func (b *SystemBackend) handleCapabilitiesAccessor(req *logical.Request, d *fram
 		return nil, err
 	}
 
-	capabilities, err := b.Core.Capabilities(aEntry.TokenID, d.Get("path").(string))
+	capabilities, err := b.Core.Capabilities(aEntry.TokenID, b.namespace(req).Path+d.Get("path").(string))
 	if err != nil {
 		return nil, err
 	}
@@ -1348,11 +1392,16 @@ func (b *SystemBackend) handleMountTable(
 	b.Core.mountsLock.RLock()
 	defer b.Core.mountsLock.RUnlock()
 
+	ns := b.namespace(req)
 	resp := &logical.Response{
 		Data: make(map[string]interface{}),
 	}
 
 	for _, entry := range b.Core.mounts.Entries {
+		if entry.NamespaceID != ns.ID {
+			continue
+		}
+
 		// Populate mount info
 		info := map[string]interface{}{
 			"type":        entry.Type,
@@ -1480,6 +1529,7 @@ func (b *SystemBackend) handleMount(
 		Description: description,
 		Config:      config,
 		Local:       local,
+		NamespaceID: b.namespace(req).ID,
 	}
 
 	// Attempt mount
@@ -1506,10 +1556,10 @@ func handleError(
 func (b *SystemBackend) handleUnmount(
 	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
 	path := data.Get("path").(string)
-	path = sanitizeMountPath(path)
+	path = b.namespace(req).Path + sanitizeMountPath(path)
 
 	repState := b.Core.replicationState
-	entry := b.Core.router.MatchingMountEntry(path)
+	entry := b.namespaceMountEntry(req, path)
 	if entry != nil && !entry.Local && repState.HasState(consts.ReplicationPerformanceSecondary) {
 		return logical.ErrorResponse("cannot unmount a non-local mount on a replication secondary"), nil
 	}
@@ -1517,7 +1567,7 @@ func (b *SystemBackend) handleUnmount(
 	// We return success when the mount does not exists to not expose if the
 	// mount existed or not
 	match := b.Core.router.MatchingMount(path)
-	if match == "" || path != match {
+	if entry == nil || match == "" || path != match {
 		return nil, nil
 	}
 
@@ -1544,11 +1594,15 @@ func (b *SystemBackend) handleRemount(
 			logical.ErrInvalidRequest
 	}
 
-	fromPath = sanitizeMountPath(fromPath)
-	toPath = sanitizeMountPath(toPath)
+	ns := b.namespace(req)
+	fromPath = ns.Path + sanitizeMountPath(fromPath)
+	toPath = ns.Path + sanitizeMountPath(toPath)
 
-	entry := b.Core.router.MatchingMountEntry(fromPath)
-	if entry != nil && !entry.Local && repState.HasState(consts.ReplicationPerformanceSecondary) {
+	entry := b.namespaceMountEntry(req, fromPath)
+	if entry == nil {
+		return logical.ErrorResponse(fmt.Sprintf("no matching mount at '%s'", strings.TrimPrefix(fromPath, ns.Path))), logical.ErrInvalidRequest
+	}
+	if !entry.Local && repState.HasState(consts.ReplicationPerformanceSecondary) {
 		return logical.ErrorResponse("cannot remount a non-local mount on a replication secondary"), nil
 	}
 
@@ -1570,7 +1624,7 @@ func (b *SystemBackend) handleAuthTuneRead(
 				"path must be specified as a string"),
 			logical.ErrInvalidRequest
 	}
-	return b.handleTuneReadCommon("auth/" + path)
+	return b.handleTuneReadCommon(req, "auth/"+path)
 }
 
 // handleMountTuneRead is used to get config settings on a backend
@@ -1586,12 +1640,12 @@ func (b *SystemBackend) handleMountTuneRead(
 	// This call will read both logical backend's configuration as well as auth backends'.
 	// Retaining this behavior for backward compatibility. If this behavior is not desired,
 	// an error can be returned if path has a prefix of "auth/".
-	return b.handleTuneReadCommon(path)
+	return b.handleTuneReadCommon(req, path)
 }
 
 // handleTuneReadCommon returns the config settings of a path
-func (b *SystemBackend) handleTuneReadCommon(path string) (*logical.Response, error) {
-	path = sanitizeMountPath(path)
+func (b *SystemBackend) handleTuneReadCommon(req *logical.Request, path string) (*logical.Response, error) {
+	path = b.namespace(req).Path + sanitizeMountPath(path)
 
 	sysView := b.Core.router.MatchingSystemView(path)
 	if sysView == nil {
@@ -1599,7 +1653,7 @@ func (b *SystemBackend) handleTuneReadCommon(path string) (*logical.Response, er
 		return handleError(fmt.Errorf("sys: cannot fetch sysview for path %s", path))
 	}
 
-	mountEntry := b.Core.router.MatchingMountEntry(path)
+	mountEntry := b.namespaceMountEntry(req, path)
 	if mountEntry == nil {
 		b.Backend.Logger().Error("sys: cannot fetch mount entry", "path", path)
 		return handleError(fmt.Errorf("sys: cannot fetch mount entry for path %s", path))
@@ -1613,6 +1667,10 @@ func (b *SystemBackend) handleTuneReadCommon(path string) (*logical.Response, er
 		},
 	}
 
+	if mountEntry.Table == credentialTableType {
+		resp.Data["token_type"] = configTokenTypeString(mountEntry.Config.TokenType)
+	}
+
 	return resp, nil
 }
 
@@ -1624,7 +1682,7 @@ func (b *SystemBackend) handleAuthTuneWrite(
 		return logical.ErrorResponse("path must be specified as a string"),
 			logical.ErrInvalidRequest
 	}
-	return b.handleTuneWriteCommon("auth/"+path, data)
+	return b.handleTuneWriteCommon(req, "auth/"+path, data)
 }
 
 // handleMountTuneWrite is used to set config settings on a backend
@@ -1638,25 +1696,26 @@ func (b *SystemBackend) handleMountTuneWrite(
 	// This call will write both logical backend's configuration as well as auth backends'.
 	// Retaining this behavior for backward compatibility. If this behavior is not desired,
 	// an error can be returned if path has a prefix of "auth/".
-	return b.handleTuneWriteCommon(path, data)
+	return b.handleTuneWriteCommon(req, path, data)
 }
 
 // handleTuneWriteCommon is used to set config settings on a path
 func (b *SystemBackend) handleTuneWriteCommon(
-	path string, data *framework.FieldData) (*logical.Response, error) {
+	req *logical.Request, path string, data *framework.FieldData) (*logical.Response, error) {
 	repState := b.Core.replicationState
 
-	path = sanitizeMountPath(path)
+	relPath := sanitizeMountPath(path)
+	path = b.namespace(req).Path + relPath
 
 	// Prevent protected paths from being changed
 	for _, p := range untunableMounts {
-		if strings.HasPrefix(path, p) {
+		if strings.HasPrefix(relPath, p) {
 			b.Backend.Logger().Error("sys: cannot tune this mount", "path", path)
 			return handleError(fmt.Errorf("sys: cannot tune '%s'", path))
 		}
 	}
 
-	mountEntry := b.Core.router.MatchingMountEntry(path)
+	mountEntry := b.namespaceMountEntry(req, path)
 	if mountEntry == nil {
 		b.Backend.Logger().Error("sys: tune failed: no mount entry found", "path", path)
 		return handleError(fmt.Errorf("sys: tune of path '%s' failed: no mount entry found", path))
@@ -1667,7 +1726,7 @@ func (b *SystemBackend) handleTuneWriteCommon(
 
 	var lock *sync.RWMutex
 	switch {
-	case strings.HasPrefix(path, credentialRoutePrefix):
+	case mountEntry.Table == credentialTableType:
 		lock = &b.Core.authLock
 	default:
 		lock = &b.Core.mountsLock
@@ -1677,7 +1736,7 @@ func (b *SystemBackend) handleTuneWriteCommon(
 	defer lock.Unlock()
 
 	// Check again after grabbing the lock
-	mountEntry = b.Core.router.MatchingMountEntry(path)
+	mountEntry = b.namespaceMountEntry(req, path)
 	if mountEntry == nil {
 		b.Backend.Logger().Error("sys: tune failed: no mount entry found", "path", path)
 		return handleError(fmt.Errorf("sys: tune of path '%s' failed: no mount entry found", path))
@@ -1735,7 +1794,7 @@ func (b *SystemBackend) handleTuneWriteCommon(
 		// Update the mount table
 		var err error
 		switch {
-		case strings.HasPrefix(path, "auth/"):
+		case mountEntry.Table == credentialTableType:
 			err = b.Core.persistAuth(b.Core.auth, mountEntry.Local)
 		default:
 			err = b.Core.persistMounts(b.Core.mounts, mountEntry.Local)
@@ -1749,9 +1808,53 @@ func (b *SystemBackend) handleTuneWriteCommon(
 		}
 	}
 
+	if rawTokenType, ok := data.GetOk("token_type"); ok {
+		tokenType, err := parseMountTokenType(rawTokenType.(string))
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+
+		oldTokenType := mountEntry.Config.TokenType
+		mountEntry.Config.TokenType = tokenType
+
+		// Only auth mounts have the token_type field, so this is always the
+		// auth table
+		if err := b.Core.persistAuth(b.Core.auth, mountEntry.Local); err != nil {
+			mountEntry.Config.TokenType = oldTokenType
+			return handleError(err)
+		}
+		if b.Core.logger.IsInfo() {
+			b.Core.logger.Info("core: mount tuning of token_type successful", "path", path, "token_type", tokenType.String())
+		}
+	}
+
 	return nil, nil
 }
 
+// parseMountTokenType parses the token_type of an auth mount. Besides the
+// types of tokens, default-service and default-batch let the auth backend
+// choose the type, defaulting to the given one.
+func parseMountTokenType(raw string) (logical.TokenType, error) {
+	tokenType, err := logical.ParseTokenType(raw)
+	if err != nil {
+		return logical.TokenTypeDefault, err
+	}
+	if tokenType == logical.TokenTypeDefault {
+		tokenType = logical.TokenTypeDefaultService
+	}
+	return tokenType, nil
+}
+
+// configTokenTypeString returns the name of the token_type of an auth mount
+// or token role, the ones configured before token types existed using
+// default-service
+func configTokenTypeString(tokenType logical.TokenType) string {
+	if tokenType == logical.TokenTypeDefault {
+		tokenType = logical.TokenTypeDefaultService
+	}
+	return tokenType.String()
+}
+
 // handleLease is use to view the metadata for a given LeaseID
 func (b *SystemBackend) handleLeaseLookup(
 	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
@@ -1761,6 +1864,10 @@ func (b *SystemBackend) handleLeaseLookup(
 			logical.ErrInvalidRequest
 	}
 
+	if resp, err := b.checkLeaseNamespace(req, leaseID); err != nil {
+		return resp, err
+	}
+
 	leaseTimes, err := b.Core.expiration.FetchLeaseTimes(leaseID)
 	if err != nil {
 		b.Backend.Logger().Error("sys: error retrieving lease", "lease_id", leaseID, "error", err)
@@ -1789,12 +1896,15 @@ func (b *SystemBackend) handleLeaseLookup(
 		resp.Data["expire_time"] = leaseTimes.ExpireTime
 		resp.Data["ttl"] = leaseTimes.ttl()
 	}
+	if leaseTimes.isIrrevocable() {
+		resp.Data["revoke_error"] = leaseTimes.RevokeErr
+	}
 	return resp, nil
 }
 
 func (b *SystemBackend) handleLeaseLookupList(
 	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	prefix := data.Get("prefix").(string)
+	prefix := b.namespace(req).Path + data.Get("prefix").(string)
 	if prefix != "" && !strings.HasSuffix(prefix, "/") {
 		prefix = prefix + "/"
 	}
@@ -1807,6 +1917,28 @@ func (b *SystemBackend) handleLeaseLookupList(
 	return logical.ListResponse(keys), nil
 }
 
+// handleLeasesIrrevocable lists the leases of the namespace of the request
+// that could not be revoked after the maximum number of attempts
+func (b *SystemBackend) handleLeasesIrrevocable(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	leases := b.Core.expiration.IrrevocableLeases(b.namespace(req).Path)
+
+	leaseInfos := make([]map[string]interface{}, 0, len(leases))
+	for _, le := range leases {
+		leaseInfos = append(leaseInfos, map[string]interface{}{
+			"lease_id":     le.LeaseID,
+			"expire_time":  le.ExpireTime,
+			"revoke_error": le.RevokeErr,
+		})
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"leases": leaseInfos,
+		},
+	}, nil
+}
+
 // handleRenew is used to renew a lease with a given LeaseID
 func (b *SystemBackend) handleRenew(
 	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
@@ -1819,6 +1951,9 @@ func (b *SystemBackend) handleRenew(
 		return logical.ErrorResponse("lease_id must be specified"),
 			logical.ErrInvalidRequest
 	}
+	if resp, err := b.checkLeaseNamespace(req, leaseID); err != nil {
+		return resp, err
+	}
 	incrementRaw := data.Get("increment").(int)
 
 	// Convert the increment
@@ -1846,6 +1981,10 @@ func (b *SystemBackend) handleRevoke(
 			logical.ErrInvalidRequest
 	}
 
+	if resp, err := b.checkLeaseNamespace(req, leaseID); err != nil {
+		return resp, err
+	}
+
 	// Invoke the expiration manager directly
 	if err := b.Core.expiration.Revoke(leaseID); err != nil {
 		b.Backend.Logger().Error("sys: lease revocation failed", "lease_id", leaseID, "error", err)
@@ -1870,7 +2009,7 @@ func (b *SystemBackend) handleRevokeForce(
 func (b *SystemBackend) handleRevokePrefixCommon(
 	req *logical.Request, data *framework.FieldData, force bool) (*logical.Response, error) {
 	// Get all the options
-	prefix := data.Get("prefix").(string)
+	prefix := b.namespace(req).Path + data.Get("prefix").(string)
 
 	// Invoke the expiration manager directly
 	var err error
@@ -1892,10 +2031,15 @@ func (b *SystemBackend) handleAuthTable(
 	b.Core.authLock.RLock()
 	defer b.Core.authLock.RUnlock()
 
+	ns := b.namespace(req)
 	resp := &logical.Response{
 		Data: make(map[string]interface{}),
 	}
 	for _, entry := range b.Core.auth.Entries {
+		if entry.NamespaceID != ns.ID {
+			continue
+		}
+
 		info := map[string]interface{}{
 			"type":        entry.Type,
 			"description": entry.Description,
@@ -1903,6 +2047,7 @@ func (b *SystemBackend) handleAuthTable(
 			"config": map[string]interface{}{
 				"default_lease_ttl": int64(entry.Config.DefaultLeaseTTL.Seconds()),
 				"max_lease_ttl":     int64(entry.Config.MaxLeaseTTL.Seconds()),
+				"token_type":        configTokenTypeString(entry.Config.TokenType),
 			},
 			"local": entry.Local,
 		}
@@ -1960,6 +2105,12 @@ func (b *SystemBackend) handleEnableAuth(
 			logical.ErrInvalidRequest
 	}
 
+	tokenType, err := parseMountTokenType(apiConfig.TokenType)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+	config.TokenType = tokenType
+
 	path = sanitizeMountPath(path)
 
 	// Create the mount entry
@@ -1970,6 +2121,7 @@ func (b *SystemBackend) handleEnableAuth(
 		Description: description,
 		Config:      config,
 		Local:       local,
+		NamespaceID: b.namespace(req).ID,
 	}
 
 	// Attempt enabling
@@ -1986,10 +2138,11 @@ func (b *SystemBackend) handleDisableAuth(
 	path := data.Get("path").(string)
 	path = sanitizeMountPath(path)
 
-	fullPath := credentialRoutePrefix + path
+	ns := b.namespace(req)
+	fullPath := ns.Path + credentialRoutePrefix + path
 
 	repState := b.Core.replicationState
-	entry := b.Core.router.MatchingMountEntry(fullPath)
+	entry := b.namespaceMountEntry(req, fullPath)
 	if entry != nil && !entry.Local && repState.HasState(consts.ReplicationPerformanceSecondary) {
 		return logical.ErrorResponse("cannot unmount a non-local mount on a replication secondary"), nil
 	}
@@ -1997,12 +2150,12 @@ func (b *SystemBackend) handleDisableAuth(
 	// We return success when the mount does not exists to not expose if the
 	// mount existed or not
 	match := b.Core.router.MatchingMount(fullPath)
-	if match == "" || fullPath != match {
+	if entry == nil || match == "" || fullPath != match {
 		return nil, nil
 	}
 
 	// Attempt disable
-	if err := b.Core.disableCredential(path); err != nil {
+	if err := b.Core.disableNamespaceCredential(ns, path); err != nil {
 		b.Backend.Logger().Error("sys: disable auth mount failed", "path", path, "error", err)
 		return handleError(err)
 	}
@@ -2013,7 +2166,7 @@ func (b *SystemBackend) handleDisableAuth(
 func (b *SystemBackend) handlePolicyList(
 	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
 	// Get all the configured policies
-	policies, err := b.Core.policyStore.ListPolicies(PolicyTypeACL)
+	policies, err := b.policyStore(req).ListPolicies(PolicyTypeACL)
 
 	// Add the special "root" policy
 	policies = append(policies, "root")
@@ -2027,7 +2180,7 @@ func (b *SystemBackend) handlePolicyList(
 
 func (b *SystemBackend) handlePoliciesList(policyType PolicyType) func(*logical.Request, *framework.FieldData) (*logical.Response, error) {
 	return func(req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-		policies, err := b.Core.policyStore.ListPolicies(policyType)
+		policies, err := b.policyStore(req).ListPolicies(policyType)
 		if err != nil {
 			return nil, err
 		}
@@ -2048,7 +2201,7 @@ func (b *SystemBackend) handlePoliciesRead(policyType PolicyType) func(*logical.
 	return func(req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
 		name := data.Get("name").(string)
 
-		policy, err := b.Core.policyStore.GetPolicy(name, policyType)
+		policy, err := b.policyStore(req).GetPolicy(name, policyType)
 		if err != nil {
 			return handleError(err)
 		}
@@ -2073,7 +2226,7 @@ func (b *SystemBackend) handlePolicyRead(
 	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
 	name := data.Get("name").(string)
 
-	policy, err := b.Core.policyStore.GetPolicy(name, PolicyTypeACL)
+	policy, err := b.policyStore(req).GetPolicy(name, PolicyTypeACL)
 	if err != nil {
 		return handleError(err)
 	}
@@ -2124,7 +2277,7 @@ func (b *SystemBackend) handlePoliciesSet(policyType PolicyType) func(*logical.R
 		}
 
 		// Update the policy
-		if err := b.Core.policyStore.SetPolicy(policy); err != nil {
+		if err := b.policyStore(req).SetPolicy(policy); err != nil {
 			return handleError(err)
 		}
 		return nil, nil
@@ -2164,7 +2317,7 @@ func (b *SystemBackend) handlePolicySet(
 	policy.Paths = p.Paths
 
 	// Update the policy
-	if err := b.Core.policyStore.SetPolicy(policy); err != nil {
+	if err := b.policyStore(req).SetPolicy(policy); err != nil {
 		return handleError(err)
 	}
 	return resp, nil
@@ -2174,7 +2327,7 @@ func (b *SystemBackend) handlePoliciesDelete(policyType PolicyType) func(*logica
 	return func(req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
 		name := data.Get("name").(string)
 
-		if err := b.Core.policyStore.DeletePolicy(name, policyType); err != nil {
+		if err := b.policyStore(req).DeletePolicy(name, policyType); err != nil {
 			return handleError(err)
 		}
 		return nil, nil
@@ -2186,7 +2339,7 @@ func (b *SystemBackend) handlePolicyDelete(
 	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
 	name := data.Get("name").(string)
 
-	if err := b.Core.policyStore.DeletePolicy(name, PolicyTypeACL); err != nil {
+	if err := b.policyStore(req).DeletePolicy(name, PolicyTypeACL); err != nil {
 		return handleError(err)
 	}
 	return nil, nil
@@ -2496,6 +2649,13 @@ func (b *SystemBackend) handleWrappingUnwrap(
 		token = req.ClientToken
 	}
 
+	// Requests held by a control group are performed once they are
+	// authorized, instead of reading a stored response. Their wrapping tokens
+	// stay valid until then.
+	if resp, err := b.Core.performControlGroupRequest(req, token); resp != nil || err != nil {
+		return resp, err
+	}
+
 	if thirdParty {
 		// Use the token to decrement the use count to avoid a second operation on the token.
 		_, err := b.Core.tokenStore.UseTokenByID(token)
@@ -2620,6 +2780,12 @@ func (b *SystemBackend) handleWrappingRewrap(
 		token = req.ClientToken
 	}
 
+	if cgReq, err := b.Core.controlGroupRequest(token); err != nil {
+		return nil, fmt.Errorf("error looking up control group request: %v", err)
+	} else if cgReq != nil {
+		return logical.ErrorResponse("requests held by a control group cannot be rewrapped"), logical.ErrInvalidRequest
+	}
+
 	if thirdParty {
 		// Use the token to decrement the use count to avoid a second operation on the token.
 		_, err := b.Core.tokenStore.UseTokenByID(token)
@@ -2806,6 +2972,17 @@ func (b *SystemBackend) pathRandomWrite(
 	return resp, nil
 }
 
+// handleMetricsRead returns the metrics of this node in the requested format
+func (b *SystemBackend) handleMetricsRead(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	helper := b.Core.MetricsHelper()
+	if helper == nil {
+		return logical.ErrorResponse("metrics are not available"), logical.ErrInvalidRequest
+	}
+
+	return helper.ResponseForFormat(d.Get("format").(string))
+}
+
 func sanitizeMountPath(path string) string {
 	if !strings.HasSuffix(path, "/") {
 		path += "/"
@@ -2972,6 +3149,10 @@ in the plugin catalog.`,
 		`The max lease TTL for this mount.`,
 	},
 
+	"token_type": {
+		`The type of the tokens issued by logins against this auth mount: "service", "batch", "default-service" or "default-batch". With the default types, the auth backend can choose the type.`,
+	},
+
 	"remount": {
 		"Move the mount point of an already-mounted backend.",
 		`
@@ -3201,6 +3382,426 @@ Enable a new audit backend or disable an existing backend.
 		`,
 	},
 
+	"raft-bootstrap": {
+		"Adds a new node to the raft cluster.",
+		`
+This path is called by a node joining the cluster. The node is added to the
+raft configuration and receives the key used to secure the connections
+between raft peers.
+		`,
+	},
+
+	"raft-configuration": {
+		"Returns the raft cluster configuration.",
+		`
+Returns the ID and cluster address of every server in the raft
+configuration, along with whether it is the leader and whether it votes.
+		`,
+	},
+
+	"raft-remove-peer": {
+		"Removes a node from the raft cluster.",
+		`
+Removes the server with the given ID from the raft configuration. This is
+used to remove nodes that have been permanently taken out of service.
+		`,
+	},
+
+	"namespaces-list": {
+		"Lists the namespaces nested directly underneath the current namespace.",
+		"",
+	},
+
+	"namespaces": {
+		"Creates, reads or deletes a namespace underneath the current namespace.",
+		`
+Namespaces are isolated subtrees of the API, each with its own secret engines,
+auth methods, policies, identity store and tokens. A namespace is created
+directly underneath the namespace of the request and is reached by prefixing
+request paths with its path, or by setting the X-Vault-Namespace header.
+Nested namespaces are created from within their parent. A namespace can only
+be deleted once its child namespaces have been deleted; deleting it revokes
+all of its leases and tokens and removes its mounts and policies.
+		`,
+	},
+
+	"namespaces-path": {
+		"The path of the namespace, relative to the current namespace.",
+		"",
+	},
+
+	"quotas-list": {
+		"Lists the quotas of a type.",
+		"",
+	},
+
+	"quotas-rate-limit": {
+		"Creates, reads or deletes a rate-limit quota.",
+		`
+A rate-limit quota limits the rate of requests made against a path prefix, or
+against all paths if no path is given, using a token bucket that is refilled
+at the given rate and holds up to the burst size. By default every client
+address has its own bucket; with the "path" scope all clients share one.
+Requests over the limit are rejected with a 429 status code. Where several
+quotas match a request, the one with the longest path applies.
+		`,
+	},
+
+	"quotas-lease-count": {
+		"Creates, reads or deletes a lease-count quota.",
+		`
+A lease-count quota limits the number of leases and token leases that may
+exist under a path prefix, or under all paths if no path is given. Requests
+that would create a lease over the limit are rejected with a 429 status code
+until leases are revoked or expire. Reading the quota returns the number of
+leases currently counted against it.
+		`,
+	},
+
+	"quotas-name": {
+		"The name of the quota.",
+		"",
+	},
+
+	"quotas-path": {
+		"The mount or path prefix the quota applies to. Empty applies to all paths.",
+		"",
+	},
+
+	"quotas-rate": {
+		"The number of requests per second allowed.",
+		"",
+	},
+
+	"quotas-burst": {
+		"The number of requests allowed in a burst. Defaults to the rate, rounded up.",
+		"",
+	},
+
+	"quotas-scope": {
+		`Whether each client address has its own token bucket ("client") or all clients share one ("path").`,
+		"",
+	},
+
+	"quotas-max-leases": {
+		"The maximum number of leases allowed under the path.",
+		"",
+	},
+
+	"mfa-method-list": {
+		"Lists the MFA methods.",
+		`
+This path responds to the following HTTP methods.
+
+    LIST /
+        List the names of the MFA methods of all types.
+		`,
+	},
+
+	"mfa-method-name": {
+		"Name of the MFA method.",
+		"",
+	},
+
+	"mfa-mount-accessor": {
+		"Accessor of the auth mount whose alias names are used as the usernames of the method.",
+		"",
+	},
+
+	"mfa-username-format": {
+		`Format of the username of the method. Supports {{persona.name}}, {{entity.name}}, {{persona.metadata.<key>}} and {{entity.metadata.<key>}}.`,
+		"",
+	},
+
+	"mfa-method-totp": {
+		"Configures a TOTP MFA method.",
+		`
+TOTP methods validate a passcode against a secret stored on the entity of the
+login. Secrets are generated with the generate and admin-generate endpoints,
+using the parameters of the method. Only the issuer and the QR code size can be
+changed once the method exists.
+
+This path responds to the following HTTP methods.
+
+    GET /<name>
+        Read the TOTP method.
+
+    POST /<name>
+        Create or update the TOTP method.
+
+    DELETE /<name>
+        Delete the TOTP method.
+		`,
+	},
+
+	"mfa-totp-issuer": {
+		"The name of the organization issuing the keys.",
+		"",
+	},
+
+	"mfa-totp-period": {
+		"The length of the period of a passcode, in seconds. Defaults to 30.",
+		"",
+	},
+
+	"mfa-totp-key-size": {
+		"The size in bytes of the generated keys. Defaults to 20.",
+		"",
+	},
+
+	"mfa-totp-qr-size": {
+		"The pixel size of the generated square QR code. Set to 0 to not return a QR code. Defaults to 200.",
+		"",
+	},
+
+	"mfa-totp-algorithm": {
+		`The hashing algorithm of the passcodes, "SHA1", "SHA256" or "SHA512". Defaults to "SHA1".`,
+		"",
+	},
+
+	"mfa-totp-digits": {
+		"The number of digits of the passcodes, 6 or 8. Defaults to 6.",
+		"",
+	},
+
+	"mfa-totp-skew": {
+		"The number of periods before and after the current one whose passcodes are accepted, 0 or 1. Defaults to 1.",
+		"",
+	},
+
+	"mfa-totp-entity-id": {
+		"ID of the entity.",
+		"",
+	},
+
+	"mfa-totp-generate": {
+		"Generates a TOTP secret for the entity of the calling token.",
+		`
+The secret is returned as an otpauth URL and a QR code, to be added to an
+authenticator app. Nothing is returned if the entity already has a secret for
+the method.
+		`,
+	},
+
+	"mfa-totp-admin-generate": {
+		"Generates a TOTP secret for the given entity.",
+		`
+The secret is returned as an otpauth URL and a QR code, to be added to an
+authenticator app. Nothing is returned if the entity already has a secret for
+the method.
+		`,
+	},
+
+	"mfa-totp-admin-destroy": {
+		"Removes the TOTP secret of the given entity.",
+		"",
+	},
+
+	"mfa-method-okta": {
+		"Configures an Okta MFA method.",
+		`
+Okta methods send an Okta Verify push to the Okta user of the entity of the
+login and wait for it to be approved.
+
+This path responds to the following HTTP methods.
+
+    GET /<name>
+        Read the Okta method.
+
+    POST /<name>
+        Create or update the Okta method.
+
+    DELETE /<name>
+        Delete the Okta method.
+		`,
+	},
+
+	"mfa-okta-org-name": {
+		"Name of the organization in the Okta API.",
+		"",
+	},
+
+	"mfa-okta-api-token": {
+		"Okta API token.",
+		"",
+	},
+
+	"mfa-okta-base-url": {
+		`Base domain of the Okta API, such as "okta.com", "oktapreview.com" or "okta-emea.com". Defaults to "okta.com".`,
+		"",
+	},
+
+	"mfa-method-duo": {
+		"Configures a Duo MFA method.",
+		`
+Duo methods validate a Duo passcode for the Duo user of the entity of the
+login, or send a Duo push to the user and wait for it to be approved if no
+passcode is given.
+
+This path responds to the following HTTP methods.
+
+    GET /<name>
+        Read the Duo method.
+
+    POST /<name>
+        Create or update the Duo method.
+
+    DELETE /<name>
+        Delete the Duo method.
+		`,
+	},
+
+	"mfa-duo-integration-key": {
+		"Duo integration key.",
+		"",
+	},
+
+	"mfa-duo-secret-key": {
+		"Duo secret key.",
+		"",
+	},
+
+	"mfa-duo-api-hostname": {
+		"Duo API hostname.",
+		"",
+	},
+
+	"mfa-duo-push-info": {
+		"Additional information shown in the Duo push, URL encoded.",
+		"",
+	},
+
+	"mfa-method-pingid": {
+		"Configures a PingID MFA method.",
+		`
+PingID methods send a PingID push to the PingID user of the entity of the
+login and wait for it to be approved.
+
+This path responds to the following HTTP methods.
+
+    GET /<name>
+        Read the PingID method.
+
+    POST /<name>
+        Create or update the PingID method.
+
+    DELETE /<name>
+        Delete the PingID method.
+		`,
+	},
+
+	"mfa-pingid-settings-file": {
+		"The base64 encoded settings file of the organization, downloaded from the PingID configuration page.",
+		"",
+	},
+
+	"mfa-login-enforcement-list": {
+		"Lists the MFA login enforcements.",
+		`
+This path responds to the following HTTP methods.
+
+    LIST /
+        List the names of the login enforcements.
+		`,
+	},
+
+	"mfa-login-enforcement": {
+		"Configures an MFA login enforcement.",
+		`
+Login enforcements require MFA methods to be validated for logins through the
+given auth mounts, or through auth methods of the given types. Such logins
+return an MFA request ID instead of a token, which is obtained by validating
+the MFA methods through sys/mfa/validate.
+
+This path responds to the following HTTP methods.
+
+    GET /<name>
+        Read the login enforcement.
+
+    POST /<name>
+        Create or update the login enforcement.
+
+    DELETE /<name>
+        Delete the login enforcement.
+		`,
+	},
+
+	"mfa-login-enforcement-name": {
+		"Name of the login enforcement.",
+		"",
+	},
+
+	"mfa-login-enforcement-methods": {
+		"Names of the MFA methods that must be validated.",
+		"",
+	},
+
+	"mfa-login-enforcement-accessors": {
+		"Accessors of the auth mounts whose logins require MFA.",
+		"",
+	},
+
+	"mfa-login-enforcement-types": {
+		`Types of the auth methods whose logins require MFA, such as "approle" or "cert".`,
+		"",
+	},
+
+	"mfa-validate": {
+		"Validates the MFA methods of a login and returns its token.",
+		`
+Logins that require MFA return an MFA request ID and the MFA methods to
+validate. Writing the request ID here, along with the passcodes of the methods
+that use them, completes the login. Push methods wait for the push to be
+approved. A request ID can be validated once, within five minutes of the login.
+		`,
+	},
+
+	"mfa-validate-request-id": {
+		"The MFA request ID returned by the login.",
+		"",
+	},
+
+	"mfa-validate-payload": {
+		"A map of MFA method names to lists of passcodes. Push methods take an empty list.",
+		"",
+	},
+
+	"control-group-authorize": {
+		"Authorizes a request held by a control group.",
+		`
+Requests to paths whose policies set a control group are held, and return a
+wrapping token instead of a response. Members of the identity groups named by
+the factors of the control group authorize such a request by writing the
+accessor of its wrapping token here. Requesters cannot authorize their own
+requests. Once every factor has as many authorizations as it requires, the
+request is performed when the wrapping token is unwrapped.
+		`,
+	},
+
+	"control-group-request": {
+		"Returns the status of a request held by a control group.",
+		`
+Returns whether the request held by the wrapping token with the given accessor
+is approved, along with the entities that made and authorized it.
+		`,
+	},
+
+	"control-group-accessor": {
+		"The accessor of the wrapping token returned for the held request.",
+		"",
+	},
+
+	"raft-snapshot": {
+		"Takes or restores a snapshot of the raft storage.",
+		`
+Reading this path returns a gzipped snapshot of the storage. Writing a
+base64 encoded snapshot to it replaces the data of the whole cluster with the
+snapshot, after which the active node steps down so that its state is
+rebuilt from the restored data. Restoring a snapshot taken on a cluster with
+different keys seals the node.
+		`,
+	},
+
 	"rotate": {
 		"Rotates the backend encryption key used to persist data.",
 		`
@@ -3333,6 +3934,18 @@ This path responds to the following HTTP methods.
 		`,
 	},
 
+	"leases-irrevocable": {
+		"List the leases that could not be revoked.",
+		`
+The revocation of an expired lease is retried with an exponential backoff. When
+it still fails after the maximum number of attempts, the lease is marked
+irrevocable and the error of the last attempt is stored with it. This endpoint
+lists these leases, so that they can be cleaned up once the cause of the error
+is fixed by revoking them through sys/leases/revoke, or given up on through
+sys/leases/revoke-force.
+		`,
+	},
+
 	"leases-list-prefix": {
 		`The path to list leases under. Example: "aws/creds/deploy"`,
 		"",
@@ -3360,4 +3973,13 @@ This path responds to the following HTTP methods.
 		"Generate random bytes",
 		"This function can be used to generate high-entropy random bytes.",
 	},
+
+	"metrics": {
+		"Export the metrics of this node.",
+		`
+Returns the metrics kept in memory by the node serving the request, in the
+Prometheus text exposition format or as JSON. Prometheus metrics must be
+enabled by setting "prometheus_retention_time" in the telemetry configuration.
+		`,
+	},
 }
@@ -0,0 +1,114 @@
+package vault
+
+import (
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+// controlGroupPaths returns the paths used to authorize the requests held by
+// control groups and to look up their status
+func (b *SystemBackend) controlGroupPaths() []*framework.Path {
+	return []*framework.Path{
+		&framework.Path{
+			Pattern: "control-group/authorize$",
+
+			Fields: map[string]*framework.FieldSchema{
+				"accessor": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: strings.TrimSpace(sysHelp["control-group-accessor"][0]),
+				},
+			},
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.UpdateOperation: b.handleControlGroupAuthorize,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["control-group-authorize"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["control-group-authorize"][1]),
+		},
+
+		&framework.Path{
+			Pattern: "control-group/request$",
+
+			Fields: map[string]*framework.FieldSchema{
+				"accessor": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: strings.TrimSpace(sysHelp["control-group-accessor"][0]),
+				},
+			},
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.UpdateOperation: b.handleControlGroupRequest,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["control-group-request"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["control-group-request"][1]),
+		},
+	}
+}
+
+// handleControlGroupAuthorize records the authorization of a held request by
+// the entity of the calling token
+func (b *SystemBackend) handleControlGroupAuthorize(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	cgReq, err := b.Core.authorizeControlGroupRequest(data.Get("accessor").(string), req.EntityID)
+	if err != nil {
+		if _, ok := err.(logical.HTTPCodedError); ok {
+			return handleError(err)
+		}
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"approved": cgReq.approved(),
+		},
+	}, nil
+}
+
+// handleControlGroupRequest returns the status of a held request, along with
+// the entities that made and authorized it
+func (b *SystemBackend) handleControlGroupRequest(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	_, cgReq, err := b.Core.controlGroupRequestByAccessor(data.Get("accessor").(string))
+	if err != nil {
+		if _, ok := err.(logical.HTTPCodedError); ok {
+			return handleError(err)
+		}
+		return nil, err
+	}
+
+	authorizations := make([]map[string]interface{}, 0, len(cgReq.Authorizations))
+	for _, authz := range cgReq.Authorizations {
+		authorizations = append(authorizations, map[string]interface{}{
+			"entity_id":   authz.EntityID,
+			"entity_name": b.controlGroupEntityName(authz.EntityID),
+			"time":        authz.Time.Format(time.RFC3339Nano),
+		})
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"approved":     cgReq.approved(),
+			"request_path": cgReq.Path,
+			"request_entity": map[string]interface{}{
+				"id":   cgReq.RequestEntityID,
+				"name": b.controlGroupEntityName(cgReq.RequestEntityID),
+			},
+			"authorizations": authorizations,
+		},
+	}, nil
+}
+
+// controlGroupEntityName returns the name of an entity, or an empty string if
+// it no longer exists
+func (b *SystemBackend) controlGroupEntityName(entityID string) string {
+	_, entity, err := b.Core.mfaIdentityStore(entityID)
+	if err != nil || entity == nil {
+		return ""
+	}
+	return entity.Name
+}
@@ -0,0 +1,662 @@
+package vault
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image/png"
+	"strings"
+
+	"github.com/hashicorp/vault/helper/strutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+// mfaPaths returns the paths used to manage the MFA methods and login
+// enforcements, and to validate logins held for MFA
+func (b *SystemBackend) mfaPaths() []*framework.Path {
+	return []*framework.Path{
+		&framework.Path{
+			Pattern: "mfa/method/?$",
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ListOperation: b.handleMFAMethodList,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["mfa-method-list"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["mfa-method-list"][1]),
+		},
+
+		&framework.Path{
+			Pattern: "mfa/method/totp/" + framework.GenericNameRegex("name") + "$",
+
+			Fields: map[string]*framework.FieldSchema{
+				"name": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: strings.TrimSpace(sysHelp["mfa-method-name"][0]),
+				},
+				"issuer": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: strings.TrimSpace(sysHelp["mfa-totp-issuer"][0]),
+				},
+				"period": &framework.FieldSchema{
+					Type:        framework.TypeDurationSecond,
+					Default:     30,
+					Description: strings.TrimSpace(sysHelp["mfa-totp-period"][0]),
+				},
+				"key_size": &framework.FieldSchema{
+					Type:        framework.TypeInt,
+					Default:     20,
+					Description: strings.TrimSpace(sysHelp["mfa-totp-key-size"][0]),
+				},
+				"qr_size": &framework.FieldSchema{
+					Type:        framework.TypeInt,
+					Default:     200,
+					Description: strings.TrimSpace(sysHelp["mfa-totp-qr-size"][0]),
+				},
+				"algorithm": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Default:     "SHA1",
+					Description: strings.TrimSpace(sysHelp["mfa-totp-algorithm"][0]),
+				},
+				"digits": &framework.FieldSchema{
+					Type:        framework.TypeInt,
+					Default:     6,
+					Description: strings.TrimSpace(sysHelp["mfa-totp-digits"][0]),
+				},
+				"skew": &framework.FieldSchema{
+					Type:        framework.TypeInt,
+					Default:     1,
+					Description: strings.TrimSpace(sysHelp["mfa-totp-skew"][0]),
+				},
+			},
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ReadOperation:   b.handleMFAMethodRead(MFATypeTOTP),
+				logical.UpdateOperation: b.handleMFAMethodUpdate(MFATypeTOTP),
+				logical.DeleteOperation: b.handleMFAMethodDelete(MFATypeTOTP),
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["mfa-method-totp"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["mfa-method-totp"][1]),
+		},
+
+		&framework.Path{
+			Pattern: "mfa/method/totp/" + framework.GenericNameRegex("name") + "/generate$",
+
+			Fields: map[string]*framework.FieldSchema{
+				"name": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: strings.TrimSpace(sysHelp["mfa-method-name"][0]),
+				},
+			},
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ReadOperation: b.handleMFATOTPGenerate,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["mfa-totp-generate"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["mfa-totp-generate"][1]),
+		},
+
+		&framework.Path{
+			Pattern: "mfa/method/totp/" + framework.GenericNameRegex("name") + "/admin-generate$",
+
+			Fields: map[string]*framework.FieldSchema{
+				"name": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: strings.TrimSpace(sysHelp["mfa-method-name"][0]),
+				},
+				"entity_id": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: strings.TrimSpace(sysHelp["mfa-totp-entity-id"][0]),
+				},
+			},
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.UpdateOperation: b.handleMFATOTPAdminGenerate,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["mfa-totp-admin-generate"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["mfa-totp-admin-generate"][1]),
+		},
+
+		&framework.Path{
+			Pattern: "mfa/method/totp/" + framework.GenericNameRegex("name") + "/admin-destroy$",
+
+			Fields: map[string]*framework.FieldSchema{
+				"name": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: strings.TrimSpace(sysHelp["mfa-method-name"][0]),
+				},
+				"entity_id": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: strings.TrimSpace(sysHelp["mfa-totp-entity-id"][0]),
+				},
+			},
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.UpdateOperation: b.handleMFATOTPAdminDestroy,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["mfa-totp-admin-destroy"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["mfa-totp-admin-destroy"][1]),
+		},
+
+		&framework.Path{
+			Pattern: "mfa/method/okta/" + framework.GenericNameRegex("name") + "$",
+
+			Fields: mfaPushMethodFields(map[string]*framework.FieldSchema{
+				"org_name": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: strings.TrimSpace(sysHelp["mfa-okta-org-name"][0]),
+				},
+				"api_token": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: strings.TrimSpace(sysHelp["mfa-okta-api-token"][0]),
+				},
+				"base_url": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: strings.TrimSpace(sysHelp["mfa-okta-base-url"][0]),
+				},
+			}),
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ReadOperation:   b.handleMFAMethodRead(MFATypeOkta),
+				logical.UpdateOperation: b.handleMFAMethodUpdate(MFATypeOkta),
+				logical.DeleteOperation: b.handleMFAMethodDelete(MFATypeOkta),
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["mfa-method-okta"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["mfa-method-okta"][1]),
+		},
+
+		&framework.Path{
+			Pattern: "mfa/method/duo/" + framework.GenericNameRegex("name") + "$",
+
+			Fields: mfaPushMethodFields(map[string]*framework.FieldSchema{
+				"integration_key": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: strings.TrimSpace(sysHelp["mfa-duo-integration-key"][0]),
+				},
+				"secret_key": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: strings.TrimSpace(sysHelp["mfa-duo-secret-key"][0]),
+				},
+				"api_hostname": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: strings.TrimSpace(sysHelp["mfa-duo-api-hostname"][0]),
+				},
+				"push_info": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: strings.TrimSpace(sysHelp["mfa-duo-push-info"][0]),
+				},
+			}),
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ReadOperation:   b.handleMFAMethodRead(MFATypeDuo),
+				logical.UpdateOperation: b.handleMFAMethodUpdate(MFATypeDuo),
+				logical.DeleteOperation: b.handleMFAMethodDelete(MFATypeDuo),
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["mfa-method-duo"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["mfa-method-duo"][1]),
+		},
+
+		&framework.Path{
+			Pattern: "mfa/method/pingid/" + framework.GenericNameRegex("name") + "$",
+
+			Fields: mfaPushMethodFields(map[string]*framework.FieldSchema{
+				"settings_file_base64": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: strings.TrimSpace(sysHelp["mfa-pingid-settings-file"][0]),
+				},
+			}),
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ReadOperation:   b.handleMFAMethodRead(MFATypePingID),
+				logical.UpdateOperation: b.handleMFAMethodUpdate(MFATypePingID),
+				logical.DeleteOperation: b.handleMFAMethodDelete(MFATypePingID),
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["mfa-method-pingid"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["mfa-method-pingid"][1]),
+		},
+
+		&framework.Path{
+			Pattern: "mfa/login-enforcement/?$",
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ListOperation: b.handleMFALoginEnforcementList,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["mfa-login-enforcement-list"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["mfa-login-enforcement-list"][1]),
+		},
+
+		&framework.Path{
+			Pattern: "mfa/login-enforcement/" + framework.GenericNameRegex("name") + "$",
+
+			Fields: map[string]*framework.FieldSchema{
+				"name": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: strings.TrimSpace(sysHelp["mfa-login-enforcement-name"][0]),
+				},
+				"mfa_method_names": &framework.FieldSchema{
+					Type:        framework.TypeCommaStringSlice,
+					Description: strings.TrimSpace(sysHelp["mfa-login-enforcement-methods"][0]),
+				},
+				"auth_method_accessors": &framework.FieldSchema{
+					Type:        framework.TypeCommaStringSlice,
+					Description: strings.TrimSpace(sysHelp["mfa-login-enforcement-accessors"][0]),
+				},
+				"auth_method_types": &framework.FieldSchema{
+					Type:        framework.TypeCommaStringSlice,
+					Description: strings.TrimSpace(sysHelp["mfa-login-enforcement-types"][0]),
+				},
+			},
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ReadOperation:   b.handleMFALoginEnforcementRead,
+				logical.UpdateOperation: b.handleMFALoginEnforcementUpdate,
+				logical.DeleteOperation: b.handleMFALoginEnforcementDelete,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["mfa-login-enforcement"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["mfa-login-enforcement"][1]),
+		},
+
+		// Validation requests are handled by the core, which holds the
+		// logins; the path only serves their help
+		&framework.Path{
+			Pattern: "mfa/validate$",
+
+			Fields: map[string]*framework.FieldSchema{
+				"mfa_request_id": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: strings.TrimSpace(sysHelp["mfa-validate-request-id"][0]),
+				},
+				"mfa_payload": &framework.FieldSchema{
+					Type:        framework.TypeMap,
+					Description: strings.TrimSpace(sysHelp["mfa-validate-payload"][0]),
+				},
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["mfa-validate"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["mfa-validate"][1]),
+		},
+	}
+}
+
+// mfaPushMethodFields returns the fields of a push method, which map
+// entities to the username of the user of the method
+func mfaPushMethodFields(fields map[string]*framework.FieldSchema) map[string]*framework.FieldSchema {
+	fields["name"] = &framework.FieldSchema{
+		Type:        framework.TypeString,
+		Description: strings.TrimSpace(sysHelp["mfa-method-name"][0]),
+	}
+	fields["mount_accessor"] = &framework.FieldSchema{
+		Type:        framework.TypeString,
+		Description: strings.TrimSpace(sysHelp["mfa-mount-accessor"][0]),
+	}
+	fields["username_format"] = &framework.FieldSchema{
+		Type:        framework.TypeString,
+		Description: strings.TrimSpace(sysHelp["mfa-username-format"][0]),
+	}
+	return fields
+}
+
+// handleMFAMethodList lists the MFA methods of all types
+func (b *SystemBackend) handleMFAMethodList(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	return logical.ListResponse(b.Core.mfa.MethodNames("")), nil
+}
+
+// handleMFAMethodRead returns the configuration of an MFA method
+func (b *SystemBackend) handleMFAMethodRead(methodType string) framework.OperationFunc {
+	return func(req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		method := b.Core.mfa.Method(data.Get("name").(string))
+		if method == nil || method.Type != methodType {
+			return nil, nil
+		}
+
+		resp := &logical.Response{
+			Data: map[string]interface{}{
+				"id":   method.ID,
+				"name": method.Name,
+				"type": method.Type,
+			},
+		}
+		if methodType != MFATypeTOTP {
+			resp.Data["mount_accessor"] = method.MountAccessor
+			resp.Data["username_format"] = method.UsernameFormat
+		}
+
+		switch methodType {
+		case MFATypeTOTP:
+			resp.Data["issuer"] = method.Issuer
+			resp.Data["period"] = method.Period
+			resp.Data["key_size"] = method.KeySize
+			resp.Data["qr_size"] = method.QRSize
+			resp.Data["algorithm"] = method.Algorithm
+			resp.Data["digits"] = method.Digits
+			resp.Data["skew"] = method.Skew
+		case MFATypeOkta:
+			resp.Data["org_name"] = method.OrgName
+			resp.Data["api_token"] = method.APIToken
+			resp.Data["base_url"] = method.BaseURL
+		case MFATypeDuo:
+			resp.Data["integration_key"] = method.IntegrationKey
+			resp.Data["secret_key"] = method.SecretKey
+			resp.Data["api_hostname"] = method.APIHostname
+			resp.Data["push_info"] = method.PushInfo
+		case MFATypePingID:
+			resp.Data["use_signature"] = method.UseSignature
+			resp.Data["idp_url"] = method.IdpURL
+			resp.Data["org_alias"] = method.OrgAlias
+			resp.Data["admin_url"] = method.AdminURL
+			resp.Data["authenticator_url"] = method.AuthenticatorURL
+		}
+		return resp, nil
+	}
+}
+
+// handleMFAMethodUpdate creates or updates an MFA method
+func (b *SystemBackend) handleMFAMethodUpdate(methodType string) framework.OperationFunc {
+	return func(req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		name := data.Get("name").(string)
+
+		method := &MFAMethod{
+			Name: name,
+			Type: methodType,
+		}
+		if existing := b.Core.mfa.Method(name); existing != nil {
+			if existing.Type != methodType {
+				return logical.ErrorResponse(fmt.Sprintf("MFA method %q already exists with type %q", name, existing.Type)), logical.ErrInvalidRequest
+			}
+			method = existing
+		}
+
+		if methodType != MFATypeTOTP {
+			if accessor, ok := data.GetOk("mount_accessor"); ok {
+				method.MountAccessor = accessor.(string)
+			}
+			if format, ok := data.GetOk("username_format"); ok {
+				method.UsernameFormat = format.(string)
+			}
+			if method.MountAccessor == "" {
+				return logical.ErrorResponse("mount_accessor is required"), logical.ErrInvalidRequest
+			}
+			if b.Core.router.MatchingMountByAccessor(method.MountAccessor) == nil {
+				return logical.ErrorResponse(fmt.Sprintf("unknown mount accessor %q", method.MountAccessor)), logical.ErrInvalidRequest
+			}
+		}
+
+		var errResp *logical.Response
+		switch methodType {
+		case MFATypeTOTP:
+			errResp = parseTOTPMethod(method, data)
+		case MFATypeOkta:
+			errResp = parseOktaMethod(method, data)
+		case MFATypeDuo:
+			errResp = parseDuoMethod(method, data)
+		case MFATypePingID:
+			errResp = parsePingIDMethod(method, data)
+		}
+		if errResp != nil {
+			return errResp, logical.ErrInvalidRequest
+		}
+
+		if err := b.Core.mfa.SetMethod(method); err != nil {
+			return handleError(err)
+		}
+		return nil, nil
+	}
+}
+
+func parseTOTPMethod(method *MFAMethod, data *framework.FieldData) *logical.Response {
+	// Methods keep their parameters once created, since the secrets of
+	// entities are generated with them
+	if method.ID == "" {
+		method.Period = uint(data.Get("period").(int))
+		method.KeySize = uint(data.Get("key_size").(int))
+		method.Algorithm = strings.ToUpper(data.Get("algorithm").(string))
+		method.Digits = data.Get("digits").(int)
+		method.Skew = uint(data.Get("skew").(int))
+		method.QRSize = data.Get("qr_size").(int)
+	}
+	if issuer, ok := data.GetOk("issuer"); ok {
+		method.Issuer = issuer.(string)
+	}
+	if qrSize, ok := data.GetOk("qr_size"); ok {
+		method.QRSize = qrSize.(int)
+	}
+
+	switch {
+	case method.Issuer == "":
+		return logical.ErrorResponse("issuer is required")
+	case method.Period == 0:
+		return logical.ErrorResponse("period must be greater than zero")
+	case method.KeySize == 0:
+		return logical.ErrorResponse("key_size must be greater than zero")
+	case method.QRSize < 0:
+		return logical.ErrorResponse("qr_size cannot be negative")
+	case method.Digits != 6 && method.Digits != 8:
+		return logical.ErrorResponse("digits must be 6 or 8")
+	case method.Skew > 1:
+		return logical.ErrorResponse("skew must be 0 or 1")
+	}
+	if _, ok := totpAlgorithms[method.Algorithm]; !ok {
+		return logical.ErrorResponse("algorithm must be SHA1, SHA256 or SHA512")
+	}
+	return nil
+}
+
+func parseOktaMethod(method *MFAMethod, data *framework.FieldData) *logical.Response {
+	if orgName, ok := data.GetOk("org_name"); ok {
+		method.OrgName = orgName.(string)
+	}
+	if apiToken, ok := data.GetOk("api_token"); ok {
+		method.APIToken = apiToken.(string)
+	}
+	if baseURL, ok := data.GetOk("base_url"); ok {
+		method.BaseURL = baseURL.(string)
+	}
+
+	switch {
+	case method.OrgName == "":
+		return logical.ErrorResponse("org_name is required")
+	case method.APIToken == "":
+		return logical.ErrorResponse("api_token is required")
+	}
+	return nil
+}
+
+func parseDuoMethod(method *MFAMethod, data *framework.FieldData) *logical.Response {
+	if integrationKey, ok := data.GetOk("integration_key"); ok {
+		method.IntegrationKey = integrationKey.(string)
+	}
+	if secretKey, ok := data.GetOk("secret_key"); ok {
+		method.SecretKey = secretKey.(string)
+	}
+	if apiHostname, ok := data.GetOk("api_hostname"); ok {
+		method.APIHostname = apiHostname.(string)
+	}
+	if pushInfo, ok := data.GetOk("push_info"); ok {
+		method.PushInfo = pushInfo.(string)
+	}
+
+	switch {
+	case method.IntegrationKey == "":
+		return logical.ErrorResponse("integration_key is required")
+	case method.SecretKey == "":
+		return logical.ErrorResponse("secret_key is required")
+	case method.APIHostname == "":
+		return logical.ErrorResponse("api_hostname is required")
+	}
+	return nil
+}
+
+func parsePingIDMethod(method *MFAMethod, data *framework.FieldData) *logical.Response {
+	settings, ok := data.GetOk("settings_file_base64")
+	if !ok {
+		if method.SettingsFileBase64 == "" {
+			return logical.ErrorResponse("settings_file_base64 is required")
+		}
+		return nil
+	}
+
+	method.SettingsFileBase64 = settings.(string)
+	if err := parsePingIDSettings(method); err != nil {
+		return logical.ErrorResponse(fmt.Sprintf("invalid settings file: %v", err))
+	}
+	return nil
+}
+
+// handleMFAMethodDelete removes an MFA method
+func (b *SystemBackend) handleMFAMethodDelete(methodType string) framework.OperationFunc {
+	return func(req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		name := data.Get("name").(string)
+		method := b.Core.mfa.Method(name)
+		if method == nil || method.Type != methodType {
+			return nil, nil
+		}
+
+		if err := b.Core.mfa.DeleteMethod(name); err != nil {
+			return handleError(err)
+		}
+		return nil, nil
+	}
+}
+
+// handleMFATOTPGenerate generates a TOTP secret for the entity of the
+// calling token
+func (b *SystemBackend) handleMFATOTPGenerate(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	if req.EntityID == "" {
+		return logical.ErrorResponse("the calling token has no entity"), logical.ErrInvalidRequest
+	}
+	return b.generateTOTPSecret(data.Get("name").(string), req.EntityID)
+}
+
+// handleMFATOTPAdminGenerate generates a TOTP secret for the given entity
+func (b *SystemBackend) handleMFATOTPAdminGenerate(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	entityID := data.Get("entity_id").(string)
+	if entityID == "" {
+		return logical.ErrorResponse("entity_id is required"), logical.ErrInvalidRequest
+	}
+	return b.generateTOTPSecret(data.Get("name").(string), entityID)
+}
+
+func (b *SystemBackend) generateTOTPSecret(name, entityID string) (*logical.Response, error) {
+	method := b.Core.mfa.Method(name)
+	if method == nil || method.Type != MFATypeTOTP {
+		return logical.ErrorResponse(fmt.Sprintf("unknown TOTP method %q", name)), logical.ErrInvalidRequest
+	}
+
+	key, err := b.Core.generateTOTPSecret(method, entityID)
+	if err != nil {
+		return handleError(err)
+	}
+	if key == nil {
+		resp := &logical.Response{}
+		resp.AddWarning(fmt.Sprintf("entity already has a secret for MFA method %q", name))
+		return resp, nil
+	}
+
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			"url": key.String(),
+		},
+	}
+
+	// Don't include QR code if size is set to zero
+	if method.QRSize > 0 {
+		barcode, err := key.Image(method.QRSize, method.QRSize)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate QR code image: %v", err)
+		}
+
+		var buff bytes.Buffer
+		png.Encode(&buff, barcode)
+		resp.Data["barcode"] = base64.StdEncoding.EncodeToString(buff.Bytes())
+	}
+	return resp, nil
+}
+
+// handleMFATOTPAdminDestroy removes the TOTP secret of the given entity
+func (b *SystemBackend) handleMFATOTPAdminDestroy(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := data.Get("name").(string)
+	entityID := data.Get("entity_id").(string)
+	if entityID == "" {
+		return logical.ErrorResponse("entity_id is required"), logical.ErrInvalidRequest
+	}
+
+	if err := b.Core.destroyTOTPSecret(name, entityID); err != nil {
+		return handleError(err)
+	}
+	return nil, nil
+}
+
+// handleMFALoginEnforcementList lists the login enforcements
+func (b *SystemBackend) handleMFALoginEnforcementList(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	return logical.ListResponse(b.Core.mfa.LoginEnforcementNames()), nil
+}
+
+// handleMFALoginEnforcementRead returns a login enforcement
+func (b *SystemBackend) handleMFALoginEnforcementRead(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	enforcement := b.Core.mfa.LoginEnforcement(data.Get("name").(string))
+	if enforcement == nil {
+		return nil, nil
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"name":                  enforcement.Name,
+			"mfa_method_names":      enforcement.MFAMethodNames,
+			"auth_method_accessors": enforcement.AuthMethodAccessors,
+			"auth_method_types":     enforcement.AuthMethodTypes,
+		},
+	}, nil
+}
+
+// handleMFALoginEnforcementUpdate creates or updates a login enforcement
+func (b *SystemBackend) handleMFALoginEnforcementUpdate(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := data.Get("name").(string)
+
+	enforcement := &MFALoginEnforcement{
+		Name: name,
+	}
+	if existing := b.Core.mfa.LoginEnforcement(name); existing != nil {
+		enforcement = existing
+	}
+
+	if methods, ok := data.GetOk("mfa_method_names"); ok {
+		enforcement.MFAMethodNames = strutil.RemoveDuplicates(methods.([]string), false)
+	}
+	if accessors, ok := data.GetOk("auth_method_accessors"); ok {
+		enforcement.AuthMethodAccessors = strutil.RemoveDuplicates(accessors.([]string), false)
+	}
+	if types, ok := data.GetOk("auth_method_types"); ok {
+		enforcement.AuthMethodTypes = strutil.RemoveDuplicates(types.([]string), false)
+	}
+
+	if err := b.Core.mfa.SetLoginEnforcement(enforcement); err != nil {
+		return handleError(err)
+	}
+	return nil, nil
+}
+
+// handleMFALoginEnforcementDelete removes a login enforcement
+func (b *SystemBackend) handleMFALoginEnforcementDelete(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	if err := b.Core.mfa.DeleteLoginEnforcement(data.Get("name").(string)); err != nil {
+		return handleError(err)
+	}
+	return nil, nil
+}
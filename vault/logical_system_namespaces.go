@@ -0,0 +1,165 @@
+package vault
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+// namespacePaths returns the paths used to manage the namespaces nested
+// directly underneath the namespace of the request
+func (b *SystemBackend) namespacePaths() []*framework.Path {
+	return []*framework.Path{
+		&framework.Path{
+			Pattern: "namespaces/?$",
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ListOperation: b.handleNamespacesList,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["namespaces-list"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["namespaces-list"][1]),
+		},
+
+		&framework.Path{
+			Pattern: "namespaces/(?P<path>.+)",
+
+			Fields: map[string]*framework.FieldSchema{
+				"path": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: strings.TrimSpace(sysHelp["namespaces-path"][0]),
+				},
+			},
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ReadOperation:   b.handleNamespacesRead,
+				logical.CreateOperation: b.handleNamespacesCreate,
+				logical.UpdateOperation: b.handleNamespacesCreate,
+				logical.DeleteOperation: b.handleNamespacesDelete,
+			},
+
+			ExistenceCheck: b.handleNamespacesExistenceCheck,
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["namespaces"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["namespaces"][1]),
+		},
+	}
+}
+
+// namespace returns the namespace a request to the system backend was made
+// in, based on the path the backend is mounted at
+func (b *SystemBackend) namespace(req *logical.Request) *Namespace {
+	return b.Core.namespaceByPath(req.MountPoint)
+}
+
+// policyStore returns the policy store of the namespace of the request
+func (b *SystemBackend) policyStore(req *logical.Request) *PolicyStore {
+	return b.Core.namespacePolicyStore(b.namespace(req))
+}
+
+// namespaceMountEntry returns the mount entry matching the given full path if
+// it belongs to the namespace of the request. Mounts of nested namespaces are
+// managed through the system backend of that namespace.
+func (b *SystemBackend) namespaceMountEntry(req *logical.Request, path string) *MountEntry {
+	entry := b.Core.router.MatchingMountEntry(path)
+	if entry == nil || entry.Namespace() != b.namespace(req) {
+		return nil
+	}
+	return entry
+}
+
+// checkLeaseNamespace returns an error if the lease was not issued within the
+// namespace of the request or one of its children
+func (b *SystemBackend) checkLeaseNamespace(req *logical.Request, leaseID string) (*logical.Response, error) {
+	if !strings.HasPrefix(leaseID, b.namespace(req).Path) {
+		return logical.ErrorResponse("invalid lease"), logical.ErrInvalidRequest
+	}
+	return nil, nil
+}
+
+// childNamespace returns the namespace with the given relative path directly
+// underneath the namespace of the request, or nil if there is none
+func (b *SystemBackend) childNamespace(req *logical.Request, path string) *Namespace {
+	path = strings.Trim(path, "/") + "/"
+	for _, child := range b.Core.namespaceChildren(b.namespace(req)) {
+		if strings.TrimPrefix(child.Path, b.namespace(req).Path) == path {
+			return child
+		}
+	}
+	return nil
+}
+
+func (b *SystemBackend) handleNamespacesExistenceCheck(
+	req *logical.Request, data *framework.FieldData) (bool, error) {
+	return b.childNamespace(req, data.Get("path").(string)) != nil, nil
+}
+
+// handleNamespacesList lists the namespaces directly underneath the namespace
+// of the request
+func (b *SystemBackend) handleNamespacesList(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	parent := b.namespace(req)
+
+	var keys []string
+	for _, child := range b.Core.namespaceChildren(parent) {
+		keys = append(keys, strings.TrimPrefix(child.Path, parent.Path))
+	}
+	sort.Strings(keys)
+	return logical.ListResponse(keys), nil
+}
+
+// handleNamespacesRead returns a namespace underneath the namespace of the
+// request
+func (b *SystemBackend) handleNamespacesRead(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	ns := b.childNamespace(req, data.Get("path").(string))
+	if ns == nil {
+		return nil, nil
+	}
+	return b.namespaceResponse(req, ns), nil
+}
+
+// handleNamespacesCreate creates a namespace underneath the namespace of the
+// request
+func (b *SystemBackend) handleNamespacesCreate(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	path := data.Get("path").(string)
+	if ns := b.childNamespace(req, path); ns != nil {
+		return b.namespaceResponse(req, ns), nil
+	}
+
+	ns, err := b.Core.createNamespace(b.namespace(req), path)
+	if err != nil {
+		b.Backend.Logger().Error("sys: namespace creation failed", "path", path, "error", err)
+		return handleError(err)
+	}
+	return b.namespaceResponse(req, ns), nil
+}
+
+// handleNamespacesDelete deletes a namespace underneath the namespace of the
+// request, along with everything in it
+func (b *SystemBackend) handleNamespacesDelete(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	path := data.Get("path").(string)
+	ns := b.childNamespace(req, path)
+	if ns == nil {
+		return nil, nil
+	}
+
+	if err := b.Core.deleteNamespace(ns); err != nil {
+		b.Backend.Logger().Error("sys: namespace deletion failed", "path", path, "error", err)
+		return handleError(err)
+	}
+	return nil, nil
+}
+
+func (b *SystemBackend) namespaceResponse(req *logical.Request, ns *Namespace) *logical.Response {
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"id":   ns.ID,
+			"path": strings.TrimPrefix(ns.Path, b.namespace(req).Path),
+		},
+	}
+}
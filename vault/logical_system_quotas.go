@@ -0,0 +1,227 @@
+package vault
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+// quotaPaths returns the paths used to manage the rate-limit and lease-count
+// quotas
+func (b *SystemBackend) quotaPaths() []*framework.Path {
+	return []*framework.Path{
+		&framework.Path{
+			Pattern: "quotas/rate-limit/?$",
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ListOperation: b.handleQuotasList(QuotaTypeRateLimit),
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["quotas-list"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["quotas-list"][1]),
+		},
+
+		&framework.Path{
+			Pattern: "quotas/rate-limit/(?P<name>.+)",
+
+			Fields: map[string]*framework.FieldSchema{
+				"name": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: strings.TrimSpace(sysHelp["quotas-name"][0]),
+				},
+				"path": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: strings.TrimSpace(sysHelp["quotas-path"][0]),
+				},
+				"rate": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: strings.TrimSpace(sysHelp["quotas-rate"][0]),
+				},
+				"burst": &framework.FieldSchema{
+					Type:        framework.TypeInt,
+					Description: strings.TrimSpace(sysHelp["quotas-burst"][0]),
+				},
+				"scope": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Default:     RateLimitScopeClient,
+					Description: strings.TrimSpace(sysHelp["quotas-scope"][0]),
+				},
+			},
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ReadOperation:   b.handleQuotasRead(QuotaTypeRateLimit),
+				logical.CreateOperation: b.handleRateLimitQuotaUpdate,
+				logical.UpdateOperation: b.handleRateLimitQuotaUpdate,
+				logical.DeleteOperation: b.handleQuotasDelete(QuotaTypeRateLimit),
+			},
+
+			ExistenceCheck: b.handleQuotasExistenceCheck(QuotaTypeRateLimit),
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["quotas-rate-limit"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["quotas-rate-limit"][1]),
+		},
+
+		&framework.Path{
+			Pattern: "quotas/lease-count/?$",
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ListOperation: b.handleQuotasList(QuotaTypeLeaseCount),
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["quotas-list"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["quotas-list"][1]),
+		},
+
+		&framework.Path{
+			Pattern: "quotas/lease-count/(?P<name>.+)",
+
+			Fields: map[string]*framework.FieldSchema{
+				"name": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: strings.TrimSpace(sysHelp["quotas-name"][0]),
+				},
+				"path": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: strings.TrimSpace(sysHelp["quotas-path"][0]),
+				},
+				"max_leases": &framework.FieldSchema{
+					Type:        framework.TypeInt,
+					Description: strings.TrimSpace(sysHelp["quotas-max-leases"][0]),
+				},
+			},
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ReadOperation:   b.handleQuotasRead(QuotaTypeLeaseCount),
+				logical.CreateOperation: b.handleLeaseCountQuotaUpdate,
+				logical.UpdateOperation: b.handleLeaseCountQuotaUpdate,
+				logical.DeleteOperation: b.handleQuotasDelete(QuotaTypeLeaseCount),
+			},
+
+			ExistenceCheck: b.handleQuotasExistenceCheck(QuotaTypeLeaseCount),
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["quotas-lease-count"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["quotas-lease-count"][1]),
+		},
+	}
+}
+
+func (b *SystemBackend) handleQuotasExistenceCheck(quotaType string) func(*logical.Request, *framework.FieldData) (bool, error) {
+	return func(req *logical.Request, data *framework.FieldData) (bool, error) {
+		return b.Core.quotas.Quota(quotaType, data.Get("name").(string)) != nil, nil
+	}
+}
+
+// handleQuotasList lists the quotas of a type
+func (b *SystemBackend) handleQuotasList(quotaType string) framework.OperationFunc {
+	return func(req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		names := b.Core.quotas.QuotaNames(quotaType)
+		sort.Strings(names)
+		return logical.ListResponse(names), nil
+	}
+}
+
+// handleQuotasRead returns the definition of a quota. Lease-count quotas also
+// return the number of leases currently counted against them.
+func (b *SystemBackend) handleQuotasRead(quotaType string) framework.OperationFunc {
+	return func(req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		name := data.Get("name").(string)
+		quota := b.Core.quotas.Quota(quotaType, name)
+		if quota == nil {
+			return nil, nil
+		}
+
+		resp := &logical.Response{
+			Data: map[string]interface{}{
+				"name": quota.Name,
+				"type": quota.Type,
+				"path": quota.Path,
+			},
+		}
+		switch quotaType {
+		case QuotaTypeRateLimit:
+			resp.Data["rate"] = quota.Rate
+			resp.Data["burst"] = quota.Burst
+			resp.Data["scope"] = quota.Scope
+		case QuotaTypeLeaseCount:
+			resp.Data["max_leases"] = quota.MaxLeases
+			resp.Data["counter"] = b.Core.quotas.LeaseCount(name)
+		}
+		return resp, nil
+	}
+}
+
+// handleRateLimitQuotaUpdate creates or updates a rate-limit quota
+func (b *SystemBackend) handleRateLimitQuotaUpdate(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := data.Get("name").(string)
+
+	quota := &Quota{
+		Name: name,
+		Type: QuotaTypeRateLimit,
+	}
+	if existing := b.Core.quotas.Quota(QuotaTypeRateLimit, name); existing != nil {
+		*quota = *existing
+	}
+
+	if path, ok := data.GetOk("path"); ok {
+		quota.Path = path.(string)
+	}
+	if rateRaw, ok := data.GetOk("rate"); ok {
+		rate, err := strconv.ParseFloat(rateRaw.(string), 64)
+		if err != nil {
+			return logical.ErrorResponse(fmt.Sprintf("invalid rate: %v", err)), logical.ErrInvalidRequest
+		}
+		quota.Rate = rate
+	}
+	if burst, ok := data.GetOk("burst"); ok {
+		quota.Burst = burst.(int)
+	}
+	if scope, ok := data.GetOk("scope"); ok {
+		quota.Scope = scope.(string)
+	}
+
+	if err := b.Core.quotas.SetQuota(quota); err != nil {
+		return handleError(err)
+	}
+	return nil, nil
+}
+
+// handleLeaseCountQuotaUpdate creates or updates a lease-count quota
+func (b *SystemBackend) handleLeaseCountQuotaUpdate(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := data.Get("name").(string)
+
+	quota := &Quota{
+		Name: name,
+		Type: QuotaTypeLeaseCount,
+	}
+	if existing := b.Core.quotas.Quota(QuotaTypeLeaseCount, name); existing != nil {
+		*quota = *existing
+	}
+
+	if path, ok := data.GetOk("path"); ok {
+		quota.Path = path.(string)
+	}
+	if maxLeases, ok := data.GetOk("max_leases"); ok {
+		quota.MaxLeases = maxLeases.(int)
+	}
+
+	if err := b.Core.quotas.SetQuota(quota); err != nil {
+		return handleError(err)
+	}
+	return nil, nil
+}
+
+// handleQuotasDelete removes a quota
+func (b *SystemBackend) handleQuotasDelete(quotaType string) framework.OperationFunc {
+	return func(req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		if err := b.Core.quotas.DeleteQuota(quotaType, data.Get("name").(string)); err != nil {
+			return handleError(err)
+		}
+		return nil, nil
+	}
+}
@@ -41,6 +41,9 @@ func TestSystemBackend_RootPaths(t *testing.T) {
 		"leases/revoke-prefix/*",
 		"leases/revoke-force/*",
 		"leases/lookup/*",
+		"leases/irrevocable",
+		"storage/raft/*",
+		"quotas/*",
 	}
 
 	b := testSystemBackend(t)
@@ -1144,6 +1147,7 @@ func TestSystemBackend_authTable(t *testing.T) {
 			"config": map[string]interface{}{
 				"default_lease_ttl": int64(0),
 				"max_lease_ttl":     int64(0),
+				"token_type":        "default-service",
 			},
 			"local": false,
 		},
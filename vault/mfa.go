@@ -0,0 +1,664 @@
+package vault
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/helper/identity"
+	"github.com/hashicorp/vault/helper/strutil"
+	"github.com/hashicorp/vault/logical"
+	log "github.com/mgutz/logxi/v1"
+	cache "github.com/patrickmn/go-cache"
+)
+
+const (
+	// mfaBarrierPrefix is the prefix, within the system view, under which
+	// the MFA configuration is stored
+	mfaBarrierPrefix = "mfa/"
+
+	// mfaMethodPrefix and mfaLoginEnforcementPrefix are the prefixes,
+	// within the MFA view, of the MFA methods and login enforcements
+	mfaMethodPrefix           = "method/"
+	mfaLoginEnforcementPrefix = "login-enforcement/"
+
+	// mfaLoginRequestTTL is how long a login held for MFA can be completed
+	mfaLoginRequestTTL = 5 * time.Minute
+
+	// MFATypeTOTP validates a passcode against the TOTP secret of the entity
+	MFATypeTOTP = "totp"
+
+	// MFATypeOkta sends an Okta Verify push to the user of the entity
+	MFATypeOkta = "okta"
+
+	// MFATypeDuo sends a Duo push, or validates a Duo passcode, for the user
+	// of the entity
+	MFATypeDuo = "duo"
+
+	// MFATypePingID sends a PingID push to the user of the entity
+	MFATypePingID = "pingid"
+)
+
+// errMFARequestNotFound is returned when validating an MFA request ID that
+// is unknown, expired or already validated
+var errMFARequestNotFound = errors.New("invalid or expired MFA request ID")
+
+// mfaTypes are the supported MFA method types
+var mfaTypes = []string{
+	MFATypeTOTP,
+	MFATypeOkta,
+	MFATypeDuo,
+	MFATypePingID,
+}
+
+// MFAMethod is the stored configuration of an MFA method. Method names are
+// unique across types, since they are used to refer to methods from login
+// enforcements and MFA credentials.
+type MFAMethod struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Type string `json:"type"`
+
+	// MountAccessor and UsernameFormat map an entity to the username of the
+	// push methods. The name of the alias of the entity on the mount is used
+	// as the username, unless a format is given.
+	MountAccessor  string `json:"mount_accessor,omitempty"`
+	UsernameFormat string `json:"username_format,omitempty"`
+
+	// TOTP parameters used to generate the secrets of entities
+	Issuer    string `json:"issuer,omitempty"`
+	Period    uint   `json:"period,omitempty"`
+	KeySize   uint   `json:"key_size,omitempty"`
+	QRSize    int    `json:"qr_size,omitempty"`
+	Algorithm string `json:"algorithm,omitempty"`
+	Digits    int    `json:"digits,omitempty"`
+	Skew      uint   `json:"skew,omitempty"`
+
+	// Okta API access
+	OrgName  string `json:"org_name,omitempty"`
+	APIToken string `json:"api_token,omitempty"`
+	BaseURL  string `json:"base_url,omitempty"`
+
+	// Duo API access
+	IntegrationKey string `json:"integration_key,omitempty"`
+	SecretKey      string `json:"secret_key,omitempty"`
+	APIHostname    string `json:"api_hostname,omitempty"`
+	PushInfo       string `json:"push_info,omitempty"`
+
+	// PingID settings, parsed from the settings file of the organization
+	SettingsFileBase64 string `json:"settings_file_base64,omitempty"`
+	UseBase64Key       string `json:"use_base64_key,omitempty"`
+	UseSignature       bool   `json:"use_signature,omitempty"`
+	IdpURL             string `json:"idp_url,omitempty"`
+	OrgAlias           string `json:"org_alias,omitempty"`
+	AdminURL           string `json:"admin_url,omitempty"`
+	AuthenticatorURL   string `json:"authenticator_url,omitempty"`
+	Token              string `json:"token,omitempty"`
+}
+
+// usesPasscode returns whether the method is validated with a passcode
+// rather than a push to a device
+func (m *MFAMethod) usesPasscode() bool {
+	return m.Type == MFATypeTOTP
+}
+
+// MFALoginEnforcement requires the listed MFA methods to be validated for
+// logins through the given auth mounts, or through auth methods of the given
+// types
+type MFALoginEnforcement struct {
+	Name                string   `json:"name"`
+	MFAMethodNames      []string `json:"mfa_method_names"`
+	AuthMethodAccessors []string `json:"auth_method_accessors"`
+	AuthMethodTypes     []string `json:"auth_method_types"`
+}
+
+// pendingMFALogin is a login that is held until its MFA methods are
+// validated. It keeps the original request so that the token is created as
+// if the login completed directly.
+type pendingMFALogin struct {
+	req       *logical.Request
+	resp      *logical.Response
+	namespace *Namespace
+	entityID  string
+	methods   []*MFAMethod
+}
+
+// MFAManager holds the MFA methods and login enforcements, and the logins
+// waiting for MFA validation. Pending logins and used passcodes are only kept
+// in the memory of the active node, which handles all logins.
+type MFAManager struct {
+	core   *Core
+	view   *BarrierView
+	logger log.Logger
+
+	lock         sync.RWMutex
+	methods      map[string]*MFAMethod
+	enforcements map[string]*MFALoginEnforcement
+
+	pendingLogins *cache.Cache
+	usedPasscodes *cache.Cache
+}
+
+// setupMFA is invoked after the identity store is loaded to load the MFA
+// methods and login enforcements
+func (c *Core) setupMFA() error {
+	m := &MFAManager{
+		core:          c,
+		view:          c.systemBarrierView.SubView(mfaBarrierPrefix),
+		logger:        c.logger,
+		methods:       make(map[string]*MFAMethod),
+		enforcements:  make(map[string]*MFALoginEnforcement),
+		pendingLogins: cache.New(mfaLoginRequestTTL, time.Minute),
+		usedPasscodes: cache.New(0, 30*time.Second),
+	}
+
+	names, err := m.view.List(mfaMethodPrefix)
+	if err != nil {
+		return fmt.Errorf("failed to list MFA methods: %v", err)
+	}
+	for _, name := range names {
+		method := new(MFAMethod)
+		if err := m.readEntry(mfaMethodPrefix+name, method); err != nil {
+			return err
+		}
+		m.methods[name] = method
+	}
+
+	names, err = m.view.List(mfaLoginEnforcementPrefix)
+	if err != nil {
+		return fmt.Errorf("failed to list MFA login enforcements: %v", err)
+	}
+	for _, name := range names {
+		enforcement := new(MFALoginEnforcement)
+		if err := m.readEntry(mfaLoginEnforcementPrefix+name, enforcement); err != nil {
+			return err
+		}
+		m.enforcements[name] = enforcement
+	}
+
+	c.mfa = m
+	return nil
+}
+
+// teardownMFA is used before we seal the vault to forget the MFA
+// configuration and the pending logins
+func (c *Core) teardownMFA() error {
+	c.mfa = nil
+	return nil
+}
+
+func (m *MFAManager) readEntry(key string, out interface{}) error {
+	entry, err := m.view.Get(key)
+	if err != nil {
+		return fmt.Errorf("failed to read MFA configuration: %v", err)
+	}
+	if entry == nil {
+		return nil
+	}
+	if err := entry.DecodeJSON(out); err != nil {
+		return fmt.Errorf("failed to decode MFA configuration: %v", err)
+	}
+	return nil
+}
+
+func (m *MFAManager) writeEntry(key string, in interface{}) error {
+	entry, err := logical.StorageEntryJSON(key, in)
+	if err != nil {
+		return fmt.Errorf("failed to encode MFA configuration: %v", err)
+	}
+	if err := m.view.Put(entry); err != nil {
+		return fmt.Errorf("failed to persist MFA configuration: %v", err)
+	}
+	return nil
+}
+
+// Method returns a copy of the named MFA method, or nil if there is none
+func (m *MFAManager) Method(name string) *MFAMethod {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	method, ok := m.methods[name]
+	if !ok {
+		return nil
+	}
+	ret := *method
+	return &ret
+}
+
+// MethodNames returns the sorted names of the MFA methods of the given type,
+// or of all methods if the type is empty
+func (m *MFAManager) MethodNames(methodType string) []string {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	var names []string
+	for name, method := range m.methods {
+		if methodType == "" || method.Type == methodType {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SetMethod stores an MFA method, replacing the method of the same name. A
+// method cannot change its type.
+func (m *MFAManager) SetMethod(method *MFAMethod) error {
+	if !strutil.StrListContains(mfaTypes, method.Type) {
+		return fmt.Errorf("unknown MFA method type %q", method.Type)
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if existing, ok := m.methods[method.Name]; ok {
+		if existing.Type != method.Type {
+			return fmt.Errorf("MFA method %q already exists with type %q", method.Name, existing.Type)
+		}
+		method.ID = existing.ID
+	}
+	if method.ID == "" {
+		id, err := uuid.GenerateUUID()
+		if err != nil {
+			return err
+		}
+		method.ID = id
+	}
+
+	if err := m.writeEntry(mfaMethodPrefix+method.Name, method); err != nil {
+		return err
+	}
+	m.methods[method.Name] = method
+	return nil
+}
+
+// DeleteMethod removes an MFA method. Methods still required by a login
+// enforcement cannot be removed.
+func (m *MFAManager) DeleteMethod(name string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for _, enforcement := range m.enforcements {
+		if strutil.StrListContains(enforcement.MFAMethodNames, name) {
+			return fmt.Errorf("MFA method %q is used by login enforcement %q", name, enforcement.Name)
+		}
+	}
+
+	if err := m.view.Delete(mfaMethodPrefix + name); err != nil {
+		return fmt.Errorf("failed to delete MFA method: %v", err)
+	}
+	delete(m.methods, name)
+	return nil
+}
+
+// LoginEnforcement returns a copy of the named login enforcement, or nil if
+// there is none
+func (m *MFAManager) LoginEnforcement(name string) *MFALoginEnforcement {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	enforcement, ok := m.enforcements[name]
+	if !ok {
+		return nil
+	}
+	ret := *enforcement
+	return &ret
+}
+
+// LoginEnforcementNames returns the sorted names of the login enforcements
+func (m *MFAManager) LoginEnforcementNames() []string {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	var names []string
+	for name := range m.enforcements {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SetLoginEnforcement validates and stores a login enforcement, replacing the
+// enforcement of the same name
+func (m *MFAManager) SetLoginEnforcement(enforcement *MFALoginEnforcement) error {
+	if len(enforcement.MFAMethodNames) == 0 {
+		return fmt.Errorf("at least one MFA method must be given")
+	}
+	if len(enforcement.AuthMethodAccessors) == 0 && len(enforcement.AuthMethodTypes) == 0 {
+		return fmt.Errorf("at least one auth method accessor or type must be given")
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for _, name := range enforcement.MFAMethodNames {
+		if _, ok := m.methods[name]; !ok {
+			return fmt.Errorf("unknown MFA method %q", name)
+		}
+	}
+	for _, accessor := range enforcement.AuthMethodAccessors {
+		if m.core.router.MatchingMountByAccessor(accessor) == nil {
+			return fmt.Errorf("unknown auth method accessor %q", accessor)
+		}
+	}
+
+	if err := m.writeEntry(mfaLoginEnforcementPrefix+enforcement.Name, enforcement); err != nil {
+		return err
+	}
+	m.enforcements[enforcement.Name] = enforcement
+	return nil
+}
+
+// DeleteLoginEnforcement removes a login enforcement
+func (m *MFAManager) DeleteLoginEnforcement(name string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if err := m.view.Delete(mfaLoginEnforcementPrefix + name); err != nil {
+		return fmt.Errorf("failed to delete MFA login enforcement: %v", err)
+	}
+	delete(m.enforcements, name)
+	return nil
+}
+
+// loginMethods returns the MFA methods that the login enforcements require
+// for a login request, sorted by name
+func (m *MFAManager) loginMethods(req *logical.Request) []*MFAMethod {
+	if m == nil {
+		return nil
+	}
+
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	required := make(map[string]*MFAMethod)
+	for _, enforcement := range m.enforcements {
+		if !strutil.StrListContains(enforcement.AuthMethodAccessors, req.MountAccessor) &&
+			!strutil.StrListContains(enforcement.AuthMethodTypes, req.MountType) {
+			continue
+		}
+		for _, name := range enforcement.MFAMethodNames {
+			if method, ok := m.methods[name]; ok {
+				required[name] = method
+			}
+		}
+	}
+
+	var names []string
+	for name := range required {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	methods := make([]*MFAMethod, 0, len(names))
+	for _, name := range names {
+		ret := *required[name]
+		methods = append(methods, &ret)
+	}
+	return methods
+}
+
+// holdLogin keeps a login until its MFA methods are validated and returns
+// the response telling the client which methods to validate
+func (m *MFAManager) holdLogin(req *logical.Request, resp *logical.Response, ns *Namespace, entity *identity.Entity, methods []*MFAMethod) (*logical.Response, error) {
+	if entity == nil {
+		return logical.ErrorResponse("MFA is required for this login, but the auth method did not return an identity alias"), logical.ErrPermissionDenied
+	}
+
+	requestID, err := uuid.GenerateUUID()
+	if err != nil {
+		return nil, err
+	}
+	m.pendingLogins.SetDefault(requestID, &pendingMFALogin{
+		req:       req,
+		resp:      resp,
+		namespace: ns,
+		entityID:  entity.ID,
+		methods:   methods,
+	})
+
+	var required []map[string]interface{}
+	for _, method := range methods {
+		required = append(required, map[string]interface{}{
+			"name":          method.Name,
+			"type":          method.Type,
+			"uses_passcode": method.usesPasscode(),
+		})
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"mfa_request_id": requestID,
+			"mfa_methods":    required,
+		},
+	}, nil
+}
+
+// validateLogin validates the MFA credentials given for a held login and
+// returns the login on success. Each login can be validated once; a failed
+// validation requires the client to log in again.
+func (m *MFAManager) validateLogin(requestID string, creds map[string][]string, remoteAddr string) (*pendingMFALogin, error) {
+	raw, ok := m.pendingLogins.Get(requestID)
+	if !ok {
+		return nil, errMFARequestNotFound
+	}
+	m.pendingLogins.Delete(requestID)
+	pending := raw.(*pendingMFALogin)
+
+	iStore := m.core.namespaceIdentityStore(pending.namespace)
+	if iStore == nil {
+		return nil, fmt.Errorf("identity store of the login is unavailable")
+	}
+	entity, err := iStore.memDBEntityByID(pending.entityID, false)
+	if err != nil {
+		return nil, err
+	}
+	if entity == nil {
+		return nil, fmt.Errorf("entity of the login no longer exists")
+	}
+
+	for _, method := range pending.methods {
+		if err := m.validateMethod(method, entity, creds[method.Name], remoteAddr); err != nil {
+			return nil, fmt.Errorf("MFA method %q: %v", method.Name, err)
+		}
+	}
+	return pending, nil
+}
+
+// validateRequestMFA validates the MFA methods that the policies of a token
+// require for a request, using the MFA credentials supplied with the request
+func (c *Core) validateRequestMFA(req *logical.Request, entity *identity.Entity, methodNames []string) error {
+	if entity == nil {
+		return fmt.Errorf("MFA is required, but the token has no entity")
+	}
+
+	var remoteAddr string
+	if req.Connection != nil {
+		remoteAddr = req.Connection.RemoteAddr
+	}
+
+	for _, name := range methodNames {
+		method := c.mfa.Method(name)
+		if method == nil {
+			return fmt.Errorf("MFA method %q required by policy does not exist", name)
+		}
+		if err := c.mfa.validateMethod(method, entity, req.MFACreds[name], remoteAddr); err != nil {
+			return fmt.Errorf("MFA method %q: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// validateMethod validates an MFA method for an entity, either by checking
+// one of the given passcodes or by sending a push to the device of the user
+func (m *MFAManager) validateMethod(method *MFAMethod, entity *identity.Entity, passcodes []string, remoteAddr string) error {
+	var passcode string
+	if len(passcodes) > 0 {
+		passcode = passcodes[0]
+	}
+
+	switch method.Type {
+	case MFATypeTOTP:
+		return m.validateTOTP(method, entity, passcode)
+	case MFATypeOkta:
+		username, err := mfaUsername(method, entity)
+		if err != nil {
+			return err
+		}
+		return validateOktaPush(method, username)
+	case MFATypeDuo:
+		username, err := mfaUsername(method, entity)
+		if err != nil {
+			return err
+		}
+		// Duo passcodes may be given as passcode=<value>
+		return validateDuo(method, username, strings.TrimPrefix(passcode, "passcode="), remoteAddr)
+	case MFATypePingID:
+		username, err := mfaUsername(method, entity)
+		if err != nil {
+			return err
+		}
+		return validatePingIDPush(method, username)
+	default:
+		return fmt.Errorf("unknown MFA method type %q", method.Type)
+	}
+}
+
+// mfaUsername returns the username of an entity for a push method. It is the
+// name of the alias of the entity on the mount of the method, formatted with
+// the username format of the method.
+func mfaUsername(method *MFAMethod, entity *identity.Entity) (string, error) {
+	var alias *identity.Alias
+	for _, a := range entity.Aliases {
+		if a.MountAccessor == method.MountAccessor {
+			alias = a
+			break
+		}
+	}
+	if alias == nil {
+		return "", fmt.Errorf("entity has no alias on mount %q", method.MountAccessor)
+	}
+
+	if method.UsernameFormat == "" {
+		return alias.Name, nil
+	}
+
+	username := method.UsernameFormat
+	username = strings.Replace(username, "{{persona.name}}", alias.Name, -1)
+	username = strings.Replace(username, "{{entity.name}}", entity.Name, -1)
+	for k, v := range alias.Metadata {
+		username = strings.Replace(username, "{{persona.metadata."+k+"}}", v, -1)
+	}
+	for k, v := range entity.Metadata {
+		username = strings.Replace(username, "{{entity.metadata."+k+"}}", v, -1)
+	}
+	if strings.Contains(username, "{{") {
+		return "", fmt.Errorf("username format %q references missing values", method.UsernameFormat)
+	}
+	return username, nil
+}
+
+// mfaIdentityStore returns the identity store holding the given entity,
+// looking in the root namespace first
+func (c *Core) mfaIdentityStore(entityID string) (*IdentityStore, *identity.Entity, error) {
+	stores := []*IdentityStore{c.identityStore}
+
+	c.namespacesLock.RLock()
+	for _, ns := range c.namespaces {
+		if iStore := c.namespaceIdentityStore(ns); iStore != nil {
+			stores = append(stores, iStore)
+		}
+	}
+	c.namespacesLock.RUnlock()
+
+	for _, iStore := range stores {
+		entity, err := iStore.memDBEntityByID(entityID, true)
+		if err != nil {
+			return nil, nil, err
+		}
+		if entity != nil {
+			return iStore, entity, nil
+		}
+	}
+	return nil, nil, nil
+}
+
+// handleLoginMFAValidate validates the MFA credentials of a held login and
+// creates its token. The credentials are given in mfa_payload as a map of MFA
+// method names to passcodes; push methods take an empty list.
+func (c *Core) handleLoginMFAValidate(req *logical.Request) (*logical.Response, *logical.Auth, error) {
+	if req.Operation != logical.UpdateOperation {
+		return nil, nil, logical.ErrUnsupportedOperation
+	}
+	if c.mfa == nil {
+		return nil, nil, ErrInternalError
+	}
+
+	requestID, _ := req.Data["mfa_request_id"].(string)
+	if requestID == "" {
+		return logical.ErrorResponse("missing mfa_request_id"), nil, logical.ErrInvalidRequest
+	}
+	creds, err := parseMFAPayload(req.Data["mfa_payload"])
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), nil, logical.ErrInvalidRequest
+	}
+
+	var remoteAddr string
+	if req.Connection != nil {
+		remoteAddr = req.Connection.RemoteAddr
+	}
+
+	pending, err := c.mfa.validateLogin(requestID, creds, remoteAddr)
+	if err == errMFARequestNotFound {
+		return logical.ErrorResponse(err.Error()), nil, logical.ErrInvalidRequest
+	}
+	if err != nil {
+		c.logger.Debug("core: MFA validation failed", "error", err)
+		return logical.ErrorResponse(err.Error()), nil, logical.ErrPermissionDenied
+	}
+
+	resp := pending.resp
+	if errResp, retAuth, err := c.createLoginToken(pending.req, resp.Auth); err != nil {
+		return errResp, retAuth, err
+	}
+	return resp, resp.Auth, nil
+}
+
+// parseMFAPayload parses the MFA credentials of a validation request. Each
+// method name maps to a list of passcodes, or to a single passcode.
+func parseMFAPayload(raw interface{}) (map[string][]string, error) {
+	creds := make(map[string][]string)
+	if raw == nil {
+		return creds, nil
+	}
+
+	payload, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("mfa_payload must be a map of MFA method names to passcodes")
+	}
+	for name, value := range payload {
+		switch value := value.(type) {
+		case nil:
+			creds[name] = nil
+		case string:
+			creds[name] = []string{value}
+		case []string:
+			creds[name] = value
+		case []interface{}:
+			for _, v := range value {
+				passcode, ok := v.(string)
+				if !ok {
+					return nil, fmt.Errorf("passcodes of MFA method %q must be strings", name)
+				}
+				creds[name] = append(creds[name], passcode)
+			}
+		default:
+			return nil, fmt.Errorf("passcodes of MFA method %q must be a list of strings", name)
+		}
+	}
+	return creds, nil
+}
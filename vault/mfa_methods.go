@@ -0,0 +1,473 @@
+package vault
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/duosecurity/duo_api_golang"
+	"github.com/duosecurity/duo_api_golang/authapi"
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/go-cleanhttp"
+	"github.com/hashicorp/vault/helper/identity"
+	otplib "github.com/pquerna/otp"
+	totplib "github.com/pquerna/otp/totp"
+)
+
+const (
+	// mfaPushTimeout is how long the user has to approve a push
+	mfaPushTimeout = time.Minute
+
+	// mfaPushPollInterval is how often the status of an Okta push is checked
+	mfaPushPollInterval = time.Second
+)
+
+// totpAlgorithms maps the supported TOTP algorithm names to their values
+var totpAlgorithms = map[string]otplib.Algorithm{
+	"SHA1":   otplib.AlgorithmSHA1,
+	"SHA256": otplib.AlgorithmSHA256,
+	"SHA512": otplib.AlgorithmSHA512,
+}
+
+// validateTOTP validates a passcode against the TOTP secret of the entity.
+// Passcodes cannot be used twice within the period they are valid in.
+func (m *MFAManager) validateTOTP(method *MFAMethod, entity *identity.Entity, passcode string) error {
+	secret, ok := entity.MFASecrets[method.Name]
+	if !ok {
+		return fmt.Errorf("entity has no TOTP secret for the method")
+	}
+	if passcode == "" {
+		return fmt.Errorf("a passcode is required")
+	}
+
+	usedKey := fmt.Sprintf("%s_%s_%s", entity.ID, method.Name, passcode)
+	if _, ok := m.usedPasscodes.Get(usedKey); ok {
+		return fmt.Errorf("passcode already used; wait until the next time period")
+	}
+
+	valid, err := totplib.ValidateCustom(passcode, secret.Key, time.Now(), totplib.ValidateOpts{
+		Period:    uint(secret.Period),
+		Skew:      uint(secret.Skew),
+		Digits:    otplib.Digits(secret.Digits),
+		Algorithm: totpAlgorithms[secret.Algorithm],
+	})
+	if err != nil && err != otplib.ErrValidateInputInvalidLength {
+		return errwrap.Wrapf("failed to validate passcode: {{err}}", err)
+	}
+	if !valid {
+		return fmt.Errorf("invalid passcode")
+	}
+
+	// Cover the skew on both sides of the current period
+	m.usedPasscodes.Set(usedKey, nil, time.Duration(secret.Period*(2+secret.Skew))*time.Second)
+	return nil
+}
+
+// generateTOTPSecret generates a TOTP secret for a method and stores it on
+// the entity. It returns the key to share with the user, or nil if the
+// entity already has a secret for the method.
+func (c *Core) generateTOTPSecret(method *MFAMethod, entityID string) (*otplib.Key, error) {
+	iStore, entity, err := c.mfaIdentityStore(entityID)
+	if err != nil {
+		return nil, err
+	}
+	if entity == nil {
+		return nil, fmt.Errorf("unknown entity %q", entityID)
+	}
+
+	lock := iStore.LockForEntityID(entityID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	// Read the entity again now that the lock is held
+	entity, err = iStore.memDBEntityByID(entityID, true)
+	if err != nil {
+		return nil, err
+	}
+	if entity == nil {
+		return nil, fmt.Errorf("unknown entity %q", entityID)
+	}
+	if _, ok := entity.MFASecrets[method.Name]; ok {
+		return nil, nil
+	}
+
+	key, err := totplib.Generate(totplib.GenerateOpts{
+		Issuer:      method.Issuer,
+		AccountName: entity.ID,
+		Period:      method.Period,
+		Digits:      otplib.Digits(method.Digits),
+		Algorithm:   totpAlgorithms[method.Algorithm],
+		SecretSize:  method.KeySize,
+	})
+	if err != nil {
+		return nil, errwrap.Wrapf("failed to generate TOTP key: {{err}}", err)
+	}
+
+	if entity.MFASecrets == nil {
+		entity.MFASecrets = make(map[string]*identity.MFASecret)
+	}
+	entity.MFASecrets[method.Name] = &identity.MFASecret{
+		MethodName: method.Name,
+		Key:        key.Secret(),
+		Period:     uint32(method.Period),
+		Algorithm:  method.Algorithm,
+		Digits:     uint32(method.Digits),
+		Skew:       uint32(method.Skew),
+	}
+	if err := iStore.upsertEntityNonLocked(entity, nil, true); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// destroyTOTPSecret removes the TOTP secret of a method from the entity
+func (c *Core) destroyTOTPSecret(methodName, entityID string) error {
+	iStore, entity, err := c.mfaIdentityStore(entityID)
+	if err != nil {
+		return err
+	}
+	if entity == nil {
+		return fmt.Errorf("unknown entity %q", entityID)
+	}
+
+	lock := iStore.LockForEntityID(entityID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	entity, err = iStore.memDBEntityByID(entityID, true)
+	if err != nil {
+		return err
+	}
+	if entity == nil {
+		return fmt.Errorf("unknown entity %q", entityID)
+	}
+	if _, ok := entity.MFASecrets[methodName]; !ok {
+		return nil
+	}
+
+	delete(entity.MFASecrets, methodName)
+	return iStore.upsertEntityNonLocked(entity, nil, true)
+}
+
+// oktaVerifyResult is the result of an Okta factor verification
+type oktaVerifyResult struct {
+	FactorResult string `json:"factorResult"`
+	Links        struct {
+		Poll struct {
+			Href string `json:"href"`
+		} `json:"poll"`
+	} `json:"_links"`
+}
+
+// validateOktaPush sends an Okta Verify push to the user and waits for it to
+// be approved
+func validateOktaPush(method *MFAMethod, username string) error {
+	baseURL := method.BaseURL
+	if baseURL == "" {
+		baseURL = "okta.com"
+	}
+	api := fmt.Sprintf("https://%s.%s/api/v1", method.OrgName, baseURL)
+	client := cleanhttp.DefaultClient()
+	client.Timeout = 10 * time.Second
+
+	var user struct {
+		ID string `json:"id"`
+	}
+	if err := oktaRequest(client, method, "GET", api+"/users/"+url.PathEscape(username), &user); err != nil {
+		return errwrap.Wrapf("failed to look up Okta user: {{err}}", err)
+	}
+
+	var factors []struct {
+		ID         string `json:"id"`
+		FactorType string `json:"factorType"`
+		Provider   string `json:"provider"`
+	}
+	if err := oktaRequest(client, method, "GET", api+"/users/"+user.ID+"/factors", &factors); err != nil {
+		return errwrap.Wrapf("failed to list Okta factors: {{err}}", err)
+	}
+	var factorID string
+	for _, factor := range factors {
+		if factor.FactorType == "push" && factor.Provider == "OKTA" {
+			factorID = factor.ID
+			break
+		}
+	}
+	if factorID == "" {
+		return fmt.Errorf("user %q has no Okta Verify push factor enrolled", username)
+	}
+
+	var result oktaVerifyResult
+	if err := oktaRequest(client, method, "POST", api+"/users/"+user.ID+"/factors/"+factorID+"/verify", &result); err != nil {
+		return errwrap.Wrapf("failed to send Okta push: {{err}}", err)
+	}
+
+	deadline := time.Now().Add(mfaPushTimeout)
+	for result.FactorResult == "WAITING" {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out waiting for the push to be approved")
+		}
+		time.Sleep(mfaPushPollInterval)
+
+		if err := oktaRequest(client, method, "GET", result.Links.Poll.Href, &result); err != nil {
+			return errwrap.Wrapf("failed to poll Okta push: {{err}}", err)
+		}
+	}
+
+	if result.FactorResult != "SUCCESS" {
+		return fmt.Errorf("push was not approved: %s", strings.ToLower(result.FactorResult))
+	}
+	return nil
+}
+
+func oktaRequest(client *http.Client, method *MFAMethod, httpMethod, url string, out interface{}) error {
+	req, err := http.NewRequest(httpMethod, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "SSWS "+method.APIToken)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// validateDuo validates a Duo passcode for the user, or sends a Duo push to
+// the user and waits for it to be approved if no passcode is given
+func validateDuo(method *MFAMethod, username, passcode, remoteAddr string) error {
+	client := authapi.NewAuthApi(*duoapi.NewDuoApi(
+		method.IntegrationKey,
+		method.SecretKey,
+		method.APIHostname,
+		"vault",
+		duoapi.SetTimeout(mfaPushTimeout),
+	))
+
+	preauth, err := client.Preauth(
+		authapi.PreauthUsername(username),
+		authapi.PreauthIpAddr(remoteAddr),
+	)
+	if err != nil || preauth == nil {
+		return fmt.Errorf("failed to call Duo preauth")
+	}
+	if preauth.StatResult.Stat != "OK" {
+		return fmt.Errorf("failed to look up Duo user: %s", duoMessage(preauth.StatResult))
+	}
+
+	switch preauth.Response.Result {
+	case "allow":
+		return nil
+	case "auth":
+	case "enroll":
+		return fmt.Errorf("%s (%s)", preauth.Response.Status_Msg, preauth.Response.Enroll_Portal_Url)
+	default:
+		return fmt.Errorf("%s", preauth.Response.Status_Msg)
+	}
+
+	factor := "push"
+	options := []func(*url.Values){authapi.AuthUsername(username)}
+	if passcode != "" {
+		factor = "passcode"
+		options = append(options, authapi.AuthPasscode(passcode))
+	} else {
+		options = append(options, authapi.AuthDevice("auto"))
+		if method.PushInfo != "" {
+			options = append(options, authapi.AuthPushinfo(method.PushInfo))
+		}
+	}
+
+	result, err := client.Auth(factor, options...)
+	if err != nil || result == nil {
+		return fmt.Errorf("failed to call Duo auth")
+	}
+	if result.StatResult.Stat != "OK" {
+		return fmt.Errorf("failed to authenticate Duo user: %s", duoMessage(result.StatResult))
+	}
+	if result.Response.Result != "allow" {
+		return fmt.Errorf("%s", result.Response.Status_Msg)
+	}
+	return nil
+}
+
+func duoMessage(result authapi.StatResult) string {
+	var msg string
+	if result.Message != nil {
+		msg = *result.Message
+	}
+	if result.Message_Detail != nil {
+		msg = msg + " (" + *result.Message_Detail + ")"
+	}
+	return msg
+}
+
+// parsePingIDSettings sets the PingID parameters of a method from the
+// base64 encoded settings file of the organization
+func parsePingIDSettings(method *MFAMethod) error {
+	settings, err := base64.StdEncoding.DecodeString(method.SettingsFileBase64)
+	if err != nil {
+		return errwrap.Wrapf("failed to decode settings file: {{err}}", err)
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(settings))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("invalid line in settings file: %q", line)
+		}
+		value := strings.TrimSpace(parts[1])
+		switch strings.TrimSpace(parts[0]) {
+		case "use_base64_key":
+			method.UseBase64Key = value
+		case "use_signature":
+			method.UseSignature = value == "true"
+		case "token":
+			method.Token = value
+		case "idp_url":
+			method.IdpURL = value
+		case "org_alias":
+			method.OrgAlias = value
+		case "admin_url":
+			method.AdminURL = value
+		case "authenticator_url":
+			method.AuthenticatorURL = value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	switch {
+	case method.UseBase64Key == "":
+		return fmt.Errorf("settings file is missing use_base64_key")
+	case method.IdpURL == "":
+		return fmt.Errorf("settings file is missing idp_url")
+	case method.OrgAlias == "":
+		return fmt.Errorf("settings file is missing org_alias")
+	case method.Token == "":
+		return fmt.Errorf("settings file is missing token")
+	}
+	return nil
+}
+
+// validatePingIDPush sends a PingID push to the user and waits for it to be
+// approved. Requests and responses of the PingID API are JWTs signed with
+// the key of the organization.
+func validatePingIDPush(method *MFAMethod, username string) error {
+	key, err := base64.StdEncoding.DecodeString(method.UseBase64Key)
+	if err != nil {
+		return errwrap.Wrapf("failed to decode PingID key: {{err}}", err)
+	}
+
+	reqBody := map[string]interface{}{
+		"reqHeader": map[string]interface{}{
+			"locale":    "en",
+			"orgAlias":  method.OrgAlias,
+			"secretKey": method.Token,
+			"timestamp": time.Now().Format("2006-01-02 15:04:05.000"),
+			"version":   "4.9",
+		},
+		"reqBody": map[string]interface{}{
+			"spAlias":  "web",
+			"userName": username,
+			"authType": "CONFIRM",
+		},
+	}
+	token, err := pingIDSign(method, key, reqBody)
+	if err != nil {
+		return err
+	}
+
+	client := cleanhttp.DefaultClient()
+	client.Timeout = mfaPushTimeout
+	resp, err := client.Post(strings.TrimSuffix(method.IdpURL, "/")+"/rest/4/authonline/do", "application/json", strings.NewReader(token))
+	if err != nil {
+		return errwrap.Wrapf("failed to send PingID push: {{err}}", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	var result struct {
+		ResponseBody struct {
+			ErrorID  int    `json:"errorId"`
+			ErrorMsg string `json:"errorMsg"`
+		} `json:"responseBody"`
+	}
+	if err := pingIDVerify(key, string(body), &result); err != nil {
+		return errwrap.Wrapf("invalid PingID response: {{err}}", err)
+	}
+	if result.ResponseBody.ErrorID != 200 {
+		return fmt.Errorf("push was not approved: %s", result.ResponseBody.ErrorMsg)
+	}
+	return nil
+}
+
+// pingIDSign returns the payload as a JWT signed with HS256
+func pingIDSign(method *MFAMethod, key []byte, payload interface{}) (string, error) {
+	header, err := json.Marshal(map[string]string{
+		"alg":       "HS256",
+		"org_alias": method.OrgAlias,
+		"token":     method.Token,
+	})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(signed))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// pingIDVerify checks the signature of a JWT returned by PingID and decodes
+// its payload
+func pingIDVerify(key []byte, token string, out interface{}) error {
+	parts := strings.Split(strings.TrimSpace(token), ".")
+	if len(parts) != 3 {
+		return fmt.Errorf("malformed token")
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return err
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if !hmac.Equal(sig, mac.Sum(nil)) {
+		return fmt.Errorf("signature mismatch")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(payload, out)
+}
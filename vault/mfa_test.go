@@ -0,0 +1,182 @@
+package vault
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+	otplib "github.com/pquerna/otp"
+	totplib "github.com/pquerna/otp/totp"
+)
+
+func TestCore_LoginMFA_TOTP(t *testing.T) {
+	noop := &NoopBackend{
+		Login: []string{"login"},
+		Response: &logical.Response{
+			Auth: &logical.Auth{
+				Policies: []string{"default"},
+				Alias: &logical.Alias{
+					Name: "testuser",
+				},
+			},
+		},
+	}
+	c, _, root := TestCoreUnsealed(t)
+	c.credentialBackends["noop"] = func(*logical.BackendConfig) (logical.Backend, error) {
+		return noop, nil
+	}
+
+	me := &MountEntry{
+		Table: credentialTableType,
+		Path:  "noop/",
+		Type:  "noop",
+	}
+	if err := c.enableCredential(me); err != nil {
+		t.Fatal(err)
+	}
+
+	login := func() *logical.Response {
+		resp, err := c.HandleRequest(&logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "auth/noop/login",
+		})
+		if err != nil || resp == nil || resp.IsError() {
+			t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+		}
+		return resp
+	}
+
+	// Log in once without MFA to create the entity
+	entityID := login().Auth.EntityID
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "sys/mfa/method/totp/my_totp")
+	req.Data["issuer"] = "vault"
+	req.ClientToken = root
+	if resp, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v, resp: %#v", err, resp)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "sys/mfa/method/totp/my_totp/admin-generate")
+	req.Data["entity_id"] = entityID
+	req.ClientToken = root
+	resp, err := c.HandleRequest(req)
+	if err != nil || resp == nil || resp.Data["url"] == nil || resp.Data["barcode"] == nil {
+		t.Fatalf("err: %v, resp: %#v", err, resp)
+	}
+	key, err := otplib.NewKeyFromURL(resp.Data["url"].(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "sys/mfa/login-enforcement/noop")
+	req.Data["mfa_method_names"] = "my_totp"
+	req.Data["auth_method_accessors"] = me.Accessor
+	req.ClientToken = root
+	if resp, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v, resp: %#v", err, resp)
+	}
+
+	// Methods used by an enforcement cannot be deleted
+	req = logical.TestRequest(t, logical.DeleteOperation, "sys/mfa/method/totp/my_totp")
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err == nil {
+		t.Fatal("expected error deleting an enforced method")
+	}
+
+	// The login is now held for MFA
+	resp = login()
+	if resp.Auth != nil {
+		t.Fatalf("expected no token before MFA validation, got %#v", resp.Auth)
+	}
+	requestID, ok := resp.Data["mfa_request_id"].(string)
+	if !ok || requestID == "" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	validate := func(requestID, passcode string) (*logical.Response, error) {
+		return c.HandleRequest(&logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "sys/mfa/validate",
+			Data: map[string]interface{}{
+				"mfa_request_id": requestID,
+				"mfa_payload": map[string]interface{}{
+					"my_totp": []interface{}{passcode},
+				},
+			},
+		})
+	}
+
+	// A wrong passcode fails the login, which must be retried
+	if _, err := validate(requestID, "000000"); err != logical.ErrPermissionDenied {
+		t.Fatalf("expected permission denied, got %v", err)
+	}
+	if _, err := validate(requestID, "000000"); err != logical.ErrInvalidRequest {
+		t.Fatalf("expected the request ID to be consumed, got %v", err)
+	}
+
+	passcode, err := totplib.GenerateCode(key.Secret(), time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	requestID = login().Data["mfa_request_id"].(string)
+	resp, err = validate(requestID, passcode)
+	if err != nil || resp == nil || resp.Auth == nil {
+		t.Fatalf("err: %v, resp: %#v", err, resp)
+	}
+	if resp.Auth.ClientToken == "" || resp.Auth.EntityID != entityID {
+		t.Fatalf("bad: %#v", resp.Auth)
+	}
+	te, err := c.tokenStore.Lookup(resp.Auth.ClientToken)
+	if err != nil || te == nil {
+		t.Fatalf("token was not created: %v", err)
+	}
+
+	// Passcodes cannot be reused
+	requestID = login().Data["mfa_request_id"].(string)
+	if _, err := validate(requestID, passcode); err != logical.ErrPermissionDenied {
+		t.Fatalf("expected permission denied, got %v", err)
+	}
+}
+
+func TestParsePingIDSettings(t *testing.T) {
+	settings := `#Auto-Generated from PingOne
+use_base64_key=c2VjcmV0
+use_signature=true
+token=abc123
+idp_url=https://idpxnyl3m.pingidentity.com/pingid
+org_alias=181459b0-9fb1-4938-8c86
+admin_url=https://idpxnyl3m.pingidentity.com/pingid
+authenticator_url=https://authenticator.pingone.com/pingid/ppm
+`
+	method := &MFAMethod{
+		SettingsFileBase64: base64.StdEncoding.EncodeToString([]byte(settings)),
+	}
+	if err := parsePingIDSettings(method); err != nil {
+		t.Fatal(err)
+	}
+	if method.UseBase64Key != "c2VjcmV0" || !method.UseSignature || method.Token != "abc123" ||
+		method.IdpURL != "https://idpxnyl3m.pingidentity.com/pingid" || method.OrgAlias != "181459b0-9fb1-4938-8c86" {
+		t.Fatalf("bad: %#v", method)
+	}
+
+	// Signed requests can be verified with the same key
+	token, err := pingIDSign(method, []byte("secret"), map[string]string{"foo": "bar"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out map[string]string
+	if err := pingIDVerify([]byte("secret"), token, &out); err != nil || out["foo"] != "bar" {
+		t.Fatalf("err: %v, out: %#v", err, out)
+	}
+	if err := pingIDVerify([]byte("other"), token, &out); err == nil {
+		t.Fatal("expected signature mismatch")
+	}
+
+	method = &MFAMethod{
+		SettingsFileBase64: base64.StdEncoding.EncodeToString([]byte("token=abc123\n")),
+	}
+	if err := parsePingIDSettings(method); err == nil {
+		t.Fatal("expected error for incomplete settings file")
+	}
+}
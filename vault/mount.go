@@ -135,11 +135,11 @@ func (t *MountTable) Hash() ([]byte, error) {
 	return hash[:], nil
 }
 
-// setTaint is used to set the taint on given entry
+// setTaint is used to set the taint on the entry with the given API path
 func (t *MountTable) setTaint(path string, value bool) *MountEntry {
 	n := len(t.Entries)
 	for i := 0; i < n; i++ {
-		if t.Entries[i].Path == path {
+		if t.Entries[i].APIPath() == path {
 			t.Entries[i].Tainted = value
 			return t.Entries[i]
 		}
@@ -147,12 +147,12 @@ func (t *MountTable) setTaint(path string, value bool) *MountEntry {
 	return nil
 }
 
-// remove is used to remove a given path entry; returns the entry that was
-// removed
+// remove is used to remove the entry with the given API path; returns the
+// entry that was removed
 func (t *MountTable) remove(path string) *MountEntry {
 	n := len(t.Entries)
 	for i := 0; i < n; i++ {
-		if entry := t.Entries[i]; entry.Path == path {
+		if entry := t.Entries[i]; entry.APIPath() == path {
 			t.Entries[i], t.Entries[n-1] = t.Entries[n-1], nil
 			t.Entries = t.Entries[:n-1]
 			return entry
@@ -182,6 +182,13 @@ type MountEntry struct {
 	Options     map[string]string `json:"options"`           // Backend options
 	Local       bool              `json:"local"`             // Local mounts are not replicated or affected by replication
 	Tainted     bool              `json:"tainted,omitempty"` // Set as a Write-Ahead flag for unmount/remount
+
+	// NamespaceID is the ID of the namespace the mount lives in; it is
+	// empty for the root namespace. Path is relative to the namespace.
+	NamespaceID string `json:"namespace_id,omitempty"`
+
+	// namespace is resolved from NamespaceID when the table is loaded
+	namespace *Namespace
 }
 
 // MountConfig is used to hold settable options
@@ -191,6 +198,10 @@ type MountConfig struct {
 	ForceNoCache    bool          `json:"force_no_cache" structs:"force_no_cache" mapstructure:"force_no_cache"`          // Override for global default
 	PluginName      string        `json:"plugin_name,omitempty" structs:"plugin_name,omitempty" mapstructure:"plugin_name"`
 	SealWrap        bool          `json:"seal_wrap" structs:"seal_wrap" mapstructure:"seal_wrap"`
+
+	// TokenType is the type of the tokens issued by logins against an auth
+	// mount
+	TokenType logical.TokenType `json:"token_type,omitempty" structs:"token_type" mapstructure:"token_type"`
 }
 
 // APIMountConfig is an embedded struct of api.MountConfigInput
@@ -200,6 +211,7 @@ type APIMountConfig struct {
 	ForceNoCache    bool   `json:"force_no_cache" structs:"force_no_cache" mapstructure:"force_no_cache"`
 	PluginName      string `json:"plugin_name,omitempty" structs:"plugin_name,omitempty" mapstructure:"plugin_name"`
 	SealWrap        bool   `json:"seal_wrap" structs:"seal_wrap" mapstructure:"seal_wrap"`
+	TokenType       string `json:"token_type" structs:"token_type" mapstructure:"token_type"`
 }
 
 // Clone returns a deep copy of the mount entry
@@ -208,7 +220,44 @@ func (e *MountEntry) Clone() (*MountEntry, error) {
 	if err != nil {
 		return nil, err
 	}
-	return cp.(*MountEntry), nil
+	clone := cp.(*MountEntry)
+	clone.namespace = e.namespace
+	return clone, nil
+}
+
+// Namespace returns the namespace the entry belongs to
+func (e *MountEntry) Namespace() *Namespace {
+	if e.namespace == nil {
+		return rootNamespace
+	}
+	return e.namespace
+}
+
+// APIPath returns the path the entry is reached at through the API and the
+// router, which includes the path of its namespace and, for credential
+// backends, the auth/ prefix
+func (e *MountEntry) APIPath() string {
+	path := e.Path
+	if e.Table == credentialTableType {
+		path = credentialRoutePrefix + path
+	}
+	return e.Namespace().Path + path
+}
+
+// setEntryNamespace resolves the namespace of an entry from its NamespaceID
+func (c *Core) setEntryNamespace(entry *MountEntry) error {
+	if entry.NamespaceID == "" {
+		// Entries of the root namespace leave it unset
+		return nil
+	}
+
+	ns := c.namespaceByID(entry.NamespaceID)
+	if ns == nil {
+		c.logger.Error("core: namespace of mount entry not found", "path", entry.Path, "namespace_id", entry.NamespaceID)
+		return fmt.Errorf("namespace %q of mount %q not found", entry.NamespaceID, entry.Path)
+	}
+	entry.namespace = ns
+	return nil
 }
 
 // Mount is used to mount a new backend to the mount table.
@@ -218,6 +267,10 @@ func (c *Core) mount(entry *MountEntry) error {
 		entry.Path += "/"
 	}
 
+	if err := c.setEntryNamespace(entry); err != nil {
+		return err
+	}
+
 	// Prevent protected paths from being mounted
 	for _, p := range protectedMounts {
 		if strings.HasPrefix(entry.Path, p) {
@@ -238,11 +291,18 @@ func (c *Core) mountInternal(entry *MountEntry) error {
 	c.mountsLock.Lock()
 	defer c.mountsLock.Unlock()
 
+	path := entry.APIPath()
+
 	// Verify there is no conflicting mount
-	if match := c.router.MatchingMount(entry.Path); match != "" {
+	if match := c.router.MatchingMount(path); match != "" {
 		return logical.CodedError(409, fmt.Sprintf("existing mount at %s", match))
 	}
 
+	// Ensure the path does not reach into a nested namespace
+	if ns := c.namespaceByPath(path); ns != entry.Namespace() {
+		return logical.CodedError(409, fmt.Sprintf("path is in use by namespace %s", ns.Path))
+	}
+
 	// Generate a new UUID and view
 	if entry.UUID == "" {
 		entryUUID, err := uuid.GenerateUUID()
@@ -299,12 +359,12 @@ func (c *Core) mountInternal(entry *MountEntry) error {
 	}
 	c.mounts = newTable
 
-	if err := c.router.Mount(backend, entry.Path, entry, view); err != nil {
+	if err := c.router.Mount(backend, path, entry, view); err != nil {
 		return err
 	}
 
 	if c.logger.IsInfo() {
-		c.logger.Info("core: successful mount", "path", entry.Path, "type", entry.Type)
+		c.logger.Info("core: successful mount", "path", path, "type", entry.Type)
 	}
 	return nil
 }
@@ -318,8 +378,9 @@ func (c *Core) unmount(path string) error {
 	}
 
 	// Prevent protected paths from being unmounted
+	relPath := strings.TrimPrefix(path, c.namespaceByPath(path).Path)
 	for _, p := range protectedMounts {
-		if strings.HasPrefix(path, p) {
+		if strings.HasPrefix(relPath, p) {
 			return fmt.Errorf("cannot unmount '%s'", path)
 		}
 	}
@@ -475,12 +536,18 @@ func (c *Core) remount(src, dst string) error {
 	}
 
 	// Prevent protected paths from being remounted
+	ns := c.namespaceByPath(src)
 	for _, p := range protectedMounts {
-		if strings.HasPrefix(src, p) {
+		if strings.HasPrefix(strings.TrimPrefix(src, ns.Path), p) {
 			return fmt.Errorf("cannot remount '%s'", src)
 		}
 	}
 
+	// Mounts cannot be moved between namespaces
+	if dstNS := c.namespaceByPath(dst); dstNS != ns {
+		return fmt.Errorf("cannot remount '%s' into namespace '%s'", src, dstNS.Path)
+	}
+
 	// Verify exact match of the route
 	match := c.router.MatchingMount(src)
 	if match == "" || src != match {
@@ -514,8 +581,8 @@ func (c *Core) remount(src, dst string) error {
 	c.mountsLock.Lock()
 	var entry *MountEntry
 	for _, entry = range c.mounts.Entries {
-		if entry.Path == src {
-			entry.Path = dst
+		if entry.APIPath() == src {
+			entry.Path = strings.TrimPrefix(dst, ns.Path)
 			entry.Tainted = false
 			break
 		}
@@ -529,7 +596,7 @@ func (c *Core) remount(src, dst string) error {
 
 	// Update the mount table
 	if err := c.persistMounts(c.mounts, entry.Local); err != nil {
-		entry.Path = src
+		entry.Path = strings.TrimPrefix(src, ns.Path)
 		entry.Tainted = true
 		c.mountsLock.Unlock()
 		c.logger.Error("core: failed to update mounts table", "error", err)
@@ -606,7 +673,7 @@ func (c *Core) loadMounts() error {
 		for _, requiredMount := range c.requiredMountTable().Entries {
 			foundRequired := false
 			for _, coreMount := range c.mounts.Entries {
-				if coreMount.Type == requiredMount.Type {
+				if coreMount.Type == requiredMount.Type && coreMount.NamespaceID == "" {
 					foundRequired = true
 					break
 				}
@@ -627,6 +694,9 @@ func (c *Core) loadMounts() error {
 
 		// Upgrade to table-scoped entries
 		for _, entry := range c.mounts.Entries {
+			if err := c.setEntryNamespace(entry); err != nil {
+				return err
+			}
 			if entry.Type == "cubbyhole" && !entry.Local {
 				entry.Local = true
 				needPersist = true
@@ -746,7 +816,7 @@ func (c *Core) setupMounts() error {
 
 		// Initialize the backend, special casing for system
 		barrierPath := backendBarrierPrefix + entry.UUID + "/"
-		if entry.Type == "system" {
+		if entry.Type == "system" && entry.NamespaceID == "" {
 			barrierPath = systemBarrierPrefix
 		}
 
@@ -791,19 +861,19 @@ func (c *Core) setupMounts() error {
 
 	ROUTER_MOUNT:
 		// Mount the backend
-		err = c.router.Mount(backend, entry.Path, entry, view)
+		err = c.router.Mount(backend, entry.APIPath(), entry, view)
 		if err != nil {
-			c.logger.Error("core: failed to mount entry", "path", entry.Path, "error", err)
+			c.logger.Error("core: failed to mount entry", "path", entry.APIPath(), "error", err)
 			return errLoadMountsFailed
 		}
 
 		if c.logger.IsInfo() {
-			c.logger.Info("core: successfully mounted backend", "type", entry.Type, "path", entry.Path)
+			c.logger.Info("core: successfully mounted backend", "type", entry.Type, "path", entry.APIPath())
 		}
 
 		// Ensure the path is tainted if set in the mount table
 		if entry.Tainted {
-			c.router.Taint(entry.Path)
+			c.router.Taint(entry.APIPath())
 		}
 	}
 	return nil
@@ -818,7 +888,7 @@ func (c *Core) unloadMounts() error {
 	if c.mounts != nil {
 		mountTable := c.mounts.shallowClone()
 		for _, e := range mountTable.Entries {
-			backend := c.router.MatchingBackend(e.Path)
+			backend := c.router.MatchingBackend(e.APIPath())
 			if backend != nil {
 				backend.Cleanup()
 			}
@@ -992,13 +1062,17 @@ func (c *Core) singletonMountTables() (mounts, auth *MountTable) {
 func (c *Core) setCoreBackend(entry *MountEntry, backend logical.Backend, view *BarrierView) {
 	switch entry.Type {
 	case "system":
-		c.systemBackend = backend.(*SystemBackend)
-		c.systemBarrierView = view
+		if entry.NamespaceID == "" {
+			c.systemBackend = backend.(*SystemBackend)
+			c.systemBarrierView = view
+		}
 	case "cubbyhole":
 		ch := backend.(*CubbyholeBackend)
 		ch.saltUUID = entry.UUID
 		ch.storageView = view
 	case "identity":
-		c.identityStore = backend.(*IdentityStore)
+		if entry.NamespaceID == "" {
+			c.identityStore = backend.(*IdentityStore)
+		}
 	}
 }
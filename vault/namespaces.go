@@ -0,0 +1,461 @@
+package vault
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/helper/consts"
+	"github.com/hashicorp/vault/logical"
+)
+
+const (
+	// coreNamespacesPath is the prefix under which the namespace records are
+	// stored. Like the mount tables they are protected by the barrier.
+	coreNamespacesPath = "core/namespaces/"
+
+	// namespaceBarrierPrefix is the prefix, within the system view, under
+	// which the policies of each namespace are stored
+	namespaceBarrierPrefix = "namespaces/"
+)
+
+var (
+	// rootNamespace is the namespace of everything that is not explicitly
+	// placed in a namespace, which covers all data written before namespaces
+	// existed. Its empty ID and path are never persisted.
+	rootNamespace = &Namespace{
+		ID:   "",
+		Path: "",
+	}
+
+	// namespaceSysPaths are the system backend paths that are available
+	// inside of a namespace. Entries ending in a slash are prefixes. All other
+	// system paths, such as audit devices, seal management, raw storage and
+	// plugins, only exist in the root namespace.
+	namespaceSysPaths = []string{
+		"auth",
+		"auth/",
+		"mounts",
+		"mounts/",
+		"remount",
+		"policy",
+		"policy/",
+		"policies/acl",
+		"policies/acl/",
+		"capabilities",
+		"capabilities-self",
+		"capabilities-accessor",
+		"renew",
+		"renew/",
+		"revoke",
+		"revoke/",
+		"revoke-prefix/",
+		"leases/lookup",
+		"leases/lookup/",
+		"leases/renew",
+		"leases/renew/",
+		"leases/revoke",
+		"leases/revoke/",
+		"leases/revoke-prefix/",
+		"namespaces",
+		"namespaces/",
+		"tools/",
+	}
+)
+
+// Namespace is an isolated subtree of the API. Each namespace has its own
+// mounts, auth methods, policies, identity store and tokens, all of which are
+// reached through paths prefixed with the path of the namespace.
+type Namespace struct {
+	// ID is the unique identifier of the namespace, used to associate mounts
+	// and tokens with it
+	ID string `json:"id"`
+
+	// Path is the full path of the namespace, including the paths of its
+	// parents, always ending in a slash; for example "team-a/dev/"
+	Path string `json:"path"`
+
+	// policyStore holds the policies of the namespace
+	policyStore *PolicyStore
+}
+
+// HasParent returns whether the namespace is the given namespace or nested
+// underneath it
+func (ns *Namespace) HasParent(parent *Namespace) bool {
+	return strings.HasPrefix(ns.Path, parent.Path)
+}
+
+// loadNamespaces is invoked as part of postUnseal to load the namespace
+// records; it must run before the mount and auth tables are loaded
+func (c *Core) loadNamespaces() error {
+	view := NewBarrierView(c.barrier, coreNamespacesPath)
+	keys, err := view.List("")
+	if err != nil {
+		c.logger.Error("core: failed to list namespaces", "error", err)
+		return err
+	}
+
+	namespaces := make(map[string]*Namespace, len(keys))
+	for _, key := range keys {
+		raw, err := view.Get(key)
+		if err != nil {
+			c.logger.Error("core: failed to read namespace", "id", key, "error", err)
+			return err
+		}
+		if raw == nil {
+			continue
+		}
+
+		ns := new(Namespace)
+		if err := raw.DecodeJSON(ns); err != nil {
+			c.logger.Error("core: failed to decode namespace", "id", key, "error", err)
+			return err
+		}
+		namespaces[ns.Path] = ns
+	}
+
+	c.namespacesLock.Lock()
+	c.namespaces = namespaces
+	c.namespacesLock.Unlock()
+	return nil
+}
+
+// teardownNamespaces is used before we seal the vault to forget the loaded
+// namespaces. This is reversed by loadNamespaces.
+func (c *Core) teardownNamespaces() error {
+	c.namespacesLock.Lock()
+	c.namespaces = nil
+	c.namespacesLock.Unlock()
+	return nil
+}
+
+// setupNamespacePolicyStores is invoked after the root policy store is set
+// up to create the policy stores of the namespaces
+func (c *Core) setupNamespacePolicyStores() error {
+	c.namespacesLock.RLock()
+	defer c.namespacesLock.RUnlock()
+
+	for _, ns := range c.namespaces {
+		if err := c.setupNamespacePolicyStore(ns); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setupNamespacePolicyStore creates the policy store of a namespace and makes
+// sure it contains a default policy
+func (c *Core) setupNamespacePolicyStore(ns *Namespace) error {
+	view := c.systemBarrierView.SubView(namespaceBarrierPrefix + ns.ID + "/")
+	ps := NewPolicyStore(view, &dynamicSystemView{core: c})
+	if ps == nil {
+		return fmt.Errorf("failed to create policy store of namespace %q", ns.Path)
+	}
+	ps.namespace = ns
+	ns.policyStore = ps
+
+	if c.replicationState.HasState(consts.ReplicationPerformanceSecondary) {
+		// Policies will sync from the primary
+		return nil
+	}
+
+	policy, err := ps.GetPolicy("default", PolicyTypeACL)
+	if err != nil {
+		return errwrap.Wrapf("error fetching default policy from store: {{err}}", err)
+	}
+	if policy == nil {
+		return ps.createDefaultPolicy()
+	}
+	return nil
+}
+
+// namespaceByPath returns the namespace a request path belongs to, which is
+// the namespace with the longest path that prefixes it
+func (c *Core) namespaceByPath(path string) *Namespace {
+	c.namespacesLock.RLock()
+	defer c.namespacesLock.RUnlock()
+
+	ret := rootNamespace
+	for nsPath, ns := range c.namespaces {
+		if strings.HasPrefix(path, nsPath) && len(nsPath) > len(ret.Path) {
+			ret = ns
+		}
+	}
+	return ret
+}
+
+// namespaceRelativePath returns a request path relative to the namespace it
+// belongs to
+func (c *Core) namespaceRelativePath(path string) string {
+	return strings.TrimPrefix(path, c.namespaceByPath(path).Path)
+}
+
+// namespaceByID returns the namespace with the given ID, or nil if it does
+// not exist. The empty ID is the root namespace.
+func (c *Core) namespaceByID(id string) *Namespace {
+	if id == "" {
+		return rootNamespace
+	}
+
+	c.namespacesLock.RLock()
+	defer c.namespacesLock.RUnlock()
+
+	for _, ns := range c.namespaces {
+		if ns.ID == id {
+			return ns
+		}
+	}
+	return nil
+}
+
+// namespaceChildren returns the namespaces directly underneath the given one
+func (c *Core) namespaceChildren(parent *Namespace) []*Namespace {
+	c.namespacesLock.RLock()
+	defer c.namespacesLock.RUnlock()
+
+	var ret []*Namespace
+	for nsPath, ns := range c.namespaces {
+		if nsPath == parent.Path || !strings.HasPrefix(nsPath, parent.Path) {
+			continue
+		}
+		if !strings.Contains(strings.TrimSuffix(strings.TrimPrefix(nsPath, parent.Path), "/"), "/") {
+			ret = append(ret, ns)
+		}
+	}
+	return ret
+}
+
+// namespacePolicyStore returns the policy store holding the policies of the
+// given namespace
+func (c *Core) namespacePolicyStore(ns *Namespace) *PolicyStore {
+	if ns == nil || ns.ID == "" {
+		return c.policyStore
+	}
+	return ns.policyStore
+}
+
+// namespaceIdentityStore returns the identity store of the given namespace
+func (c *Core) namespaceIdentityStore(ns *Namespace) *IdentityStore {
+	if ns == nil || ns.ID == "" {
+		return c.identityStore
+	}
+	iStore, _ := c.router.MatchingBackend(ns.Path + "identity/").(*IdentityStore)
+	return iStore
+}
+
+// createNamespace creates a namespace called name directly underneath the
+// parent namespace, along with its built-in mounts and default policy
+func (c *Core) createNamespace(parent *Namespace, name string) (*Namespace, error) {
+	name = strings.Trim(name, "/")
+	switch {
+	case name == "":
+		return nil, fmt.Errorf("namespace name must be specified")
+	case strings.Contains(name, "/"):
+		return nil, fmt.Errorf("namespace name cannot contain a slash; create nested namespaces from within their parent")
+	}
+
+	nsID, err := uuid.GenerateUUID()
+	if err != nil {
+		return nil, err
+	}
+	ns := &Namespace{
+		ID:   nsID,
+		Path: parent.Path + name + "/",
+	}
+
+	c.namespacesLock.Lock()
+	if _, ok := c.namespaces[ns.Path]; ok {
+		c.namespacesLock.Unlock()
+		return nil, logical.CodedError(409, fmt.Sprintf("namespace %q already exists", ns.Path))
+	}
+
+	// The path of the namespace must not be used by, or be part of, any
+	// existing mount
+	if match := c.router.MatchingMount(ns.Path); match != "" {
+		c.namespacesLock.Unlock()
+		return nil, logical.CodedError(409, fmt.Sprintf("existing mount at %s", match))
+	}
+	if err := c.checkNamespaceMountConflicts(ns.Path); err != nil {
+		c.namespacesLock.Unlock()
+		return nil, err
+	}
+
+	entry, err := logical.StorageEntryJSON(coreNamespacesPath+ns.ID, ns)
+	if err != nil {
+		c.namespacesLock.Unlock()
+		return nil, err
+	}
+	if err := c.barrier.Put(&Entry{Key: entry.Key, Value: entry.Value}); err != nil {
+		c.namespacesLock.Unlock()
+		c.logger.Error("core: failed to persist namespace", "path", ns.Path, "error", err)
+		return nil, logical.CodedError(500, "failed to persist namespace")
+	}
+	if c.namespaces == nil {
+		c.namespaces = make(map[string]*Namespace)
+	}
+	c.namespaces[ns.Path] = ns
+	c.namespacesLock.Unlock()
+
+	if err := c.setupNamespace(ns); err != nil {
+		c.logger.Error("core: failed to set up namespace", "path", ns.Path, "error", err)
+		if delErr := c.deleteNamespace(ns); delErr != nil {
+			c.logger.Error("core: failed to clean up namespace", "path", ns.Path, "error", delErr)
+		}
+		return nil, err
+	}
+
+	if c.logger.IsInfo() {
+		c.logger.Info("core: created namespace", "path", ns.Path)
+	}
+	return ns, nil
+}
+
+// checkNamespaceMountConflicts returns an error if any mount or auth method
+// lives underneath the given path
+func (c *Core) checkNamespaceMountConflicts(path string) error {
+	c.mountsLock.RLock()
+	defer c.mountsLock.RUnlock()
+	c.authLock.RLock()
+	defer c.authLock.RUnlock()
+
+	for _, table := range []*MountTable{c.mounts, c.auth} {
+		if table == nil {
+			continue
+		}
+		for _, entry := range table.Entries {
+			if strings.HasPrefix(entry.APIPath(), path) {
+				return logical.CodedError(409, fmt.Sprintf("existing mount at %s", entry.APIPath()))
+			}
+		}
+	}
+	return nil
+}
+
+// setupNamespace creates the policy store and the built-in mounts of a new
+// namespace: sys/, cubbyhole/, identity/ and the token auth method
+func (c *Core) setupNamespace(ns *Namespace) error {
+	if err := c.setupNamespacePolicyStore(ns); err != nil {
+		return err
+	}
+
+	for _, entry := range c.requiredMountTable().Entries {
+		entry.NamespaceID = ns.ID
+		entry.namespace = ns
+		if err := c.mountInternal(entry); err != nil {
+			return err
+		}
+	}
+
+	for _, entry := range c.defaultAuthTable().Entries {
+		entry.NamespaceID = ns.ID
+		entry.namespace = ns
+		if err := c.enableCredentialInternal(entry); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// deleteNamespace removes a namespace along with all of its mounts, auth
+// methods, leases, tokens and policies. Namespaces that still contain child
+// namespaces cannot be deleted.
+func (c *Core) deleteNamespace(ns *Namespace) error {
+	if ns.ID == "" {
+		return fmt.Errorf("cannot delete the root namespace")
+	}
+
+	c.namespacesLock.RLock()
+	for nsPath := range c.namespaces {
+		if nsPath != ns.Path && strings.HasPrefix(nsPath, ns.Path) {
+			c.namespacesLock.RUnlock()
+			return logical.CodedError(400, fmt.Sprintf("namespace %q contains child namespaces which must be deleted first", ns.Path))
+		}
+	}
+	c.namespacesLock.RUnlock()
+
+	// Revoke everything issued within the namespace; this includes the
+	// tokens, since their leases live under the auth paths of the namespace
+	if c.expiration != nil {
+		if err := c.expiration.RevokePrefix(ns.Path); err != nil {
+			return err
+		}
+	}
+
+	// Disable the auth methods and unmount the secret engines, including the
+	// built-in ones
+	c.authLock.RLock()
+	var authPaths []string
+	for _, entry := range c.auth.Entries {
+		if entry.NamespaceID == ns.ID {
+			authPaths = append(authPaths, entry.APIPath())
+		}
+	}
+	c.authLock.RUnlock()
+	for _, path := range authPaths {
+		if err := c.disableCredentialInternal(path); err != nil {
+			return err
+		}
+	}
+
+	c.mountsLock.RLock()
+	var mountPaths []string
+	for _, entry := range c.mounts.Entries {
+		if entry.NamespaceID == ns.ID {
+			mountPaths = append(mountPaths, entry.APIPath())
+		}
+	}
+	c.mountsLock.RUnlock()
+	for _, path := range mountPaths {
+		if err := c.unmountInternal(path); err != nil {
+			return err
+		}
+	}
+
+	// Clear the policies and token roles
+	if err := logical.ClearView(c.systemBarrierView.SubView(namespaceBarrierPrefix + ns.ID + "/")); err != nil {
+		return err
+	}
+	if c.tokenStore != nil {
+		if err := logical.ClearView(c.tokenStore.view.SubView(c.tokenStore.rolePrefix(ns))); err != nil {
+			return err
+		}
+	}
+
+	if err := c.barrier.Delete(coreNamespacesPath + ns.ID); err != nil {
+		c.logger.Error("core: failed to delete namespace", "path", ns.Path, "error", err)
+		return logical.CodedError(500, "failed to delete namespace")
+	}
+
+	c.namespacesLock.Lock()
+	delete(c.namespaces, ns.Path)
+	c.namespacesLock.Unlock()
+
+	if c.logger.IsInfo() {
+		c.logger.Info("core: deleted namespace", "path", ns.Path)
+	}
+	return nil
+}
+
+// namespaceSysPathAllowed returns whether a request path, relative to its
+// namespace, may be served. Only a subset of the system backend is available
+// outside of the root namespace.
+func namespaceSysPathAllowed(path string) bool {
+	if !strings.HasPrefix(path, "sys/") {
+		return true
+	}
+	path = strings.TrimPrefix(path, "sys/")
+	for _, allowed := range namespaceSysPaths {
+		if strings.HasSuffix(allowed, "/") {
+			if strings.HasPrefix(path, allowed) {
+				return true
+			}
+			continue
+		}
+		if path == allowed {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,242 @@
+package vault
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/logical"
+)
+
+func testCoreCreateNamespace(t *testing.T, c *Core, token, parent, name string) {
+	req := logical.TestRequest(t, logical.UpdateOperation, parent+"sys/namespaces/"+name)
+	req.ClientToken = token
+	resp, err := c.HandleRequest(req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err: %v, resp: %#v", err, resp)
+	}
+	if resp.Data["path"] != name+"/" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+}
+
+func TestCore_Namespaces_Isolation(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+	testCoreCreateNamespace(t, c, root, "", "team-a")
+
+	// Mount a secret engine within the namespace and write a secret
+	req := logical.TestRequest(t, logical.UpdateOperation, "team-a/sys/mounts/secret")
+	req.Data["type"] = "kv"
+	req.ClientToken = root
+	if resp, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v, resp: %#v", err, resp)
+	}
+	req = logical.TestRequest(t, logical.UpdateOperation, "team-a/secret/foo")
+	req.Data["value"] = "bar"
+	req.ClientToken = root
+	if resp, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v, resp: %#v", err, resp)
+	}
+
+	// The mount tables only show the mounts of their own namespace
+	req = logical.TestRequest(t, logical.ReadOperation, "team-a/sys/mounts")
+	req.ClientToken = root
+	resp, err := c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for _, path := range []string{"secret/", "sys/", "cubbyhole/", "identity/"} {
+		if _, ok := resp.Data[path]; !ok {
+			t.Fatalf("missing %q in namespace mount table: %#v", path, resp.Data)
+		}
+	}
+	req = logical.TestRequest(t, logical.ReadOperation, "sys/mounts")
+	req.ClientToken = root
+	resp, err = c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, ok := resp.Data["team-a/secret/"]; ok {
+		t.Fatalf("namespace mount in root mount table: %#v", resp.Data)
+	}
+
+	// A policy written in the namespace only exists there and applies to
+	// paths within it
+	req = logical.TestRequest(t, logical.UpdateOperation, "team-a/sys/policy/reader")
+	req.Data["policy"] = `path "secret/*" { capabilities = ["read"] }`
+	req.ClientToken = root
+	if resp, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v, resp: %#v", err, resp)
+	}
+	if p, err := c.policyStore.GetPolicy("reader", PolicyTypeACL); err != nil || p != nil {
+		t.Fatalf("namespace policy in root policy store: %v %v", p, err)
+	}
+
+	// Create a token within the namespace holding the policy
+	req = logical.TestRequest(t, logical.UpdateOperation, "team-a/auth/token/create")
+	req.Data["policies"] = []string{"reader"}
+	req.ClientToken = root
+	resp, err = c.HandleRequest(req)
+	if err != nil || resp == nil || resp.Auth == nil {
+		t.Fatalf("err: %v, resp: %#v", err, resp)
+	}
+	nsToken := resp.Auth.ClientToken
+	if !reflect.DeepEqual(resp.Auth.Policies, []string{"default", "reader"}) {
+		t.Fatalf("bad: %#v", resp.Auth.Policies)
+	}
+
+	req = logical.TestRequest(t, logical.ReadOperation, "team-a/secret/foo")
+	req.ClientToken = nsToken
+	resp, err = c.HandleRequest(req)
+	if err != nil || resp == nil || resp.Data["value"] != "bar" {
+		t.Fatalf("err: %v, resp: %#v", err, resp)
+	}
+
+	// The token cannot reach outside of its namespace
+	for _, path := range []string{"secret/foo", "sys/mounts", "auth/token/lookup-self"} {
+		req = logical.TestRequest(t, logical.ReadOperation, path)
+		req.ClientToken = nsToken
+		if _, err := c.HandleRequest(req); err == nil || !errwrap.Contains(err, logical.ErrPermissionDenied.Error()) {
+			t.Fatalf("%s: expected permission denied, got %v", path, err)
+		}
+	}
+
+	// Only part of the system backend is available within a namespace
+	req = logical.TestRequest(t, logical.ReadOperation, "team-a/sys/audit")
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err != logical.ErrUnsupportedPath {
+		t.Fatalf("expected unsupported path, got %v", err)
+	}
+}
+
+func TestCore_Namespaces_Nested(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+	testCoreCreateNamespace(t, c, root, "", "team-a")
+	testCoreCreateNamespace(t, c, root, "team-a/", "dev")
+
+	req := logical.TestRequest(t, logical.ListOperation, "team-a/sys/namespaces")
+	req.ClientToken = root
+	resp, err := c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !reflect.DeepEqual(resp.Data["keys"], []string{"dev/"}) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// An admin of the parent namespace manages its whole subtree
+	req = logical.TestRequest(t, logical.UpdateOperation, "team-a/sys/policy/admin")
+	req.Data["policy"] = `path "*" { capabilities = ["create", "read", "update", "delete", "list", "sudo"] }`
+	req.ClientToken = root
+	if resp, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v, resp: %#v", err, resp)
+	}
+	req = logical.TestRequest(t, logical.UpdateOperation, "team-a/auth/token/create")
+	req.Data["policies"] = []string{"admin"}
+	req.ClientToken = root
+	resp, err = c.HandleRequest(req)
+	if err != nil || resp == nil || resp.Auth == nil {
+		t.Fatalf("err: %v, resp: %#v", err, resp)
+	}
+	adminToken := resp.Auth.ClientToken
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "team-a/sys/mounts/kv")
+	req.Data["type"] = "kv"
+	req.ClientToken = adminToken
+	if resp, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v, resp: %#v", err, resp)
+	}
+	req = logical.TestRequest(t, logical.ReadOperation, "team-a/dev/sys/mounts")
+	req.ClientToken = adminToken
+	if resp, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v, resp: %#v", err, resp)
+	}
+
+	// The child namespace inherits none of the policies of its parent, so a
+	// token created within it cannot reach the parent
+	req = logical.TestRequest(t, logical.UpdateOperation, "team-a/dev/auth/token/create")
+	req.Data["policies"] = []string{"admin"}
+	req.ClientToken = adminToken
+	resp, err = c.HandleRequest(req)
+	if err != nil || resp == nil || resp.Auth == nil {
+		t.Fatalf("err: %v, resp: %#v", err, resp)
+	}
+	devToken := resp.Auth.ClientToken
+	for _, path := range []string{"team-a/dev/sys/mounts", "team-a/sys/mounts"} {
+		req = logical.TestRequest(t, logical.ReadOperation, path)
+		req.ClientToken = devToken
+		if _, err := c.HandleRequest(req); err == nil || !errwrap.Contains(err, logical.ErrPermissionDenied.Error()) {
+			t.Fatalf("%s: expected permission denied, got %v", path, err)
+		}
+	}
+
+	// Mounts cannot reach into a child namespace
+	req = logical.TestRequest(t, logical.UpdateOperation, "team-a/sys/mounts/dev/kv")
+	req.Data["type"] = "kv"
+	req.ClientToken = adminToken
+	if _, err := c.HandleRequest(req); err == nil {
+		t.Fatalf("expected error mounting into child namespace")
+	}
+
+	// A namespace with children cannot be deleted
+	req = logical.TestRequest(t, logical.DeleteOperation, "sys/namespaces/team-a")
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err == nil {
+		t.Fatalf("expected error deleting namespace with children")
+	}
+
+	req = logical.TestRequest(t, logical.DeleteOperation, "team-a/sys/namespaces/dev")
+	req.ClientToken = adminToken
+	if resp, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v, resp: %#v", err, resp)
+	}
+	req = logical.TestRequest(t, logical.DeleteOperation, "sys/namespaces/team-a")
+	req.ClientToken = root
+	if resp, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v, resp: %#v", err, resp)
+	}
+
+	// Deleting the namespace revokes its tokens and removes its mounts
+	if te, err := c.tokenStore.Lookup(adminToken); err != nil || te != nil {
+		t.Fatalf("token not revoked: %#v %v", te, err)
+	}
+	if match := c.router.MatchingMount("team-a/kv/foo"); match != "" {
+		t.Fatalf("mount not removed: %q", match)
+	}
+	if len(c.namespaces) != 0 {
+		t.Fatalf("bad: %#v", c.namespaces)
+	}
+}
+
+func TestCore_Namespaces_Persist(t *testing.T) {
+	c, keys, root := TestCoreUnsealed(t)
+	testCoreCreateNamespace(t, c, root, "", "team-a")
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "team-a/sys/mounts/kv")
+	req.Data["type"] = "kv"
+	req.ClientToken = root
+	if resp, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v, resp: %#v", err, resp)
+	}
+
+	if err := c.Seal(root); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for _, key := range keys {
+		if _, err := TestCoreUnseal(c, key); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	ns := c.namespaceByPath("team-a/kv/")
+	if ns.Path != "team-a/" || ns.policyStore == nil {
+		t.Fatalf("bad: %#v", ns)
+	}
+	entry := c.router.MatchingMountEntry("team-a/kv/foo")
+	if entry == nil || entry.Path != "kv/" || entry.Namespace() != ns {
+		t.Fatalf("bad: %#v", entry)
+	}
+	if c.namespaceIdentityStore(ns) == nil {
+		t.Fatalf("missing identity store of namespace")
+	}
+}
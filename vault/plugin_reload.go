@@ -2,7 +2,6 @@ package vault
 
 import (
 	"fmt"
-	"strings"
 
 	multierror "github.com/hashicorp/go-multierror"
 	"github.com/hashicorp/vault/logical"
@@ -23,14 +22,8 @@ func (c *Core) reloadMatchingPluginMounts(mounts []string) error {
 			// return fmt.Errorf("cannot fetch mount entry on %s", mount)
 		}
 
-		var isAuth bool
-		fullPath := c.router.MatchingMount(mount)
-		if strings.HasPrefix(fullPath, credentialRoutePrefix) {
-			isAuth = true
-		}
-
 		if entry.Type == "plugin" {
-			err := c.reloadPluginCommon(entry, isAuth)
+			err := c.reloadPluginCommon(entry, entry.Table == credentialTableType)
 			if err != nil {
 				errors = multierror.Append(errors, fmt.Errorf("cannot reload plugin on %s: %v", mount, err))
 				continue
@@ -77,7 +70,7 @@ func (c *Core) reloadMatchingPlugin(pluginName string) error {
 // MountEntry. entry.Type should be checked by the caller to ensure that
 // it's a "plugin" type.
 func (c *Core) reloadPluginCommon(entry *MountEntry, isAuth bool) error {
-	path := entry.Path
+	path := entry.APIPath()
 
 	// Fast-path out if the backend doesn't exist
 	raw, ok := c.router.root.Get(path)
@@ -3,6 +3,7 @@ package vault
 import (
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
@@ -11,6 +12,7 @@ import (
 	"github.com/hashicorp/hcl"
 	"github.com/hashicorp/hcl/hcl/ast"
 	"github.com/hashicorp/vault/helper/parseutil"
+	"github.com/hashicorp/vault/helper/strutil"
 	"github.com/mitchellh/copystructure"
 )
 
@@ -100,6 +102,8 @@ type PathRules struct {
 	MaxWrappingTTLHCL    interface{}              `hcl:"max_wrapping_ttl"`
 	AllowedParametersHCL map[string][]interface{} `hcl:"allowed_parameters"`
 	DeniedParametersHCL  map[string][]interface{} `hcl:"denied_parameters"`
+	MFAMethodsHCL        []string                 `hcl:"mfa_methods"`
+	ControlGroupHCL      *ControlGroupHCL         `hcl:"control_group"`
 }
 
 type ACLPermissions struct {
@@ -108,6 +112,39 @@ type ACLPermissions struct {
 	MaxWrappingTTL     time.Duration
 	AllowedParameters  map[string][]interface{}
 	DeniedParameters   map[string][]interface{}
+	MFAMethods         []string
+	ControlGroup       *ControlGroup
+}
+
+// ControlGroupHCL is the control_group stanza of a path, with its factors
+// keyed by name
+type ControlGroupHCL struct {
+	TTL     interface{}                       `hcl:"ttl"`
+	Factors map[string]*ControlGroupFactorHCL `hcl:"factor"`
+}
+
+type ControlGroupFactorHCL struct {
+	Identity *IdentityFactor `hcl:"identity"`
+}
+
+// ControlGroup holds requests to a path until they are authorized by all of
+// its factors. TTL bounds how long a held request waits for authorization.
+type ControlGroup struct {
+	TTL     time.Duration
+	Factors []*ControlGroupFactor
+}
+
+type ControlGroupFactor struct {
+	Name     string
+	Identity *IdentityFactor
+}
+
+// IdentityFactor is satisfied once a number of members of the given identity
+// groups have authorized a request
+type IdentityFactor struct {
+	GroupIDs          []string `hcl:"group_ids"`
+	GroupNames        []string `hcl:"group_names"`
+	ApprovalsRequired int      `hcl:"approvals"`
 }
 
 func (p *ACLPermissions) Clone() (*ACLPermissions, error) {
@@ -117,6 +154,18 @@ func (p *ACLPermissions) Clone() (*ACLPermissions, error) {
 		MaxWrappingTTL:     p.MaxWrappingTTL,
 	}
 
+	if p.MFAMethods != nil {
+		ret.MFAMethods = make([]string, len(p.MFAMethods))
+		copy(ret.MFAMethods, p.MFAMethods)
+	}
+
+	if p.ControlGroup != nil {
+		cg := *p.ControlGroup
+		cg.Factors = make([]*ControlGroupFactor, len(p.ControlGroup.Factors))
+		copy(cg.Factors, p.ControlGroup.Factors)
+		ret.ControlGroup = &cg
+	}
+
 	switch {
 	case p.AllowedParameters == nil:
 	case len(p.AllowedParameters) == 0:
@@ -200,10 +249,15 @@ func parsePaths(result *Policy, list *ast.ObjectList) error {
 			"denied_parameters",
 			"min_wrapping_ttl",
 			"max_wrapping_ttl",
+			"mfa_methods",
+			"control_group",
 		}
 		if err := checkHCLKeys(item.Val, valid); err != nil {
 			return multierror.Prefix(err, fmt.Sprintf("path %q:", key))
 		}
+		if err := checkControlGroupHCLKeys(item.Val); err != nil {
+			return multierror.Prefix(err, fmt.Sprintf("path %q:", key))
+		}
 
 		var pc PathRules
 
@@ -271,6 +325,16 @@ func parsePaths(result *Policy, list *ast.ObjectList) error {
 				pc.Permissions.DeniedParameters[strings.ToLower(key)] = val
 			}
 		}
+		if pc.MFAMethodsHCL != nil {
+			pc.Permissions.MFAMethods = strutil.RemoveDuplicates(pc.MFAMethodsHCL, false)
+		}
+		if pc.ControlGroupHCL != nil {
+			cg, err := parseControlGroup(pc.ControlGroupHCL)
+			if err != nil {
+				return fmt.Errorf("path %q: %v", key, err)
+			}
+			pc.Permissions.ControlGroup = cg
+		}
 		if pc.MinWrappingTTLHCL != nil {
 			dur, err := parseutil.ParseDurationSecond(pc.MinWrappingTTLHCL)
 			if err != nil {
@@ -299,6 +363,85 @@ func parsePaths(result *Policy, list *ast.ObjectList) error {
 	return nil
 }
 
+// parseControlGroup turns the control_group stanza of a path into a control
+// group, with its factors sorted by name
+func parseControlGroup(cgHCL *ControlGroupHCL) (*ControlGroup, error) {
+	cg := new(ControlGroup)
+	if cgHCL.TTL != nil {
+		dur, err := parseutil.ParseDurationSecond(cgHCL.TTL)
+		if err != nil {
+			return nil, errwrap.Wrapf("error parsing control group ttl: {{err}}", err)
+		}
+		cg.TTL = dur
+	}
+
+	if len(cgHCL.Factors) == 0 {
+		return nil, errors.New("control group requires at least one factor")
+	}
+	names := make([]string, 0, len(cgHCL.Factors))
+	for name := range cgHCL.Factors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		factor := cgHCL.Factors[name]
+		if factor == nil || factor.Identity == nil {
+			return nil, fmt.Errorf("control group factor %q requires an identity stanza", name)
+		}
+		if len(factor.Identity.GroupIDs) == 0 && len(factor.Identity.GroupNames) == 0 {
+			return nil, fmt.Errorf("control group factor %q requires group_ids or group_names", name)
+		}
+		switch {
+		case factor.Identity.ApprovalsRequired < 0:
+			return nil, fmt.Errorf("control group factor %q cannot require a negative number of approvals", name)
+		case factor.Identity.ApprovalsRequired == 0:
+			factor.Identity.ApprovalsRequired = 1
+		}
+		cg.Factors = append(cg.Factors, &ControlGroupFactor{
+			Name:     name,
+			Identity: factor.Identity,
+		})
+	}
+
+	return cg, nil
+}
+
+// checkControlGroupHCLKeys checks for invalid keys within the control_group
+// stanza of a path
+func checkControlGroupHCLKeys(node ast.Node) error {
+	obj, ok := node.(*ast.ObjectType)
+	if !ok {
+		return nil
+	}
+
+	for _, cgItem := range obj.List.Filter("control_group").Items {
+		if err := checkHCLKeys(cgItem.Val, []string{"ttl", "factor"}); err != nil {
+			return multierror.Prefix(err, "control_group:")
+		}
+		cgObj, ok := cgItem.Val.(*ast.ObjectType)
+		if !ok {
+			continue
+		}
+		for _, factorItem := range cgObj.List.Filter("factor").Items {
+			if err := checkHCLKeys(factorItem.Val, []string{"identity"}); err != nil {
+				return multierror.Prefix(err, "control_group factor:")
+			}
+			factorObj, ok := factorItem.Val.(*ast.ObjectType)
+			if !ok {
+				continue
+			}
+			for _, identityItem := range factorObj.List.Filter("identity").Items {
+				if err := checkHCLKeys(identityItem.Val, []string{"group_ids", "group_names", "approvals"}); err != nil {
+					return multierror.Prefix(err, "control_group factor identity:")
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
 func checkHCLKeys(node ast.Node, valid []string) error {
 	var list *ast.ObjectList
 	switch n := node.(type) {
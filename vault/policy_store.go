@@ -97,6 +97,11 @@ path "sys/wrapping/unwrap" {
     capabilities = ["update"]
 }
 
+# Allow a token to check the status of a request held by a control group
+path "sys/control-group/request" {
+    capabilities = ["update"]
+}
+
 # Allow general purpose tools
 path "sys/tools/hash" {
 	capabilities = ["update"]
@@ -134,6 +139,9 @@ type PolicyStore struct {
 	modifyLock *sync.RWMutex
 	// Stores whether a token policy is ACL or RGP
 	policyTypeMap sync.Map
+	// The namespace the policies belong to; the paths of the policies of
+	// namespaces other than the root namespace are relative to the namespace
+	namespace *Namespace
 }
 
 // PolicyEntry is used to store a policy by name
@@ -204,7 +212,7 @@ func (c *Core) setupPolicyStore() error {
 		}
 	}
 
-	return nil
+	return c.setupNamespacePolicyStores()
 }
 
 // teardownPolicyStore is used to reverse setupPolicyStore
@@ -276,8 +284,14 @@ func (ps *PolicyStore) setPolicyInternal(p *Policy) error {
 		ps.policyTypeMap.Store(p.Name, PolicyTypeACL)
 
 		if ps.tokenPoliciesLRU != nil {
-			// Update the LRU cache
-			ps.tokenPoliciesLRU.Add(p.Name, p)
+			if ps.inNamespace() {
+				// Evict it instead, so that the paths are made absolute
+				// when it is next loaded
+				ps.tokenPoliciesLRU.Remove(p.Name)
+			} else {
+				// Update the LRU cache
+				ps.tokenPoliciesLRU.Add(p.Name, p)
+			}
 		}
 
 	default:
@@ -323,8 +337,8 @@ func (ps *PolicyStore) GetPolicy(name string, policyType PolicyType) (*Policy, e
 		}
 	}
 
-	// Special case the root policy
-	if policyType == PolicyTypeACL && name == "root" {
+	// Special case the root policy, which only exists in the root namespace
+	if policyType == PolicyTypeACL && name == "root" && !ps.inNamespace() {
 		p := &Policy{Name: "root"}
 		if cache != nil {
 			cache.Add(p.Name, p)
@@ -374,6 +388,13 @@ func (ps *PolicyStore) GetPolicy(name string, policyType PolicyType) (*Policy, e
 		// Reset this in case they set the name in the policy itself
 		policy.Name = name
 
+		// Paths in the policies of a namespace are relative to it
+		if ps.inNamespace() {
+			for _, pr := range policy.Paths {
+				pr.Prefix = ps.namespace.Path + pr.Prefix
+			}
+		}
+
 		ps.policyTypeMap.Store(name, PolicyTypeACL)
 
 	default:
@@ -508,6 +529,12 @@ func (ps *PolicyStore) createResponseWrappingPolicy() error {
 	return ps.setPolicyInternal(policy)
 }
 
+// inNamespace returns whether the store holds the policies of a namespace
+// other than the root namespace
+func (ps *PolicyStore) inNamespace() bool {
+	return ps.namespace != nil && ps.namespace.ID != ""
+}
+
 func (ps *PolicyStore) sanitizeName(name string) string {
 	return strings.ToLower(strings.TrimSpace(name))
 }
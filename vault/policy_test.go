@@ -85,6 +85,31 @@ path "test/types" {
 		"bool" = [false]
 	}
 }
+
+# Check that MFA methods are being added to sealing
+path "sys/seal" {
+	capabilities = ["update", "sudo"]
+	mfa_methods = ["my_totp", "my_duo", "my_totp"]
+}
+
+# Check that control groups are being added to the root credentials
+path "prod/root" {
+	capabilities = ["read"]
+	control_group = {
+		ttl = "4h"
+		factor "managers" {
+			identity {
+				group_names = ["managers"]
+				approvals = 2
+			}
+		}
+		factor "auditors" {
+			identity {
+				group_ids = ["abcd"]
+			}
+		}
+	}
+}
 `)
 
 func TestPolicy_Parse(t *testing.T) {
@@ -225,6 +250,55 @@ func TestPolicy_Parse(t *testing.T) {
 			},
 			Glob: false,
 		},
+		&PathRules{
+			Prefix: "sys/seal",
+			Policy: "",
+			Capabilities: []string{
+				"update",
+				"sudo",
+			},
+			MFAMethodsHCL: []string{"my_totp", "my_duo", "my_totp"},
+			Permissions: &ACLPermissions{
+				CapabilitiesBitmap: (UpdateCapabilityInt | SudoCapabilityInt),
+				MFAMethods:         []string{"my_duo", "my_totp"},
+			},
+			Glob: false,
+		},
+		&PathRules{
+			Prefix: "prod/root",
+			Policy: "",
+			Capabilities: []string{
+				"read",
+			},
+			ControlGroupHCL: &ControlGroupHCL{
+				TTL: "4h",
+				Factors: map[string]*ControlGroupFactorHCL{
+					"managers": &ControlGroupFactorHCL{
+						Identity: &IdentityFactor{GroupNames: []string{"managers"}, ApprovalsRequired: 2},
+					},
+					"auditors": &ControlGroupFactorHCL{
+						Identity: &IdentityFactor{GroupIDs: []string{"abcd"}, ApprovalsRequired: 1},
+					},
+				},
+			},
+			Permissions: &ACLPermissions{
+				CapabilitiesBitmap: ReadCapabilityInt,
+				ControlGroup: &ControlGroup{
+					TTL: 4 * time.Hour,
+					Factors: []*ControlGroupFactor{
+						&ControlGroupFactor{
+							Name:     "auditors",
+							Identity: &IdentityFactor{GroupIDs: []string{"abcd"}, ApprovalsRequired: 1},
+						},
+						&ControlGroupFactor{
+							Name:     "managers",
+							Identity: &IdentityFactor{GroupNames: []string{"managers"}, ApprovalsRequired: 2},
+						},
+					},
+				},
+			},
+			Glob: false,
+		},
 	}
 	if !reflect.DeepEqual(p.Paths, expect) {
 		t.Errorf("expected \n\n%#v\n\n to be \n\n%#v\n\n", p.Paths, expect)
@@ -312,3 +386,42 @@ path "/" {
 		t.Errorf("bad error: %s", err)
 	}
 }
+
+func TestPolicy_ParseBadControlGroup(t *testing.T) {
+	_, err := ParseACLPolicy(strings.TrimSpace(`
+path "/" {
+	capabilities = ["read"]
+	control_group = {
+		factor "managers" {
+			identity {
+				group_names = ["managers"]
+				approval = 2
+			}
+		}
+	}
+}
+`))
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+
+	if !strings.Contains(err.Error(), "invalid key 'approval'") {
+		t.Errorf("bad error: %s", err)
+	}
+
+	_, err = ParseACLPolicy(strings.TrimSpace(`
+path "/" {
+	capabilities = ["read"]
+	control_group = {
+		ttl = "1h"
+	}
+}
+`))
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+
+	if !strings.Contains(err.Error(), `path "/": control group requires at least one factor`) {
+		t.Errorf("bad error: %s", err)
+	}
+}
@@ -0,0 +1,567 @@
+package vault
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	metrics "github.com/armon/go-metrics"
+	log "github.com/mgutz/logxi/v1"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+const (
+	// quotasBarrierPrefix is the prefix, within the system view, under which
+	// the quota definitions are stored
+	quotasBarrierPrefix = "quotas/"
+
+	// QuotaTypeRateLimit limits the rate of requests made against a path
+	QuotaTypeRateLimit = "rate-limit"
+
+	// QuotaTypeLeaseCount limits the number of leases under a path
+	QuotaTypeLeaseCount = "lease-count"
+
+	// RateLimitScopeClient keeps a separate token bucket for every client
+	// address
+	RateLimitScopeClient = "client"
+
+	// RateLimitScopePath shares a single token bucket between all clients
+	RateLimitScopePath = "path"
+
+	// rateLimitPurgeInterval is how often the token buckets of clients that
+	// have stopped making requests are dropped
+	rateLimitPurgeInterval = time.Minute
+)
+
+// quotaExemptPaths are never rate limited, so that a misconfigured quota can
+// always be fixed
+var quotaExemptPaths = []string{
+	"sys/quotas/",
+}
+
+// Quota is the stored definition of a quota. Path is a mount or path prefix
+// the quota applies to, or empty for a global quota. Where several quotas of
+// the same type match a path, the one with the longest path applies.
+type Quota struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	Path string `json:"path"`
+
+	// Rate is the number of requests per second a rate-limit quota allows,
+	// with bursts of up to Burst requests
+	Rate  float64 `json:"rate,omitempty"`
+	Burst int     `json:"burst,omitempty"`
+	Scope string  `json:"scope,omitempty"`
+
+	// MaxLeases is the number of leases a lease-count quota allows
+	MaxLeases int `json:"max_leases,omitempty"`
+}
+
+// storageKey returns the key the quota is stored at
+func (q *Quota) storageKey() string {
+	return q.Type + "/" + q.Name
+}
+
+// pathPrefix returns the path of the quota ending at a path segment, so that a
+// quota on "secret" does not apply to "secret-other/"
+func (q *Quota) pathPrefix() string {
+	if q.Path == "" || strings.HasSuffix(q.Path, "/") {
+		return q.Path
+	}
+	return q.Path + "/"
+}
+
+// appliesTo returns whether the quota applies to a request path or lease ID.
+// Lease IDs are the path the lease was created at followed by a unique ID, so
+// a quota on a path applies to the requests and leases of that path and below.
+func (q *Quota) appliesTo(path string) bool {
+	prefix := q.pathPrefix()
+	return strings.HasPrefix(path, prefix) || path+"/" == prefix
+}
+
+// rateLimitQuota tracks the token buckets of a rate-limit quota
+type rateLimitQuota struct {
+	*Quota
+
+	lock      sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastPurge time.Time
+}
+
+// tokenBucket holds the tokens left for a client or path. It is refilled at
+// the rate of the quota when it is taken from.
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// allow takes a token from the bucket of the given key, returning false if
+// the bucket is empty
+func (q *rateLimitQuota) allow(key string, now time.Time) bool {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if q.Scope != RateLimitScopeClient {
+		key = ""
+	}
+
+	if now.Sub(q.lastPurge) > rateLimitPurgeInterval {
+		q.purge(now)
+	}
+
+	bucket, ok := q.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{
+			tokens: float64(q.Burst),
+			last:   now,
+		}
+		q.buckets[key] = bucket
+	}
+
+	bucket.tokens = math.Min(float64(q.Burst), bucket.tokens+now.Sub(bucket.last).Seconds()*q.Rate)
+	bucket.last = now
+
+	if bucket.tokens < 1 {
+		return false
+	}
+	bucket.tokens--
+	return true
+}
+
+// purge drops the buckets that have been refilled completely, which are no
+// different from new ones. The caller must hold the lock.
+func (q *rateLimitQuota) purge(now time.Time) {
+	for key, bucket := range q.buckets {
+		if bucket.tokens+now.Sub(bucket.last).Seconds()*q.Rate >= float64(q.Burst) {
+			delete(q.buckets, key)
+		}
+	}
+	q.lastPurge = now
+}
+
+// leaseCountQuota tracks the leases counted against a lease-count quota. A
+// lease is only counted against the quota with the longest path matching it.
+type leaseCountQuota struct {
+	*Quota
+
+	count int
+}
+
+// leaseChange is a lease reserved or released while the leases are being
+// collected for a recount
+type leaseChange struct {
+	leaseID  string
+	reserved bool
+}
+
+// QuotaManager enforces the rate-limit and lease-count quotas. The quota
+// definitions are kept in storage and loaded by the active node, which is the
+// only node handling requests, so the counts and token buckets only live in
+// its memory. Lease counts are recounted from storage when a node becomes
+// active.
+type QuotaManager struct {
+	view      *BarrierView
+	leaseView *BarrierView
+	logger    log.Logger
+
+	lock        sync.RWMutex
+	rateLimits  map[string]*rateLimitQuota
+	leaseCounts map[string]*leaseCountQuota
+
+	// recountLock serializes the recounts of the lease-count quotas. While a
+	// recount collects the leases, leaseChanges records the leases reserved
+	// and released in the meantime; it is nil otherwise.
+	recountLock  sync.Mutex
+	leaseChanges []leaseChange
+}
+
+// setupQuotas is invoked after we've loaded the mount table to load the
+// quotas and count the leases of the lease-count quotas
+func (c *Core) setupQuotas() error {
+	qm := &QuotaManager{
+		view:        c.systemBarrierView.SubView(quotasBarrierPrefix),
+		leaseView:   c.systemBarrierView.SubView(expirationSubPath + leaseViewPrefix),
+		logger:      c.logger,
+		rateLimits:  make(map[string]*rateLimitQuota),
+		leaseCounts: make(map[string]*leaseCountQuota),
+	}
+
+	for _, quotaType := range []string{QuotaTypeRateLimit, QuotaTypeLeaseCount} {
+		names, err := qm.view.List(quotaType + "/")
+		if err != nil {
+			return fmt.Errorf("failed to list quotas: %v", err)
+		}
+		for _, name := range names {
+			quota, err := qm.readQuota(quotaType, name)
+			if err != nil {
+				return err
+			}
+			if quota == nil {
+				continue
+			}
+			if quota.Type == QuotaTypeLeaseCount {
+				// The leases are counted once all quotas are loaded
+				qm.leaseCounts[quota.Name] = &leaseCountQuota{Quota: quota}
+				continue
+			}
+			if err := qm.addQuota(quota); err != nil {
+				return err
+			}
+		}
+	}
+
+	if len(qm.leaseCounts) > 0 {
+		if err := qm.recountLeases(nil); err != nil {
+			return err
+		}
+	}
+
+	c.metricsMutex.Lock()
+	c.quotas = qm
+	c.metricsMutex.Unlock()
+	return nil
+}
+
+// teardownQuotas is used before we seal the vault to forget the quotas
+func (c *Core) teardownQuotas() error {
+	c.metricsMutex.Lock()
+	c.quotas = nil
+	c.metricsMutex.Unlock()
+	return nil
+}
+
+// readQuota reads the stored definition of a quota
+func (qm *QuotaManager) readQuota(quotaType, name string) (*Quota, error) {
+	entry, err := qm.view.Get(quotaType + "/" + name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read quota: %v", err)
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	quota := new(Quota)
+	if err := entry.DecodeJSON(quota); err != nil {
+		return nil, fmt.Errorf("failed to decode quota: %v", err)
+	}
+	return quota, nil
+}
+
+// Quota returns the definition of the named quota, or nil if there is none
+func (qm *QuotaManager) Quota(quotaType, name string) *Quota {
+	qm.lock.RLock()
+	defer qm.lock.RUnlock()
+
+	switch quotaType {
+	case QuotaTypeRateLimit:
+		if q, ok := qm.rateLimits[name]; ok {
+			return q.Quota
+		}
+	case QuotaTypeLeaseCount:
+		if q, ok := qm.leaseCounts[name]; ok {
+			return q.Quota
+		}
+	}
+	return nil
+}
+
+// LeaseCount returns the number of leases counted against the named
+// lease-count quota
+func (qm *QuotaManager) LeaseCount(name string) int {
+	qm.lock.RLock()
+	defer qm.lock.RUnlock()
+
+	if q, ok := qm.leaseCounts[name]; ok {
+		return q.count
+	}
+	return 0
+}
+
+// QuotaNames returns the names of the quotas of the given type
+func (qm *QuotaManager) QuotaNames(quotaType string) []string {
+	qm.lock.RLock()
+	defer qm.lock.RUnlock()
+
+	var names []string
+	switch quotaType {
+	case QuotaTypeRateLimit:
+		for name := range qm.rateLimits {
+			names = append(names, name)
+		}
+	case QuotaTypeLeaseCount:
+		for name := range qm.leaseCounts {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// SetQuota validates and stores a quota, replacing the quota of the same
+// name. The token buckets of a replaced rate-limit quota are reset.
+func (qm *QuotaManager) SetQuota(quota *Quota) error {
+	quota.Path = strings.TrimPrefix(quota.Path, "/")
+
+	switch quota.Type {
+	case QuotaTypeRateLimit:
+		if quota.Rate <= 0 {
+			return fmt.Errorf("rate must be positive")
+		}
+		if quota.Burst <= 0 {
+			quota.Burst = int(math.Ceil(quota.Rate))
+		}
+		switch quota.Scope {
+		case "":
+			quota.Scope = RateLimitScopeClient
+		case RateLimitScopeClient, RateLimitScopePath:
+		default:
+			return fmt.Errorf("scope must be %q or %q", RateLimitScopeClient, RateLimitScopePath)
+		}
+	case QuotaTypeLeaseCount:
+		if quota.MaxLeases <= 0 {
+			return fmt.Errorf("max_leases must be positive")
+		}
+	default:
+		return fmt.Errorf("unknown quota type %q", quota.Type)
+	}
+
+	if existing := qm.quotaForPath(quota.Type, quota.Path); existing != nil && existing.pathPrefix() == quota.pathPrefix() && existing.Name != quota.Name {
+		return fmt.Errorf("quota %q already applies to path %q", existing.Name, quota.Path)
+	}
+
+	entry, err := logical.StorageEntryJSON(quota.storageKey(), quota)
+	if err != nil {
+		return fmt.Errorf("failed to encode quota: %v", err)
+	}
+	if err := qm.view.Put(entry); err != nil {
+		return fmt.Errorf("failed to persist quota: %v", err)
+	}
+
+	return qm.addQuota(quota)
+}
+
+// DeleteQuota removes the named quota. The leases of a lease-count quota are
+// counted against the quotas that apply to them instead.
+func (qm *QuotaManager) DeleteQuota(quotaType, name string) error {
+	if err := qm.view.Delete(quotaType + "/" + name); err != nil {
+		return fmt.Errorf("failed to delete quota: %v", err)
+	}
+
+	switch quotaType {
+	case QuotaTypeRateLimit:
+		qm.lock.Lock()
+		delete(qm.rateLimits, name)
+		qm.lock.Unlock()
+	case QuotaTypeLeaseCount:
+		return qm.recountLeases(func() {
+			delete(qm.leaseCounts, name)
+		})
+	}
+	return nil
+}
+
+// addQuota starts enforcing a quota. A lease-count quota takes over the
+// leases of the quotas with shorter paths, so all of them are recounted.
+func (qm *QuotaManager) addQuota(quota *Quota) error {
+	switch quota.Type {
+	case QuotaTypeRateLimit:
+		qm.lock.Lock()
+		qm.rateLimits[quota.Name] = &rateLimitQuota{
+			Quota:     quota,
+			buckets:   make(map[string]*tokenBucket),
+			lastPurge: time.Now(),
+		}
+		qm.lock.Unlock()
+
+	case QuotaTypeLeaseCount:
+		return qm.recountLeases(func() {
+			qm.leaseCounts[quota.Name] = &leaseCountQuota{Quota: quota}
+		})
+	}
+	return nil
+}
+
+// recountLeases counts the leases in storage against the lease-count quotas
+// that apply to them. The leases are collected without holding the lock, so
+// the leases reserved and released in the meantime are recorded and applied
+// to the collected ones. The update function, if given, changes the
+// lease-count quotas under the lock right before they are recounted.
+func (qm *QuotaManager) recountLeases(update func()) error {
+	qm.recountLock.Lock()
+	defer qm.recountLock.Unlock()
+
+	qm.lock.Lock()
+	qm.leaseChanges = []leaseChange{}
+	qm.lock.Unlock()
+
+	leaseIDs, err := logical.CollectKeys(qm.leaseView)
+
+	qm.lock.Lock()
+	defer qm.lock.Unlock()
+
+	changes := qm.leaseChanges
+	qm.leaseChanges = nil
+	if err != nil {
+		return fmt.Errorf("failed to count leases: %v", err)
+	}
+
+	leases := make(map[string]struct{}, len(leaseIDs))
+	for _, leaseID := range leaseIDs {
+		leases[leaseID] = struct{}{}
+	}
+	for _, change := range changes {
+		if change.reserved {
+			leases[change.leaseID] = struct{}{}
+		} else {
+			delete(leases, change.leaseID)
+		}
+	}
+
+	if update != nil {
+		update()
+	}
+
+	for _, q := range qm.leaseCounts {
+		q.count = 0
+	}
+	for leaseID := range leases {
+		if q := qm.leaseCountForPath(leaseID); q != nil {
+			q.count++
+		}
+	}
+	return nil
+}
+
+// quotaForPath returns the definition of the quota of the given type with the
+// longest path matching the given path, or nil if there is none
+func (qm *QuotaManager) quotaForPath(quotaType, path string) *Quota {
+	qm.lock.RLock()
+	defer qm.lock.RUnlock()
+
+	switch quotaType {
+	case QuotaTypeRateLimit:
+		if q := qm.rateLimitForPath(path); q != nil {
+			return q.Quota
+		}
+	case QuotaTypeLeaseCount:
+		if q := qm.leaseCountForPath(path); q != nil {
+			return q.Quota
+		}
+	}
+	return nil
+}
+
+// rateLimitForPath returns the rate-limit quota that applies to the path. The
+// caller must hold the lock.
+func (qm *QuotaManager) rateLimitForPath(path string) *rateLimitQuota {
+	var match *rateLimitQuota
+	for _, q := range qm.rateLimits {
+		if q.appliesTo(path) && (match == nil || len(q.pathPrefix()) > len(match.pathPrefix())) {
+			match = q
+		}
+	}
+	return match
+}
+
+// leaseCountForPath returns the lease-count quota that applies to the path.
+// The caller must hold the lock.
+func (qm *QuotaManager) leaseCountForPath(path string) *leaseCountQuota {
+	var match *leaseCountQuota
+	for _, q := range qm.leaseCounts {
+		if q.appliesTo(path) && (match == nil || len(q.pathPrefix()) > len(match.pathPrefix())) {
+			match = q
+		}
+	}
+	return match
+}
+
+// allowRequest applies the rate-limit quota of the path of the request. It
+// returns ErrRateLimitQuotaExceeded if the token bucket of the client or path
+// is empty.
+func (qm *QuotaManager) allowRequest(req *logical.Request) error {
+	if qm == nil {
+		return nil
+	}
+	for _, exempt := range quotaExemptPaths {
+		if strings.HasPrefix(req.Path, exempt) {
+			return nil
+		}
+	}
+
+	qm.lock.RLock()
+	q := qm.rateLimitForPath(req.Path)
+	qm.lock.RUnlock()
+	if q == nil {
+		return nil
+	}
+
+	var clientAddr string
+	if req.Connection != nil {
+		clientAddr = req.Connection.RemoteAddr
+	}
+	if !q.allow(clientAddr, time.Now()) {
+		metrics.IncrCounterWithLabels([]string{"quota", "rate_limit", "violation"}, 1,
+			[]metrics.Label{{Name: "name", Value: q.Name}})
+		return logical.ErrRateLimitQuotaExceeded
+	}
+	return nil
+}
+
+// reserveLease counts a new lease against the lease-count quota of its path.
+// It returns ErrLeaseCountQuotaExceeded if the quota has no room left, in
+// which case the lease must not be created.
+func (qm *QuotaManager) reserveLease(leaseID string) error {
+	if qm == nil {
+		return nil
+	}
+
+	qm.lock.Lock()
+	defer qm.lock.Unlock()
+
+	q := qm.leaseCountForPath(leaseID)
+	if q != nil && q.count >= q.MaxLeases {
+		metrics.IncrCounterWithLabels([]string{"quota", "lease_count", "violation"}, 1,
+			[]metrics.Label{{Name: "name", Value: q.Name}})
+		return logical.ErrLeaseCountQuotaExceeded
+	}
+	if qm.leaseChanges != nil {
+		qm.leaseChanges = append(qm.leaseChanges, leaseChange{leaseID: leaseID, reserved: true})
+	}
+	if q != nil {
+		q.count++
+	}
+	return nil
+}
+
+// releaseLease stops counting a lease that was revoked, or could not be
+// created after it was reserved
+func (qm *QuotaManager) releaseLease(leaseID string) {
+	if qm == nil {
+		return
+	}
+
+	qm.lock.Lock()
+	defer qm.lock.Unlock()
+
+	if qm.leaseChanges != nil {
+		qm.leaseChanges = append(qm.leaseChanges, leaseChange{leaseID: leaseID})
+	}
+	if q := qm.leaseCountForPath(leaseID); q != nil && q.count > 0 {
+		q.count--
+	}
+}
+
+// emitMetrics is invoked periodically to emit the lease counts
+func (qm *QuotaManager) emitMetrics() {
+	qm.lock.RLock()
+	defer qm.lock.RUnlock()
+
+	for name, q := range qm.leaseCounts {
+		metrics.SetGaugeWithLabels([]string{"quota", "lease_count", "counter"}, float32(q.count),
+			[]metrics.Label{{Name: "name", Value: name}})
+		metrics.SetGaugeWithLabels([]string{"quota", "lease_count", "max"}, float32(q.MaxLeases),
+			[]metrics.Label{{Name: "name", Value: name}})
+	}
+}
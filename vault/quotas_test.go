@@ -0,0 +1,346 @@
+package vault
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/logical"
+)
+
+func TestRateLimitQuota_Allow(t *testing.T) {
+	now := time.Now()
+	q := &rateLimitQuota{
+		Quota: &Quota{
+			Name:  "test",
+			Type:  QuotaTypeRateLimit,
+			Rate:  2,
+			Burst: 3,
+			Scope: RateLimitScopeClient,
+		},
+		buckets:   make(map[string]*tokenBucket),
+		lastPurge: now,
+	}
+
+	// The burst is allowed, after which the bucket is empty
+	for i := 0; i < 3; i++ {
+		if !q.allow("127.0.0.1", now) {
+			t.Fatalf("request %d was not allowed", i)
+		}
+	}
+	if q.allow("127.0.0.1", now) {
+		t.Fatal("request over the burst was allowed")
+	}
+
+	// Other clients have their own bucket
+	if !q.allow("127.0.0.2", now) {
+		t.Fatal("request of another client was not allowed")
+	}
+
+	// The bucket is refilled at the rate of the quota
+	now = now.Add(500 * time.Millisecond)
+	if !q.allow("127.0.0.1", now) {
+		t.Fatal("request after refill was not allowed")
+	}
+	if q.allow("127.0.0.1", now) {
+		t.Fatal("request over the refill was allowed")
+	}
+
+	// Buckets that are full again are purged
+	now = now.Add(rateLimitPurgeInterval + time.Second)
+	q.lock.Lock()
+	q.purge(now)
+	n := len(q.buckets)
+	q.lock.Unlock()
+	if n != 0 {
+		t.Fatalf("expected buckets to be purged, got %d", n)
+	}
+
+	// With the path scope all clients share a bucket
+	q.Scope = RateLimitScopePath
+	for i := 0; i < 3; i++ {
+		if !q.allow("127.0.0.1", now) {
+			t.Fatalf("request %d was not allowed", i)
+		}
+	}
+	if q.allow("127.0.0.2", now) {
+		t.Fatal("request over the shared burst was allowed")
+	}
+}
+
+func TestQuotaManager_PathBoundary(t *testing.T) {
+	qm := &QuotaManager{
+		rateLimits: map[string]*rateLimitQuota{
+			"secret": &rateLimitQuota{Quota: &Quota{Name: "secret", Type: QuotaTypeRateLimit, Path: "secret"}},
+		},
+		leaseCounts: map[string]*leaseCountQuota{
+			"secret": &leaseCountQuota{Quota: &Quota{Name: "secret", Type: QuotaTypeLeaseCount, Path: "secret"}},
+		},
+	}
+
+	for _, path := range []string{"secret", "secret/foo"} {
+		if q := qm.rateLimitForPath(path); q == nil {
+			t.Fatalf("expected rate-limit quota to apply to %q", path)
+		}
+		if q := qm.leaseCountForPath(path); q == nil {
+			t.Fatalf("expected lease-count quota to apply to %q", path)
+		}
+	}
+	for _, path := range []string{"secret-other/foo", "secretive/foo"} {
+		if q := qm.rateLimitForPath(path); q != nil {
+			t.Fatalf("expected no rate-limit quota to apply to %q", path)
+		}
+		if q := qm.leaseCountForPath(path); q != nil {
+			t.Fatalf("expected no lease-count quota to apply to %q", path)
+		}
+	}
+}
+
+func TestQuotaManager_SetQuota(t *testing.T) {
+	c, keys, _ := TestCoreUnsealed(t)
+
+	err := c.quotas.SetQuota(&Quota{Name: "bad", Type: QuotaTypeRateLimit})
+	if err == nil {
+		t.Fatal("expected error for missing rate")
+	}
+	err = c.quotas.SetQuota(&Quota{Name: "bad", Type: QuotaTypeLeaseCount})
+	if err == nil {
+		t.Fatal("expected error for missing max_leases")
+	}
+
+	quota := &Quota{Name: "foo", Type: QuotaTypeRateLimit, Path: "/secret/", Rate: 1.5}
+	if err := c.quotas.SetQuota(quota); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if quota.Path != "secret/" || quota.Burst != 2 || quota.Scope != RateLimitScopeClient {
+		t.Fatalf("bad: %#v", quota)
+	}
+
+	// Only one quota of a type may apply to a path
+	err = c.quotas.SetQuota(&Quota{Name: "bar", Type: QuotaTypeRateLimit, Path: "secret/", Rate: 1})
+	if err == nil {
+		t.Fatal("expected error for duplicate path")
+	}
+	err = c.quotas.SetQuota(&Quota{Name: "bar", Type: QuotaTypeLeaseCount, Path: "secret/", MaxLeases: 1})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The quotas are loaded by a second core with the same physical
+	conf := &CoreConfig{
+		Physical:     c.physical,
+		DisableMlock: true,
+	}
+	c2, err := NewCore(conf)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for _, key := range keys {
+		if _, err := TestCoreUnseal(c2, key); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+	if q := c2.quotas.Quota(QuotaTypeRateLimit, "foo"); q == nil || q.Rate != 1.5 {
+		t.Fatalf("bad: %#v", q)
+	}
+	if q := c2.quotas.Quota(QuotaTypeLeaseCount, "bar"); q == nil || q.MaxLeases != 1 {
+		t.Fatalf("bad: %#v", q)
+	}
+
+	if err := c.quotas.DeleteQuota(QuotaTypeRateLimit, "foo"); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if q := c.quotas.Quota(QuotaTypeRateLimit, "foo"); q != nil {
+		t.Fatalf("bad: %#v", q)
+	}
+	if q := c.quotas.Quota(QuotaTypeLeaseCount, "bar"); q == nil {
+		t.Fatal("lease-count quota was deleted along with the rate-limit quota")
+	}
+}
+
+func TestCore_Quotas_RateLimit(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "sys/quotas/rate-limit/global")
+	req.Data["rate"] = "1"
+	req.Data["burst"] = 2
+	req.Data["scope"] = RateLimitScopePath
+	req.ClientToken = root
+	if resp, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v, resp: %#v", err, resp)
+	}
+
+	for i := 0; i < 2; i++ {
+		req = logical.TestRequest(t, logical.ReadOperation, "sys/mounts")
+		req.ClientToken = root
+		if resp, err := c.HandleRequest(req); err != nil {
+			t.Fatalf("err: %v, resp: %#v", err, resp)
+		}
+	}
+	req = logical.TestRequest(t, logical.ReadOperation, "sys/mounts")
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err != logical.ErrRateLimitQuotaExceeded {
+		t.Fatalf("expected rate limit error, got %v", err)
+	}
+
+	// The quotas themselves can still be managed
+	req = logical.TestRequest(t, logical.ReadOperation, "sys/quotas/rate-limit/global")
+	req.ClientToken = root
+	resp, err := c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Data["rate"] != float64(1) || resp.Data["burst"] != 2 || resp.Data["scope"] != RateLimitScopePath {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	req = logical.TestRequest(t, logical.DeleteOperation, "sys/quotas/rate-limit/global")
+	req.ClientToken = root
+	if resp, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v, resp: %#v", err, resp)
+	}
+	req = logical.TestRequest(t, logical.ReadOperation, "sys/mounts")
+	req.ClientToken = root
+	if resp, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v, resp: %#v", err, resp)
+	}
+}
+
+func TestCore_Quotas_LeaseCount(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "sys/quotas/lease-count/tokens")
+	req.Data["path"] = "auth/token/create"
+	req.Data["max_leases"] = 2
+	req.ClientToken = root
+	if resp, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v, resp: %#v", err, resp)
+	}
+
+	createToken := func() (*logical.Response, error) {
+		req := logical.TestRequest(t, logical.UpdateOperation, "auth/token/create")
+		req.Data["policies"] = []string{"default"}
+		req.ClientToken = root
+		return c.HandleRequest(req)
+	}
+
+	var tokens []string
+	for i := 0; i < 2; i++ {
+		resp, err := createToken()
+		if err != nil || resp == nil || resp.Auth == nil {
+			t.Fatalf("err: %v, resp: %#v", err, resp)
+		}
+		tokens = append(tokens, resp.Auth.ClientToken)
+	}
+	if _, err := createToken(); !errwrap.Contains(err, logical.ErrLeaseCountQuotaExceeded.Error()) {
+		t.Fatalf("expected lease count error, got %v", err)
+	}
+
+	req = logical.TestRequest(t, logical.ReadOperation, "sys/quotas/lease-count/tokens")
+	req.ClientToken = root
+	resp, err := c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Data["counter"] != 2 || resp.Data["max_leases"] != 2 {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// Revoking a token makes room for another
+	req = logical.TestRequest(t, logical.UpdateOperation, "auth/token/revoke")
+	req.Data["token"] = tokens[0]
+	req.ClientToken = root
+	if resp, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v, resp: %#v", err, resp)
+	}
+	if resp, err := createToken(); err != nil || resp == nil || resp.Auth == nil {
+		t.Fatalf("err: %v, resp: %#v", err, resp)
+	}
+
+	// Quotas created after leases exist count them
+	req = logical.TestRequest(t, logical.DeleteOperation, "sys/quotas/lease-count/tokens")
+	req.ClientToken = root
+	if resp, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v, resp: %#v", err, resp)
+	}
+	req = logical.TestRequest(t, logical.UpdateOperation, "sys/quotas/lease-count/tokens")
+	req.Data["path"] = "auth/token/"
+	req.Data["max_leases"] = 10
+	req.ClientToken = root
+	if resp, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v, resp: %#v", err, resp)
+	}
+	if n := c.quotas.LeaseCount("tokens"); n != 2 {
+		t.Fatalf("expected 2 leases, got %d", n)
+	}
+}
+
+func TestCore_Quotas_LeaseCountNested(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+
+	setQuota := func(name, path string) {
+		req := logical.TestRequest(t, logical.UpdateOperation, "sys/quotas/lease-count/"+name)
+		req.Data["path"] = path
+		req.Data["max_leases"] = 2
+		req.ClientToken = root
+		if resp, err := c.HandleRequest(req); err != nil {
+			t.Fatalf("err: %v, resp: %#v", err, resp)
+		}
+	}
+	createToken := func() (*logical.Response, error) {
+		req := logical.TestRequest(t, logical.UpdateOperation, "auth/token/create")
+		req.Data["policies"] = []string{"default"}
+		req.ClientToken = root
+		return c.HandleRequest(req)
+	}
+	checkCounts := func(outer, inner int) {
+		t.Helper()
+		if n := c.quotas.LeaseCount("outer"); n != outer {
+			t.Fatalf("expected %d leases for outer quota, got %d", outer, n)
+		}
+		if n := c.quotas.LeaseCount("inner"); n != inner {
+			t.Fatalf("expected %d leases for inner quota, got %d", inner, n)
+		}
+	}
+
+	var tokens []string
+	for i := 0; i < 2; i++ {
+		resp, err := createToken()
+		if err != nil || resp == nil || resp.Auth == nil {
+			t.Fatalf("err: %v, resp: %#v", err, resp)
+		}
+		tokens = append(tokens, resp.Auth.ClientToken)
+	}
+
+	setQuota("outer", "auth/token/")
+	checkCounts(2, 0)
+
+	// The inner quota takes over the leases under its path
+	setQuota("inner", "auth/token/create")
+	checkCounts(0, 2)
+
+	// Only the inner quota applies to new leases, even though the outer
+	// quota has room left
+	if _, err := createToken(); !errwrap.Contains(err, logical.ErrLeaseCountQuotaExceeded.Error()) {
+		t.Fatalf("expected lease count error, got %v", err)
+	}
+
+	// Revocations are released from the inner quota
+	req := logical.TestRequest(t, logical.UpdateOperation, "auth/token/revoke")
+	req.Data["token"] = tokens[0]
+	req.ClientToken = root
+	if resp, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v, resp: %#v", err, resp)
+	}
+	checkCounts(0, 1)
+
+	// Deleting the inner quota hands its leases back to the outer one
+	req = logical.TestRequest(t, logical.DeleteOperation, "sys/quotas/lease-count/inner")
+	req.ClientToken = root
+	if resp, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v, resp: %#v", err, resp)
+	}
+	if n := c.quotas.LeaseCount("outer"); n != 1 {
+		t.Fatalf("expected 1 lease for outer quota, got %d", n)
+	}
+}
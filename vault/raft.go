@@ -0,0 +1,345 @@
+package vault
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/helper/jsonutil"
+	"github.com/hashicorp/vault/physical/raft"
+)
+
+const (
+	// raftTLSStoragePath is where the key securing raft connections is kept
+	raftTLSStoragePath = "core/raft/tls"
+
+	// raftLeaderTimeout bounds how long initialization waits for a freshly
+	// bootstrapped node to elect itself
+	raftLeaderTimeout = 30 * time.Second
+)
+
+var (
+	// ErrNotRaftStorage is returned by the raft operations when another
+	// storage backend is in use
+	ErrNotRaftStorage = errors.New("raft storage is not in use")
+)
+
+// raftClusterAddr returns the host and port of the cluster address, which is
+// the address peers use to reach this node
+func (c *Core) raftClusterAddr() (string, error) {
+	if c.clusterAddr == "" {
+		return "", errors.New("raft storage requires a cluster address")
+	}
+
+	u, err := url.Parse(c.clusterAddr)
+	if err != nil {
+		return "", errwrap.Wrapf("error parsing cluster address: {{err}}", err)
+	}
+
+	return u.Host, nil
+}
+
+// raftTLSKey reads the raft TLS key from the barrier, optionally creating it
+// when it does not exist yet
+func (c *Core) raftTLSKey(create bool) (*raft.TLSKey, error) {
+	entry, err := c.barrier.Get(raftTLSStoragePath)
+	if err != nil {
+		return nil, err
+	}
+
+	if entry != nil {
+		var tlsKey raft.TLSKey
+		if err := jsonutil.DecodeJSON(entry.Value, &tlsKey); err != nil {
+			return nil, errwrap.Wrapf("failed to decode raft tls key: {{err}}", err)
+		}
+		return &tlsKey, nil
+	}
+
+	if !create {
+		return nil, nil
+	}
+
+	tlsKey, err := raft.GenerateTLSKey()
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := json.Marshal(tlsKey)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.barrier.Put(&Entry{
+		Key:   raftTLSStoragePath,
+		Value: value,
+	}); err != nil {
+		return nil, errwrap.Wrapf("failed to store raft tls key: {{err}}", err)
+	}
+
+	return tlsKey, nil
+}
+
+// setupRaftCluster starts raft on this node with a stream layer carried over
+// the cluster port
+func (c *Core) setupRaftCluster(tlsKey *raft.TLSKey) error {
+	addr, err := c.raftClusterAddr()
+	if err != nil {
+		return err
+	}
+
+	layer, err := raft.NewRaftLayer(c.logger, tlsKey, addr)
+	if err != nil {
+		return err
+	}
+
+	c.clusterParamsLock.Lock()
+	c.raftLayer = layer
+	c.clusterParamsLock.Unlock()
+
+	if err := c.raftStorage.SetupCluster(raft.SetupOpts{
+		StreamLayer: layer,
+	}); err != nil {
+		c.clusterParamsLock.Lock()
+		c.raftLayer = nil
+		c.clusterParamsLock.Unlock()
+		return err
+	}
+
+	return nil
+}
+
+// bootstrapRaft creates a new single node raft cluster during initialization
+// and waits for this node to become the leader so that the initial data can
+// be written. The state lock must be held.
+func (c *Core) bootstrapRaft() error {
+	if c.raftStorage == nil || c.raftStorage.Initialized() {
+		return nil
+	}
+
+	addr, err := c.raftClusterAddr()
+	if err != nil {
+		return err
+	}
+
+	if err := c.raftStorage.Bootstrap([]raft.Peer{
+		{
+			ID:      c.raftStorage.NodeID(),
+			Address: addr,
+		},
+	}); err != nil {
+		return err
+	}
+
+	// The TLS key can only be created once the barrier is initialized; it
+	// is not needed until other nodes join
+	if err := c.setupRaftCluster(nil); err != nil {
+		return err
+	}
+
+	return c.raftStorage.WaitForLeader(raftLeaderTimeout)
+}
+
+// initRaftTLS creates the raft TLS key during initialization. The barrier
+// must be unsealed.
+func (c *Core) initRaftTLS() error {
+	if c.raftStorage == nil {
+		return nil
+	}
+
+	tlsKey, err := c.raftTLSKey(true)
+	if err != nil {
+		return err
+	}
+
+	c.clusterParamsLock.RLock()
+	layer := c.raftLayer
+	c.clusterParamsLock.RUnlock()
+
+	if layer == nil {
+		return errors.New("raft storage is not running")
+	}
+
+	return layer.SetTLSKey(tlsKey)
+}
+
+// startRaftStorage starts raft, if it is not already running from an
+// initialization or a join, and the cluster listener carrying its traffic.
+// It is run during unseal with the state lock held.
+func (c *Core) startRaftStorage() error {
+	if c.raftStorage == nil {
+		return nil
+	}
+
+	tlsKey, err := c.raftTLSKey(false)
+	if err != nil {
+		return err
+	}
+	if tlsKey == nil {
+		return errors.New("raft tls key not found in storage")
+	}
+
+	if c.raftStorage.Initialized() {
+		c.clusterParamsLock.RLock()
+		layer := c.raftLayer
+		c.clusterParamsLock.RUnlock()
+
+		if err := layer.SetTLSKey(tlsKey); err != nil {
+			return err
+		}
+	} else if err := c.setupRaftCluster(tlsKey); err != nil {
+		return err
+	}
+
+	return c.startClusterListener()
+}
+
+// stopRaftStorage stops raft and the cluster listener. It is run while
+// sealing with the state lock held.
+func (c *Core) stopRaftStorage() error {
+	if c.raftStorage == nil {
+		return nil
+	}
+
+	err := c.raftStorage.TeardownCluster()
+
+	c.clusterParamsLock.Lock()
+	c.raftLayer = nil
+	c.clusterParamsLock.Unlock()
+
+	c.stopClusterListener()
+
+	return err
+}
+
+// RaftJoinRequest holds the parameters used to join an existing raft cluster
+type RaftJoinRequest struct {
+	// LeaderAPIAddr is the API address of the active node of the cluster
+	LeaderAPIAddr string
+
+	// LeaderCACert is an optional PEM encoded CA certificate used to verify
+	// the TLS certificate of the leader's API
+	LeaderCACert string
+
+	// Token is used to authenticate to the leader and must be allowed to
+	// write to sys/storage/raft/bootstrap
+	Token string
+}
+
+// JoinRaftCluster adds this node to an existing raft cluster. The node must
+// not be initialized; once it has joined it receives the cluster's data and
+// can be unsealed with the cluster's keys.
+func (c *Core) JoinRaftCluster(joinReq *RaftJoinRequest) error {
+	if c.raftStorage == nil {
+		return ErrNotRaftStorage
+	}
+	if joinReq.LeaderAPIAddr == "" {
+		return errors.New("leader API address is required")
+	}
+
+	c.stateLock.Lock()
+	defer c.stateLock.Unlock()
+
+	init, err := c.Initialized()
+	if err != nil {
+		return err
+	}
+	if init {
+		return ErrAlreadyInit
+	}
+
+	hasState, err := c.raftStorage.HasState()
+	if err != nil {
+		return err
+	}
+	if hasState || c.raftStorage.Initialized() {
+		return errors.New("node is already part of a raft cluster")
+	}
+
+	addr, err := c.raftClusterAddr()
+	if err != nil {
+		return err
+	}
+
+	config := api.DefaultConfig()
+	config.Address = joinReq.LeaderAPIAddr
+	if joinReq.LeaderCACert != "" {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM([]byte(joinReq.LeaderCACert)) {
+			return errors.New("could not parse leader CA certificate")
+		}
+		config.HttpClient.Transport.(*http.Transport).TLSClientConfig.RootCAs = pool
+	}
+
+	client, err := api.NewClient(config)
+	if err != nil {
+		return err
+	}
+	client.SetToken(joinReq.Token)
+
+	secret, err := client.Logical().Write("sys/storage/raft/bootstrap", map[string]interface{}{
+		"server_id":    c.raftStorage.NodeID(),
+		"cluster_addr": addr,
+	})
+	if err != nil {
+		return errwrap.Wrapf("error asking the leader to add this node: {{err}}", err)
+	}
+	if secret == nil || secret.Data["tls_key"] == nil {
+		return errors.New("leader returned no raft tls key")
+	}
+
+	// Round trip through JSON to decode the base64 encoded key material
+	keyJSON, err := json.Marshal(secret.Data["tls_key"])
+	if err != nil {
+		return err
+	}
+	var tlsKey raft.TLSKey
+	if err := jsonutil.DecodeJSON(keyJSON, &tlsKey); err != nil {
+		return errwrap.Wrapf("failed to decode raft tls key: {{err}}", err)
+	}
+
+	if err := c.setupRaftCluster(&tlsKey); err != nil {
+		return err
+	}
+
+	if err := c.startClusterListener(); err != nil {
+		return err
+	}
+
+	if c.logger.IsInfo() {
+		c.logger.Info("core: joined raft cluster", "leader_api_addr", joinReq.LeaderAPIAddr, "node_id", c.raftStorage.NodeID())
+	}
+
+	return nil
+}
+
+// raftBootstrapAnswer adds a joining node to the cluster and returns the TLS
+// key it needs to talk to its peers
+func (c *Core) raftBootstrapAnswer(serverID, clusterAddr string) (*raft.TLSKey, []raft.Peer, error) {
+	if c.raftStorage == nil {
+		return nil, nil, ErrNotRaftStorage
+	}
+
+	tlsKey, err := c.raftTLSKey(false)
+	if err != nil {
+		return nil, nil, err
+	}
+	if tlsKey == nil {
+		return nil, nil, fmt.Errorf("raft tls key not found in storage")
+	}
+
+	if err := c.raftStorage.AddPeer(serverID, clusterAddr); err != nil {
+		return nil, nil, err
+	}
+
+	peers, err := c.raftStorage.Peers()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return tlsKey, peers, nil
+}
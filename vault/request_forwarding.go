@@ -13,6 +13,7 @@ import (
 	"time"
 
 	"github.com/hashicorp/vault/helper/forwarding"
+	"github.com/hashicorp/vault/physical/raft"
 	"golang.org/x/net/context"
 	"golang.org/x/net/http2"
 	"google.golang.org/grpc"
@@ -158,6 +159,22 @@ func (c *Core) startForwarding() error {
 					})
 					c.clusterParamsLock.RUnlock()
 
+				case raft.RaftALPN:
+					c.clusterParamsLock.RLock()
+					layer := c.raftLayer
+					c.clusterParamsLock.RUnlock()
+
+					if layer == nil {
+						conn.Close()
+						continue
+					}
+
+					c.logger.Trace("core: got raft connection")
+					if err := layer.Handoff(conn); err != nil {
+						c.logger.Debug("core: failed to hand off raft connection", "error", err)
+						conn.Close()
+					}
+
 				default:
 					c.logger.Debug("core: unknown negotiated protocol on cluster port")
 					conn.Close()
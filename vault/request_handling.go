@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/armon/go-metrics"
+	"github.com/hashicorp/errwrap"
 	"github.com/hashicorp/go-multierror"
 	"github.com/hashicorp/vault/helper/consts"
 	"github.com/hashicorp/vault/helper/identity"
@@ -43,6 +44,15 @@ func (c *Core) HandleRequest(req *logical.Request) (resp *logical.Response, err
 		return logical.ErrorResponse("cannot write to a path ending in '/'"), nil
 	}
 
+	// Only part of the system backend is served within namespaces
+	if ns := c.namespaceByPath(req.Path); ns.ID != "" && !namespaceSysPathAllowed(strings.TrimPrefix(req.Path, ns.Path)) {
+		return logical.ErrorResponse(fmt.Sprintf("path '%s' is not available within a namespace", req.Path)), logical.ErrUnsupportedPath
+	}
+
+	if err := c.quotas.allowRequest(req); err != nil {
+		return nil, err
+	}
+
 	var auth *logical.Auth
 	if c.router.LoginPath(req.Path) {
 		resp, auth, err = c.handleLoginRequest(req)
@@ -63,12 +73,14 @@ func (c *Core) HandleRequest(req *logical.Request) (resp *logical.Response, err
 	// We are wrapping if there is anything to wrap (not a nil response) and a
 	// TTL was specified for the token. Errors on a call should be returned to
 	// the caller, so wrapping is turned off if an error is hit and the error
-	// is logged to the audit log.
+	// is logged to the audit log. Requests held by a control group come back
+	// already wrapped.
 	wrapping := resp != nil &&
 		err == nil &&
 		!resp.IsError() &&
 		resp.WrapInfo != nil &&
-		resp.WrapInfo.TTL != 0
+		resp.WrapInfo.TTL != 0 &&
+		resp.WrapInfo.Token == ""
 
 	if wrapping {
 		cubbyResp, cubbyErr := c.wrapInCubbyhole(req, resp, auth)
@@ -158,13 +170,23 @@ func (c *Core) handleRequest(req *logical.Request) (retResp *logical.Response, r
 			}(te.ID)
 		}
 	}
+	if cgErr, ok := ctErr.(*controlGroupRequiredError); ok {
+		resp, err := c.holdControlGroupRequest(req, auth, te, cgErr.controlGroup)
+		if err != nil {
+			retErr = multierror.Append(retErr, err)
+		}
+		return resp, auth, retErr
+	}
 	if ctErr != nil {
 		// If it is an internal error we return that, otherwise we
 		// return invalid request so that the status codes can be correct
 		var errType error
-		switch ctErr {
-		case ErrInternalError, logical.ErrPermissionDenied:
+		switch {
+		case ctErr == ErrInternalError, ctErr == logical.ErrPermissionDenied:
 			errType = ctErr
+		case errwrap.Contains(ctErr, logical.ErrPermissionDenied.Error()):
+			// Failed MFA validations carry their cause along with the denial
+			errType = logical.ErrPermissionDenied
 		default:
 			errType = logical.ErrInvalidRequest
 		}
@@ -192,6 +214,13 @@ func (c *Core) handleRequest(req *logical.Request) (retResp *logical.Response, r
 		return nil, auth, retErr
 	}
 
+	// Batch tokens are never revoked, so the cubbyhole of one would never be
+	// destroyed
+	if te.Type == logical.TokenTypeBatch && strings.HasPrefix(c.namespaceRelativePath(req.Path), "cubbyhole/") {
+		retErr = multierror.Append(retErr, logical.ErrInvalidRequest)
+		return logical.ErrorResponse("cubbyhole operations are not supported with batch tokens"), auth, retErr
+	}
+
 	// Route the request
 	resp, routeErr := c.router.Route(req)
 	if resp != nil {
@@ -236,8 +265,9 @@ func (c *Core) handleRequest(req *logical.Request) (retResp *logical.Response, r
 
 	// If there is a secret, we must register it with the expiration manager.
 	// We exclude renewal of a lease, since it does not need to be re-registered
-	if resp != nil && resp.Secret != nil && !strings.HasPrefix(req.Path, "sys/renew") &&
-		!strings.HasPrefix(req.Path, "sys/leases/renew") {
+	nsPath := c.namespaceRelativePath(req.Path)
+	if resp != nil && resp.Secret != nil && !strings.HasPrefix(nsPath, "sys/renew") &&
+		!strings.HasPrefix(nsPath, "sys/leases/renew") {
 		// Get the SystemView for the mount
 		sysView := c.router.MatchingSystemView(req.Path)
 		if sysView == nil {
@@ -273,11 +303,30 @@ func (c *Core) handleRequest(req *logical.Request) (retResp *logical.Response, r
 			}
 		}
 
+		// Leases created with a batch token can't outlive it, and are
+		// tracked against its parent so that they are revoked along with it
+		registerReq := req
+		if te.Type == logical.TokenTypeBatch {
+			remaining := time.Unix(te.CreationTime, 0).Add(te.TTL).Sub(time.Now())
+			if resp.Secret.TTL > remaining {
+				resp.Secret.TTL = remaining
+			}
+			if te.Parent != "" {
+				registerReq = new(logical.Request)
+				*registerReq = *req
+				registerReq.ClientToken = te.Parent
+			}
+		}
+
 		if registerLease {
-			leaseID, err := c.expiration.Register(req, resp)
+			leaseID, err := c.expiration.Register(registerReq, resp)
 			if err != nil {
 				c.logger.Error("core: failed to register lease", "request_path", req.Path, "error", err)
-				retErr = multierror.Append(retErr, ErrInternalError)
+				if errwrap.Contains(err, logical.ErrLeaseCountQuotaExceeded.Error()) {
+					retErr = multierror.Append(retErr, logical.ErrLeaseCountQuotaExceeded)
+				} else {
+					retErr = multierror.Append(retErr, ErrInternalError)
+				}
 				return nil, auth, retErr
 			}
 			resp.Secret.LeaseID = leaseID
@@ -287,15 +336,15 @@ func (c *Core) handleRequest(req *logical.Request) (retResp *logical.Response, r
 	// Only the token store is allowed to return an auth block, for any
 	// other request this is an internal error. We exclude renewal of a token,
 	// since it does not need to be re-registered
-	if resp != nil && resp.Auth != nil && !strings.HasPrefix(req.Path, "auth/token/renew") {
-		if !strings.HasPrefix(req.Path, "auth/token/") {
+	if resp != nil && resp.Auth != nil && !strings.HasPrefix(nsPath, "auth/token/renew") {
+		if !strings.HasPrefix(nsPath, "auth/token/") {
 			c.logger.Error("core: unexpected Auth response for non-token backend", "request_path", req.Path)
 			retErr = multierror.Append(retErr, ErrInternalError)
 			return nil, auth, retErr
 		}
 
 		// Register with the expiration manager. We use the token's actual path
-		// here because roles allow suffixes.
+		// here because roles allow suffixes. Batch tokens have no lease.
 		te, err := c.tokenStore.Lookup(resp.Auth.ClientToken)
 		if err != nil {
 			c.logger.Error("core: failed to look up token", "error", err)
@@ -303,11 +352,17 @@ func (c *Core) handleRequest(req *logical.Request) (retResp *logical.Response, r
 			return nil, auth, retErr
 		}
 
-		if err := c.expiration.RegisterAuth(te.Path, resp.Auth); err != nil {
-			c.tokenStore.Revoke(te.ID)
-			c.logger.Error("core: failed to register token lease", "request_path", req.Path, "error", err)
-			retErr = multierror.Append(retErr, ErrInternalError)
-			return nil, auth, retErr
+		if te.Type != logical.TokenTypeBatch {
+			if err := c.expiration.RegisterAuth(te.Path, resp.Auth); err != nil {
+				c.tokenStore.Revoke(te.ID)
+				c.logger.Error("core: failed to register token lease", "request_path", req.Path, "error", err)
+				if err == logical.ErrLeaseCountQuotaExceeded {
+					retErr = multierror.Append(retErr, err)
+				} else {
+					retErr = multierror.Append(retErr, ErrInternalError)
+				}
+				return nil, auth, retErr
+			}
 		}
 	}
 
@@ -341,9 +396,14 @@ func (c *Core) handleLoginRequest(req *logical.Request) (retResp *logical.Respon
 		return nil, nil, ErrInternalError
 	}
 
+	// Logins held for MFA are completed by the core rather than a backend
+	if req.Path == "sys/mfa/validate" && req.Operation != logical.HelpOperation {
+		return c.handleLoginMFAValidate(req)
+	}
+
 	// The token store uses authentication even when creating a new token,
 	// so it's handled in handleRequest. It should not be reached here.
-	if strings.HasPrefix(req.Path, "auth/token/") {
+	if strings.HasPrefix(c.namespaceRelativePath(req.Path), "auth/token/") {
 		c.logger.Error("core: unexpected login request for token backend", "request_path", req.Path)
 		return nil, nil, ErrInternalError
 	}
@@ -399,6 +459,9 @@ func (c *Core) handleLoginRequest(req *logical.Request) (retResp *logical.Respon
 		var entity *identity.Entity
 		auth = resp.Auth
 
+		// Tokens belong to the namespace of the auth method that issued them
+		ns := c.namespaceByPath(req.Path)
+
 		if auth.Alias != nil {
 			// Overwrite the mount type and mount path in the alias
 			// information
@@ -409,98 +472,169 @@ func (c *Core) handleLoginRequest(req *logical.Request) (retResp *logical.Respon
 				return nil, nil, fmt.Errorf("missing name in alias")
 			}
 
+			identityStore := c.namespaceIdentityStore(ns)
+			if identityStore == nil {
+				c.logger.Error("core: identity store of namespace is unavailable", "namespace", ns.Path)
+				return nil, nil, ErrInternalError
+			}
+
 			var err error
 
 			// Check if an entity already exists for the given alias
-			entity, err = c.identityStore.EntityByAliasFactors(auth.Alias.MountAccessor, auth.Alias.Name, false)
+			entity, err = identityStore.EntityByAliasFactors(auth.Alias.MountAccessor, auth.Alias.Name, false)
 			if err != nil {
 				return nil, nil, err
 			}
 
-			// If not, create one.
+			// If not, create one. Otherwise, keep the metadata of the alias
+			// up to date with what the auth backend returned.
 			if entity == nil {
 				c.logger.Debug("core: creating a new entity", "alias", auth.Alias)
-				entity, err = c.identityStore.CreateEntity(auth.Alias)
+				entity, err = identityStore.CreateEntity(auth.Alias)
 				if err != nil {
 					return nil, nil, err
 				}
 				if entity == nil {
 					return nil, nil, fmt.Errorf("failed to create an entity for the authenticated alias")
 				}
+			} else {
+				err = identityStore.UpdateAliasMetadata(entity.ID, auth.Alias)
+				if err != nil {
+					return nil, nil, err
+				}
 			}
 
 			auth.EntityID = entity.ID
+
+			// Update the memberships of the entity in the external groups
+			// tied to this mount
+			for _, groupAlias := range auth.GroupAliases {
+				groupAlias.MountType = req.MountType
+				groupAlias.MountAccessor = req.MountAccessor
+			}
+			err = identityStore.refreshExternalGroupMembershipsByEntityID(entity.ID, auth.GroupAliases, req.MountAccessor)
+			if err != nil {
+				return nil, nil, err
+			}
 		}
 
-		if strutil.StrListSubset(auth.Policies, []string{"root"}) {
-			return logical.ErrorResponse("authentication backends cannot create root tokens"), nil, logical.ErrInvalidRequest
+		// Hold the login until the MFA methods required for the auth
+		// method are validated. The token is created once they are.
+		if methods := c.mfa.loginMethods(req); len(methods) > 0 {
+			mfaResp, err := c.mfa.holdLogin(req, resp, ns, entity, methods)
+			return mfaResp, nil, err
 		}
 
-		// Determine the source of the login
-		source := c.router.MatchingMount(req.Path)
-		source = strings.TrimPrefix(source, credentialRoutePrefix)
-		source = strings.Replace(source, "/", "-", -1)
+		if errResp, retAuth, err := c.createLoginToken(req, auth); err != nil {
+			return errResp, retAuth, err
+		}
+	}
 
-		// Prepend the source to the display name
-		auth.DisplayName = strings.TrimSuffix(source+auth.DisplayName, "-")
+	return resp, auth, routeErr
+}
 
-		sysView := c.router.MatchingSystemView(req.Path)
-		if sysView == nil {
-			c.logger.Error("core: unable to look up sys view for login path", "request_path", req.Path)
-			return nil, nil, ErrInternalError
-		}
+// createLoginToken creates the token of a login for the authentication
+// returned by the auth method
+func (c *Core) createLoginToken(req *logical.Request, auth *logical.Auth) (*logical.Response, *logical.Auth, error) {
+	// Tokens belong to the namespace of the auth method that issued them
+	ns := c.namespaceByPath(req.Path)
 
-		// Set the default lease if not provided
-		if auth.TTL == 0 {
-			auth.TTL = sysView.DefaultLeaseTTL()
-		}
+	if strutil.StrListSubset(auth.Policies, []string{"root"}) {
+		return logical.ErrorResponse("authentication backends cannot create root tokens"), nil, logical.ErrInvalidRequest
+	}
 
-		// Limit the lease duration
-		if auth.TTL > sysView.MaxLeaseTTL() {
-			auth.TTL = sysView.MaxLeaseTTL()
-		}
+	// Determine the source of the login
+	source := c.router.MatchingMount(req.Path)
+	source = strings.TrimPrefix(source, ns.Path+credentialRoutePrefix)
+	source = strings.Replace(source, "/", "-", -1)
 
-		// Generate a token
-		te := TokenEntry{
-			Path:         req.Path,
-			Policies:     auth.Policies,
-			Meta:         auth.Metadata,
-			DisplayName:  auth.DisplayName,
-			CreationTime: time.Now().Unix(),
-			TTL:          auth.TTL,
-			NumUses:      auth.NumUses,
-			EntityID:     auth.EntityID,
-		}
+	// Prepend the source to the display name
+	auth.DisplayName = strings.TrimSuffix(source+auth.DisplayName, "-")
 
-		te.Policies = policyutil.SanitizePolicies(te.Policies, true)
+	sysView := c.router.MatchingSystemView(req.Path)
+	if sysView == nil {
+		c.logger.Error("core: unable to look up sys view for login path", "request_path", req.Path)
+		return nil, nil, ErrInternalError
+	}
 
-		// Prevent internal policies from being assigned to tokens
-		for _, policy := range te.Policies {
-			if strutil.StrListContains(nonAssignablePolicies, policy) {
-				return logical.ErrorResponse(fmt.Sprintf("cannot assign policy %q", policy)), nil, logical.ErrInvalidRequest
-			}
-		}
+	// Set the default lease if not provided
+	if auth.TTL == 0 {
+		auth.TTL = sysView.DefaultLeaseTTL()
+	}
 
-		if err := c.tokenStore.create(&te); err != nil {
-			c.logger.Error("core: failed to create token", "error", err)
-			return nil, auth, ErrInternalError
+	// Limit the lease duration
+	if auth.TTL > sysView.MaxLeaseTTL() {
+		auth.TTL = sysView.MaxLeaseTTL()
+	}
+
+	// The mount decides the type of the token, unless it lets the
+	// backend choose it
+	mountTokenType := logical.TokenTypeDefaultService
+	if me := c.router.MatchingMountEntry(req.Path); me != nil && me.Config.TokenType != logical.TokenTypeDefault {
+		mountTokenType = me.Config.TokenType
+	}
+	tokenType, err := mountTokenType.Resolve(auth.TokenType)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), nil, logical.ErrInvalidRequest
+	}
+	if tokenType == logical.TokenTypeBatch {
+		switch {
+		case auth.NumUses != 0:
+			return logical.ErrorResponse("batch tokens cannot have a limited number of uses"), nil, logical.ErrInvalidRequest
+		case auth.Period != 0:
+			return logical.ErrorResponse("batch tokens cannot be periodic"), nil, logical.ErrInvalidRequest
+		}
+		auth.Renewable = false
+	}
+
+	// Generate a token
+	te := TokenEntry{
+		Path:         req.Path,
+		Policies:     auth.Policies,
+		Meta:         auth.Metadata,
+		DisplayName:  auth.DisplayName,
+		CreationTime: time.Now().Unix(),
+		TTL:          auth.TTL,
+		NumUses:      auth.NumUses,
+		EntityID:     auth.EntityID,
+		Type:         tokenType,
+		NamespaceID:  ns.ID,
+	}
+
+	te.Policies = policyutil.SanitizePolicies(te.Policies, true)
+
+	// Prevent internal policies from being assigned to tokens
+	for _, policy := range te.Policies {
+		if strutil.StrListContains(nonAssignablePolicies, policy) {
+			return logical.ErrorResponse(fmt.Sprintf("cannot assign policy %q", policy)), nil, logical.ErrInvalidRequest
 		}
+	}
+
+	if err := c.tokenStore.create(&te); err != nil {
+		c.logger.Error("core: failed to create token", "error", err)
+		return nil, auth, ErrInternalError
+	}
 
-		// Populate the client token and accessor
-		auth.ClientToken = te.ID
-		auth.Accessor = te.Accessor
-		auth.Policies = te.Policies
+	// Populate the client token and accessor
+	auth.ClientToken = te.ID
+	auth.Accessor = te.Accessor
+	auth.Policies = te.Policies
+	auth.TokenType = te.Type
 
-		// Register with the expiration manager
+	// Register with the expiration manager, batch tokens have no lease
+	if te.Type != logical.TokenTypeBatch {
 		if err := c.expiration.RegisterAuth(te.Path, auth); err != nil {
 			c.tokenStore.Revoke(te.ID)
 			c.logger.Error("core: failed to register token lease", "request_path", req.Path, "error", err)
+			if err == logical.ErrLeaseCountQuotaExceeded {
+				return nil, auth, err
+			}
 			return nil, auth, ErrInternalError
 		}
-
-		// Attach the display name, might be used by audit backends
-		req.DisplayName = auth.DisplayName
 	}
 
-	return resp, auth, routeErr
+	// Attach the display name, might be used by audit backends
+	req.DisplayName = auth.DisplayName
+
+	return nil, nil, nil
 }
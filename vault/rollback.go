@@ -111,10 +111,7 @@ func (m *RollbackManager) triggerRollbacks() {
 	backends := m.backends()
 
 	for _, e := range backends {
-		path := e.Path
-		if e.Table == credentialTableType {
-			path = credentialRoutePrefix + path
-		}
+		path := e.APIPath()
 
 		// When the mount is filtered, the backend will be nil
 		backend := m.router.MatchingBackend(path)
@@ -146,7 +143,8 @@ func (m *RollbackManager) startRollback(path string) *rollbackState {
 
 // attemptRollback invokes a RollbackOperation for the given path
 func (m *RollbackManager) attemptRollback(path string, rs *rollbackState) (err error) {
-	defer metrics.MeasureSince([]string{"rollback", "attempt", strings.Replace(path, "/", "-", -1)}, time.Now())
+	defer metrics.MeasureSinceWithLabels([]string{"rollback", "attempt"}, time.Now(),
+		[]metrics.Label{{Name: "mount", Value: strings.Replace(path, "/", "-", -1)}})
 	if m.logger.IsTrace() {
 		m.logger.Trace("rollback: attempting rollback", "path", path)
 	}
@@ -328,6 +328,9 @@ func (r *Router) matchingStoragePrefix(path string, apiPath bool) (string, strin
 		mountPath = credentialRoutePrefix + mountPath
 	}
 
+	// Add back the path of the namespace
+	mountPath = re.mountEntry.Namespace().Path + mountPath
+
 	return mountPath, prefix, true
 }
 
@@ -359,8 +362,10 @@ func (r *Router) routeCommon(req *logical.Request, existenceCheck bool) (*logica
 		return logical.ErrorResponse(fmt.Sprintf("no handler for route '%s'", req.Path)), false, false, logical.ErrUnsupportedPath
 	}
 	req.Path = adjustedPath
-	defer metrics.MeasureSince([]string{"route", string(req.Operation),
-		strings.Replace(mount, "/", "-", -1)}, time.Now())
+	// The mount is a label so that it can be aggregated over; sinks without
+	// label support append it to the key as before
+	defer metrics.MeasureSinceWithLabels([]string{"route", string(req.Operation)}, time.Now(),
+		[]metrics.Label{{Name: "mount", Value: strings.Replace(mount, "/", "-", -1)}})
 	re := raw.(*routeEntry)
 
 	// Filtered mounts will have a nil backend
@@ -392,11 +397,14 @@ func (r *Router) routeCommon(req *logical.Request, existenceCheck bool) (*logica
 
 	originalEntityID := req.EntityID
 
+	// The built-in mounts are identified by their path within their namespace
+	nsPath := strings.TrimPrefix(originalPath, re.mountEntry.Namespace().Path)
+
 	// Allow EntityID to passthrough to the system backend. This is required to
 	// allow clients to generate MFA credentials in respective entity objects
 	// in identity store via the system backend.
 	switch {
-	case strings.HasPrefix(originalPath, "sys/"):
+	case strings.HasPrefix(nsPath, "sys/"):
 	default:
 		req.EntityID = ""
 	}
@@ -405,9 +413,9 @@ func (r *Router) routeCommon(req *logical.Request, existenceCheck bool) (*logica
 	// or system backend.
 	clientToken := req.ClientToken
 	switch {
-	case strings.HasPrefix(originalPath, "auth/token/"):
-	case strings.HasPrefix(originalPath, "sys/"):
-	case strings.HasPrefix(originalPath, "cubbyhole/"):
+	case strings.HasPrefix(nsPath, "auth/token/"):
+	case strings.HasPrefix(nsPath, "sys/"):
+	case strings.HasPrefix(nsPath, "cubbyhole/"):
 		// In order for the token store to revoke later, we need to have the same
 		// salted ID, so we double-salt what's going to the cubbyhole backend
 		salt, err := r.tokenStoreSaltFunc()
@@ -41,10 +41,12 @@ const (
 )
 
 const (
-	SealTypeShamir = "shamir"
-	SealTypePKCS11 = "pkcs11"
-	SealTypeAWSKMS = "awskms"
-	SealTypeTest   = "test-auto"
+	SealTypeShamir  = "shamir"
+	SealTypePKCS11  = "pkcs11"
+	SealTypeAWSKMS  = "awskms"
+	SealTypeTransit = "transit"
+	SealTypeKeyFile = "keyfile"
+	SealTypeTest    = "test-auto"
 
 	RecoveryTypeUnsupported = "unsupported"
 	RecoveryTypeShamir      = "shamir"
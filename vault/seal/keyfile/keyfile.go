@@ -0,0 +1,183 @@
+package keyfile
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/vault/seal"
+	log "github.com/mgutz/logxi/v1"
+)
+
+// keySize is the size of the key encryption key, for AES-256
+const keySize = 32
+
+// KeyFileSeal wraps keys with a key encryption key (KEK) read from a file on
+// the local disk. The KEK is kept next to the data it protects, so this seal
+// is meant for development and testing, not production.
+type KeyFileSeal struct {
+	logger log.Logger
+
+	path        string
+	generateKey bool
+
+	l     sync.RWMutex
+	aead  cipher.AEAD
+	keyID string
+}
+
+// Ensure we are implementing the seal.Access interface
+var _ seal.Access = (*KeyFileSeal)(nil)
+
+// NewSeal creates a KeyFileSeal from its configuration. The key itself is
+// only read when the seal is initialized.
+func NewSeal(conf map[string]string, logger log.Logger) (*KeyFileSeal, error) {
+	path := conf["path"]
+	if path == "" {
+		return nil, errors.New("'path' must be set")
+	}
+
+	var generateKey bool
+	if generateKeyRaw, ok := conf["generate_key"]; ok {
+		var err error
+		generateKey, err = strconv.ParseBool(generateKeyRaw)
+		if err != nil {
+			return nil, errwrap.Wrapf("failed parsing 'generate_key' parameter: {{err}}", err)
+		}
+	}
+
+	return &KeyFileSeal{
+		logger:      logger,
+		path:        path,
+		generateKey: generateKey,
+	}, nil
+}
+
+// Init reads the key from the key file, generating it first if that is
+// enabled and the file does not exist
+func (s *KeyFileSeal) Init() error {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	encoded, err := ioutil.ReadFile(s.path)
+	switch {
+	case os.IsNotExist(err) && s.generateKey:
+		key := make([]byte, keySize)
+		if _, err := rand.Read(key); err != nil {
+			return errwrap.Wrapf("failed to generate key: {{err}}", err)
+		}
+		encoded = []byte(base64.StdEncoding.EncodeToString(key))
+		if err := ioutil.WriteFile(s.path, encoded, 0600); err != nil {
+			return errwrap.Wrapf("failed to write key file: {{err}}", err)
+		}
+		if s.logger.IsInfo() {
+			s.logger.Info("seal: generated key encryption key", "path", s.path)
+		}
+	case err != nil:
+		return errwrap.Wrapf("failed to read key file: {{err}}", err)
+	}
+
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(encoded)))
+	if err != nil {
+		return errwrap.Wrapf("failed to decode key file: {{err}}", err)
+	}
+	if len(key) != keySize {
+		return fmt.Errorf("key must be %d bytes long, got %d", keySize, len(key))
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return err
+	}
+
+	// The key ID identifies the key without revealing it
+	sum := sha256.Sum256(key)
+	s.keyID = hex.EncodeToString(sum[:8])
+	s.aead = aead
+
+	return nil
+}
+
+// Finalize forgets the key
+func (s *KeyFileSeal) Finalize() error {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	s.aead = nil
+	return nil
+}
+
+// SealType returns the type of this seal
+func (s *KeyFileSeal) SealType() string {
+	return seal.KeyFile
+}
+
+// KeyID returns the identifier of the key in the key file
+func (s *KeyFileSeal) KeyID() string {
+	s.l.RLock()
+	defer s.l.RUnlock()
+
+	return s.keyID
+}
+
+// Encrypt wraps the plaintext with the key encryption key
+func (s *KeyFileSeal) Encrypt(plaintext []byte) (*seal.EncryptedBlobInfo, error) {
+	s.l.RLock()
+	defer s.l.RUnlock()
+
+	if s.aead == nil {
+		return nil, errors.New("seal is not initialized")
+	}
+
+	iv := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(iv); err != nil {
+		return nil, err
+	}
+
+	return &seal.EncryptedBlobInfo{
+		Ciphertext: s.aead.Seal(nil, iv, plaintext, nil),
+		IV:         iv,
+		KeyID:      s.keyID,
+	}, nil
+}
+
+// Decrypt unwraps a blob encrypted with the key encryption key
+func (s *KeyFileSeal) Decrypt(in *seal.EncryptedBlobInfo) ([]byte, error) {
+	s.l.RLock()
+	defer s.l.RUnlock()
+
+	if s.aead == nil {
+		return nil, errors.New("seal is not initialized")
+	}
+	if in == nil {
+		return nil, errors.New("given input for decryption is nil")
+	}
+	if in.KeyID != s.keyID {
+		return nil, fmt.Errorf("value was encrypted with key %q, but the key file holds key %q", in.KeyID, s.keyID)
+	}
+	if len(in.IV) != s.aead.NonceSize() {
+		return nil, errors.New("invalid initialization vector")
+	}
+
+	plaintext, err := s.aead.Open(nil, in.IV, in.Ciphertext, nil)
+	if err != nil {
+		return nil, errwrap.Wrapf("failed to decrypt value: {{err}}", err)
+	}
+
+	return plaintext, nil
+}
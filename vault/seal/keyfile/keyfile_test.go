@@ -0,0 +1,105 @@
+package keyfile
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/vault/helper/logformat"
+	log "github.com/mgutz/logxi/v1"
+)
+
+func TestKeyFileSeal(t *testing.T) {
+	logger := logformat.NewVaultLogger(log.LevelTrace)
+
+	dir, err := ioutil.TempDir("", "vault-keyfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "kek")
+
+	if _, err := NewSeal(map[string]string{}, logger); err == nil {
+		t.Fatal("expected error without a path")
+	}
+
+	// A missing key file is an error unless asked to generate it
+	s, err := NewSeal(map[string]string{"path": path}, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Init(); err == nil {
+		t.Fatal("expected error reading missing key file")
+	}
+
+	s, err = NewSeal(map[string]string{"path": path, "generate_key": "true"}, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Init(); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Fatalf("bad key file permissions: %v", info.Mode().Perm())
+	}
+
+	blob, err := s.Encrypt([]byte("foo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if blob.KeyID == "" || blob.KeyID != s.KeyID() {
+		t.Fatalf("bad key id: %q", blob.KeyID)
+	}
+
+	// The generated key is picked up again on restart
+	s, err = NewSeal(map[string]string{"path": path, "generate_key": "true"}, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Init(); err != nil {
+		t.Fatal(err)
+	}
+	pt, err := s.Decrypt(blob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(pt, []byte("foo")) {
+		t.Fatalf("bad plaintext: %q", pt)
+	}
+
+	// Tampered values are rejected
+	blob.Ciphertext[0] ^= 0xff
+	if _, err := s.Decrypt(blob); err == nil {
+		t.Fatal("expected error decrypting tampered value")
+	}
+
+	// Values wrapped with another key are rejected
+	otherPath := filepath.Join(dir, "other")
+	other, err := NewSeal(map[string]string{"path": otherPath, "generate_key": "true"}, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := other.Init(); err != nil {
+		t.Fatal(err)
+	}
+	otherBlob, err := other.Encrypt([]byte("foo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Decrypt(otherBlob); err == nil {
+		t.Fatal("expected error decrypting value wrapped with another key")
+	}
+
+	if err := s.Finalize(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Encrypt([]byte("foo")); err == nil {
+		t.Fatal("expected error after finalize")
+	}
+}
@@ -0,0 +1,47 @@
+package seal
+
+const (
+	// Transit is the type of the seal wrapping keys with a transit key on
+	// another Vault server
+	Transit = "transit"
+
+	// KeyFile is the type of the seal wrapping keys with a key encryption
+	// key read from a local file
+	KeyFile = "keyfile"
+
+	// Test is the type of the in-memory seal used in tests
+	Test = "test-auto"
+)
+
+// Access is implemented by the auto-unseal mechanisms. It is used to wrap the
+// master key and the recovery key before they are written to storage, so
+// that Vault can unseal itself without unseal keys being entered.
+type Access interface {
+	// SealType returns the type of the seal, stored with the seal
+	// configuration
+	SealType() string
+
+	// KeyID returns the identifier of the key currently used to encrypt
+	KeyID() string
+
+	// Init is called when Vault starts and before any data is encrypted or
+	// decrypted
+	Init() error
+
+	// Finalize is called when Vault shuts down
+	Finalize() error
+
+	// Encrypt wraps the given plaintext
+	Encrypt([]byte) (*EncryptedBlobInfo, error)
+
+	// Decrypt unwraps a blob previously returned by Encrypt
+	Decrypt(*EncryptedBlobInfo) ([]byte, error)
+}
+
+// EncryptedBlobInfo contains a value wrapped by a seal along with the
+// information needed to unwrap it
+type EncryptedBlobInfo struct {
+	Ciphertext []byte `json:"ciphertext"`
+	IV         []byte `json:"iv,omitempty"`
+	KeyID      string `json:"key_id"`
+}
@@ -0,0 +1,68 @@
+package seal
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+)
+
+// TestSeal is an in-memory Access for tests. Its key only lives as long as
+// the value does.
+type TestSeal struct {
+	aead cipher.AEAD
+}
+
+// NewTestSeal returns a TestSeal with a random key
+func NewTestSeal() *TestSeal {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic(err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		panic(err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		panic(err)
+	}
+	return &TestSeal{
+		aead: aead,
+	}
+}
+
+func (s *TestSeal) SealType() string {
+	return Test
+}
+
+func (s *TestSeal) KeyID() string {
+	return "test"
+}
+
+func (s *TestSeal) Init() error {
+	return nil
+}
+
+func (s *TestSeal) Finalize() error {
+	return nil
+}
+
+func (s *TestSeal) Encrypt(plaintext []byte) (*EncryptedBlobInfo, error) {
+	iv := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(iv); err != nil {
+		return nil, err
+	}
+	return &EncryptedBlobInfo{
+		Ciphertext: s.aead.Seal(nil, iv, plaintext, nil),
+		IV:         iv,
+		KeyID:      s.KeyID(),
+	}, nil
+}
+
+func (s *TestSeal) Decrypt(blob *EncryptedBlobInfo) ([]byte, error) {
+	if blob == nil {
+		return nil, errors.New("no encrypted value given")
+	}
+	return s.aead.Open(nil, blob.IV, blob.Ciphertext, nil)
+}
@@ -0,0 +1,178 @@
+package transit
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/vault/seal"
+	log "github.com/mgutz/logxi/v1"
+)
+
+// TransitSeal wraps keys using a transit key on another Vault server
+type TransitSeal struct {
+	logger log.Logger
+
+	client    *api.Client
+	mountPath string
+	keyName   string
+
+	currentKeyID *atomic.Value
+}
+
+// Ensure we are implementing the seal.Access interface
+var _ seal.Access = (*TransitSeal)(nil)
+
+// NewSeal creates a TransitSeal from its configuration. Settings that are
+// not given fall back to the usual VAULT_* environment variables.
+func NewSeal(conf map[string]string, logger log.Logger) (*TransitSeal, error) {
+	keyName := conf["key_name"]
+	if keyName == "" {
+		return nil, errors.New("'key_name' must be set")
+	}
+
+	mountPath := conf["mount_path"]
+	if mountPath == "" {
+		mountPath = "transit"
+	}
+
+	apiConfig := api.DefaultConfig()
+	if addr := conf["address"]; addr != "" {
+		apiConfig.Address = addr
+	}
+
+	tlsConfig := &api.TLSConfig{
+		CACert:        conf["tls_ca_cert"],
+		ClientCert:    conf["tls_client_cert"],
+		ClientKey:     conf["tls_client_key"],
+		TLSServerName: conf["tls_server_name"],
+	}
+	if skipVerifyRaw, ok := conf["tls_skip_verify"]; ok {
+		skipVerify, err := strconv.ParseBool(skipVerifyRaw)
+		if err != nil {
+			return nil, errwrap.Wrapf("failed parsing 'tls_skip_verify' parameter: {{err}}", err)
+		}
+		tlsConfig.Insecure = skipVerify
+	}
+	if err := apiConfig.ConfigureTLS(tlsConfig); err != nil {
+		return nil, err
+	}
+
+	client, err := api.NewClient(apiConfig)
+	if err != nil {
+		return nil, err
+	}
+	if token := conf["token"]; token != "" {
+		client.SetToken(token)
+	}
+	if client.Token() == "" {
+		return nil, errors.New("no token provided for the transit seal")
+	}
+
+	s := &TransitSeal{
+		logger:       logger,
+		client:       client,
+		mountPath:    strings.Trim(mountPath, "/"),
+		keyName:      keyName,
+		currentKeyID: new(atomic.Value),
+	}
+	s.currentKeyID.Store("")
+
+	return s, nil
+}
+
+// Init is a no-op; the transit key must already exist
+func (s *TransitSeal) Init() error {
+	return nil
+}
+
+// Finalize is a no-op
+func (s *TransitSeal) Finalize() error {
+	return nil
+}
+
+// SealType returns the type of this seal
+func (s *TransitSeal) SealType() string {
+	return seal.Transit
+}
+
+// KeyID returns the name and version of the transit key last used to
+// encrypt
+func (s *TransitSeal) KeyID() string {
+	return s.currentKeyID.Load().(string)
+}
+
+// Encrypt sends the plaintext to the transit encrypt endpoint
+func (s *TransitSeal) Encrypt(plaintext []byte) (*seal.EncryptedBlobInfo, error) {
+	secret, err := s.client.Logical().Write(path.Join(s.mountPath, "encrypt", s.keyName), map[string]interface{}{
+		"plaintext": base64.StdEncoding.EncodeToString(plaintext),
+	})
+	if err != nil {
+		return nil, errwrap.Wrapf("error encrypting with transit: {{err}}", err)
+	}
+	if secret == nil || secret.Data == nil {
+		return nil, errors.New("no response from transit encrypt")
+	}
+
+	ciphertext, ok := secret.Data["ciphertext"].(string)
+	if !ok || ciphertext == "" {
+		return nil, errors.New("no ciphertext in response from transit encrypt")
+	}
+
+	keyID, err := s.keyIDFromCiphertext(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	s.currentKeyID.Store(keyID)
+
+	return &seal.EncryptedBlobInfo{
+		Ciphertext: []byte(ciphertext),
+		KeyID:      keyID,
+	}, nil
+}
+
+// Decrypt sends the ciphertext to the transit decrypt endpoint
+func (s *TransitSeal) Decrypt(in *seal.EncryptedBlobInfo) ([]byte, error) {
+	if in == nil {
+		return nil, errors.New("given input for decryption is nil")
+	}
+
+	secret, err := s.client.Logical().Write(path.Join(s.mountPath, "decrypt", s.keyName), map[string]interface{}{
+		"ciphertext": string(in.Ciphertext),
+	})
+	if err != nil {
+		return nil, errwrap.Wrapf("error decrypting with transit: {{err}}", err)
+	}
+	if secret == nil || secret.Data == nil {
+		return nil, errors.New("no response from transit decrypt")
+	}
+
+	plaintextB64, ok := secret.Data["plaintext"].(string)
+	if !ok {
+		return nil, errors.New("no plaintext in response from transit decrypt")
+	}
+
+	plaintext, err := base64.StdEncoding.DecodeString(plaintextB64)
+	if err != nil {
+		return nil, errwrap.Wrapf("error decoding plaintext from transit: {{err}}", err)
+	}
+
+	return plaintext, nil
+}
+
+// keyIDFromCiphertext builds the key ID from the key version embedded in a
+// transit ciphertext of the form vault:v1:...
+func (s *TransitSeal) keyIDFromCiphertext(ciphertext string) (string, error) {
+	splitted := strings.SplitN(ciphertext, ":", 3)
+	if len(splitted) != 3 || splitted[0] != "vault" || !strings.HasPrefix(splitted[1], "v") {
+		return "", fmt.Errorf("invalid ciphertext returned by transit")
+	}
+
+	return fmt.Sprintf("%s/%s", s.keyName, splitted[1]), nil
+}
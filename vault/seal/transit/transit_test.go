@@ -0,0 +1,99 @@
+package transit
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/builtin/logical/transit"
+	"github.com/hashicorp/vault/helper/logformat"
+	vaulthttp "github.com/hashicorp/vault/http"
+	"github.com/hashicorp/vault/vault"
+	log "github.com/mgutz/logxi/v1"
+)
+
+func TestTransitSeal(t *testing.T) {
+	logger := logformat.NewVaultLogger(log.LevelTrace)
+
+	if err := vault.AddTestLogicalBackend("transit", transit.Factory); err != nil {
+		t.Fatal(err)
+	}
+	core, _, token := vault.TestCoreUnsealed(t)
+	ln, addr := vaulthttp.TestServer(t, core)
+	defer ln.Close()
+
+	config := api.DefaultConfig()
+	config.Address = addr
+	client, err := api.NewClient(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SetToken(token)
+	if err := client.Sys().Mount("unseal-transit", &api.MountInput{
+		Type: "transit",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Logical().Write("unseal-transit/keys/unseal", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewSeal(map[string]string{"address": addr, "token": token}, logger); err == nil {
+		t.Fatal("expected error without a key name")
+	}
+
+	s, err := NewSeal(map[string]string{
+		"address":    addr,
+		"token":      token,
+		"mount_path": "unseal-transit/",
+		"key_name":   "unseal",
+	}, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	blob, err := s.Encrypt([]byte("foo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if blob.KeyID != "unseal/v1" || s.KeyID() != "unseal/v1" {
+		t.Fatalf("bad key id: %q", blob.KeyID)
+	}
+
+	// Values stay readable after the transit key is rotated
+	if _, err := client.Logical().Write("unseal-transit/keys/unseal/rotate", nil); err != nil {
+		t.Fatal(err)
+	}
+	pt, err := s.Decrypt(blob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(pt, []byte("foo")) {
+		t.Fatalf("bad plaintext: %q", pt)
+	}
+
+	blob, err = s.Encrypt([]byte("foo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if blob.KeyID != "unseal/v2" {
+		t.Fatalf("bad key id: %q", blob.KeyID)
+	}
+
+	// A seal pointing at a missing key fails
+	s, err = NewSeal(map[string]string{
+		"address":    addr,
+		"token":      token,
+		"mount_path": "unseal-transit",
+		"key_name":   "missing",
+	}, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Decrypt(blob); err == nil {
+		t.Fatal("expected error decrypting with missing key")
+	}
+}
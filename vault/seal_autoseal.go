@@ -0,0 +1,350 @@
+package vault
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/hashicorp/vault/helper/jsonutil"
+	"github.com/hashicorp/vault/physical"
+	"github.com/hashicorp/vault/vault/seal"
+)
+
+// autoSeal is a Seal that stores the master key wrapped by a seal.Access, so
+// that Vault can unseal itself on startup. Recovery keys take the place of
+// unseal keys for operations that need an operator's consent.
+type autoSeal struct {
+	seal.Access
+
+	core *Core
+
+	configLock     sync.RWMutex
+	barrierConfig  *SealConfig
+	recoveryConfig *SealConfig
+}
+
+// Ensure we are implementing the Seal interface
+var _ Seal = (*autoSeal)(nil)
+
+// NewAutoSeal returns a Seal which wraps its keys using the given access
+func NewAutoSeal(access seal.Access) Seal {
+	return &autoSeal{
+		Access: access,
+	}
+}
+
+func (d *autoSeal) checkCore() error {
+	if d.core == nil {
+		return fmt.Errorf("seal does not have a core set")
+	}
+	return nil
+}
+
+func (d *autoSeal) SetCore(core *Core) {
+	d.core = core
+}
+
+func (d *autoSeal) Init() error {
+	return d.Access.Init()
+}
+
+func (d *autoSeal) Finalize() error {
+	return d.Access.Finalize()
+}
+
+func (d *autoSeal) BarrierType() string {
+	return d.SealType()
+}
+
+func (d *autoSeal) StoredKeysSupported() bool {
+	return true
+}
+
+func (d *autoSeal) RecoveryKeySupported() bool {
+	return true
+}
+
+// SetStoredKeys wraps the unseal keys using the seal and stores them in
+// plaintext storage
+func (d *autoSeal) SetStoredKeys(keys [][]byte) error {
+	if err := d.checkCore(); err != nil {
+		return err
+	}
+	if keys == nil {
+		return fmt.Errorf("keys were nil")
+	}
+	if len(keys) == 0 {
+		return fmt.Errorf("no keys provided")
+	}
+
+	buf, err := json.Marshal(keys)
+	if err != nil {
+		return fmt.Errorf("failed to encode keys for storage: %v", err)
+	}
+
+	if err := d.putWrapped(storedBarrierKeysPath, buf); err != nil {
+		return fmt.Errorf("failed to store keys: %v", err)
+	}
+
+	return nil
+}
+
+// GetStoredKeys unwraps the stored unseal keys
+func (d *autoSeal) GetStoredKeys() ([][]byte, error) {
+	if err := d.checkCore(); err != nil {
+		return nil, err
+	}
+
+	pt, err := d.getWrapped(storedBarrierKeysPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch stored keys: %v", err)
+	}
+	if pt == nil {
+		return nil, nil
+	}
+
+	var keys [][]byte
+	if err := json.Unmarshal(pt, &keys); err != nil {
+		return nil, fmt.Errorf("failed to decode stored keys: %v", err)
+	}
+
+	return keys, nil
+}
+
+func (d *autoSeal) BarrierConfig() (*SealConfig, error) {
+	d.configLock.RLock()
+	if d.barrierConfig != nil {
+		defer d.configLock.RUnlock()
+		return d.barrierConfig.Clone(), nil
+	}
+	d.configLock.RUnlock()
+
+	conf, err := d.readConfig(barrierSealConfigPath, d.BarrierType(), "seal")
+	if err != nil || conf == nil {
+		return nil, err
+	}
+
+	d.configLock.Lock()
+	d.barrierConfig = conf
+	d.configLock.Unlock()
+
+	return conf.Clone(), nil
+}
+
+func (d *autoSeal) SetBarrierConfig(config *SealConfig) error {
+	if err := d.checkCore(); err != nil {
+		return err
+	}
+
+	d.configLock.Lock()
+	defer d.configLock.Unlock()
+
+	// Provide a way to wipe out the cached value (also prevents actually
+	// saving a nil config)
+	if config == nil {
+		d.barrierConfig = nil
+		return nil
+	}
+
+	config.Type = d.BarrierType()
+	if err := d.writeConfig(barrierSealConfigPath, config, "seal"); err != nil {
+		return err
+	}
+
+	d.barrierConfig = config.Clone()
+	return nil
+}
+
+func (d *autoSeal) RecoveryType() string {
+	return RecoveryTypeShamir
+}
+
+func (d *autoSeal) RecoveryConfig() (*SealConfig, error) {
+	d.configLock.RLock()
+	if d.recoveryConfig != nil {
+		defer d.configLock.RUnlock()
+		return d.recoveryConfig.Clone(), nil
+	}
+	d.configLock.RUnlock()
+
+	conf, err := d.readConfig(recoverySealConfigPlaintextPath, d.RecoveryType(), "recovery")
+	if err != nil || conf == nil {
+		return nil, err
+	}
+
+	d.configLock.Lock()
+	d.recoveryConfig = conf
+	d.configLock.Unlock()
+
+	return conf.Clone(), nil
+}
+
+func (d *autoSeal) SetRecoveryConfig(config *SealConfig) error {
+	if err := d.checkCore(); err != nil {
+		return err
+	}
+
+	d.configLock.Lock()
+	defer d.configLock.Unlock()
+
+	if config == nil {
+		d.recoveryConfig = nil
+		return nil
+	}
+
+	config.Type = d.RecoveryType()
+	if err := d.writeConfig(recoverySealConfigPlaintextPath, config, "recovery"); err != nil {
+		return err
+	}
+
+	d.recoveryConfig = config.Clone()
+	return nil
+}
+
+// SetRecoveryKey wraps the recovery key using the seal and stores it
+func (d *autoSeal) SetRecoveryKey(key []byte) error {
+	if err := d.checkCore(); err != nil {
+		return err
+	}
+	if len(key) == 0 {
+		return fmt.Errorf("recovery key to store is empty")
+	}
+
+	if err := d.putWrapped(recoveryKeyPath, key); err != nil {
+		return fmt.Errorf("failed to store recovery key: %v", err)
+	}
+
+	return nil
+}
+
+func (d *autoSeal) VerifyRecoveryKey(key []byte) error {
+	if len(key) == 0 {
+		return fmt.Errorf("recovery key to verify is empty")
+	}
+
+	recoveryKey, err := d.getRecoveryKey()
+	if err != nil {
+		return err
+	}
+
+	if subtle.ConstantTimeCompare(key, recoveryKey) != 1 {
+		return fmt.Errorf("recovery key does not match submitted values")
+	}
+
+	return nil
+}
+
+// getRecoveryKey unwraps the stored recovery key
+func (d *autoSeal) getRecoveryKey() ([]byte, error) {
+	if err := d.checkCore(); err != nil {
+		return nil, err
+	}
+
+	recoveryKey, err := d.getWrapped(recoveryKeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch recovery key: %v", err)
+	}
+	if recoveryKey == nil {
+		return nil, fmt.Errorf("no recovery key found")
+	}
+
+	return recoveryKey, nil
+}
+
+// putWrapped encrypts the value with the seal and stores it at the given
+// path, bypassing the barrier so that it can be read while sealed
+func (d *autoSeal) putWrapped(path string, value []byte) error {
+	blobInfo, err := d.Encrypt(value)
+	if err != nil {
+		return err
+	}
+
+	buf, err := json.Marshal(blobInfo)
+	if err != nil {
+		return err
+	}
+
+	return d.core.physical.Put(&physical.Entry{
+		Key:   path,
+		Value: buf,
+	})
+}
+
+// getWrapped reads and decrypts a value stored by putWrapped. A nil value is
+// returned if there is nothing stored at the path.
+func (d *autoSeal) getWrapped(path string) ([]byte, error) {
+	pe, err := d.core.physical.Get(path)
+	if err != nil {
+		return nil, err
+	}
+	if pe == nil {
+		return nil, nil
+	}
+
+	var blobInfo seal.EncryptedBlobInfo
+	if err := jsonutil.DecodeJSON(pe.Value, &blobInfo); err != nil {
+		return nil, err
+	}
+
+	if keyID := d.KeyID(); keyID != "" && blobInfo.KeyID != keyID && d.core.logger.IsDebug() {
+		d.core.logger.Debug("core: stored value was wrapped with a different seal key", "path", path, "key_id", blobInfo.KeyID)
+	}
+
+	return d.Decrypt(&blobInfo)
+}
+
+// readConfig reads and validates a seal configuration stored in plaintext
+func (d *autoSeal) readConfig(path, expectedType, desc string) (*SealConfig, error) {
+	if err := d.checkCore(); err != nil {
+		return nil, err
+	}
+
+	pe, err := d.core.physical.Get(path)
+	if err != nil {
+		d.core.logger.Error(fmt.Sprintf("core: failed to read %s configuration", desc), "error", err)
+		return nil, fmt.Errorf("failed to check %s configuration: %v", desc, err)
+	}
+
+	// If the configuration is missing, we are not initialized
+	if pe == nil {
+		d.core.logger.Info(fmt.Sprintf("core: %s configuration missing, not initialized", desc))
+		return nil, nil
+	}
+
+	var conf SealConfig
+	if err := jsonutil.DecodeJSON(pe.Value, &conf); err != nil {
+		d.core.logger.Error(fmt.Sprintf("core: failed to decode %s configuration", desc), "error", err)
+		return nil, fmt.Errorf("failed to decode %s configuration: %v", desc, err)
+	}
+
+	if conf.Type != expectedType {
+		d.core.logger.Error(fmt.Sprintf("core: %s type does not match loaded type", desc), "stored_type", conf.Type, "loaded_type", expectedType)
+		return nil, fmt.Errorf("%s type of %s does not match loaded type of %s", desc, conf.Type, expectedType)
+	}
+
+	if err := conf.Validate(); err != nil {
+		d.core.logger.Error(fmt.Sprintf("core: invalid %s configuration", desc), "error", err)
+		return nil, fmt.Errorf("%s validation failed: %v", desc, err)
+	}
+
+	return &conf, nil
+}
+
+// writeConfig stores a seal configuration in plaintext
+func (d *autoSeal) writeConfig(path string, config *SealConfig, desc string) error {
+	buf, err := json.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s configuration: %v", desc, err)
+	}
+
+	if err := d.core.physical.Put(&physical.Entry{
+		Key:   path,
+		Value: buf,
+	}); err != nil {
+		d.core.logger.Error(fmt.Sprintf("core: failed to write %s configuration", desc), "error", err)
+		return fmt.Errorf("failed to write %s configuration: %v", desc, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,356 @@
+package vault
+
+import (
+	"encoding/base64"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/vault/helper/jsonutil"
+	"github.com/hashicorp/vault/helper/logformat"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/physical"
+	"github.com/hashicorp/vault/physical/inmem"
+	"github.com/hashicorp/vault/shamir"
+	"github.com/hashicorp/vault/vault/seal"
+	log "github.com/mgutz/logxi/v1"
+)
+
+func testAutoSealCore(t *testing.T, phys physical.Backend, s, migrationSeal Seal) *Core {
+	t.Helper()
+	logger := logformat.NewVaultLogger(log.LevelTrace)
+	conf := testCoreConfig(t, phys, logger)
+	conf.Seal = s
+	conf.MigrationSeal = migrationSeal
+	core, err := NewCore(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return core
+}
+
+func testAutoSealInit(t *testing.T, core *Core) *InitResult {
+	t.Helper()
+	result, err := core.Initialize(&InitParams{
+		BarrierConfig: &SealConfig{
+			SecretShares:    1,
+			SecretThreshold: 1,
+			StoredShares:    1,
+		},
+		RecoveryConfig: &SealConfig{
+			SecretShares:    3,
+			SecretThreshold: 2,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func testInmem(t *testing.T) physical.Backend {
+	t.Helper()
+	inm, err := inmem.NewInmem(nil, logformat.NewVaultLogger(log.LevelTrace))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return inm
+}
+
+func TestAutoSeal_UnsealWithStoredKeys(t *testing.T) {
+	phys := testInmem(t)
+	access := seal.NewTestSeal()
+
+	core := testAutoSealCore(t, phys, NewAutoSeal(access), nil)
+	result := testAutoSealInit(t, core)
+	if len(result.SecretShares) != 0 {
+		t.Fatalf("expected no unseal keys, got %d", len(result.SecretShares))
+	}
+	if len(result.RecoveryShares) != 3 {
+		t.Fatalf("expected 3 recovery keys, got %d", len(result.RecoveryShares))
+	}
+
+	if err := core.UnsealWithStoredKeys(); err != nil {
+		t.Fatal(err)
+	}
+	if sealed, _ := core.Sealed(); sealed {
+		t.Fatal("should be unsealed")
+	}
+
+	// A new core with the same seal unseals itself
+	core = testAutoSealCore(t, phys, NewAutoSeal(access), nil)
+	if err := core.UnsealWithStoredKeys(); err != nil {
+		t.Fatal(err)
+	}
+	if sealed, _ := core.Sealed(); sealed {
+		t.Fatal("should be unsealed")
+	}
+
+	// A seal with another key cannot
+	core = testAutoSealCore(t, phys, NewAutoSeal(seal.NewTestSeal()), nil)
+	if err := core.UnsealWithStoredKeys(); err == nil {
+		t.Fatal("expected error")
+	}
+	if sealed, _ := core.Sealed(); !sealed {
+		t.Fatal("should be sealed")
+	}
+}
+
+func TestAutoSeal_RecoveryKeys(t *testing.T) {
+	core := testAutoSealCore(t, testInmem(t), NewAutoSeal(seal.NewTestSeal()), nil)
+	result := testAutoSealInit(t, core)
+
+	recoveryConfig, err := core.SealAccess().RecoveryConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &SealConfig{
+		Type:            RecoveryTypeShamir,
+		SecretShares:    3,
+		SecretThreshold: 2,
+	}
+	if !reflect.DeepEqual(recoveryConfig, expected) {
+		t.Fatalf("bad: %#v", recoveryConfig)
+	}
+
+	barrierConfig, err := core.SealAccess().BarrierConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if barrierConfig.Type != SealTypeTest || barrierConfig.StoredShares != 1 {
+		t.Fatalf("bad: %#v", barrierConfig)
+	}
+
+	// Recovery keys stand in for unseal keys when generating a root token
+	if err := core.UnsealWithStoredKeys(); err != nil {
+		t.Fatal(err)
+	}
+	otpBytes, err := GenerateRandBytes(16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := core.GenerateRootInit(base64.StdEncoding.EncodeToString(otpBytes), ""); err != nil {
+		t.Fatal(err)
+	}
+	genRootConfig, err := core.GenerateRootConfiguration()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var genResult *GenerateRootResult
+	for _, key := range result.RecoveryShares[:2] {
+		genResult, err = core.GenerateRootUpdate(key, genRootConfig.Nonce)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if genResult == nil || genResult.EncodedRootToken == "" {
+		t.Fatalf("expected root token to be generated: %#v", genResult)
+	}
+}
+
+func TestSealMigration_ShamirToAuto(t *testing.T) {
+	phys := testInmem(t)
+
+	core := testAutoSealCore(t, phys, nil, nil)
+	keys, _ := TestCoreInit(t, core)
+	for _, key := range keys {
+		if _, err := core.Unseal(TestKeyCopy(key)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	core.Shutdown()
+
+	// The migration seal is used until the unseal keys have been entered
+	access := seal.NewTestSeal()
+	core = testAutoSealCore(t, phys, NewAutoSeal(access), &DefaultSeal{})
+	if core.SealAccess().StoredKeysSupported() {
+		t.Fatal("expected shamir seal before migration")
+	}
+	for _, key := range keys {
+		if _, err := core.Unseal(TestKeyCopy(key)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if sealed, _ := core.Sealed(); sealed {
+		t.Fatal("should be unsealed")
+	}
+	if !core.SealAccess().StoredKeysSupported() {
+		t.Fatal("expected auto seal after migration")
+	}
+	core.Shutdown()
+
+	// From now on the core unseals itself, and the old unseal keys are the
+	// recovery keys
+	core = testAutoSealCore(t, phys, NewAutoSeal(access), &DefaultSeal{})
+	if err := core.UnsealWithStoredKeys(); err != nil {
+		t.Fatal(err)
+	}
+	if sealed, _ := core.Sealed(); sealed {
+		t.Fatal("should be unsealed")
+	}
+
+	recoveryConfig, err := core.SealAccess().RecoveryConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if recoveryConfig.SecretShares != 3 || recoveryConfig.SecretThreshold != 3 {
+		t.Fatalf("bad: %#v", recoveryConfig)
+	}
+	if err := core.SealAccess().VerifyRecoveryKey(TestKeyCopy(testCombineKeys(t, keys))); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSealMigration_AutoToShamir(t *testing.T) {
+	phys := testInmem(t)
+	access := seal.NewTestSeal()
+
+	core := testAutoSealCore(t, phys, NewAutoSeal(access), nil)
+	result := testAutoSealInit(t, core)
+	core.Shutdown()
+
+	// The disabled auto seal unseals the core one last time
+	core = testAutoSealCore(t, phys, &DefaultSeal{}, NewAutoSeal(access))
+	if err := core.UnsealWithStoredKeys(); err != nil {
+		t.Fatal(err)
+	}
+	if sealed, _ := core.Sealed(); sealed {
+		t.Fatal("should be unsealed")
+	}
+	if core.SealAccess().StoredKeysSupported() {
+		t.Fatal("expected shamir seal after migration")
+	}
+	core.Shutdown()
+
+	// The recovery keys are now the unseal keys
+	core = testAutoSealCore(t, phys, &DefaultSeal{}, nil)
+	barrierConfig, err := core.SealAccess().BarrierConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if barrierConfig.SecretShares != 3 || barrierConfig.SecretThreshold != 2 || barrierConfig.StoredShares != 0 {
+		t.Fatalf("bad: %#v", barrierConfig)
+	}
+	for _, key := range result.RecoveryShares[:2] {
+		if _, err := core.Unseal(TestKeyCopy(key)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if sealed, _ := core.Sealed(); sealed {
+		t.Fatal("should be unsealed")
+	}
+
+	for _, path := range []string{storedBarrierKeysPath, recoveryKeyPath, recoverySealConfigPlaintextPath} {
+		entry, err := phys.Get(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if entry != nil {
+			t.Fatalf("expected %s to be removed", path)
+		}
+	}
+}
+
+func TestSealMigration_HA(t *testing.T) {
+	logger := logformat.NewVaultLogger(log.LevelTrace)
+	inm, err := inmem.NewInmemHA(nil, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newCore := func(s, migrationSeal Seal, redirectAddr string) *Core {
+		conf := testCoreConfig(t, inm, logger)
+		conf.HAPhysical = inm.(physical.HABackend)
+		conf.RedirectAddr = redirectAddr
+		conf.Seal = s
+		conf.MigrationSeal = migrationSeal
+		core, err := NewCore(conf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return core
+	}
+
+	storedSealType := func() string {
+		pe, err := inm.Get(barrierSealConfigPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var conf SealConfig
+		if err := jsonutil.DecodeJSON(pe.Value, &conf); err != nil {
+			t.Fatal(err)
+		}
+		return conf.Type
+	}
+
+	// The active node still runs with the old configuration
+	core := newCore(nil, nil, "http://127.0.0.1:8200")
+	keys, root := TestCoreInit(t, core)
+	for _, key := range keys {
+		if _, err := TestCoreUnseal(core, TestKeyCopy(key)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	TestWaitActive(t, core)
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "secret/foo")
+	req.Data["foo"] = "bar"
+	req.ClientToken = root
+	if _, err := core.HandleRequest(req); err != nil {
+		t.Fatal(err)
+	}
+
+	// A standby with the new seal does not migrate the keys
+	access := seal.NewTestSeal()
+	core2 := newCore(NewAutoSeal(access), &DefaultSeal{}, "http://127.0.0.1:8500")
+	for _, key := range keys {
+		if _, err := TestCoreUnseal(core2, TestKeyCopy(key)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if standby, _ := core2.Standby(); !standby {
+		t.Fatal("should be standby")
+	}
+	if core2.SealAccess().StoredKeysSupported() {
+		t.Fatal("expected shamir seal on standby")
+	}
+	if typ := storedSealType(); typ != "" && typ != SealTypeShamir {
+		t.Fatalf("expected keys not to be migrated, got seal type %q", typ)
+	}
+
+	// Once the standby takes over it migrates the keys
+	if err := core.Seal(root); err != nil {
+		t.Fatal(err)
+	}
+	TestWaitActive(t, core2)
+	if !core2.SealAccess().StoredKeysSupported() {
+		t.Fatal("expected auto seal after migration")
+	}
+	if typ := storedSealType(); typ != SealTypeTest {
+		t.Fatalf("expected keys to be migrated, got seal type %q", typ)
+	}
+
+	req = logical.TestRequest(t, logical.ReadOperation, "secret/foo")
+	req.ClientToken = root
+	resp, err := core2.HandleRequest(req)
+	if err != nil || resp == nil || resp.Data["foo"] != "bar" {
+		t.Fatalf("err: %v, resp: %#v", err, resp)
+	}
+
+	// The new seal unseals the nodes from now on
+	core3 := newCore(NewAutoSeal(access), nil, "http://127.0.0.1:8300")
+	if err := core3.UnsealWithStoredKeys(); err != nil {
+		t.Fatal(err)
+	}
+	if sealed, _ := core3.Sealed(); sealed {
+		t.Fatal("should be unsealed")
+	}
+}
+
+func testCombineKeys(t *testing.T, keys [][]byte) []byte {
+	t.Helper()
+	combined, err := shamir.Combine(keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return combined
+}
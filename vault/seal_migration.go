@@ -0,0 +1,178 @@
+package vault
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/vault/helper/jsonutil"
+)
+
+// setupSealMigration checks if storage is still sealed with the migration
+// seal rather than the configured one. If so, the migration seal is used
+// until the next unseal, at which point the keys are moved over to the
+// configured seal.
+func (c *Core) setupSealMigration(migrationSeal Seal) error {
+	if migrationSeal == nil || migrationSeal.BarrierType() == c.seal.BarrierType() {
+		return nil
+	}
+
+	pe, err := c.physical.Get(barrierSealConfigPath)
+	if err != nil {
+		return fmt.Errorf("failed to check seal configuration: %v", err)
+	}
+
+	// Nothing to migrate if we are not initialized
+	if pe == nil {
+		return nil
+	}
+
+	var conf SealConfig
+	if err := jsonutil.DecodeJSON(pe.Value, &conf); err != nil {
+		return fmt.Errorf("failed to decode seal configuration: %v", err)
+	}
+	if conf.Type == "" {
+		conf.Type = SealTypeShamir
+	}
+	if conf.Type != migrationSeal.BarrierType() {
+		return nil
+	}
+
+	migrationSeal.SetCore(c)
+	c.pendingSeal = c.seal
+	c.seal = migrationSeal
+
+	if c.logger.IsInfo() {
+		c.logger.Info("core: seal migration pending, keys will be migrated on unseal", "from", migrationSeal.BarrierType(), "to", c.pendingSeal.BarrierType())
+	}
+
+	return nil
+}
+
+// migrateSeal moves the keys from the seal in use to the pending seal and
+// then switches over to it. Since the barrier may be rekeyed, it is only run
+// by the active node, from postUnseal, with the state lock held. Nodes that
+// become active after another node migrated the keys only switch seals.
+func (c *Core) migrateSeal() error {
+	if c.pendingSeal == nil {
+		return nil
+	}
+
+	pe, err := c.physical.Get(barrierSealConfigPath)
+	if err != nil {
+		return fmt.Errorf("failed to check seal configuration: %v", err)
+	}
+	if pe == nil {
+		return fmt.Errorf("seal configuration not found")
+	}
+	var conf SealConfig
+	if err := jsonutil.DecodeJSON(pe.Value, &conf); err != nil {
+		return fmt.Errorf("failed to decode seal configuration: %v", err)
+	}
+	if conf.Type == "" {
+		conf.Type = SealTypeShamir
+	}
+	if conf.Type != c.seal.BarrierType() {
+		if c.logger.IsInfo() {
+			c.logger.Info("core: seal already migrated", "from", c.seal.BarrierType(), "to", c.pendingSeal.BarrierType())
+		}
+		c.switchToPendingSeal()
+		return nil
+	}
+
+	barrierConfig, err := c.seal.BarrierConfig()
+	if err != nil {
+		return err
+	}
+
+	keyring, err := c.barrier.Keyring()
+	if err != nil {
+		return err
+	}
+	masterKey := make([]byte, len(keyring.MasterKey()))
+	copy(masterKey, keyring.MasterKey())
+	defer memzero(masterKey)
+
+	switch {
+	case !c.seal.StoredKeysSupported() && c.pendingSeal.StoredKeysSupported():
+		// The existing unseal keys become the recovery keys, and the master
+		// key is stored with the new seal
+		recoveryConfig := barrierConfig.Clone()
+		recoveryConfig.Nonce = ""
+		recoveryConfig.StoredShares = 0
+
+		if err := c.pendingSeal.SetStoredKeys([][]byte{masterKey}); err != nil {
+			return err
+		}
+		if err := c.pendingSeal.SetRecoveryConfig(recoveryConfig); err != nil {
+			return err
+		}
+		if err := c.pendingSeal.SetRecoveryKey(masterKey); err != nil {
+			return err
+		}
+		if err := c.pendingSeal.SetBarrierConfig(&SealConfig{
+			SecretShares:    1,
+			SecretThreshold: 1,
+			StoredShares:    1,
+		}); err != nil {
+			return err
+		}
+
+	case c.seal.StoredKeysSupported() && !c.pendingSeal.StoredKeysSupported():
+		// The recovery keys become the unseal keys, so the barrier is rekeyed
+		// to the recovery key
+		as, ok := c.seal.(*autoSeal)
+		if !ok {
+			return fmt.Errorf("cannot migrate from seal type %s", c.seal.BarrierType())
+		}
+
+		recoveryConfig, err := as.RecoveryConfig()
+		if err != nil {
+			return err
+		}
+		if recoveryConfig == nil {
+			return fmt.Errorf("recovery configuration not found")
+		}
+		recoveryKey, err := as.getRecoveryKey()
+		if err != nil {
+			return err
+		}
+		defer memzero(recoveryKey)
+
+		if err := c.barrier.Rekey(recoveryKey); err != nil {
+			return fmt.Errorf("failed to rekey barrier: %v", err)
+		}
+
+		newConfig := recoveryConfig.Clone()
+		newConfig.Nonce = ""
+		newConfig.StoredShares = 0
+		if err := c.pendingSeal.SetBarrierConfig(newConfig); err != nil {
+			return err
+		}
+
+		for _, path := range []string{storedBarrierKeysPath, recoveryKeyPath, recoverySealConfigPlaintextPath} {
+			if err := c.physical.Delete(path); err != nil {
+				return fmt.Errorf("failed to remove %s: %v", path, err)
+			}
+		}
+
+	default:
+		return fmt.Errorf("cannot migrate from seal type %s to %s", c.seal.BarrierType(), c.pendingSeal.BarrierType())
+	}
+
+	if c.logger.IsInfo() {
+		c.logger.Info("core: seal migration complete", "from", c.seal.BarrierType(), "to", c.pendingSeal.BarrierType())
+	}
+
+	c.switchToPendingSeal()
+
+	return nil
+}
+
+// switchToPendingSeal replaces the seal in use with the pending seal
+func (c *Core) switchToPendingSeal() {
+	c.seal.SetBarrierConfig(nil)
+	if c.seal.RecoveryKeySupported() {
+		c.seal.SetRecoveryConfig(nil)
+	}
+	c.seal = c.pendingSeal
+	c.pendingSeal = nil
+}
@@ -35,6 +35,7 @@ import (
 	"github.com/hashicorp/vault/api"
 	"github.com/hashicorp/vault/audit"
 	"github.com/hashicorp/vault/helper/logformat"
+	"github.com/hashicorp/vault/helper/metricsutil"
 	"github.com/hashicorp/vault/helper/reload"
 	"github.com/hashicorp/vault/helper/salt"
 	"github.com/hashicorp/vault/logical"
@@ -246,6 +247,27 @@ func TestCoreUnsealedRaw(t testing.T) (*Core, [][]byte, string) {
 	return testCoreUnsealed(t, core)
 }
 
+// TestCoreUnsealedWithMetrics returns a pure in-memory core that is already
+// initialized and unsealed, and serves the metrics of the given helper.
+func TestCoreUnsealedWithMetrics(t testing.T, helper *metricsutil.MetricsHelper, unauthenticatedAccess bool) (*Core, [][]byte, string) {
+	t.Helper()
+	logger := logformat.NewVaultLogger(log.LevelTrace)
+	physicalBackend, err := physInmem.NewInmem(nil, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conf := testCoreConfig(t, physicalBackend, logger)
+	conf.MetricsHelper = helper
+	conf.UnauthenticatedMetricsAccess = unauthenticatedAccess
+
+	core, err := NewCore(conf)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	return testCoreUnsealed(t, core)
+}
+
 func testCoreUnsealed(t testing.T, core *Core) (*Core, [][]byte, string) {
 	t.Helper()
 	keys, token := TestCoreInit(t, core)
@@ -1,6 +1,7 @@
 package vault
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"sync"
@@ -60,6 +61,14 @@ const (
 	// again (or when the revocation function is run again), but all other uses
 	// will report the token invalid
 	tokenRevocationFailed = -3
+
+	// batchTokenPrefix is the prefix of the IDs of batch tokens, which
+	// distinguishes them from the IDs of service tokens
+	batchTokenPrefix = "b."
+
+	// batchTokenEncryptionPath is the path batch tokens are encrypted for by
+	// the barrier
+	batchTokenEncryptionPath = "core/token/batch"
 )
 
 var (
@@ -74,7 +83,17 @@ var (
 			// Should only ever happen in testing
 			return nil
 		}
-		return ts.cubbyholeBackend.revoke(salt.SaltID(ts.cubbyholeBackend.saltUUID, saltedID, salt.SHA1Hash))
+		if err := ts.cubbyholeBackend.revoke(salt.SaltID(ts.cubbyholeBackend.saltUUID, saltedID, salt.SHA1Hash)); err != nil {
+			return err
+		}
+
+		// Tokens can also use the cubbyholes of nested namespaces
+		for _, ch := range ts.namespaceCubbyholes() {
+			if err := ch.revoke(salt.SaltID(ch.saltUUID, saltedID, salt.SHA1Hash)); err != nil {
+				return err
+			}
+		}
+		return nil
 	}
 )
 
@@ -86,16 +105,22 @@ type TokenStore struct {
 
 	view *BarrierView
 
+	// core is used to look up the namespaces tokens belong to
+	core *Core
+
 	expiration *ExpirationManager
 
 	cubbyholeBackend *CubbyholeBackend
 
-	policyLookupFunc func(string) (*Policy, error)
+	policyLookupFunc func(*Namespace, string) (*Policy, error)
 
 	tokenLocks []*locksutil.LockEntry
 
 	cubbyholeDestroyer func(*TokenStore, string) error
 
+	// batchTokenEncryptor encrypts the state of batch tokens into their IDs
+	batchTokenEncryptor BarrierEncryptor
+
 	logger log.Logger
 
 	saltLock   sync.RWMutex
@@ -113,16 +138,22 @@ func NewTokenStore(c *Core, config *logical.BackendConfig) (*TokenStore, error)
 
 	// Initialize the store
 	t := &TokenStore{
-		view:               view,
-		cubbyholeDestroyer: destroyCubbyhole,
-		logger:             c.logger,
-		tokenLocks:         locksutil.CreateLocks(),
-		saltLock:           sync.RWMutex{},
+		view:                view,
+		core:                c,
+		cubbyholeDestroyer:  destroyCubbyhole,
+		batchTokenEncryptor: c.barrier,
+		logger:              c.logger,
+		tokenLocks:          locksutil.CreateLocks(),
+		saltLock:            sync.RWMutex{},
 	}
 
 	if c.policyStore != nil {
-		t.policyLookupFunc = func(name string) (*Policy, error) {
-			return c.policyStore.GetPolicy(name, PolicyTypeToken)
+		t.policyLookupFunc = func(ns *Namespace, name string) (*Policy, error) {
+			ps := c.namespacePolicyStore(ns)
+			if ps == nil {
+				return nil, fmt.Errorf("policy store of namespace %q is unavailable", ns.Path)
+			}
+			return ps.GetPolicy(name, PolicyTypeToken)
 		}
 	}
 
@@ -218,6 +249,12 @@ func NewTokenStore(c *Core, config *logical.BackendConfig) (*TokenStore, error)
 						Default:     true,
 						Description: tokenRenewableHelp,
 					},
+
+					"token_type": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Default:     "default-service",
+						Description: tokenTypeHelp,
+					},
 				},
 
 				Callbacks: map[logical.Operation]framework.OperationFunc{
@@ -585,6 +622,29 @@ type TokenEntry struct {
 	ExplicitMaxTTLDeprecated time.Duration `json:"ExplicitMaxTTL" mapstructure:"ExplicitMaxTTL" structs:"ExplicitMaxTTL" sentinel:""`
 
 	EntityID string `json:"entity_id" mapstructure:"entity_id" structs:"entity_id"`
+
+	// The type of the token, service or batch. Entries of tokens created
+	// before batch tokens existed have the default type, which is service.
+	Type logical.TokenType `json:"type" mapstructure:"type" structs:"type"`
+
+	// The ID of the namespace the token was created in; empty for the root
+	// namespace
+	NamespaceID string `json:"namespace_id,omitempty" mapstructure:"namespace_id" structs:"namespace_id"`
+}
+
+// batchTokenEntry is the state of a batch token, which is encrypted into
+// its ID. Keys are kept short since they end up in the token.
+type batchTokenEntry struct {
+	Parent       string            `json:"pa,omitempty"`
+	Policies     []string          `json:"p"`
+	Path         string            `json:"pt"`
+	Meta         map[string]string `json:"m,omitempty"`
+	DisplayName  string            `json:"d"`
+	CreationTime int64             `json:"c"`
+	TTL          time.Duration     `json:"t"`
+	Role         string            `json:"r,omitempty"`
+	EntityID     string            `json:"e,omitempty"`
+	NamespaceID  string            `json:"n,omitempty"`
 }
 
 func (te *TokenEntry) SentinelGet(key string) (interface{}, error) {
@@ -618,6 +678,9 @@ func (te *TokenEntry) SentinelGet(key string) (interface{}, error) {
 
 	case "meta", "metadata":
 		return te.Meta, nil
+
+	case "type":
+		return te.Type.String(), nil
 	}
 
 	return nil, nil
@@ -652,6 +715,10 @@ type tsRoleEntry struct {
 	// If set, the token entry will have an explicit maximum TTL set, rather
 	// than deferring to role/mount values
 	ExplicitMaxTTL time.Duration `json:"explicit_max_ttl" mapstructure:"explicit_max_ttl" structs:"explicit_max_ttl"`
+
+	// The type of the tokens created using this role. The default types
+	// allow the creation request to choose the type.
+	TokenType logical.TokenType `json:"token_type" mapstructure:"token_type" structs:"token_type"`
 }
 
 type accessorEntry struct {
@@ -758,6 +825,14 @@ func (ts *TokenStore) createAccessor(entry *TokenEntry) error {
 // a newly generated ID if not provided.
 func (ts *TokenStore) create(entry *TokenEntry) error {
 	defer metrics.MeasureSince([]string{"token", "create"}, time.Now())
+
+	switch entry.Type {
+	case logical.TokenTypeBatch:
+		return ts.createBatchToken(entry)
+	case logical.TokenTypeDefault:
+		entry.Type = logical.TokenTypeService
+	}
+
 	// Generate an ID if necessary
 	if entry.ID == "" {
 		entryUUID, err := uuid.GenerateUUID()
@@ -786,6 +861,109 @@ func (ts *TokenStore) create(entry *TokenEntry) error {
 	return ts.storeCommon(entry, true)
 }
 
+// createBatchToken sets the ID of a batch token, which is the encryption of
+// its entry. Batch tokens are never persisted, so they have no accessor and
+// can't be renewed or revoked; they are valid until they expire, or until
+// their parent is revoked if they are not orphans.
+func (ts *TokenStore) createBatchToken(entry *TokenEntry) error {
+	defer metrics.MeasureSince([]string{"token", "create_batch_token"}, time.Now())
+
+	switch {
+	case entry.ID != "":
+		return fmt.Errorf("batch tokens cannot have a custom ID")
+	case entry.NumUses != 0:
+		return fmt.Errorf("batch tokens cannot have a limited number of uses")
+	case entry.Period != 0:
+		return fmt.Errorf("batch tokens cannot be periodic")
+	case entry.TTL <= 0:
+		return fmt.Errorf("batch tokens must have a TTL")
+	}
+
+	entry.Policies = policyutil.SanitizePolicies(entry.Policies, policyutil.DoNotAddDefaultPolicy)
+	entry.Accessor = ""
+
+	enc, err := jsonutil.EncodeJSON(&batchTokenEntry{
+		Parent:       entry.Parent,
+		Policies:     entry.Policies,
+		Path:         entry.Path,
+		Meta:         entry.Meta,
+		DisplayName:  entry.DisplayName,
+		CreationTime: entry.CreationTime,
+		TTL:          entry.TTL,
+		Role:         entry.Role,
+		EntityID:     entry.EntityID,
+		NamespaceID:  entry.NamespaceID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode batch token entry: %v", err)
+	}
+
+	ciphertext, err := ts.batchTokenEncryptor.Encrypt(batchTokenEncryptionPath, enc)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt batch token entry: %v", err)
+	}
+
+	entry.ID = batchTokenPrefix + base64.RawURLEncoding.EncodeToString(ciphertext)
+	return nil
+}
+
+// lookupBatchToken decrypts the entry of a batch token. Tokens that can't be
+// decrypted, or that expired, are invalid, and nil is returned for them.
+func (ts *TokenStore) lookupBatchToken(id string) (*TokenEntry, error) {
+	ciphertext, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(id, batchTokenPrefix))
+	if err != nil {
+		return nil, nil
+	}
+
+	plaintext, err := ts.batchTokenEncryptor.Decrypt(batchTokenEncryptionPath, ciphertext)
+	if err != nil {
+		if err == ErrBarrierSealed {
+			return nil, err
+		}
+		return nil, nil
+	}
+
+	var bte batchTokenEntry
+	if err := jsonutil.DecodeJSON(plaintext, &bte); err != nil {
+		return nil, fmt.Errorf("failed to decode batch token entry: %v", err)
+	}
+
+	if time.Now().After(time.Unix(bte.CreationTime, 0).Add(bte.TTL)) {
+		return nil, nil
+	}
+
+	// Batch tokens that aren't orphans are revoked along with their parent
+	if bte.Parent != "" {
+		parent, err := ts.Lookup(bte.Parent)
+		if err != nil {
+			return nil, fmt.Errorf("failed to lookup parent of batch token: %v", err)
+		}
+		if parent == nil {
+			return nil, nil
+		}
+	}
+
+	return &TokenEntry{
+		ID:           id,
+		Parent:       bte.Parent,
+		Policies:     bte.Policies,
+		Path:         bte.Path,
+		Meta:         bte.Meta,
+		DisplayName:  bte.DisplayName,
+		CreationTime: bte.CreationTime,
+		TTL:          bte.TTL,
+		Role:         bte.Role,
+		EntityID:     bte.EntityID,
+		Type:         logical.TokenTypeBatch,
+		NamespaceID:  bte.NamespaceID,
+	}, nil
+}
+
+// isBatchTokenID returns whether the ID is the one of a batch token
+func isBatchTokenID(id string) bool {
+	return strings.HasPrefix(id, batchTokenPrefix)
+}
+
 // Store is used to store an updated token entry without writing the
 // secondary index.
 func (ts *TokenStore) store(entry *TokenEntry) error {
@@ -920,6 +1098,11 @@ func (ts *TokenStore) Lookup(id string) (*TokenEntry, error) {
 		return nil, fmt.Errorf("cannot lookup blank token")
 	}
 
+	// Batch tokens are validated without reading storage
+	if isBatchTokenID(id) {
+		return ts.lookupBatchToken(id)
+	}
+
 	lock := locksutil.LockForKey(ts.tokenLocks, id)
 	lock.RLock()
 	defer lock.RUnlock()
@@ -1006,6 +1189,11 @@ func (ts *TokenStore) lookupSalted(saltedID string, tainted bool) (*TokenEntry,
 		persistNeeded = true
 	}
 
+	// Tokens created before batch tokens existed are service tokens
+	if entry.Type == logical.TokenTypeDefault {
+		entry.Type = logical.TokenTypeService
+	}
+
 	// If fields are getting upgraded, store the changes
 	if persistNeeded {
 		if err := ts.storeCommon(entry, false); err != nil {
@@ -1023,6 +1211,9 @@ func (ts *TokenStore) Revoke(id string) error {
 	if id == "" {
 		return fmt.Errorf("cannot revoke blank token")
 	}
+	if isBatchTokenID(id) {
+		return fmt.Errorf("batch tokens cannot be revoked")
+	}
 
 	saltedID, err := ts.SaltID(id)
 	if err != nil {
@@ -1159,6 +1350,9 @@ func (ts *TokenStore) RevokeTree(id string) error {
 	if id == "" {
 		return fmt.Errorf("cannot tree-revoke blank token")
 	}
+	if isBatchTokenID(id) {
+		return fmt.Errorf("batch tokens cannot be revoked")
+	}
 
 	// Get the salted ID
 	saltedId, err := ts.SaltID(id)
@@ -1203,7 +1397,7 @@ func (ts *TokenStore) revokeTreeSalted(saltedId string) error {
 func (ts *TokenStore) handleCreateAgainstRole(
 	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
 	name := d.Get("role_name").(string)
-	roleEntry, err := ts.tokenStoreRole(name)
+	roleEntry, err := ts.tokenStoreRole(ts.requestNamespace(req), name)
 	if err != nil {
 		return nil, err
 	}
@@ -1493,6 +1687,10 @@ func (ts *TokenStore) handleUpdateRevokeAccessor(req *logical.Request, data *fra
 		return nil, err
 	}
 
+	if resp, err := ts.checkTokenNamespace(req, aEntry.TokenID); resp != nil || err != nil {
+		return resp, err
+	}
+
 	// Revoke the token and its children
 	if err := ts.RevokeTree(aEntry.TokenID); err != nil {
 		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
@@ -1537,9 +1735,27 @@ func (ts *TokenStore) handleCreateCommon(
 			logical.ErrInvalidRequest
 	}
 
+	// Batch tokens can't be revoked, so they can't create tokens that would
+	// outlive a revocation of their tree
+	if parent.Type == logical.TokenTypeBatch {
+		return logical.ErrorResponse("batch tokens cannot create more tokens"),
+			logical.ErrInvalidRequest
+	}
+
 	// Check if the client token has sudo/root privileges for the requested path
 	isSudo := ts.System().SudoPrivilege(req.MountPoint+req.Path, req.ClientToken)
 
+	// Tokens are created in the namespace of the endpoint being used. As
+	// policies are scoped to namespaces, the policies of a parent in another
+	// namespace say nothing about the ones of the child, so creating tokens
+	// for a nested namespace takes sudo privileges.
+	ns := ts.requestNamespace(req)
+	crossNamespace := parent.NamespaceID != ns.ID
+	if crossNamespace && !isSudo {
+		return logical.ErrorResponse("root or sudo privileges required to create tokens in another namespace"),
+			logical.ErrInvalidRequest
+	}
+
 	// Read and parse the fields
 	var data struct {
 		ID              string
@@ -1554,6 +1770,7 @@ func (ts *TokenStore) handleCreateCommon(
 		DisplayName     string `mapstructure:"display_name"`
 		NumUses         int    `mapstructure:"num_uses"`
 		Period          string
+		Type            string
 	}
 	if err := mapstructure.WeakDecode(req.Data, &data); err != nil {
 		return logical.ErrorResponse(fmt.Sprintf(
@@ -1566,19 +1783,37 @@ func (ts *TokenStore) handleCreateCommon(
 			logical.ErrInvalidRequest
 	}
 
+	requestedType, err := logical.ParseTokenType(data.Type)
+	if err != nil || (requestedType != logical.TokenTypeDefault &&
+		requestedType != logical.TokenTypeService && requestedType != logical.TokenTypeBatch) {
+		return logical.ErrorResponse(fmt.Sprintf("invalid token type %q", data.Type)),
+			logical.ErrInvalidRequest
+	}
+
+	configuredType := logical.TokenTypeDefaultService
+	if role != nil && role.TokenType != logical.TokenTypeDefault {
+		configuredType = role.TokenType
+	}
+	tokenType, err := configuredType.Resolve(requestedType)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
 	// Setup the token entry
 	te := TokenEntry{
 		Parent: req.ClientToken,
 
-		// The mount point is always the same since we have only one token
-		// store; using req.MountPoint causes trouble in tests since they don't
-		// have an official mount
-		Path: fmt.Sprintf("auth/token/%s", req.Path),
+		// The mount point is always the same within a namespace since there
+		// is only one token store; using req.MountPoint causes trouble in
+		// tests since they don't have an official mount
+		Path: fmt.Sprintf("%sauth/token/%s", ns.Path, req.Path),
 
 		Meta:         data.Metadata,
 		DisplayName:  "token",
 		NumUses:      data.NumUses,
 		CreationTime: time.Now().Unix(),
+		Type:         tokenType,
+		NamespaceID:  ns.ID,
 	}
 
 	renewable := true
@@ -1619,6 +1854,14 @@ func (ts *TokenStore) handleCreateCommon(
 			return logical.ErrorResponse("root or sudo privileges required to specify token id"),
 				logical.ErrInvalidRequest
 		}
+		if te.Type == logical.TokenTypeBatch {
+			return logical.ErrorResponse("batch tokens cannot have a custom ID"),
+				logical.ErrInvalidRequest
+		}
+		if isBatchTokenID(data.ID) {
+			return logical.ErrorResponse(fmt.Sprintf("token IDs cannot start with %q", batchTokenPrefix)),
+				logical.ErrInvalidRequest
+		}
 		te.ID = data.ID
 	}
 
@@ -1692,6 +1935,11 @@ func (ts *TokenStore) handleCreateCommon(
 
 		data.Policies = finalPolicies
 
+	// No policies specified for a token of another namespace, which only
+	// gets the default policy of its own namespace
+	case crossNamespace && len(data.Policies) == 0:
+		addDefault = !data.NoDefaultPolicy
+
 	// No policies specified, inherit parent
 	case len(data.Policies) == 0:
 		// Only inherit "default" if the parent already has it, so don't touch addDefault here
@@ -1742,6 +1990,9 @@ func (ts *TokenStore) handleCreateCommon(
 	if strutil.StrListContains(data.Policies, "root") && !strutil.StrListContains(parent.Policies, "root") {
 		return logical.ErrorResponse("root tokens may not be created without parent token being root"), logical.ErrInvalidRequest
 	}
+	if ns.ID != "" && strutil.StrListContains(te.Policies, "root") {
+		return logical.ErrorResponse("root tokens cannot be created within a namespace"), logical.ErrInvalidRequest
+	}
 
 	//
 	// NOTE: Do not modify policies below this line. We need the checks above
@@ -1772,8 +2023,9 @@ func (ts *TokenStore) handleCreateCommon(
 
 	// At this point, it is clear whether the token is going to be an orphan or
 	// not. If the token is not going to be an orphan, inherit the parent's
-	// entity identifier into the child token.
-	if te.Parent != "" {
+	// entity identifier into the child token. Entities are scoped to the
+	// identity store of a namespace, so they are not carried across.
+	if te.Parent != "" && !crossNamespace {
 		te.EntityID = parent.EntityID
 	}
 
@@ -1902,6 +2154,18 @@ func (ts *TokenStore) handleCreateCommon(
 		renewable = false
 	}
 
+	if te.Type == logical.TokenTypeBatch {
+		switch {
+		case te.NumUses != 0:
+			return logical.ErrorResponse("batch tokens cannot have a limited number of uses"), logical.ErrInvalidRequest
+		case periodToUse != 0:
+			return logical.ErrorResponse("batch tokens cannot be periodic"), logical.ErrInvalidRequest
+		case te.TTL == 0:
+			return logical.ErrorResponse("batch tokens must have a TTL"), logical.ErrInvalidRequest
+		}
+		renewable = false
+	}
+
 	// Create the token
 	if err := ts.create(&te); err != nil {
 		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
@@ -1920,11 +2184,12 @@ func (ts *TokenStore) handleCreateCommon(
 		ClientToken: te.ID,
 		Accessor:    te.Accessor,
 		EntityID:    te.EntityID,
+		TokenType:   te.Type,
 	}
 
 	if ts.policyLookupFunc != nil {
 		for _, p := range te.Policies {
-			policy, err := ts.policyLookupFunc(p)
+			policy, err := ts.policyLookupFunc(ns, p)
 			if err != nil {
 				return logical.ErrorResponse(fmt.Sprintf("could not look up policy %s", p)), nil
 			}
@@ -1964,6 +2229,10 @@ func (ts *TokenStore) handleRevokeTree(
 		urltoken = true
 	}
 
+	if resp, err := ts.checkTokenNamespace(req, id); resp != nil || err != nil {
+		return resp, err
+	}
+
 	// Revoke the token and its children
 	if err := ts.RevokeTree(id); err != nil {
 		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
@@ -2010,6 +2279,10 @@ func (ts *TokenStore) handleRevokeOrphan(
 			logical.ErrInvalidRequest
 	}
 
+	if resp, err := ts.checkTokenNamespace(req, id); resp != nil || err != nil {
+		return resp, err
+	}
+
 	// Revoke and orphan
 	if err := ts.Revoke(id); err != nil {
 		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
@@ -2049,21 +2322,30 @@ func (ts *TokenStore) handleLookup(
 		return logical.ErrorResponse("missing token ID"), logical.ErrInvalidRequest
 	}
 
-	lock := locksutil.LockForKey(ts.tokenLocks, id)
-	lock.RLock()
-	defer lock.RUnlock()
+	var out *TokenEntry
+	if isBatchTokenID(id) {
+		var err error
+		out, err = ts.lookupBatchToken(id)
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+	} else {
+		lock := locksutil.LockForKey(ts.tokenLocks, id)
+		lock.RLock()
+		defer lock.RUnlock()
 
-	// Lookup the token
-	saltedId, err := ts.SaltID(id)
-	if err != nil {
-		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
-	}
-	out, err := ts.lookupSalted(saltedId, true)
-	if err != nil {
-		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		// Lookup the token
+		saltedId, err := ts.SaltID(id)
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+		out, err = ts.lookupSalted(saltedId, true)
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
 	}
 
-	if out == nil {
+	if out == nil || !ts.tokenInNamespace(out, ts.requestNamespace(req)) {
 		return logical.ErrorResponse("bad token"), logical.ErrPermissionDenied
 	}
 
@@ -2085,6 +2367,7 @@ func (ts *TokenStore) handleLookup(
 			"ttl":              int64(0),
 			"explicit_max_ttl": int64(out.ExplicitMaxTTL.Seconds()),
 			"entity_id":        out.EntityID,
+			"type":             out.Type.String(),
 		},
 	}
 
@@ -2099,6 +2382,20 @@ func (ts *TokenStore) handleLookup(
 		resp.Data["period"] = int64(out.Period.Seconds())
 	}
 
+	// Batch tokens have no lease, they expire after their TTL
+	if out.Type == logical.TokenTypeBatch {
+		expireTime := time.Unix(out.CreationTime, 0).Add(out.TTL)
+		resp.Data["expire_time"] = expireTime
+		resp.Data["ttl"] = int64(expireTime.Sub(time.Now()).Seconds())
+		resp.Data["renewable"] = false
+		resp.Data["issue_time"] = time.Unix(out.CreationTime, 0)
+
+		if urltoken {
+			resp.AddWarning(`Using a token in the path is unsafe as the token can be logged in many places. Please use POST or PUT with the token passed in via the "token" parameter.`)
+		}
+		return resp, nil
+	}
+
 	// Fetch the last renewal time
 	leaseTimes, err := ts.expiration.FetchLeaseTimesByToken(out.Path, out.ID)
 	if err != nil {
@@ -2156,10 +2453,14 @@ func (ts *TokenStore) handleRenew(
 	}
 
 	// Verify the token exists
-	if te == nil {
+	if te == nil || !ts.tokenInNamespace(te, ts.requestNamespace(req)) {
 		return logical.ErrorResponse("token not found"), logical.ErrInvalidRequest
 	}
 
+	if te.Type == logical.TokenTypeBatch {
+		return logical.ErrorResponse("batch tokens cannot be renewed"), logical.ErrInvalidRequest
+	}
+
 	// Renew the token and its children
 	resp, err := ts.expiration.RenewToken(req, te.Path, te.ID, increment)
 
@@ -2170,6 +2471,69 @@ func (ts *TokenStore) handleRenew(
 	return resp, err
 }
 
+// requestNamespace returns the namespace of the token store endpoint a
+// request was made against
+func (ts *TokenStore) requestNamespace(req *logical.Request) *Namespace {
+	if ts.core == nil {
+		return rootNamespace
+	}
+	return ts.core.namespaceByPath(req.MountPoint)
+}
+
+// tokenNamespace returns the namespace a token was created in, or nil if
+// the namespace no longer exists
+func (ts *TokenStore) tokenNamespace(te *TokenEntry) *Namespace {
+	if ts.core == nil {
+		return rootNamespace
+	}
+	return ts.core.namespaceByID(te.NamespaceID)
+}
+
+// tokenInNamespace returns whether a token belongs to the given namespace or
+// one nested underneath it
+func (ts *TokenStore) tokenInNamespace(te *TokenEntry, ns *Namespace) bool {
+	tokenNS := ts.tokenNamespace(te)
+	return tokenNS != nil && tokenNS.HasParent(ns)
+}
+
+// checkTokenNamespace returns an error response if the token with the given
+// ID is outside of the namespace of the request; the token store endpoints of
+// a namespace can only operate on the tokens of that namespace and of the
+// ones nested underneath it
+func (ts *TokenStore) checkTokenNamespace(req *logical.Request, id string) (*logical.Response, error) {
+	ns := ts.requestNamespace(req)
+	if ns.ID == "" {
+		return nil, nil
+	}
+
+	te, err := ts.Lookup(id)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+	if te != nil && !ts.tokenInNamespace(te, ns) {
+		return logical.ErrorResponse("token not found"), logical.ErrInvalidRequest
+	}
+	return nil, nil
+}
+
+// namespaceCubbyholes returns the cubbyhole backends of the namespaces
+func (ts *TokenStore) namespaceCubbyholes() []*CubbyholeBackend {
+	if ts.core == nil {
+		return nil
+	}
+
+	ts.core.namespacesLock.RLock()
+	defer ts.core.namespacesLock.RUnlock()
+
+	var ret []*CubbyholeBackend
+	for nsPath := range ts.core.namespaces {
+		if ch, ok := ts.core.router.MatchingBackend(nsPath + "cubbyhole/").(*CubbyholeBackend); ok {
+			ret = append(ret, ch)
+		}
+	}
+	return ret
+}
+
 func (ts *TokenStore) destroyCubbyhole(saltedID string) error {
 	if ts.cubbyholeBackend == nil {
 		// Should only ever happen in testing
@@ -2224,7 +2588,11 @@ func (ts *TokenStore) authRenew(
 		return f(req, d)
 	}
 
-	role, err := ts.tokenStoreRole(te.Role)
+	ns := ts.tokenNamespace(te)
+	if ns == nil {
+		return nil, fmt.Errorf("namespace of token could not be found, not renewing")
+	}
+	role, err := ts.tokenStoreRole(ns, te.Role)
 	if err != nil {
 		return nil, fmt.Errorf("error looking up role %s: %s", te.Role, err)
 	}
@@ -2256,8 +2624,16 @@ func (ts *TokenStore) authRenew(
 	return f(req, d)
 }
 
-func (ts *TokenStore) tokenStoreRole(name string) (*tsRoleEntry, error) {
-	entry, err := ts.view.Get(fmt.Sprintf("%s%s", rolesPrefix, name))
+// rolePrefix returns the storage prefix of the roles of a namespace
+func (ts *TokenStore) rolePrefix(ns *Namespace) string {
+	if ns.ID == "" {
+		return rolesPrefix
+	}
+	return rolesPrefix + namespaceBarrierPrefix + ns.ID + "/"
+}
+
+func (ts *TokenStore) tokenStoreRole(ns *Namespace, name string) (*tsRoleEntry, error) {
+	entry, err := ts.view.Get(fmt.Sprintf("%s%s", ts.rolePrefix(ns), name))
 	if err != nil {
 		return nil, err
 	}
@@ -2275,14 +2651,19 @@ func (ts *TokenStore) tokenStoreRole(name string) (*tsRoleEntry, error) {
 
 func (ts *TokenStore) tokenStoreRoleList(
 	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
-	entries, err := ts.view.List(rolesPrefix)
+	prefix := ts.rolePrefix(ts.requestNamespace(req))
+	entries, err := ts.view.List(prefix)
 	if err != nil {
 		return nil, err
 	}
 
-	ret := make([]string, len(entries))
-	for i, entry := range entries {
-		ret[i] = strings.TrimPrefix(entry, rolesPrefix)
+	ret := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		// Skip the roles of namespaces
+		if strings.HasSuffix(entry, "/") {
+			continue
+		}
+		ret = append(ret, strings.TrimPrefix(entry, prefix))
 	}
 
 	return logical.ListResponse(ret), nil
@@ -2290,7 +2671,7 @@ func (ts *TokenStore) tokenStoreRoleList(
 
 func (ts *TokenStore) tokenStoreRoleDelete(
 	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	err := ts.view.Delete(fmt.Sprintf("%s%s", rolesPrefix, data.Get("role_name").(string)))
+	err := ts.view.Delete(fmt.Sprintf("%s%s", ts.rolePrefix(ts.requestNamespace(req)), data.Get("role_name").(string)))
 	if err != nil {
 		return nil, err
 	}
@@ -2300,7 +2681,7 @@ func (ts *TokenStore) tokenStoreRoleDelete(
 
 func (ts *TokenStore) tokenStoreRoleRead(
 	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	role, err := ts.tokenStoreRole(data.Get("role_name").(string))
+	role, err := ts.tokenStoreRole(ts.requestNamespace(req), data.Get("role_name").(string))
 	if err != nil {
 		return nil, err
 	}
@@ -2318,6 +2699,7 @@ func (ts *TokenStore) tokenStoreRoleRead(
 			"orphan":              role.Orphan,
 			"path_suffix":         role.PathSuffix,
 			"renewable":           role.Renewable,
+			"token_type":          configTokenTypeString(role.TokenType),
 		},
 	}
 
@@ -2329,7 +2711,7 @@ func (ts *TokenStore) tokenStoreRoleExistenceCheck(req *logical.Request, data *f
 	if name == "" {
 		return false, fmt.Errorf("role name cannot be empty")
 	}
-	role, err := ts.tokenStoreRole(name)
+	role, err := ts.tokenStoreRole(ts.requestNamespace(req), name)
 	if err != nil {
 		return false, err
 	}
@@ -2343,7 +2725,7 @@ func (ts *TokenStore) tokenStoreRoleCreateUpdate(
 	if name == "" {
 		return logical.ErrorResponse("role name cannot be empty"), nil
 	}
-	entry, err := ts.tokenStoreRole(name)
+	entry, err := ts.tokenStoreRole(ts.requestNamespace(req), name)
 	if err != nil {
 		return nil, err
 	}
@@ -2422,6 +2804,33 @@ func (ts *TokenStore) tokenStoreRoleCreateUpdate(
 		return logical.ErrorResponse(fmt.Sprintf("error registering path suffix: %s", consts.ErrPathContainsParentReferences)), nil
 	}
 
+	tokenTypeStr, ok := data.GetOk("token_type")
+	if !ok && req.Operation == logical.CreateOperation {
+		tokenTypeStr, ok = data.Get("token_type"), true
+	}
+	if ok {
+		tokenType, err := logical.ParseTokenType(tokenTypeStr.(string))
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+		if tokenType == logical.TokenTypeDefault {
+			tokenType = logical.TokenTypeDefaultService
+		}
+		entry.TokenType = tokenType
+	}
+
+	// Batch tokens are never renewable, which only needs to be explicit if
+	// renewable was requested
+	if entry.TokenType == logical.TokenTypeBatch {
+		if entry.Period != 0 {
+			return logical.ErrorResponse("batch tokens cannot be periodic"), nil
+		}
+		if _, ok := data.GetOk("renewable"); ok && entry.Renewable {
+			return logical.ErrorResponse("batch tokens cannot be renewable"), nil
+		}
+		entry.Renewable = false
+	}
+
 	allowedPoliciesStr, ok := data.GetOk("allowed_policies")
 	if ok {
 		entry.AllowedPolicies = policyutil.SanitizePolicies(strings.Split(allowedPoliciesStr.(string), ","), policyutil.DoNotAddDefaultPolicy)
@@ -2437,7 +2846,7 @@ func (ts *TokenStore) tokenStoreRoleCreateUpdate(
 	}
 
 	// Store it
-	jsonEntry, err := logical.StorageEntryJSON(fmt.Sprintf("%s%s", rolesPrefix, name), entry)
+	jsonEntry, err := logical.StorageEntryJSON(fmt.Sprintf("%s%s", ts.rolePrefix(ts.requestNamespace(req)), name), entry)
 	if err != nil {
 		return nil, err
 	}
@@ -2510,4 +2919,9 @@ or revoke them. Because this can be used to
 cause a denial of service, this endpoint
 requires 'sudo' capability in addition to
 'list'.`
+	tokenTypeHelp = `The type of the tokens created via this
+role: "service", "batch", "default-service" or
+"default-batch". With the default types, the
+"type" parameter of the creation request can
+override the type. Defaults to "default-service".`
 )
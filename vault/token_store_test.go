@@ -902,6 +902,7 @@ func TestTokenStore_HandleRequest_CreateToken_DisplayName(t *testing.T) {
 		Path:        "auth/token/create",
 		DisplayName: "token-foo-bar-baz",
 		TTL:         0,
+		Type:        logical.TokenTypeService,
 	}
 	out, err := ts.Lookup(resp.Auth.ClientToken)
 	if err != nil {
@@ -934,6 +935,7 @@ func TestTokenStore_HandleRequest_CreateToken_NumUses(t *testing.T) {
 		DisplayName: "token",
 		NumUses:     1,
 		TTL:         0,
+		Type:        logical.TokenTypeService,
 	}
 	out, err := ts.Lookup(resp.Auth.ClientToken)
 	if err != nil {
@@ -997,6 +999,7 @@ func TestTokenStore_HandleRequest_CreateToken_NoPolicy(t *testing.T) {
 		Path:        "auth/token/create",
 		DisplayName: "token",
 		TTL:         0,
+		Type:        logical.TokenTypeService,
 	}
 	out, err := ts.Lookup(resp.Auth.ClientToken)
 	if err != nil {
@@ -1452,6 +1455,7 @@ func TestTokenStore_HandleRequest_Lookup(t *testing.T) {
 		"explicit_max_ttl": int64(0),
 		"expire_time":      nil,
 		"entity_id":        "",
+		"type":             "service",
 	}
 
 	if resp.Data["creation_time"].(int64) == 0 {
@@ -1492,6 +1496,7 @@ func TestTokenStore_HandleRequest_Lookup(t *testing.T) {
 		"explicit_max_ttl": int64(0),
 		"renewable":        true,
 		"entity_id":        "",
+		"type":             "service",
 	}
 
 	if resp.Data["creation_time"].(int64) == 0 {
@@ -1543,6 +1548,7 @@ func TestTokenStore_HandleRequest_Lookup(t *testing.T) {
 		"explicit_max_ttl": int64(0),
 		"renewable":        true,
 		"entity_id":        "",
+		"type":             "service",
 	}
 
 	if resp.Data["creation_time"].(int64) == 0 {
@@ -1625,6 +1631,7 @@ func TestTokenStore_HandleRequest_LookupSelf(t *testing.T) {
 		"ttl":              int64(3600),
 		"explicit_max_ttl": int64(0),
 		"entity_id":        "",
+		"type":             "service",
 	}
 
 	if resp.Data["creation_time"].(int64) == 0 {
@@ -1795,6 +1802,7 @@ func TestTokenStore_RoleCRUD(t *testing.T) {
 		"path_suffix":         "happenin",
 		"explicit_max_ttl":    int64(0),
 		"renewable":           true,
+		"token_type":          "default-service",
 	}
 
 	if !reflect.DeepEqual(expected, resp.Data) {
@@ -1839,6 +1847,7 @@ func TestTokenStore_RoleCRUD(t *testing.T) {
 		"path_suffix":         "happenin",
 		"explicit_max_ttl":    int64(0),
 		"renewable":           false,
+		"token_type":          "default-service",
 	}
 
 	if !reflect.DeepEqual(expected, resp.Data) {
@@ -1876,6 +1885,7 @@ func TestTokenStore_RoleCRUD(t *testing.T) {
 		"path_suffix":         "happenin",
 		"period":              int64(0),
 		"renewable":           false,
+		"token_type":          "default-service",
 	}
 
 	if !reflect.DeepEqual(expected, resp.Data) {
@@ -3586,3 +3596,209 @@ func TestTokenStore_TidyLeaseRevocation(t *testing.T) {
 		t.Fatal("found leases")
 	}
 }
+
+func TestTokenStore_BatchToken(t *testing.T) {
+	c, ts, _, root := TestCoreWithTokenStore(t)
+
+	policy, _ := ParseACLPolicy(tokenCreationPolicy)
+	policy.Name = "foo"
+	if err := c.policyStore.SetPolicy(policy); err != nil {
+		t.Fatal(err)
+	}
+
+	// Create a parent for the batch token
+	testCoreMakeToken(t, c, root, "parent", "1h", []string{"foo"})
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "auth/token/create")
+	req.ClientToken = "parent"
+	req.Data = map[string]interface{}{
+		"type":     "batch",
+		"policies": []string{"foo"},
+		"ttl":      "10m",
+	}
+	resp, err := c.HandleRequest(req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	if resp.Auth.TokenType != logical.TokenTypeBatch {
+		t.Fatalf("bad: %#v", resp.Auth)
+	}
+	if resp.Auth.Accessor != "" {
+		t.Fatalf("batch tokens should not have an accessor: %#v", resp.Auth)
+	}
+	if resp.Auth.Renewable {
+		t.Fatalf("batch tokens should not be renewable: %#v", resp.Auth)
+	}
+	batch := resp.Auth.ClientToken
+	if !strings.HasPrefix(batch, batchTokenPrefix) {
+		t.Fatalf("bad: %s", batch)
+	}
+
+	// Nothing is persisted for the batch token
+	keys, err := ts.view.List(lookupPrefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 {
+		t.Fatalf("expected only the root and parent tokens to be stored, got %d entries", len(keys))
+	}
+
+	te, err := ts.Lookup(batch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if te == nil {
+		t.Fatalf("expected batch token to be found")
+	}
+	if te.Type != logical.TokenTypeBatch || te.Parent != "parent" || te.TTL != 10*time.Minute {
+		t.Fatalf("bad: %#v", te)
+	}
+	if !reflect.DeepEqual(te.Policies, []string{"default", "foo"}) {
+		t.Fatalf("bad: %#v", te.Policies)
+	}
+
+	req = logical.TestRequest(t, logical.ReadOperation, "auth/token/lookup-self")
+	req.ClientToken = batch
+	resp, err = c.HandleRequest(req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	if resp.Data["type"] != "batch" || resp.Data["renewable"] != false || resp.Data["accessor"] != "" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// Batch tokens can't be renewed, revoked, create tokens or use the
+	// cubbyhole
+	for _, path := range []string{
+		"auth/token/renew-self",
+		"auth/token/revoke-self",
+		"auth/token/create",
+		"cubbyhole/foo",
+	} {
+		req = logical.TestRequest(t, logical.UpdateOperation, path)
+		req.ClientToken = batch
+		req.Data["foo"] = "bar"
+		resp, err = c.HandleRequest(req)
+		if err == nil && (resp == nil || !resp.IsError()) {
+			t.Fatalf("expected error for %q, got: %#v", path, resp)
+		}
+	}
+
+	// Tampered tokens are not valid
+	tampered := batch[:len(batch)-2] + "AA"
+	if tampered == batch {
+		tampered = batch[:len(batch)-2] + "BB"
+	}
+	if te, err := ts.Lookup(tampered); err != nil || te != nil {
+		t.Fatalf("expected tampered token to be invalid, got %#v %v", te, err)
+	}
+
+	// Revoking the parent invalidates the batch token
+	if err := ts.RevokeTree("parent"); err != nil {
+		t.Fatal(err)
+	}
+	if te, err := ts.Lookup(batch); err != nil || te != nil {
+		t.Fatalf("expected batch token to be revoked with its parent, got %#v %v", te, err)
+	}
+}
+
+func TestTokenStore_BatchToken_Expiration(t *testing.T) {
+	_, ts, _, root := TestCoreWithTokenStore(t)
+
+	te := &TokenEntry{
+		Parent:       root,
+		Policies:     []string{"default"},
+		Path:         "auth/token/create",
+		CreationTime: time.Now().Add(-time.Hour).Unix(),
+		TTL:          time.Minute,
+		Type:         logical.TokenTypeBatch,
+	}
+	if err := ts.create(te); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := ts.Lookup(te.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != nil {
+		t.Fatalf("expected expired batch token to be invalid, got %#v", out)
+	}
+
+	// Batch tokens must expire
+	te = &TokenEntry{
+		Policies: []string{"default"},
+		Path:     "auth/token/create",
+		Type:     logical.TokenTypeBatch,
+	}
+	if err := ts.create(te); err == nil {
+		t.Fatalf("expected error creating batch token without a TTL")
+	}
+}
+
+func TestTokenStore_RoleTokenType(t *testing.T) {
+	c, _, _, root := TestCoreWithTokenStore(t)
+
+	createRole := func(data map[string]interface{}) *logical.Response {
+		req := logical.TestRequest(t, logical.UpdateOperation, "auth/token/roles/test")
+		req.ClientToken = root
+		req.Data = data
+		resp, err := c.HandleRequest(req)
+		if err != nil && (resp == nil || !resp.IsError()) {
+			t.Fatal(err)
+		}
+		return resp
+	}
+	createToken := func(tokenType string) *logical.Response {
+		req := logical.TestRequest(t, logical.UpdateOperation, "auth/token/create/test")
+		req.ClientToken = root
+		req.Data["ttl"] = "1h"
+		if tokenType != "" {
+			req.Data["type"] = tokenType
+		}
+		resp, err := c.HandleRequest(req)
+		if err != nil && (resp == nil || !resp.IsError()) {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	// Batch roles can't be periodic
+	resp := createRole(map[string]interface{}{"token_type": "batch", "period": "1h"})
+	if resp == nil || !resp.IsError() {
+		t.Fatalf("expected error, got: %#v", resp)
+	}
+
+	resp = createRole(map[string]interface{}{"token_type": "batch"})
+	if resp != nil && resp.IsError() {
+		t.Fatalf("err: %v", resp.Error())
+	}
+
+	resp = createToken("")
+	if resp.IsError() || resp.Auth.TokenType != logical.TokenTypeBatch {
+		t.Fatalf("bad: %#v", resp)
+	}
+	resp = createToken("service")
+	if !resp.IsError() {
+		t.Fatalf("expected error requesting a service token from a batch role")
+	}
+
+	// With default-batch the request can choose the type
+	resp = createRole(map[string]interface{}{"token_type": "default-batch"})
+	if resp != nil && resp.IsError() {
+		t.Fatalf("err: %v", resp.Error())
+	}
+	resp = createToken("")
+	if resp.IsError() || resp.Auth.TokenType != logical.TokenTypeBatch {
+		t.Fatalf("bad: %#v", resp)
+	}
+	resp = createToken("service")
+	if resp.IsError() || resp.Auth.TokenType != logical.TokenTypeService {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	resp = createRole(map[string]interface{}{"token_type": "invalid"})
+	if resp == nil || !resp.IsError() {
+		t.Fatalf("expected error, got: %#v", resp)
+	}
+}